@@ -2,8 +2,10 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/openai/openai-go"
@@ -15,8 +17,11 @@ import (
 // Client wraps an OpenAI-compatible client with provider configuration
 type Client struct {
 	*openai.Client
-	provider string
-	model    string
+	provider   string
+	model      string
+	jsonSchema bool // provider accepts response_format (see Request.ExecuteJSON)
+	moderation bool // provider serves /moderations (see Moderate)
+	limiter    *limiter
 }
 
 // ProviderConfig holds configuration for a single AI provider
@@ -24,8 +29,16 @@ type ProviderConfig struct {
 	APIKey  string `yaml:"api_key" json:"api_key"`
 	BaseURL string `yaml:"base_url" json:"base_url"`
 	Model   string `yaml:"model" json:"model"`
+	// JSONSchema enables the response_format parameter for Request.ExecuteJSON.
+	// Defaults to true for the openai provider, false elsewhere.
+	JSONSchema bool `yaml:"json_schema" json:"json_schema"`
+	// Moderation makes Moderate use the provider's /moderations endpoint
+	// instead of a chat prompt. Defaults to true for the openai provider.
+	Moderation bool `yaml:"moderation" json:"moderation"`
 }
 
+var errStreamClosed = errors.New("stream closed")
+
 var (
 	clients    = make(map[string]*Client)
 	clientsMux sync.RWMutex
@@ -56,6 +69,14 @@ func loadProviderConfig(provider string) (*ProviderConfig, error) {
 	cfg.APIKey = viper.GetString(providerPath + ".api_key")
 	cfg.BaseURL = viper.GetString(providerPath + ".base_url")
 	cfg.Model = viper.GetString(providerPath + ".model")
+	cfg.JSONSchema = provider == "openai"
+	if viper.IsSet(providerPath + ".json_schema") {
+		cfg.JSONSchema = viper.GetBool(providerPath + ".json_schema")
+	}
+	cfg.Moderation = provider == "openai"
+	if viper.IsSet(providerPath + ".moderation") {
+		cfg.Moderation = viper.GetBool(providerPath + ".moderation")
+	}
 
 	// Environment variable fallback (e.g., AI_OPENAI_API_KEY)
 	envPrefix := fmt.Sprintf("AI_%s_", toEnvKey(provider))
@@ -117,6 +138,10 @@ func toEnvKey(provider string) string {
 
 // initProvider initializes a provider client
 func initProvider(provider string) (*Client, error) {
+	if provider == mockProvider {
+		return initMockProvider()
+	}
+
 	cfg, err := loadProviderConfig(provider)
 	if err != nil {
 		return nil, err
@@ -134,9 +159,11 @@ func initProvider(provider string) (*Client, error) {
 	client := openai.NewClient(opts...)
 
 	return &Client{
-		Client:   client,
-		provider: provider,
-		model:    cfg.Model,
+		Client:     client,
+		provider:   provider,
+		model:      cfg.Model,
+		jsonSchema: cfg.JSONSchema,
+		moderation: cfg.Moderation,
 	}, nil
 }
 
@@ -152,6 +179,15 @@ func Get(provider ...string) *Client {
 		p = provider[0]
 	}
 
+	client, err := getClient(p)
+	if err != nil {
+		panic(err.Error())
+	}
+	return client
+}
+
+// getClient returns the client for a provider, initializing it on first use
+func getClient(p string) (*Client, error) {
 	once := getOnce(p)
 	once.Do(func() {
 		client, err := initProvider(p)
@@ -159,6 +195,7 @@ func Get(provider ...string) *Client {
 			initErrors[p] = err
 			return
 		}
+		client.limiter = loadLimiter(p)
 		clientsMux.Lock()
 		clients[p] = client
 		clientsMux.Unlock()
@@ -170,12 +207,12 @@ func Get(provider ...string) *Client {
 
 	if client == nil {
 		if err := initErrors[p]; err != nil {
-			panic(fmt.Sprintf("ai provider %q initialization failed: %v", p, err))
+			return nil, fmt.Errorf("ai provider %q initialization failed: %w", p, err)
 		}
-		panic(fmt.Sprintf("ai provider %q not configured", p))
+		return nil, fmt.Errorf("ai provider %q not configured", p)
 	}
 
-	return client
+	return client, nil
 }
 
 // GetError returns the initialization error for a provider, if any
@@ -208,6 +245,17 @@ func (c *Client) Model() string {
 
 // Chat sends a chat completion request and returns the response content
 func (c *Client) Chat(ctx context.Context, messages []Message, opts ...ChatOption) (string, error) {
+	result, err := c.ChatWithUsage(ctx, messages, opts...)
+	if err != nil {
+		return "", err
+	}
+	return result.Content, nil
+}
+
+// ChatWithUsage sends a chat completion request and returns the response
+// content together with the model that served it and its token usage
+// Calls wait for the provider's max_rpm and max_concurrent capacity
+func (c *Client) ChatWithUsage(ctx context.Context, messages []Message, opts ...ChatOption) (*ChatResult, error) {
 	params := openai.ChatCompletionNewParams{
 		Model:    openai.F(openai.ChatModel(c.model)),
 		Messages: openai.F(toOpenAIMessages(messages)),
@@ -218,23 +266,46 @@ func (c *Client) Chat(ctx context.Context, messages []Message, opts ...ChatOptio
 		opt(&params)
 	}
 
+	release, err := c.limiter.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
 	resp, err := c.Client.Chat.Completions.New(ctx, params)
+	release()
 	if err != nil {
-		return "", fmt.Errorf("chat completion failed: %w", err)
+		return nil, fmt.Errorf("chat completion failed: %w", err)
+	}
+
+	result := &ChatResult{
+		Provider: c.provider,
+		Model:    resp.Model,
+		Usage:    toUsage(resp.Usage),
+	}
+	if result.Model == "" {
+		result.Model = string(params.Model.Value)
 	}
+	reportUsage(c.provider, result.Model, result.Usage)
 
 	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response choices returned")
+		return nil, fmt.Errorf("no response choices returned")
 	}
+	result.Content = resp.Choices[0].Message.Content
 
-	return resp.Choices[0].Message.Content, nil
+	return result, nil
 }
 
 // ChatStream sends a streaming chat completion request
+// Usage is requested with stream_options and read from the final chunk when
+// the provider returns it (see Stream.Usage)
+// The stream holds a max_concurrent slot until it completes, fails or is
+// closed
 func (c *Client) ChatStream(ctx context.Context, messages []Message, opts ...ChatOption) *Stream {
 	params := openai.ChatCompletionNewParams{
 		Model:    openai.F(openai.ChatModel(c.model)),
 		Messages: openai.F(toOpenAIMessages(messages)),
+		StreamOptions: openai.F(openai.ChatCompletionStreamOptionsParam{
+			IncludeUsage: openai.F(true),
+		}),
 	}
 
 	// Apply options
@@ -242,9 +313,23 @@ func (c *Client) ChatStream(ctx context.Context, messages []Message, opts ...Cha
 		opt(&params)
 	}
 
+	release, err := c.limiter.acquire(ctx)
+	if err != nil {
+		return &Stream{
+			stream:   ssestream.NewStream[openai.ChatCompletionChunk](nil, err),
+			provider: c.provider,
+			model:    string(params.Model.Value),
+			release:  func() {},
+		}
+	}
 	stream := c.Client.Chat.Completions.NewStreaming(ctx, params)
 
-	return &Stream{stream: stream}
+	return &Stream{
+		stream:   stream,
+		provider: c.provider,
+		model:    string(params.Model.Value),
+		release:  release,
+	}
 }
 
 // Message represents a chat message
@@ -255,30 +340,88 @@ type Message struct {
 
 // Stream wraps the streaming response
 type Stream struct {
-	stream *ssestream.Stream[openai.ChatCompletionChunk]
+	stream   *ssestream.Stream[openai.ChatCompletionChunk]
+	provider string
+	model    string
+	usage    Usage
+	done     bool
+
+	// onDone, if set, receives the full content when the stream completes,
+	// or the error when it fails or is closed early
+	onDone  func(content string, err error)
+	content strings.Builder
+
+	release func() // Frees the provider capacity held by the stream
+
+	restorer *piiRestorer // Set by WithPIIRedaction
 }
 
-// Next returns the next chunk of the stream
+// Next returns the next chunk of content, skipping chunks without any
+// Returns "" with a nil error once the stream is complete
 func (s *Stream) Next() (string, error) {
-	if !s.stream.Next() {
-		if err := s.stream.Err(); err != nil {
-			return "", err
+	for s.stream.Next() {
+		chunk := s.stream.Current()
+		if chunk.Model != "" {
+			s.model = chunk.Model
+		}
+		if chunk.Usage.TotalTokens > 0 {
+			s.usage = toUsage(chunk.Usage)
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			if s.onDone != nil {
+				s.content.WriteString(chunk.Choices[0].Delta.Content)
+			}
+			if s.restorer != nil {
+				if text := s.restorer.push(chunk.Choices[0].Delta.Content); text != "" {
+					return text, nil
+				}
+				continue
+			}
+			return chunk.Choices[0].Delta.Content, nil
 		}
-		return "", nil
 	}
-
-	chunk := s.stream.Current()
-	if len(chunk.Choices) > 0 {
-		return chunk.Choices[0].Delta.Content, nil
+	if err := s.stream.Err(); err != nil {
+		s.finish(err)
+		return "", err
+	}
+	if s.restorer != nil {
+		if text := s.restorer.flush(); text != "" {
+			return text, nil
+		}
+	}
+	if !s.done {
+		reportUsage(s.provider, s.model, s.usage)
+		s.finish(nil)
 	}
 	return "", nil
 }
 
+// Usage returns the token usage reported by the provider
+// It is zero until the stream is complete, or if the provider sends none
+func (s *Stream) Usage() Usage {
+	return s.usage
+}
+
 // Close closes the stream
 func (s *Stream) Close() error {
+	s.finish(errStreamClosed)
 	return s.stream.Close()
 }
 
+// finish marks the stream done and calls onDone once
+func (s *Stream) finish(err error) {
+	if s.done {
+		return
+	}
+	s.done = true
+	if s.release != nil {
+		s.release()
+	}
+	if s.onDone != nil {
+		s.onDone(s.content.String(), err)
+	}
+}
+
 // Err returns any error that occurred during streaming
 func (s *Stream) Err() error {
 	return s.stream.Err()
@@ -0,0 +1,175 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	responseCachePrefix        = "ai:response:"
+	defaultCacheTTL            = 7 * 24 * 60 * 60
+	defaultCacheMaxTemperature = 0.5
+)
+
+var cacheHits, cacheMisses atomic.Int64
+
+// Cache stores cached responses and URL summaries. Nothing is cached until
+// one is set with SetCache.
+type Cache interface {
+	// Get returns the value of key; ok is false when it is not cached
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+var (
+	cache    Cache
+	cacheMux sync.RWMutex
+)
+
+// SetCache sets the store of the response and summary caches, e.g. Redis.
+// A nil cache turns caching off.
+//
+// Example:
+//
+//	type redisCache struct{ rds redis.UniversalClient }
+//
+//	func (c redisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+//		v, err := c.rds.Get(ctx, key).Bytes()
+//		if err == redis.Nil {
+//			return nil, false, nil
+//		}
+//		return v, err == nil, err
+//	}
+//
+//	func (c redisCache) Set(ctx context.Context, key string, v []byte, ttl time.Duration) error {
+//		return c.rds.Set(ctx, key, v, ttl).Err()
+//	}
+//
+//	ai.SetCache(redisCache{rds})
+func SetCache(c Cache) {
+	cacheMux.Lock()
+	cache = c
+	cacheMux.Unlock()
+}
+
+func getCache() Cache {
+	cacheMux.RLock()
+	defer cacheMux.RUnlock()
+	return cache
+}
+
+// CacheCounters holds the response cache hit and miss counts of this process
+type CacheCounters struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// CacheStats returns the response cache counters. Requests that bypass the
+// cache are not counted; a TranslateBatch counts each text.
+func CacheStats() CacheCounters {
+	return CacheCounters{Hits: cacheHits.Load(), Misses: cacheMisses.Load()}
+}
+
+// WithCacheDisabled bypasses the response cache for this request, for
+// dynamic content that is not worth storing
+func (r *Request) WithCacheDisabled() *Request {
+	r.options.noCache = true
+	return r
+}
+
+// responseCacheTTL returns the TTL in seconds of cached responses, or 0 when
+// r should bypass the cache: caching is off (ai.cache.enabled, no SetCache),
+// disabled for the request, or its temperature exceeds ai.cache.max_temperature
+// since creative output should vary
+func (r *Request) responseCacheTTL() int {
+	if r.options.noCache || !viper.GetBool("ai.cache.enabled") || getCache() == nil {
+		return 0
+	}
+	maxTemp := defaultCacheMaxTemperature
+	if viper.IsSet("ai.cache.max_temperature") {
+		maxTemp = viper.GetFloat64("ai.cache.max_temperature")
+	}
+	if r.options.temperature > maxTemp {
+		return 0
+	}
+	if ttl := viper.GetInt("ai.cache.ttl_seconds"); ttl > 0 {
+		return ttl
+	}
+	return defaultCacheTTL
+}
+
+// responseCacheKey hashes the provider and model r asks for, the prompt and
+// the temperature. It returns "" when the provider is not configured, so the
+// request fails as it would uncached.
+func (r *Request) responseCacheKey(messages []Message) string {
+	provider := r.providers()[0]
+	client, err := getClient(provider)
+	if err != nil {
+		return ""
+	}
+	data, _ := json.Marshal(struct {
+		Provider    string    `json:"provider"`
+		Model       string    `json:"model"`
+		Messages    []Message `json:"messages"`
+		Temperature float64   `json:"temperature"`
+	}{provider, client.Model(), messages, r.options.temperature})
+	sum := sha256.Sum256(data)
+	return responseCachePrefix + hex.EncodeToString(sum[:])
+}
+
+// cachedChat is chat behind the response cache. Only successful responses
+// are stored; hits are marked Cached and report no usage.
+func (r *Request) cachedChat(ctx context.Context, messages []Message, chatOpts func(*Client) []ChatOption) (*ChatResult, error) {
+	ttl := r.responseCacheTTL()
+	key := ""
+	if ttl > 0 {
+		key = r.responseCacheKey(messages)
+	}
+	if key == "" {
+		return r.chat(ctx, messages, chatOpts)
+	}
+
+	var cached ChatResult
+	if cacheGet(key, &cached) {
+		cacheHits.Add(1)
+		cached.Cached = true
+		return &cached, nil
+	}
+	cacheMisses.Add(1)
+
+	result, err := r.chat(ctx, messages, chatOpts)
+	if err != nil {
+		return nil, err
+	}
+	cacheSet(key, ChatResult{Content: result.Content, Provider: result.Provider, Model: result.Model}, ttl)
+	return result, nil
+}
+
+// cacheGet decodes the cached JSON of key into val. Cache errors count as
+// misses.
+func cacheGet(key string, val any) bool {
+	c := getCache()
+	if c == nil {
+		return false
+	}
+	data, ok, err := c.Get(context.Background(), key)
+	return ok && err == nil && json.Unmarshal(data, val) == nil
+}
+
+// cacheSet stores val as JSON under key for ttl seconds, ignoring errors
+func cacheSet(key string, val any, ttl int) {
+	c := getCache()
+	if c == nil {
+		return
+	}
+	if data, err := json.Marshal(val); err == nil {
+		c.Set(context.Background(), key, data, time.Duration(ttl)*time.Second)
+	}
+}
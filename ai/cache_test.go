@@ -0,0 +1,175 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var batchItem = regexp.MustCompile(`(?m)^\d+\. (.*)$`)
+
+// memCache is an in-memory Cache
+type memCache struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (c *memCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.data[key]
+	return v, ok, nil
+}
+
+func (c *memCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[key] = value
+	return nil
+}
+
+// useMemCache sets an empty memCache for the test
+func useMemCache(t *testing.T) {
+	SetCache(&memCache{data: map[string][]byte{}})
+	t.Cleanup(func() { SetCache(nil) })
+}
+
+// newEchoProvider registers a provider that answers batch prompts with
+// "T:<item>" per numbered item and other prompts with "T:<last line>".
+// Its name is unique, so cache keys never collide across runs.
+func newEchoProvider(t *testing.T) (string, *atomic.Int32, *[]string) {
+	t.Helper()
+	useMemCache(t)
+
+	var calls atomic.Int32
+	var prompts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content json.RawMessage `json:"content"` // String or content parts
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt := mockContent(req.Messages[len(req.Messages)-1].Content)
+		prompts = append(prompts, prompt)
+		calls.Add(1)
+
+		var content string
+		if items := batchItem.FindAllStringSubmatch(prompt, -1); items != nil {
+			out := make([]string, len(items))
+			for i, m := range items {
+				out[i] = "T:" + m[1]
+			}
+			data, _ := json.Marshal(out)
+			content = string(data)
+		} else {
+			lines := strings.Split(prompt, "\n")
+			content = "T:" + lines[len(lines)-1]
+		}
+		data, _ := json.Marshal(content)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"c","object":"chat.completion","created":0,"model":"test",
+			"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":%s}}]}`, data)
+	}))
+	t.Cleanup(srv.Close)
+
+	provider := fmt.Sprintf("echo_%d", time.Now().UnixNano())
+	viper.Set("ai.providers."+provider+".base_url", srv.URL)
+	viper.Set("ai.providers."+provider+".model", "test")
+	viper.Set("ai.cache.enabled", true)
+	t.Cleanup(func() { viper.Set("ai.cache.enabled", false) })
+	return provider, &calls, &prompts
+}
+
+func TestResponseCache(t *testing.T) {
+	provider, calls, _ := newEchoProvider(t)
+	ctx := context.Background()
+	before := CacheStats()
+
+	translate := func(r *Request) *ChatResult {
+		t.Helper()
+		res, err := r.UseProvider(provider).ExecuteWithUsage(ctx)
+		if err != nil {
+			t.Fatalf("ExecuteWithUsage failed: %v", err)
+		}
+		return res
+	}
+
+	first := translate(NewRequest("Save").Translate("ja"))
+	second := translate(NewRequest("Save").Translate("ja"))
+	if first.Cached || !second.Cached || second.Content != first.Content || calls.Load() != 1 {
+		t.Fatalf("expected second call from cache: %+v %+v after %d calls", first, second, calls.Load())
+	}
+	stats := CacheStats()
+	if stats.Hits-before.Hits != 1 || stats.Misses-before.Misses != 1 {
+		t.Errorf("unexpected counters: %+v (before %+v)", stats, before)
+	}
+
+	translate(NewRequest("Save").Translate("ko"))
+	if calls.Load() != 2 {
+		t.Errorf("a different prompt should miss, got %d calls", calls.Load())
+	}
+
+	translate(NewRequest("Save").Translate("ja").WithCacheDisabled())
+	translate(NewRequest("Save").Translate("ja").WithTemperature(0.9))
+	if calls.Load() != 4 {
+		t.Errorf("disabled and creative requests should bypass the cache, got %d calls", calls.Load())
+	}
+	if after := CacheStats(); after.Hits+after.Misses != stats.Hits+stats.Misses+1 {
+		t.Errorf("bypassed requests should not be counted: %+v", after)
+	}
+}
+
+func TestTranslateBatchCache(t *testing.T) {
+	provider, calls, prompts := newEchoProvider(t)
+	ctx := context.Background()
+
+	if _, err := TranslateBatch(ctx, []string{"Save", "Cancel"}, "ja", TranslateWithProvider(provider)); err != nil {
+		t.Fatalf("TranslateBatch failed: %v", err)
+	}
+
+	results, err := TranslateBatch(ctx, []string{"Cancel", "Delete", "Save"}, "ja", TranslateWithProvider(provider))
+	if err != nil {
+		t.Fatalf("TranslateBatch failed: %v", err)
+	}
+	if strings.Join(results, ",") != "T:Cancel,T:Delete,T:Save" {
+		t.Errorf("unexpected results: %v", results)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls.Load())
+	}
+	last := (*prompts)[1]
+	if !strings.Contains(last, "1. Delete") || strings.Contains(last, "Save") || strings.Contains(last, "Cancel") {
+		t.Errorf("only the uncached text should be sent: %q", last)
+	}
+
+	if _, err := TranslateBatch(ctx, []string{"Save", "Delete"}, "ja", TranslateWithProvider(provider)); err != nil || calls.Load() != 2 {
+		t.Errorf("fully cached batch should not call the provider: %d calls, %v", calls.Load(), err)
+	}
+}
+
+func TestResponseCacheKeyStable(t *testing.T) {
+	viper.Set("ai.providers.keytest.base_url", "http://localhost")
+	viper.Set("ai.providers.keytest.model", "m1")
+	glossary := map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"}
+	r := NewRequest("text").Translate("ja").WithGlossary(glossary).UseProvider("keytest")
+	key := r.responseCacheKey(r.buildPrompt())
+	for range 20 {
+		if got := r.responseCacheKey(r.buildPrompt()); got != key {
+			t.Fatal("cache key should not depend on glossary iteration order")
+		}
+	}
+	if r.WithTemperature(0.1).responseCacheKey(r.buildPrompt()) == key {
+		t.Error("temperature should change the cache key")
+	}
+}
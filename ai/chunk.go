@@ -0,0 +1,245 @@
+package ai
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// WithChunking splits inputs longer than maxChunkChars into chunks that are
+// processed separately and reassembled in order, for documents that exceed
+// the model's context window. Splits happen on paragraph and HTML block
+// boundaries first, then lines, then sentences; template variables and HTML
+// tags are never split. All options (glossary, style, ...) apply to every
+// chunk. Applies to Execute and ExecuteWithUsage.
+//
+// Example:
+//
+//	result, err := ai.NewRequest(longHTML).
+//	    Translate("de").
+//	    AsTemplate().
+//	    WithChunking(4000).
+//	    WithConcurrency(4).
+//	    WithProgress(func(done, total int) { log.Printf("%d/%d", done, total) }).
+//	    Execute(ctx)
+func (r *Request) WithChunking(maxChunkChars int) *Request {
+	r.options.chunkSize = maxChunkChars
+	return r
+}
+
+// WithConcurrency sets how many chunks are processed in parallel (default 1)
+func (r *Request) WithConcurrency(n int) *Request {
+	r.options.concurrency = n
+	return r
+}
+
+// WithProgress sets a callback invoked after each chunk completes
+func (r *Request) WithProgress(fn func(done, total int)) *Request {
+	r.options.progress = fn
+	return r
+}
+
+// TranslateWithChunking translates long documents in chunks of at most
+// maxChunkChars (see Request.WithChunking)
+func TranslateWithChunking(maxChunkChars int) TranslateOption {
+	return func(r *Request) { r.WithChunking(maxChunkChars) }
+}
+
+var (
+	// blockBoundary matches blank lines and the end of block-level HTML elements
+	blockBoundary = regexp.MustCompile(`(?i)\n[ \t]*\n\s*|</(?:p|div|h[1-6]|li|ul|ol|table|tr|section|article|blockquote|pre|header|footer)>\s*|<br\s*/?>\s*`)
+
+	// chunkBoundaries are tried in order until pieces fit the chunk size
+	chunkBoundaries = []*regexp.Regexp{
+		blockBoundary,
+		regexp.MustCompile(`\n\s*`),
+		regexp.MustCompile(`[.!?。！？]+\s*`),
+	}
+
+	// protectedSpan matches text a chunk boundary must never fall inside:
+	// template variables, format verbs, HTML tags and PII placeholders
+	protectedSpan = regexp.MustCompile(`⟦[^⟧]*⟧|\{\{.*?\}\}|\$\{[^}]*\}|\{[^{}\s]+\}|%[-+# 0-9.]*[a-zA-Z]|<[^<>]+>`)
+)
+
+// chunk is a piece of the input; only body is sent to the model, the
+// surrounding whitespace is restored on reassembly
+type chunk struct {
+	lead, body, trail string
+}
+
+// splitChunks splits text into chunks of at most limit runes, except where a
+// protected span alone is longer
+func splitChunks(text string, limit int) []chunk {
+	protected := protectedSpan.FindAllStringIndex(text, -1)
+	units := splitUnits(text, 0, limit, 0, protected)
+
+	var chunks []chunk
+	var cur strings.Builder
+	n := 0
+	flush := func() {
+		s := cur.String()
+		body := strings.TrimSpace(s)
+		lead := s[:strings.Index(s, body)]
+		chunks = append(chunks, chunk{lead: lead, body: body, trail: s[len(lead)+len(body):]})
+		cur.Reset()
+		n = 0
+	}
+	for _, u := range units {
+		l := utf8.RuneCountInString(u)
+		if n > 0 && n+l > limit {
+			flush()
+		}
+		cur.WriteString(u)
+		n += l
+	}
+	if cur.Len() > 0 {
+		flush()
+	}
+	return chunks
+}
+
+// splitUnits splits text at chunkBoundaries[level], recursing into finer
+// boundaries for pieces still longer than limit. offset is the position of
+// text in the input, for checking protected spans.
+func splitUnits(text string, offset, limit, level int, protected [][]int) []string {
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+	if level == len(chunkBoundaries) {
+		return hardSplit(text, offset, limit, protected)
+	}
+
+	var units []string
+	start := 0
+	for _, m := range chunkBoundaries[level].FindAllStringIndex(text, -1) {
+		end := m[1]
+		if end <= start || end >= len(text) || spanAt(offset+end, protected) != nil {
+			continue
+		}
+		units = append(units, splitUnits(text[start:end], offset+start, limit, level+1, protected)...)
+		start = end
+	}
+	return append(units, splitUnits(text[start:], offset+start, limit, level+1, protected)...)
+}
+
+// hardSplit cuts text every limit runes, moving cuts out of protected spans
+func hardSplit(text string, offset, limit int, protected [][]int) []string {
+	var units []string
+	for utf8.RuneCountInString(text) > limit {
+		cut := 0
+		for i := 0; i < limit; i++ {
+			_, size := utf8.DecodeRuneInString(text[cut:])
+			cut += size
+		}
+		if span := spanAt(offset+cut, protected); span != nil {
+			if span[0] > offset {
+				cut = span[0] - offset
+			} else {
+				cut = span[1] - offset
+			}
+		}
+		if cut >= len(text) {
+			break
+		}
+		units = append(units, text[:cut])
+		text = text[cut:]
+		offset += cut
+	}
+	return append(units, text)
+}
+
+// spanAt returns the protected span strictly containing pos, if any
+func spanAt(pos int, protected [][]int) []int {
+	for _, span := range protected {
+		if span[0] < pos && pos < span[1] {
+			return span
+		}
+		if span[0] >= pos {
+			break
+		}
+	}
+	return nil
+}
+
+// executeChunked processes each chunk as its own request and reassembles
+// the results in order. Usage is summed over all chunks.
+func (r *Request) executeChunked(ctx context.Context) (*ChatResult, error) {
+	chunks := splitChunks(r.input, r.options.chunkSize)
+	outputs := make([]string, len(chunks))
+	result := &ChatResult{}
+
+	pending := 0
+	for _, c := range chunks {
+		if c.body != "" {
+			pending++
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := max(r.options.concurrency, 1)
+	sem := make(chan struct{}, concurrency)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		done     int
+		firstErr error
+	)
+	for i, c := range chunks {
+		if c.body == "" {
+			outputs[i] = c.lead + c.trail
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			cr := *r
+			cr.input = c.body
+			res, err := cr.cachedChat(ctx, cr.buildPrompt(), func(*Client) []ChatOption {
+				return []ChatOption{WithTemperature(r.options.temperature)}
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			outputs[i] = c.lead + res.Content + c.trail
+			result.Provider, result.Model = res.Provider, res.Model
+			result.PromptTokens += res.PromptTokens
+			result.CompletionTokens += res.CompletionTokens
+			result.TotalTokens += res.TotalTokens
+			done++
+			if r.options.progress != nil {
+				r.options.progress(done, pending)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result.Content = strings.Join(outputs, "")
+	return result, nil
+}
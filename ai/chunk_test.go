@@ -0,0 +1,136 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+func joinChunks(chunks []chunk) string {
+	var b strings.Builder
+	for _, c := range chunks {
+		b.WriteString(c.lead + c.body + c.trail)
+	}
+	return b.String()
+}
+
+func TestSplitChunks(t *testing.T) {
+	t.Run("paragraphs", func(t *testing.T) {
+		text := "\nFirst paragraph.\n\nSecond paragraph.\n\n\nThird paragraph.\n"
+		chunks := splitChunks(text, 20)
+		if joinChunks(chunks) != text {
+			t.Fatalf("chunks must reassemble to the input, got %q", joinChunks(chunks))
+		}
+		var bodies []string
+		for _, c := range chunks {
+			bodies = append(bodies, c.body)
+		}
+		if got := strings.Join(bodies, "|"); got != "First paragraph.|Second paragraph.|Third paragraph." {
+			t.Errorf("unexpected chunks: %q", got)
+		}
+		if chunks[0].lead != "\n" || chunks[1].trail != "\n\n\n" {
+			t.Errorf("separators not preserved: %+v", chunks)
+		}
+	})
+
+	t.Run("html blocks are packed up to the limit", func(t *testing.T) {
+		text := "<p>One.</p><p>Two.</p><div>Three.</div><p>Four.</p>"
+		chunks := splitChunks(text, 30)
+		if joinChunks(chunks) != text || len(chunks) != 2 || chunks[0].body != "<p>One.</p><p>Two.</p>" {
+			t.Errorf("unexpected chunks: %+v", chunks)
+		}
+	})
+
+	t.Run("long paragraph falls back to sentences", func(t *testing.T) {
+		text := "Alpha beta. Gamma delta! Epsilon zeta? 你好世界。再见。"
+		chunks := splitChunks(text, 14)
+		if joinChunks(chunks) != text {
+			t.Fatalf("chunks must reassemble to the input")
+		}
+		for _, c := range chunks {
+			if n := utf8.RuneCountInString(c.lead + c.body + c.trail); n > 14 {
+				t.Errorf("chunk %q has %d runes", c.body, n)
+			}
+		}
+	})
+
+	t.Run("template variables are never split", func(t *testing.T) {
+		for size := 3; size < 40; size++ {
+			text := "Dear {{.CustomerName}}, your order ${orderId} of {count} items ships to <a href=\"https://x.io/o\">here</a> soon"
+			for _, c := range splitChunks(text, size) {
+				if strings.Count(c.body, "{{") != strings.Count(c.body, "}}") ||
+					strings.Count(c.body, "<") != strings.Count(c.body, ">") ||
+					strings.Count(c.body, "{") != strings.Count(c.body, "}") {
+					t.Fatalf("size %d: chunk %q splits a protected span", size, c.body)
+				}
+			}
+		}
+	})
+}
+
+func TestExecuteChunked(t *testing.T) {
+	setupMock(t, nil)
+
+	var mu sync.Mutex
+	var calls int
+	MockRespond(func(msgs []Message) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if !strings.Contains(msgs[0].Content, `"order" → "Bestellung"`) || !strings.Contains(msgs[0].Content, "STYLE:") {
+			t.Errorf("glossary and style must apply to every chunk: %s", msgs[0].Content)
+		}
+		return false
+	}, "")
+
+	var paragraphs []string
+	for i := range 10 {
+		paragraphs = append(paragraphs, fmt.Sprintf("Paragraph %d about the order.", i))
+	}
+	input := strings.Join(paragraphs, "\n\n")
+
+	var progress []int
+	result, err := NewRequest(input).
+		Translate("de").
+		UseProvider("mock").
+		WithGlossary(map[string]string{"order": "Bestellung"}).
+		WithStyle(StyleFormal).
+		WithChunking(70).
+		WithConcurrency(3).
+		WithProgress(func(done, total int) {
+			if total != 5 {
+				t.Errorf("total = %d, want 5", total)
+			}
+			progress = append(progress, done)
+		}).
+		ExecuteWithUsage(context.Background())
+	if err != nil {
+		t.Fatalf("chunked Execute failed: %v", err)
+	}
+
+	var want []string
+	for i := 0; i < 10; i += 2 {
+		want = append(want, "[de] "+paragraphs[i]+"\n\n"+paragraphs[i+1])
+	}
+	if result.Content != strings.Join(want, "\n\n") {
+		t.Errorf("output not reassembled in order:\n%s", result.Content)
+	}
+	if calls != 5 || fmt.Sprint(progress) != "[1 2 3 4 5]" {
+		t.Errorf("calls = %d, progress = %v", calls, progress)
+	}
+	if result.Provider != "mock" || result.TotalTokens == 0 {
+		t.Errorf("usage should be summed over chunks: %+v", result)
+	}
+}
+
+func TestExecuteChunkedError(t *testing.T) {
+	setupMock(t, map[string]any{"error_rate_500": 1})
+
+	_, err := NewRequest("One.\n\nTwo.\n\nThree.").Translate("de").UseProvider("mock").WithChunking(5).Execute(context.Background())
+	if err == nil {
+		t.Fatal("expected error when a chunk fails")
+	}
+}
@@ -0,0 +1,153 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// defaultTokenBudget is the history budget of a new Conversation
+const defaultTokenBudget = 8000
+
+// messageOverhead approximates the tokens each message adds for its role
+// and delimiters
+const messageOverhead = 4
+
+// Conversation is a multi-turn chat that keeps its own history. When the
+// estimated size of the history exceeds the token budget, the oldest
+// non-system messages are dropped first.
+//
+// A Conversation is not safe for concurrent use. It can be persisted between
+// requests with json.Marshal and restored with json.Unmarshal.
+//
+// Example:
+//
+//	conv := ai.Get().NewConversation("You are a support agent for Acme.")
+//	answer, err := conv.Ask(ctx, "How do I reset my password?")
+//	answer, err = conv.Ask(ctx, "And if I no longer have that email?")
+type Conversation struct {
+	client   *Client
+	provider string
+	messages []Message
+	budget   int
+}
+
+// conversationJSON is the persisted form of a Conversation
+type conversationJSON struct {
+	Provider    string    `json:"provider"`
+	Messages    []Message `json:"messages"`
+	TokenBudget int       `json:"token_budget"`
+}
+
+// NewConversation starts a conversation with an optional system prompt
+func (c *Client) NewConversation(systemPrompt string) *Conversation {
+	conv := &Conversation{client: c, provider: c.provider, budget: defaultTokenBudget}
+	if systemPrompt != "" {
+		conv.messages = append(conv.messages, SystemMessage(systemPrompt))
+	}
+	return conv
+}
+
+// WithTokenBudget sets the estimated token limit for the history sent with
+// each question; <= 0 disables truncation
+func (conv *Conversation) WithTokenBudget(tokens int) *Conversation {
+	conv.budget = tokens
+	return conv
+}
+
+// Ask sends a user message with the history and records the answer
+// On error the history is left unchanged.
+func (conv *Conversation) Ask(ctx context.Context, userMsg string, opts ...ChatOption) (string, error) {
+	messages := conv.withQuestion(userMsg)
+
+	answer, err := conv.getClient().Chat(ctx, messages, opts...)
+	if err != nil {
+		return "", err
+	}
+	conv.messages = append(messages, AssistantMessage(answer))
+	return answer, nil
+}
+
+// AskStream sends a user message with the history and streams the answer
+// The question and answer are recorded once the stream completes; if it
+// fails or is closed early, the history is left unchanged.
+func (conv *Conversation) AskStream(ctx context.Context, userMsg string, opts ...ChatOption) *Stream {
+	messages := conv.withQuestion(userMsg)
+
+	stream := conv.getClient().ChatStream(ctx, messages, opts...)
+	stream.onDone = func(answer string, err error) {
+		if err != nil {
+			return
+		}
+		conv.messages = append(messages, AssistantMessage(answer))
+	}
+	return stream
+}
+
+// History returns a copy of the messages, system prompt first
+func (conv *Conversation) History() []Message {
+	return append([]Message(nil), conv.messages...)
+}
+
+// MarshalJSON implements json.Marshaler
+func (conv *Conversation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(conversationJSON{
+		Provider:    conv.provider,
+		Messages:    conv.messages,
+		TokenBudget: conv.budget,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+// The client for the stored provider is resolved on the next question.
+func (conv *Conversation) UnmarshalJSON(data []byte) error {
+	var v conversationJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*conv = Conversation{provider: v.Provider, messages: v.Messages, budget: v.TokenBudget}
+	return nil
+}
+
+func (conv *Conversation) getClient() *Client {
+	if conv.client == nil {
+		conv.client = Get(conv.provider)
+	}
+	return conv.client
+}
+
+// withQuestion returns a copy of the history with userMsg appended, dropping
+// the oldest non-system messages until it fits the budget. The question is
+// always kept. The history itself is not changed.
+func (conv *Conversation) withQuestion(userMsg string) []Message {
+	messages := append(conv.History(), UserMessage(userMsg))
+	if conv.budget <= 0 {
+		return messages
+	}
+	total := 0
+	for _, msg := range messages {
+		total += estimateTokens(msg.Content) + messageOverhead
+	}
+	for i := 0; total > conv.budget && i < len(messages)-1; {
+		if messages[i].Role == "system" {
+			i++
+			continue
+		}
+		total -= estimateTokens(messages[i].Content) + messageOverhead
+		messages = append(messages[:i], messages[i+1:]...)
+	}
+	return messages
+}
+
+// estimateTokens approximates the token count of s: about four ASCII
+// characters per token, one token per other character (e.g. CJK)
+func estimateTokens(s string) int {
+	ascii := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] < utf8.RuneSelf {
+			ascii++
+		}
+	}
+	other := utf8.RuneCountInString(s) - ascii
+	return (ascii+3)/4 + other
+}
@@ -0,0 +1,170 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConversationAsk(t *testing.T) {
+	client := setupMock(t, nil)
+	MockRespond(func(msgs []Message) bool {
+		return len(msgs) == 4 && msgs[1].Content == "My name is Ann." && msgs[3].Content == "What is my name?"
+	}, "Your name is Ann.")
+	MockRespond(func(msgs []Message) bool { return len(msgs) == 2 }, "Nice to meet you.")
+
+	conv := client.NewConversation("You are helpful.")
+	if _, err := conv.Ask(context.Background(), "My name is Ann."); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := conv.Ask(context.Background(), "What is my name?")
+	if err != nil || answer != "Your name is Ann." {
+		t.Fatalf("history should be sent with each question, got %q, %v", answer, err)
+	}
+
+	history := conv.History()
+	if len(history) != 5 || history[0].Role != "system" || history[4].Content != "Your name is Ann." {
+		t.Errorf("unexpected history: %+v", history)
+	}
+}
+
+func TestConversationAskError(t *testing.T) {
+	client := setupMock(t, map[string]any{"error_rate_500": 1})
+
+	conv := client.NewConversation("")
+	if _, err := conv.Ask(context.Background(), "hi"); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(conv.History()) != 0 {
+		t.Errorf("failed question should not be recorded: %+v", conv.History())
+	}
+}
+
+func TestConversationAskStream(t *testing.T) {
+	client := setupMock(t, map[string]any{"chunk_size": 2})
+	MockRespond(func([]Message) bool { return true }, "streamed answer")
+
+	conv := client.NewConversation("sys")
+	stream := conv.AskStream(context.Background(), "hi")
+	for {
+		chunk, err := stream.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunk == "" {
+			break
+		}
+	}
+	stream.Close()
+
+	history := conv.History()
+	if len(history) != 3 || history[2] != AssistantMessage("streamed answer") {
+		t.Errorf("streamed answer should be recorded once: %+v", history)
+	}
+
+	// Closed before completion: the question is dropped
+	stream = conv.AskStream(context.Background(), "again")
+	stream.Next()
+	stream.Close()
+	if len(conv.History()) != 3 {
+		t.Errorf("abandoned stream should not change history: %+v", conv.History())
+	}
+}
+
+func TestConversationTruncate(t *testing.T) {
+	client := setupMock(t, nil)
+	var sent []Message
+	MockRespond(func(msgs []Message) bool {
+		sent = msgs
+		return true
+	}, "ok")
+
+	conv := client.NewConversation("system prompt").WithTokenBudget(40)
+	for _, q := range []string{"first question here", "second question here", "third question here"} {
+		if _, err := conv.Ask(context.Background(), q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if sent[0].Role != "system" || sent[len(sent)-1].Content != "third question here" {
+		t.Fatalf("system prompt and latest question must be kept: %+v", sent)
+	}
+	for _, msg := range sent {
+		if msg.Content == "first question here" {
+			t.Errorf("oldest messages should be dropped first: %+v", sent)
+		}
+	}
+	total := 0
+	for _, msg := range sent {
+		total += estimateTokens(msg.Content) + messageOverhead
+	}
+	if total > 40 {
+		t.Errorf("history of %d tokens exceeds the budget", total)
+	}
+}
+
+func TestConversationJSON(t *testing.T) {
+	client := setupMock(t, nil)
+	MockRespond(func(msgs []Message) bool {
+		return len(msgs) == 4 && msgs[1].Content == "remember 42"
+	}, "You said 42.")
+
+	conv := client.NewConversation("sys").WithTokenBudget(500)
+	conv.Ask(context.Background(), "remember 42")
+
+	data, err := json.Marshal(conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"provider":"mock"`) || !strings.Contains(string(data), `"token_budget":500`) {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+
+	var restored Conversation
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := restored.Ask(context.Background(), "what did I say?")
+	if err != nil || answer != "You said 42." {
+		t.Errorf("restored conversation should continue with its history, got %q, %v", answer, err)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if got := estimateTokens("abcdefgh"); got != 2 {
+		t.Errorf("ASCII: got %d, want 2", got)
+	}
+	if got := estimateTokens("你好世界"); got != 4 {
+		t.Errorf("CJK: got %d, want 4", got)
+	}
+}
+
+func TestConversationTruncateKeptOnError(t *testing.T) {
+	client := setupMock(t, nil)
+	MockRespond(func([]Message) bool { return true }, "ok")
+
+	conv := client.NewConversation("system prompt").WithTokenBudget(40)
+	for _, q := range []string{"first question here", "second question here"} {
+		if _, err := conv.Ask(context.Background(), q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := conv.History()
+
+	// A failed call would truncate the history; nothing was exchanged, so
+	// nothing may be dropped
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := conv.Ask(ctx, "third question here"); err == nil {
+		t.Fatal("expected error")
+	}
+	stream := conv.AskStream(ctx, "third question here")
+	if _, err := stream.Next(); err == nil {
+		t.Fatal("expected stream error")
+	}
+	if after := conv.History(); !reflect.DeepEqual(after, before) {
+		t.Errorf("failed questions should leave the history unchanged:\n%+v\n%+v", before, after)
+	}
+}
@@ -0,0 +1,125 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DetectedLanguage is the result of language detection
+type DetectedLanguage struct {
+	Lang       string  `json:"lang"`       // ISO 639-1 code, e.g. "en"
+	Confidence float64 `json:"confidence"` // 0.0-1.0
+}
+
+const detectLanguageRules = `You are a language identification expert. Identify the language of the text.
+
+RULES:
+1. Use ISO 639-1 codes (two lowercase letters, e.g. "en", "zh", "ja")
+2. For mixed-language text, return the dominant language (most of the meaningful content)
+3. Ignore code, URLs, numbers and template variables when deciding
+4. confidence is a number between 0 and 1`
+
+// DetectLanguage returns the ISO 639-1 code of the dominant language of text
+// and a confidence score between 0 and 1
+//
+// Example:
+//
+//	lang, confidence, err := ai.DetectLanguage(ctx, "Bonjour tout le monde")
+//	// lang: "fr", confidence: 0.98
+func DetectLanguage(ctx context.Context, text string, opts ...TranslateOption) (string, float64, error) {
+	if strings.TrimSpace(text) == "" {
+		return "", 0, fmt.Errorf("no text to detect")
+	}
+
+	r := NewRequest(text).WithTemperature(0)
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	prompt := []Message{
+		SystemMessage(detectLanguageRules + "\n\nRespond with ONLY a JSON object: {\"lang\":\"xx\",\"confidence\":0.95}"),
+		UserMessage(text),
+	}
+
+	var detected DetectedLanguage
+	if err := r.detect(ctx, prompt, &detected); err != nil {
+		return "", 0, err
+	}
+	if err := detected.normalize(); err != nil {
+		return "", 0, err
+	}
+	return detected.Lang, detected.Confidence, nil
+}
+
+// DetectLanguages detects the language of multiple texts in a single API call
+//
+// Example:
+//
+//	results, err := ai.DetectLanguages(ctx, []string{"Hello", "你好"})
+//	// results: [{Lang: "en", Confidence: 0.99}, {Lang: "zh", Confidence: 0.99}]
+func DetectLanguages(ctx context.Context, texts []string, opts ...TranslateOption) ([]DetectedLanguage, error) {
+	if len(texts) == 0 {
+		return []DetectedLanguage{}, nil
+	}
+
+	r := NewRequest("").WithTemperature(0)
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	var user strings.Builder
+	user.WriteString("Detect the language of each item:\n\n")
+	for i, text := range texts {
+		user.WriteString(fmt.Sprintf("%d. %s\n", i+1, text))
+	}
+	prompt := []Message{
+		SystemMessage(detectLanguageRules + "\n5. Detect each numbered item separately, in input order" +
+			"\n\nRespond with ONLY a JSON object: {\"results\":[{\"lang\":\"xx\",\"confidence\":0.95}, ...]}"),
+		UserMessage(user.String()),
+	}
+
+	var batch struct {
+		Results []DetectedLanguage `json:"results"`
+	}
+	if err := r.detect(ctx, prompt, &batch); err != nil {
+		return nil, err
+	}
+	if len(batch.Results) != len(texts) {
+		return nil, fmt.Errorf("expected %d detections, got %d", len(texts), len(batch.Results))
+	}
+	for i := range batch.Results {
+		if err := batch.Results[i].normalize(); err != nil {
+			return nil, fmt.Errorf("item %d: %w", i+1, err)
+		}
+	}
+	return batch.Results, nil
+}
+
+// detect sends a detection prompt in JSON mode and decodes the response
+func (r *Request) detect(ctx context.Context, prompt []Message, out any) error {
+	result, err := r.chat(ctx, prompt, func(client *Client) []ChatOption {
+		opts := []ChatOption{WithTemperature(r.options.temperature)}
+		if client.jsonSchema {
+			opts = append(opts, withResponseFormat(nil))
+		}
+		return opts
+	})
+	if err != nil {
+		return err
+	}
+	if err := decodeJSON(result.Content, out); err != nil {
+		return fmt.Errorf("failed to parse detection result: %w\nRaw: %s", err, result.Content)
+	}
+	return nil
+}
+
+// normalize lowercases the code, validates it and clamps the confidence
+func (d *DetectedLanguage) normalize() error {
+	d.Lang = strings.ToLower(strings.TrimSpace(d.Lang))
+	if len(d.Lang) != 2 || d.Lang[0] < 'a' || d.Lang[0] > 'z' || d.Lang[1] < 'a' || d.Lang[1] > 'z' {
+		return fmt.Errorf("invalid language code %q", d.Lang)
+	}
+	d.Confidence = min(max(d.Confidence, 0), 1)
+	return nil
+}
@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	setupMock(t, nil)
+	MockRespond(func(msgs []Message) bool {
+		return strings.Contains(msgs[0].Content, "dominant language") && msgs[1].Content == "Bonjour, this is mostly français texte"
+	}, "```json\n{\"lang\":\"FR\",\"confidence\":1.2}\n```")
+
+	lang, confidence, err := DetectLanguage(context.Background(), "Bonjour, this is mostly français texte", TranslateWithProvider("mock"))
+	if err != nil {
+		t.Fatalf("DetectLanguage failed: %v", err)
+	}
+	if lang != "fr" || confidence != 1 {
+		t.Errorf("expected normalized fr/1, got %s/%v", lang, confidence)
+	}
+
+	if _, _, err := DetectLanguage(context.Background(), "  ", TranslateWithProvider("mock")); err == nil {
+		t.Error("expected error for empty text")
+	}
+}
+
+func TestDetectLanguageInvalidResponse(t *testing.T) {
+	setupMock(t, nil)
+	for _, response := range []string{"French", `{"lang":"french","confidence":0.9}`} {
+		ResetMockResponses()
+		MockRespond(func([]Message) bool { return true }, response)
+		if _, _, err := DetectLanguage(context.Background(), "Bonjour", TranslateWithProvider("mock")); err == nil {
+			t.Errorf("expected error for response %q", response)
+		}
+	}
+}
+
+func TestDetectLanguages(t *testing.T) {
+	setupMock(t, nil)
+	MockRespond(func(msgs []Message) bool {
+		return strings.HasPrefix(msgs[1].Content, "Detect the language of each item:\n\n1. Hello\n")
+	}, `{"results":[{"lang":"en","confidence":0.99},{"lang":"zh","confidence":0.97}]}`)
+
+	results, err := DetectLanguages(context.Background(), []string{"Hello", "你好"}, TranslateWithProvider("mock"))
+	if err != nil {
+		t.Fatalf("DetectLanguages failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Lang != "en" || results[1].Lang != "zh" || results[1].Confidence != 0.97 {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	if _, err := DetectLanguages(context.Background(), []string{"Hello"}, TranslateWithProvider("mock")); err == nil {
+		t.Error("expected count mismatch error")
+	}
+	if results, err := DetectLanguages(context.Background(), nil); err != nil || len(results) != 0 {
+		t.Errorf("empty input should return no results, got %v, %v", results, err)
+	}
+}
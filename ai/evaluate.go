@@ -0,0 +1,415 @@
+package ai
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// bestOfTemperature is the minimum temperature ExecuteBest samples
+// candidates at, so they actually differ
+const bestOfTemperature = 0.9
+
+// EvalIssue is a specific problem found in a translation
+// Offsets are in characters (runes) of the translation, end exclusive
+type EvalIssue struct {
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Text     string `json:"text"`     // The translation text in [Start, End)
+	Category string `json:"category"` // "accuracy", "fluency" or "terminology"
+	Severity string `json:"severity"` // "minor", "major" or "critical"
+	Message  string `json:"message"`
+}
+
+// EvalResult is the quality grade of a translation, all scores 0-100
+type EvalResult struct {
+	Score       float64     `json:"score"`
+	Accuracy    float64     `json:"accuracy"`
+	Fluency     float64     `json:"fluency"`
+	Terminology float64     `json:"terminology"`
+	Issues      []EvalIssue `json:"issues"`
+	// Warnings lists the fields the model left out or got wrong; they are
+	// defaulted (scores to 0) or corrected rather than failing the call
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// RankedTranslation is a candidate translation with its grade
+type RankedTranslation struct {
+	Index int    // Position in the candidates passed to RankTranslations
+	Text  string // The candidate translation
+	*EvalResult
+}
+
+const evaluateRules = `You are a professional translation quality reviewer. Grade the translation of the source text into %s.
+
+SCORING (numbers 0-100):
+• accuracy: the meaning is complete and correct, with no omissions, additions or mistranslations
+• fluency: the translation reads naturally and grammatically
+• terminology: domain terms, names and glossary terms are translated correctly and consistently
+• score: overall quality, weighing accuracy most
+
+ISSUES (one entry per specific problem, empty when there is none):
+• text: the affected span, copied exactly from the translation
+• start, end: character offsets of the span in the translation (0-based, end exclusive)
+• category: "accuracy", "fluency" or "terminology"
+• severity: "minor", "major" or "critical"
+• message: a short explanation with a suggested fix`
+
+const evaluateFormat = `{"score":85,"accuracy":90,"fluency":80,"terminology":85,"issues":[{"text":"...","start":0,"end":3,"category":"fluency","severity":"minor","message":"..."}]}`
+
+// Evaluate grades translation, a translation of source into targetLang,
+// with an overall score, sub-scores for accuracy, fluency and terminology,
+// and the specific issues found. Fields missing from the model's response
+// default to zero and are listed in EvalResult.Warnings.
+// Glossary and context options are taken into account when grading.
+//
+// Example:
+//
+//	result, err := ai.Evaluate(ctx, "Add to cart", "添加到购物车", "zh")
+//	if result.Score < 80 {
+//	    for _, issue := range result.Issues {
+//	        log.Printf("%d-%d %s: %s", issue.Start, issue.End, issue.Category, issue.Message)
+//	    }
+//	}
+func Evaluate(ctx context.Context, source, translation, targetLang string, opts ...TranslateOption) (*EvalResult, error) {
+	r := NewRequest(source).WithTemperature(0)
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	prompt := []Message{
+		SystemMessage(r.evaluateSystemPrompt(targetLang) + "\n\nRespond with ONLY a JSON object: " + evaluateFormat),
+		UserMessage(fmt.Sprintf("SOURCE:\n%s\n\nTRANSLATION:\n%s", source, translation)),
+	}
+	text, err := r.evaluate(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return parseEvalResult(text, translation)
+}
+
+// RankTranslations grades candidate translations of source into targetLang
+// in a single API call and returns them best first. Candidates with equal
+// scores keep their input order.
+//
+// Example:
+//
+//	ranked, err := ai.RankTranslations(ctx, "Add to cart", []string{"加入购物车", "添加到车"}, "zh")
+//	best := ranked[0].Text
+func RankTranslations(ctx context.Context, source string, candidates []string, targetLang string, opts ...TranslateOption) ([]RankedTranslation, error) {
+	if len(candidates) == 0 {
+		return []RankedTranslation{}, nil
+	}
+
+	r := NewRequest(source).WithTemperature(0)
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r.rank(ctx, source, candidates, targetLang)
+}
+
+// ExecuteBest generates n candidates of the request at a higher temperature
+// and returns the one RankTranslations grades best, with its grade.
+// The request must include a Translate task; the last one sets the language
+// candidates are graded in.
+//
+// Example:
+//
+//	text, grade, err := ai.NewRequest("Add to cart").
+//	    Translate("zh").
+//	    ExecuteBest(ctx, 3)
+func (r *Request) ExecuteBest(ctx context.Context, n int) (string, *EvalResult, error) {
+	if n < 1 {
+		return "", nil, fmt.Errorf("ExecuteBest needs at least 1 candidate, got %d", n)
+	}
+	targetLang := ""
+	for _, t := range r.tasks {
+		if t.taskType == taskTranslate {
+			targetLang = t.params["target_lang"]
+		}
+	}
+	if targetLang == "" {
+		return "", nil, fmt.Errorf("ExecuteBest requires a Translate task")
+	}
+
+	// Moderation, candidates and grading see the redacted input; only the
+	// winner is restored
+	br := *r
+	var redactions []Redaction
+	if r.options.redactPII {
+		rr, red := r.redacted()
+		br, redactions = *rr, red
+	}
+	if err := br.checkModeration(ctx); err != nil {
+		return "", nil, err
+	}
+	br.options.moderate = false
+	br.options.temperature = max(br.options.temperature, bestOfTemperature)
+	br.options.noCache = true
+
+	candidates := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range candidates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			candidates[i], errs[i] = br.Execute(ctx)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	er := NewRequest(br.input).WithTemperature(0)
+	er.provider, er.fallback = r.provider, r.fallback
+	er.options.glossary, er.options.context = br.options.glossary, br.options.context
+	ranked, err := er.rank(ctx, br.input, candidates, targetLang)
+	if err != nil {
+		return "", nil, err
+	}
+	best := ranked[0].Text
+	if redactions != nil {
+		best, _ = restorePII(best, redactions, false)
+	}
+	return best, ranked[0].EvalResult, nil
+}
+
+// rank grades candidates in one call and sorts them best first
+func (r *Request) rank(ctx context.Context, source string, candidates []string, targetLang string) ([]RankedTranslation, error) {
+	var user strings.Builder
+	user.WriteString(fmt.Sprintf("SOURCE:\n%s", source))
+	for i, c := range candidates {
+		user.WriteString(fmt.Sprintf("\n\nTRANSLATION %d:\n%s", i+1, c))
+	}
+	prompt := []Message{
+		SystemMessage(r.evaluateSystemPrompt(targetLang) +
+			"\n\nGrade each numbered translation separately, in input order. Offsets are relative to that translation." +
+			"\n\nRespond with ONLY a JSON object: {\"results\":[" + evaluateFormat + ", ...]}"),
+		UserMessage(user.String()),
+	}
+	text, err := r.evaluate(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	results, err := parseEvalResults(text, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := make([]RankedTranslation, len(candidates))
+	for i, c := range candidates {
+		ranked[i] = RankedTranslation{Index: i, Text: c, EvalResult: results[i]}
+	}
+	slices.SortStableFunc(ranked, func(a, b RankedTranslation) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	return ranked, nil
+}
+
+// evaluateSystemPrompt returns the grading rules with the request's
+// glossary and context
+func (r *Request) evaluateSystemPrompt(targetLang string) string {
+	var system strings.Builder
+	system.WriteString(fmt.Sprintf(evaluateRules, getLanguageName(targetLang)))
+	if len(r.options.glossary) > 0 {
+		system.WriteString("\n\nTERM GLOSSARY (required translations):\n")
+		for _, source := range slices.Sorted(maps.Keys(r.options.glossary)) {
+			system.WriteString(fmt.Sprintf("• %q → %q\n", source, r.options.glossary[source]))
+		}
+	}
+	if r.options.context != "" {
+		system.WriteString(fmt.Sprintf("\n\nCONTEXT: %s", r.options.context))
+	}
+	return system.String()
+}
+
+// evaluate sends a grading prompt in JSON mode and returns the raw response
+func (r *Request) evaluate(ctx context.Context, prompt []Message) (string, error) {
+	result, err := r.chat(ctx, prompt, func(client *Client) []ChatOption {
+		opts := []ChatOption{WithTemperature(r.options.temperature)}
+		if client.jsonSchema {
+			opts = append(opts, withResponseFormat(nil))
+		}
+		return opts
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.Content, nil
+}
+
+// parseEvalResult decodes a single evaluation of translation
+func parseEvalResult(text, translation string) (*EvalResult, error) {
+	var fields map[string]json.RawMessage
+	if err := decodeJSON(text, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse evaluation: %w\nRaw: %s", err, text)
+	}
+	return newEvalResult(fields, translation), nil
+}
+
+// parseEvalResults decodes a batch evaluation of translations, in order
+func parseEvalResults(text string, translations []string) ([]*EvalResult, error) {
+	var batch struct {
+		Results []map[string]json.RawMessage `json:"results"`
+	}
+	if err := decodeJSON(text, &batch); err != nil {
+		return nil, fmt.Errorf("failed to parse evaluation: %w\nRaw: %s", err, text)
+	}
+	if len(batch.Results) != len(translations) {
+		return nil, fmt.Errorf("expected %d evaluations, got %d", len(translations), len(batch.Results))
+	}
+	results := make([]*EvalResult, len(translations))
+	for i, fields := range batch.Results {
+		results[i] = newEvalResult(fields, translations[i])
+	}
+	return results, nil
+}
+
+// newEvalResult builds a result from the decoded fields, defaulting and
+// correcting what is missing or invalid with a warning
+func newEvalResult(fields map[string]json.RawMessage, translation string) *EvalResult {
+	res := &EvalResult{Issues: []EvalIssue{}}
+	for _, f := range []struct {
+		name string
+		dst  *float64
+	}{
+		{"score", &res.Score},
+		{"accuracy", &res.Accuracy},
+		{"fluency", &res.Fluency},
+		{"terminology", &res.Terminology},
+	} {
+		v, ok := res.number(fields, "", f.name)
+		if ok && (v < 0 || v > 100) {
+			res.warn("%s %v out of range, clamped to 0-100", f.name, v)
+			v = min(max(v, 0), 100)
+		}
+		*f.dst = v
+	}
+
+	raw, ok := fields["issues"]
+	if !ok || string(raw) == "null" {
+		return res
+	}
+	var issues []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &issues); err != nil {
+		res.warn("issues is not a list of objects, ignored")
+		return res
+	}
+	for i, issue := range issues {
+		res.Issues = append(res.Issues, res.newIssue(fmt.Sprintf("issues[%d].", i), issue, translation))
+	}
+	return res
+}
+
+// newIssue builds an issue, locating its span in translation. The quoted
+// text is trusted over the offsets, which models often miscount: the
+// occurrence nearest to the given start wins.
+func (res *EvalResult) newIssue(prefix string, fields map[string]json.RawMessage, translation string) EvalIssue {
+	issue := EvalIssue{
+		Text:     stringField(fields, "text"),
+		Category: strings.ToLower(stringField(fields, "category")),
+		Severity: strings.ToLower(stringField(fields, "severity")),
+		Message:  stringField(fields, "message"),
+	}
+
+	if issue.Text != "" {
+		hint, _ := parseNumber(fields["start"])
+		if pos := nearestIndex(translation, issue.Text, int(hint)); pos >= 0 {
+			issue.Start = pos
+			issue.End = pos + utf8.RuneCountInString(issue.Text)
+			return issue
+		}
+		res.warn("%stext %q not found in the translation", prefix, issue.Text)
+	}
+
+	start, _ := res.number(fields, prefix, "start")
+	end, _ := res.number(fields, prefix, "end")
+	issue.Start, issue.End = int(start), int(end)
+	length := utf8.RuneCountInString(translation)
+	if issue.Start < 0 || issue.End > length || issue.Start > issue.End {
+		res.warn("%soffsets %d-%d out of range, clamped", prefix, issue.Start, issue.End)
+		issue.Start = min(max(issue.Start, 0), length)
+		issue.End = min(max(issue.End, issue.Start), length)
+	}
+	issue.Text = string([]rune(translation)[issue.Start:issue.End])
+	return issue
+}
+
+// number reads a numeric field, warning and returning 0 and false when it
+// is missing or invalid
+func (res *EvalResult) number(fields map[string]json.RawMessage, prefix, name string) (float64, bool) {
+	raw, ok := fields[name]
+	if !ok || string(raw) == "null" {
+		res.warn("%s%s missing, defaulted to 0", prefix, name)
+		return 0, false
+	}
+	v, ok := parseNumber(raw)
+	if !ok {
+		res.warn("%s%s is not a number (%s), defaulted to 0", prefix, name, raw)
+	}
+	return v, ok
+}
+
+func (res *EvalResult) warn(format string, args ...any) {
+	res.Warnings = append(res.Warnings, fmt.Sprintf(format, args...))
+}
+
+// parseNumber decodes a JSON number or numeric string
+func parseNumber(raw json.RawMessage) (float64, bool) {
+	var v any
+	if json.Unmarshal(raw, &v) != nil {
+		return 0, false
+	}
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// stringField reads a string field, "" when missing or not a string
+func stringField(fields map[string]json.RawMessage, name string) string {
+	var s string
+	json.Unmarshal(fields[name], &s)
+	return strings.TrimSpace(s)
+}
+
+// nearestIndex returns the rune offset of the occurrence of substr in s
+// closest to hint, or -1
+func nearestIndex(s, substr string, hint int) int {
+	best := -1
+	for from := 0; from <= len(s); {
+		i := strings.Index(s[from:], substr)
+		if i < 0 {
+			break
+		}
+		pos := utf8.RuneCountInString(s[:from+i])
+		if best < 0 || abs(pos-hint) < abs(best-hint) {
+			best = pos
+		}
+		_, size := utf8.DecodeRuneInString(s[from+i:])
+		from += i + max(size, 1)
+	}
+	return best
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
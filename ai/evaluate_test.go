@@ -0,0 +1,243 @@
+package ai
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseEvalResult(t *testing.T) {
+	const translation = "添加到购物车，添加到收藏"
+	tests := []struct {
+		name     string
+		response string
+		want     EvalResult
+		warnings int
+	}{
+		{
+			name:     "complete",
+			response: `{"score":82,"accuracy":90,"fluency":75,"terminology":80,"issues":[{"text":"收藏","start":10,"end":12,"category":"Terminology","severity":"minor","message":"Use 心愿单"}]}`,
+			want: EvalResult{Score: 82, Accuracy: 90, Fluency: 75, Terminology: 80, Issues: []EvalIssue{
+				{Start: 10, End: 12, Text: "收藏", Category: "terminology", Severity: "minor", Message: "Use 心愿单"},
+			}},
+		},
+		{
+			name:     "fenced with prose",
+			response: "Here is the evaluation:\n```json\n{\"score\":70,\"accuracy\":70,\"fluency\":70,\"terminology\":70,\"issues\":[]}\n```\nHope it helps.",
+			want:     EvalResult{Score: 70, Accuracy: 70, Fluency: 70, Terminology: 70, Issues: []EvalIssue{}},
+		},
+		{
+			name:     "missing fields",
+			response: `{"score":60}`,
+			want:     EvalResult{Score: 60, Issues: []EvalIssue{}},
+			warnings: 3,
+		},
+		{
+			name:     "numeric strings and out of range",
+			response: `{"score":"88","accuracy":120,"fluency":-5,"terminology":"n/a","issues":null}`,
+			want:     EvalResult{Score: 88, Accuracy: 100, Fluency: 0, Issues: []EvalIssue{}},
+			warnings: 3,
+		},
+		{
+			name:     "text located over miscounted offsets",
+			response: `{"score":50,"accuracy":50,"fluency":50,"terminology":50,"issues":[{"text":"添加到","start":6,"end":9,"category":"fluency"}]}`,
+			want: EvalResult{Score: 50, Accuracy: 50, Fluency: 50, Terminology: 50, Issues: []EvalIssue{
+				{Start: 7, End: 10, Text: "添加到", Category: "fluency"},
+			}},
+		},
+		{
+			name:     "offsets without text",
+			response: `{"score":50,"accuracy":50,"fluency":50,"terminology":50,"issues":[{"start":0,"end":2},{"start":10,"end":99},{"end":1}]}`,
+			want: EvalResult{Score: 50, Accuracy: 50, Fluency: 50, Terminology: 50, Issues: []EvalIssue{
+				{Start: 0, End: 2, Text: "添加"},
+				{Start: 10, End: 12, Text: "收藏"},
+				{Start: 0, End: 1, Text: "添"},
+			}},
+			warnings: 2,
+		},
+		{
+			name:     "text not found",
+			response: `{"score":50,"accuracy":50,"fluency":50,"terminology":50,"issues":[{"text":"购物袋","start":3,"end":6}]}`,
+			want: EvalResult{Score: 50, Accuracy: 50, Fluency: 50, Terminology: 50, Issues: []EvalIssue{
+				{Start: 3, End: 6, Text: "购物车"},
+			}},
+			warnings: 1,
+		},
+		{
+			name:     "issues not a list",
+			response: `{"score":50,"accuracy":50,"fluency":50,"terminology":50,"issues":"none"}`,
+			want:     EvalResult{Score: 50, Accuracy: 50, Fluency: 50, Terminology: 50, Issues: []EvalIssue{}},
+			warnings: 1,
+		},
+		{
+			name:     "null",
+			response: `null`,
+			want:     EvalResult{Issues: []EvalIssue{}},
+			warnings: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEvalResult(tt.response, translation)
+			if err != nil {
+				t.Fatalf("parseEvalResult failed: %v", err)
+			}
+			if len(got.Warnings) != tt.warnings {
+				t.Errorf("expected %d warnings, got %q", tt.warnings, got.Warnings)
+			}
+			got.Warnings = nil
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+
+	for _, response := range []string{"", "Great translation!", `[{"score":90}]`} {
+		if _, err := parseEvalResult(response, translation); err == nil {
+			t.Errorf("expected error for %q", response)
+		}
+	}
+}
+
+func TestNearestIndex(t *testing.T) {
+	tests := []struct {
+		s, substr string
+		hint      int
+		want      int
+	}{
+		{"ab ab ab", "ab", 0, 0},
+		{"ab ab ab", "ab", 4, 3},
+		{"ab ab ab", "ab", 99, 6},
+		{"日本 日本", "日本", 2, 3},
+		{"aaa", "aa", 1, 1},
+		{"abc", "x", 0, -1},
+	}
+	for _, tt := range tests {
+		if got := nearestIndex(tt.s, tt.substr, tt.hint); got != tt.want {
+			t.Errorf("nearestIndex(%q, %q, %d) = %d, want %d", tt.s, tt.substr, tt.hint, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	setupMock(t, nil)
+	MockRespond(func(msgs []Message) bool {
+		return strings.Contains(msgs[0].Content, "translation quality reviewer") &&
+			strings.Contains(msgs[0].Content, `"cart" → "购物车"`) &&
+			msgs[1].Content == "SOURCE:\nAdd to cart\n\nTRANSLATION:\n添加到车"
+	}, `{"score":55,"accuracy":60,"fluency":70,"terminology":30,"issues":[{"text":"车","category":"terminology","severity":"major","message":"Use 购物车"}]}`)
+
+	result, err := Evaluate(context.Background(), "Add to cart", "添加到车", "zh",
+		TranslateWithProvider("mock"), TranslateWithGlossary(map[string]string{"cart": "购物车"}))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if result.Score != 55 || result.Terminology != 30 || len(result.Issues) != 1 || len(result.Warnings) != 0 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if issue := result.Issues[0]; issue.Start != 3 || issue.End != 4 || issue.Severity != "major" {
+		t.Errorf("unexpected issue: %+v", issue)
+	}
+}
+
+func TestRankTranslations(t *testing.T) {
+	setupMock(t, nil)
+	MockRespond(func(msgs []Message) bool {
+		return strings.Contains(msgs[1].Content, "TRANSLATION 3:\nC")
+	}, `{"results":[{"score":70,"accuracy":70,"fluency":70,"terminology":70},{"score":90,"accuracy":90,"fluency":90,"terminology":90},{"score":70}]}`)
+
+	ranked, err := RankTranslations(context.Background(), "src", []string{"A", "B", "C"}, "zh", TranslateWithProvider("mock"))
+	if err != nil {
+		t.Fatalf("RankTranslations failed: %v", err)
+	}
+	var order []string
+	for _, r := range ranked {
+		order = append(order, r.Text)
+	}
+	if strings.Join(order, "") != "BAC" || ranked[0].Index != 1 || ranked[0].Score != 90 {
+		t.Errorf("expected B, A, C with ties in input order, got %v", order)
+	}
+	if len(ranked[2].Warnings) != 3 {
+		t.Errorf("expected warnings for the partial result, got %q", ranked[2].Warnings)
+	}
+
+	if _, err := RankTranslations(context.Background(), "src", []string{"A", "B"}, "zh", TranslateWithProvider("mock")); err == nil {
+		t.Error("expected count mismatch error")
+	}
+	if ranked, err := RankTranslations(context.Background(), "src", nil, "zh"); err != nil || len(ranked) != 0 {
+		t.Errorf("empty input should return no results, got %v, %v", ranked, err)
+	}
+}
+
+func TestExecuteBest(t *testing.T) {
+	setupMock(t, nil)
+	// Each candidate answer is served once
+	for _, answer := range []string{"购物车 A", "购物车 B", "购物车 C"} {
+		var used atomic.Bool
+		MockRespond(func(msgs []Message) bool {
+			return strings.HasPrefix(msgs[1].Content, "Translate:") && used.CompareAndSwap(false, true)
+		}, answer)
+	}
+	var (
+		mu      sync.Mutex
+		grading string
+	)
+	MockRespond(func(msgs []Message) bool {
+		if !strings.Contains(msgs[0].Content, "translation quality reviewer") {
+			return false
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		grading = msgs[1].Content
+		return true
+	}, `{"results":[{"score":60,"accuracy":60,"fluency":60,"terminology":60},{"score":95,"accuracy":95,"fluency":95,"terminology":95},{"score":80,"accuracy":80,"fluency":80,"terminology":80}]}`)
+
+	text, grade, err := NewRequest("Add to cart").UseProvider("mock").Translate("zh").ExecuteBest(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("ExecuteBest failed: %v", err)
+	}
+	// The second candidate in the grading prompt scored best
+	_, second, _ := strings.Cut(grading, "TRANSLATION 2:\n")
+	second, _, _ = strings.Cut(second, "\n")
+	if text != second || grade.Score != 95 {
+		t.Errorf("expected the top-ranked %q, got %q (%+v)", second, text, grade)
+	}
+
+	if _, _, err := NewRequest("x").UseProvider("mock").Polish().ExecuteBest(context.Background(), 2); err == nil {
+		t.Error("expected error without a Translate task")
+	}
+	if _, _, err := NewRequest("x").UseProvider("mock").Translate("zh").ExecuteBest(context.Background(), 0); err == nil {
+		t.Error("expected error for n < 1")
+	}
+}
+
+func TestExecuteBestModeratesRedactedInput(t *testing.T) {
+	setupMock(t, nil)
+	MockRespond(func(msgs []Message) bool { return true }, "写信给 ⟦EMAIL_1⟧")
+	var moderated atomic.Value
+	MockRespond(func(msgs []Message) bool {
+		if !isModerationPrompt(msgs) {
+			return false
+		}
+		moderated.Store(msgs[1].Content)
+		return true
+	}, `{"harassment":0,"hate":0,"sexual":0,"self-harm":0,"violence":0}`)
+	MockRespond(func(msgs []Message) bool {
+		return strings.Contains(msgs[0].Content, "translation quality reviewer")
+	}, `{"results":[{"score":90,"accuracy":90,"fluency":90,"terminology":90}]}`)
+
+	text, _, err := NewRequest("Write to ann@example.com").UseProvider("mock").
+		Translate("zh").Moderate().WithPIIRedaction().ExecuteBest(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("ExecuteBest failed: %v", err)
+	}
+	if text != "写信给 ann@example.com" {
+		t.Errorf("expected the winner restored, got %q", text)
+	}
+	if content, _ := moderated.Load().(string); !strings.Contains(content, "⟦EMAIL_1⟧") || strings.Contains(content, "ann@example.com") {
+		t.Errorf("moderation should see the redacted input, got %q", content)
+	}
+}
@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+
+	"github.com/openai/openai-go"
+	"github.com/spf13/viper"
+)
+
+// WithFallback sets the providers to try, in order, when the selected
+// provider fails with a retryable error (rate limit, 5xx, timeout).
+// Overrides the ai.fallback config list.
+//
+// Example:
+//
+//	result, err := ai.NewRequest(text).
+//	    Translate("ja").
+//	    UseProvider("openai").
+//	    WithFallback("deepseek").
+//	    ExecuteWithUsage(ctx)
+//	// result.Provider: provider that served the request
+func (r *Request) WithFallback(providers ...string) *Request {
+	r.fallback = providers
+	return r
+}
+
+// providers returns the chain to try: the selected provider followed by the
+// WithFallback providers, or ai.fallback when none are set
+func (r *Request) providers() []string {
+	primary := r.provider
+	if primary == "" {
+		primary = getDefaultProvider()
+	}
+	fallback := r.fallback
+	if fallback == nil {
+		fallback = viper.GetStringSlice("ai.fallback")
+	}
+
+	chain := []string{primary}
+	for _, p := range fallback {
+		if p != "" && !slices.Contains(chain, p) {
+			chain = append(chain, p)
+		}
+	}
+	return chain
+}
+
+// chat sends messages to each provider of the chain in turn, moving on only
+// after a retryable error. chatOpts builds the options for each client.
+func (r *Request) chat(ctx context.Context, messages []Message, chatOpts func(*Client) []ChatOption) (*ChatResult, error) {
+	chain := r.providers()
+
+	var errs []error
+	for _, p := range chain {
+		client, err := getClient(p)
+		if err == nil {
+			var result *ChatResult
+			result, err = client.ChatWithUsage(ctx, messages, chatOpts(client)...)
+			if err == nil {
+				return result, nil
+			}
+			if !retryable(ctx, err) {
+				errs = append(errs, fmt.Errorf("%s: %w", p, err))
+				break
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p, err))
+	}
+
+	if len(chain) == 1 {
+		return nil, errors.Unwrap(errs[0])
+	}
+	return nil, fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
+
+// retryable reports whether err warrants trying the next provider: rate
+// limits, server errors and timeouts, but not cancellation or expiry of ctx
+// itself
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/openai/openai-go"
+	"github.com/spf13/viper"
+)
+
+// setupFailing registers a provider named "failing" whose API answers every
+// request with status. It returns the request counter.
+func setupFailing(t *testing.T, status int) *atomic.Int32 {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After-Ms", "1")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"error":{"message":"failing","type":"server_error"}}`))
+	}))
+
+	reset := func() {
+		viper.Set("ai.providers.failing", nil)
+		clientsMux.Lock()
+		delete(clients, "failing")
+		delete(initOnce, "failing")
+		delete(initErrors, "failing")
+		clientsMux.Unlock()
+	}
+	reset()
+	t.Cleanup(func() {
+		reset()
+		server.Close()
+	})
+	viper.Set("ai.providers.failing", map[string]any{"api_key": "k", "base_url": server.URL, "model": "m"})
+	return &calls
+}
+
+func TestFallbackOnRetryableError(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		setupMock(t, nil)
+		setupFailing(t, status)
+
+		result, err := NewRequest("Hello").Translate("zh").UseProvider("failing").WithFallback("mock").ExecuteWithUsage(context.Background())
+		if err != nil {
+			t.Fatalf("status %d: expected fallback to succeed: %v", status, err)
+		}
+		if result.Provider != "mock" || result.Content != "[zh] Hello" {
+			t.Errorf("status %d: unexpected result: %+v", status, result)
+		}
+	}
+}
+
+func TestFallbackFromConfig(t *testing.T) {
+	setupMock(t, nil)
+	setupFailing(t, http.StatusBadGateway)
+	viper.Set("ai.fallback", []string{"failing", "mock"})
+	defer viper.Set("ai.fallback", nil)
+
+	result, err := NewRequest("Hello").Translate("zh").UseProvider("failing").ExecuteWithUsage(context.Background())
+	if err != nil || result.Provider != "mock" {
+		t.Fatalf("ai.fallback should be used: %+v, %v", result, err)
+	}
+}
+
+func TestFallbackNotOnClientError(t *testing.T) {
+	setupMock(t, nil)
+	calls := setupFailing(t, http.StatusBadRequest)
+
+	_, err := NewRequest("Hello").Translate("zh").UseProvider("failing").WithFallback("mock").Execute(context.Background())
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("400 must not fall back, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestFallbackNotOnCanceledContext(t *testing.T) {
+	setupMock(t, nil)
+	setupFailing(t, http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewRequest("Hello").Translate("zh").UseProvider("failing").WithFallback("mock").Execute(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled ctx must not fall back, got %v", err)
+	}
+}
+
+func TestFallbackAllFail(t *testing.T) {
+	setupMock(t, map[string]any{"error_rate_429": 1})
+	setupFailing(t, http.StatusInternalServerError)
+
+	_, err := NewRequest("Hello").Translate("zh").UseProvider("failing").WithFallback("mock", "unconfigured").Execute(context.Background())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, p := range []string{"failing:", "mock:", "unconfigured:"} {
+		if !strings.Contains(err.Error(), p) {
+			t.Errorf("error should include %q: %v", p, err)
+		}
+	}
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		t.Errorf("provider errors should be wrapped, got %v", err)
+	}
+}
@@ -5,6 +5,7 @@ go 1.24.0
 require (
 	github.com/openai/openai-go v0.1.0-alpha.44
 	github.com/spf13/viper v1.21.0
+	golang.org/x/net v0.52.0
 )
 
 require (
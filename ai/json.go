@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+const jsonInstruction = "\n\nRespond with ONLY a valid JSON value. No markdown code fences, no explanations before or after the JSON."
+
+// jsonCorrection is the follow-up sent when a response fails to unmarshal
+const jsonCorrection = "Your previous response was not valid JSON (%v). Respond again with ONLY the corrected JSON value."
+
+// ExecuteJSON runs the request in JSON mode and unmarshals the result into out
+// Tasks are optional: without any, the input is processed according to the
+// context, constraints and schema (e.g. extracting fields from free text).
+// Markdown code fences and trailing prose are ignored; if the response still
+// fails to unmarshal, the model is asked once to correct it.
+//
+// Example:
+//
+//	var contact struct {
+//	    Name  string `json:"name"`
+//	    Email string `json:"email"`
+//	}
+//	err := ai.NewRequest(signature).
+//	    WithJSONSchema(`{"type":"object","properties":{"name":{"type":"string"},"email":{"type":"string"}}}`).
+//	    ExecuteJSON(ctx, &contact)
+func (r *Request) ExecuteJSON(ctx context.Context, out any) error {
+	if r.options.redactPII {
+		return r.executeJSONRedacted(ctx, out)
+	}
+	if err := r.checkModeration(ctx); err != nil {
+		return err
+	}
+
+	var schema any
+	if r.options.jsonSchema != "" {
+		if err := json.Unmarshal([]byte(r.options.jsonSchema), &schema); err != nil {
+			return fmt.Errorf("invalid JSON schema: %w", err)
+		}
+	}
+
+	jr := *r
+	jr.options.json = true
+
+	chatOpts := func(client *Client) []ChatOption {
+		opts := []ChatOption{WithTemperature(r.options.temperature)}
+		if client.jsonSchema {
+			opts = append(opts, withResponseFormat(schema))
+		}
+		return opts
+	}
+
+	messages := jr.buildPrompt()
+	result, err := jr.chat(ctx, messages, chatOpts)
+	if err != nil {
+		return err
+	}
+	err = decodeJSON(result.Content, out)
+	if err == nil {
+		return nil
+	}
+
+	// One corrective round trip to the same provider with the invalid
+	// output in context
+	client, _ := getClient(result.Provider)
+	messages = append(messages, AssistantMessage(result.Content), UserMessage(fmt.Sprintf(jsonCorrection, err)))
+	text, err := client.Chat(ctx, messages, chatOpts(client)...)
+	if err != nil {
+		return err
+	}
+	if err := decodeJSON(text, out); err != nil {
+		return fmt.Errorf("invalid JSON response: %w", err)
+	}
+	return nil
+}
+
+// withResponseFormat sets response_format to json_schema when a schema is
+// given, json_object otherwise
+func withResponseFormat(schema any) ChatOption {
+	return func(p *openai.ChatCompletionNewParams) {
+		if schema == nil {
+			p.ResponseFormat = openai.F[openai.ChatCompletionNewParamsResponseFormatUnion](openai.ResponseFormatJSONObjectParam{
+				Type: openai.F(openai.ResponseFormatJSONObjectTypeJSONObject),
+			})
+			return
+		}
+		p.ResponseFormat = openai.F[openai.ChatCompletionNewParamsResponseFormatUnion](openai.ResponseFormatJSONSchemaParam{
+			Type: openai.F(openai.ResponseFormatJSONSchemaTypeJSONSchema),
+			JSONSchema: openai.F(openai.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   openai.F("response"),
+				Schema: openai.F(schema),
+			}),
+		})
+	}
+}
+
+// decodeJSON unmarshals the first JSON value in text into out
+// Trailing prose after the value is ignored
+func decodeJSON(text string, out any) error {
+	return json.NewDecoder(strings.NewReader(stripCodeFences(text))).Decode(out)
+}
+
+// stripCodeFences returns the body of the first markdown code block in text,
+// or text itself (trimmed) when there is none
+func stripCodeFences(text string) string {
+	text = strings.TrimSpace(text)
+	start := strings.Index(text, "```")
+	if start < 0 {
+		return text
+	}
+	body := text[start+3:]
+	end := strings.Index(body, "```")
+	if end < 0 {
+		end = len(body)
+	}
+	// Skip the info string ("json") on the opening fence line unless the
+	// whole block is on one line
+	if nl := strings.IndexByte(body[:end], '\n'); nl >= 0 {
+		return strings.TrimSpace(body[nl+1 : end])
+	}
+	return strings.TrimSpace(body[:end])
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+type contact struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func TestStripCodeFences(t *testing.T) {
+	tests := map[string]string{
+		`{"a":1}`:                 `{"a":1}`,
+		"  {\"a\":1}\n":           `{"a":1}`,
+		"```json\n{\"a\":1}\n```": `{"a":1}`,
+		"```\n[1,2]\n```":         `[1,2]`,
+		"Here you go:\n```json\n{\"a\":1}\n```\nDone.": `{"a":1}`,
+		"```{\"a\":1}```": `{"a":1}`,
+	}
+	for in, want := range tests {
+		if got := stripCodeFences(in); got != want {
+			t.Errorf("stripCodeFences(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestExecuteJSONFenced(t *testing.T) {
+	setupMock(t, nil)
+	MockRespond(func([]Message) bool { return true },
+		"```json\n{\"name\":\"Ann\",\"email\":\"ann@example.com\"}\n```\nLet me know if you need more.")
+
+	var got contact
+	if err := NewRequest("Ann <ann@example.com>").UseProvider("mock").ExecuteJSON(context.Background(), &got); err != nil {
+		t.Fatalf("ExecuteJSON failed: %v", err)
+	}
+	if got.Name != "Ann" || got.Email != "ann@example.com" {
+		t.Errorf("unexpected result: %+v", got)
+	}
+}
+
+func TestExecuteJSONRetry(t *testing.T) {
+	setupMock(t, nil)
+	MockRespond(func(msgs []Message) bool {
+		return len(msgs) == 2
+	}, `{"name": "Ann",`)
+	MockRespond(func(msgs []Message) bool {
+		last := msgs[len(msgs)-1]
+		return len(msgs) == 4 && msgs[2].Content == `{"name": "Ann",` && strings.Contains(last.Content, "not valid JSON")
+	}, `{"name":"Ann"}`)
+
+	var got contact
+	if err := NewRequest("Ann").UseProvider("mock").ExecuteJSON(context.Background(), &got); err != nil {
+		t.Fatalf("ExecuteJSON should recover after a corrective follow-up: %v", err)
+	}
+	if got.Name != "Ann" {
+		t.Errorf("unexpected result: %+v", got)
+	}
+
+	// Still invalid after the retry
+	ResetMockResponses()
+	MockRespond(func([]Message) bool { return true }, "not json")
+	if err := NewRequest("Ann").UseProvider("mock").ExecuteJSON(context.Background(), &got); err == nil {
+		t.Error("expected error when the corrected response is still invalid")
+	}
+}
+
+func TestExecuteJSONSchemaPrompt(t *testing.T) {
+	setupMock(t, nil)
+	schema := `{"type":"object","properties":{"email":{"type":"string"}}}`
+	MockRespond(func(msgs []Message) bool {
+		system := msgs[0].Content
+		return strings.Contains(system, "JSON SCHEMA") && strings.Contains(system, schema) &&
+			strings.Contains(system, "ONLY a valid JSON value") && !strings.Contains(system, "processed text")
+	}, `{"email":"ann@example.com"}`)
+
+	var got contact
+	err := NewRequest("Ann <ann@example.com>").UseProvider("mock").WithJSONSchema(schema).ExecuteJSON(context.Background(), &got)
+	if err != nil || got.Email != "ann@example.com" {
+		t.Fatalf("schema should be embedded in the system prompt, got %+v, %v", got, err)
+	}
+
+	if err := NewRequest("x").UseProvider("mock").WithJSONSchema("{").ExecuteJSON(context.Background(), &got); err == nil {
+		t.Error("expected error for an invalid schema")
+	}
+}
+
+func TestWithResponseFormat(t *testing.T) {
+	var p openai.ChatCompletionNewParams
+	withResponseFormat(nil)(&p)
+	if f, ok := p.ResponseFormat.Value.(openai.ResponseFormatJSONObjectParam); !ok || f.Type.Value != openai.ResponseFormatJSONObjectTypeJSONObject {
+		t.Errorf("expected json_object, got %#v", p.ResponseFormat.Value)
+	}
+
+	withResponseFormat(map[string]any{"type": "object"})(&p)
+	f, ok := p.ResponseFormat.Value.(openai.ResponseFormatJSONSchemaParam)
+	if !ok || f.Type.Value != openai.ResponseFormatJSONSchemaTypeJSONSchema || f.JSONSchema.Value.Schema.Value == nil {
+		t.Errorf("expected json_schema, got %#v", p.ResponseFormat.Value)
+	}
+}
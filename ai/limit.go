@@ -0,0 +1,180 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ErrRateLimitTimeout is returned when ctx ends while a call waits for the
+// provider's max_rpm or max_concurrent capacity. It wraps the ctx error.
+var ErrRateLimitTimeout = errors.New("ai: context done while waiting for provider capacity")
+
+// ProviderLoad holds the calls of a provider admitted and waiting
+type ProviderLoad struct {
+	InFlight int `json:"in_flight"` // Calls running (streams until closed or complete)
+	Queued   int `json:"queued"`    // Calls waiting for capacity
+}
+
+// ProviderStats returns the current load of provider. It is zero for
+// providers not used yet.
+func ProviderStats(provider string) ProviderLoad {
+	clientsMux.RLock()
+	client := clients[provider]
+	clientsMux.RUnlock()
+	if client == nil || client.limiter == nil {
+		return ProviderLoad{}
+	}
+	return client.limiter.stats()
+}
+
+// clock abstracts time for the limiter so tests can drive it
+type clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func())
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) AfterFunc(d time.Duration, f func()) { time.AfterFunc(d, f) }
+
+// limiter gates calls of one provider with a token bucket refilled at
+// max_rpm and a max_concurrent semaphore. Waiting calls are admitted in
+// arrival order. A zero limit is unlimited; calls are still counted.
+type limiter struct {
+	clock         clock
+	maxConcurrent int
+	rate          float64 // Tokens per second; 0 = unlimited
+	burst         float64
+
+	mu       sync.Mutex
+	tokens   float64
+	last     time.Time
+	inFlight int
+	waiters  []*waiter
+	timerSet bool // A refill dispatch is scheduled
+}
+
+type waiter struct {
+	ready    chan struct{}
+	admitted bool
+}
+
+// loadLimiter reads ai.providers.<provider>.max_rpm and .max_concurrent
+func loadLimiter(provider string) *limiter {
+	path := fmt.Sprintf("ai.providers.%s.", provider)
+	return newLimiter(viper.GetInt(path+"max_rpm"), viper.GetInt(path+"max_concurrent"), realClock{})
+}
+
+// newLimiter allows maxRPM calls per minute, bursting up to one second's
+// worth (at least one call), and maxConcurrent calls at a time
+func newLimiter(maxRPM, maxConcurrent int, c clock) *limiter {
+	l := &limiter{clock: c, maxConcurrent: maxConcurrent, last: c.Now()}
+	if maxRPM > 0 {
+		l.rate = float64(maxRPM) / 60
+		l.burst = math.Max(1, math.Ceil(l.rate))
+		l.tokens = l.burst
+	}
+	return l
+}
+
+// acquire blocks until the call may start and returns the func that ends
+// it. It returns ErrRateLimitTimeout when ctx ends first.
+func (l *limiter) acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	release = sync.OnceFunc(l.release)
+
+	l.mu.Lock()
+	if len(l.waiters) == 0 && l.admit() {
+		l.mu.Unlock()
+		return release, nil
+	}
+	w := &waiter{ready: make(chan struct{})}
+	l.waiters = append(l.waiters, w)
+	l.dispatch()
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if w.admitted {
+		l.inFlight--
+		l.dispatch()
+	} else {
+		l.waiters = slices.DeleteFunc(l.waiters, func(o *waiter) bool { return o == w })
+	}
+	return nil, fmt.Errorf("%w: %w", ErrRateLimitTimeout, ctx.Err())
+}
+
+// admit takes a concurrency slot and a token if both are available.
+// Called with mu held.
+func (l *limiter) admit() bool {
+	if l.maxConcurrent > 0 && l.inFlight >= l.maxConcurrent {
+		return false
+	}
+	if l.rate > 0 {
+		now := l.clock.Now()
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+		if l.tokens < 1 {
+			return false
+		}
+		l.tokens--
+	}
+	l.inFlight++
+	return true
+}
+
+// dispatch admits waiters from the head of the queue while capacity lasts.
+// When only a token is missing it schedules itself for the refill.
+// Called with mu held.
+func (l *limiter) dispatch() {
+	for len(l.waiters) > 0 && l.admit() {
+		w := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		w.admitted = true
+		close(w.ready)
+	}
+	if len(l.waiters) == 0 || l.timerSet || l.rate == 0 {
+		return
+	}
+	if l.maxConcurrent > 0 && l.inFlight >= l.maxConcurrent {
+		return // release dispatches
+	}
+	wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	l.timerSet = true
+	l.clock.AfterFunc(wait, func() {
+		l.mu.Lock()
+		l.timerSet = false
+		l.dispatch()
+		l.mu.Unlock()
+	})
+}
+
+func (l *limiter) release() {
+	l.mu.Lock()
+	l.inFlight--
+	l.dispatch()
+	l.mu.Unlock()
+}
+
+func (l *limiter) stats() ProviderLoad {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return ProviderLoad{InFlight: l.inFlight, Queued: len(l.waiters)}
+}
@@ -0,0 +1,194 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// fakeClock runs AfterFunc callbacks when Advance passes their deadline
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	f  func()
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timers = append(c.timers, fakeTimer{c.now.Add(d), f})
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	var due []func()
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			due = append(due, t.f)
+		}
+	}
+	c.timers = pending
+	c.mu.Unlock()
+	for _, f := range due {
+		f()
+	}
+}
+
+func TestLimiterRateOrdering(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	l := newLimiter(60, 0, clk) // 1 call per second, burst 1
+	ctx := context.Background()
+
+	if _, err := l.acquire(ctx); err != nil {
+		t.Fatalf("first call should pass: %v", err)
+	}
+
+	var order []int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := l.acquire(ctx); err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		}()
+		// Queue the callers in a known order
+		waitFor(t, func() bool { return l.stats().Queued == i+1 })
+	}
+
+	for n := 1; n <= 3; n++ {
+		clk.Advance(time.Second)
+		waitFor(t, func() bool { mu.Lock(); defer mu.Unlock(); return len(order) == n })
+	}
+	wg.Wait()
+	if fmt.Sprint(order) != "[0 1 2]" {
+		t.Errorf("callers should be admitted in arrival order, got %v", order)
+	}
+	if s := l.stats(); s.InFlight != 4 || s.Queued != 0 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+func TestLimiterTimeout(t *testing.T) {
+	l := newLimiter(0, 1, &fakeClock{})
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx)
+	if !errors.Is(err, ErrRateLimitTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected ErrRateLimitTimeout, got %v", err)
+	}
+	if s := l.stats(); s.InFlight != 1 || s.Queued != 0 {
+		t.Errorf("timed out caller should leave the queue: %+v", s)
+	}
+
+	release()
+	release() // Releasing twice frees one slot
+	if s := l.stats(); s.InFlight != 0 {
+		t.Errorf("expected no calls in flight, got %+v", s)
+	}
+}
+
+func TestProviderMaxConcurrent(t *testing.T) {
+	var running, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"c","object":"chat.completion","created":0,"model":"test",
+			"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer srv.Close()
+
+	provider := fmt.Sprintf("limited_%d", time.Now().UnixNano())
+	viper.Set("ai.providers."+provider+".base_url", srv.URL)
+	viper.Set("ai.providers."+provider+".model", "test")
+	viper.Set("ai.providers."+provider+".max_concurrent", 2)
+	client, err := getClient(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var sawQueued atomic.Bool
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Chat(context.Background(), []Message{UserMessage("hi")}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	go func() {
+		for range 50 {
+			if ProviderStats(provider).Queued > 0 {
+				sawQueued.Store(true)
+				return
+			}
+			time.Sleep(2 * time.Millisecond)
+		}
+	}()
+	wg.Wait()
+
+	if p := peak.Load(); p > 2 {
+		t.Errorf("max_concurrent 2 exceeded: %d requests at once", p)
+	}
+	if !sawQueued.Load() {
+		t.Error("expected callers queued behind max_concurrent")
+	}
+	if s := ProviderStats(provider); s != (ProviderLoad{}) {
+		t.Errorf("expected idle provider, got %+v", s)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
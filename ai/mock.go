@@ -0,0 +1,452 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/spf13/viper"
+)
+
+// ============================================
+// Mock Provider
+// ============================================
+
+// mockProvider is the provider name that selects the built-in mock.
+// It needs no API key and never touches the network.
+//
+// Configuration (all optional):
+//
+//	ai:
+//	  providers:
+//	    mock:
+//	      model: "mock"
+//	      fixtures_file: "testdata/ai_fixtures.yml"
+//	      latency: "200ms"
+//	      error_rate_429: 0.1
+//	      error_rate_500: 0.05
+//	      stream_interrupt_rate: 0.1
+//	      chunk_size: 4         # runes per streamed chunk
+//	      chunk_delay: "20ms"
+//	      seed: 42              # RNG seed for error injection
+//
+// Response resolution order:
+//  1. Responders registered with MockRespond, most recent first
+//  2. Fixtures from fixtures_file, in file order (substring match)
+//  3. Deterministic fallback: "[<lang>] <text>" for translations,
+//     "[mock] <last user message>" otherwise
+const mockProvider = "mock"
+
+// mockConfig holds the ai.providers.mock.* settings.
+type mockConfig struct {
+	Model               string
+	Fixtures            []mockFixture
+	Latency             time.Duration
+	ErrorRate429        float64
+	ErrorRate500        float64
+	StreamInterruptRate float64
+	ChunkSize           int
+	ChunkDelay          time.Duration
+	Seed                uint64
+}
+
+// mockFixture maps a prompt substring to a canned response.
+type mockFixture struct {
+	Match    string `mapstructure:"match"`
+	Response string `mapstructure:"response"`
+}
+
+type mockResponder struct {
+	matcher  func(messages []Message) bool
+	response string
+}
+
+var (
+	mockResponders    []mockResponder
+	mockRespondersMux sync.RWMutex
+)
+
+// MockRespond registers a canned response for the mock provider. Responders
+// are checked before fixtures, most recently registered first, so a test can
+// override a broader responder registered earlier.
+//
+// Example:
+//
+//	ai.MockRespond(func(msgs []ai.Message) bool {
+//	    return strings.Contains(msgs[len(msgs)-1].Content, "refund")
+//	}, "Refunds take 3-5 business days.")
+func MockRespond(matcher func(messages []Message) bool, response string) {
+	mockRespondersMux.Lock()
+	defer mockRespondersMux.Unlock()
+	mockResponders = append(mockResponders, mockResponder{matcher: matcher, response: response})
+}
+
+// ResetMockResponses removes all responders registered with MockRespond.
+func ResetMockResponses() {
+	mockRespondersMux.Lock()
+	defer mockRespondersMux.Unlock()
+	mockResponders = nil
+}
+
+// loadMockConfig reads ai.providers.mock.* and the fixtures file.
+func loadMockConfig() (*mockConfig, error) {
+	path := "ai.providers." + mockProvider
+	cfg := &mockConfig{
+		Model:               viper.GetString(path + ".model"),
+		Latency:             viper.GetDuration(path + ".latency"),
+		ErrorRate429:        viper.GetFloat64(path + ".error_rate_429"),
+		ErrorRate500:        viper.GetFloat64(path + ".error_rate_500"),
+		StreamInterruptRate: viper.GetFloat64(path + ".stream_interrupt_rate"),
+		ChunkSize:           viper.GetInt(path + ".chunk_size"),
+		ChunkDelay:          viper.GetDuration(path + ".chunk_delay"),
+		Seed:                viper.GetUint64(path + ".seed"),
+	}
+	if cfg.Model == "" {
+		cfg.Model = mockProvider
+	}
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = 4
+	}
+
+	if file := viper.GetString(path + ".fixtures_file"); file != "" {
+		v := viper.New()
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("%s.fixtures_file: %w", path, err)
+		}
+		if err := v.UnmarshalKey("fixtures", &cfg.Fixtures); err != nil {
+			return nil, fmt.Errorf("%s.fixtures_file: %w", path, err)
+		}
+	}
+	return cfg, nil
+}
+
+// initMockProvider builds a client whose HTTP transport answers in-process.
+func initMockProvider() (*Client, error) {
+	cfg, err := loadMockConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &mockTransport{
+		cfg: cfg,
+		rng: rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)),
+	}
+	client := openai.NewClient(
+		option.WithAPIKey(mockProvider),
+		option.WithBaseURL("http://mock.invalid/v1/"),
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+		option.WithMaxRetries(0), // Injected errors surface as-is
+	)
+
+	return &Client{
+		Client:     client,
+		provider:   mockProvider,
+		model:      cfg.Model,
+		jsonSchema: true,
+	}, nil
+}
+
+// mockTransport serves chat completions without network access.
+type mockTransport struct {
+	cfg *mockConfig
+
+	mu  sync.Mutex
+	rng *rand.Rand
+	seq int
+}
+
+// roll returns the next pseudo-random number in [0, 1).
+func (m *mockTransport) roll() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rng.Float64()
+}
+
+func (m *mockTransport) nextID() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seq++
+	return fmt.Sprintf("mock-%d", m.seq)
+}
+
+func (m *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"` // String or content parts
+		} `json:"messages"`
+		Stream        bool `json:"stream"`
+		StreamOptions struct {
+			IncludeUsage bool `json:"include_usage"`
+		} `json:"stream_options"`
+	}
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &body); err != nil {
+			return mockJSON(req, http.StatusBadRequest, mockError("invalid_request_error", err.Error())), nil
+		}
+	}
+
+	if m.cfg.Latency > 0 {
+		select {
+		case <-time.After(m.cfg.Latency):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	switch r := m.roll(); {
+	case r < m.cfg.ErrorRate429:
+		return mockJSON(req, http.StatusTooManyRequests, mockError("rate_limit_error", "mock: injected rate limit")), nil
+	case r < m.cfg.ErrorRate429+m.cfg.ErrorRate500:
+		return mockJSON(req, http.StatusInternalServerError, mockError("server_error", "mock: injected server error")), nil
+	}
+
+	messages := make([]Message, len(body.Messages))
+	for i, msg := range body.Messages {
+		messages[i] = Message{Role: msg.Role, Content: mockContent(msg.Content)}
+	}
+	text := m.respond(messages)
+
+	id := m.nextID()
+	usage := mockUsage(messages, text)
+	if body.Stream {
+		interrupt := m.roll() < m.cfg.StreamInterruptRate
+		if !body.StreamOptions.IncludeUsage {
+			usage = nil
+		}
+		return m.stream(req, id, body.Model, text, usage, interrupt), nil
+	}
+
+	return mockJSON(req, http.StatusOK, map[string]any{
+		"id":      id,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   body.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]any{"role": "assistant", "content": text},
+			"finish_reason": "stop",
+		}},
+		"usage": usage,
+	}), nil
+}
+
+// mockUsage approximates token counts by word count.
+func mockUsage(messages []Message, text string) map[string]any {
+	prompt := 0
+	for _, msg := range messages {
+		prompt += len(strings.Fields(msg.Content))
+	}
+	completion := len(strings.Fields(text))
+	return map[string]any{
+		"prompt_tokens":     prompt,
+		"completion_tokens": completion,
+		"total_tokens":      prompt + completion,
+	}
+}
+
+// respond resolves the response text for a conversation.
+func (m *mockTransport) respond(messages []Message) string {
+	mockRespondersMux.RLock()
+	for i := len(mockResponders) - 1; i >= 0; i-- {
+		if mockResponders[i].matcher(messages) {
+			response := mockResponders[i].response
+			mockRespondersMux.RUnlock()
+			return response
+		}
+	}
+	mockRespondersMux.RUnlock()
+
+	for _, f := range m.cfg.Fixtures {
+		for _, msg := range messages {
+			if f.Match != "" && strings.Contains(msg.Content, f.Match) {
+				return f.Response
+			}
+		}
+	}
+
+	return mockFallback(messages)
+}
+
+// stream writes text as SSE chunks of cfg.ChunkSize runes, paced by
+// cfg.ChunkDelay, followed by a usage chunk when usage is non-nil. An
+// interrupted stream stops halfway with an error event.
+func (m *mockTransport) stream(req *http.Request, id, model, text string, usage map[string]any, interrupt bool) *http.Response {
+	pr, pw := io.Pipe()
+	chunks := splitRunes(text, m.cfg.ChunkSize)
+
+	go func() {
+		ctx := req.Context()
+		write := func(v any) error {
+			data, _ := json.Marshal(v)
+			_, err := fmt.Fprintf(pw, "data: %s\n\n", data)
+			return err
+		}
+		chunk := func(content string, finish any) map[string]any {
+			return map[string]any{
+				"id":      id,
+				"object":  "chat.completion.chunk",
+				"created": time.Now().Unix(),
+				"model":   model,
+				"choices": []map[string]any{{
+					"index":         0,
+					"delta":         map[string]any{"content": content},
+					"finish_reason": finish,
+				}},
+			}
+		}
+
+		for i, c := range chunks {
+			if interrupt && i >= len(chunks)/2 {
+				write(mockError("server_error", "mock: stream interrupted"))
+				pw.Close()
+				return
+			}
+			if i > 0 && m.cfg.ChunkDelay > 0 {
+				select {
+				case <-time.After(m.cfg.ChunkDelay):
+				case <-ctx.Done():
+					pw.CloseWithError(ctx.Err())
+					return
+				}
+			}
+			if err := write(chunk(c, nil)); err != nil {
+				return
+			}
+		}
+		write(chunk("", "stop"))
+		if usage != nil {
+			write(map[string]any{
+				"id":      id,
+				"object":  "chat.completion.chunk",
+				"created": time.Now().Unix(),
+				"model":   model,
+				"choices": []map[string]any{},
+				"usage":   usage,
+			})
+		}
+		fmt.Fprint(pw, "data: [DONE]\n\n")
+		pw.Close()
+	}()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       pr,
+		Request:    req,
+	}
+}
+
+var (
+	translateLangPattern = regexp.MustCompile(`translate to ([^.\n→]+)`)
+	batchLangPattern     = regexp.MustCompile(`^Translate each item to (.+) and return as a JSON array:`)
+	batchItemPattern     = regexp.MustCompile(`^\d+\. (.*)$`)
+)
+
+// mockFallback produces a deterministic response that keeps the structure of
+// the request, so tests can assert on it without canned responses.
+func mockFallback(messages []Message) string {
+	var system, user string
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			system = msg.Content
+		case "user":
+			user = msg.Content
+		}
+	}
+
+	// TranslateBatch: JSON array of "[lang] item"
+	if m := batchLangPattern.FindStringSubmatch(user); m != nil {
+		lang := mockLangCode(m[1])
+		items := []string{}
+		for _, line := range strings.Split(user, "\n") {
+			if item := batchItemPattern.FindStringSubmatch(line); item != nil {
+				items = append(items, fmt.Sprintf("[%s] %s", lang, item[1]))
+			}
+		}
+		data, _ := json.Marshal(items)
+		return string(data)
+	}
+
+	// Translate / TranslateTemplate: "[lang] text"
+	if text, ok := strings.CutPrefix(user, "Translate:\n\n"); ok {
+		if m := translateLangPattern.FindStringSubmatch(system); m != nil {
+			return fmt.Sprintf("[%s] %s", mockLangCode(m[1]), text)
+		}
+	}
+
+	return "[mock] " + user
+}
+
+// mockLangCode maps a prompt language name back to its code.
+func mockLangCode(name string) string {
+	name = strings.TrimSpace(name)
+	for code, full := range languageNames {
+		if full == name {
+			return code
+		}
+	}
+	return name
+}
+
+// mockContent extracts text from a string or content-parts message.
+func mockContent(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var parts []struct {
+		Text string `json:"text"`
+	}
+	json.Unmarshal(raw, &parts)
+	var b strings.Builder
+	for _, p := range parts {
+		b.WriteString(p.Text)
+	}
+	return b.String()
+}
+
+func mockError(typ, message string) map[string]any {
+	return map[string]any{"error": map[string]any{"type": typ, "message": message}}
+}
+
+func mockJSON(req *http.Request, status int, v any) *http.Response {
+	data, _ := json.Marshal(v)
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(data)),
+		Request:    req,
+	}
+}
+
+// splitRunes splits s into chunks of n runes.
+func splitRunes(s string, n int) []string {
+	runes := []rune(s)
+	var chunks []string
+	for len(runes) > 0 {
+		size := min(n, len(runes))
+		chunks = append(chunks, string(runes[:size]))
+		runes = runes[size:]
+	}
+	return chunks
+}
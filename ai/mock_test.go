@@ -0,0 +1,217 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/spf13/viper"
+)
+
+// setupMock configures the mock provider with settings under ai.providers.mock
+// and discards any previously initialized mock client.
+func setupMock(t *testing.T, settings map[string]any) *Client {
+	t.Helper()
+	reset := func() {
+		for key := range settings {
+			viper.Set("ai.providers.mock."+key, nil)
+		}
+		clientsMux.Lock()
+		delete(clients, mockProvider)
+		delete(initOnce, mockProvider)
+		delete(initErrors, mockProvider)
+		clientsMux.Unlock()
+		ResetMockResponses()
+	}
+	reset()
+	t.Cleanup(reset)
+
+	for key, value := range settings {
+		viper.Set("ai.providers.mock."+key, value)
+	}
+	return Get(mockProvider)
+}
+
+func TestMockNoConfig(t *testing.T) {
+	client := setupMock(t, nil)
+	if client.Provider() != "mock" || client.Model() != "mock" {
+		t.Errorf("unexpected client: %s/%s", client.Provider(), client.Model())
+	}
+
+	got, err := client.Chat(context.Background(), []Message{UserMessage("ping")})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if got != "[mock] ping" {
+		t.Errorf("expected echo fallback, got %q", got)
+	}
+}
+
+func TestMockMatcherPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	fixtures := filepath.Join(dir, "fixtures.yml")
+	os.WriteFile(fixtures, []byte(`fixtures:
+  - match: "refund"
+    response: "fixture: refund"
+  - match: "refund policy"
+    response: "fixture: refund policy"
+  - match: "shipping"
+    response: "fixture: shipping"
+`), 0o644)
+
+	client := setupMock(t, map[string]any{"fixtures_file": fixtures})
+	ctx := context.Background()
+	ask := func(prompt string) string {
+		t.Helper()
+		got, err := client.Chat(ctx, []Message{UserMessage(prompt)})
+		if err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+		return got
+	}
+
+	// Fixtures match in file order
+	if got := ask("what is your refund policy?"); got != "fixture: refund" {
+		t.Errorf("expected first matching fixture, got %q", got)
+	}
+
+	// Responders win over fixtures, the most recent first
+	MockRespond(func(msgs []Message) bool { return strings.Contains(msgs[0].Content, "refund") }, "responder: refund")
+	MockRespond(func(msgs []Message) bool { return strings.Contains(msgs[0].Content, "policy") }, "responder: policy")
+
+	if got := ask("what is your refund policy?"); got != "responder: policy" {
+		t.Errorf("expected latest responder, got %q", got)
+	}
+	if got := ask("refund please"); got != "responder: refund" {
+		t.Errorf("expected earlier responder, got %q", got)
+	}
+	if got := ask("shipping times"); got != "fixture: shipping" {
+		t.Errorf("expected fixture fallthrough, got %q", got)
+	}
+
+	ResetMockResponses()
+	if got := ask("refund please"); got != "fixture: refund" {
+		t.Errorf("expected fixture after reset, got %q", got)
+	}
+}
+
+func TestMockTranslateFallback(t *testing.T) {
+	setupMock(t, nil)
+	viper.Set("ai.default", mockProvider)
+	t.Cleanup(func() { viper.Set("ai.default", nil) })
+	ctx := context.Background()
+
+	for range 2 {
+		got, err := Translate(ctx, "Hello World", "zh")
+		if err != nil {
+			t.Fatalf("Translate failed: %v", err)
+		}
+		if got != "[zh] Hello World" {
+			t.Errorf("expected deterministic pseudo-translation, got %q", got)
+		}
+	}
+
+	got, err := TranslateTemplate(ctx, "<p>Hi {{.Name}}</p>", "zh-TW")
+	if err != nil || got != "[zh-TW] <p>Hi {{.Name}}</p>" {
+		t.Errorf("unexpected template translation: %q, %v", got, err)
+	}
+
+	batch, err := TranslateBatch(ctx, []string{"Hello", "Thank you"}, "ja")
+	if err != nil {
+		t.Fatalf("TranslateBatch failed: %v", err)
+	}
+	if len(batch) != 2 || batch[0] != "[ja] Hello" || batch[1] != "[ja] Thank you" {
+		t.Errorf("unexpected batch: %q", batch)
+	}
+}
+
+func TestMockErrorInjection(t *testing.T) {
+	run := func(seed int) (rateLimited, serverErrors int) {
+		client := setupMock(t, map[string]any{"error_rate_429": 0.2, "error_rate_500": 0.1, "seed": seed})
+		for range 200 {
+			_, err := client.Chat(context.Background(), []Message{UserMessage("hi")})
+			var apiErr *openai.Error
+			switch {
+			case err == nil:
+			case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests:
+				rateLimited++
+			case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusInternalServerError:
+				serverErrors++
+			default:
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		return
+	}
+
+	r1, s1 := run(7)
+	r2, s2 := run(7)
+	if r1 != r2 || s1 != s2 {
+		t.Errorf("same seed should inject the same errors: %d/%d vs %d/%d", r1, s1, r2, s2)
+	}
+	if r1 < 25 || r1 > 55 || s1 < 8 || s1 > 32 {
+		t.Errorf("error counts far from configured rates: 429=%d 500=%d of 200", r1, s1)
+	}
+}
+
+func TestMockStream(t *testing.T) {
+	client := setupMock(t, map[string]any{"chunk_size": 3, "chunk_delay": "5ms"})
+	MockRespond(func([]Message) bool { return true }, "你好，世界！Hello")
+
+	start := time.Now()
+	stream := client.ChatStream(context.Background(), []Message{UserMessage("hi")})
+	defer stream.Close()
+
+	var chunks []string
+	for {
+		chunk, err := stream.Next()
+		if err != nil {
+			t.Fatalf("stream failed: %v", err)
+		}
+		if chunk == "" {
+			if stream.Err() != nil {
+				t.Fatalf("stream error: %v", stream.Err())
+			}
+			break
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	if got := strings.Join(chunks, "|"); got != "你好，|世界！|Hel|lo" {
+		t.Errorf("unexpected chunks: %q", got)
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("chunks should be paced, stream took %v", elapsed)
+	}
+}
+
+func TestMockStreamInterrupted(t *testing.T) {
+	client := setupMock(t, map[string]any{"chunk_size": 1, "stream_interrupt_rate": 1})
+	MockRespond(func([]Message) bool { return true }, "abcdef")
+
+	stream := client.ChatStream(context.Background(), []Message{UserMessage("hi")})
+	defer stream.Close()
+
+	var received string
+	var err error
+	for {
+		var chunk string
+		chunk, err = stream.Next()
+		if err != nil || chunk == "" {
+			break
+		}
+		received += chunk
+	}
+	if received != "abc" {
+		t.Errorf("expected first half before interruption, got %q", received)
+	}
+	if err == nil || !strings.Contains(err.Error(), "stream interrupted") {
+		t.Errorf("expected interruption error, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func isModerationPrompt(msgs []Message) bool {
+	return strings.Contains(msgs[0].Content, "content safety classifier")
+}
+
+func TestModerationThresholds(t *testing.T) {
+	viper.Set("ai.moderation.thresholds.violence", 0.8)
+	defer viper.Set("ai.moderation.thresholds", nil)
+
+	scores := map[ModerationCategory]float64{
+		CategoryHarassment: 0.49,
+		CategoryHate:       0,
+		CategorySexual:     0,
+		CategorySelfHarm:   0,
+		CategoryViolence:   0.7,
+	}
+	if result := newModerationResult(scores, "p"); !result.Allowed || len(result.Flagged()) != 0 {
+		t.Errorf("scores below thresholds should be allowed: %+v", result)
+	}
+
+	scores[CategoryHarassment] = 0.5
+	scores[CategoryViolence] = 0.8
+	result := newModerationResult(scores, "p")
+	if result.Allowed {
+		t.Error("scores at thresholds should be blocked")
+	}
+	if flagged := result.Flagged(); len(flagged) != 2 || flagged[0] != CategoryHarassment || flagged[1] != CategoryViolence {
+		t.Errorf("unexpected flags: %v", flagged)
+	}
+}
+
+func TestModerateJSONFallback(t *testing.T) {
+	setupMock(t, nil)
+	MockRespond(isModerationPrompt, "```json\n{\"harassment\":0.1,\"hate\":-0.2,\"sexual\":0,\"self-harm\":0,\"violence\":1.4}\n```")
+
+	result, err := Moderate(context.Background(), "I will hurt you", TranslateWithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Moderate failed: %v", err)
+	}
+	if result.Allowed || !result.Flags[CategoryViolence] || result.Provider != "mock" {
+		t.Errorf("expected violence to be flagged: %+v", result)
+	}
+	if result.Scores[CategoryViolence] != 1 || result.Scores[CategoryHate] != 0 {
+		t.Errorf("scores should be clamped to [0, 1]: %v", result.Scores)
+	}
+
+	for _, response := range []string{"safe", `{"harassment":0,"hate":0,"sexual":0,"violence":0}`} {
+		ResetMockResponses()
+		MockRespond(isModerationPrompt, response)
+		if _, err := Moderate(context.Background(), "hello", TranslateWithProvider("mock")); err == nil {
+			t.Errorf("expected error for response %q", response)
+		}
+	}
+}
+
+func TestRequestModerate(t *testing.T) {
+	setupMock(t, nil)
+	MockRespond(isModerationPrompt, `{"harassment":0,"hate":0,"sexual":0,"self-harm":0.9,"violence":0}`)
+
+	_, err := NewRequest("Hello").Moderate().Translate("zh").UseProvider("mock").Execute(context.Background())
+	if !errors.Is(err, ErrContentBlocked) || !strings.Contains(err.Error(), "self-harm") {
+		t.Fatalf("expected ErrContentBlocked for self-harm, got %v", err)
+	}
+
+	ResetMockResponses()
+	MockRespond(isModerationPrompt, `{"harassment":0,"hate":0,"sexual":0,"self-harm":0,"violence":0}`)
+	result, err := NewRequest("Hello").Moderate().Translate("zh").UseProvider("mock").Execute(context.Background())
+	if err != nil || result != "[zh] Hello" {
+		t.Errorf("allowed content should be translated, got %q, %v", result, err)
+	}
+	if result, err := NewRequest("Hello").Moderate().UseProvider("mock").Execute(context.Background()); err != nil || result != "Hello" {
+		t.Errorf("moderation-only request should return the input, got %q, %v", result, err)
+	}
+}
+
+func TestModerateNativeEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/moderations" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"modr-1","model":"omni-moderation-latest","results":[{"flagged":true,"categories":{},
+			"category_scores":{"harassment":0.01,"hate":0.02,"hate/threatening":0.7,"sexual":0,"self-harm":0,"violence":0.1}}]}`))
+	}))
+	reset := func() {
+		viper.Set("ai.providers.moderated", nil)
+		clientsMux.Lock()
+		delete(clients, "moderated")
+		delete(initOnce, "moderated")
+		delete(initErrors, "moderated")
+		clientsMux.Unlock()
+	}
+	reset()
+	t.Cleanup(func() {
+		reset()
+		server.Close()
+	})
+	viper.Set("ai.providers.moderated", map[string]any{"api_key": "k", "base_url": server.URL, "model": "m", "moderation": true})
+
+	result, err := Moderate(context.Background(), "text", TranslateWithProvider("moderated"))
+	if err != nil {
+		t.Fatalf("Moderate failed: %v", err)
+	}
+	if result.Allowed || !result.Flags[CategoryHate] || result.Scores[CategoryHate] != 0.7 || result.Flags[CategoryViolence] {
+		t.Errorf("expected hate/threatening to flag hate: %+v", result)
+	}
+}
@@ -0,0 +1,297 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// PII kinds detected by WithPIIRedaction
+const (
+	PIIEmail = "EMAIL"
+	PIIPhone = "PHONE"
+	PIICard  = "CARD"
+	PIIIP    = "IP"
+)
+
+// Redaction is a value replaced by a placeholder before the request was sent
+type Redaction struct {
+	Kind        string `json:"kind"`
+	Placeholder string `json:"placeholder"` // e.g. ⟦EMAIL_1⟧
+	Original    string `json:"original"`
+}
+
+// RedactionReport describes what ExecuteRedacted replaced
+type RedactionReport struct {
+	Redactions []Redaction `json:"redactions"`
+	// Missing lists the placeholders the model dropped from its response;
+	// their original values are absent from the result
+	Missing []string `json:"missing,omitempty"`
+}
+
+// piiPattern detects one kind of PII; valid, if set, rejects false positives
+type piiPattern struct {
+	kind  string
+	re    *regexp.Regexp
+	valid func(string) bool
+}
+
+// builtinPIIPatterns are applied in order, so card numbers and IP addresses
+// are replaced before the looser phone pattern sees their digits
+var builtinPIIPatterns = []piiPattern{
+	{kind: PIIEmail, re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)},
+	{kind: PIICard, re: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), valid: luhnValid},
+	{kind: PIIIP, re: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`), valid: ipValid},
+	{kind: PIIIP, re: regexp.MustCompile(`(?i)(?:[0-9a-f]{1,4})?(?::[0-9a-f]{0,4}){2,7}`), valid: ipv6Valid},
+	{kind: PIIPhone, re: regexp.MustCompile(`\+[1-9]\d{6,14}\b|(?:\+\d{1,3}[ .-]?)?(?:\(\d{1,4}\)[ .-]?)?\d{2,4}(?:[ .-]\d{2,4}){1,4}\b`), valid: phoneValid},
+}
+
+// placeholderPattern matches a placeholder, tolerating spaces the model
+// may add inside the brackets
+var placeholderPattern = regexp.MustCompile(`⟦\s*([A-Z0-9_]+_\d+)\s*⟧`)
+
+const piiPreservationRule = "\n\nPLACEHOLDERS: Tokens like ⟦EMAIL_1⟧ stand for redacted values. Keep every one exactly as-is (do not translate, modify or remove them)."
+
+// WithPIIRedaction replaces emails, phone numbers, card numbers (passing the
+// Luhn check) and IP addresses in the input, context and glossary with
+// placeholders like ⟦EMAIL_1⟧ before anything is sent to the provider,
+// including moderation, and restores the original values in the response.
+// The same value always gets the same placeholder. Applies to Execute,
+// ExecuteWithUsage, ExecuteStream and ExecuteJSON; use ExecuteRedacted for
+// a report.
+//
+// Example:
+//
+//	reply, err := ai.NewRequest(ticket).
+//	    Translate("en").
+//	    WithPIIRedaction().
+//	    Execute(ctx)
+func (r *Request) WithPIIRedaction() *Request {
+	r.options.redactPII = true
+	return r
+}
+
+// WithPIIPattern redacts matches of re as kind (e.g. "ORDER_ID") in
+// addition to the built-in patterns, and enables WithPIIRedaction
+func (r *Request) WithPIIPattern(kind string, re *regexp.Regexp) *Request {
+	r.options.redactPII = true
+	r.options.piiPatterns = append(r.options.piiPatterns, piiPattern{kind: strings.ToUpper(kind), re: re})
+	return r
+}
+
+// ExecuteRedacted runs the request with WithPIIRedaction and also returns a
+// report of the values replaced and the placeholders the model dropped
+func (r *Request) ExecuteRedacted(ctx context.Context) (string, *RedactionReport, error) {
+	rr, redactions := r.redacted()
+	result, err := rr.ExecuteWithUsage(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	content, dropped := restorePII(result.Content, redactions, false)
+	// Placeholders from the context or glossary need not appear in the output
+	var missing []string
+	for _, placeholder := range dropped {
+		if strings.Contains(rr.input, placeholder) {
+			missing = append(missing, placeholder)
+		}
+	}
+	return content, &RedactionReport{Redactions: redactions, Missing: missing}, nil
+}
+
+// redacted returns a copy of r whose input, context and glossary have PII
+// replaced, with redaction turned off so it is not applied twice. A value
+// gets the same placeholder wherever it appears.
+func (r *Request) redacted() (*Request, []Redaction) {
+	rr := *r
+	rr.options.redactPII = false
+	rr.options.piiRedacted = true
+	red := newPIIRedactor(slices.Concat(builtinPIIPatterns, r.options.piiPatterns))
+	rr.input = red.redact(r.input)
+	rr.options.context = red.redact(r.options.context)
+	if r.options.glossary != nil {
+		rr.options.glossary = make(map[string]string, len(r.options.glossary))
+		for _, source := range slices.Sorted(maps.Keys(r.options.glossary)) {
+			rr.options.glossary[red.redact(source)] = red.redact(r.options.glossary[source])
+		}
+	}
+	return &rr, red.redactions
+}
+
+// executeRedacted is ExecuteWithUsage with PII redaction
+func (r *Request) executeRedacted(ctx context.Context) (*ChatResult, error) {
+	rr, redactions := r.redacted()
+	result, err := rr.ExecuteWithUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	restored := *result
+	restored.Content, _ = restorePII(result.Content, redactions, false)
+	return &restored, nil
+}
+
+// executeJSONRedacted is ExecuteJSON with PII redaction; values are
+// restored as JSON strings before unmarshalling
+func (r *Request) executeJSONRedacted(ctx context.Context, out any) error {
+	rr, redactions := r.redacted()
+	var raw json.RawMessage
+	if err := rr.ExecuteJSON(ctx, &raw); err != nil {
+		return err
+	}
+	restored, _ := restorePII(string(raw), redactions, true)
+	return json.Unmarshal([]byte(restored), out)
+}
+
+// redactPII replaces the matches of patterns in text with placeholders
+func redactPII(text string, patterns []piiPattern) (string, []Redaction) {
+	red := newPIIRedactor(patterns)
+	text = red.redact(text)
+	return text, red.redactions
+}
+
+// piiRedactor replaces PII with placeholders, numbering them across all the
+// texts it redacts
+type piiRedactor struct {
+	patterns   []piiPattern
+	byValue    map[string]string // kind + value -> placeholder
+	counts     map[string]int
+	redactions []Redaction
+}
+
+func newPIIRedactor(patterns []piiPattern) *piiRedactor {
+	return &piiRedactor{
+		patterns: patterns,
+		byValue:  make(map[string]string),
+		counts:   make(map[string]int),
+	}
+}
+
+// redact replaces the matches of the patterns in text with placeholders
+func (p *piiRedactor) redact(text string) string {
+	for _, pat := range p.patterns {
+		text = pat.re.ReplaceAllStringFunc(text, func(match string) string {
+			if pat.valid != nil && !pat.valid(match) {
+				return match
+			}
+			key := pat.kind + "\x00" + match
+			if placeholder, ok := p.byValue[key]; ok {
+				return placeholder
+			}
+			p.counts[pat.kind]++
+			placeholder := fmt.Sprintf("⟦%s_%d⟧", pat.kind, p.counts[pat.kind])
+			p.byValue[key] = placeholder
+			p.redactions = append(p.redactions, Redaction{Kind: pat.kind, Placeholder: placeholder, Original: match})
+			return placeholder
+		})
+	}
+	return text
+}
+
+// restorePII replaces the placeholders in text with the original values,
+// JSON-escaped if inJSON, and returns the placeholders not found
+func restorePII(text string, redactions []Redaction, inJSON bool) (string, []string) {
+	if len(redactions) == 0 {
+		return text, nil
+	}
+	originals := make(map[string]string, len(redactions))
+	for _, red := range redactions {
+		value := red.Original
+		if inJSON {
+			b, _ := json.Marshal(value)
+			value = string(b[1 : len(b)-1])
+		}
+		originals[strings.Trim(red.Placeholder, "⟦⟧")] = value
+	}
+
+	found := make(map[string]bool)
+	text = placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := placeholderPattern.FindStringSubmatch(match)[1]
+		value, ok := originals[name]
+		if !ok {
+			return match
+		}
+		found[name] = true
+		return value
+	})
+
+	var missing []string
+	for _, red := range redactions {
+		if !found[strings.Trim(red.Placeholder, "⟦⟧")] {
+			missing = append(missing, red.Placeholder)
+		}
+	}
+	return text, missing
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func ipValid(s string) bool {
+	return net.ParseIP(s) != nil
+}
+
+// ipv6Valid rejects a bare "::", which is valid but usually just punctuation
+func ipv6Valid(s string) bool {
+	return s != "::" && ipValid(s)
+}
+
+// isoDate matches dates the loose phone pattern would otherwise catch
+var isoDate = regexp.MustCompile(`^\d{4}[-.]\d{1,2}[-.]\d{1,2}$`)
+
+// phoneValid requires 7 to 15 digits, as E.164 does, and rejects dates
+func phoneValid(s string) bool {
+	digits := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+	return digits >= 7 && digits <= 15 && !isoDate.MatchString(s)
+}
+
+// piiRestorer restores placeholders in streamed content, holding back a
+// trailing partial placeholder until the next chunk completes it
+type piiRestorer struct {
+	redactions []Redaction
+	pending    string
+}
+
+// push returns the restored text that is safe to emit
+func (p *piiRestorer) push(delta string) string {
+	text := p.pending + delta
+	p.pending = ""
+	if i := strings.LastIndex(text, "⟦"); i >= 0 && !strings.Contains(text[i:], "⟧") && len(text)-i < 64 {
+		text, p.pending = text[:i], text[i:]
+	}
+	restored, _ := restorePII(text, p.redactions, false)
+	return restored
+}
+
+// flush returns the text held back at the end of the stream
+func (p *piiRestorer) flush() string {
+	text := p.pending
+	p.pending = ""
+	restored, _ := restorePII(text, p.redactions, false)
+	return restored
+}
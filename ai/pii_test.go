@@ -0,0 +1,201 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedactPII(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"Mail ann@example.com or ann@example.com", "Mail ⟦EMAIL_1⟧ or ⟦EMAIL_1⟧"},
+		{"a.b+tag@mail.example.co.uk, bob@example.org", "⟦EMAIL_1⟧, ⟦EMAIL_2⟧"},
+		{"Call +14155552671 or (415) 555-2671", "Call ⟦PHONE_1⟧ or ⟦PHONE_2⟧"},
+		{"手机 138 0013 8000，电话 +86 10-6552-9988", "手机 ⟦PHONE_1⟧，电话 ⟦PHONE_2⟧"},
+		{"Card 4111 1111 1111 1111 and 4111-1111-1111-1111", "Card ⟦CARD_1⟧ and ⟦CARD_2⟧"},
+		{"Not a card 4111 1111 1111 1112", "Not a card 4111 1111 1111 1112"},
+		{"From 192.168.0.1 and 2001:db8::1, not 999.1.1.1", "From ⟦IP_1⟧ and ⟦IP_2⟧, not 999.1.1.1"},
+		{"On 2024-01-15 at 10:30:00, order 12345 :: done", "On 2024-01-15 at 10:30:00, order 12345 :: done"},
+	}
+	for _, tt := range tests {
+		got, _ := redactPII(tt.input, builtinPIIPatterns)
+		if got != tt.want {
+			t.Errorf("redactPII(%q)\n got %q\nwant %q", tt.input, got, tt.want)
+		}
+	}
+
+	_, redactions := redactPII("ann@example.com, +14155552671, ann@example.com", builtinPIIPatterns)
+	if fmt.Sprint(redactions) != "[{EMAIL ⟦EMAIL_1⟧ ann@example.com} {PHONE ⟦PHONE_1⟧ +14155552671}]" {
+		t.Errorf("unexpected redactions: %v", redactions)
+	}
+}
+
+func TestRestorePII(t *testing.T) {
+	input := `Ann <ann@example.com> paid with 4111111111111111 from 10.0.0.1, call +14155552671`
+	redacted, redactions := redactPII(input, builtinPIIPatterns)
+	if strings.ContainsAny(redacted, "@") || len(redactions) != 4 {
+		t.Fatalf("unexpected redaction: %q %v", redacted, redactions)
+	}
+
+	// Round trip
+	if got, missing := restorePII(redacted, redactions, false); got != input || len(missing) != 0 {
+		t.Errorf("round trip failed: %q, missing %v", got, missing)
+	}
+
+	// The model reorders placeholders, adds spaces and drops one
+	response := "Rufen Sie ⟦ PHONE_1 ⟧ an. ⟦EMAIL_1⟧ zahlte von ⟦IP_1⟧. ⟦UNKNOWN_1⟧"
+	got, missing := restorePII(response, redactions, false)
+	if got != "Rufen Sie +14155552671 an. ann@example.com zahlte von 10.0.0.1. ⟦UNKNOWN_1⟧" {
+		t.Errorf("unexpected restore: %q", got)
+	}
+	if fmt.Sprint(missing) != "[⟦CARD_1⟧]" {
+		t.Errorf("expected dropped card placeholder, got %v", missing)
+	}
+
+	// JSON-escaped values
+	redactions = []Redaction{{Kind: "NAME", Placeholder: "⟦NAME_1⟧", Original: `O"Neil`}}
+	if got, _ := restorePII(`{"name":"⟦NAME_1⟧"}`, redactions, true); got != `{"name":"O\"Neil"}` {
+		t.Errorf("unexpected JSON restore: %s", got)
+	}
+}
+
+func TestLuhnValid(t *testing.T) {
+	for number, want := range map[string]bool{
+		"4111 1111 1111 1111": true,
+		"5500-0000-0000-0004": true,
+		"378282246310005":     true,
+		"4111 1111 1111 1112": false,
+		"1234567890123":       false,
+	} {
+		if got := luhnValid(number); got != want {
+			t.Errorf("luhnValid(%q) = %v, want %v", number, got, want)
+		}
+	}
+}
+
+func TestExecuteRedacted(t *testing.T) {
+	setupMock(t, nil)
+	var sent []string
+	MockRespond(func(msgs []Message) bool {
+		for _, m := range msgs {
+			sent = append(sent, m.Content)
+		}
+		return false
+	}, "")
+
+	input := "Order #A-1024 for ann@example.com, card 4111 1111 1111 1111"
+	result, report, err := NewRequest(input).
+		Translate("de").
+		UseProvider("mock").
+		WithPIIPattern("order", regexp.MustCompile(`#[A-Z]-\d+`)).
+		ExecuteRedacted(context.Background())
+	if err != nil {
+		t.Fatalf("ExecuteRedacted failed: %v", err)
+	}
+	if result != "[de] "+input {
+		t.Errorf("values not restored: %q", result)
+	}
+	if len(report.Redactions) != 3 || report.Redactions[2].Placeholder != "⟦ORDER_1⟧" || len(report.Missing) != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	prompt := strings.Join(sent, "\n")
+	for _, value := range []string{"ann@example.com", "4111", "A-1024"} {
+		if strings.Contains(prompt, value) {
+			t.Errorf("%q sent to the provider", value)
+		}
+	}
+	if !strings.Contains(prompt, "⟦EMAIL_1⟧") || !strings.Contains(prompt, "PLACEHOLDERS:") {
+		t.Errorf("expected placeholders and preservation rule in prompt:\n%s", prompt)
+	}
+
+	// The model drops a placeholder
+	MockRespond(func([]Message) bool { return true }, "Bestellung ⟦ORDER_1⟧ bezahlt")
+	result, report, _ = NewRequest(input).Translate("de").UseProvider("mock").
+		WithPIIPattern("ORDER", regexp.MustCompile(`#[A-Z]-\d+`)).
+		ExecuteRedacted(context.Background())
+	if result != "Bestellung #A-1024 bezahlt" || fmt.Sprint(report.Missing) != "[⟦EMAIL_1⟧ ⟦CARD_1⟧]" {
+		t.Errorf("unexpected result %q, missing %v", result, report.Missing)
+	}
+}
+
+func TestPIIRedactionContextAndGlossary(t *testing.T) {
+	setupMock(t, nil)
+	var sent []string
+	MockRespond(func(msgs []Message) bool {
+		for _, m := range msgs {
+			sent = append(sent, m.Content)
+		}
+		return true
+	}, "Antwort an ⟦EMAIL_1⟧ über ⟦IP_1⟧")
+
+	result, report, err := NewRequest("Reply to the customer").
+		Translate("de").
+		UseProvider("mock").
+		WithContext("Customer ann@example.com, from 10.0.0.1").
+		WithGlossary(map[string]string{"ann@example.com": "ann@example.com"}).
+		ExecuteRedacted(context.Background())
+	if err != nil {
+		t.Fatalf("ExecuteRedacted failed: %v", err)
+	}
+	prompt := strings.Join(sent, "\n")
+	for _, value := range []string{"ann@example.com", "10.0.0.1"} {
+		if strings.Contains(prompt, value) {
+			t.Errorf("%q sent to the provider:\n%s", value, prompt)
+		}
+	}
+	if !strings.Contains(prompt, "CONTEXT: Customer ⟦EMAIL_1⟧, from ⟦IP_1⟧") || !strings.Contains(prompt, `"⟦EMAIL_1⟧" → "⟦EMAIL_1⟧"`) {
+		t.Errorf("expected context and glossary redacted with shared placeholders:\n%s", prompt)
+	}
+	if result != "Antwort an ann@example.com über 10.0.0.1" {
+		t.Errorf("values not restored: %q", result)
+	}
+	if len(report.Redactions) != 2 || len(report.Missing) != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestPIIRedactionExecuteModes(t *testing.T) {
+	setupMock(t, map[string]any{"chunk_size": 3})
+	input := "Reply to ann@example.com from 10.0.0.1"
+	ctx := context.Background()
+
+	// Execute
+	got, err := NewRequest(input).Polish().UseProvider("mock").WithPIIRedaction().Execute(ctx)
+	if err != nil || got != "[mock] Polish this text:\n\n"+input {
+		t.Errorf("Execute: %q, %v", got, err)
+	}
+
+	// Stream: placeholders are split across chunks
+	stream, err := NewRequest(input).Polish().UseProvider("mock").WithPIIRedaction().ExecuteStream(ctx)
+	if err != nil {
+		t.Fatalf("ExecuteStream failed: %v", err)
+	}
+	var streamed strings.Builder
+	for {
+		chunk, err := stream.Next()
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		if chunk == "" {
+			break
+		}
+		streamed.WriteString(chunk)
+	}
+	if streamed.String() != "[mock] Polish this text:\n\n"+input {
+		t.Errorf("stream: %q", streamed.String())
+	}
+
+	// JSON
+	MockRespond(func([]Message) bool { return true }, `{"email":"⟦EMAIL_1⟧","ip":"⟦IP_1⟧"}`)
+	var contact struct{ Email, IP string }
+	if err := NewRequest(input).UseProvider("mock").WithPIIRedaction().ExecuteJSON(ctx, &contact); err != nil {
+		t.Fatalf("ExecuteJSON failed: %v", err)
+	}
+	if contact.Email != "ann@example.com" || contact.IP != "10.0.0.1" {
+		t.Errorf("ExecuteJSON: %+v", contact)
+	}
+}
@@ -0,0 +1,227 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/spf13/viper"
+)
+
+// promptContextMarker separates the input part of a prompt template from the
+// part rendered into the request context
+const promptContextMarker = "{{/* context */}}"
+
+// promptExtensions are the file types LoadPrompts reads
+var promptExtensions = []string{".tmpl", ".md"}
+
+// prompt is a registered template and the top-level variables it uses
+type prompt struct {
+	input   *template.Template
+	context *template.Template // nil without a context block
+	vars    []string
+}
+
+var (
+	prompts       = make(map[string]*prompt)
+	promptsMux    sync.RWMutex
+	promptsOnce   sync.Once
+	promptsDirErr error
+)
+
+// RegisterPrompt parses tmpl as a text/template and registers it under name,
+// replacing any prompt of the same name. Text after a {{/* context */}} line
+// is rendered into the request context instead of the input.
+//
+// Example:
+//
+//	ai.RegisterPrompt("support_reply", `Reply to this ticket from {{.Customer}}:
+//	{{.Ticket}}
+//	{{/* context */}}
+//	Product: {{.Product}}`)
+func RegisterPrompt(name, tmpl string) error {
+	p := &prompt{}
+	input, context, hasContext := strings.Cut(tmpl, promptContextMarker)
+
+	var err error
+	if p.input, err = parsePrompt(name, input, &p.vars); err != nil {
+		return err
+	}
+	if hasContext {
+		if p.context, err = parsePrompt(name+"/context", context, &p.vars); err != nil {
+			return err
+		}
+	}
+
+	promptsMux.Lock()
+	prompts[name] = p
+	promptsMux.Unlock()
+	return nil
+}
+
+// LoadPrompts registers every .tmpl and .md file in dir under its file name
+// without the extension (e.g. "support_reply.md" → "support_reply").
+// Prompts in ai.prompts_dir are loaded automatically on first use of
+// FromPrompt.
+func LoadPrompts(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read prompts dir: %w", err)
+	}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || !slices.Contains(promptExtensions, ext) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read prompt: %w", err)
+		}
+		if err := RegisterPrompt(strings.TrimSuffix(entry.Name(), ext), string(data)); err != nil {
+			return fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// FromPrompt renders the named prompt with data into a new Request. It fails
+// with the list of missing variables when data lacks any top-level field the
+// template uses.
+//
+// Example:
+//
+//	req, err := ai.FromPrompt("support_reply", map[string]any{
+//	    "Customer": "Alice", "Ticket": ticket, "Product": "Kaitu",
+//	})
+//	if err != nil {
+//	    return err
+//	}
+//	reply, err := req.Polish().Execute(ctx)
+func FromPrompt(name string, data any) (*Request, error) {
+	promptsOnce.Do(func() {
+		if dir := viper.GetString("ai.prompts_dir"); dir != "" {
+			promptsDirErr = LoadPrompts(dir)
+		}
+	})
+
+	promptsMux.RLock()
+	p := prompts[name]
+	promptsMux.RUnlock()
+	if p == nil {
+		if promptsDirErr != nil {
+			return nil, fmt.Errorf("prompt %q not found (ai.prompts_dir: %v)", name, promptsDirErr)
+		}
+		return nil, fmt.Errorf("prompt %q not found", name)
+	}
+
+	if missing := missingVars(p.vars, data); len(missing) > 0 {
+		return nil, fmt.Errorf("prompt %q: missing template variables: %s", name, strings.Join(missing, ", "))
+	}
+
+	input, err := renderPrompt(p.input, data)
+	if err != nil {
+		return nil, fmt.Errorf("prompt %q: %w", name, err)
+	}
+	r := NewRequest(input)
+	if p.context != nil {
+		context, err := renderPrompt(p.context, data)
+		if err != nil {
+			return nil, fmt.Errorf("prompt %q: %w", name, err)
+		}
+		r.WithContext(context)
+	}
+	return r, nil
+}
+
+// parsePrompt parses text and appends the top-level variables it uses to vars
+func parsePrompt(name, text string, vars *[]string) (*template.Template, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	if t.Tree != nil {
+		collectVars(t.Tree.Root, vars)
+	}
+	return t, nil
+}
+
+// collectVars walks the parse tree for fields of the data passed to the
+// template ({{.Name}}). Bodies of range and with blocks are skipped since
+// dot is rebound there.
+func collectVars(node parse.Node, vars *[]string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectVars(child, vars)
+		}
+	case *parse.ActionNode:
+		collectVars(n.Pipe, vars)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				collectVars(arg, vars)
+			}
+		}
+	case *parse.FieldNode:
+		if !slices.Contains(*vars, n.Ident[0]) {
+			*vars = append(*vars, n.Ident[0])
+		}
+	case *parse.IfNode:
+		collectVars(n.Pipe, vars)
+		collectVars(n.List, vars)
+		collectVars(n.ElseList, vars)
+	case *parse.RangeNode:
+		collectVars(n.Pipe, vars)
+		collectVars(n.ElseList, vars)
+	case *parse.WithNode:
+		collectVars(n.Pipe, vars)
+		collectVars(n.ElseList, vars)
+	}
+}
+
+// missingVars returns the vars that data has no key, field or method for.
+// Data of other kinds is left to the template to validate.
+func missingVars(vars []string, data any) []string {
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	var missing []string
+	for _, name := range vars {
+		switch v.Kind() {
+		case reflect.Invalid, reflect.Pointer:
+			missing = append(missing, name)
+		case reflect.Map:
+			if v.Type().Key().Kind() == reflect.String && !v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())).IsValid() {
+				missing = append(missing, name)
+			}
+		case reflect.Struct:
+			if !v.FieldByName(name).IsValid() && !v.MethodByName(name).IsValid() && !reflect.ValueOf(data).MethodByName(name).IsValid() {
+				missing = append(missing, name)
+			}
+		}
+	}
+	return missing
+}
+
+// renderPrompt executes t and trims surrounding whitespace
+func renderPrompt(t *template.Template, data any) (string, error) {
+	var out strings.Builder
+	if err := t.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %w", err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
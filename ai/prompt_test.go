@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestFromPromptVariables(t *testing.T) {
+	err := RegisterPrompt("test_reply", "Reply to {{.Customer}}:\n{{.Ticket}}\n{{if .VIP}}Prioritize.{{end}}{{range .Tags}}#{{.}} {{end}}\n"+
+		promptContextMarker+"\nProduct: {{.Product}}\n")
+	if err != nil {
+		t.Fatalf("RegisterPrompt failed: %v", err)
+	}
+
+	r, err := FromPrompt("test_reply", map[string]any{
+		"Customer": "Alice", "Ticket": "App crashes", "VIP": true, "Tags": []string{"ios"}, "Product": "Kaitu",
+	})
+	if err != nil {
+		t.Fatalf("FromPrompt failed: %v", err)
+	}
+	if r.input != "Reply to Alice:\nApp crashes\nPrioritize.#ios" || r.options.context != "Product: Kaitu" {
+		t.Errorf("unexpected request: input %q, context %q", r.input, r.options.context)
+	}
+
+	_, err = FromPrompt("test_reply", map[string]any{"Customer": "Alice", "VIP": false})
+	if err == nil || !strings.Contains(err.Error(), "missing template variables: Ticket, Tags, Product") {
+		t.Errorf("expected missing variables error, got %v", err)
+	}
+
+	type ticket struct {
+		Customer, Ticket string
+		VIP              bool
+		Tags             []string
+	}
+	if _, err := FromPrompt("test_reply", &ticket{Customer: "Bob"}); err == nil || !strings.HasSuffix(err.Error(), ": Product") {
+		t.Errorf("expected Product to be missing from struct, got %v", err)
+	}
+	if _, err := FromPrompt("test_reply", nil); err == nil {
+		t.Error("expected error for nil data")
+	}
+
+	if err := RegisterPrompt("broken", "{{.Name"); err == nil {
+		t.Error("expected parse error")
+	}
+	if _, err := FromPrompt("unknown", nil); err == nil {
+		t.Error("expected error for unknown prompt")
+	}
+}
+
+func TestLoadPrompts(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "test_greeting.tmpl"), []byte("Hello {{.Name}}"), 0o644)
+	os.WriteFile(filepath.Join(dir, "test_summary.md"), []byte("# Summary\n\n{{.Text}}\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("{{.Ignored"), 0o644)
+
+	viper.Set("ai.prompts_dir", dir)
+	defer viper.Set("ai.prompts_dir", nil)
+	promptsOnce = sync.Once{}
+	t.Cleanup(func() { promptsOnce = sync.Once{} })
+
+	r, err := FromPrompt("test_greeting", map[string]string{"Name": "Bob"})
+	if err != nil || r.input != "Hello Bob" {
+		t.Fatalf("unexpected greeting: %v, %v", r, err)
+	}
+	r, err = FromPrompt("test_summary", map[string]string{"Text": "body"})
+	if err != nil || r.input != "# Summary\n\nbody" {
+		t.Fatalf("unexpected summary: %v, %v", r, err)
+	}
+	if _, err := FromPrompt("notes", nil); err == nil {
+		t.Error(".txt files should not be loaded")
+	}
+
+	os.WriteFile(filepath.Join(dir, "test_bad.md"), []byte("{{if}}"), 0o644)
+	if err := LoadPrompts(dir); err == nil || !strings.Contains(err.Error(), "test_bad.md") {
+		t.Errorf("expected parse error naming the file, got %v", err)
+	}
+	if err := LoadPrompts(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing dir")
+	}
+}
@@ -3,6 +3,8 @@ package ai
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 )
 
@@ -21,6 +23,7 @@ type Request struct {
 	tasks    []task
 	options  requestOptions
 	provider string
+	fallback []string
 }
 
 // task represents a single processing task
@@ -94,6 +97,16 @@ type requestOptions struct {
 	maxLength   int
 	isTemplate  bool
 	format      string // output format hint
+	json        bool   // set by ExecuteJSON
+	jsonSchema  string // JSON Schema for ExecuteJSON output
+	chunkSize   int    // max runes per chunk; 0 = no chunking
+	concurrency int    // chunks processed in parallel
+	progress    func(done, total int)
+	noCache     bool // set by WithCacheDisabled
+	moderate    bool // set by Moderate
+	redactPII   bool // set by WithPIIRedaction
+	piiPatterns []piiPattern
+	piiRedacted bool // input holds PII placeholders
 }
 
 // NewRequest creates a new request builder with the input text
@@ -234,6 +247,14 @@ func (r *Request) WithFormat(format string) *Request {
 	return r
 }
 
+// WithJSONSchema constrains ExecuteJSON output to a JSON Schema
+// The schema is embedded in the system prompt and, when the provider
+// supports it, sent as the response_format of the request
+func (r *Request) WithJSONSchema(schema string) *Request {
+	r.options.jsonSchema = schema
+	return r
+}
+
 // UseProvider specifies which AI provider to use
 func (r *Request) UseProvider(provider string) *Request {
 	r.provider = provider
@@ -246,16 +267,37 @@ func (r *Request) UseProvider(provider string) *Request {
 
 // Execute runs the request and returns the result
 func (r *Request) Execute(ctx context.Context) (string, error) {
-	if len(r.tasks) == 0 {
-		return "", fmt.Errorf("no tasks specified, use Translate(), Polish(), etc.")
+	result, err := r.ExecuteWithUsage(ctx)
+	if err != nil {
+		return "", err
 	}
+	return result.Content, nil
+}
 
-	client := Get(r.provider)
-	messages := r.buildPrompt()
+// ExecuteWithUsage runs the request and returns the result with the provider
+// and model that served it and its token usage
+func (r *Request) ExecuteWithUsage(ctx context.Context) (*ChatResult, error) {
+	if len(r.tasks) == 0 && !r.options.moderate {
+		return nil, fmt.Errorf("no tasks specified, use Translate(), Polish(), etc.")
+	}
+	if r.options.redactPII {
+		return r.executeRedacted(ctx)
+	}
 
-	opts := []ChatOption{WithTemperature(r.options.temperature)}
+	if err := r.checkModeration(ctx); err != nil {
+		return nil, err
+	}
+	if len(r.tasks) == 0 {
+		return &ChatResult{Content: r.input}, nil
+	}
+
+	if r.options.chunkSize > 0 {
+		return r.executeChunked(ctx)
+	}
 
-	return client.Chat(ctx, messages, opts...)
+	return r.cachedChat(ctx, r.buildPrompt(), func(*Client) []ChatOption {
+		return []ChatOption{WithTemperature(r.options.temperature)}
+	})
 }
 
 // ExecuteStream runs the request and returns a streaming response
@@ -263,6 +305,19 @@ func (r *Request) ExecuteStream(ctx context.Context) (*Stream, error) {
 	if len(r.tasks) == 0 {
 		return nil, fmt.Errorf("no tasks specified")
 	}
+	if r.options.redactPII {
+		rr, redactions := r.redacted()
+		stream, err := rr.ExecuteStream(ctx)
+		if err != nil {
+			return nil, err
+		}
+		stream.restorer = &piiRestorer{redactions: redactions}
+		return stream, nil
+	}
+
+	if err := r.checkModeration(ctx); err != nil {
+		return nil, err
+	}
 
 	client := Get(r.provider)
 	messages := r.buildPrompt()
@@ -291,6 +346,9 @@ func (r *Request) buildPrompt() []Message {
 	if r.options.isTemplate {
 		system.WriteString(templatePreservationRules)
 	}
+	if r.options.piiRedacted {
+		system.WriteString(piiPreservationRule)
+	}
 
 	// Add style instructions
 	if r.options.style != "" {
@@ -310,8 +368,8 @@ func (r *Request) buildPrompt() []Message {
 	// Add glossary
 	if len(r.options.glossary) > 0 {
 		system.WriteString("\n\nTERM GLOSSARY (use these exact translations/terms):\n")
-		for source, target := range r.options.glossary {
-			system.WriteString(fmt.Sprintf("• %q → %q\n", source, target))
+		for _, source := range slices.Sorted(maps.Keys(r.options.glossary)) {
+			system.WriteString(fmt.Sprintf("• %q → %q\n", source, r.options.glossary[source]))
 		}
 	}
 
@@ -339,7 +397,14 @@ func (r *Request) buildPrompt() []Message {
 	}
 
 	// Final instruction
-	system.WriteString("\n\nRespond with ONLY the processed text. No explanations, no quotes around the result.")
+	if r.options.json {
+		system.WriteString(jsonInstruction)
+		if r.options.jsonSchema != "" {
+			system.WriteString(fmt.Sprintf("\n\nJSON SCHEMA (the response must validate against it):\n%s", r.options.jsonSchema))
+		}
+	} else {
+		system.WriteString("\n\nRespond with ONLY the processed text. No explanations, no quotes around the result.")
+	}
 
 	// Build user message
 	user.WriteString(r.buildUserPrompt())
@@ -382,7 +447,10 @@ func (r *Request) buildTaskInstructions() string {
 		}
 	}
 
-	if len(parts) == 1 {
+	switch len(parts) {
+	case 0:
+		return ""
+	case 1:
 		return fmt.Sprintf("Your task is to %s.\n", parts[0])
 	}
 
@@ -465,6 +533,7 @@ TEMPLATE PRESERVATION RULES (CRITICAL):
    • Template variables: {{.Name}}, {{.OrderID}}, ${variable}, {name}, %s, etc.
    • URLs: https://..., http://...
    • Email addresses: user@example.com
+   • Redaction placeholders: ⟦EMAIL_1⟧, ⟦PHONE_2⟧, etc.
    • Code snippets and technical identifiers
 2. Only process the human-readable text content
 3. Maintain original structure and formatting`
@@ -0,0 +1,783 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ============================================
+// URL Summarization
+// ============================================
+
+// PageSummary is the result of SummarizeURL
+type PageSummary struct {
+	URL          string `json:"url"`           // Final URL after redirects
+	CanonicalURL string `json:"canonical_url"` // <link rel="canonical">, falls back to URL
+	Title        string `json:"title"`
+	Summary      string `json:"summary"`
+	WordCount    int    `json:"word_count"`   // Words in the extracted content (CJK characters count as words)
+	ContentHash  string `json:"content_hash"` // SHA-256 of the extracted content
+	Cached       bool   `json:"-"`
+}
+
+// NotHTMLError is returned when the fetched page is not an HTML document
+type NotHTMLError struct {
+	URL         string
+	ContentType string
+}
+
+func (e *NotHTMLError) Error() string {
+	return fmt.Sprintf("%s is not an HTML page (content type %q)", e.URL, e.ContentType)
+}
+
+// PageTooLargeError is returned when the page body exceeds the configured limit
+type PageTooLargeError struct {
+	URL   string
+	Limit int64
+}
+
+func (e *PageTooLargeError) Error() string {
+	return fmt.Sprintf("%s exceeds the %d byte size limit", e.URL, e.Limit)
+}
+
+var (
+	// ErrDomainNotAllowed is returned when the URL (or a redirect target) fails the domain allowlist/denylist
+	ErrDomainNotAllowed = errors.New("domain not allowed")
+	// ErrDisallowedByRobots is returned when robots.txt disallows fetching the URL
+	ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+	// ErrNoReadableContent is returned when no text could be extracted from the page
+	ErrNoReadableContent = errors.New("no readable content")
+)
+
+const (
+	defaultSummarizeTimeout   = 15 * time.Second
+	defaultSummarizeMaxBody   = 2 << 20 // 2 MiB
+	defaultSummarizeUserAgent = "qtoolkit-summarizer/1.0"
+	defaultSummarizeCacheTTL  = 24 * time.Hour
+	maxRobotsBodySize         = 512 << 10
+	summaryChunkRunes         = 6000
+	summaryCachePrefix        = "ai:summary:"
+)
+
+type summarizeURLOptions struct {
+	timeout     time.Duration
+	maxBodySize int64
+	userAgent   string
+	allowed     []string
+	denied      []string
+	provider    string
+	cacheTTL    time.Duration
+}
+
+// SummarizeURLOption configures SummarizeURL
+type SummarizeURLOption func(*summarizeURLOptions)
+
+// SummarizeWithTimeout sets the timeout for fetching the page (default 15s)
+func SummarizeWithTimeout(d time.Duration) SummarizeURLOption {
+	return func(o *summarizeURLOptions) { o.timeout = d }
+}
+
+// SummarizeWithMaxBodySize sets the maximum page size in bytes (default 2 MiB)
+func SummarizeWithMaxBodySize(n int64) SummarizeURLOption {
+	return func(o *summarizeURLOptions) { o.maxBodySize = n }
+}
+
+// SummarizeWithUserAgent sets the User-Agent sent to the site and matched against robots.txt
+func SummarizeWithUserAgent(ua string) SummarizeURLOption {
+	return func(o *summarizeURLOptions) { o.userAgent = ua }
+}
+
+// SummarizeWithAllowedDomains only allows these domains and their subdomains
+func SummarizeWithAllowedDomains(domains ...string) SummarizeURLOption {
+	return func(o *summarizeURLOptions) { o.allowed = append(o.allowed, domains...) }
+}
+
+// SummarizeWithDeniedDomains rejects these domains and their subdomains
+func SummarizeWithDeniedDomains(domains ...string) SummarizeURLOption {
+	return func(o *summarizeURLOptions) { o.denied = append(o.denied, domains...) }
+}
+
+// SummarizeWithProvider specifies which AI provider to use
+func SummarizeWithProvider(provider string) SummarizeURLOption {
+	return func(o *summarizeURLOptions) { o.provider = provider }
+}
+
+// SummarizeWithCacheTTL sets how long summaries are cached (default 24h, 0 disables)
+func SummarizeWithCacheTTL(ttl time.Duration) SummarizeURLOption {
+	return func(o *summarizeURLOptions) { o.cacheTTL = ttl }
+}
+
+// SummarizeURL fetches a web page, extracts its main content and summarizes it.
+//
+// The fetch honours robots.txt, the domain allowlist/denylist, a timeout and a
+// body size limit. Navigation, headers, footers, scripts and styles are dropped
+// and the densest text block is kept. Long content is summarized per chunk and
+// the partial summaries combined.
+//
+// Summaries are cached keyed by URL and content hash, so a page is only
+// re-summarized when its content changes. Caching is skipped when no cache is
+// set with SetCache.
+//
+// Example:
+//
+//	summary, err := ai.SummarizeURL(ctx, "https://example.com/blog/post",
+//		ai.SummarizeWithAllowedDomains("example.com"))
+func SummarizeURL(ctx context.Context, rawURL string, opts ...SummarizeURLOption) (*PageSummary, error) {
+	o := &summarizeURLOptions{
+		timeout:     defaultSummarizeTimeout,
+		maxBodySize: defaultSummarizeMaxBody,
+		userAgent:   defaultSummarizeUserAgent,
+		cacheTTL:    defaultSummarizeCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid url %q", rawURL)
+	}
+	if !o.domainAllowed(u.Hostname()) {
+		return nil, fmt.Errorf("%w: %s", ErrDomainNotAllowed, u.Hostname())
+	}
+
+	client := &http.Client{
+		Timeout: o.timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if !o.domainAllowed(req.URL.Hostname()) {
+				return fmt.Errorf("%w: %s", ErrDomainNotAllowed, req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+
+	if !robotsAllowed(ctx, client, u, o.userAgent) {
+		return nil, fmt.Errorf("%w: %s", ErrDisallowedByRobots, rawURL)
+	}
+
+	body, finalURL, err := fetchPage(ctx, client, u, o)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("parse html: %w", err)
+	}
+	page := extractReadable(doc, finalURL)
+	if page.text == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNoReadableContent, rawURL)
+	}
+
+	hash := sha256.Sum256([]byte(page.text))
+	result := &PageSummary{
+		URL:          finalURL.String(),
+		CanonicalURL: page.canonical,
+		Title:        page.title,
+		WordCount:    countWords(page.text),
+		ContentHash:  hex.EncodeToString(hash[:]),
+	}
+	if result.CanonicalURL == "" {
+		result.CanonicalURL = result.URL
+	}
+
+	key := summaryCacheKey(rawURL, result.ContentHash)
+	if o.cacheTTL > 0 {
+		var cached PageSummary
+		if cacheGet(key, &cached) {
+			cached.Cached = true
+			return &cached, nil
+		}
+	}
+
+	result.Summary, err = summarizeText(ctx, page.title, page.text, o.provider)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.cacheTTL > 0 {
+		cacheSet(key, result, int(o.cacheTTL.Seconds()))
+	}
+	return result, nil
+}
+
+// domainAllowed checks host against the denylist first, then the allowlist (if any)
+func (o *summarizeURLOptions) domainAllowed(host string) bool {
+	for _, d := range o.denied {
+		if matchDomain(host, d) {
+			return false
+		}
+	}
+	if len(o.allowed) == 0 {
+		return true
+	}
+	for _, d := range o.allowed {
+		if matchDomain(host, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDomain reports whether host is domain or a subdomain of it
+func matchDomain(host, domain string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// fetchPage downloads the page, enforcing content type and size limits
+func fetchPage(ctx context.Context, client *http.Client, u *url.URL, o *summarizeURLOptions) (string, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("User-Agent", o.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("fetch %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("fetch %s: status %d", u, resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return "", nil, &NotHTMLError{URL: u.String(), ContentType: contentType}
+	}
+
+	if resp.ContentLength > o.maxBodySize {
+		return "", nil, &PageTooLargeError{URL: u.String(), Limit: o.maxBodySize}
+	}
+	// Content-Length may be absent or wrong, so read one byte past the limit to detect overflow
+	data, err := io.ReadAll(io.LimitReader(resp.Body, o.maxBodySize+1))
+	if err != nil {
+		return "", nil, fmt.Errorf("read %s: %w", u, err)
+	}
+	if int64(len(data)) > o.maxBodySize {
+		return "", nil, &PageTooLargeError{URL: u.String(), Limit: o.maxBodySize}
+	}
+
+	return string(data), resp.Request.URL, nil
+}
+
+// ============================================
+// robots.txt
+// ============================================
+
+// robotsAllowed fetches /robots.txt and checks the path against the rules for
+// userAgent. A missing or unreadable robots.txt allows everything.
+func robotsAllowed(ctx context.Context, client *http.Client, u *url.URL, userAgent string) bool {
+	robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL.String(), nil)
+	if err != nil {
+		return true
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return true
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return robotsRulesAllow(io.LimitReader(resp.Body, maxRobotsBodySize), userAgent, path)
+}
+
+// robotsRulesAllow applies the most specific matching group (our product token,
+// else "*") using longest-match precedence, Allow winning ties.
+func robotsRulesAllow(r io.Reader, userAgent, path string) bool {
+	type rule struct {
+		allow  bool
+		prefix string
+	}
+
+	product := strings.ToLower(userAgent)
+	if i := strings.IndexAny(product, "/ "); i >= 0 {
+		product = product[:i]
+	}
+
+	var (
+		agents             []string
+		inRules            bool
+		specific, wildcard []rule
+		matchSpecific      bool
+		matchWildcard      bool
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A user-agent line after rules starts a new group
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if key == "disallow" && value == "" {
+				continue // Empty Disallow allows everything
+			}
+			rl := rule{allow: key == "allow", prefix: value}
+			for _, a := range agents {
+				switch {
+				case a == "*":
+					wildcard = append(wildcard, rl)
+					matchWildcard = true
+				case product != "" && strings.Contains(product, a):
+					specific = append(specific, rl)
+					matchSpecific = true
+				}
+			}
+		}
+	}
+
+	rules := wildcard
+	if matchSpecific {
+		rules = specific
+	} else if !matchWildcard {
+		return true
+	}
+
+	best, allowed := -1, true
+	for _, rl := range rules {
+		if !robotsPathMatch(path, rl.prefix) {
+			continue
+		}
+		if n := len(rl.prefix); n > best || (n == best && rl.allow) {
+			best, allowed = n, rl.allow
+		}
+	}
+	return allowed
+}
+
+// robotsPathMatch matches a robots.txt path pattern supporting '*' and a trailing '$'
+func robotsPathMatch(path, pattern string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	if anchored && rest != "" {
+		// '*' before '$' may absorb the remainder
+		return len(parts) > 1 && strings.HasSuffix(path, parts[len(parts)-1])
+	}
+	return true
+}
+
+// ============================================
+// Readable Content Extraction
+// ============================================
+
+type readablePage struct {
+	title     string
+	canonical string
+	text      string
+}
+
+// boilerplateTags are removed before scoring
+var boilerplateTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+	atom.Form: true, atom.Iframe: true, atom.Svg: true, atom.Button: true,
+	atom.Select: true, atom.Dialog: true,
+}
+
+// boilerplateRoles are ARIA landmark roles that never hold the main content
+var boilerplateRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true,
+	"complementary": true, "search": true, "dialog": true,
+}
+
+// boilerplateHints are class/id fragments of common page chrome
+var boilerplateHints = []string{
+	"sidebar", "cookie", "breadcrumb", "comment", "share", "social",
+	"related", "advert", "promo", "newsletter", "subscribe", "menu",
+}
+
+// blockTags end a line when rendering text
+var blockTags = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Li: true, atom.Ul: true, atom.Ol: true, atom.Pre: true, atom.Blockquote: true,
+	atom.Table: true, atom.Tr: true, atom.Td: true, atom.Th: true, atom.Br: true,
+	atom.Figure: true, atom.Figcaption: true, atom.Dd: true, atom.Dt: true, atom.Hr: true,
+}
+
+// extractReadable returns the title, canonical URL and main text of a document
+func extractReadable(doc *html.Node, base *url.URL) readablePage {
+	var page readablePage
+	var ogTitle string
+	var body *html.Node
+
+	var walkHead func(n *html.Node)
+	walkHead = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Title:
+				if page.title == "" {
+					page.title = collapseSpace(nodeText(n))
+				}
+			case atom.Link:
+				if hasToken(attr(n, "rel"), "canonical") && page.canonical == "" {
+					if ref, err := base.Parse(attr(n, "href")); err == nil && attr(n, "href") != "" {
+						page.canonical = ref.String()
+					}
+				}
+			case atom.Meta:
+				if attr(n, "property") == "og:title" {
+					ogTitle = collapseSpace(attr(n, "content"))
+				}
+			case atom.Body:
+				body = n
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walkHead(c)
+		}
+	}
+	walkHead(doc)
+
+	if page.title == "" {
+		page.title = ogTitle
+	}
+	if body == nil {
+		return page
+	}
+
+	removeBoilerplate(body)
+
+	best := densestBlock(body)
+	if best == nil {
+		best = body
+	}
+	page.text = renderText(best)
+	return page
+}
+
+// removeBoilerplate detaches navigation, scripts and other page chrome in place
+func removeBoilerplate(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && isBoilerplate(c)) {
+			n.RemoveChild(c)
+		} else {
+			removeBoilerplate(c)
+		}
+		c = next
+	}
+}
+
+func isBoilerplate(n *html.Node) bool {
+	if boilerplateTags[n.DataAtom] || boilerplateRoles[attr(n, "role")] {
+		return true
+	}
+	// Never drop the semantic content containers on a class hint
+	if n.DataAtom == atom.Article || n.DataAtom == atom.Main || n.DataAtom == atom.Body {
+		return false
+	}
+	hints := strings.ToLower(attr(n, "class") + " " + attr(n, "id"))
+	for _, h := range boilerplateHints {
+		if strings.Contains(hints, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// densestBlock scores each paragraph's parent (and half to the grandparent)
+// by link-discounted text length and returns the highest scoring container.
+func densestBlock(body *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && isParagraph(n) {
+			text := collapseSpace(nodeText(n))
+			length := utf8.RuneCountInString(text)
+			if length >= 25 {
+				linkLen := utf8.RuneCountInString(collapseSpace(linkText(n)))
+				score := float64(length) * (1 - float64(linkLen)/float64(length))
+				if p := n.Parent; p != nil {
+					scores[p] += score
+					if gp := p.Parent; gp != nil {
+						scores[gp] += score / 2
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(body)
+
+	var best *html.Node
+	var bestScore float64
+	for n, s := range scores {
+		if s > bestScore {
+			best, bestScore = n, s
+		}
+	}
+	return best
+}
+
+// isParagraph reports whether n is a text-bearing block: a p/pre/blockquote/li,
+// or a div holding text directly (common in CMS output without <p> tags)
+func isParagraph(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Blockquote, atom.Li, atom.Td:
+		return true
+	case atom.Div:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && blockTags[c.DataAtom] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// renderText converts a subtree to plain text with one paragraph per line group
+func renderText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+		case html.ElementNode:
+			if blockTags[n.DataAtom] {
+				b.WriteString("\n")
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+			if blockTags[n.DataAtom] {
+				b.WriteString("\n")
+			}
+		}
+	}
+	walk(n)
+
+	var paragraphs []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if line = collapseSpace(line); line != "" {
+			paragraphs = append(paragraphs, line)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+func linkText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			b.WriteString(nodeText(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasToken(list, token string) bool {
+	for _, f := range strings.Fields(strings.ToLower(list)) {
+		if f == token {
+			return true
+		}
+	}
+	return false
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// countWords counts whitespace-separated words, treating each CJK character as a word
+func countWords(text string) int {
+	count := 0
+	for _, field := range strings.Fields(text) {
+		inWord := false
+		for _, r := range field {
+			if unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+				unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) {
+				count++
+				inWord = false
+				continue
+			}
+			if !inWord {
+				count++
+				inWord = true
+			}
+		}
+	}
+	return count
+}
+
+// ============================================
+// Summarization
+// ============================================
+
+// summarizeText summarizes text in one request, or per chunk plus a combining
+// pass when the text exceeds summaryChunkRunes
+func summarizeText(ctx context.Context, title, text, provider string) (string, error) {
+	desc := "Main content of a web page"
+	if title != "" {
+		desc += " titled " + fmt.Sprintf("%q", title)
+	}
+
+	chunks := chunkParagraphs(text, summaryChunkRunes)
+	if len(chunks) == 1 {
+		return NewRequest(text).Summarize().WithContext(desc).UseProvider(provider).Execute(ctx)
+	}
+
+	partials := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		partial, err := NewRequest(chunk).
+			Summarize().
+			WithContext(fmt.Sprintf("%s (part %d of %d)", desc, i+1, len(chunks))).
+			UseProvider(provider).
+			Execute(ctx)
+		if err != nil {
+			return "", fmt.Errorf("summarize chunk %d: %w", i+1, err)
+		}
+		partials = append(partials, partial)
+	}
+
+	return NewRequest(strings.Join(partials, "\n\n")).
+		Summarize().
+		WithContext(desc + ", given as summaries of consecutive parts").
+		UseProvider(provider).
+		Execute(ctx)
+}
+
+// chunkParagraphs groups paragraphs into chunks of at most limit runes,
+// splitting single oversized paragraphs on rune boundaries
+func chunkParagraphs(text string, limit int) []string {
+	var chunks []string
+	var cur strings.Builder
+	curLen := 0
+
+	flush := func() {
+		if curLen > 0 {
+			chunks = append(chunks, cur.String())
+			cur.Reset()
+			curLen = 0
+		}
+	}
+
+	for _, p := range strings.Split(text, "\n\n") {
+		runes := []rune(p)
+		for len(runes) > limit {
+			flush()
+			chunks = append(chunks, string(runes[:limit]))
+			runes = runes[limit:]
+		}
+		if curLen > 0 && curLen+2+len(runes) > limit {
+			flush()
+		}
+		if curLen > 0 {
+			cur.WriteString("\n\n")
+			curLen += 2
+		}
+		cur.WriteString(string(runes))
+		curLen += len(runes)
+	}
+	flush()
+	return chunks
+}
+
+// ============================================
+// Cache (SetCache, optional)
+// ============================================
+
+func summaryCacheKey(rawURL, contentHash string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return summaryCachePrefix + hex.EncodeToString(sum[:8]) + ":" + contentHash[:16]
+}
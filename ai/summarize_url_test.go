@@ -0,0 +1,294 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/net/html"
+)
+
+const boilerplatePage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Choosing a Queue</title>
+  <link rel="canonical" href="/articles/choosing-a-queue">
+  <style>body { color: red }</style>
+  <script>var tracking = "lots of tracking code that should never be summarized";</script>
+</head>
+<body>
+  <header><a href="/">Home</a> <a href="/blog">Blog</a> <a href="/about">About us and our long company history</a></header>
+  <nav><ul><li><a href="/a">Products and services we offer to enterprise customers</a></li><li><a href="/b">Pricing plans for every team size</a></li></ul></nav>
+  <div class="cookie-banner">We use cookies to improve your experience on this website, please accept them.</div>
+  <div id="layout">
+    <div class="sidebar"><p>Subscribe to our newsletter for weekly updates on all the things we do.</p></div>
+    <article>
+      <h1>Choosing a Queue</h1>
+      <p>Message queues decouple producers from consumers so that bursts of traffic do not overwhelm downstream services.</p>
+      <p>Redis streams are a good fit when you already operate Redis and need ordered, replayable delivery.</p>
+      <p>SQS trades ordering guarantees for managed scaling, which suits spiky workloads without an operations team.</p>
+    </article>
+  </div>
+  <footer><p>Copyright 2026 Example Corp. All rights reserved. Terms of service and privacy policy apply.</p></footer>
+</body>
+</html>`
+
+// newFakeAIProvider registers an OpenAI-compatible provider backed by httptest
+// and returns its name, a counter of chat completion calls and the received prompts.
+func newFakeAIProvider(t *testing.T) (string, *atomic.Int32, *[]string) {
+	t.Helper()
+
+	var calls atomic.Int32
+	var prompts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content json.RawMessage `json:"content"` // String or content parts
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Messages) > 0 {
+			prompts = append(prompts, string(req.Messages[len(req.Messages)-1].Content))
+		}
+
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"c%d","object":"chat.completion","created":0,"model":"test",
+			"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"summary %d"}}]}`, n, n)
+	}))
+	t.Cleanup(srv.Close)
+
+	provider := fmt.Sprintf("fake_%d", time.Now().UnixNano())
+	viper.Set("ai.providers."+provider+".base_url", srv.URL)
+	viper.Set("ai.providers."+provider+".model", "test")
+	return provider, &calls, &prompts
+}
+
+func TestExtractReadable(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(boilerplatePage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com/post?id=1")
+
+	page := extractReadable(doc, base)
+
+	if page.title != "Choosing a Queue" {
+		t.Errorf("title = %q", page.title)
+	}
+	if page.canonical != "https://example.com/articles/choosing-a-queue" {
+		t.Errorf("canonical = %q", page.canonical)
+	}
+	if !strings.Contains(page.text, "Redis streams are a good fit") {
+		t.Errorf("main content missing: %q", page.text)
+	}
+	for _, junk := range []string{"tracking", "Pricing plans", "cookies", "newsletter", "Copyright", "company history", "color: red"} {
+		if strings.Contains(page.text, junk) {
+			t.Errorf("boilerplate %q leaked into %q", junk, page.text)
+		}
+	}
+	if strings.Count(page.text, "\n\n") < 2 {
+		t.Errorf("expected paragraphs to be separated, got %q", page.text)
+	}
+}
+
+func TestSummarizeURL(t *testing.T) {
+	provider, calls, prompts := newFakeAIProvider(t)
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, boilerplatePage)
+	}))
+	defer site.Close()
+
+	summary, err := SummarizeURL(context.Background(), site.URL+"/post",
+		SummarizeWithProvider(provider), SummarizeWithCacheTTL(0))
+	if err != nil {
+		t.Fatalf("SummarizeURL failed: %v", err)
+	}
+
+	if summary.Summary != "summary 1" || calls.Load() != 1 {
+		t.Errorf("unexpected summary %q after %d calls", summary.Summary, calls.Load())
+	}
+	if summary.Title != "Choosing a Queue" {
+		t.Errorf("title = %q", summary.Title)
+	}
+	if summary.CanonicalURL != site.URL+"/articles/choosing-a-queue" {
+		t.Errorf("canonical = %q", summary.CanonicalURL)
+	}
+	if summary.WordCount < 40 || summary.WordCount > 60 {
+		t.Errorf("word count = %d", summary.WordCount)
+	}
+	if strings.Contains((*prompts)[0], "newsletter") || !strings.Contains((*prompts)[0], "SQS trades ordering") {
+		t.Errorf("prompt should contain only the article: %q", (*prompts)[0])
+	}
+}
+
+func TestSummarizeURLGuards(t *testing.T) {
+	provider, calls, _ := newFakeAIProvider(t)
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+		case "/data.json":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"a":1}`)
+		case "/big":
+			w.Header().Set("Content-Type", "text/html")
+			// Streamed without Content-Length so the limit is enforced while reading
+			w.(http.Flusher).Flush()
+			fmt.Fprint(w, "<html><body><p>"+strings.Repeat("x", 4096)+"</p></body></html>")
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, boilerplatePage)
+		}
+	}))
+	defer site.Close()
+
+	ctx := context.Background()
+	opts := []SummarizeURLOption{SummarizeWithProvider(provider), SummarizeWithCacheTTL(0), SummarizeWithMaxBodySize(1024)}
+
+	_, err := SummarizeURL(ctx, site.URL+"/data.json", opts...)
+	var notHTML *NotHTMLError
+	if !errors.As(err, &notHTML) || notHTML.ContentType != "application/json" {
+		t.Errorf("expected NotHTMLError, got %v", err)
+	}
+
+	_, err = SummarizeURL(ctx, site.URL+"/big", opts...)
+	var tooLarge *PageTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 1024 {
+		t.Errorf("expected PageTooLargeError, got %v", err)
+	}
+
+	_, err = SummarizeURL(ctx, site.URL+"/private/page", opts...)
+	if !errors.Is(err, ErrDisallowedByRobots) {
+		t.Errorf("expected ErrDisallowedByRobots, got %v", err)
+	}
+
+	_, err = SummarizeURL(ctx, site.URL+"/post", append(opts, SummarizeWithAllowedDomains("example.com"))...)
+	if !errors.Is(err, ErrDomainNotAllowed) {
+		t.Errorf("expected ErrDomainNotAllowed for allowlist, got %v", err)
+	}
+
+	_, err = SummarizeURL(ctx, site.URL+"/post", append(opts, SummarizeWithDeniedDomains("127.0.0.1"))...)
+	if !errors.Is(err, ErrDomainNotAllowed) {
+		t.Errorf("expected ErrDomainNotAllowed for denylist, got %v", err)
+	}
+
+	if calls.Load() != 0 {
+		t.Errorf("guards should fail before calling the AI provider, got %d calls", calls.Load())
+	}
+}
+
+func TestSummarizeURLCache(t *testing.T) {
+	useMemCache(t)
+
+	provider, calls, _ := newFakeAIProvider(t)
+
+	content := "<p>The first version of this article explains how caching summaries saves tokens.</p>"
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><head><title>Cache</title></head><body><nav>Menu</nav>%s</body></html>", content)
+	}))
+	defer site.Close()
+
+	ctx := context.Background()
+	pageURL := fmt.Sprintf("%s/cache/%d", site.URL, time.Now().UnixNano())
+	opts := []SummarizeURLOption{SummarizeWithProvider(provider), SummarizeWithCacheTTL(time.Minute)}
+
+	first, err := SummarizeURL(ctx, pageURL, opts...)
+	if err != nil {
+		t.Fatalf("SummarizeURL failed: %v", err)
+	}
+
+	second, err := SummarizeURL(ctx, pageURL, opts...)
+	if err != nil {
+		t.Fatalf("SummarizeURL failed: %v", err)
+	}
+	if !second.Cached || second.Summary != first.Summary || calls.Load() != 1 {
+		t.Errorf("expected cached summary, got %+v after %d calls", second, calls.Load())
+	}
+
+	// Changed content produces a new hash and is summarized again
+	content = "<p>The second version of this article was rewritten, so its summary must be refreshed.</p>"
+	third, err := SummarizeURL(ctx, pageURL, opts...)
+	if err != nil {
+		t.Fatalf("SummarizeURL failed: %v", err)
+	}
+
+	if third.Cached || third.ContentHash == first.ContentHash || third.Summary != "summary 2" {
+		t.Errorf("expected fresh summary for changed content, got %+v", third)
+	}
+}
+
+func TestRobotsRulesAllow(t *testing.T) {
+	robots := `
+# comment
+User-agent: *
+Disallow: /admin
+Allow: /admin/public
+
+User-agent: qtoolkit-summarizer
+Disallow: /drafts/
+Disallow: /*.pdf$
+`
+	tests := []struct {
+		ua, path string
+		want     bool
+	}{
+		{"other-bot/1.0", "/admin/settings", false},
+		{"other-bot/1.0", "/admin/public/page", true},
+		{"other-bot/1.0", "/drafts/x", true},
+		{"qtoolkit-summarizer/1.0", "/drafts/x", false},
+		{"qtoolkit-summarizer/1.0", "/admin/settings", true}, // Specific group replaces "*"
+		{"qtoolkit-summarizer/1.0", "/files/report.pdf", false},
+		{"qtoolkit-summarizer/1.0", "/files/report.pdf?x=1", true},
+	}
+	for _, tt := range tests {
+		if got := robotsRulesAllow(strings.NewReader(robots), tt.ua, tt.path); got != tt.want {
+			t.Errorf("robotsRulesAllow(%q, %q) = %v, want %v", tt.ua, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestChunkParagraphs(t *testing.T) {
+	text := strings.Repeat("a", 40) + "\n\n" + strings.Repeat("b", 40) + "\n\n" + strings.Repeat("c", 130)
+	chunks := chunkParagraphs(text, 100)
+
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %d: %q", len(chunks), chunks)
+	}
+	if chunks[0] != strings.Repeat("a", 40)+"\n\n"+strings.Repeat("b", 40) {
+		t.Errorf("paragraphs under the limit should share a chunk: %q", chunks[0])
+	}
+	if len(chunks[1]) != 100 || len(chunks[2]) != 30 {
+		t.Errorf("oversized paragraph should be split, got %d and %d", len(chunks[1]), len(chunks[2]))
+	}
+}
+
+func TestCountWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"hello world", 2},
+		{"  spaced   out\n\ntext ", 3},
+		{"你好世界", 4},
+		{"Go语言 rocks", 4},
+	}
+	for _, tt := range tests {
+		if got := countWords(tt.text); got != tt.want {
+			t.Errorf("countWords(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ErrTemplateCorrupted is matched (errors.Is) by the *TemplateCorruptedError
+// a ValidatedStream returns when the output lost or altered placeholders
+var ErrTemplateCorrupted = errors.New("template placeholders corrupted")
+
+// TemplateCorruptedError lists the placeholders that differ between the
+// input and the streamed output. Template variables are compared with
+// whitespace inside the braces removed, HTML tags by name only, so
+// translated attributes such as alt or title are not reported.
+type TemplateCorruptedError struct {
+	Missing []string // In the input but missing from the output
+	Altered []string // In the output but not in the input
+}
+
+func (e *TemplateCorruptedError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Altered) > 0 {
+		parts = append(parts, "altered "+strings.Join(e.Altered, ", "))
+	}
+	return fmt.Sprintf("%v: %s", ErrTemplateCorrupted, strings.Join(parts, "; "))
+}
+
+func (e *TemplateCorruptedError) Is(target error) bool {
+	return target == ErrTemplateCorrupted
+}
+
+var (
+	// templatePlaceholder matches template variables and HTML tags
+	templatePlaceholder = regexp.MustCompile(`\{\{.*?\}\}|\$\{[^}]*\}|\{[^{}\s]+\}|<[^<>]+>`)
+
+	// htmlTagName captures the name of an HTML tag, with the / of a closing tag
+	htmlTagName = regexp.MustCompile(`^<\s*(/?)\s*([a-zA-Z][a-zA-Z0-9-]*)`)
+)
+
+// maxPlaceholderLen bounds the text held back after an opening { or < while
+// waiting for the chunk that completes a placeholder
+const maxPlaceholderLen = 256
+
+// placeholderKey normalizes a placeholder for comparison
+func placeholderKey(p string) string {
+	if strings.HasPrefix(p, "<") {
+		if m := htmlTagName.FindStringSubmatch(p); m != nil {
+			return "<" + m[1] + strings.ToLower(m[2]) + ">"
+		}
+		return p
+	}
+	return strings.Join(strings.Fields(p), "")
+}
+
+// countPlaceholders returns the normalized placeholders in text with their counts
+func countPlaceholders(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, p := range templatePlaceholder.FindAllString(text, -1) {
+		counts[placeholderKey(p)]++
+	}
+	return counts
+}
+
+// textStream is the part of *Stream a ValidatedStream reads
+type textStream interface {
+	Next() (string, error)
+	Usage() Usage
+	Close() error
+}
+
+// ValidatedStream is a Stream that checks the template placeholders of the
+// output against the input. Chunks are returned as they arrive; once the
+// stream is complete Next returns a *TemplateCorruptedError if placeholders
+// were lost or altered, so the caller can retry with Execute.
+type ValidatedStream struct {
+	stream   textStream
+	expected map[string]int // Placeholders of the input
+	found    map[string]int // Placeholders of the output so far
+
+	text    strings.Builder
+	scanned int // Bytes of text already scanned for placeholders
+	done    bool
+	err     error
+}
+
+// ExecuteStreamValidated runs the request like ExecuteStream (AsTemplate is
+// implied) and validates the template variables and HTML tags of the output.
+//
+// Example:
+//
+//	stream, err := ai.NewRequest(tpl).Translate("ja").ExecuteStreamValidated(ctx)
+//	if err != nil {
+//	    return err
+//	}
+//	defer stream.Close()
+//	for {
+//	    chunk, err := stream.Next()
+//	    if errors.Is(err, ai.ErrTemplateCorrupted) {
+//	        // Discard the streamed text and fall back to Execute
+//	    }
+//	    if err != nil || chunk == "" {
+//	        break
+//	    }
+//	    send(chunk)
+//	}
+func (r *Request) ExecuteStreamValidated(ctx context.Context) (*ValidatedStream, error) {
+	tr := *r
+	tr.options.isTemplate = true
+	stream, err := tr.ExecuteStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newValidatedStream(stream, r.input), nil
+}
+
+func newValidatedStream(stream textStream, input string) *ValidatedStream {
+	return &ValidatedStream{
+		stream:   stream,
+		expected: countPlaceholders(input),
+		found:    make(map[string]int),
+	}
+}
+
+// Next returns the next chunk of content
+// Returns "" once the stream is complete, with a *TemplateCorruptedError if
+// the output placeholders differ from the input
+func (s *ValidatedStream) Next() (string, error) {
+	if s.done {
+		return "", s.err
+	}
+	chunk, err := s.stream.Next()
+	if err != nil {
+		s.done, s.err = true, err
+		return "", err
+	}
+	if chunk == "" {
+		s.scan(true)
+		s.done, s.err = true, s.validate()
+		return "", s.err
+	}
+	s.text.WriteString(chunk)
+	s.scan(false)
+	return chunk, nil
+}
+
+// Final returns the text received so far, the full output once Next has
+// returned ""
+func (s *ValidatedStream) Final() string {
+	return s.text.String()
+}
+
+// Usage returns the token usage reported by the provider
+func (s *ValidatedStream) Usage() Usage {
+	return s.stream.Usage()
+}
+
+// Close closes the stream
+func (s *ValidatedStream) Close() error {
+	return s.stream.Close()
+}
+
+// scan counts the placeholders completed since the last scan. A placeholder
+// ending at the end of the text may still grow with the next chunk, so it is
+// counted later, unless final.
+func (s *ValidatedStream) scan(final bool) {
+	text := s.text.String()
+	for s.scanned < len(text) {
+		rest := text[s.scanned:]
+		if loc := templatePlaceholder.FindStringIndex(rest); loc != nil && (final || s.scanned+loc[1] < len(text)) {
+			s.found[placeholderKey(rest[loc[0]:loc[1]])]++
+			s.scanned += loc[1]
+			continue
+		}
+		i := strings.IndexAny(rest, "{$<")
+		if final || i < 0 {
+			s.scanned = len(text)
+			return
+		}
+		if len(rest)-i > maxPlaceholderLen {
+			// Not a placeholder, move past the opening character
+			s.scanned += i + 1
+			continue
+		}
+		s.scanned += i
+		return
+	}
+}
+
+// validate compares the placeholders of the output with the input
+func (s *ValidatedStream) validate() error {
+	var e TemplateCorruptedError
+	for p, n := range s.expected {
+		if s.found[p] < n {
+			e.Missing = append(e.Missing, p)
+		}
+	}
+	for p, n := range s.found {
+		if n > s.expected[p] {
+			e.Altered = append(e.Altered, p)
+		}
+	}
+	if len(e.Missing) == 0 && len(e.Altered) == 0 {
+		return nil
+	}
+	slices.Sort(e.Missing)
+	slices.Sort(e.Altered)
+	return &e
+}
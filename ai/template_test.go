@@ -0,0 +1,165 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeStream feeds chunks to a ValidatedStream, then err (or the end)
+type fakeStream struct {
+	chunks []string
+	err    error
+	closed bool
+}
+
+func (f *fakeStream) Next() (string, error) {
+	if len(f.chunks) == 0 {
+		return "", f.err
+	}
+	chunk := f.chunks[0]
+	f.chunks = f.chunks[1:]
+	return chunk, nil
+}
+
+func (f *fakeStream) Usage() Usage { return Usage{TotalTokens: 42} }
+
+func (f *fakeStream) Close() error {
+	f.closed = true
+	return nil
+}
+
+// drain reads s to the end and returns the chunks and the final error
+func drain(t *testing.T, s *ValidatedStream) ([]string, error) {
+	t.Helper()
+	var chunks []string
+	for range 100 {
+		chunk, err := s.Next()
+		if err != nil || chunk == "" {
+			return chunks, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	t.Fatal("stream did not end")
+	return nil, nil
+}
+
+func TestValidatedStream(t *testing.T) {
+	const input = `Hello {{ .Name }}, you have <b class="n">${count}</b> messages in {inbox}.<br/>`
+	tests := []struct {
+		name    string
+		chunks  []string
+		missing []string
+		altered []string
+	}{
+		{
+			name:   "intact",
+			chunks: []string{"Hola {{.Name}}, tienes ", `<b class="x">${count}</b>`, " mensajes en {inbox}.<br>"},
+		},
+		{
+			name:   "placeholders split across chunks",
+			chunks: []string{"Hola {", "{ .Na", "me }", "}, tienes <", "b>$", "{count}<", "/b> mensajes en {in", "box}.<br", "/>"},
+		},
+		{
+			name:    "variable translated",
+			chunks:  []string{"Hola {{.Nombre}}, tienes <b>${count}</b> mensajes en {inbox}.<br/>"},
+			missing: []string{"{{.Name}}"},
+			altered: []string{"{{.Nombre}}"},
+		},
+		{
+			name:    "tag and variable dropped",
+			chunks:  []string{"Hola {{.Name}}, tienes ${count} ", "mensajes en {inbox}.<br/>"},
+			missing: []string{"</b>", "<b>"},
+		},
+		{
+			name:    "duplicated",
+			chunks:  []string{"Hola {{.Name}} {{.Name}}, tienes <b>${count}</b> mensajes en {inbox}.<br/>"},
+			altered: []string{"{{.Name}}"},
+		},
+		{
+			name:    "truncated mid placeholder",
+			chunks:  []string{"Hola {{.Name}}, tienes <b>${count}</b> mensajes en {inbox}.<br"},
+			missing: []string{"<br>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newValidatedStream(&fakeStream{chunks: tt.chunks}, input)
+			chunks, err := drain(t, s)
+			if !reflect.DeepEqual(chunks, tt.chunks) {
+				t.Errorf("chunks must pass through unchanged, got %q", chunks)
+			}
+			if s.Final() != strings.Join(tt.chunks, "") {
+				t.Errorf("unexpected final text %q", s.Final())
+			}
+			if tt.missing == nil && tt.altered == nil {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			var corrupted *TemplateCorruptedError
+			if !errors.As(err, &corrupted) || !errors.Is(err, ErrTemplateCorrupted) {
+				t.Fatalf("expected TemplateCorruptedError, got %v", err)
+			}
+			if !reflect.DeepEqual(corrupted.Missing, tt.missing) || !reflect.DeepEqual(corrupted.Altered, tt.altered) {
+				t.Errorf("got missing %q altered %q, want %q %q", corrupted.Missing, corrupted.Altered, tt.missing, tt.altered)
+			}
+			if _, again := s.Next(); again != err {
+				t.Errorf("expected the same error after the end, got %v", again)
+			}
+		})
+	}
+}
+
+func TestValidatedStreamLongText(t *testing.T) {
+	// An opening brace that never completes must not hold back scanning
+	input := "{{.A}} " + strings.Repeat("x", 10) + " {{.B}}"
+	chunks := []string{"{{.A}} {", strings.Repeat("y", maxPlaceholderLen), strings.Repeat("y", 10), " {{.B}}"}
+	s := newValidatedStream(&fakeStream{chunks: chunks}, input)
+	if _, err := drain(t, s); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if s.found["{{.A}}"] != 1 || s.found["{{.B}}"] != 1 {
+		t.Errorf("unexpected placeholders %v", s.found)
+	}
+}
+
+func TestValidatedStreamError(t *testing.T) {
+	streamErr := errors.New("connection reset")
+	fake := &fakeStream{chunks: []string{"Hola {{.Na"}, err: streamErr}
+	s := newValidatedStream(fake, "Hello {{.Name}}")
+	if _, err := drain(t, s); err != streamErr {
+		t.Fatalf("expected the stream error, got %v", err)
+	}
+	if s.Final() != "Hola {{.Na" || s.Usage().TotalTokens != 42 {
+		t.Errorf("unexpected final %q, usage %+v", s.Final(), s.Usage())
+	}
+	s.Close()
+	if !fake.closed {
+		t.Error("expected Close to close the underlying stream")
+	}
+}
+
+func TestExecuteStreamValidated(t *testing.T) {
+	setupMock(t, map[string]any{"chunk_size": 4})
+	MockRespond(func(msgs []Message) bool {
+		return strings.Contains(msgs[0].Content, templatePreservationRules)
+	}, "Hola {{.Name}}, <b>bienvenido</b>")
+
+	req := NewRequest("Hello {{.Name}}, <b>welcome</b>").UseProvider("mock").Translate("es")
+	s, err := req.ExecuteStreamValidated(context.Background())
+	if err != nil {
+		t.Fatalf("ExecuteStreamValidated failed: %v", err)
+	}
+	defer s.Close()
+	chunks, err := drain(t, s)
+	if err != nil || len(chunks) < 2 || s.Final() != "Hola {{.Name}}, <b>bienvenido</b>" {
+		t.Errorf("unexpected result %q, %v", chunks, err)
+	}
+	if req.options.isTemplate {
+		t.Error("ExecuteStreamValidated must not change the caller's request")
+	}
+}
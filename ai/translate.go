@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 )
 
@@ -126,7 +128,8 @@ func TranslateTemplate(ctx context.Context, template, targetLang string, opts ..
 }
 
 // TranslateBatch translates multiple texts in a single API call
-// More efficient than calling Translate multiple times
+// More efficient than calling Translate multiple times. With ai.cache
+// enabled each text is cached on its own, so only uncached texts are sent
 //
 // Example:
 //
@@ -144,17 +147,53 @@ func TranslateBatch(ctx context.Context, texts []string, targetLang string, opts
 		opt(r)
 	}
 
-	client := Get(r.provider)
-	prompt := buildBatchTranslatePrompt(texts, targetLang, r)
+	// Look up each text as a batch of one, so keys do not depend on the
+	// rest of the batch
+	results := make([]string, len(texts))
+	keys := make([]string, len(texts))
+	var missing []int
+	ttl := r.responseCacheTTL()
+	for i, text := range texts {
+		if ttl > 0 {
+			keys[i] = r.responseCacheKey(buildBatchTranslatePrompt([]string{text}, targetLang, r))
+		}
+		if keys[i] != "" && cacheGet(keys[i], &results[i]) {
+			cacheHits.Add(1)
+			continue
+		}
+		if keys[i] != "" {
+			cacheMisses.Add(1)
+		}
+		missing = append(missing, i)
+	}
+	if len(missing) == 0 {
+		return results, nil
+	}
 
-	chatOpts := []ChatOption{WithTemperature(r.options.temperature)}
+	pending := make([]string, len(missing))
+	for j, i := range missing {
+		pending[j] = texts[i]
+	}
+	prompt := buildBatchTranslatePrompt(pending, targetLang, r)
 
-	result, err := client.Chat(ctx, prompt, chatOpts...)
+	result, err := r.chat(ctx, prompt, func(*Client) []ChatOption {
+		return []ChatOption{WithTemperature(r.options.temperature)}
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return parseBatchResult(result, len(texts))
+	translations, err := parseBatchResult(result.Content, len(pending))
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range missing {
+		results[i] = translations[j]
+		if keys[i] != "" {
+			cacheSet(keys[i], translations[j], ttl)
+		}
+	}
+	return results, nil
 }
 
 // buildBatchTranslatePrompt constructs the prompt for batch translation
@@ -178,8 +217,8 @@ RULES:
 	// Add glossary if set
 	if len(r.options.glossary) > 0 {
 		systemPrompt.WriteString("\n\nTERM GLOSSARY:\n")
-		for source, target := range r.options.glossary {
-			systemPrompt.WriteString(fmt.Sprintf("• %q → %q\n", source, target))
+		for _, source := range slices.Sorted(maps.Keys(r.options.glossary)) {
+			systemPrompt.WriteString(fmt.Sprintf("• %q → %q\n", source, r.options.glossary[source]))
 		}
 	}
 
@@ -200,11 +239,7 @@ RULES:
 // parseBatchResult parses the JSON array response from batch translation
 func parseBatchResult(result string, expectedCount int) ([]string, error) {
 	// Clean up - sometimes AI adds markdown code blocks
-	result = strings.TrimSpace(result)
-	result = strings.TrimPrefix(result, "```json")
-	result = strings.TrimPrefix(result, "```")
-	result = strings.TrimSuffix(result, "```")
-	result = strings.TrimSpace(result)
+	result = stripCodeFences(result)
 
 	var translations []string
 	if err := json.Unmarshal([]byte(result), &translations); err != nil {
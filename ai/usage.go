@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"sync"
+
+	"github.com/openai/openai-go"
+)
+
+// Usage holds the token counts of a single completion
+type Usage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// ChatResult is a completion with the provider and model that served it and
+// its usage
+type ChatResult struct {
+	Content  string `json:"content"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Usage
+	Cached bool `json:"-"` // Served from the response cache (see ai.cache)
+}
+
+var (
+	usageHook    func(provider, model string, usage Usage)
+	usageHookMux sync.RWMutex
+)
+
+// OnUsage registers a hook called after every completion (Chat, Execute,
+// and streams once they complete), e.g. to export token metrics.
+// Usage is zero when the provider does not report it. Pass nil to remove
+// the hook.
+//
+// Example:
+//
+//	ai.OnUsage(func(provider, model string, u ai.Usage) {
+//	    tokensTotal.WithLabelValues(provider, model).Add(float64(u.TotalTokens))
+//	})
+func OnUsage(fn func(provider, model string, usage Usage)) {
+	usageHookMux.Lock()
+	defer usageHookMux.Unlock()
+	usageHook = fn
+}
+
+// reportUsage calls the OnUsage hook, if any
+func reportUsage(provider, model string, usage Usage) {
+	usageHookMux.RLock()
+	fn := usageHook
+	usageHookMux.RUnlock()
+	if fn != nil {
+		fn(provider, model, usage)
+	}
+}
+
+func toUsage(u openai.CompletionUsage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+}
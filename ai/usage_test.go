@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"context"
+	"testing"
+)
+
+type usageRecord struct {
+	provider, model string
+	usage           Usage
+}
+
+// recordUsage installs an OnUsage hook for the duration of the test.
+func recordUsage(t *testing.T) *[]usageRecord {
+	t.Helper()
+	var records []usageRecord
+	OnUsage(func(provider, model string, usage Usage) {
+		records = append(records, usageRecord{provider, model, usage})
+	})
+	t.Cleanup(func() { OnUsage(nil) })
+	return &records
+}
+
+func TestChatWithUsage(t *testing.T) {
+	client := setupMock(t, nil)
+	records := recordUsage(t)
+	MockRespond(func([]Message) bool { return true }, "one two three")
+
+	result, err := client.ChatWithUsage(context.Background(), []Message{SystemMessage("be brief"), UserMessage("count")})
+	if err != nil {
+		t.Fatalf("ChatWithUsage failed: %v", err)
+	}
+	want := Usage{PromptTokens: 3, CompletionTokens: 3, TotalTokens: 6}
+	if result.Content != "one two three" || result.Model != "mock" || result.Usage != want {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(*records) != 1 || (*records)[0] != (usageRecord{"mock", "mock", want}) {
+		t.Errorf("OnUsage not called with the usage: %+v", *records)
+	}
+
+	// Chat reports through the same hook
+	if _, err := client.Chat(context.Background(), []Message{UserMessage("again")}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*records) != 2 {
+		t.Errorf("expected hook call for Chat, got %d", len(*records))
+	}
+}
+
+func TestExecuteWithUsage(t *testing.T) {
+	setupMock(t, nil)
+	records := recordUsage(t)
+
+	result, err := NewRequest("Hello").Translate("zh").UseProvider("mock").ExecuteWithUsage(context.Background())
+	if err != nil {
+		t.Fatalf("ExecuteWithUsage failed: %v", err)
+	}
+	if result.Content != "[zh] Hello" || result.TotalTokens == 0 || result.TotalTokens != result.PromptTokens+result.CompletionTokens {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(*records) != 1 {
+		t.Errorf("expected 1 hook call, got %d", len(*records))
+	}
+}
+
+func TestStreamUsage(t *testing.T) {
+	client := setupMock(t, map[string]any{"chunk_size": 2})
+	records := recordUsage(t)
+	MockRespond(func([]Message) bool { return true }, "alpha beta")
+
+	stream := client.ChatStream(context.Background(), []Message{UserMessage("hi")})
+	defer stream.Close()
+
+	var text string
+	for {
+		chunk, err := stream.Next()
+		if err != nil {
+			t.Fatalf("stream failed: %v", err)
+		}
+		if chunk == "" {
+			break
+		}
+		text += chunk
+	}
+	stream.Next() // Reading past the end must not report twice
+
+	want := Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3}
+	if text != "alpha beta" || stream.Usage() != want {
+		t.Errorf("unexpected stream result %q, usage %+v", text, stream.Usage())
+	}
+	if len(*records) != 1 || (*records)[0].usage != want {
+		t.Errorf("OnUsage should fire once at the end of the stream: %+v", *records)
+	}
+}
@@ -0,0 +1,95 @@
+package appstore
+
+import "time"
+
+// AccessPolicy 配置 DecideAccess 的判定规则。
+type AccessPolicy struct {
+	// HonorGracePeriod 为 true 时，过期后在 gracePeriodExpiresDate 之前保留访问。
+	HonorGracePeriod bool
+	// BillingRetryAllowance 为账单重试期内（自动续订开启）且没有
+	// gracePeriodExpiresDate 时，过期后额外保留访问的时长；0 表示不保留。
+	BillingRetryAllowance time.Duration
+	// AllowFamilyShared 为 true 时，家庭共享的购买也授予访问。
+	AllowFamilyShared bool
+}
+
+// AccessReason 说明访问判定的原因。
+type AccessReason string
+
+const (
+	AccessReason_ActiveSubscription AccessReason = "active_subscription" // 订阅有效期内
+	AccessReason_GracePeriod        AccessReason = "grace_period"        // 已过期，处于账单宽限期
+	AccessReason_BillingRetry       AccessReason = "billing_retry"       // 已过期，处于账单重试期(按策略保留)
+	AccessReason_Purchased          AccessReason = "purchased"           // 无过期时间的购买(如非消耗型项目)
+	AccessReason_Expired            AccessReason = "expired"             // 已过期
+	AccessReason_Revoked            AccessReason = "revoked"             // 已退款或被撤销
+	AccessReason_FamilyShared       AccessReason = "family_shared"       // 家庭共享购买，策略不允许
+	AccessReason_NoTransaction      AccessReason = "no_transaction"      // 没有交易信息
+)
+
+// AccessDecision 是 DecideAccess 的判定结果。
+type AccessDecision struct {
+	Allowed bool
+	Reason  AccessReason
+	// FlipsAt 是 Allowed 在当前数据下将发生变化的时间，调用方可据此安排复查；
+	// 零值表示不会因时间推移而变化（需要新的交易或续期信息）。
+	FlipsAt time.Time
+}
+
+// DecideAccess 根据交易与续期信息判定用户在 now 时刻是否有访问权限。
+// 所有时间比较都使用传入的 now，便于测试。r 可以为 nil（如非订阅购买）。
+//
+// 判定顺序：
+//  1. 已撤销(revocationDate) → Revoked
+//  2. 家庭共享且策略不允许 → FamilyShared
+//  3. 无 expiresDate → Purchased
+//  4. now 早于 expiresDate → ActiveSubscription
+//  5. 策略允许且 now 早于 gracePeriodExpiresDate → GracePeriod
+//  6. 无宽限期时间、自动续订开启且处于账单重试期，now 早于
+//     expiresDate + BillingRetryAllowance → BillingRetry
+//  7. 其他 → Expired
+//
+// 恰好等于过期时间时视为已过期。
+func DecideAccess(t *TransactionInfo, r *RenewalInfo, policy AccessPolicy, now time.Time) AccessDecision {
+	if t == nil {
+		return AccessDecision{Reason: AccessReason_NoTransaction}
+	}
+	if t.RevocationDate > 0 {
+		return AccessDecision{Reason: AccessReason_Revoked}
+	}
+	if t.InAppOwnershipType == OwnershipType_FAMILY_SHARED && !policy.AllowFamilyShared {
+		return AccessDecision{Reason: AccessReason_FamilyShared}
+	}
+	if t.ExpiresDate == 0 {
+		return AccessDecision{Allowed: true, Reason: AccessReason_Purchased}
+	}
+
+	expires := time.UnixMilli(t.ExpiresDate)
+	end, extendedReason := accessEnd(expires, r, policy)
+
+	switch {
+	case now.Before(expires):
+		return AccessDecision{Allowed: true, Reason: AccessReason_ActiveSubscription, FlipsAt: end}
+	case now.Before(end):
+		return AccessDecision{Allowed: true, Reason: extendedReason, FlipsAt: end}
+	default:
+		return AccessDecision{Reason: AccessReason_Expired}
+	}
+}
+
+// accessEnd 返回访问实际结束的时间，以及过期后到该时间之间的判定原因。
+func accessEnd(expires time.Time, r *RenewalInfo, policy AccessPolicy) (time.Time, AccessReason) {
+	if r == nil {
+		return expires, AccessReason_Expired
+	}
+	if r.GracePeriodExpiresDate > 0 {
+		if grace := time.UnixMilli(r.GracePeriodExpiresDate); policy.HonorGracePeriod && grace.After(expires) {
+			return grace, AccessReason_GracePeriod
+		}
+		return expires, AccessReason_Expired
+	}
+	if r.IsInBillingRetryPeriod && r.AutoRenewStatus == AutoRenewStatus_On && policy.BillingRetryAllowance > 0 {
+		return expires.Add(policy.BillingRetryAllowance), AccessReason_BillingRetry
+	}
+	return expires, AccessReason_Expired
+}
@@ -0,0 +1,85 @@
+package appstore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecideAccess(t *testing.T) {
+	expires := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	grace := expires.Add(16 * 24 * time.Hour)
+	allowance := 3 * 24 * time.Hour
+	ms := func(t time.Time) int64 { return t.UnixMilli() }
+
+	sub := func(mod func(*TransactionInfo)) *TransactionInfo {
+		ti := &TransactionInfo{
+			Type:               TransactionType_AutoRenewableSubscription,
+			InAppOwnershipType: OwnershipType_PURCHASED,
+			ExpiresDate:        ms(expires),
+		}
+		if mod != nil {
+			mod(ti)
+		}
+		return ti
+	}
+	inGrace := &RenewalInfo{AutoRenewStatus: AutoRenewStatus_On, IsInBillingRetryPeriod: true, GracePeriodExpiresDate: ms(grace)}
+	inRetry := &RenewalInfo{AutoRenewStatus: AutoRenewStatus_On, IsInBillingRetryPeriod: true}
+	retryAutoRenewOff := &RenewalInfo{AutoRenewStatus: AutoRenewStatus_Off, IsInBillingRetryPeriod: true}
+	renewing := &RenewalInfo{AutoRenewStatus: AutoRenewStatus_On}
+
+	full := AccessPolicy{HonorGracePeriod: true, BillingRetryAllowance: allowance, AllowFamilyShared: true}
+	strict := AccessPolicy{}
+
+	tests := []struct {
+		name    string
+		t       *TransactionInfo
+		r       *RenewalInfo
+		policy  AccessPolicy
+		now     time.Time
+		allowed bool
+		reason  AccessReason
+		flipsAt time.Time
+	}{
+		{"no transaction", nil, nil, full, expires, false, AccessReason_NoTransaction, time.Time{}},
+
+		{"active", sub(nil), renewing, full, expires.Add(-time.Hour), true, AccessReason_ActiveSubscription, expires},
+		{"active without renewal info", sub(nil), nil, full, expires.Add(-time.Hour), true, AccessReason_ActiveSubscription, expires},
+		{"one ms before expiry", sub(nil), renewing, full, expires.Add(-time.Millisecond), true, AccessReason_ActiveSubscription, expires},
+		{"exactly at expiry", sub(nil), renewing, full, expires, false, AccessReason_Expired, time.Time{}},
+		{"expired", sub(nil), renewing, full, expires.Add(time.Hour), false, AccessReason_Expired, time.Time{}},
+
+		// Active with a known grace period flips at the end of grace, not at expiry
+		{"active with grace ahead", sub(nil), inGrace, full, expires.Add(-time.Hour), true, AccessReason_ActiveSubscription, grace},
+		{"grace at expiry", sub(nil), inGrace, full, expires, true, AccessReason_GracePeriod, grace},
+		{"grace just before end", sub(nil), inGrace, full, grace.Add(-time.Millisecond), true, AccessReason_GracePeriod, grace},
+		{"grace exactly at end", sub(nil), inGrace, full, grace, false, AccessReason_Expired, time.Time{}},
+		{"grace not honored", sub(nil), inGrace, strict, expires, false, AccessReason_Expired, time.Time{}},
+		{"grace not honored ignores retry allowance", sub(nil), inGrace, AccessPolicy{BillingRetryAllowance: allowance}, expires, false, AccessReason_Expired, time.Time{}},
+		{"grace date before expiry", sub(nil), &RenewalInfo{GracePeriodExpiresDate: ms(expires.Add(-time.Hour))}, full, expires, false, AccessReason_Expired, time.Time{}},
+
+		{"billing retry", sub(nil), inRetry, full, expires.Add(time.Hour), true, AccessReason_BillingRetry, expires.Add(allowance)},
+		{"billing retry at allowance end", sub(nil), inRetry, full, expires.Add(allowance), false, AccessReason_Expired, time.Time{}},
+		{"billing retry without allowance", sub(nil), inRetry, AccessPolicy{HonorGracePeriod: true}, expires, false, AccessReason_Expired, time.Time{}},
+		{"billing retry with auto-renew off", sub(nil), retryAutoRenewOff, full, expires.Add(time.Hour), false, AccessReason_Expired, time.Time{}},
+		{"active with retry allowance ahead", sub(nil), inRetry, full, expires.Add(-time.Hour), true, AccessReason_ActiveSubscription, expires.Add(allowance)},
+
+		{"revoked", sub(func(t *TransactionInfo) { t.RevocationDate = ms(expires.Add(-time.Hour)) }), renewing, full, expires.Add(-2 * time.Hour), false, AccessReason_Revoked, time.Time{}},
+		{"revoked during grace", sub(func(t *TransactionInfo) { t.RevocationDate = ms(expires) }), inGrace, full, expires.Add(time.Hour), false, AccessReason_Revoked, time.Time{}},
+
+		{"family shared allowed", sub(func(t *TransactionInfo) { t.InAppOwnershipType = OwnershipType_FAMILY_SHARED }), renewing, full, expires.Add(-time.Hour), true, AccessReason_ActiveSubscription, expires},
+		{"family shared denied", sub(func(t *TransactionInfo) { t.InAppOwnershipType = OwnershipType_FAMILY_SHARED }), renewing, strict, expires.Add(-time.Hour), false, AccessReason_FamilyShared, time.Time{}},
+
+		{"non-consumable", sub(func(t *TransactionInfo) { t.Type, t.ExpiresDate = TransactionType_NonConsumable, 0 }), nil, strict, expires, true, AccessReason_Purchased, time.Time{}},
+		{"non-consumable revoked", sub(func(t *TransactionInfo) { t.ExpiresDate, t.RevocationDate = 0, ms(expires) }), nil, strict, expires, false, AccessReason_Revoked, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DecideAccess(tt.t, tt.r, tt.policy, tt.now)
+			if got.Allowed != tt.allowed || got.Reason != tt.reason || !got.FlipsAt.Equal(tt.flipsAt) {
+				t.Errorf("DecideAccess() = {%v %s %v}, want {%v %s %v}",
+					got.Allowed, got.Reason, got.FlipsAt, tt.allowed, tt.reason, tt.flipsAt)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package appstore
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"crypto/x509"
@@ -15,6 +16,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -31,6 +33,7 @@ var (
 	ErrParsingJWT              = errors.New("error parsing JWT")
 	ErrPublicKeyExtraction     = errors.New("error extracting public key")
 	ErrNoSignedTransaction     = errors.New("no signed transaction in notification")
+	ErrInvalidOrderId          = errors.New("invalid order id")
 )
 
 //go:embed certs/AppleRootCA-G3.pem
@@ -104,63 +107,96 @@ func GenerateJwtToken(bundleId string) (string, error) {
 	return token, nil
 }
 
-// API端点常量
-const (
-	IAP_SERVER_API         = "https://api.storekit.itunes.apple.com"
-	IAP_SANDBOX_SERVER_API = "https://api.storekit-sandbox.itunes.apple.com"
+// jwtTokenRefreshBefore 缓存的令牌在过期前该时长内重新签发
+const jwtTokenRefreshBefore = 5 * time.Minute
+
+type cachedJwtToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+var (
+	jwtTokenCache   = map[string]cachedJwtToken{} // bundleId|keyId|issuer -> token
+	jwtTokenCacheMu sync.Mutex
 )
 
-// 从Apple服务器获取交易信息
-func GetTransaction(ctx context.Context, bundleId, transactionId string) (*TransactionInfo, error) {
-	if bundleId == "" || transactionId == "" {
-		return nil, errors.New("bundleId and transactionId are required")
+// getJwtToken 返回缓存的 API 令牌，临近过期时重新签发
+func getJwtToken(bundleId string) (string, error) {
+	cacheKey := bundleId + "|" + viper.GetString("appstore.iap.keyId") + "|" + viper.GetString("appstore.iap.issuer")
+
+	jwtTokenCacheMu.Lock()
+	defer jwtTokenCacheMu.Unlock()
+	if cached, ok := jwtTokenCache[cacheKey]; ok && time.Until(cached.expiresAt) > jwtTokenRefreshBefore {
+		return cached.token, nil
 	}
 
-	// 先尝试正式环境
-	info, err := getTransactionFromEnvironment(ctx, bundleId, transactionId, false)
+	token, err := GenerateJwtToken(bundleId)
 	if err != nil {
-		// 如果失败，尝试沙盒环境
-		info, err = getTransactionFromEnvironment(ctx, bundleId, transactionId, true)
+		return "", err
 	}
-	return info, err
+	jwtTokenCache[cacheKey] = cachedJwtToken{token: token, expiresAt: time.Now().Add(time.Hour)}
+	return token, nil
 }
 
-// 从特定环境获取交易信息
-func getTransactionFromEnvironment(ctx context.Context, bundleId, transactionId string, isSandbox bool) (*TransactionInfo, error) {
-	baseUrl := IAP_SERVER_API
+// API端点常量
+const (
+	IAP_SERVER_API         = "https://api.storekit.itunes.apple.com"
+	IAP_SANDBOX_SERVER_API = "https://api.storekit-sandbox.itunes.apple.com"
+)
+
+// base URL 默认指向正式/沙盒；以包变量形式提供以便测试覆盖。
+var (
+	apiBaseProd    = IAP_SERVER_API
+	apiBaseSandbox = IAP_SANDBOX_SERVER_API
+)
+
+// apiBase 返回正式或沙盒环境的 base URL
+func apiBase(isSandbox bool) string {
 	if isSandbox {
-		baseUrl = IAP_SANDBOX_SERVER_API
+		return apiBaseSandbox
 	}
+	return apiBaseProd
+}
 
-	url := fmt.Sprintf("%s/inApps/v1/transactions/%s", baseUrl, transactionId)
+// apiGet 以缓存的 JWT 令牌调用 App Store Server API，返回 200 响应的 body
+func apiGet(ctx context.Context, bundleId, reqURL string) ([]byte, error) {
+	return apiRequest(ctx, bundleId, "GET", reqURL, nil)
+}
 
-	jwtToken, err := GenerateJwtToken(bundleId)
+// apiRequest 以缓存的 JWT 令牌调用 App Store Server API，payload 非 nil 时以
+// JSON 发送，返回 2xx 响应的 body
+func apiRequest(ctx context.Context, bundleId, method, reqURL string, payload any) ([]byte, error) {
+	jwtToken, err := getJwtToken(bundleId)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate JWT token: %w", err)
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	var reqBody io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-
-	// 添加context到请求
-	req = req.WithContext(ctx)
-
 	req.Header.Set("Authorization", "Bearer "+jwtToken)
 	req.Header.Add("Accept", "application/json")
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
 	}
 
+	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
 	}
 
@@ -168,6 +204,31 @@ func getTransactionFromEnvironment(ctx context.Context, bundleId, transactionId
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
+	return body, nil
+}
+
+// apiGetWithFallback 先向正式环境 GET path，失败再回退沙盒，
+// 返回响应 body 及成功的环境（isSandbox），供后续翻页沿用
+func apiGetWithFallback(ctx context.Context, bundleId, path string) (body []byte, isSandbox bool, err error) {
+	body, err = apiGet(ctx, bundleId, apiBase(false)+path)
+	if err == nil {
+		return body, false, nil
+	}
+	body, err = apiGet(ctx, bundleId, apiBase(true)+path)
+	return body, true, err
+}
+
+// 从Apple服务器获取交易信息
+func GetTransaction(ctx context.Context, bundleId, transactionId string) (*TransactionInfo, error) {
+	if bundleId == "" || transactionId == "" {
+		return nil, errors.New("bundleId and transactionId are required")
+	}
+
+	// 先尝试正式环境，失败再尝试沙盒环境
+	body, _, err := apiGetWithFallback(ctx, bundleId, "/inApps/v1/transactions/"+transactionId)
+	if err != nil {
+		return nil, err
+	}
 
 	var data map[string]string
 	if err := json.Unmarshal(body, &data); err != nil {
@@ -204,19 +265,48 @@ func parseJWT(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
 	return token, nil
 }
 
+// NotificationOption 配置 NewNotification 的解析行为
+type NotificationOption func(*notificationOptions)
+
+type notificationOptions struct {
+	strict  bool
+	rootPEM []byte // 信任根，默认内置 Apple 根
+}
+
+// WithStrictVerification 开启严格校验：通知及其中的交易、续期信息都必须通过
+// VerifySignedPayload，任一校验失败则解析失败，IsValid 为 false。
+// 默认模式只记录证书链校验失败的日志，不校验签名。
+func WithStrictVerification() NotificationOption {
+	return func(o *notificationOptions) {
+		o.strict = true
+	}
+}
+
+// withRootCA 替换信任根（测试用）。
+func withRootCA(rootPEM []byte) NotificationOption {
+	return func(o *notificationOptions) {
+		o.rootPEM = rootPEM
+	}
+}
+
 // NewNotification 解析App Store通知
-func NewNotification(ctx context.Context, payload string) (*AppStoreServerNotification, error) {
+func NewNotification(ctx context.Context, payload string, opts ...NotificationOption) (*AppStoreServerNotification, error) {
 	if payload == "" {
 		return nil, ErrInvalidPayload
 	}
 
+	options := notificationOptions{rootPEM: AppleRootCAPEM}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	// 初始化通知对象
 	asn := &AppStoreServerNotification{
 		IsValid: false,
 	}
 
 	// 解析通知
-	err := asn.parseNotification(ctx, payload)
+	err := asn.parseNotification(ctx, payload, options)
 	if err != nil {
 		return asn, fmt.Errorf("notification parsing failed: %w", err)
 	}
@@ -225,7 +315,7 @@ func NewNotification(ctx context.Context, payload string) (*AppStoreServerNotifi
 }
 
 // parseNotification 解析通知负载 - 上下文参数放在第一位
-func (asn *AppStoreServerNotification) parseNotification(ctx context.Context, payload string) error {
+func (asn *AppStoreServerNotification) parseNotification(ctx context.Context, payload string, opts notificationOptions) error {
 	// 使用panic恢复来确保即使遇到意外错误也不会崩溃整个程序
 	defer func() {
 		if r := recover(); r != nil {
@@ -233,8 +323,13 @@ func (asn *AppStoreServerNotification) parseNotification(ctx context.Context, pa
 		}
 	}()
 
+	// 严格模式：证书链、签名和时间声明都必须校验通过
+	if opts.strict {
+		return asn.parseVerified(payload, opts.rootPEM)
+	}
+
 	// 尝试验证证书链
-	if err := verifyPayload(payload); err != nil {
+	if err := verifyPayloadWithRoot(payload, opts.rootPEM); err != nil {
 		log.Warnf(ctx, "Certificate verification failed: %v", err)
 		// 继续处理，不阻断流程
 	}
@@ -289,6 +384,76 @@ func (asn *AppStoreServerNotification) parseNotification(ctx context.Context, pa
 	return nil
 }
 
+// parseVerified 严格模式解析：通知及其中的交易、续期信息任一校验失败即返回错误
+func (asn *AppStoreServerNotification) parseVerified(payload string, rootPEM []byte) error {
+	notificationPayload := &NotificationPayload{}
+	if err := verifySignedPayloadWithRoot(payload, notificationPayload, rootPEM); err != nil {
+		return err
+	}
+
+	var transactionInfo *TransactionInfo
+	if signed := notificationPayload.Data.SignedTransactionInfo; signed != "" {
+		transactionInfo = &TransactionInfo{}
+		if err := verifySignedPayloadWithRoot(signed, transactionInfo, rootPEM); err != nil {
+			return fmt.Errorf("transaction info: %w", err)
+		}
+		if transactionInfo.TransactionId == "" || transactionInfo.BundleId == "" {
+			return fmt.Errorf("%w: transaction info missing required fields", ErrMissingTransactionInfo)
+		}
+	}
+
+	var renewalInfo *RenewalInfo
+	if signed := notificationPayload.Data.SignedRenewalInfo; signed != "" {
+		renewalInfo = &RenewalInfo{}
+		if err := verifySignedPayloadWithRoot(signed, renewalInfo, rootPEM); err != nil {
+			return fmt.Errorf("renewal info: %w", err)
+		}
+	}
+
+	asn.Payload = notificationPayload
+	asn.TransactionInfo = transactionInfo
+	asn.RenewalInfo = renewalInfo
+	asn.IsValid = notificationPayload.NotificationType != ""
+	return nil
+}
+
+// VerifySignedPayload 校验 Apple 签名的 JWS（通知、交易或续期信息）并解析到 claims：
+// x5c 叶子证书须经中间证书链到内置 Apple 根，签名须为叶子证书公钥的 ES256 签名，
+// exp/iat 存在时须有效。
+func VerifySignedPayload(payload string, claims jwt.Claims) error {
+	return verifySignedPayloadWithRoot(payload, claims, AppleRootCAPEM)
+}
+
+// verifySignedPayloadWithRoot 与 VerifySignedPayload 相同，但允许注入信任根（测试用）。
+func verifySignedPayloadWithRoot(payload string, claims jwt.Claims, rootPEM []byte) error {
+	if err := verifyPayloadWithRoot(payload, rootPEM); err != nil {
+		return fmt.Errorf("%w: %v", ErrCertificateVerification, err)
+	}
+
+	// 证书链已校验，叶子证书必然存在
+	leafCertBytes, _ := extractHeaderByIndex(payload, 0)
+	leaf, err := x509.ParseCertificate(leafCertBytes)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPublicKeyExtraction, err)
+	}
+	publicKey, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: leaf certificate key is not ECDSA", ErrPublicKeyExtraction)
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}),
+		jwt.WithIssuedAt(),
+	)
+	_, err = parser.ParseWithClaims(payload, claims, func(token *jwt.Token) (interface{}, error) {
+		return publicKey, nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrParsingJWT, err)
+	}
+	return nil
+}
+
 // extractHeaderByIndex 从JWT payload中提取x5c证书
 func extractHeaderByIndex(payload string, index int) ([]byte, error) {
 	// 获取JWT头部
@@ -395,12 +560,6 @@ func verifyCertificateChainWithRoot(certBytes, intermediateCertBytes, rootPEM []
 
 // ==================== Get All Subscription Statuses ====================
 
-// base URL 默认指向正式/沙盒；以包变量形式提供以便测试覆盖。
-var (
-	subStatusBaseProd    = IAP_SERVER_API
-	subStatusBaseSandbox = IAP_SANDBOX_SERVER_API
-)
-
 // buildSubscriptionStatusURL 拼接 Get All Subscription Statuses 的请求 URL，
 // 可选附带一个或多个 status 过滤参数。
 func buildSubscriptionStatusURL(base, transactionId string, statuses []int32) string {
@@ -424,46 +583,9 @@ func GetAllSubscriptionStatuses(ctx context.Context, bundleId, transactionId str
 		return nil, errors.New("bundleId and transactionId are required")
 	}
 
-	resp, err := getSubscriptionStatusesFromEnv(ctx, bundleId, transactionId, false, statuses)
-	if err != nil {
-		resp, err = getSubscriptionStatusesFromEnv(ctx, bundleId, transactionId, true, statuses)
-	}
-	return resp, err
-}
-
-func getSubscriptionStatusesFromEnv(ctx context.Context, bundleId, transactionId string, isSandbox bool, statuses []int32) (*StatusResponse, error) {
-	base := subStatusBaseProd
-	if isSandbox {
-		base = subStatusBaseSandbox
-	}
-	reqURL := buildSubscriptionStatusURL(base, transactionId, statuses)
-
-	jwtToken, err := GenerateJwtToken(bundleId)
+	body, _, err := apiGetWithFallback(ctx, bundleId, buildSubscriptionStatusURL("", transactionId, statuses))
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate JWT token: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+jwtToken)
-	req.Header.Add("Accept", "application/json")
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
 	var out StatusResponse
@@ -9,6 +9,7 @@ import (
 	"crypto/x509/pkix"
 	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"math/big"
 	"net/http"
 	"net/http/httptest"
@@ -118,6 +119,14 @@ func setTestIapKey(t *testing.T) {
 	t.Cleanup(viper.Reset)
 }
 
+// setTestAPIBase points the App Store Server API at test servers.
+func setTestAPIBase(t *testing.T, prod, sandbox string) {
+	t.Helper()
+	oldP, oldS := apiBaseProd, apiBaseSandbox
+	apiBaseProd, apiBaseSandbox = prod, sandbox
+	t.Cleanup(func() { apiBaseProd, apiBaseSandbox = oldP, oldS })
+}
+
 // ---------- A: x5c leaf-indexing fix ----------
 
 func TestVerifyPayloadWithRoot_ValidChainPasses(t *testing.T) {
@@ -169,6 +178,83 @@ func TestVerifyPayload_RejectsNonAppleChain(t *testing.T) {
 	}
 }
 
+// ---------- strict signature verification ----------
+
+func TestVerifySignedPayloadWithRoot(t *testing.T) {
+	rootPEM, leafKey, leafDER, intDER, rootDER := makeChain(t)
+	x5c := []string{b64(leafDER), b64(intDER), b64(rootDER)}
+	now := time.Now().Unix()
+
+	ti := &TransactionInfo{}
+	jws := signJWS(t, leafKey, x5c, jwt.MapClaims{"transactionId": "TX1", "bundleId": "io.kaitu.app", "iat": now})
+	if err := verifySignedPayloadWithRoot(jws, ti, rootPEM); err != nil {
+		t.Fatalf("valid payload should verify, got: %v", err)
+	}
+	if ti.TransactionId != "TX1" {
+		t.Fatalf("claims not decoded: %+v", ti)
+	}
+
+	// 证书链有效，但签名来自另一把私钥
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	forged := signJWS(t, otherKey, x5c, jwt.MapClaims{"transactionId": "TX1"})
+	if err := verifySignedPayloadWithRoot(forged, &TransactionInfo{}, rootPEM); !errors.Is(err, ErrParsingJWT) {
+		t.Fatalf("forged signature must be rejected, got: %v", err)
+	}
+
+	expired := signJWS(t, leafKey, x5c, jwt.MapClaims{"transactionId": "TX1", "exp": now - 60})
+	if err := verifySignedPayloadWithRoot(expired, &TransactionInfo{}, rootPEM); !errors.Is(err, ErrParsingJWT) {
+		t.Fatalf("expired payload must be rejected, got: %v", err)
+	}
+
+	if err := VerifySignedPayload(jws, &TransactionInfo{}); !errors.Is(err, ErrCertificateVerification) {
+		t.Fatalf("non-Apple chain must fail certificate verification, got: %v", err)
+	}
+}
+
+func TestNewNotificationStrictVerification(t *testing.T) {
+	rootPEM, leafKey, leafDER, intDER, rootDER := makeChain(t)
+	x5c := []string{b64(leafDER), b64(intDER), b64(rootDER)}
+	txJWS := signJWS(t, leafKey, x5c, jwt.MapClaims{"transactionId": "TX1", "bundleId": "io.kaitu.app"})
+	payload := signJWS(t, leafKey, x5c, jwt.MapClaims{
+		"notificationType": "DID_RENEW",
+		"data":             map[string]interface{}{"signedTransactionInfo": txJWS},
+	})
+	ctx := context.Background()
+
+	// 默认模式：证书链不通过也照常解析
+	asn, err := NewNotification(ctx, payload)
+	if err != nil || !asn.IsValid {
+		t.Fatalf("lenient mode should accept the payload, got valid=%v err=%v", asn.IsValid, err)
+	}
+
+	// 严格模式，内置 Apple 根：拒绝
+	asn, err = NewNotification(ctx, payload, WithStrictVerification())
+	if !errors.Is(err, ErrCertificateVerification) || asn.IsValid {
+		t.Fatalf("strict mode must reject a non-Apple chain, got valid=%v err=%v", asn.IsValid, err)
+	}
+
+	// 严格模式，信任测试根：通过
+	asn, err = NewNotification(ctx, payload, WithStrictVerification(), withRootCA(rootPEM))
+	if err != nil || !asn.IsValid {
+		t.Fatalf("strict mode should accept a trusted chain, got valid=%v err=%v", asn.IsValid, err)
+	}
+	if asn.TransactionInfo == nil || asn.TransactionInfo.TransactionId != "TX1" {
+		t.Fatalf("transaction info not decoded: %+v", asn.TransactionInfo)
+	}
+
+	// 内层交易信息签名无效：整体失败
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	forgedTx := signJWS(t, otherKey, x5c, jwt.MapClaims{"transactionId": "TX1", "bundleId": "io.kaitu.app"})
+	payload = signJWS(t, leafKey, x5c, jwt.MapClaims{
+		"notificationType": "DID_RENEW",
+		"data":             map[string]interface{}{"signedTransactionInfo": forgedTx},
+	})
+	asn, err = NewNotification(ctx, payload, WithStrictVerification(), withRootCA(rootPEM))
+	if !errors.Is(err, ErrParsingJWT) || asn.IsValid || asn.TransactionInfo != nil {
+		t.Fatalf("strict mode must reject a forged transaction, got valid=%v err=%v", asn.IsValid, err)
+	}
+}
+
 // ---------- B: GetAllSubscriptionStatuses ----------
 
 func TestBuildSubscriptionStatusURL(t *testing.T) {
@@ -243,9 +329,7 @@ func TestGetAllSubscriptionStatuses_ProdFailsSandboxSucceeds(t *testing.T) {
 	}))
 	defer sandbox.Close()
 
-	oldP, oldS := subStatusBaseProd, subStatusBaseSandbox
-	subStatusBaseProd, subStatusBaseSandbox = prod.URL, sandbox.URL
-	t.Cleanup(func() { subStatusBaseProd, subStatusBaseSandbox = oldP, oldS })
+	setTestAPIBase(t, prod.URL, sandbox.URL)
 
 	resp, err := GetAllSubscriptionStatuses(context.Background(), "io.kaitu.app", "TX1")
 	if err != nil {
@@ -0,0 +1,195 @@
+package appstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ConsumptionRequest 是 Send Consumption Information 的请求体。
+// 收到 CONSUMPTION_REQUEST 通知后需在 12 小时内发送，Apple 据此决定是否退款。
+// 各枚举字段取值见对应的 *_ 常量，0 均表示未声明。
+type ConsumptionRequest struct {
+	AccountTenure            int32  `json:"accountTenure"`            // AccountTenure_*
+	AppAccountToken          string `json:"appAccountToken"`          // 购买时的 appAccountToken，没有时为空
+	ConsumptionStatus        int32  `json:"consumptionStatus"`        // ConsumptionStatus_*
+	CustomerConsented        bool   `json:"customerConsented"`        // 必须为 true，用户同意提供消费数据
+	DeliveryStatus           int32  `json:"deliveryStatus"`           // DeliveryStatus_*
+	LifetimeDollarsPurchased int32  `json:"lifetimeDollarsPurchased"` // LifetimeDollars_*
+	LifetimeDollarsRefunded  int32  `json:"lifetimeDollarsRefunded"`  // LifetimeDollars_*
+	Platform                 int32  `json:"platform"`                 // Platform_*
+	PlayTime                 int32  `json:"playTime"`                 // PlayTime_*
+	RefundPreference         int32  `json:"refundPreference"`         // RefundPreference_*
+	SampleContentProvided    bool   `json:"sampleContentProvided"`    // 购买前是否提供了试用内容
+	UserStatus               int32  `json:"userStatus"`               // UserStatus_*
+}
+
+// 账号注册时长 - 对应 ConsumptionRequest.AccountTenure
+const (
+	AccountTenure_Undeclared   int32 = 0
+	AccountTenure_0To3Days     int32 = 1
+	AccountTenure_3To10Days    int32 = 2
+	AccountTenure_10To30Days   int32 = 3
+	AccountTenure_30To90Days   int32 = 4
+	AccountTenure_90To180Days  int32 = 5
+	AccountTenure_180To365Days int32 = 6
+	AccountTenure_Over365Days  int32 = 7
+)
+
+// 内购内容消耗程度 - 对应 ConsumptionRequest.ConsumptionStatus
+const (
+	ConsumptionStatus_Undeclared        int32 = 0
+	ConsumptionStatus_NotConsumed       int32 = 1 // 未消耗
+	ConsumptionStatus_PartiallyConsumed int32 = 2 // 部分消耗
+	ConsumptionStatus_FullyConsumed     int32 = 3 // 全部消耗
+)
+
+// 交付状态 - 对应 ConsumptionRequest.DeliveryStatus
+const (
+	DeliveryStatus_Delivered      int32 = 0 // 已正常交付
+	DeliveryStatus_QualityIssue   int32 = 1 // 因质量问题未交付
+	DeliveryStatus_WrongItem      int32 = 2 // 交付了错误的内容
+	DeliveryStatus_ServerOutage   int32 = 3 // 因服务端故障未交付
+	DeliveryStatus_CurrencyChange int32 = 4 // 因游戏内货币变更未交付
+	DeliveryStatus_Other          int32 = 5 // 其他原因未交付
+)
+
+// 累计消费/退款金额区间(美元) - 对应 LifetimeDollarsPurchased/LifetimeDollarsRefunded
+const (
+	LifetimeDollars_Undeclared int32 = 0
+	LifetimeDollars_Zero       int32 = 1
+	LifetimeDollars_Under50    int32 = 2 // 0.01-49.99
+	LifetimeDollars_Under100   int32 = 3 // 50-99.99
+	LifetimeDollars_Under500   int32 = 4 // 100-499.99
+	LifetimeDollars_Under1000  int32 = 5 // 500-999.99
+	LifetimeDollars_Under2000  int32 = 6 // 1000-1999.99
+	LifetimeDollars_Over2000   int32 = 7
+)
+
+// 用户使用的平台 - 对应 ConsumptionRequest.Platform
+const (
+	Platform_Undeclared int32 = 0
+	Platform_Apple      int32 = 1
+	Platform_NonApple   int32 = 2
+)
+
+// 用户使用时长 - 对应 ConsumptionRequest.PlayTime
+const (
+	PlayTime_Undeclared    int32 = 0
+	PlayTime_Under5Minutes int32 = 1
+	PlayTime_Under1Hour    int32 = 2 // 5-60 分钟
+	PlayTime_Under6Hours   int32 = 3
+	PlayTime_Under1Day     int32 = 4 // 6-24 小时
+	PlayTime_Under4Days    int32 = 5
+	PlayTime_Under16Days   int32 = 6
+	PlayTime_Over16Days    int32 = 7
+)
+
+// 开发者对退款的倾向 - 对应 ConsumptionRequest.RefundPreference
+const (
+	RefundPreference_Undeclared int32 = 0
+	RefundPreference_Grant      int32 = 1 // 倾向于同意退款
+	RefundPreference_Decline    int32 = 2 // 倾向于拒绝退款
+	RefundPreference_NoPrefer   int32 = 3 // 无倾向
+)
+
+// 用户账号状态 - 对应 ConsumptionRequest.UserStatus
+const (
+	UserStatus_Undeclared int32 = 0
+	UserStatus_Active     int32 = 1
+	UserStatus_Suspended  int32 = 2
+	UserStatus_Terminated int32 = 3
+	UserStatus_Limited    int32 = 4
+)
+
+// 延长订阅的原因 - 对应 ExtendRenewalDate 的 reasonCode
+const (
+	ExtendReasonCode_Undeclared           = 0
+	ExtendReasonCode_CustomerSatisfaction = 1 // 客户满意度补偿
+	ExtendReasonCode_Other                = 2
+	ExtendReasonCode_ServiceIssue         = 3 // 服务中断补偿
+)
+
+// validate 校验枚举字段的取值范围，避免请求被 Apple 以 400 拒绝。
+func (r *ConsumptionRequest) validate() error {
+	if !r.CustomerConsented {
+		return errors.New("customerConsented must be true")
+	}
+	fields := []struct {
+		name  string
+		value int32
+		max   int32
+	}{
+		{"accountTenure", r.AccountTenure, AccountTenure_Over365Days},
+		{"consumptionStatus", r.ConsumptionStatus, ConsumptionStatus_FullyConsumed},
+		{"deliveryStatus", r.DeliveryStatus, DeliveryStatus_Other},
+		{"lifetimeDollarsPurchased", r.LifetimeDollarsPurchased, LifetimeDollars_Over2000},
+		{"lifetimeDollarsRefunded", r.LifetimeDollarsRefunded, LifetimeDollars_Over2000},
+		{"platform", r.Platform, Platform_NonApple},
+		{"playTime", r.PlayTime, PlayTime_Over16Days},
+		{"refundPreference", r.RefundPreference, RefundPreference_NoPrefer},
+		{"userStatus", r.UserStatus, UserStatus_Limited},
+	}
+	for _, f := range fields {
+		if f.value < 0 || f.value > f.max {
+			return fmt.Errorf("%s must be between 0 and %d, got %d", f.name, f.max, f.value)
+		}
+	}
+	return nil
+}
+
+// SendConsumptionInfo 调用 Apple 的 Send Consumption Information 端点，
+// 回应 CONSUMPTION_REQUEST 通知。与 GetTransaction 一致：先试正式环境，失败再回退沙盒。
+func SendConsumptionInfo(ctx context.Context, bundleId, originalTransactionId string, req *ConsumptionRequest) error {
+	if bundleId == "" || originalTransactionId == "" || req == nil {
+		return errors.New("bundleId, originalTransactionId and req are required")
+	}
+	if err := req.validate(); err != nil {
+		return fmt.Errorf("invalid consumption request: %w", err)
+	}
+
+	path := "/inApps/v1/transactions/consumption/" + url.PathEscape(originalTransactionId)
+	return apiPutWithFallback(ctx, bundleId, path, req)
+}
+
+// extendRenewalDateRequest 是 Extend a Subscription Renewal Date 的请求体。
+type extendRenewalDateRequest struct {
+	ExtendByDays      int    `json:"extendByDays"`
+	ExtendReasonCode  int    `json:"extendReasonCode"`
+	RequestIdentifier string `json:"requestIdentifier"`
+}
+
+// ExtendRenewalDate 将一个有效订阅的续期日期延后 extendByDays 天(1-90)，用于补偿用户。
+// reasonCode 为 ExtendReasonCode_* 常量；requestIdentifier 由调用方生成(如 UUID)，
+// 相同标识的重复请求只生效一次。每个订阅一年内最多延长两次。
+func ExtendRenewalDate(ctx context.Context, bundleId, originalTransactionId string, extendByDays int, reasonCode int, requestIdentifier string) error {
+	if bundleId == "" || originalTransactionId == "" {
+		return errors.New("bundleId and originalTransactionId are required")
+	}
+	if extendByDays < 1 || extendByDays > 90 {
+		return fmt.Errorf("extendByDays must be between 1 and 90, got %d", extendByDays)
+	}
+	if reasonCode < ExtendReasonCode_Undeclared || reasonCode > ExtendReasonCode_ServiceIssue {
+		return fmt.Errorf("reasonCode must be between 0 and 3, got %d", reasonCode)
+	}
+	if requestIdentifier == "" || len(requestIdentifier) > 128 {
+		return errors.New("requestIdentifier is required and at most 128 characters")
+	}
+
+	path := "/inApps/v1/subscriptions/extend/" + url.PathEscape(originalTransactionId)
+	return apiPutWithFallback(ctx, bundleId, path, &extendRenewalDateRequest{
+		ExtendByDays:      extendByDays,
+		ExtendReasonCode:  reasonCode,
+		RequestIdentifier: requestIdentifier,
+	})
+}
+
+// apiPutWithFallback 先向正式环境 PUT，失败再回退沙盒
+func apiPutWithFallback(ctx context.Context, bundleId, path string, payload any) error {
+	_, err := apiRequest(ctx, bundleId, "PUT", apiBase(false)+path, payload)
+	if err != nil {
+		_, err = apiRequest(ctx, bundleId, "PUT", apiBase(true)+path, payload)
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package appstore
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendConsumptionInfo_FallsBackToSandbox(t *testing.T) {
+	setTestIapKey(t)
+	prod := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer prod.Close()
+
+	var body map[string]any
+	sandbox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/inApps/v1/transactions/consumption/OTX1" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer sandbox.Close()
+	setTestAPIBase(t, prod.URL, sandbox.URL)
+
+	err := SendConsumptionInfo(context.Background(), "io.kaitu.app", "OTX1", &ConsumptionRequest{
+		AccountTenure:            AccountTenure_Over365Days,
+		ConsumptionStatus:        ConsumptionStatus_FullyConsumed,
+		CustomerConsented:        true,
+		DeliveryStatus:           DeliveryStatus_Delivered,
+		LifetimeDollarsPurchased: LifetimeDollars_Under100,
+		Platform:                 Platform_Apple,
+		RefundPreference:         RefundPreference_Decline,
+		UserStatus:               UserStatus_Active,
+	})
+	if err != nil {
+		t.Fatalf("SendConsumptionInfo failed: %v", err)
+	}
+	if body["accountTenure"] != float64(7) || body["consumptionStatus"] != float64(3) || body["customerConsented"] != true ||
+		body["refundPreference"] != float64(2) || body["appAccountToken"] != "" {
+		t.Errorf("unexpected body: %v", body)
+	}
+}
+
+func TestSendConsumptionInfo_Validation(t *testing.T) {
+	for name, req := range map[string]*ConsumptionRequest{
+		"not consented": {AccountTenure: AccountTenure_0To3Days},
+		"out of range":  {CustomerConsented: true, PlayTime: 8},
+		"negative":      {CustomerConsented: true, DeliveryStatus: -1},
+	} {
+		if err := SendConsumptionInfo(context.Background(), "io.kaitu.app", "OTX1", req); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestExtendRenewalDate(t *testing.T) {
+	setTestIapKey(t)
+	var body extendRenewalDateRequest
+	prod := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/inApps/v1/subscriptions/extend/OTX1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer prod.Close()
+	setTestAPIBase(t, prod.URL, "http://127.0.0.1:0")
+
+	err := ExtendRenewalDate(context.Background(), "io.kaitu.app", "OTX1", 7, ExtendReasonCode_ServiceIssue, "outage-2026-10-01-OTX1")
+	if err != nil {
+		t.Fatalf("ExtendRenewalDate failed: %v", err)
+	}
+	if body.ExtendByDays != 7 || body.ExtendReasonCode != 3 || body.RequestIdentifier != "outage-2026-10-01-OTX1" {
+		t.Errorf("unexpected body: %+v", body)
+	}
+
+	for name, days := range map[string]int{"zero days": 0, "too many days": 91} {
+		if err := ExtendRenewalDate(context.Background(), "io.kaitu.app", "OTX1", days, 0, "r"); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+	if err := ExtendRenewalDate(context.Background(), "io.kaitu.app", "OTX1", 7, 4, "r"); err == nil {
+		t.Error("reason code 4 should be rejected")
+	}
+	if err := ExtendRenewalDate(context.Background(), "io.kaitu.app", "OTX1", 7, 0, ""); err == nil {
+		t.Error("empty requestIdentifier should be rejected")
+	}
+}
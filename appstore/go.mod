@@ -3,6 +3,7 @@ module github.com/wordgate/qtoolkit/appstore
 go 1.24.0
 
 require (
+	github.com/gin-gonic/gin v1.11.0
 	github.com/golang-jwt/jwt/v5 v5.2.3
 	github.com/rs/xid v1.6.0
 	github.com/spf13/viper v1.21.0
@@ -0,0 +1,118 @@
+package appstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wordgate/qtoolkit/log"
+)
+
+// NotificationFunc 处理一类通知。通知不含交易或续期信息时 tx、renewal 为 nil。
+type NotificationFunc func(ctx context.Context, payload *NotificationPayload, tx *TransactionInfo, renewal *RenewalInfo) error
+
+// Handler 按 NotificationType/Subtype 分发 App Store 服务器通知。
+// 路由优先级：类型+子类型 > 类型 > OnUnknown；都未注册的通知被忽略。
+// 注册应在开始处理通知前完成。
+//
+//	h := appstore.NewHandler(appstore.WithStrictVerification())
+//	h.OnSubscribed(func(ctx context.Context, p *appstore.NotificationPayload, tx *appstore.TransactionInfo, r *appstore.RenewalInfo) error {
+//	    return grantAccess(ctx, tx)
+//	})
+//	h.On(appstore.NotificationType_DID_CHANGE_RENEWAL_STATUS, appstore.Subtype_AUTO_RENEW_DISABLED, onAutoRenewOff)
+//	router.POST("/appstore/notifications", h.GinHandler())
+type Handler struct {
+	routes  map[string]NotificationFunc // "TYPE" 或 "TYPE/SUBTYPE"
+	unknown NotificationFunc
+	opts    []NotificationOption
+}
+
+// NewHandler 创建通知分发器，opts 传给 NewNotification。
+func NewHandler(opts ...NotificationOption) *Handler {
+	return &Handler{
+		routes: make(map[string]NotificationFunc),
+		opts:   opts,
+	}
+}
+
+// On 注册某类型（subtype 为空时匹配该类型所有子类型）通知的处理函数。
+func (h *Handler) On(notificationType, subtype string, fn NotificationFunc) {
+	h.routes[routeKey(notificationType, subtype)] = fn
+}
+
+// OnSubscribed 处理 SUBSCRIBED（首次订阅或重新订阅，子类型区分）。
+func (h *Handler) OnSubscribed(fn NotificationFunc) {
+	h.On(NotificationType_SUBSCRIBED, "", fn)
+}
+
+// OnDidRenew 处理 DID_RENEW（订阅续期成功）。
+func (h *Handler) OnDidRenew(fn NotificationFunc) {
+	h.On(NotificationType_DID_RENEW, "", fn)
+}
+
+// OnExpired 处理 EXPIRED（订阅过期）。
+func (h *Handler) OnExpired(fn NotificationFunc) {
+	h.On(NotificationType_EXPIRED, "", fn)
+}
+
+// OnRefund 处理 REFUND（Apple 已退款）。
+func (h *Handler) OnRefund(fn NotificationFunc) {
+	h.On(NotificationType_REFUND, "", fn)
+}
+
+// OnUnknown 处理没有注册处理函数的通知。
+func (h *Handler) OnUnknown(fn NotificationFunc) {
+	h.unknown = fn
+}
+
+func routeKey(notificationType, subtype string) string {
+	if subtype == "" {
+		return notificationType
+	}
+	return notificationType + "/" + subtype
+}
+
+// Handle 解析签名通知并分发到对应的处理函数，返回解析或处理错误。
+func (h *Handler) Handle(ctx context.Context, signedPayload string) error {
+	asn, err := NewNotification(ctx, signedPayload, h.opts...)
+	if err != nil {
+		return err
+	}
+	if !asn.IsValid {
+		return errors.New("invalid notification")
+	}
+	return h.dispatch(ctx, asn)
+}
+
+func (h *Handler) dispatch(ctx context.Context, asn *AppStoreServerNotification) error {
+	p := asn.Payload
+	fn, ok := h.routes[routeKey(p.NotificationType, p.Subtype)]
+	if !ok {
+		fn, ok = h.routes[p.NotificationType]
+	}
+	if !ok {
+		fn = h.unknown
+	}
+	if fn == nil {
+		return nil
+	}
+	if err := fn(ctx, p, asn.TransactionInfo, asn.RenewalInfo); err != nil {
+		return fmt.Errorf("handle %s notification %s: %w", routeKey(p.NotificationType, p.Subtype), p.NotificationUUID, err)
+	}
+	return nil
+}
+
+// GinHandler 返回接收 App Store 服务器通知的 gin 处理器。
+// 总是响应 200，解析或处理失败只记录日志，避免 Apple 反复重发。
+func (h *Handler) GinHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req AppStoreServerRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Errorf(c, "appstore notification: invalid request body: %v", err)
+		} else if err := h.Handle(c, req.SignedPayload); err != nil {
+			log.Errorf(c, "appstore notification: %v", err)
+		}
+		c.Status(200)
+	}
+}
@@ -0,0 +1,92 @@
+package appstore
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestHandlerRouting(t *testing.T) {
+	_, leafKey, leafDER, intDER, rootDER := makeChain(t)
+	x5c := []string{b64(leafDER), b64(intDER), b64(rootDER)}
+	notification := func(notificationType, subtype string) string {
+		tx := signJWS(t, leafKey, x5c, jwt.MapClaims{"transactionId": "TX1", "bundleId": "io.kaitu.app"})
+		return signJWS(t, leafKey, x5c, jwt.MapClaims{
+			"notificationType": notificationType,
+			"subtype":          subtype,
+			"data":             map[string]interface{}{"signedTransactionInfo": tx},
+		})
+	}
+
+	var got []string
+	record := func(name string) NotificationFunc {
+		return func(ctx context.Context, p *NotificationPayload, tx *TransactionInfo, r *RenewalInfo) error {
+			if tx == nil || tx.TransactionId != "TX1" {
+				t.Errorf("%s: transaction info not passed: %+v", name, tx)
+			}
+			got = append(got, name)
+			return nil
+		}
+	}
+
+	h := NewHandler()
+	h.OnSubscribed(record("subscribed"))
+	h.On(NotificationType_SUBSCRIBED, Subtype_INITIAL_BUY, record("initial_buy"))
+	h.OnDidRenew(record("did_renew"))
+	h.OnRefund(record("refund"))
+	h.OnUnknown(record("unknown"))
+
+	ctx := context.Background()
+	for _, n := range []struct{ notificationType, subtype string }{
+		{NotificationType_SUBSCRIBED, Subtype_INITIAL_BUY},
+		{NotificationType_SUBSCRIBED, Subtype_RESUBSCRIBE},
+		{NotificationType_DID_RENEW, ""},
+		{NotificationType_REFUND, ""},
+		{NotificationType_EXPIRED, Subtype_VOLUNTARY},
+	} {
+		if err := h.Handle(ctx, notification(n.notificationType, n.subtype)); err != nil {
+			t.Fatalf("Handle %s failed: %v", n.notificationType, err)
+		}
+	}
+	if want := "initial_buy,subscribed,did_renew,refund,unknown"; strings.Join(got, ",") != want {
+		t.Fatalf("expected routes %s, got %v", want, got)
+	}
+
+	h.OnRefund(func(ctx context.Context, p *NotificationPayload, tx *TransactionInfo, r *RenewalInfo) error {
+		return errors.New("db down")
+	})
+	if err := h.Handle(ctx, notification(NotificationType_REFUND, "")); err == nil || !strings.Contains(err.Error(), "db down") {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+	if err := h.Handle(ctx, "not-a-jwt"); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
+
+func TestHandlerGinAlwaysOK(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(WithStrictVerification())
+	called := false
+	h.OnUnknown(func(ctx context.Context, p *NotificationPayload, tx *TransactionInfo, r *RenewalInfo) error {
+		called = true
+		return nil
+	})
+	r := gin.New()
+	r.POST("/notify", h.GinHandler())
+
+	for _, body := range []string{`{"signedPayload":"a.b.c"}`, `not json`} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", "/notify", strings.NewReader(body)))
+		if w.Code != 200 {
+			t.Errorf("expected 200 for %q, got %d", body, w.Code)
+		}
+	}
+	if called {
+		t.Error("invalid notifications must not be dispatched")
+	}
+}
@@ -0,0 +1,140 @@
+package appstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/url"
+)
+
+// HistoryOptions 是 Get Transaction History 的查询参数，均可选。
+type HistoryOptions struct {
+	Revision    string   // 上一页的 HistoryPage.Revision，空表示第一页
+	Sort        string   // HistorySort_* 常量，默认升序
+	ProductType []string // ProductType_* 常量
+	ProductId   []string
+}
+
+// buildHistoryURL 拼接 Get Transaction History 的请求 URL。
+func buildHistoryURL(base, transactionId string, opts *HistoryOptions) string {
+	u := fmt.Sprintf("%s/inApps/v2/history/%s", base, url.PathEscape(transactionId))
+	if opts == nil {
+		return u
+	}
+	q := url.Values{}
+	if opts.Revision != "" {
+		q.Set("revision", opts.Revision)
+	}
+	if opts.Sort != "" {
+		q.Set("sort", opts.Sort)
+	}
+	for _, t := range opts.ProductType {
+		q.Add("productType", t)
+	}
+	for _, id := range opts.ProductId {
+		q.Add("productId", id)
+	}
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	return u
+}
+
+// GetTransactionHistory 调用 Apple 的 Get Transaction History 端点，返回一页交易。
+// transactionId 可以是该用户的任意交易ID（通常为 originalTransactionId）。
+// 与 GetTransaction 一致：先试正式环境，失败再回退沙盒。
+// 翻页时将返回的 Revision 传入 opts.Revision，或使用 TransactionHistory 迭代全部交易。
+func GetTransactionHistory(ctx context.Context, bundleId, transactionId string, opts *HistoryOptions) (*HistoryPage, error) {
+	if bundleId == "" || transactionId == "" {
+		return nil, errors.New("bundleId and transactionId are required")
+	}
+
+	body, _, err := apiGetWithFallback(ctx, bundleId, buildHistoryURL("", transactionId, opts))
+	if err != nil {
+		return nil, err
+	}
+	return decodeHistoryPage(body)
+}
+
+func getHistoryFromEnv(ctx context.Context, bundleId, transactionId string, isSandbox bool, opts *HistoryOptions) (*HistoryPage, error) {
+	body, err := apiGet(ctx, bundleId, buildHistoryURL(apiBase(isSandbox), transactionId, opts))
+	if err != nil {
+		return nil, err
+	}
+	return decodeHistoryPage(body)
+}
+
+func decodeHistoryPage(body []byte) (*HistoryPage, error) {
+	var page HistoryPage
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return &page, nil
+}
+
+// TransactionHistory 按 hasMore 逐页获取并解析全部交易。第一页决定环境
+// (正式或沙盒)，之后的页直接请求该环境。出错时产出 (nil, err) 后结束。
+//
+//	for ti, err := range appstore.TransactionHistory(ctx, bundleId, otid, nil) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    reconcile(ti)
+//	}
+func TransactionHistory(ctx context.Context, bundleId, transactionId string, opts *HistoryOptions) iter.Seq2[*TransactionInfo, error] {
+	return func(yield func(*TransactionInfo, error) bool) {
+		query := HistoryOptions{}
+		if opts != nil {
+			query = *opts
+		}
+
+		page, err := GetTransactionHistory(ctx, bundleId, transactionId, &query)
+		for {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			transactions, err := page.DecodeTransactions()
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, ti := range transactions {
+				if !yield(ti, nil) {
+					return
+				}
+			}
+			if !page.HasMore {
+				return
+			}
+			query.Revision = page.Revision
+			page, err = getHistoryFromEnv(ctx, bundleId, transactionId, page.Environment == Environment_Sandbox, &query)
+		}
+	}
+}
+
+// DecodeTransactions 解析本页签名的交易信息(JWS)。
+func (p *HistoryPage) DecodeTransactions() ([]*TransactionInfo, error) {
+	infos, err := decodeSignedTransactions(p.SignedTransactions)
+	if err != nil {
+		return nil, err
+	}
+	transactions := make([]*TransactionInfo, len(infos))
+	for i := range infos {
+		transactions[i] = &infos[i]
+	}
+	return transactions, nil
+}
+
+// decodeSignedTransactions 逐条解析签名的交易信息(JWS)
+func decodeSignedTransactions(signed []string) ([]TransactionInfo, error) {
+	transactions := make([]TransactionInfo, len(signed))
+	for i, s := range signed {
+		if _, err := parseJWT(s, &transactions[i]); err != nil {
+			return nil, fmt.Errorf("failed to parse transaction info: %w", err)
+		}
+	}
+	return transactions, nil
+}
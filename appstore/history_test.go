@@ -0,0 +1,105 @@
+package appstore
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestBuildHistoryURL(t *testing.T) {
+	if got := buildHistoryURL("https://h", "OTX1", nil); got != "https://h/inApps/v2/history/OTX1" {
+		t.Fatalf("unexpected url: %s", got)
+	}
+	got := buildHistoryURL("https://h", "OTX1", &HistoryOptions{
+		Revision:    "rev/1",
+		Sort:        HistorySort_Descending,
+		ProductType: []string{ProductType_AutoRenewable, ProductType_Consumable},
+	})
+	for _, part := range []string{"revision=rev%2F1", "sort=DESCENDING", "productType=AUTO_RENEWABLE", "productType=CONSUMABLE"} {
+		if !strings.Contains(got, part) {
+			t.Errorf("url %s missing %s", got, part)
+		}
+	}
+}
+
+func TestTransactionHistory_FollowsRevisionInSandbox(t *testing.T) {
+	setTestIapKey(t)
+	_, leafKey, leafDER, intDER, rootDER := makeChain(t)
+	x5c := []string{b64(leafDER), b64(intDER), b64(rootDER)}
+	tx := func(id string) string {
+		return signJWS(t, leafKey, x5c, jwt.MapClaims{"transactionId": id, "bundleId": "io.kaitu.app"})
+	}
+
+	prodHits := 0
+	prod := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prodHits++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer prod.Close()
+
+	var auths []string
+	sandbox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auths = append(auths, r.Header.Get("Authorization"))
+		if r.URL.Path != "/inApps/v2/history/OTX1" || r.URL.Query().Get("sort") != HistorySort_Ascending {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		page := HistoryPage{Environment: Environment_Sandbox}
+		switch r.URL.Query().Get("revision") {
+		case "":
+			page.SignedTransactions = []string{tx("T1"), tx("T2")}
+			page.Revision, page.HasMore = "rev-2", true
+		case "rev-2":
+			page.SignedTransactions = []string{tx("T3")}
+			page.Revision = "rev-3"
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer sandbox.Close()
+	setTestAPIBase(t, prod.URL, sandbox.URL)
+
+	var ids []string
+	for ti, err := range TransactionHistory(context.Background(), "io.kaitu.app", "OTX1", &HistoryOptions{Sort: HistorySort_Ascending}) {
+		if err != nil {
+			t.Fatalf("history failed: %v", err)
+		}
+		ids = append(ids, ti.TransactionId)
+	}
+	if strings.Join(ids, ",") != "T1,T2,T3" {
+		t.Fatalf("unexpected transactions: %v", ids)
+	}
+	if prodHits != 1 {
+		t.Errorf("later pages should stay in sandbox, production hit %d times", prodHits)
+	}
+	if len(auths) != 2 || auths[0] != auths[1] {
+		t.Errorf("JWT token should be cached across calls, got %v", auths)
+	}
+}
+
+func TestTransactionHistory_Error(t *testing.T) {
+	setTestIapKey(t)
+	fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer fail.Close()
+	setTestAPIBase(t, fail.URL, fail.URL)
+
+	n := 0
+	for ti, err := range TransactionHistory(context.Background(), "io.kaitu.app", "OTX1", nil) {
+		n++
+		if err == nil || ti != nil {
+			t.Fatalf("expected error, got %+v", ti)
+		}
+	}
+	if n != 1 {
+		t.Fatalf("expected a single error, got %d items", n)
+	}
+}
@@ -0,0 +1,73 @@
+package appstore
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+)
+
+// LookUpOrderID 调用 Apple 的 Look Up Order ID 端点，返回用户收据上的订单号
+// (orderID) 对应的全部交易，按购买时间升序排列。订单号无效时返回 ErrInvalidOrderId。
+// 与 GetTransaction 一致：先试正式环境，失败再回退沙盒。
+func LookUpOrderID(ctx context.Context, bundleId, orderID string) ([]TransactionInfo, error) {
+	if bundleId == "" || orderID == "" {
+		return nil, errors.New("bundleId and orderID are required")
+	}
+
+	body, _, err := apiGetWithFallback(ctx, bundleId, "/inApps/v1/lookup/"+url.PathEscape(orderID))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp OrderLookupResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if resp.Status != OrderLookupStatus_Valid {
+		return nil, ErrInvalidOrderId
+	}
+
+	transactions, err := decodeSignedTransactions(resp.SignedTransactions)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(transactions, func(a, b TransactionInfo) int {
+		return cmp.Compare(a.PurchaseDate, b.PurchaseDate)
+	})
+	return transactions, nil
+}
+
+// GetRefundHistory 调用 Apple 的 Get Refund History 端点，按 hasMore 逐页获取
+// 该用户全部已退款的交易，也可传入该用户的其他交易ID。
+// 第一页先试正式环境，失败再回退沙盒，之后的页直接请求该环境。
+func GetRefundHistory(ctx context.Context, bundleId, originalTransactionId string) ([]TransactionInfo, error) {
+	if bundleId == "" || originalTransactionId == "" {
+		return nil, errors.New("bundleId and originalTransactionId are required")
+	}
+
+	path := "/inApps/v2/refund/lookup/" + url.PathEscape(originalTransactionId)
+	body, isSandbox, err := apiGetWithFallback(ctx, bundleId, path)
+	var transactions []TransactionInfo
+	for {
+		if err != nil {
+			return nil, err
+		}
+		var page RefundHistoryPage
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		refunds, err := decodeSignedTransactions(page.SignedTransactions)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, refunds...)
+		if !page.HasMore {
+			return transactions, nil
+		}
+		body, err = apiGet(ctx, bundleId, apiBase(isSandbox)+path+"?revision="+url.QueryEscape(page.Revision))
+	}
+}
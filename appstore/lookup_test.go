@@ -0,0 +1,150 @@
+package appstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signedTransactionFixture 返回签名交易信息(JWS)的生成函数
+func signedTransactionFixture(t *testing.T) func(id string, purchaseDate int64) string {
+	t.Helper()
+	_, leafKey, leafDER, intDER, rootDER := makeChain(t)
+	x5c := []string{b64(leafDER), b64(intDER), b64(rootDER)}
+	return func(id string, purchaseDate int64) string {
+		return signJWS(t, leafKey, x5c, jwt.MapClaims{
+			"transactionId":         id,
+			"originalTransactionId": "OTX1",
+			"bundleId":              "io.kaitu.app",
+			"productId":             "pro.monthly",
+			"purchaseDate":          purchaseDate,
+		})
+	}
+}
+
+func TestLookUpOrderID_SortedByPurchaseDate(t *testing.T) {
+	setTestIapKey(t)
+	tx := signedTransactionFixture(t)
+
+	var path string
+	prod := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		json.NewEncoder(w).Encode(OrderLookupResponse{
+			Status:             OrderLookupStatus_Valid,
+			SignedTransactions: []string{tx("T2", 1700000200000), tx("T1", 1700000100000), tx("T3", 1700000300000)},
+		})
+	}))
+	defer prod.Close()
+	setTestAPIBase(t, prod.URL, prod.URL)
+
+	transactions, err := LookUpOrderID(context.Background(), "io.kaitu.app", "MK5TTTVWJH")
+	if err != nil {
+		t.Fatalf("LookUpOrderID failed: %v", err)
+	}
+	if path != "/inApps/v1/lookup/MK5TTTVWJH" {
+		t.Errorf("unexpected path: %s", path)
+	}
+	var ids []string
+	for _, ti := range transactions {
+		ids = append(ids, ti.TransactionId)
+	}
+	if strings.Join(ids, ",") != "T1,T2,T3" || transactions[0].ProductId != "pro.monthly" {
+		t.Fatalf("expected transactions sorted by purchase date, got %v", ids)
+	}
+}
+
+func TestLookUpOrderID_InvalidAndFallback(t *testing.T) {
+	setTestIapKey(t)
+	prod := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer prod.Close()
+	sandboxHit := false
+	sandbox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sandboxHit = true
+		w.Write([]byte(`{"status":1,"signedTransactions":[]}`))
+	}))
+	defer sandbox.Close()
+	setTestAPIBase(t, prod.URL, sandbox.URL)
+
+	if _, err := LookUpOrderID(context.Background(), "io.kaitu.app", "BADORDER"); !errors.Is(err, ErrInvalidOrderId) {
+		t.Fatalf("expected ErrInvalidOrderId, got %v", err)
+	}
+	if !sandboxHit {
+		t.Error("sandbox endpoint was never hit")
+	}
+	if _, err := LookUpOrderID(context.Background(), "io.kaitu.app", ""); err == nil {
+		t.Error("expected error for empty orderID")
+	}
+}
+
+func TestGetRefundHistory_FollowsRevisionInSandbox(t *testing.T) {
+	setTestIapKey(t)
+	tx := signedTransactionFixture(t)
+
+	prodHits := 0
+	prod := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prodHits++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer prod.Close()
+
+	sandbox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/inApps/v2/refund/lookup/OTX1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var page RefundHistoryPage
+		switch r.URL.Query().Get("revision") {
+		case "":
+			page.SignedTransactions = []string{tx("R1", 1), tx("R2", 2)}
+			page.Revision, page.HasMore = "rev-2", true
+		case "rev-2":
+			page.SignedTransactions = []string{tx("R3", 3)}
+			page.Revision = "rev-3"
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer sandbox.Close()
+	setTestAPIBase(t, prod.URL, sandbox.URL)
+
+	refunds, err := GetRefundHistory(context.Background(), "io.kaitu.app", "OTX1")
+	if err != nil {
+		t.Fatalf("GetRefundHistory failed: %v", err)
+	}
+	var ids []string
+	for _, ti := range refunds {
+		ids = append(ids, ti.TransactionId)
+	}
+	if strings.Join(ids, ",") != "R1,R2,R3" {
+		t.Fatalf("unexpected refunds: %v", ids)
+	}
+	if prodHits != 1 {
+		t.Errorf("later pages should stay in sandbox, production hit %d times", prodHits)
+	}
+}
+
+func TestGetRefundHistory_Error(t *testing.T) {
+	setTestIapKey(t)
+	fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer fail.Close()
+	setTestAPIBase(t, fail.URL, fail.URL)
+
+	if refunds, err := GetRefundHistory(context.Background(), "io.kaitu.app", "OTX1"); err == nil || refunds != nil {
+		t.Fatalf("expected error, got %v", refunds)
+	}
+	if _, err := GetRefundHistory(context.Background(), "", "OTX1"); err == nil {
+		t.Error("expected error for empty bundleId")
+	}
+}
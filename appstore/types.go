@@ -273,3 +273,50 @@ const (
 )
 
 // 其他相关类型定义...
+
+// ==================== Get Transaction History 响应类型 ====================
+
+// HistoryPage 是 Get Transaction History 端点返回的一页交易。
+type HistoryPage struct {
+	Revision           string   `json:"revision"` // 下一页的游标，传入 HistoryOptions.Revision
+	HasMore            bool     `json:"hasMore"`  // 是否还有下一页
+	BundleId           string   `json:"bundleId"`
+	AppAppleId         int64    `json:"appAppleId"`
+	Environment        string   `json:"environment"`
+	SignedTransactions []string `json:"signedTransactions"` // 签名交易信息(JWS)
+}
+
+// 交易历史排序 - 对应 HistoryOptions.Sort
+const (
+	HistorySort_Ascending  = "ASCENDING"  // 按修改时间升序
+	HistorySort_Descending = "DESCENDING" // 按修改时间降序
+)
+
+// 交易历史产品类型过滤 - 对应 HistoryOptions.ProductType
+const (
+	ProductType_AutoRenewable = "AUTO_RENEWABLE"
+	ProductType_NonRenewable  = "NON_RENEWABLE"
+	ProductType_Consumable    = "CONSUMABLE"
+	ProductType_NonConsumable = "NON_CONSUMABLE"
+)
+
+// ==================== Look Up Order ID / Get Refund History 响应类型 ====================
+
+// OrderLookupResponse 是 Look Up Order ID 端点的响应。
+type OrderLookupResponse struct {
+	Status             int32    `json:"status"`             // 见 OrderLookupStatus_* 常量
+	SignedTransactions []string `json:"signedTransactions"` // 签名交易信息(JWS)
+}
+
+// 订单查询结果 - 对应 OrderLookupResponse.Status
+const (
+	OrderLookupStatus_Valid   int32 = 0 // 订单号有效
+	OrderLookupStatus_Invalid int32 = 1 // 订单号无效
+)
+
+// RefundHistoryPage 是 Get Refund History 端点返回的一页退款交易。
+type RefundHistoryPage struct {
+	Revision           string   `json:"revision"` // 下一页的游标
+	HasMore            bool     `json:"hasMore"`  // 是否还有下一页
+	SignedTransactions []string `json:"signedTransactions"`
+}
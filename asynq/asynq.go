@@ -29,7 +29,9 @@ import (
 	"time"
 
 	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
+	qredis "github.com/wordgate/qtoolkit/redis"
 )
 
 // HandlerFunc is the function signature for task handlers.
@@ -56,10 +58,9 @@ var (
 
 // Config holds the asynq configuration.
 type Config struct {
-	// Redis connection (falls back to redis.* if not set)
-	RedisAddr     string `mapstructure:"redis_addr"`
-	RedisPassword string `mapstructure:"redis_password"`
-	RedisDB       int    `mapstructure:"redis_db"`
+	// Redis connection, read from asynq.redis.* falling back to redis.*
+	// like qtoolkit/redis clients
+	redis *qredis.ConnConfig
 
 	// Worker configuration
 	Concurrency    int            `mapstructure:"concurrency"`
@@ -70,6 +71,12 @@ type Config struct {
 	DefaultMaxRetry int           `mapstructure:"default_max_retry"`
 	DefaultTimeout  time.Duration `mapstructure:"default_timeout"`
 
+	// Queue receiving exhausted tasks as DeadLetter envelopes; empty disables
+	DeadLetterQueue string `mapstructure:"dead_letter_queue"`
+
+	// How long Shutdown waits for active tasks before requeueing them
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+
 	// Monitor configuration
 	Monitor MonitorConfig `mapstructure:"monitor"`
 }
@@ -77,6 +84,10 @@ type Config struct {
 // MonitorConfig holds the asynqmon UI configuration.
 type MonitorConfig struct {
 	ReadOnly bool `mapstructure:"readonly"`
+
+	// Basic auth credentials; the UI is open when either is empty
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
 }
 
 var (
@@ -90,29 +101,19 @@ var (
 	mux          *asynq.ServeMux
 	serverOnce   sync.Once
 	serverMux    sync.Mutex
-	workerOnce   sync.Once
 	workerActive bool
+	workerDone   chan struct{} // Closed by Shutdown
 
-	scheduler     *asynq.Scheduler
-	schedulerOnce sync.Once
-	cronTasks     []cronTask
+	// lifecycleMux serializes worker start and Shutdown
+	lifecycleMux sync.Mutex
 
 	handlers    = make(map[string]HandlerFunc)
 	handlersMux sync.RWMutex
-
-	shutdownOnce sync.Once
 )
 
-type cronTask struct {
-	cronspec string
-	taskType string
-	payload  any
-	opts     []asynq.Option
-}
-
 // loadConfig loads configuration from viper with cascading fallback.
 // Priority: asynq.* -> redis.* (for connection settings)
-// FATAL: Crashes if redis.addr is not configured.
+// FATAL: Crashes if the Redis connection is not configured.
 func loadConfig() *Config {
 	configOnce.Do(func() {
 		globalConfig = &Config{
@@ -120,6 +121,7 @@ func loadConfig() *Config {
 			Queues:          map[string]int{"default": 1},
 			DefaultMaxRetry: 3,
 			DefaultTimeout:  30 * time.Minute,
+			ShutdownTimeout: 8 * time.Second,
 		}
 
 		// Load asynq specific config
@@ -127,21 +129,11 @@ func loadConfig() *Config {
 			// Use defaults on error
 		}
 
-		// Fallback to redis.* for connection settings
-		if globalConfig.RedisAddr == "" {
-			globalConfig.RedisAddr = viper.GetString("redis.addr")
-		}
-		if globalConfig.RedisPassword == "" {
-			globalConfig.RedisPassword = viper.GetString("redis.password")
-		}
-		if globalConfig.RedisDB == 0 && viper.IsSet("redis.db") {
-			globalConfig.RedisDB = viper.GetInt("redis.db")
-		}
-
-		// FATAL: Redis address is required
-		if globalConfig.RedisAddr == "" {
-			log.Fatal("asynq: redis.addr is required but not configured")
+		redisCfg, err := qredis.LoadConfig("asynq")
+		if err != nil {
+			log.Fatalf("asynq: %v", err)
 		}
+		globalConfig.redis = redisCfg
 
 		// Ensure defaults
 		if globalConfig.Concurrency <= 0 {
@@ -159,14 +151,18 @@ func GetConfig() *Config {
 	return loadConfig()
 }
 
+// redisConnOpt connects asynq with a qtoolkit/redis client.
+type redisConnOpt struct {
+	cfg *qredis.ConnConfig
+}
+
+func (o redisConnOpt) MakeRedisClient() any {
+	return qredis.NewClient(o.cfg)
+}
+
 // getRedisOpt returns the asynq redis connection option.
-func getRedisOpt() asynq.RedisClientOpt {
-	cfg := loadConfig()
-	return asynq.RedisClientOpt{
-		Addr:     cfg.RedisAddr,
-		Password: cfg.RedisPassword,
-		DB:       cfg.RedisDB,
-	}
+func getRedisOpt() asynq.RedisConnOpt {
+	return redisConnOpt{cfg: loadConfig().redis}
 }
 
 // getClient returns the singleton asynq client (lazy init).
@@ -183,9 +179,12 @@ func initServer() {
 		cfg := loadConfig()
 
 		serverCfg := asynq.Config{
-			Concurrency:    cfg.Concurrency,
-			Queues:         cfg.Queues,
-			StrictPriority: cfg.StrictPriority,
+			Concurrency:     cfg.Concurrency,
+			Queues:          serverQueues(cfg),
+			StrictPriority:  cfg.StrictPriority,
+			RetryDelayFunc:  retryDelay,
+			ErrorHandler:    asynq.ErrorHandlerFunc(errorHandler),
+			ShutdownTimeout: cfg.ShutdownTimeout,
 		}
 
 		server = asynq.NewServer(getRedisOpt(), serverCfg)
@@ -197,56 +196,77 @@ func initServer() {
 // Called automatically on first MonitorHandler() or Enqueue() call.
 // This is idempotent and safe to call multiple times.
 func ensureWorkerStarted() {
-	workerOnce.Do(func() {
-		handlersMux.RLock()
-		hasHandlers := len(handlers) > 0
-		handlersMux.RUnlock()
+	handlersMux.RLock()
+	hasHandlers := len(handlers) > 0
+	handlersMux.RUnlock()
 
-		if !hasHandlers {
-			return
-		}
+	if !hasHandlers {
+		return
+	}
+
+	lifecycleMux.Lock()
+	defer lifecycleMux.Unlock()
+	if err := startWorker(); err != nil {
+		fmt.Fprintf(os.Stderr, "asynq: server error: %v\n", err)
+	}
+}
 
-		initServer()
+// startWorker registers the handlers and starts the server, the scheduler
+// and signal handling, unless the worker is already running.
+// The caller must hold lifecycleMux.
+func startWorker() error {
+	serverMux.Lock()
+	active := workerActive
+	serverMux.Unlock()
+	if active {
+		return nil
+	}
 
-		// Register all handlers to mux
-		handlersMux.RLock()
-		for taskType, handler := range handlers {
-			h := handler // capture
-			mux.HandleFunc(taskType, func(ctx context.Context, t *asynq.Task) error {
-				return h(ctx, t.Payload())
-			})
-		}
-		handlersMux.RUnlock()
+	initServer()
 
-		// Start server in goroutine
-		go func() {
-			if err := server.Run(mux); err != nil {
-				fmt.Fprintf(os.Stderr, "asynq: server error: %v\n", err)
-			}
-		}()
+	// Register all handlers to mux
+	handlersMux.RLock()
+	for taskType, handler := range handlers {
+		mux.HandleFunc(taskType, taskHandler(taskType, handler))
+	}
+	handlersMux.RUnlock()
 
-		workerActive = true
+	// Start does not connect, so check Redis first. On failure, drop the
+	// server and mux so the next call starts over with fresh registrations.
+	err := server.Ping()
+	if err == nil {
+		err = server.Start(mux)
+	}
+	if err != nil {
+		server, mux, serverOnce = nil, nil, sync.Once{}
+		return err
+	}
 
-		// Start scheduler if cron tasks are registered
-		startScheduler()
+	serverMux.Lock()
+	workerActive = true
+	done := make(chan struct{})
+	workerDone = done
+	serverMux.Unlock()
 
-		// Register graceful shutdown
-		registerShutdown()
-	})
+	// Start scheduler if cron tasks are registered
+	startScheduler()
+
+	// Graceful shutdown on SIGINT/SIGTERM
+	go handleSignals(done)
+	return nil
 }
 
-// registerShutdown registers signal handlers for graceful shutdown.
-func registerShutdown() {
-	shutdownOnce.Do(func() {
-		go func() {
-			sigCh := make(chan os.Signal, 1)
-			signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-			<-sigCh
-
-			// Graceful shutdown
-			Shutdown()
-		}()
-	})
+// handleSignals shuts down on SIGINT/SIGTERM, until done is closed.
+func handleSignals(done <-chan struct{}) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	select {
+	case <-sigCh:
+		Shutdown(context.Background())
+	case <-done:
+	}
 }
 
 // Handle registers a handler for the given task type.
@@ -258,59 +278,9 @@ func Handle(taskType string, handler HandlerFunc) {
 	handlersMux.Unlock()
 }
 
-// Cron registers a periodic task with cron expression.
-// The task will be enqueued according to the cron schedule.
-//
-// Cron expressions:
-//
-//	"*/5 * * * *"     - Every 5 minutes
-//	"0 * * * *"       - Every hour
-//	"0 9 * * *"       - Every day at 9:00 AM
-//	"0 9 * * 1"       - Every Monday at 9:00 AM
-//	"@every 1h"       - Every hour
-//	"@every 30m"      - Every 30 minutes
-//	"@daily"          - Every day at midnight
-//
-// Example:
-//
-//	asynq.Cron("@every 5m", "metrics:collect", nil)
-//	asynq.Cron("0 9 * * *", "report:daily", ReportPayload{Type: "daily"})
-func Cron(cronspec string, taskType string, payload any, opts ...Option) {
-	cronTasks = append(cronTasks, cronTask{
-		cronspec: cronspec,
-		taskType: taskType,
-		payload:  payload,
-		opts:     opts,
-	})
-}
-
-// startScheduler starts the cron scheduler if cron tasks are registered.
-func startScheduler() {
-	schedulerOnce.Do(func() {
-		if len(cronTasks) == 0 {
-			return
-		}
-
-		loc, _ := time.LoadLocation("Local")
-		scheduler = asynq.NewScheduler(getRedisOpt(), &asynq.SchedulerOpts{
-			Location: loc,
-		})
-
-		for _, ct := range cronTasks {
-			data, _ := marshal(ct.payload)
-			task := asynq.NewTask(ct.taskType, data, ct.opts...)
-			scheduler.Register(ct.cronspec, task)
-		}
-
-		go func() {
-			if err := scheduler.Run(); err != nil {
-				fmt.Fprintf(os.Stderr, "asynq: scheduler error: %v\n", err)
-			}
-		}()
-	})
-}
-
-// Run starts the worker server and blocks until shutdown signal is received.
+// Run starts the worker server and blocks until Shutdown is called or a
+// shutdown signal is received. If the worker was already started by
+// Enqueue or Mount, Run only waits for it to stop.
 // Use this for dedicated worker processes that don't serve HTTP.
 func Run() error {
 	handlersMux.RLock()
@@ -321,55 +291,64 @@ func Run() error {
 		return fmt.Errorf("asynq: no handlers registered")
 	}
 
-	initServer()
-
-	// Register all handlers
-	handlersMux.RLock()
-	for taskType, handler := range handlers {
-		h := handler // capture
-		mux.HandleFunc(taskType, func(ctx context.Context, t *asynq.Task) error {
-			return h(ctx, t.Payload())
-		})
+	lifecycleMux.Lock()
+	err := startWorker()
+	serverMux.Lock()
+	done := workerDone
+	serverMux.Unlock()
+	lifecycleMux.Unlock()
+	if err != nil {
+		return err
 	}
-	handlersMux.RUnlock()
 
-	// Start scheduler for cron tasks
-	startScheduler()
-
-	// Register graceful shutdown
-	registerShutdown()
-
-	// Run blocks until shutdown
-	return server.Run(mux)
+	<-done
+	return nil
 }
 
 // Enqueue enqueues a task for immediate processing.
 // Automatically starts the worker if handlers are registered.
 func Enqueue(taskType string, payload any, opts ...Option) (*TaskInfo, error) {
-	// Auto-start worker on first enqueue
-	ensureWorkerStarted()
-
 	data, err := marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("asynq: failed to marshal payload: %w", err)
 	}
+	tp := traceParent(nil, opts)
+	if h := activeHarness(); h != nil {
+		return h.enqueue(taskType, data, tp, opts)
+	}
+
+	// Auto-start worker on first enqueue
+	ensureWorkerStarted()
 
-	task := asynq.NewTask(taskType, data, opts...)
+	task := asynq.NewTask(taskType, withTrace(tp, data), opts...)
 	return getClient().Enqueue(task)
 }
 
 // EnqueueContext enqueues a task with context for immediate processing.
+// The OpenTelemetry span of ctx, if any, is propagated to the handler
+// (see TraceContext).
 // Automatically starts the worker if handlers are registered.
 func EnqueueContext(ctx context.Context, taskType string, payload any, opts ...Option) (*TaskInfo, error) {
-	// Auto-start worker on first enqueue
-	ensureWorkerStarted()
+	if activeHarness() == nil {
+		// Auto-start worker on first enqueue
+		ensureWorkerStarted()
+	}
+	return enqueue(ctx, taskType, payload, opts...)
+}
 
+// enqueue enqueues a task without starting the worker, so it is safe to
+// call from the worker itself, even while Shutdown holds lifecycleMux.
+func enqueue(ctx context.Context, taskType string, payload any, opts ...Option) (*TaskInfo, error) {
 	data, err := marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("asynq: failed to marshal payload: %w", err)
 	}
+	tp := traceParent(ctx, opts)
+	if h := activeHarness(); h != nil {
+		return h.enqueue(taskType, data, tp, opts)
+	}
 
-	task := asynq.NewTask(taskType, data, opts...)
+	task := asynq.NewTask(taskType, withTrace(tp, data), opts...)
 	return getClient().EnqueueContext(ctx, task)
 }
 
@@ -391,20 +370,65 @@ func EnqueueUnique(taskType string, payload any, ttl time.Duration, opts ...Opti
 	return Enqueue(taskType, payload, opts...)
 }
 
-// Shutdown gracefully shuts down the worker, scheduler and client.
-func Shutdown() {
-	serverMux.Lock()
-	defer serverMux.Unlock()
+// Shutdown gracefully shuts down the worker, scheduler and client. It stops
+// fetching new tasks and blocks until active tasks finish, ctx expires or
+// asynq.shutdown_timeout elapses; tasks still running then are requeued.
+//
+// Shutdown resets the package state, so a later Run or Enqueue starts
+// afresh. It is safe to call multiple times. Returns ctx.Err() if ctx
+// expired first; the shutdown then completes in the background.
+func Shutdown(ctx context.Context) error {
+	lifecycleMux.Lock()
+	defer lifecycleMux.Unlock()
+
+	cronMux.Lock()
+	sched := scheduler
+	cronMux.Unlock()
+
+	stopped := make(chan struct{})
+	go func(sched *asynq.Scheduler, srv *asynq.Server, cli *asynq.Client, insp *asynq.Inspector, rc redis.UniversalClient) {
+		defer close(stopped)
+		if sched != nil {
+			sched.Shutdown()
+		}
+		if srv != nil {
+			srv.Shutdown()
+		}
+		if cli != nil {
+			cli.Close()
+		}
+		if insp != nil {
+			insp.Close()
+		}
+		if rc != nil {
+			rc.Close()
+		}
+	}(sched, server, client, inspector, resultClient)
 
-	if scheduler != nil {
-		scheduler.Shutdown()
-	}
-	if server != nil {
-		server.Shutdown()
+	var err error
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		err = ctx.Err()
 	}
-	if client != nil {
-		client.Close()
+
+	server, mux, serverOnce = nil, nil, sync.Once{}
+	client, clientOnce = nil, sync.Once{}
+	inspector, inspectorOnce = nil, sync.Once{}
+	resultClient, resultClientOnce = nil, sync.Once{}
+
+	serverMux.Lock()
+	if workerDone != nil {
+		close(workerDone)
+		workerDone = nil
 	}
+	workerActive = false
+	serverMux.Unlock()
+
+	cronMux.Lock()
+	scheduler = nil
+	cronMux.Unlock()
+	return err
 }
 
 // marshal converts payload to JSON bytes.
@@ -427,6 +451,9 @@ func Unmarshal(payload []byte, v any) error {
 // The ID of a task is guaranteed to be unique.
 // The ID of a task doesn't change if the task is being retried.
 func GetTaskID(ctx context.Context) string {
+	if id, ok := ctx.Value(taskIDKey{}).(string); ok {
+		return id
+	}
 	id, ok := asynq.GetTaskID(ctx)
 	if !ok {
 		return ""
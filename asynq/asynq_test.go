@@ -0,0 +1,182 @@
+package asynq
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+// setupTestRedis points the package at a test Redis (localhost:6379, db 15)
+// with all queues cleared, and skips the test when Redis is unavailable.
+// Settings are applied from asynq.* viper keys set before the call.
+func setupTestRedis(t *testing.T) asynq.RedisConnOpt {
+	t.Helper()
+	if os.Getenv("REDIS_TEST_SKIP") != "" {
+		t.Skip("Skipping Redis tests (REDIS_TEST_SKIP is set)")
+	}
+	conn, err := net.DialTimeout("tcp", "localhost:6379", time.Second)
+	if err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+	conn.Close()
+
+	viper.Set("redis.addr", "localhost:6379")
+	viper.Set("asynq.redis.db", 15)
+	configOnce = sync.Once{}
+	globalConfig = nil
+	// Fresh clients, since asynq clients cache which queues they registered
+	Shutdown(context.Background())
+	opt := getRedisOpt()
+
+	rdb := opt.MakeRedisClient().(redis.UniversalClient)
+	defer rdb.Close()
+	if err := rdb.FlushDB(context.Background()).Err(); err != nil {
+		t.Fatalf("failed to flush test Redis: %v", err)
+	}
+	return opt
+}
+
+func TestShutdownAndRestart(t *testing.T) {
+	setupTestRedis(t)
+
+	handlersMux.Lock()
+	savedHandlers := handlers
+	handlers = make(map[string]HandlerFunc)
+	handlersMux.Unlock()
+	t.Cleanup(func() {
+		Shutdown(context.Background())
+		handlersMux.Lock()
+		handlers = savedHandlers
+		handlersMux.Unlock()
+	})
+
+	started := make(chan string, 2)
+	var completed atomic.Int32
+	Handle("slow", func(ctx context.Context, payload []byte) error {
+		started <- string(payload)
+		time.Sleep(500 * time.Millisecond)
+		completed.Add(1)
+		return nil
+	})
+
+	// Enqueue starts the worker
+	if _, err := Enqueue("slow", []byte("first")); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task not started")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if completed.Load() != 1 {
+		t.Fatal("Shutdown returned before the active task completed")
+	}
+	if err := Shutdown(ctx); err != nil {
+		t.Errorf("second Shutdown failed: %v", err)
+	}
+
+	// Run re-initializes the worker and blocks until the next Shutdown
+	runErr := make(chan error, 1)
+	go func() { runErr <- Run() }()
+	if _, err := Enqueue("slow", []byte("second")); err != nil {
+		t.Fatalf("Enqueue after Shutdown failed: %v", err)
+	}
+	select {
+	case p := <-started:
+		if p != "second" {
+			t.Errorf("unexpected task %q", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("task not started after restart")
+	}
+
+	select {
+	case err := <-runErr:
+		t.Fatalf("Run returned before Shutdown: %v", err)
+	default:
+	}
+	if err := Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if completed.Load() != 2 {
+		t.Error("second task did not complete")
+	}
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("Run failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Run did not return after Shutdown")
+	}
+}
+
+func TestRunUnreachableRedis(t *testing.T) {
+	setupTestRedis(t)
+
+	handlersMux.Lock()
+	savedHandlers := handlers
+	handlers = make(map[string]HandlerFunc)
+	handlersMux.Unlock()
+	t.Cleanup(func() {
+		Shutdown(context.Background())
+		handlersMux.Lock()
+		handlers = savedHandlers
+		handlersMux.Unlock()
+		viper.Set("redis.addr", "localhost:6379")
+		configOnce = sync.Once{}
+		globalConfig = nil
+	})
+	Handle("noop", func(ctx context.Context, payload []byte) error { return nil })
+
+	viper.Set("redis.addr", "127.0.0.1:1")
+	configOnce = sync.Once{}
+	globalConfig = nil
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- Run() }()
+	select {
+	case err := <-runErr:
+		if err == nil {
+			t.Fatal("expected Run to fail with unreachable Redis")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run blocked with unreachable Redis")
+	}
+
+	// The failed start must not leave the worker marked as running
+	viper.Set("redis.addr", "localhost:6379")
+	configOnce = sync.Once{}
+	globalConfig = nil
+	go func() { runErr <- Run() }()
+	select {
+	case err := <-runErr:
+		t.Fatalf("Run returned before Shutdown: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("Run failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Run did not return after Shutdown")
+	}
+}
@@ -0,0 +1,227 @@
+package asynq
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	// cronMux guards cronTasks and scheduler
+	cronMux   sync.Mutex
+	scheduler *asynq.Scheduler
+	cronTasks []*CronEntry
+)
+
+// CronEntry is a periodic task registered with Cron or CronFunc.
+type CronEntry struct {
+	cronspec  string
+	taskType  string
+	payload   any
+	payloadFn func() any // CronFunc only
+	opts      []asynq.Option
+
+	// Set while registered with the running scheduler
+	entryID string
+	task    *asynq.Task
+}
+
+// CronTaskInfo describes a registered cron task, see ListCronTasks.
+type CronTaskInfo struct {
+	ID       string    `json:"id"` // Scheduler entry ID, empty until the worker starts
+	Spec     string    `json:"spec"`
+	TaskType string    `json:"task_type"`
+	Dynamic  bool      `json:"dynamic"` // Registered with CronFunc
+	Next     time.Time `json:"next"`    // Zero if the spec is invalid
+}
+
+// Cron registers a periodic task with cron expression.
+// The task will be enqueued according to the cron schedule.
+//
+// Cron expressions:
+//
+//	"*/5 * * * *"     - Every 5 minutes
+//	"0 * * * *"       - Every hour
+//	"0 9 * * *"       - Every day at 9:00 AM
+//	"0 9 * * 1"       - Every Monday at 9:00 AM
+//	"@every 1h"       - Every hour
+//	"@every 30m"      - Every 30 minutes
+//	"@daily"          - Every day at midnight
+//
+// Tasks registered after the worker started are scheduled right away.
+// The returned entry can be unregistered.
+//
+// Example:
+//
+//	asynq.Cron("@every 5m", "metrics:collect", nil)
+//	asynq.Cron("0 9 * * *", "report:daily", ReportPayload{Type: "daily"})
+func Cron(cronspec string, taskType string, payload any, opts ...Option) *CronEntry {
+	return addCron(&CronEntry{
+		cronspec: cronspec,
+		taskType: taskType,
+		payload:  payload,
+		opts:     opts,
+	})
+}
+
+// CronFunc is like Cron, but calls payloadFn each time the schedule fires
+// and enqueues its result.
+//
+// Example:
+//
+//	asynq.CronFunc("0 9 * * *", "report:daily", func() any {
+//	    return ReportPayload{Date: time.Now().AddDate(0, 0, -1).Format("2006-01-02")}
+//	})
+func CronFunc(cronspec string, taskType string, payloadFn func() any, opts ...Option) *CronEntry {
+	return addCron(&CronEntry{
+		cronspec:  cronspec,
+		taskType:  taskType,
+		payloadFn: payloadFn,
+		opts:      opts,
+	})
+}
+
+func addCron(e *CronEntry) *CronEntry {
+	cronMux.Lock()
+	defer cronMux.Unlock()
+	cronTasks = append(cronTasks, e)
+
+	if scheduler != nil {
+		registerCron(scheduler, e)
+		return e
+	}
+	serverMux.Lock()
+	active := workerActive
+	serverMux.Unlock()
+	if active {
+		startSchedulerLocked()
+	}
+	return e
+}
+
+// Unregister removes the task from the schedule. It is a no-op if the task
+// was already unregistered.
+func (e *CronEntry) Unregister() error {
+	cronMux.Lock()
+	defer cronMux.Unlock()
+
+	i := slices.Index(cronTasks, e)
+	if i < 0 {
+		return nil
+	}
+	cronTasks = slices.Delete(cronTasks, i, i+1)
+
+	if scheduler == nil || e.entryID == "" {
+		return nil
+	}
+	id := e.entryID
+	e.entryID, e.task = "", nil
+	return scheduler.Unregister(id)
+}
+
+// payloadData marshals the payload, calling payloadFn for CronFunc tasks.
+func (e *CronEntry) payloadData() ([]byte, error) {
+	payload := e.payload
+	if e.payloadFn != nil {
+		payload = e.payloadFn()
+	}
+	return marshal(payload)
+}
+
+// ListCronTasks returns the registered cron tasks in registration order,
+// with their next run time in the local time zone.
+func ListCronTasks() []CronTaskInfo {
+	cronMux.Lock()
+	defer cronMux.Unlock()
+
+	now := time.Now()
+	infos := make([]CronTaskInfo, len(cronTasks))
+	for i, e := range cronTasks {
+		infos[i] = CronTaskInfo{
+			ID:       e.entryID,
+			Spec:     e.cronspec,
+			TaskType: e.taskType,
+			Dynamic:  e.payloadFn != nil,
+		}
+		// Same parser as the scheduler
+		if schedule, err := cron.ParseStandard(e.cronspec); err == nil {
+			infos[i].Next = schedule.Next(now)
+		}
+	}
+	return infos
+}
+
+// startScheduler starts the cron scheduler if cron tasks are registered.
+func startScheduler() {
+	cronMux.Lock()
+	defer cronMux.Unlock()
+	startSchedulerLocked()
+}
+
+// startSchedulerLocked is startScheduler with cronMux held.
+func startSchedulerLocked() {
+	if scheduler != nil || len(cronTasks) == 0 {
+		return
+	}
+
+	loc, _ := time.LoadLocation("Local")
+	scheduler = asynq.NewScheduler(getRedisOpt(), &asynq.SchedulerOpts{
+		Location:       loc,
+		PreEnqueueFunc: refreshCronPayload,
+	})
+
+	for _, e := range cronTasks {
+		registerCron(scheduler, e)
+	}
+
+	if err := scheduler.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "asynq: scheduler error: %v\n", err)
+	}
+}
+
+// registerCron registers e with s. The caller must hold cronMux.
+func registerCron(s *asynq.Scheduler, e *CronEntry) {
+	var data []byte
+	if e.payloadFn == nil {
+		data, _ = marshal(e.payload)
+	}
+	task := asynq.NewTask(e.taskType, data, e.opts...)
+	id, err := s.Register(e.cronspec, task)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "asynq: cron %q for %s: %v\n", e.cronspec, e.taskType, err)
+		return
+	}
+	e.entryID, e.task = id, task
+}
+
+// refreshCronPayload is the scheduler's PreEnqueueFunc. A scheduler entry
+// always enqueues the task it was registered with, and task payloads are
+// immutable, so for CronFunc tasks the task is replaced in place by one
+// carrying a fresh payload.
+func refreshCronPayload(task *asynq.Task, _ []asynq.Option) {
+	cronMux.Lock()
+	var entry *CronEntry
+	for _, e := range cronTasks {
+		if e.task == task {
+			entry = e
+			break
+		}
+	}
+	cronMux.Unlock()
+	if entry == nil || entry.payloadFn == nil {
+		return
+	}
+
+	data, err := entry.payloadData()
+	if err != nil {
+		// The previous payload is enqueued
+		fmt.Fprintf(os.Stderr, "asynq: cron payload for %s: %v\n", entry.taskType, err)
+		return
+	}
+	*task = *asynq.NewTask(entry.taskType, data, entry.opts...)
+}
@@ -0,0 +1,91 @@
+package asynq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestCronFuncPayloads(t *testing.T) {
+	h := TestMode(t)
+
+	n := 0
+	entry := CronFunc("@every 1m", "stats:snapshot", func() any {
+		n++
+		return map[string]int{"seq": n}
+	})
+
+	// Harness
+	h.FireCron("stats:snapshot")
+	h.FireCron("stats:snapshot")
+	tasks := h.Enqueued()
+	if len(tasks) != 2 || string(tasks[0].Payload) != `{"seq":1}` || string(tasks[1].Payload) != `{"seq":2}` {
+		t.Fatalf("expected a fresh payload per firing, got %+v", tasks)
+	}
+
+	// Scheduler: each firing runs the PreEnqueueFunc on the registered task
+	s := asynq.NewScheduler(asynq.RedisClientOpt{Addr: "localhost:6379"}, nil)
+	cronMux.Lock()
+	registerCron(s, entry)
+	cronMux.Unlock()
+	var payloads []string
+	for range 2 {
+		refreshCronPayload(entry.task, nil)
+		payloads = append(payloads, string(entry.task.Payload()))
+	}
+	if payloads[0] != `{"seq":3}` || payloads[1] != `{"seq":4}` || entry.task.Type() != "stats:snapshot" {
+		t.Errorf("unexpected scheduled payloads: %v", payloads)
+	}
+}
+
+func TestCronUnregister(t *testing.T) {
+	setupTestRedis(t)
+	cronMux.Lock()
+	savedCron := cronTasks
+	cronTasks = nil
+	cronMux.Unlock()
+	t.Cleanup(func() {
+		Shutdown(context.Background())
+		cronMux.Lock()
+		cronTasks = savedCron
+		cronMux.Unlock()
+	})
+
+	daily := Cron("0 9 * * *", "report:daily", nil)
+	Cron("@every 5m", "metrics:collect", nil)
+	if tasks := ListCronTasks(); len(tasks) != 2 || tasks[0].ID != "" {
+		t.Fatalf("expected 2 unscheduled tasks, got %+v", tasks)
+	}
+
+	startScheduler()
+	tasks := ListCronTasks()
+	if len(tasks) != 2 || tasks[0].ID == "" || tasks[0].TaskType != "report:daily" {
+		t.Fatalf("expected 2 scheduled tasks, got %+v", tasks)
+	}
+	if next := tasks[0].Next; next.Hour() != 9 || next.Minute() != 0 || !next.After(time.Now()) {
+		t.Errorf("unexpected next run: %v", next)
+	}
+
+	dailyID := tasks[0].ID
+	if err := daily.Unregister(); err != nil {
+		t.Fatalf("Unregister failed: %v", err)
+	}
+	if err := daily.Unregister(); err != nil {
+		t.Errorf("second Unregister should be a no-op, got %v", err)
+	}
+	// Registered with the running scheduler right away
+	CronFunc("@every 1h", "cache:warm", func() any { return nil })
+	tasks = ListCronTasks()
+	if len(tasks) != 2 || tasks[0].TaskType != "metrics:collect" || tasks[1].ID == "" || !tasks[1].Dynamic {
+		t.Errorf("unexpected tasks after Unregister: %+v", tasks)
+	}
+
+	cronMux.Lock()
+	err := scheduler.Unregister(dailyID)
+	cronMux.Unlock()
+	if err == nil {
+		t.Error("scheduler entry should be removed by Unregister")
+	}
+}
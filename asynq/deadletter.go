@@ -0,0 +1,146 @@
+package asynq
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/spf13/viper"
+)
+
+// DeadLetterTaskType is the type of the tasks enqueued into
+// asynq.dead_letter_queue for exhausted tasks.
+const DeadLetterTaskType = "asynq:dead_letter"
+
+// DeadLetter is the payload of a dead-letter task: a task that failed its
+// final retry.
+type DeadLetter struct {
+	Type     string    `json:"type"`    // Original task type
+	Payload  []byte    `json:"payload"` // Original payload
+	Queue    string    `json:"queue"`   // Original queue
+	TaskID   string    `json:"task_id"`
+	Error    string    `json:"error"`    // Error of the final attempt
+	Attempts int       `json:"attempts"` // Attempts made, including the first
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// FailureFunc is called when a task fails its final retry.
+type FailureFunc func(ctx context.Context, taskType string, payload []byte, err error)
+
+var (
+	failureHook FailureFunc
+	failureMux  sync.RWMutex
+
+	consumeDeadLetters bool
+)
+
+// OnFailure sets a callback invoked when a task fails its final retry, or
+// returns an error wrapping SkipRetry. ctx carries the task ID and the
+// producer's trace context. Pass nil to remove the callback.
+//
+// Example:
+//
+//	asynq.OnFailure(func(ctx context.Context, taskType string, payload []byte, err error) {
+//	    alert.Send(fmt.Sprintf("task %s (%s) exhausted: %v", taskType, asynq.GetTaskID(ctx), err))
+//	})
+func OnFailure(fn FailureFunc) {
+	failureMux.Lock()
+	failureHook = fn
+	failureMux.Unlock()
+}
+
+// ConsumeDeadLetters registers handler for dead-letter tasks and makes the
+// worker of this process listen on asynq.dead_letter_queue. The payload is
+// a JSON DeadLetter. Call it before the worker starts, typically in a
+// dedicated operator process.
+//
+// Example:
+//
+//	asynq.ConsumeDeadLetters(func(ctx context.Context, payload []byte) error {
+//	    var dl asynq.DeadLetter
+//	    asynq.Unmarshal(payload, &dl)
+//	    return store.SaveFailedTask(ctx, dl)
+//	})
+//	asynq.Run()
+func ConsumeDeadLetters(handler HandlerFunc) {
+	Handle(DeadLetterTaskType, handler)
+	serverMux.Lock()
+	consumeDeadLetters = true
+	serverMux.Unlock()
+}
+
+// serverQueues returns the queues the worker listens on: the configured
+// queues plus the dead-letter queue when this process consumes it.
+func serverQueues(cfg *Config) map[string]int {
+	serverMux.Lock()
+	consume := consumeDeadLetters
+	serverMux.Unlock()
+	if !consume || cfg.DeadLetterQueue == "" {
+		return cfg.Queues
+	}
+	if _, ok := cfg.Queues[cfg.DeadLetterQueue]; ok {
+		return cfg.Queues
+	}
+	queues := make(map[string]int, len(cfg.Queues)+1)
+	for name, priority := range cfg.Queues {
+		queues[name] = priority
+	}
+	queues[cfg.DeadLetterQueue] = 1
+	return queues
+}
+
+// errorHandler is the worker's asynq.ErrorHandler. It dead-letters tasks
+// that will not be retried again.
+func errorHandler(ctx context.Context, t *asynq.Task, err error) {
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	queue, _ := asynq.GetQueueName(ctx)
+	ctx, payload := extractTrace(ctx, t.Payload())
+	handleFailure(ctx, DeadLetter{
+		Type:     t.Type(),
+		Payload:  payload,
+		Queue:    queue,
+		TaskID:   GetTaskID(ctx),
+		Attempts: retried + 1,
+	}, maxRetry, err)
+}
+
+// handleFailure stores the error as the task result, calls the OnFailure
+// callback and enqueues a dead letter if the failure is final: retries are
+// used up or err wraps SkipRetry.
+// Revoked tasks are neither retried nor archived, so they are ignored.
+func handleFailure(ctx context.Context, dl DeadLetter, maxRetry int, err error) {
+	if errors.Is(err, asynq.RevokeTask) || (dl.Attempts <= maxRetry && !errors.Is(err, SkipRetry)) {
+		return
+	}
+	storeFailure(ctx, dl.TaskID, dl.Queue, err)
+
+	failureMux.RLock()
+	fn := failureHook
+	failureMux.RUnlock()
+	if fn != nil {
+		fn(ctx, dl.Type, dl.Payload, err)
+	}
+
+	dlq := deadLetterQueue()
+	if dlq == "" || dl.Type == DeadLetterTaskType {
+		return
+	}
+	dl.Error = err.Error()
+	dl.FailedAt = time.Now()
+	if _, err := enqueue(ctx, DeadLetterTaskType, dl, Queue(dlq)); err != nil {
+		log.Printf("asynq: failed to dead-letter task %s (%s): %v", dl.Type, dl.TaskID, err)
+	}
+}
+
+// deadLetterQueue returns asynq.dead_letter_queue. TestMode reads it from
+// viper directly, since the harness needs no Redis configuration.
+func deadLetterQueue() string {
+	if activeHarness() != nil {
+		return viper.GetString("asynq.dead_letter_queue")
+	}
+	return loadConfig().DeadLetterQueue
+}
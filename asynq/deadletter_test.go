@@ -0,0 +1,219 @@
+package asynq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/spf13/viper"
+)
+
+func TestOnFailureFinalAttempt(t *testing.T) {
+	h := TestMode(t)
+
+	var failed []string
+	OnFailure(func(ctx context.Context, taskType string, payload []byte, err error) {
+		failed = append(failed, fmt.Sprintf("%s:%s:%s:%v", taskType, GetTaskID(ctx), payload, err))
+	})
+	Handle("webhook:deliver", func(ctx context.Context, payload []byte) error {
+		return errors.New("endpoint down")
+	})
+	Handle("email:send", func(ctx context.Context, payload []byte) error {
+		return fmt.Errorf("invalid address: %w", SkipRetry)
+	})
+
+	Enqueue("webhook:deliver", 1, TaskID("retrying"))          // Retries left
+	Enqueue("webhook:deliver", 2, TaskID("last"), MaxRetry(0)) // No retries
+	Enqueue("email:send", 3, TaskID("skip"))                   // SkipRetry
+	h.Drain(context.Background())
+
+	want := []string{
+		"webhook:deliver:last:2:endpoint down",
+		"email:send:skip:3:invalid address: skip retry for the task",
+	}
+	if fmt.Sprint(failed) != fmt.Sprint(want) {
+		t.Errorf("unexpected failures:\n got %q\nwant %q", failed, want)
+	}
+	for _, task := range h.Enqueued() {
+		if task.Type == DeadLetterTaskType {
+			t.Error("no dead letters without asynq.dead_letter_queue")
+		}
+	}
+}
+
+func TestDeadLetterQueue(t *testing.T) {
+	h := TestMode(t)
+	viper.Set("asynq.dead_letter_queue", "dead")
+	defer viper.Set("asynq.dead_letter_queue", nil)
+
+	Handle("webhook:deliver", func(ctx context.Context, payload []byte) error {
+		return errors.New("endpoint down")
+	})
+	var got []DeadLetter
+	ConsumeDeadLetters(func(ctx context.Context, payload []byte) error {
+		var dl DeadLetter
+		if err := Unmarshal(payload, &dl); err != nil {
+			return err
+		}
+		got = append(got, dl)
+		return errors.New("dead letters are never dead-lettered again")
+	})
+
+	Enqueue("webhook:deliver", map[string]string{"url": "https://example.com"}, TaskID("w1"), MaxRetry(0), Queue("critical"))
+	h.Drain(context.Background())
+
+	tasks := h.Enqueued()
+	if len(tasks) != 2 || tasks[1].Type != DeadLetterTaskType || tasks[1].Queue != "dead" {
+		t.Fatalf("expected a dead letter in the dead queue, got %+v", tasks)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 consumed dead letter, got %d", len(got))
+	}
+	dl := got[0]
+	if dl.Type != "webhook:deliver" || string(dl.Payload) != `{"url":"https://example.com"}` || dl.Queue != "critical" ||
+		dl.TaskID != "w1" || dl.Error != "endpoint down" || dl.Attempts != 1 || dl.FailedAt.IsZero() {
+		t.Errorf("unexpected dead letter: %+v", dl)
+	}
+}
+
+func TestServerQueues(t *testing.T) {
+	cfg := &Config{Queues: map[string]int{"default": 3}, DeadLetterQueue: "dead"}
+	if q := serverQueues(cfg); len(q) != 1 {
+		t.Errorf("dead-letter queue should only be served by consumers, got %v", q)
+	}
+
+	serverMux.Lock()
+	consumeDeadLetters = true
+	serverMux.Unlock()
+	defer func() {
+		serverMux.Lock()
+		consumeDeadLetters = false
+		serverMux.Unlock()
+	}()
+	if q := serverQueues(cfg); q["dead"] != 1 || q["default"] != 3 || len(cfg.Queues) != 1 {
+		t.Errorf("unexpected queues %v (config must not be modified)", q)
+	}
+}
+
+func TestDeadLetterWorker(t *testing.T) {
+	viper.Set("asynq.dead_letter_queue", "dead")
+	defer viper.Set("asynq.dead_letter_queue", nil)
+	opt := setupTestRedis(t)
+
+	var attempts atomic.Int32
+	failed := make(chan int32, 1)
+	OnFailure(func(ctx context.Context, taskType string, payload []byte, err error) {
+		failed <- attempts.Load()
+	})
+	defer OnFailure(nil)
+
+	srv := asynq.NewServer(opt, asynq.Config{
+		Concurrency:              1,
+		LogLevel:                 asynq.FatalLevel,
+		ErrorHandler:             asynq.ErrorHandlerFunc(errorHandler),
+		RetryDelayFunc:           func(int, error, *asynq.Task) time.Duration { return 0 },
+		DelayedTaskCheckInterval: 50 * time.Millisecond,
+	})
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("webhook:deliver", taskHandler("webhook:deliver", func(ctx context.Context, payload []byte) error {
+		attempts.Add(1)
+		return errors.New("endpoint down")
+	}))
+	if err := srv.Start(mux); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Shutdown()
+
+	if _, err := EnqueueContext(spanCtx(), "webhook:deliver", "p", MaxRetry(1), TaskID("w1")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case n := <-failed:
+		if n != 2 {
+			t.Errorf("OnFailure should fire after the final retry, fired after %d attempts", n)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("OnFailure not called")
+	}
+
+	inspector := asynq.NewInspector(opt)
+	defer inspector.Close()
+	var tasks []*asynq.TaskInfo
+	for range 50 {
+		if tasks, _ = inspector.ListPendingTasks("dead"); len(tasks) > 0 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if len(tasks) != 1 || tasks[0].Type != DeadLetterTaskType {
+		t.Fatalf("expected a dead letter in the dead queue, got %v", tasks)
+	}
+
+	_, payload := extractTrace(context.Background(), tasks[0].Payload)
+	var dl DeadLetter
+	if err := Unmarshal(payload, &dl); err != nil {
+		t.Fatal(err)
+	}
+	if dl.Type != "webhook:deliver" || string(dl.Payload) != `"p"` || dl.TaskID != "w1" || dl.Queue != "default" || dl.Attempts != 2 {
+		t.Errorf("unexpected dead letter: %+v", dl)
+	}
+	if string(tasks[0].Payload) == string(payload) {
+		t.Error("dead letter should carry the failed task's trace context")
+	}
+}
+
+func TestDeadLetterDuringShutdown(t *testing.T) {
+	viper.Set("asynq.dead_letter_queue", "dead")
+	defer viper.Set("asynq.dead_letter_queue", nil)
+	opt := setupTestRedis(t)
+
+	handlersMux.Lock()
+	savedHandlers := handlers
+	handlers = make(map[string]HandlerFunc)
+	handlersMux.Unlock()
+	t.Cleanup(func() {
+		Shutdown(context.Background())
+		handlersMux.Lock()
+		handlers = savedHandlers
+		handlersMux.Unlock()
+	})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	Handle("report:build", func(ctx context.Context, payload []byte) error {
+		close(started)
+		<-release
+		return fmt.Errorf("template missing: %w", SkipRetry)
+	})
+	if _, err := Enqueue("report:build", "r", TaskID("r1")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task not started")
+	}
+
+	// The task fails while Shutdown drains the worker
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- Shutdown(ctx) }()
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	if err := <-shutdownErr; err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	inspector := asynq.NewInspector(opt)
+	defer inspector.Close()
+	tasks, _ := inspector.ListPendingTasks("dead")
+	if len(tasks) != 1 || tasks[0].Type != DeadLetterTaskType {
+		t.Fatalf("expected a dead letter in the dead queue, got %v", tasks)
+	}
+}
@@ -0,0 +1,100 @@
+package asynq
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/hibiken/asynq"
+)
+
+// QueueStats holds the task counts of a queue.
+type QueueStats struct {
+	Size      int  `json:"size"` // All tasks in the queue, excluding completed
+	Pending   int  `json:"pending"`
+	Active    int  `json:"active"`
+	Scheduled int  `json:"scheduled"`
+	Retry     int  `json:"retry"`
+	Archived  int  `json:"archived"`
+	Paused    bool `json:"paused"`
+}
+
+var (
+	inspector     *asynq.Inspector
+	inspectorOnce sync.Once
+)
+
+// getInspector returns the singleton inspector (lazy init). It shares the
+// worker's Redis configuration but not its lifecycle, so it can be used
+// before the worker starts and in processes without handlers.
+func getInspector() *asynq.Inspector {
+	inspectorOnce.Do(func() {
+		inspector = asynq.NewInspector(getRedisOpt())
+	})
+	return inspector
+}
+
+// Stats returns the task counts of every configured queue, including the
+// dead-letter queue when set. Queues that never received a task report zero
+// counts.
+func Stats() (map[string]QueueStats, error) {
+	cfg := loadConfig()
+	names := make([]string, 0, len(cfg.Queues)+1)
+	for name := range cfg.Queues {
+		names = append(names, name)
+	}
+	if _, ok := cfg.Queues[cfg.DeadLetterQueue]; cfg.DeadLetterQueue != "" && !ok {
+		names = append(names, cfg.DeadLetterQueue)
+	}
+
+	existing, err := getInspector().Queues()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]QueueStats, len(names))
+	for _, name := range names {
+		if !slices.Contains(existing, name) {
+			stats[name] = QueueStats{}
+			continue
+		}
+		info, err := getInspector().GetQueueInfo(name)
+		if err != nil {
+			return nil, err
+		}
+		stats[name] = QueueStats{
+			Size:      info.Size,
+			Pending:   info.Pending,
+			Active:    info.Active,
+			Scheduled: info.Scheduled,
+			Retry:     info.Retry,
+			Archived:  info.Archived,
+			Paused:    info.Paused,
+		}
+	}
+	return stats, nil
+}
+
+// CancelTask signals the worker processing the task to cancel it: the
+// handler's ctx is canceled and the task is retried per its retry policy.
+// Tasks not yet running are removed with DeleteScheduled.
+func CancelTask(taskID string) error {
+	return getInspector().CancelProcessing(taskID)
+}
+
+// DeleteScheduled deletes a task that is not running (scheduled, pending,
+// retry or archived) from queue. Returns asynq.ErrTaskNotFound if there is
+// no such task.
+func DeleteScheduled(queue, taskID string) error {
+	return getInspector().DeleteTask(queue, taskID)
+}
+
+// PauseQueue stops workers from processing tasks from the queue. Tasks can
+// still be enqueued.
+func PauseQueue(name string) error {
+	return getInspector().PauseQueue(name)
+}
+
+// ResumeQueue resumes processing of a paused queue.
+func ResumeQueue(name string) error {
+	return getInspector().UnpauseQueue(name)
+}
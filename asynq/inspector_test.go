@@ -0,0 +1,107 @@
+package asynq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/spf13/viper"
+)
+
+func TestStats(t *testing.T) {
+	viper.Set("asynq.queues", map[string]int{"critical": 6, "default": 3})
+	viper.Set("asynq.dead_letter_queue", "dead")
+	defer viper.Set("asynq.queues", nil)
+	defer viper.Set("asynq.dead_letter_queue", nil)
+	setupTestRedis(t)
+
+	getClient().Enqueue(asynq.NewTask("email:send", nil))
+	getClient().Enqueue(asynq.NewTask("email:send", nil))
+	getClient().Enqueue(asynq.NewTask("report:daily", nil), Queue("critical"), ProcessIn(time.Hour), TaskID("r1"))
+
+	stats, err := Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if len(stats) != 3 {
+		t.Errorf("expected configured queues and the dead-letter queue, got %v", stats)
+	}
+	if s := stats["default"]; s.Size != 2 || s.Pending != 2 {
+		t.Errorf("unexpected default stats: %+v", s)
+	}
+	if s := stats["critical"]; s.Size != 1 || s.Scheduled != 1 {
+		t.Errorf("unexpected critical stats: %+v", s)
+	}
+	if s := stats["dead"]; s != (QueueStats{}) {
+		t.Errorf("empty queue should report zero counts: %+v", s)
+	}
+
+	if err := DeleteScheduled("critical", "r1"); err != nil {
+		t.Fatalf("DeleteScheduled failed: %v", err)
+	}
+	if err := DeleteScheduled("critical", "r1"); !errors.Is(err, asynq.ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+
+	if err := PauseQueue("default"); err != nil {
+		t.Fatalf("PauseQueue failed: %v", err)
+	}
+	stats, _ = Stats()
+	if !stats["default"].Paused || stats["critical"].Scheduled != 0 {
+		t.Errorf("unexpected stats after pause/delete: %+v", stats)
+	}
+	if err := ResumeQueue("default"); err != nil {
+		t.Fatalf("ResumeQueue failed: %v", err)
+	}
+	if stats, _ = Stats(); stats["default"].Paused {
+		t.Error("queue should be resumed")
+	}
+}
+
+func TestCancelTask(t *testing.T) {
+	opt := setupTestRedis(t)
+
+	started := make(chan string, 1)
+	canceled := make(chan struct{})
+	srv := asynq.NewServer(opt, asynq.Config{Concurrency: 1, LogLevel: asynq.FatalLevel})
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("slow", func(ctx context.Context, task *asynq.Task) error {
+		started <- GetTaskID(ctx)
+		select {
+		case <-ctx.Done():
+			close(canceled)
+			return ctx.Err()
+		case <-time.After(10 * time.Second):
+			return nil
+		}
+	})
+	if err := srv.Start(mux); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Shutdown()
+
+	getClient().Enqueue(asynq.NewTask("slow", nil), TaskID("s1"))
+	select {
+	case <-started:
+	case <-time.After(10 * time.Second):
+		t.Fatal("task not started")
+	}
+
+	// Cancellation is a pub/sub signal: repeat it in case the worker's
+	// subscription is not established yet
+	deadline := time.After(5 * time.Second)
+	for {
+		if err := CancelTask("s1"); err != nil {
+			t.Fatalf("CancelTask failed: %v", err)
+		}
+		select {
+		case <-canceled:
+			return
+		case <-time.After(200 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("handler ctx not canceled")
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package asynq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// MiddlewareFunc wraps a task handler with cross-cutting behavior.
+type MiddlewareFunc func(next HandlerFunc) HandlerFunc
+
+// ErrWorkerStarted is returned by Use once the worker is running.
+var ErrWorkerStarted = errors.New("asynq: worker already started")
+
+var (
+	middlewares   []MiddlewareFunc
+	middlewareMux sync.RWMutex
+)
+
+// Use registers middleware applied to every handler, in registration order:
+// the first registered is the outermost. Middleware runs inside trace
+// restoration, so ctx carries the producer's span and the payload is the
+// one passed to Enqueue.
+//
+// Handlers are wrapped when the worker starts, so Use must be called before
+// that (before Mount, Run or the first Enqueue); afterwards it returns
+// ErrWorkerStarted and the middleware is not registered.
+//
+// Example:
+//
+//	asynq.Use(asynq.RecoverMiddleware, asynq.LoggingMiddleware)
+func Use(mw ...MiddlewareFunc) error {
+	serverMux.Lock()
+	defer serverMux.Unlock()
+	if workerActive {
+		return ErrWorkerStarted
+	}
+
+	middlewareMux.Lock()
+	middlewares = append(middlewares, mw...)
+	middlewareMux.Unlock()
+	return nil
+}
+
+// applyMiddleware wraps h with the registered middleware.
+func applyMiddleware(h HandlerFunc) HandlerFunc {
+	middlewareMux.RLock()
+	defer middlewareMux.RUnlock()
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// RecoverMiddleware converts a handler panic into an error, so the task is
+// retried like any other failure. The stack trace is logged.
+func RecoverMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, payload []byte) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("asynq: task %s panicked: %v\n%s", GetTaskID(ctx), r, debug.Stack())
+				err = fmt.Errorf("asynq: panic: %v", r)
+			}
+		}()
+		return next(ctx, payload)
+	}
+}
+
+// LoggingMiddleware logs the type, ID, duration and error of every task.
+func LoggingMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, payload []byte) error {
+		start := time.Now()
+		err := next(ctx, payload)
+		if err != nil {
+			log.Printf("asynq: task %s (%s) failed in %v: %v", GetTaskType(ctx), GetTaskID(ctx), time.Since(start), err)
+		} else {
+			log.Printf("asynq: task %s (%s) done in %v", GetTaskType(ctx), GetTaskID(ctx), time.Since(start))
+		}
+		return err
+	}
+}
+
+// taskTypeKey carries the task type in handler contexts.
+type taskTypeKey struct{}
+
+// GetTaskType returns the type of the task being processed, if any.
+func GetTaskType(ctx context.Context) string {
+	taskType, _ := ctx.Value(taskTypeKey{}).(string)
+	return taskType
+}
@@ -0,0 +1,98 @@
+package asynq
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	h := TestMode(t)
+
+	var calls []string
+	mw := func(name string) MiddlewareFunc {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(ctx context.Context, payload []byte) error {
+				calls = append(calls, name+":"+GetTaskType(ctx)+":"+string(payload))
+				return next(ctx, payload)
+			}
+		}
+	}
+	if err := Use(mw("outer"), mw("inner")); err != nil {
+		t.Fatal(err)
+	}
+	Handle("email:send", func(ctx context.Context, payload []byte) error {
+		calls = append(calls, "handler")
+		return nil
+	})
+
+	Enqueue("email:send", "x")
+	if err := h.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(calls, " "); got != `outer:email:send:"x" inner:email:send:"x" handler` {
+		t.Errorf("unexpected call order: %s", got)
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	h := TestMode(t)
+	log.SetOutput(new(bytes.Buffer))
+	defer log.SetOutput(os.Stderr)
+
+	Use(RecoverMiddleware)
+	Handle("crash", func(ctx context.Context, payload []byte) error {
+		panic("nil map")
+	})
+
+	Enqueue("crash", nil)
+	err := h.Drain(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "panic: nil map") {
+		t.Errorf("panic should become a task error, got %v", err)
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	h := TestMode(t)
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	Use(LoggingMiddleware)
+	Handle("ok", func(ctx context.Context, payload []byte) error { return nil })
+	Handle("fail", func(ctx context.Context, payload []byte) error { return errors.New("boom") })
+
+	Enqueue("ok", nil, TaskID("t1"))
+	Enqueue("fail", nil, TaskID("t2"))
+	h.Drain(context.Background())
+
+	out := buf.String()
+	if !strings.Contains(out, "task ok (t1) done in") || !strings.Contains(out, "task fail (t2) failed in") || !strings.Contains(out, "boom") {
+		t.Errorf("unexpected log output:\n%s", out)
+	}
+}
+
+func TestUseAfterWorkerStarted(t *testing.T) {
+	TestMode(t)
+	serverMux.Lock()
+	workerActive = true
+	serverMux.Unlock()
+	defer func() {
+		serverMux.Lock()
+		workerActive = false
+		serverMux.Unlock()
+	}()
+
+	if err := Use(LoggingMiddleware); !errors.Is(err, ErrWorkerStarted) {
+		t.Errorf("expected ErrWorkerStarted, got %v", err)
+	}
+	middlewareMux.RLock()
+	defer middlewareMux.RUnlock()
+	if len(middlewares) != 0 {
+		t.Error("middleware must not be registered after the worker started")
+	}
+}
@@ -1,6 +1,8 @@
 package asynq
 
 import (
+	"crypto/subtle"
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -8,7 +10,8 @@ import (
 )
 
 // Mount mounts the asynqmon web UI to the gin router at the specified path.
-// Automatically starts the worker if handlers are registered.
+// Automatically starts the worker if handlers are registered. When
+// asynq.monitor.username and password are set, the UI requires basic auth.
 //
 // Example:
 //
@@ -26,22 +29,14 @@ func Mount(r gin.IRouter, path string) {
 	// Calculate full base path for asynqmon
 	basePath := path
 	if rg, ok := r.(*gin.RouterGroup); ok && rg.BasePath() != "/" {
-		basePath = rg.BasePath() + path
+		basePath = strings.TrimSuffix(rg.BasePath(), "/") + path
 	}
 
-	// Auto-start worker
-	ensureWorkerStarted()
-
-	cfg := loadConfig()
-	h := asynqmon.New(asynqmon.Options{
-		RootPath:     basePath,
-		RedisConnOpt: getRedisOpt(),
-		ReadOnly:     cfg.Monitor.ReadOnly,
-	})
+	h := gin.WrapH(MonitorHTTPHandler(basePath))
 
 	// Register both exact path and wildcard to handle trailing slash redirects
-	r.Any(path, gin.WrapH(h))
-	r.Any(path+"/*any", gin.WrapH(h))
+	r.Any(path, h)
+	r.Any(path+"/*any", h)
 }
 
 // MonitorHandler returns a gin.HandlerFunc for the asynqmon web UI.
@@ -52,16 +47,43 @@ func Mount(r gin.IRouter, path string) {
 //
 //	r.Any("/asynq/*any", asynq.MonitorHandler("/asynq"))
 func MonitorHandler(basePath string) gin.HandlerFunc {
+	return gin.WrapH(MonitorHTTPHandler(basePath))
+}
+
+// MonitorHTTPHandler returns the asynqmon web UI as an http.Handler for
+// non-gin servers. basePath is the full URL path it is served under; the
+// handler expects the path unstripped.
+//
+// Example:
+//
+//	http.Handle("/asynq/", asynq.MonitorHTTPHandler("/asynq"))
+func MonitorHTTPHandler(basePath string) http.Handler {
 	// Auto-start worker when monitor is mounted
 	ensureWorkerStarted()
 
 	cfg := loadConfig()
-
 	h := asynqmon.New(asynqmon.Options{
 		RootPath:     basePath,
 		RedisConnOpt: getRedisOpt(),
 		ReadOnly:     cfg.Monitor.ReadOnly,
 	})
+	if cfg.Monitor.Username == "" || cfg.Monitor.Password == "" {
+		return h
+	}
+	return basicAuth(h, cfg.Monitor.Username, cfg.Monitor.Password)
+}
 
-	return gin.WrapH(h)
+// basicAuth guards h with HTTP basic auth.
+func basicAuth(h http.Handler, username, password string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(user), []byte(username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(pass), []byte(password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="asynq", charset="UTF-8"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
 }
@@ -0,0 +1,60 @@
+package asynq
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+func serveMonitor(t *testing.T, r http.Handler, method, path string, auth ...string) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	if len(auth) == 2 {
+		req.SetBasicAuth(auth[0], auth[1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestMountReadOnly(t *testing.T) {
+	viper.Set("asynq.monitor.readonly", true)
+	defer viper.Set("asynq.monitor.readonly", nil)
+	setupTestRedis(t)
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	Mount(r.Group("/admin"), "asynq")
+
+	if code := serveMonitor(t, r, "GET", "/admin/asynq/"); code != http.StatusOK {
+		t.Errorf("dashboard index: got %d", code)
+	}
+	if code := serveMonitor(t, r, "GET", "/admin/asynq/api/queues"); code != http.StatusOK {
+		t.Errorf("queues API: got %d", code)
+	}
+	if code := serveMonitor(t, r, "POST", "/admin/asynq/api/queues/default:pause"); code != http.StatusMethodNotAllowed {
+		t.Errorf("readonly mode should reject mutations, got %d", code)
+	}
+}
+
+func TestMonitorBasicAuth(t *testing.T) {
+	viper.Set("asynq.monitor.username", "admin")
+	viper.Set("asynq.monitor.password", "secret")
+	defer viper.Set("asynq.monitor.username", nil)
+	defer viper.Set("asynq.monitor.password", nil)
+	setupTestRedis(t)
+
+	h := MonitorHTTPHandler("/asynq")
+	if code := serveMonitor(t, h, "GET", "/asynq/"); code != http.StatusUnauthorized {
+		t.Errorf("missing credentials: got %d", code)
+	}
+	if code := serveMonitor(t, h, "GET", "/asynq/", "admin", "wrong"); code != http.StatusUnauthorized {
+		t.Errorf("wrong password: got %d", code)
+	}
+	if code := serveMonitor(t, h, "GET", "/asynq/", "admin", "secret"); code != http.StatusOK {
+		t.Errorf("valid credentials: got %d", code)
+	}
+}
@@ -0,0 +1,208 @@
+package asynq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	qredis "github.com/wordgate/qtoolkit/redis"
+)
+
+const (
+	// MaxResultSize is the largest marshaled result SetResult stores.
+	// Results live in a single Redis string read in one round trip; store
+	// large outputs elsewhere (e.g. S3) and return a reference.
+	MaxResultSize = 1 << 20
+
+	// defaultResultTTL keeps results of tasks enqueued without Retention
+	defaultResultTTL = 24 * time.Hour
+
+	resultKeyPrefix = "asynq:result:"
+)
+
+// TaskFailedError is returned by GetResult and WaitForResult for a task
+// that failed without further retries.
+type TaskFailedError struct {
+	TaskID  string
+	Message string // The handler's error
+}
+
+func (e *TaskFailedError) Error() string {
+	return fmt.Sprintf("asynq: task %s failed: %s", e.TaskID, e.Message)
+}
+
+// storedResult is the Redis value of a task result.
+type storedResult struct {
+	Value  json.RawMessage `json:"value,omitempty"`
+	Failed bool            `json:"failed,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+var (
+	resultClient     redis.UniversalClient
+	resultClientOnce sync.Once
+
+	// Wait polling starts at resultPollInterval and backs off to maxResultPollInterval
+	resultPollInterval    = 50 * time.Millisecond
+	maxResultPollInterval = time.Second
+)
+
+// getResultClient returns the Redis client for results (lazy init).
+func getResultClient() redis.UniversalClient {
+	resultClientOnce.Do(func() {
+		resultClient = qredis.NewClient(loadConfig().redis)
+	})
+	return resultClient
+}
+
+// SetResult stores value, marshaled as JSON, as the result of the task
+// handled with ctx. It is kept for the task's Retention, or 24 hours without
+// one, and replaces an earlier result of the same task. Results over
+// MaxResultSize are rejected.
+//
+// Example:
+//
+//	asynq.Handle("export:csv", func(ctx context.Context, payload []byte) error {
+//	    url, err := export(ctx, payload)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    return asynq.SetResult(ctx, map[string]string{"url": url})
+//	})
+func SetResult(ctx context.Context, value any) error {
+	taskID := GetTaskID(ctx)
+	if taskID == "" {
+		return errors.New("asynq: SetResult called outside a task handler")
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("asynq: failed to marshal result: %w", err)
+	}
+	if len(data) > MaxResultSize {
+		return fmt.Errorf("asynq: result of task %s is %d bytes, over MaxResultSize", taskID, len(data))
+	}
+	queue, _ := asynq.GetQueueName(ctx)
+	return writeResult(ctx, taskID, queue, storedResult{Value: data})
+}
+
+// GetResult reads the result of taskID into dest. found is false while the
+// task has not stored a result. A task that failed for good returns found
+// and a *TaskFailedError.
+func GetResult(taskID string, dest any) (found bool, err error) {
+	return getResult(context.Background(), taskID, dest)
+}
+
+// WaitForResult blocks until taskID has a result, reads it into dest, and
+// returns nil or the task's *TaskFailedError. It is notified through Redis
+// keyspace notifications when the server has them enabled
+// (notify-keyspace-events with K and $), and polls otherwise. Returns
+// ctx.Err() when ctx is done first.
+//
+// Example:
+//
+//	info, _ := asynq.Enqueue("export:csv", req, asynq.Retention(time.Hour))
+//	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+//	defer cancel()
+//	var out struct{ URL string }
+//	err := asynq.WaitForResult(ctx, info.ID, &out)
+func WaitForResult(ctx context.Context, taskID string, dest any) error {
+	var notify <-chan *redis.Message
+	if activeHarness() == nil {
+		cfg := loadConfig()
+		channel := fmt.Sprintf("__keyspace@%d__:%s%s", cfg.redis.DB, resultKeyPrefix, taskID)
+		sub := getResultClient().Subscribe(ctx, channel)
+		defer sub.Close()
+		notify = sub.Channel()
+	}
+
+	interval := resultPollInterval
+	for {
+		found, err := getResult(ctx, taskID, dest)
+		if found || (err != nil && ctx.Err() == nil) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-notify:
+		case <-time.After(interval):
+			interval = min(interval*2, maxResultPollInterval)
+		}
+	}
+}
+
+// storeFailure records err as the final result of a failed task.
+func storeFailure(ctx context.Context, taskID, queue string, err error) {
+	if taskID == "" {
+		return
+	}
+	if werr := writeResult(ctx, taskID, queue, storedResult{Failed: true, Error: err.Error()}); werr != nil {
+		log.Printf("asynq: failed to store failure of task %s: %v", taskID, werr)
+	}
+}
+
+// writeResult stores r for taskID, in the harness under TestMode.
+func writeResult(ctx context.Context, taskID, queue string, r storedResult) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if h := activeHarness(); h != nil {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.results == nil {
+			h.results = make(map[string][]byte)
+		}
+		h.results[taskID] = data
+		return nil
+	}
+
+	ttl := defaultResultTTL
+	if info, err := getInspector().GetTaskInfo(queue, taskID); err == nil && info.Retention > 0 {
+		ttl = info.Retention
+	}
+	if err := getResultClient().Set(ctx, resultKeyPrefix+taskID, data, ttl).Err(); err != nil {
+		return fmt.Errorf("asynq: failed to store result of task %s: %w", taskID, err)
+	}
+	return nil
+}
+
+// getResult reads and decodes the result of taskID.
+func getResult(ctx context.Context, taskID string, dest any) (bool, error) {
+	var data []byte
+	if h := activeHarness(); h != nil {
+		h.mu.Lock()
+		data = h.results[taskID]
+		h.mu.Unlock()
+	} else {
+		var err error
+		data, err = getResultClient().Get(ctx, resultKeyPrefix+taskID).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("asynq: failed to read result of task %s: %w", taskID, err)
+		}
+	}
+	if data == nil {
+		return false, nil
+	}
+
+	var r storedResult
+	if err := json.Unmarshal(data, &r); err != nil {
+		return true, fmt.Errorf("asynq: invalid result of task %s: %w", taskID, err)
+	}
+	if r.Failed {
+		return true, &TaskFailedError{TaskID: taskID, Message: r.Error}
+	}
+	if dest == nil || r.Value == nil {
+		return true, nil
+	}
+	return true, json.Unmarshal(r.Value, dest)
+}
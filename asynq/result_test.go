@@ -0,0 +1,112 @@
+package asynq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+type exportResult struct {
+	URL string `json:"url"`
+}
+
+func TestResultTestMode(t *testing.T) {
+	h := TestMode(t)
+	Handle("export:csv", func(ctx context.Context, payload []byte) error {
+		return SetResult(ctx, exportResult{URL: "https://cdn/" + string(payload)})
+	})
+	Handle("export:fail", func(ctx context.Context, payload []byte) error {
+		return errors.New("disk full")
+	})
+
+	ok, _ := Enqueue("export:csv", []byte("a.csv"))
+	failed, _ := Enqueue("export:fail", nil, MaxRetry(0))
+	var out exportResult
+	if found, err := GetResult(ok.ID, &out); found || err != nil {
+		t.Fatalf("expected no result before processing, got %v, %v", found, err)
+	}
+	h.Drain(context.Background())
+
+	if found, err := GetResult(ok.ID, &out); !found || err != nil || out.URL != "https://cdn/a.csv" {
+		t.Errorf("unexpected result: %v, %v, %+v", found, err, out)
+	}
+	var failure *TaskFailedError
+	if err := WaitForResult(context.Background(), failed.ID, &out); !errors.As(err, &failure) || failure.Message != "disk full" {
+		t.Errorf("expected TaskFailedError, got %v", err)
+	}
+
+	if err := SetResult(context.Background(), 1); err == nil {
+		t.Error("expected error outside a handler")
+	}
+	Handle("export:big", func(ctx context.Context, payload []byte) error {
+		return SetResult(ctx, strings.Repeat("x", MaxResultSize))
+	})
+	Enqueue("export:big", nil)
+	if err := h.Drain(context.Background()); err == nil || !strings.Contains(err.Error(), "MaxResultSize") {
+		t.Errorf("expected size error, got %v", err)
+	}
+}
+
+func TestWaitForResultSlowHandler(t *testing.T) {
+	opt := setupTestRedis(t)
+	t.Cleanup(func() { Shutdown(context.Background()) })
+
+	srv := asynq.NewServer(opt, asynq.Config{
+		Concurrency:  2,
+		LogLevel:     asynq.FatalLevel,
+		ErrorHandler: asynq.ErrorHandlerFunc(errorHandler),
+	})
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("export:csv", taskHandler("export:csv", func(ctx context.Context, payload []byte) error {
+		time.Sleep(300 * time.Millisecond)
+		return SetResult(ctx, exportResult{URL: "https://cdn/report.csv"})
+	}))
+	mux.HandleFunc("export:fail", taskHandler("export:fail", func(ctx context.Context, payload []byte) error {
+		time.Sleep(100 * time.Millisecond)
+		return errors.New("disk full")
+	}))
+	if err := srv.Start(mux); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Shutdown()
+
+	info, err := getClient().Enqueue(asynq.NewTask("export:csv", nil), Retention(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var out exportResult
+	if err := WaitForResult(ctx, info.ID, &out); err != nil || out.URL != "https://cdn/report.csv" {
+		t.Fatalf("unexpected result: %+v, %v", out, err)
+	}
+
+	rdb := opt.MakeRedisClient().(redis.UniversalClient)
+	defer rdb.Close()
+	if ttl := rdb.TTL(ctx, resultKeyPrefix+info.ID).Val(); ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("expected the task's retention as TTL, got %v", ttl)
+	}
+
+	info, err = getClient().Enqueue(asynq.NewTask("export:fail", nil), MaxRetry(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failure *TaskFailedError
+	if err := WaitForResult(ctx, info.ID, nil); !errors.As(err, &failure) || failure.Message != "disk full" {
+		t.Errorf("expected TaskFailedError, got %v", err)
+	}
+	if ttl := rdb.TTL(ctx, resultKeyPrefix+info.ID).Val(); ttl <= 23*time.Hour {
+		t.Errorf("expected the default TTL without retention, got %v", ttl)
+	}
+
+	short, cancelShort := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelShort()
+	if err := WaitForResult(short, "unknown", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline error, got %v", err)
+	}
+}
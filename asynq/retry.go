@@ -0,0 +1,153 @@
+package asynq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// RetryDelayFunc computes the delay before the n-th retry of a failed task.
+type RetryDelayFunc = asynq.RetryDelayFunc
+
+// SkipRetry can be wrapped into a handler error to archive the task without retrying.
+var SkipRetry = asynq.SkipRetry
+
+var (
+	retryPolicies = make(map[string]RetryDelayFunc)
+	retryMux      sync.RWMutex
+)
+
+// RetryPolicy sets the retry delay function for a task type.
+// Task types without a policy use asynq's default exponential backoff.
+// Register policies before the worker starts.
+//
+// Example:
+//
+//	asynq.RetryPolicy("webhook:deliver", asynq.FixedIntervals(5*time.Second, 15*time.Second, time.Minute))
+//	asynq.RetryPolicy("report:generate", asynq.ExponentialBackoff(time.Hour, 12*time.Hour, true))
+func RetryPolicy(taskType string, fn RetryDelayFunc) {
+	retryMux.Lock()
+	retryPolicies[taskType] = fn
+	retryMux.Unlock()
+}
+
+// NoRetryAfter returns middleware that stops retrying after n retries: handler
+// errors from later attempts are wrapped with SkipRetry so the task is archived.
+// Wrap a single handler, or pass it to Use to limit every task type.
+//
+// Example:
+//
+//	asynq.Handle("webhook:deliver", asynq.NoRetryAfter(3)(deliverWebhook))
+func NoRetryAfter(n int) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, payload []byte) error {
+			retried, _ := asynq.GetRetryCount(ctx)
+			return applyRetryLimit(n, retried, next(ctx, payload))
+		}
+	}
+}
+
+// ExponentialBackoff returns a RetryDelayFunc doubling base on each retry, capped at max.
+// With jitter, the delay is randomized within [delay/2, delay).
+func ExponentialBackoff(base, max time.Duration, jitter bool) RetryDelayFunc {
+	return func(n int, _ error, _ *asynq.Task) time.Duration {
+		// Doubling stops at max, so large n or base cannot overflow
+		d := min(base, max)
+		for i := 1; i < n && d < max; i++ {
+			if d > max/2 {
+				d = max
+			} else {
+				d *= 2
+			}
+		}
+		if jitter && d > 1 {
+			d = d/2 + rand.N(d/2)
+		}
+		return d
+	}
+}
+
+// FixedIntervals returns a RetryDelayFunc using the given delays in order.
+// Once the sequence is exhausted, the last delay is reused; combine with
+// NoRetryAfter or MaxRetry to bound the number of attempts.
+func FixedIntervals(durations ...time.Duration) RetryDelayFunc {
+	return func(n int, _ error, _ *asynq.Task) time.Duration {
+		if len(durations) == 0 {
+			return 0
+		}
+		i := n - 1
+		if i < 0 {
+			i = 0
+		}
+		if i >= len(durations) {
+			i = len(durations) - 1
+		}
+		return durations[i]
+	}
+}
+
+// retryDelay dispatches to the task type's policy, falling back to the default.
+func retryDelay(n int, err error, t *asynq.Task) time.Duration {
+	retryMux.RLock()
+	fn, ok := retryPolicies[t.Type()]
+	retryMux.RUnlock()
+
+	if !ok {
+		return asynq.DefaultRetryDelayFunc(n, err, t)
+	}
+	return fn(n, err, t)
+}
+
+// applyRetryLimit converts err to SkipRetry once retried reaches limit.
+func applyRetryLimit(limit, retried int, err error) error {
+	if err == nil || errors.Is(err, SkipRetry) || retried < limit {
+		return err
+	}
+	return fmt.Errorf("%w: %w", SkipRetry, err)
+}
+
+// taskHandler adapts a HandlerFunc to the asynq mux, wrapping it with the
+// registered middleware and restoring the propagated trace context.
+func taskHandler(taskType string, h HandlerFunc) asynq.HandlerFunc {
+	h = applyMiddleware(h)
+	return func(ctx context.Context, t *asynq.Task) error {
+		ctx, payload := extractTrace(ctx, t.Payload())
+		ctx = context.WithValue(ctx, taskTypeKey{}, taskType)
+		return h(ctx, payload)
+	}
+}
+
+// HandlerInfo describes a registered handler and its effective retry policy.
+type HandlerInfo struct {
+	TaskType    string `json:"task_type"`
+	RetryPolicy string `json:"retry_policy"` // "custom" or "default"
+}
+
+// Handlers lists registered handlers sorted by task type.
+func Handlers() []HandlerInfo {
+	handlersMux.RLock()
+	types := make([]string, 0, len(handlers))
+	for taskType := range handlers {
+		types = append(types, taskType)
+	}
+	handlersMux.RUnlock()
+	sort.Strings(types)
+
+	retryMux.RLock()
+	defer retryMux.RUnlock()
+
+	infos := make([]HandlerInfo, len(types))
+	for i, taskType := range types {
+		infos[i] = HandlerInfo{TaskType: taskType, RetryPolicy: "default"}
+		if _, ok := retryPolicies[taskType]; ok {
+			infos[i].RetryPolicy = "custom"
+		}
+	}
+	return infos
+}
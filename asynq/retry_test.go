@@ -0,0 +1,179 @@
+package asynq
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func resetRetryPolicies() {
+	retryMux.Lock()
+	retryPolicies = make(map[string]RetryDelayFunc)
+	retryMux.Unlock()
+}
+
+func TestRetryDelayPerTaskType(t *testing.T) {
+	resetRetryPolicies()
+	defer resetRetryPolicies()
+
+	RetryPolicy("webhook:deliver", FixedIntervals(5*time.Second, 15*time.Second, time.Minute))
+	RetryPolicy("report:generate", ExponentialBackoff(time.Hour, 8*time.Hour, false))
+
+	failure := errors.New("handler failed")
+	webhook := asynq.NewTask("webhook:deliver", nil)
+	report := asynq.NewTask("report:generate", nil)
+
+	webhookWant := []time.Duration{5 * time.Second, 15 * time.Second, time.Minute}
+	reportWant := []time.Duration{time.Hour, 2 * time.Hour, 4 * time.Hour}
+	for i := range 3 {
+		n := i + 1
+		if got := retryDelay(n, failure, webhook); got != webhookWant[i] {
+			t.Errorf("webhook retry %d: expected %v, got %v", n, webhookWant[i], got)
+		}
+		if got := retryDelay(n, failure, report); got != reportWant[i] {
+			t.Errorf("report retry %d: expected %v, got %v", n, reportWant[i], got)
+		}
+	}
+}
+
+func TestRetryDelayDefaultFallback(t *testing.T) {
+	resetRetryPolicies()
+	defer resetRetryPolicies()
+
+	RetryPolicy("webhook:deliver", FixedIntervals(time.Second))
+
+	got := retryDelay(1, errors.New("x"), asynq.NewTask("unknown:type", nil))
+	if got == time.Second {
+		t.Error("unknown task type should not use the registered policy")
+	}
+	if got <= 0 {
+		t.Errorf("expected positive default delay, got %v", got)
+	}
+}
+
+func TestFixedIntervalsExhausted(t *testing.T) {
+	fn := FixedIntervals(5*time.Second, 15*time.Second)
+	task := asynq.NewTask("t", nil)
+
+	if got := fn(3, nil, task); got != 15*time.Second {
+		t.Errorf("expected last interval to be reused, got %v", got)
+	}
+	if got := fn(100, nil, task); got != 15*time.Second {
+		t.Errorf("expected last interval to be reused, got %v", got)
+	}
+	if got := FixedIntervals()(1, nil, task); got != 0 {
+		t.Errorf("expected 0 for empty intervals, got %v", got)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	task := asynq.NewTask("t", nil)
+	fn := ExponentialBackoff(time.Second, 10*time.Second, false)
+
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := fn(tt.n, nil, task); got != tt.want {
+			t.Errorf("n=%d: expected %v, got %v", tt.n, tt.want, got)
+		}
+	}
+
+	// Shifting a large base would overflow past the cap
+	for _, fn := range []RetryDelayFunc{
+		ExponentialBackoff(time.Second, 10*time.Second, false),
+		ExponentialBackoff(time.Hour, 12*time.Hour, false),
+		ExponentialBackoff(time.Hour, time.Duration(math.MaxInt64), false),
+	} {
+		prev := time.Duration(0)
+		for n := 1; n <= 100; n++ {
+			if got := fn(n, nil, task); got <= 0 || got < prev {
+				t.Errorf("n=%d: expected a positive, non-decreasing delay, got %v", n, got)
+			} else {
+				prev = got
+			}
+		}
+	}
+	if got := ExponentialBackoff(time.Hour, 12*time.Hour, false)(40, nil, task); got != 12*time.Hour {
+		t.Errorf("n=40: expected the cap, got %v", got)
+	}
+
+	jittered := ExponentialBackoff(time.Second, 10*time.Second, true)
+	for range 20 {
+		if got := jittered(4, nil, task); got < 4*time.Second || got >= 8*time.Second {
+			t.Errorf("jittered delay out of range: %v", got)
+		}
+	}
+}
+
+func TestNoRetryAfter(t *testing.T) {
+	failure := errors.New("handler failed")
+
+	if err := applyRetryLimit(2, 1, failure); errors.Is(err, SkipRetry) {
+		t.Error("should still retry before the limit")
+	}
+
+	err := applyRetryLimit(2, 2, failure)
+	if !errors.Is(err, SkipRetry) {
+		t.Errorf("expected SkipRetry after limit, got %v", err)
+	}
+	if !errors.Is(err, failure) {
+		t.Error("original error should be preserved")
+	}
+
+	if err := applyRetryLimit(2, 5, nil); err != nil {
+		t.Errorf("nil error should pass through, got %v", err)
+	}
+}
+
+func TestNoRetryAfterMiddleware(t *testing.T) {
+	h := taskHandler("report:generate", NoRetryAfter(0)(func(ctx context.Context, payload []byte) error {
+		return errors.New("boom")
+	}))
+
+	if err := h(context.Background(), asynq.NewTask("report:generate", nil)); !errors.Is(err, SkipRetry) {
+		t.Errorf("expected SkipRetry, got %v", err)
+	}
+}
+
+func TestHandlersListing(t *testing.T) {
+	resetRetryPolicies()
+	defer resetRetryPolicies()
+
+	handlersMux.Lock()
+	saved := handlers
+	handlers = map[string]HandlerFunc{
+		"report:generate": func(context.Context, []byte) error { return nil },
+		"email:send":      func(context.Context, []byte) error { return nil },
+	}
+	handlersMux.Unlock()
+	defer func() {
+		handlersMux.Lock()
+		handlers = saved
+		handlersMux.Unlock()
+	}()
+
+	RetryPolicy("report:generate", ExponentialBackoff(time.Hour, 8*time.Hour, true))
+
+	infos := Handlers()
+	if len(infos) != 2 {
+		t.Fatalf("expected 2 handlers, got %d", len(infos))
+	}
+	if infos[0].TaskType != "email:send" || infos[0].RetryPolicy != "default" {
+		t.Errorf("unexpected info: %+v", infos[0])
+	}
+	if infos[1].TaskType != "report:generate" || infos[1].RetryPolicy != "custom" {
+		t.Errorf("unexpected info: %+v", infos[1])
+	}
+}
@@ -0,0 +1,332 @@
+package asynq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// EnqueuedTask is a task recorded by a TestHarness.
+type EnqueuedTask struct {
+	ID        string
+	Type      string
+	Payload   []byte
+	Queue     string
+	MaxRetry  int
+	Timeout   time.Duration
+	Deadline  time.Time
+	Unique    time.Duration
+	Retention time.Duration
+	ProcessAt time.Time // When the task becomes due on the harness clock
+	Options   []Option  // Options as passed to Enqueue
+
+	// TraceParent is the W3C traceparent propagated with the task, if any.
+	// Payload excludes it; the handler receives it in its context.
+	TraceParent string
+
+	Processed bool
+	Err       error // Handler error, set once processed
+}
+
+// TestHarness replaces Redis with an in-memory queue for unit tests.
+// Created by TestMode.
+type TestHarness struct {
+	mu      sync.Mutex
+	now     time.Time
+	tasks   []*EnqueuedTask
+	seq     int
+	results map[string][]byte // Stored by SetResult and failed tasks
+}
+
+type taskIDKey struct{}
+
+var (
+	// harnessMux serializes harnesses: package state is global, so tests
+	// using TestMode (even with t.Parallel) run one at a time.
+	harnessMux sync.Mutex
+	harness    *TestHarness
+	harnessRW  sync.RWMutex
+)
+
+// TestMode switches the package to synchronous in-memory execution for the
+// duration of a test. Enqueue calls are recorded instead of sent to Redis,
+// and run through the registered handlers on Drain. Handlers, cron tasks,
+// middleware, OnFailure and ConsumeDeadLetters set during the test are
+// discarded on cleanup.
+//
+// Example:
+//
+//	func TestSignup(t *testing.T) {
+//		h := asynq.TestMode(t)
+//		asynq.Handle("email:send", handleEmailSend)
+//
+//		signup("a@example.com") // calls asynq.Enqueue("email:send", ...)
+//
+//		if err := h.Drain(context.Background()); err != nil {
+//			t.Fatal(err)
+//		}
+//	}
+func TestMode(t *testing.T) *TestHarness {
+	t.Helper()
+	harnessMux.Lock()
+
+	handlersMux.Lock()
+	savedHandlers := maps.Clone(handlers)
+	handlersMux.Unlock()
+	cronMux.Lock()
+	savedCron := slices.Clone(cronTasks)
+	cronMux.Unlock()
+	middlewareMux.Lock()
+	savedMiddlewares := slices.Clone(middlewares)
+	middlewareMux.Unlock()
+	failureMux.RLock()
+	savedFailureHook := failureHook
+	failureMux.RUnlock()
+	serverMux.Lock()
+	savedConsumeDeadLetters := consumeDeadLetters
+	serverMux.Unlock()
+
+	h := &TestHarness{now: time.Now()}
+	harnessRW.Lock()
+	harness = h
+	harnessRW.Unlock()
+
+	t.Cleanup(func() {
+		harnessRW.Lock()
+		harness = nil
+		harnessRW.Unlock()
+
+		handlersMux.Lock()
+		handlers = savedHandlers
+		handlersMux.Unlock()
+		cronMux.Lock()
+		cronTasks = savedCron
+		cronMux.Unlock()
+		middlewareMux.Lock()
+		middlewares = savedMiddlewares
+		middlewareMux.Unlock()
+		OnFailure(savedFailureHook)
+		serverMux.Lock()
+		consumeDeadLetters = savedConsumeDeadLetters
+		serverMux.Unlock()
+
+		harnessMux.Unlock()
+	})
+	return h
+}
+
+// activeHarness returns the harness installed by TestMode, if any.
+func activeHarness() *TestHarness {
+	harnessRW.RLock()
+	defer harnessRW.RUnlock()
+	return harness
+}
+
+// Now returns the harness clock.
+func (h *TestHarness) Now() time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.now
+}
+
+// Advance moves the harness clock forward, making delayed tasks due.
+func (h *TestHarness) Advance(d time.Duration) {
+	h.mu.Lock()
+	h.now = h.now.Add(d)
+	h.mu.Unlock()
+}
+
+// Enqueued returns all recorded tasks in enqueue order.
+func (h *TestHarness) Enqueued() []EnqueuedTask {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	tasks := make([]EnqueuedTask, len(h.tasks))
+	for i, t := range h.tasks {
+		tasks[i] = *t
+	}
+	return tasks
+}
+
+// Drain runs every due task through its handler in enqueue order, including
+// tasks enqueued by handlers while draining. Tasks scheduled later stay
+// pending until the clock is advanced. Failed tasks are not retried; their
+// errors are returned joined. A failure is final, triggering OnFailure and
+// dead-lettering, when the task has MaxRetry(0) or the error wraps SkipRetry.
+func (h *TestHarness) Drain(ctx context.Context) error {
+	var errs []error
+	for {
+		task := h.nextDue()
+		if task == nil {
+			return errors.Join(errs...)
+		}
+
+		err := h.run(ctx, task)
+
+		h.mu.Lock()
+		task.Processed, task.Err = true, err
+		h.mu.Unlock()
+
+		if err != nil {
+			handleFailure(context.WithValue(ctx, taskIDKey{}, task.ID), DeadLetter{
+				Type:     task.Type,
+				Payload:  task.Payload,
+				Queue:    task.Queue,
+				TaskID:   task.ID,
+				Attempts: 1,
+			}, task.MaxRetry, err)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s (%s): %w", task.Type, task.ID, err))
+		}
+		if ctx.Err() != nil {
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+	}
+}
+
+// FireCron enqueues the tasks registered with Cron or CronFunc for taskType,
+// as the scheduler would when the schedule fires.
+func (h *TestHarness) FireCron(taskType string) error {
+	cronMux.Lock()
+	entries := slices.Clone(cronTasks)
+	cronMux.Unlock()
+
+	fired := false
+	for _, ct := range entries {
+		if ct.taskType != taskType {
+			continue
+		}
+		data, err := ct.payloadData()
+		if err != nil {
+			return fmt.Errorf("asynq: failed to marshal payload: %w", err)
+		}
+		if _, err := h.enqueue(ct.taskType, data, "", ct.opts); err != nil {
+			return err
+		}
+		fired = true
+	}
+	if !fired {
+		return fmt.Errorf("asynq: no cron task registered for %q", taskType)
+	}
+	return nil
+}
+
+// nextDue returns the earliest enqueued unprocessed task that is due.
+func (h *TestHarness) nextDue() *EnqueuedTask {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, t := range h.tasks {
+		if !t.Processed && !t.ProcessAt.After(h.now) {
+			return t
+		}
+	}
+	return nil
+}
+
+// run executes a task through the same handler adapter as the worker.
+func (h *TestHarness) run(ctx context.Context, t *EnqueuedTask) error {
+	handlersMux.RLock()
+	handler, ok := handlers[t.Type]
+	handlersMux.RUnlock()
+	if !ok {
+		return fmt.Errorf("asynq: no handler registered for %q", t.Type)
+	}
+
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+	ctx = context.WithValue(ctx, taskIDKey{}, t.ID)
+	return taskHandler(t.Type, handler)(ctx, asynq.NewTask(t.Type, withTrace(t.TraceParent, t.Payload)))
+}
+
+// enqueue records a task, enforcing TaskID and Unique conflicts like Redis would.
+func (h *TestHarness) enqueue(taskType string, payload []byte, traceparent string, opts []Option) (*TaskInfo, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	t := &EnqueuedTask{
+		ID:        fmt.Sprintf("test-%d", h.seq),
+		Type:      taskType,
+		Payload:   payload,
+		Queue:     "default",
+		MaxRetry:  25, // asynq's default when MaxRetry is not given
+		ProcessAt: h.now,
+		Options:   slices.Clone(opts),
+
+		TraceParent: traceparent,
+	}
+	for _, opt := range opts {
+		switch v := opt.Value().(type) {
+		case string:
+			switch opt.Type() {
+			case asynq.QueueOpt:
+				t.Queue = v
+			case asynq.TaskIDOpt:
+				t.ID = v
+			}
+		case int:
+			t.MaxRetry = v
+		case time.Duration:
+			switch opt.Type() {
+			case asynq.TimeoutOpt:
+				t.Timeout = v
+			case asynq.UniqueOpt:
+				t.Unique = v
+			case asynq.ProcessInOpt:
+				t.ProcessAt = h.now.Add(v)
+			case asynq.RetentionOpt:
+				t.Retention = v
+			}
+		case time.Time:
+			switch opt.Type() {
+			case asynq.DeadlineOpt:
+				t.Deadline = v
+			case asynq.ProcessAtOpt:
+				t.ProcessAt = v
+			}
+		}
+	}
+
+	for _, other := range h.tasks {
+		if other.Processed {
+			continue
+		}
+		if other.ID == t.ID {
+			return nil, asynq.ErrTaskIDConflict
+		}
+		if t.Unique > 0 && other.Unique > 0 && other.Type == t.Type && other.Queue == t.Queue &&
+			string(other.Payload) == string(t.Payload) {
+			return nil, asynq.ErrDuplicateTask
+		}
+	}
+	h.tasks = append(h.tasks, t)
+
+	state := asynq.TaskStatePending
+	if t.ProcessAt.After(h.now) {
+		state = asynq.TaskStateScheduled
+	}
+	return &TaskInfo{
+		ID:            t.ID,
+		Queue:         t.Queue,
+		Type:          t.Type,
+		Payload:       t.Payload,
+		State:         state,
+		MaxRetry:      t.MaxRetry,
+		Timeout:       t.Timeout,
+		Deadline:      t.Deadline,
+		Retention:     t.Retention,
+		NextProcessAt: t.ProcessAt,
+	}, nil
+}
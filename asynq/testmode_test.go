@@ -0,0 +1,226 @@
+package asynq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestHarnessRecording(t *testing.T) {
+	h := TestMode(t)
+
+	at := h.Now().Add(time.Hour)
+	deadline := h.Now().Add(2 * time.Hour)
+
+	info, err := Enqueue("email:send", map[string]string{"to": "a@example.com"},
+		Queue("critical"), MaxRetry(5), Timeout(time.Minute), Deadline(deadline), TaskID("welcome-1"))
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if info.ID != "welcome-1" || info.Queue != "critical" || info.State != asynq.TaskStatePending {
+		t.Errorf("unexpected task info: %+v", info)
+	}
+
+	if _, err := EnqueueAt("report:daily", []byte("raw"), at); err != nil {
+		t.Fatalf("EnqueueAt failed: %v", err)
+	}
+	if _, err := EnqueueUnique("sync:user", 1, time.Hour); err != nil {
+		t.Fatalf("EnqueueUnique failed: %v", err)
+	}
+	if _, err := EnqueueUnique("sync:user", 1, time.Hour); !errors.Is(err, asynq.ErrDuplicateTask) {
+		t.Errorf("expected ErrDuplicateTask, got %v", err)
+	}
+	if _, err := Enqueue("email:send", nil, TaskID("welcome-1")); !errors.Is(err, asynq.ErrTaskIDConflict) {
+		t.Errorf("expected ErrTaskIDConflict, got %v", err)
+	}
+
+	tasks := h.Enqueued()
+	if len(tasks) != 3 {
+		t.Fatalf("expected 3 recorded tasks, got %d", len(tasks))
+	}
+
+	email := tasks[0]
+	if email.Type != "email:send" || string(email.Payload) != `{"to":"a@example.com"}` {
+		t.Errorf("unexpected type/payload: %s %s", email.Type, email.Payload)
+	}
+	if email.Queue != "critical" || email.MaxRetry != 5 || email.Timeout != time.Minute ||
+		!email.Deadline.Equal(deadline) || len(email.Options) != 5 {
+		t.Errorf("options not recorded: %+v", email)
+	}
+	if !tasks[1].ProcessAt.Equal(at) || string(tasks[1].Payload) != "raw" || tasks[1].Queue != "default" {
+		t.Errorf("unexpected scheduled task: %+v", tasks[1])
+	}
+	if tasks[2].Unique != time.Hour {
+		t.Errorf("unique ttl not recorded: %+v", tasks[2])
+	}
+}
+
+func TestHarnessDrainOrder(t *testing.T) {
+	h := TestMode(t)
+
+	var order []string
+	Handle("step", func(ctx context.Context, payload []byte) error {
+		var n int
+		Unmarshal(payload, &n)
+		order = append(order, fmt.Sprintf("step%d:%s", n, GetTaskID(ctx)))
+		if n == 1 {
+			// Tasks enqueued while draining run in the same Drain
+			Enqueue("step", 3)
+		}
+		return nil
+	})
+	Handle("fail", func(ctx context.Context, payload []byte) error {
+		order = append(order, "fail")
+		return errors.New("boom")
+	})
+
+	Enqueue("step", 1)
+	Enqueue("fail", nil)
+	Enqueue("step", 2)
+	Enqueue("missing", nil)
+
+	err := h.Drain(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "boom") || !strings.Contains(err.Error(), `no handler registered for "missing"`) {
+		t.Errorf("expected joined handler errors, got %v", err)
+	}
+
+	want := "step1:test-1 fail step2:test-3 step3:test-5"
+	if got := strings.Join(order, " "); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	for _, task := range h.Enqueued() {
+		if !task.Processed {
+			t.Errorf("task %s not processed", task.ID)
+		}
+		if (task.Type == "fail") != (task.Err != nil) && task.Type != "missing" {
+			t.Errorf("unexpected error for %s: %v", task.Type, task.Err)
+		}
+	}
+}
+
+func TestHarnessAdvance(t *testing.T) {
+	h := TestMode(t)
+
+	var ran []string
+	Handle("later", func(ctx context.Context, payload []byte) error {
+		ran = append(ran, string(payload))
+		return nil
+	})
+
+	EnqueueIn("later", []byte("10m"), 10*time.Minute)
+	EnqueueIn("later", []byte("1m"), time.Minute)
+	Enqueue("later", []byte("now"))
+
+	h.Drain(context.Background())
+	if strings.Join(ran, ",") != "now" {
+		t.Fatalf("only immediate task should run, got %v", ran)
+	}
+
+	h.Advance(time.Minute)
+	h.Drain(context.Background())
+	if strings.Join(ran, ",") != "now,1m" {
+		t.Fatalf("expected 1m task after advancing, got %v", ran)
+	}
+
+	h.Advance(time.Hour)
+	h.Drain(context.Background())
+	if strings.Join(ran, ",") != "now,1m,10m" {
+		t.Fatalf("expected all tasks after advancing, got %v", ran)
+	}
+}
+
+func TestHarnessHandlerAdapter(t *testing.T) {
+	h := TestMode(t)
+	Handle("flaky", NoRetryAfter(0)(func(ctx context.Context, payload []byte) error {
+		return errors.New("unavailable")
+	}))
+	Handle("slow", func(ctx context.Context, payload []byte) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	Enqueue("flaky", nil)
+	Enqueue("slow", nil, Timeout(10*time.Millisecond))
+	h.Drain(context.Background())
+
+	tasks := h.Enqueued()
+	if !errors.Is(tasks[0].Err, SkipRetry) {
+		t.Errorf("retry limit should apply during Drain, got %v", tasks[0].Err)
+	}
+	if !errors.Is(tasks[1].Err, context.DeadlineExceeded) {
+		t.Errorf("task timeout should apply during Drain, got %v", tasks[1].Err)
+	}
+}
+
+func TestHarnessFireCron(t *testing.T) {
+	h := TestMode(t)
+
+	var payloads []string
+	Handle("report:daily", func(ctx context.Context, payload []byte) error {
+		payloads = append(payloads, string(payload))
+		return nil
+	})
+	Cron("0 9 * * *", "report:daily", map[string]string{"type": "daily"}, Queue("low"))
+
+	if err := h.FireCron("report:daily"); err != nil {
+		t.Fatalf("FireCron failed: %v", err)
+	}
+	if err := h.FireCron("unknown"); err == nil {
+		t.Error("expected error for unregistered cron task")
+	}
+
+	if tasks := h.Enqueued(); len(tasks) != 1 || tasks[0].Queue != "low" {
+		t.Fatalf("expected cron task recorded in queue low, got %+v", tasks)
+	}
+	h.Drain(context.Background())
+	if len(payloads) != 1 || payloads[0] != `{"type":"daily"}` {
+		t.Errorf("unexpected cron payloads: %v", payloads)
+	}
+}
+
+func TestHarnessIsolation(t *testing.T) {
+	for i := range 4 {
+		t.Run(fmt.Sprintf("parallel%d", i), func(t *testing.T) {
+			t.Parallel()
+			h := TestMode(t)
+
+			taskType := fmt.Sprintf("isolated:%d", i)
+			count := 0
+			Handle(taskType, func(ctx context.Context, payload []byte) error {
+				count++
+				return nil
+			})
+			for range 3 {
+				Enqueue(taskType, i)
+			}
+			if err := h.Drain(context.Background()); err != nil {
+				t.Fatalf("Drain failed: %v", err)
+			}
+
+			tasks := h.Enqueued()
+			if len(tasks) != 3 || count != 3 {
+				t.Fatalf("expected 3 own tasks, got %d recorded, %d run", len(tasks), count)
+			}
+			for _, task := range tasks {
+				if task.Type != taskType {
+					t.Errorf("saw task from another test: %s", task.Type)
+				}
+			}
+			if n := len(Handlers()); n != 1 {
+				t.Errorf("expected only this test's handler, got %d", n)
+			}
+		})
+	}
+
+	t.Cleanup(func() {
+		if len(Handlers()) != 0 || activeHarness() != nil {
+			t.Error("handlers and harness should be restored after cleanup")
+		}
+	})
+}
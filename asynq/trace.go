@@ -0,0 +1,82 @@
+package asynq
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracePrefix marks a payload carrying a W3C traceparent: the prefix, the
+// traceparent, a newline, then the original payload. JSON never starts with
+// a NUL byte, so payloads enqueued without a trace pass through unchanged.
+const tracePrefix = "\x00traceparent:"
+
+// traceOpt is the option type of TraceContext. asynq ignores option types
+// it does not know, so the option is safe to hand to the client.
+const traceOpt asynq.OptionType = -1
+
+type traceOption struct{ ctx context.Context }
+
+func (o traceOption) String() string         { return "TraceContext()" }
+func (o traceOption) Type() asynq.OptionType { return traceOpt }
+func (o traceOption) Value() any             { return o.ctx }
+
+// TraceContext propagates the OpenTelemetry span of ctx with the task, for
+// the Enqueue variants that take no context. EnqueueContext propagates the
+// span of its ctx automatically.
+//
+// The handler's context carries the span as its remote parent, so log
+// entries and spans started in the handler join the producer's trace.
+func TraceContext(ctx context.Context) Option {
+	return traceOption{ctx: ctx}
+}
+
+// traceParent returns the traceparent to send with a task, or "" when no span
+// is active. Unique tasks are never traced: the trace context would make
+// every payload distinct and defeat deduplication.
+func traceParent(ctx context.Context, opts []Option) string {
+	for _, opt := range opts {
+		switch opt.Type() {
+		case traceOpt:
+			ctx = opt.Value().(context.Context)
+		case asynq.UniqueOpt:
+			return ""
+		}
+	}
+	if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return ""
+	}
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// withTrace prefixes payload with traceparent.
+func withTrace(traceparent string, payload []byte) []byte {
+	if traceparent == "" {
+		return payload
+	}
+	b := make([]byte, 0, len(tracePrefix)+len(traceparent)+1+len(payload))
+	b = append(b, tracePrefix...)
+	b = append(b, traceparent...)
+	b = append(b, '\n')
+	return append(b, payload...)
+}
+
+// extractTrace strips the trace prefix from payload and restores the span
+// into ctx as a remote parent.
+func extractTrace(ctx context.Context, payload []byte) (context.Context, []byte) {
+	if !bytes.HasPrefix(payload, []byte(tracePrefix)) {
+		return ctx, payload
+	}
+	rest := payload[len(tracePrefix):]
+	end := bytes.IndexByte(rest, '\n')
+	if end < 0 {
+		return ctx, payload
+	}
+	carrier := propagation.MapCarrier{"traceparent": string(rest[:end])}
+	return propagation.TraceContext{}.Extract(ctx, carrier), rest[end+1:]
+}
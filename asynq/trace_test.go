@@ -0,0 +1,94 @@
+package asynq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	testTraceID = trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	testSpanID  = trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+)
+
+func spanCtx() context.Context {
+	return trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    testTraceID,
+		SpanID:     testSpanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+}
+
+func TestTracePropagation(t *testing.T) {
+	h := TestMode(t)
+
+	var got []trace.SpanContext
+	var payloads []string
+	Handle("email:send", func(ctx context.Context, payload []byte) error {
+		got = append(got, trace.SpanContextFromContext(ctx))
+		payloads = append(payloads, string(payload))
+		return nil
+	})
+
+	if _, err := EnqueueContext(spanCtx(), "email:send", map[string]string{"to": "a"}); err != nil {
+		t.Fatalf("EnqueueContext failed: %v", err)
+	}
+	if _, err := EnqueueIn("email:send", map[string]string{"to": "b"}, time.Minute, TraceContext(spanCtx())); err != nil {
+		t.Fatalf("EnqueueIn failed: %v", err)
+	}
+	if _, err := Enqueue("email:send", map[string]string{"to": "c"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	tasks := h.Enqueued()
+	if tasks[0].TraceParent != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" || tasks[2].TraceParent != "" {
+		t.Errorf("unexpected traceparents: %q %q", tasks[0].TraceParent, tasks[2].TraceParent)
+	}
+	if string(tasks[0].Payload) != `{"to":"a"}` {
+		t.Errorf("recorded payload should exclude the trace context, got %q", tasks[0].Payload)
+	}
+
+	h.Advance(time.Minute)
+	if err := h.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 handled tasks, got %d", len(got))
+	}
+	for i, sc := range got[:2] {
+		if sc.TraceID() != testTraceID || sc.SpanID() != testSpanID || !sc.IsRemote() || !sc.IsSampled() {
+			t.Errorf("task %d: span not restored as remote parent: %+v", i, sc)
+		}
+	}
+	if got[2].IsValid() {
+		t.Errorf("untraced task should have no span, got %+v", got[2])
+	}
+	if payloads[0] != `{"to":"a"}` || payloads[1] != `{"to":"b"}` {
+		t.Errorf("handler payloads not restored: %q", payloads)
+	}
+}
+
+func TestTraceSkippedForUniqueTasks(t *testing.T) {
+	h := TestMode(t)
+
+	EnqueueContext(spanCtx(), "sync:user", 1, Unique(time.Hour))
+	if _, err := EnqueueContext(spanCtx(), "sync:user", 1, Unique(time.Hour)); err == nil {
+		t.Error("trace context must not defeat deduplication")
+	}
+	if tp := h.Enqueued()[0].TraceParent; tp != "" {
+		t.Errorf("unique task should not be traced, got %q", tp)
+	}
+}
+
+func TestExtractTraceUntraced(t *testing.T) {
+	ctx := context.Background()
+	for _, payload := range []string{"", `{"a":1}`, "\x00traceparent:truncated"} {
+		gotCtx, got := extractTrace(ctx, []byte(payload))
+		if gotCtx != ctx || string(got) != payload {
+			t.Errorf("payload %q should pass through unchanged, got %q", payload, got)
+		}
+	}
+}
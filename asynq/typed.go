@@ -0,0 +1,63 @@
+package asynq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// maxPayloadInError caps the payload bytes quoted in decode errors.
+const maxPayloadInError = 200
+
+// HandleTyped registers a handler that receives the payload decoded from
+// JSON into T. It shares the handler registry with Handle, so typed and raw
+// handlers can be mixed; registering the same task type again replaces the
+// previous handler either way.
+//
+// A payload that cannot be decoded fails the task with an error wrapping
+// SkipRetry, since retrying would not change the payload.
+//
+// Example:
+//
+//	type SendEmail struct {
+//	    To   string `json:"to"`
+//	    User struct {
+//	        ID   int    `json:"id"`
+//	        Name string `json:"name"`
+//	    } `json:"user"`
+//	}
+//
+//	asynq.HandleTyped("email:send", func(ctx context.Context, p SendEmail) error {
+//	    return mailer.Send(p.To, p.User.Name)
+//	})
+//	asynq.EnqueueTyped("email:send", SendEmail{To: "a@example.com"})
+func HandleTyped[T any](taskType string, fn func(ctx context.Context, payload T) error) {
+	Handle(taskType, func(ctx context.Context, payload []byte) error {
+		var v T
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return fmt.Errorf("asynq: failed to decode %s payload %s: %w: %w",
+				taskType, truncatePayload(payload), err, SkipRetry)
+		}
+		return fn(ctx, v)
+	})
+}
+
+// EnqueueTyped enqueues a task whose payload is T encoded as JSON, for
+// handlers registered with HandleTyped. Unlike Enqueue, a []byte payload is
+// encoded too (as a base64 JSON string), matching what HandleTyped[[]byte]
+// decodes.
+func EnqueueTyped[T any](taskType string, payload T, opts ...Option) (*TaskInfo, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("asynq: failed to marshal payload: %w", err)
+	}
+	return Enqueue(taskType, data, opts...)
+}
+
+// truncatePayload quotes payload for error messages, cut at maxPayloadInError bytes.
+func truncatePayload(payload []byte) string {
+	if len(payload) <= maxPayloadInError {
+		return fmt.Sprintf("%q", payload)
+	}
+	return fmt.Sprintf("%q... (%d bytes)", payload[:maxPayloadInError], len(payload))
+}
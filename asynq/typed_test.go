@@ -0,0 +1,88 @@
+package asynq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type orderPlaced struct {
+	OrderID  string `json:"order_id"`
+	Customer struct {
+		Email string `json:"email"`
+		Name  string `json:"name"`
+	} `json:"customer"`
+	Items []struct {
+		SKU      string `json:"sku"`
+		Quantity int    `json:"quantity"`
+	} `json:"items"`
+}
+
+func TestHandleTyped(t *testing.T) {
+	h := TestMode(t)
+
+	var got orderPlaced
+	HandleTyped("order:placed", func(ctx context.Context, p orderPlaced) error {
+		got = p
+		return nil
+	})
+	var raw []byte
+	Handle("order:raw", func(ctx context.Context, payload []byte) error {
+		raw = payload
+		return nil
+	})
+
+	var order orderPlaced
+	order.OrderID = "o-1"
+	order.Customer.Email = "a@example.com"
+	order.Customer.Name = "Zoë \"Z\""
+	order.Items = append(order.Items, struct {
+		SKU      string `json:"sku"`
+		Quantity int    `json:"quantity"`
+	}{SKU: "sku-1", Quantity: 2})
+
+	if _, err := EnqueueTyped("order:placed", order); err != nil {
+		t.Fatalf("EnqueueTyped failed: %v", err)
+	}
+	if _, err := Enqueue("order:raw", []byte("raw")); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := h.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	if got.OrderID != "o-1" || got.Customer != order.Customer || len(got.Items) != 1 || got.Items[0] != order.Items[0] {
+		t.Errorf("unexpected decoded payload: %+v", got)
+	}
+	if string(raw) != "raw" {
+		t.Errorf("raw handler got %q", raw)
+	}
+}
+
+func TestHandleTypedDecodeError(t *testing.T) {
+	h := TestMode(t)
+
+	called := false
+	HandleTyped("order:placed", func(ctx context.Context, p orderPlaced) error {
+		called = true
+		return nil
+	})
+
+	Enqueue("order:placed", []byte(`{"order_id": 42}`))
+	Enqueue("order:placed", []byte(strings.Repeat("x", 1000)))
+	err := h.Drain(context.Background())
+	if called {
+		t.Error("handler should not run for undecodable payloads")
+	}
+	if !errors.Is(err, SkipRetry) {
+		t.Errorf("decode errors should skip retries, got %v", err)
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "order:placed") || !strings.Contains(msg, `{\"order_id\": 42}`) {
+		t.Errorf("error should name the task type and payload: %v", msg)
+	}
+	if strings.Contains(msg, strings.Repeat("x", 201)) || !strings.Contains(msg, "(1000 bytes)") {
+		t.Errorf("long payload should be truncated: %v", msg)
+	}
+}
@@ -0,0 +1,183 @@
+package ec2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+)
+
+// CommandResult is the outcome of a command on one instance
+type CommandResult struct {
+	InstanceID string `json:"instance_id"`
+	Status     string `json:"status"`    // Success, Failed, Cancelled, TimedOut; Pending/InProgress if the wait ended first
+	ExitCode   int    `json:"exit_code"` // -1 until the command finishes
+	Stdout     string `json:"stdout"`    // SSM keeps the first 24000 characters
+	Stderr     string `json:"stderr"`    // SSM keeps the first 8000 characters
+	Err        error  `json:"-"`         // Set if the command did not run to completion on this instance
+}
+
+// ErrInstanceUnavailable is returned when SSM cannot reach an instance: it
+// is not a managed instance, its agent is offline, or it was terminated.
+// The command can be retried on a different host.
+var ErrInstanceUnavailable = errors.New("ec2: instance unavailable for commands")
+
+// commandGrace is how long ExecuteCommandsSync waits beyond the execution
+// timeout, covering delivery and reporting delays
+const commandGrace = 30 * time.Second
+
+// commandAPI is the subset of *ssm.Client used to run commands
+type commandAPI interface {
+	SendCommand(ctx context.Context, params *ssm.SendCommandInput, optFns ...func(*ssm.Options)) (*ssm.SendCommandOutput, error)
+	GetCommandInvocation(ctx context.Context, params *ssm.GetCommandInvocationInput, optFns ...func(*ssm.Options)) (*ssm.GetCommandInvocationOutput, error)
+}
+
+// newCommandAPI returns the SSM client for commands, replaced in tests
+var newCommandAPI = func(cfg *Config) (commandAPI, error) {
+	if cfg == nil || cfg.Region == "" {
+		return nil, fmt.Errorf("EC2 config not set or region missing")
+	}
+	awsCfg, err := loadConfig(cfg.Region, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return ssm.NewFromConfig(awsCfg), nil
+}
+
+// ExecuteCommandsSync runs shell commands on instances via AWS Systems
+// Manager and waits for them to finish. timeout is the execution timeout
+// passed to SSM (at least 30 seconds, default 1 hour); the wait itself
+// also ends when ctx is done.
+//
+// Results are in the order of instanceIDs. A command that exits non-zero
+// has Status "Failed" and its ExitCode, without Err. Instances SSM cannot
+// reach have Err wrapping ErrInstanceUnavailable; if any of instanceIDs is
+// not a managed instance, nothing runs and the error wraps it too.
+//
+// If the wait ends first, the error is non-nil and unfinished results keep
+// their last status with Err set.
+//
+// Example:
+//
+//	results, err := ec2.ExecuteCommandsSync(ctx, cfg, []string{id}, 5*time.Minute, "systemctl restart app")
+//	if errors.Is(err, ec2.ErrInstanceUnavailable) {
+//	    // pick another host
+//	}
+func ExecuteCommandsSync(ctx context.Context, cfg *Config, instanceIDs []string, timeout time.Duration, commands ...string) ([]CommandResult, error) {
+	if len(instanceIDs) == 0 {
+		return nil, fmt.Errorf("no instance IDs given")
+	}
+	api, err := newCommandAPI(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		timeout = time.Hour
+	}
+	timeout = max(timeout, 30*time.Second)
+
+	out, err := api.SendCommand(ctx, &ssm.SendCommandInput{
+		DocumentName: awsv2.String("AWS-RunShellScript"),
+		InstanceIds:  instanceIDs,
+		Parameters: map[string][]string{
+			"commands":         commands,
+			"executionTimeout": {strconv.Itoa(int(timeout.Seconds()))},
+		},
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidInstanceId" {
+			return nil, fmt.Errorf("%w: %s", ErrInstanceUnavailable, apiErr.ErrorMessage())
+		}
+		return nil, fmt.Errorf("error executing commands: %w", err)
+	}
+	commandID := awsv2.ToString(out.Command.CommandId)
+
+	results := make([]CommandResult, len(instanceIDs))
+	for i, id := range instanceIDs {
+		results[i] = CommandResult{InstanceID: id, Status: string(ssmtypes.CommandInvocationStatusPending), ExitCode: -1}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout+commandGrace)
+	defer cancel()
+
+	interval := pollInterval
+	for {
+		done := true
+		for i := range results {
+			r := &results[i]
+			if r.ExitCode >= 0 || r.Err != nil {
+				continue
+			}
+			if err := pollInvocation(ctx, api, commandID, r); err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+				r.Err = err
+				continue
+			}
+			if r.ExitCode < 0 && r.Err == nil {
+				done = false
+			}
+		}
+		if done && ctx.Err() == nil {
+			return results, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			for i := range results {
+				if results[i].ExitCode < 0 && results[i].Err == nil {
+					results[i].Err = ctx.Err()
+				}
+			}
+			return results, fmt.Errorf("timed out waiting for command %s: %w", commandID, ctx.Err())
+		case <-time.After(interval):
+		}
+		interval = min(interval*3/2, maxPollInterval)
+	}
+}
+
+// pollInvocation updates r with the invocation of commandID on its instance
+func pollInvocation(ctx context.Context, api commandAPI, commandID string, r *CommandResult) error {
+	inv, err := api.GetCommandInvocation(ctx, &ssm.GetCommandInvocationInput{
+		CommandId:  awsv2.String(commandID),
+		InstanceId: awsv2.String(r.InstanceID),
+	})
+	if err != nil {
+		var notFound *ssmtypes.InvocationDoesNotExist
+		if errors.As(err, &notFound) {
+			// Invocations are listed shortly after SendCommand returns
+			return nil
+		}
+		return fmt.Errorf("get command invocation on %s: %w", r.InstanceID, err)
+	}
+
+	r.Status = string(inv.Status)
+	switch inv.Status {
+	case ssmtypes.CommandInvocationStatusSuccess, ssmtypes.CommandInvocationStatusFailed,
+		ssmtypes.CommandInvocationStatusTimedOut, ssmtypes.CommandInvocationStatusCancelled:
+	default:
+		return nil
+	}
+
+	r.Stdout = awsv2.ToString(inv.StandardOutputContent)
+	r.Stderr = awsv2.ToString(inv.StandardErrorContent)
+	switch details := awsv2.ToString(inv.StatusDetails); details {
+	case "Undeliverable", "Terminated", "DeliveryTimedOut", "Delivery Timed Out":
+		r.Err = fmt.Errorf("%w: %s is %s", ErrInstanceUnavailable, r.InstanceID, details)
+	default:
+		r.ExitCode = int(inv.ResponseCode)
+		if r.ExitCode < 0 {
+			// SSM reports -1 for commands that never exited, e.g. on timeout
+			r.Err = fmt.Errorf("command on %s %s", r.InstanceID, details)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package ec2
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+)
+
+// mockCommand replays invocations per instance; a nil entry means the
+// invocation is not listed yet.
+type mockCommand struct {
+	sendErr     error
+	sent        *ssm.SendCommandInput
+	invocations map[string][]*ssm.GetCommandInvocationOutput
+	calls       map[string]int
+}
+
+func (m *mockCommand) SendCommand(ctx context.Context, in *ssm.SendCommandInput, _ ...func(*ssm.Options)) (*ssm.SendCommandOutput, error) {
+	m.sent = in
+	if m.sendErr != nil {
+		return nil, m.sendErr
+	}
+	return &ssm.SendCommandOutput{Command: &ssmtypes.Command{CommandId: awsv2.String("cmd-1")}}, nil
+}
+
+func (m *mockCommand) GetCommandInvocation(ctx context.Context, in *ssm.GetCommandInvocationInput, _ ...func(*ssm.Options)) (*ssm.GetCommandInvocationOutput, error) {
+	id := awsv2.ToString(in.InstanceId)
+	seq := m.invocations[id]
+	inv := seq[min(m.calls[id], len(seq)-1)]
+	m.calls[id]++
+	if inv == nil {
+		return nil, &ssmtypes.InvocationDoesNotExist{}
+	}
+	return inv, nil
+}
+
+func invocation(status ssmtypes.CommandInvocationStatus, details string, code int32, stdout string) *ssm.GetCommandInvocationOutput {
+	return &ssm.GetCommandInvocationOutput{
+		Status:                status,
+		StatusDetails:         awsv2.String(details),
+		ResponseCode:          code,
+		StandardOutputContent: awsv2.String(stdout),
+		StandardErrorContent:  awsv2.String(""),
+	}
+}
+
+func setupCommand(t *testing.T, m *mockCommand) {
+	t.Helper()
+	m.calls = map[string]int{}
+	origAPI, origPoll, origMax := newCommandAPI, pollInterval, maxPollInterval
+	newCommandAPI = func(cfg *Config) (commandAPI, error) { return m, nil }
+	pollInterval, maxPollInterval = time.Millisecond, 5*time.Millisecond
+	t.Cleanup(func() { newCommandAPI, pollInterval, maxPollInterval = origAPI, origPoll, origMax })
+}
+
+func TestExecuteCommandsSync(t *testing.T) {
+	m := &mockCommand{invocations: map[string][]*ssm.GetCommandInvocationOutput{
+		"i-1": {nil, invocation("InProgress", "InProgress", -1, ""), invocation("Success", "Success", 0, "ok\n")},
+		"i-2": {invocation("Failed", "Failed", 2, "")},
+		"i-3": {invocation("Failed", "Undeliverable", -1, "")},
+	}}
+	setupCommand(t, m)
+
+	results, err := ExecuteCommandsSync(context.Background(), &Config{Region: "us-east-1"},
+		[]string{"i-1", "i-2", "i-3"}, time.Minute, "echo ok")
+	if err != nil {
+		t.Fatalf("ExecuteCommandsSync failed: %v", err)
+	}
+	if got := m.sent.Parameters["executionTimeout"]; len(got) != 1 || got[0] != "60" {
+		t.Errorf("executionTimeout = %v, want [60]", got)
+	}
+	if r := results[0]; r.InstanceID != "i-1" || r.Status != "Success" || r.ExitCode != 0 || r.Stdout != "ok\n" || r.Err != nil {
+		t.Errorf("unexpected i-1 result: %+v", r)
+	}
+	if r := results[1]; r.Status != "Failed" || r.ExitCode != 2 || r.Err != nil {
+		t.Errorf("unexpected i-2 result: %+v", r)
+	}
+	if r := results[2]; !errors.Is(r.Err, ErrInstanceUnavailable) {
+		t.Errorf("expected ErrInstanceUnavailable for i-3, got %+v", r)
+	}
+}
+
+func TestExecuteCommandsSyncInvalidInstance(t *testing.T) {
+	setupCommand(t, &mockCommand{sendErr: &smithy.GenericAPIError{Code: "InvalidInstanceId", Message: "offline"}})
+	_, err := ExecuteCommandsSync(context.Background(), &Config{Region: "us-east-1"}, []string{"i-1"}, time.Minute, "true")
+	if !errors.Is(err, ErrInstanceUnavailable) {
+		t.Errorf("expected ErrInstanceUnavailable, got %v", err)
+	}
+}
+
+func TestExecuteCommandsSyncContextDone(t *testing.T) {
+	setupCommand(t, &mockCommand{invocations: map[string][]*ssm.GetCommandInvocationOutput{
+		"i-1": {invocation("InProgress", "InProgress", -1, "")},
+	}})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	results, err := ExecuteCommandsSync(ctx, &Config{Region: "us-east-1"}, []string{"i-1"}, time.Minute, "sleep 600")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	if r := results[0]; r.Status != "InProgress" || r.ExitCode != -1 || r.Err == nil {
+		t.Errorf("unexpected partial result: %+v", r)
+	}
+}
@@ -137,43 +137,6 @@ func loadConfig(region string, cfg *Config) (awsv2.Config, error) {
 	return awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region))
 }
 
-// CreateInstance creates a new EC2 instance
-func CreateInstance(cfg *Config, typ InstanceType, sysImage string) (string, error) {
-	if cfg == nil || cfg.Region == "" {
-		return "", fmt.Errorf("EC2 config not set or region missing")
-	}
-
-	awsCfg, err := loadConfig(cfg.Region, cfg)
-	if err != nil {
-		return "", err
-	}
-
-	client := ec2.NewFromConfig(awsCfg)
-	ctx := context.Background()
-
-	input := &ec2.RunInstancesInput{
-		BlockDeviceMappings: []ec2types.BlockDeviceMapping{
-			{
-				DeviceName: awsv2.String("/dev/xvda"),
-				Ebs: &ec2types.EbsBlockDevice{
-					VolumeSize: awsv2.Int32(20),
-				},
-			},
-		},
-		ImageId:      awsv2.String(sysImage),
-		InstanceType: ec2types.InstanceType(typ),
-		MaxCount:     awsv2.Int32(1),
-		MinCount:     awsv2.Int32(1),
-	}
-
-	result, err := client.RunInstances(ctx, input)
-	if err != nil {
-		return "", fmt.Errorf("error creating instance: %v", err)
-	}
-
-	return *result.Instances[0].InstanceId, nil
-}
-
 // TerminateInstance terminates an EC2 instance
 func TerminateInstance(cfg *Config, instanceID string) error {
 	if cfg == nil || cfg.Region == "" {
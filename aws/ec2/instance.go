@@ -0,0 +1,362 @@
+package ec2
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+// InstanceInfo describes an EC2 instance
+type InstanceInfo struct {
+	InstanceID   string            `json:"instance_id"`
+	InstanceType string            `json:"instance_type"`
+	State        string            `json:"state"` // pending, running, stopping, stopped, shutting-down, terminated
+	PublicIP     string            `json:"public_ip"`
+	PrivateIP    string            `json:"private_ip"`
+	LaunchTime   time.Time         `json:"launch_time"`
+	Tags         map[string]string `json:"tags"`
+}
+
+// CreateInstanceOptions configures CreateInstance
+type CreateInstanceOptions struct {
+	KeyName          string            // EC2 key pair name (optional)
+	SecurityGroupIDs []string          // Security group IDs (optional, default group if empty)
+	SubnetID         string            // Subnet ID (optional, default subnet if empty)
+	Tags             map[string]string // Tags for the instance and its volume (optional)
+	UserData         string            // Cloud-init user data, plain text (optional)
+	VolumeSize       int32             // Root volume size in GB (default: 20)
+	Spot             bool              // Launch as a one-time spot instance
+	MaxPrice         string            // Spot max price per hour in USD, e.g. "0.05" (default: on-demand price)
+	FallbackTypes    []InstanceType    // Types tried in order when the requested type has no capacity
+}
+
+// LaunchResult is the instance CreateInstance launched
+type LaunchResult struct {
+	InstanceID   string       `json:"instance_id"`
+	InstanceType InstanceType `json:"instance_type"` // The requested type or one of the fallback types
+	Spot         bool         `json:"spot"`
+}
+
+var (
+	// ErrInstanceNotFound is returned when an instance ID does not exist
+	ErrInstanceNotFound = errors.New("ec2: instance not found")
+
+	// ErrNoCapacity is returned when none of the instance types could be
+	// launched for lack of capacity or a too low spot price
+	ErrNoCapacity = errors.New("ec2: no capacity for any instance type")
+)
+
+// capacityErrors are the RunInstances error codes that move on to the next
+// fallback type
+var capacityErrors = map[string]bool{
+	"InsufficientInstanceCapacity": true,
+	"SpotMaxPriceTooLow":           true,
+}
+
+// describeAPI is the subset of *ec2.Client used to read instances
+type describeAPI interface {
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+}
+
+// launchAPI is the subset of *ec2.Client used to launch and tag instances
+type launchAPI interface {
+	RunInstances(ctx context.Context, params *ec2.RunInstancesInput, optFns ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error)
+	CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error)
+}
+
+var (
+	// newDescribeAPI returns the client for instance reads, replaced in tests
+	newDescribeAPI = func(cfg *Config) (describeAPI, error) {
+		return newClient(cfg)
+	}
+
+	// newLaunchAPI returns the client for launching and tagging, replaced in tests
+	newLaunchAPI = func(cfg *Config) (launchAPI, error) {
+		return newClient(cfg)
+	}
+
+	// Wait polling starts at pollInterval and backs off to maxPollInterval
+	pollInterval    = 2 * time.Second
+	maxPollInterval = 15 * time.Second
+)
+
+// newClient creates an EC2 client for cfg
+func newClient(cfg *Config) (*ec2.Client, error) {
+	if cfg == nil || cfg.Region == "" {
+		return nil, fmt.Errorf("EC2 config not set or region missing")
+	}
+	awsCfg, err := loadConfig(cfg.Region, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return ec2.NewFromConfig(awsCfg), nil
+}
+
+// CreateInstance creates a new EC2 instance and reports the type and market
+// launched. Nil opts launch on demand with a 20GB root volume and default
+// networking. It returns once the instance is requested; use WaitUntilRunning
+// to wait for it to boot. When typ has no capacity (InsufficientInstanceCapacity,
+// SpotMaxPriceTooLow) it tries opts.FallbackTypes in order, returning an
+// error wrapping ErrNoCapacity if none launches. Other errors stop at once.
+//
+// Example:
+//
+//	result, err := ec2.CreateInstance(cfg, "c6i.large", ec2.ImageUbuntu20, &ec2.CreateInstanceOptions{
+//	    Spot:          true,
+//	    FallbackTypes: []ec2.InstanceType{"c5.large", "m6i.large"},
+//	    Tags:          map[string]string{"role": "batch"},
+//	})
+func CreateInstance(cfg *Config, typ InstanceType, sysImage string, opts *CreateInstanceOptions) (*LaunchResult, error) {
+	api, err := newLaunchAPI(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &CreateInstanceOptions{}
+	}
+	input := runInstancesInput(sysImage, opts)
+
+	var capacityErrs []error
+	for _, t := range append([]InstanceType{typ}, opts.FallbackTypes...) {
+		input.InstanceType = ec2types.InstanceType(t)
+		result, err := api.RunInstances(context.Background(), input)
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && capacityErrors[apiErr.ErrorCode()] {
+			capacityErrs = append(capacityErrs, fmt.Errorf("%s: %s", t, apiErr.ErrorCode()))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error creating instance: %v", err)
+		}
+		return &LaunchResult{
+			InstanceID:   *result.Instances[0].InstanceId,
+			InstanceType: t,
+			Spot:         opts.Spot,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %w", ErrNoCapacity, errors.Join(capacityErrs...))
+}
+
+// runInstancesInput builds the RunInstances request for opts, without the
+// instance type
+func runInstancesInput(sysImage string, opts *CreateInstanceOptions) *ec2.RunInstancesInput {
+	volumeSize := opts.VolumeSize
+	if volumeSize == 0 {
+		volumeSize = 20
+	}
+
+	input := &ec2.RunInstancesInput{
+		BlockDeviceMappings: []ec2types.BlockDeviceMapping{
+			{
+				DeviceName: awsv2.String("/dev/xvda"),
+				Ebs: &ec2types.EbsBlockDevice{
+					VolumeSize: awsv2.Int32(volumeSize),
+				},
+			},
+		},
+		ImageId:          awsv2.String(sysImage),
+		MaxCount:         awsv2.Int32(1),
+		MinCount:         awsv2.Int32(1),
+		SecurityGroupIds: opts.SecurityGroupIDs,
+	}
+	if opts.KeyName != "" {
+		input.KeyName = awsv2.String(opts.KeyName)
+	}
+	if opts.SubnetID != "" {
+		input.SubnetId = awsv2.String(opts.SubnetID)
+	}
+	if opts.UserData != "" {
+		input.UserData = awsv2.String(base64.StdEncoding.EncodeToString([]byte(opts.UserData)))
+	}
+	if opts.Spot {
+		spot := &ec2types.SpotMarketOptions{
+			SpotInstanceType:             ec2types.SpotInstanceTypeOneTime,
+			InstanceInterruptionBehavior: ec2types.InstanceInterruptionBehaviorTerminate,
+		}
+		if opts.MaxPrice != "" {
+			spot.MaxPrice = awsv2.String(opts.MaxPrice)
+		}
+		input.InstanceMarketOptions = &ec2types.InstanceMarketOptionsRequest{
+			MarketType:  ec2types.MarketTypeSpot,
+			SpotOptions: spot,
+		}
+	}
+	if len(opts.Tags) > 0 {
+		// Tagged at launch, so the instance is never untagged
+		tags := toTags(opts.Tags)
+		input.TagSpecifications = []ec2types.TagSpecification{
+			{ResourceType: ec2types.ResourceTypeInstance, Tags: tags},
+			{ResourceType: ec2types.ResourceTypeVolume, Tags: tags},
+		}
+		if opts.Spot {
+			input.TagSpecifications = append(input.TagSpecifications,
+				ec2types.TagSpecification{ResourceType: ec2types.ResourceTypeSpotInstancesRequest, Tags: tags})
+		}
+	}
+	return input
+}
+
+// TagInstance adds tags to an instance, overwriting existing values of the
+// same keys
+func TagInstance(cfg *Config, instanceID string, tags map[string]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	api, err := newLaunchAPI(cfg)
+	if err != nil {
+		return err
+	}
+	_, err = api.CreateTags(context.Background(), &ec2.CreateTagsInput{
+		Resources: []string{instanceID},
+		Tags:      toTags(tags),
+	})
+	if err != nil {
+		return fmt.Errorf("error tagging instance %s: %v", instanceID, err)
+	}
+	return nil
+}
+
+func toTags(tags map[string]string) []ec2types.Tag {
+	result := make([]ec2types.Tag, 0, len(tags))
+	for k, v := range tags {
+		result = append(result, ec2types.Tag{Key: awsv2.String(k), Value: awsv2.String(v)})
+	}
+	return result
+}
+
+// GetInstance returns the current state of an instance. Returns
+// ErrInstanceNotFound if it does not exist.
+func GetInstance(cfg *Config, instanceID string) (*InstanceInfo, error) {
+	api, err := newDescribeAPI(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return getInstance(context.Background(), api, instanceID)
+}
+
+// ListInstances returns the instances matching filters, e.g.
+// {"tag:env": {"prod"}, "instance-state-name": {"running"}}. Nil filters
+// list all instances.
+func ListInstances(cfg *Config, filters map[string][]string) ([]InstanceInfo, error) {
+	api, err := newDescribeAPI(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &ec2.DescribeInstancesInput{}
+	for name, values := range filters {
+		input.Filters = append(input.Filters, ec2types.Filter{Name: awsv2.String(name), Values: values})
+	}
+
+	var instances []InstanceInfo
+	paginator := ec2.NewDescribeInstancesPaginator(api, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("error describing EC2 instances: %v", err)
+		}
+		for _, r := range page.Reservations {
+			for _, inst := range r.Instances {
+				instances = append(instances, *toInstanceInfo(&inst))
+			}
+		}
+	}
+	return instances, nil
+}
+
+// WaitUntilRunning polls the instance until it is running, backing off
+// from 2 to 15 seconds between polls. It fails early if the instance stops
+// or terminates instead, and after timeout.
+func WaitUntilRunning(cfg *Config, instanceID string, timeout time.Duration) error {
+	return waitForState(cfg, instanceID, timeout, "running", "stopping", "stopped", "shutting-down", "terminated")
+}
+
+// WaitUntilTerminated polls the instance until it is terminated, like
+// WaitUntilRunning. An instance that no longer exists counts as terminated.
+func WaitUntilTerminated(cfg *Config, instanceID string, timeout time.Duration) error {
+	return waitForState(cfg, instanceID, timeout, "terminated")
+}
+
+// waitForState polls until the instance reaches want, failing if it
+// reaches one of the failed states.
+func waitForState(cfg *Config, instanceID string, timeout time.Duration, want string, failed ...string) error {
+	api, err := newDescribeAPI(cfg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	interval := pollInterval
+	for {
+		info, err := getInstance(ctx, api, instanceID)
+		switch {
+		case errors.Is(err, ErrInstanceNotFound):
+			// New instances may not be visible yet; gone ones are terminated
+			if want == "terminated" {
+				return nil
+			}
+		case err != nil:
+			if ctx.Err() == nil {
+				return err
+			}
+		case info.State == want:
+			return nil
+		default:
+			for _, state := range failed {
+				if info.State == state {
+					return fmt.Errorf("instance %s is %s, expected %s", instanceID, info.State, want)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for instance %s to be %s", instanceID, want)
+		case <-time.After(interval):
+		}
+		interval = min(interval*3/2, maxPollInterval)
+	}
+}
+
+func getInstance(ctx context.Context, api describeAPI, instanceID string) (*InstanceInfo, error) {
+	result, err := api.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidInstanceID.NotFound" {
+		return nil, fmt.Errorf("%w: %s", ErrInstanceNotFound, instanceID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error describing EC2 instance: %v", err)
+	}
+	if len(result.Reservations) == 0 || len(result.Reservations[0].Instances) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInstanceNotFound, instanceID)
+	}
+	return toInstanceInfo(&result.Reservations[0].Instances[0]), nil
+}
+
+func toInstanceInfo(inst *ec2types.Instance) *InstanceInfo {
+	info := &InstanceInfo{
+		InstanceID:   awsv2.ToString(inst.InstanceId),
+		InstanceType: string(inst.InstanceType),
+		PublicIP:     awsv2.ToString(inst.PublicIpAddress),
+		PrivateIP:    awsv2.ToString(inst.PrivateIpAddress),
+		LaunchTime:   awsv2.ToTime(inst.LaunchTime),
+		Tags:         make(map[string]string, len(inst.Tags)),
+	}
+	if inst.State != nil {
+		info.State = string(inst.State.Name)
+	}
+	for _, tag := range inst.Tags {
+		info.Tags[awsv2.ToString(tag.Key)] = awsv2.ToString(tag.Value)
+	}
+	return info
+}
@@ -0,0 +1,188 @@
+package ec2
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+// mockDescribe returns the next state on each DescribeInstances call; an
+// empty state means the instance is not found.
+type mockDescribe struct {
+	states []string
+	calls  int
+}
+
+func (m *mockDescribe) DescribeInstances(ctx context.Context, in *ec2.DescribeInstancesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	state := m.states[min(m.calls, len(m.states)-1)]
+	m.calls++
+	if state == "" {
+		return nil, &smithy.GenericAPIError{Code: "InvalidInstanceID.NotFound", Message: "not found"}
+	}
+	return &ec2.DescribeInstancesOutput{Reservations: []ec2types.Reservation{{Instances: []ec2types.Instance{{
+		InstanceId:       awsv2.String("i-123"),
+		InstanceType:     ec2types.InstanceTypeT3Micro,
+		State:            &ec2types.InstanceState{Name: ec2types.InstanceStateName(state)},
+		PublicIpAddress:  awsv2.String("54.1.2.3"),
+		PrivateIpAddress: awsv2.String("10.0.0.5"),
+		LaunchTime:       awsv2.Time(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)),
+		Tags:             []ec2types.Tag{{Key: awsv2.String("Name"), Value: awsv2.String("web-1")}},
+	}}}}}, nil
+}
+
+func setupDescribe(t *testing.T, states ...string) *mockDescribe {
+	t.Helper()
+	m := &mockDescribe{states: states}
+	origAPI, origPoll, origMax := newDescribeAPI, pollInterval, maxPollInterval
+	newDescribeAPI = func(cfg *Config) (describeAPI, error) { return m, nil }
+	pollInterval, maxPollInterval = time.Millisecond, 5*time.Millisecond
+	t.Cleanup(func() { newDescribeAPI, pollInterval, maxPollInterval = origAPI, origPoll, origMax })
+	return m
+}
+
+func TestGetInstance(t *testing.T) {
+	setupDescribe(t, "running")
+	info, err := GetInstance(&Config{Region: "us-east-1"}, "i-123")
+	if err != nil {
+		t.Fatalf("GetInstance failed: %v", err)
+	}
+	if info.State != "running" || info.PublicIP != "54.1.2.3" || info.PrivateIP != "10.0.0.5" ||
+		info.InstanceType != "t3.micro" || info.Tags["Name"] != "web-1" || info.LaunchTime.Year() != 2024 {
+		t.Errorf("unexpected instance: %+v", info)
+	}
+
+	setupDescribe(t, "")
+	if _, err := GetInstance(&Config{Region: "us-east-1"}, "i-404"); !errors.Is(err, ErrInstanceNotFound) {
+		t.Errorf("expected ErrInstanceNotFound, got %v", err)
+	}
+}
+
+func TestWaitUntilRunning(t *testing.T) {
+	cfg := &Config{Region: "us-east-1"}
+
+	m := setupDescribe(t, "", "pending", "pending", "running")
+	if err := WaitUntilRunning(cfg, "i-123", time.Second); err != nil {
+		t.Fatalf("WaitUntilRunning failed: %v", err)
+	}
+	if m.calls != 4 {
+		t.Errorf("expected 4 polls, got %d", m.calls)
+	}
+
+	setupDescribe(t, "pending", "shutting-down")
+	if err := WaitUntilRunning(cfg, "i-123", time.Second); err == nil || !strings.Contains(err.Error(), "shutting-down") {
+		t.Errorf("expected early failure, got %v", err)
+	}
+
+	setupDescribe(t, "pending")
+	if err := WaitUntilRunning(cfg, "i-123", 20*time.Millisecond); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout, got %v", err)
+	}
+}
+
+func TestWaitUntilTerminated(t *testing.T) {
+	setupDescribe(t, "running", "shutting-down", "terminated")
+	if err := WaitUntilTerminated(&Config{Region: "us-east-1"}, "i-123", time.Second); err != nil {
+		t.Errorf("WaitUntilTerminated failed: %v", err)
+	}
+	setupDescribe(t, "shutting-down", "")
+	if err := WaitUntilTerminated(&Config{Region: "us-east-1"}, "i-123", time.Second); err != nil {
+		t.Errorf("a vanished instance counts as terminated: %v", err)
+	}
+}
+
+// mockLaunch fails RunInstances with errs[type], recording the requests
+type mockLaunch struct {
+	errs   map[ec2types.InstanceType]error
+	inputs []ec2.RunInstancesInput
+	tagged *ec2.CreateTagsInput
+}
+
+func (m *mockLaunch) RunInstances(ctx context.Context, in *ec2.RunInstancesInput, _ ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error) {
+	m.inputs = append(m.inputs, *in)
+	if err := m.errs[in.InstanceType]; err != nil {
+		return nil, err
+	}
+	return &ec2.RunInstancesOutput{Instances: []ec2types.Instance{{InstanceId: awsv2.String("i-" + string(in.InstanceType))}}}, nil
+}
+
+func (m *mockLaunch) CreateTags(ctx context.Context, in *ec2.CreateTagsInput, _ ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error) {
+	m.tagged = in
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+func setupLaunch(t *testing.T, errs map[ec2types.InstanceType]error) *mockLaunch {
+	t.Helper()
+	m := &mockLaunch{errs: errs}
+	orig := newLaunchAPI
+	newLaunchAPI = func(cfg *Config) (launchAPI, error) { return m, nil }
+	t.Cleanup(func() { newLaunchAPI = orig })
+	return m
+}
+
+func TestCreateInstance(t *testing.T) {
+	cfg := &Config{Region: "us-east-1"}
+	noCapacity := &smithy.GenericAPIError{Code: "InsufficientInstanceCapacity", Message: "no capacity"}
+	tooLow := &smithy.GenericAPIError{Code: "SpotMaxPriceTooLow", Message: "price too low"}
+	opts := &CreateInstanceOptions{
+		Spot:          true,
+		MaxPrice:      "0.05",
+		FallbackTypes: []InstanceType{"c5.large", "m6i.large"},
+		Tags:          map[string]string{"role": "batch"},
+	}
+
+	m := setupLaunch(t, map[ec2types.InstanceType]error{"c6i.large": noCapacity, "c5.large": tooLow})
+	result, err := CreateInstance(cfg, "c6i.large", ImageUbuntu20, opts)
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	if *result != (LaunchResult{InstanceID: "i-m6i.large", InstanceType: "m6i.large", Spot: true}) {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(m.inputs) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(m.inputs))
+	}
+	in := m.inputs[2]
+	if in.InstanceMarketOptions == nil || in.InstanceMarketOptions.MarketType != ec2types.MarketTypeSpot ||
+		awsv2.ToString(in.InstanceMarketOptions.SpotOptions.MaxPrice) != "0.05" {
+		t.Errorf("spot options not set: %+v", in.InstanceMarketOptions)
+	}
+	if len(in.TagSpecifications) != 3 || in.TagSpecifications[2].ResourceType != ec2types.ResourceTypeSpotInstancesRequest {
+		t.Errorf("unexpected tag specifications: %+v", in.TagSpecifications)
+	}
+
+	// Every type out of capacity
+	setupLaunch(t, map[ec2types.InstanceType]error{"c6i.large": noCapacity, "c5.large": noCapacity, "m6i.large": tooLow})
+	_, err = CreateInstance(cfg, "c6i.large", ImageUbuntu20, opts)
+	if !errors.Is(err, ErrNoCapacity) || !strings.Contains(err.Error(), "m6i.large: SpotMaxPriceTooLow") {
+		t.Errorf("expected ErrNoCapacity, got %v", err)
+	}
+
+	// Other errors are not retried
+	m = setupLaunch(t, map[ec2types.InstanceType]error{"c6i.large": &smithy.GenericAPIError{Code: "InvalidAMIID.NotFound"}})
+	if _, err := CreateInstance(cfg, "c6i.large", "ami-404", opts); err == nil || errors.Is(err, ErrNoCapacity) || len(m.inputs) != 1 {
+		t.Errorf("expected immediate failure, got %v after %d attempts", err, len(m.inputs))
+	}
+
+	// On-demand by default
+	m = setupLaunch(t, nil)
+	if result, err := CreateInstance(cfg, InstanceMicro, ImageUbuntu20, nil); err != nil || result.InstanceID != "i-t3.micro" || result.Spot || m.inputs[0].InstanceMarketOptions != nil {
+		t.Errorf("unexpected on-demand launch: %+v, %v", result, err)
+	}
+}
+
+func TestTagInstance(t *testing.T) {
+	m := setupLaunch(t, nil)
+	if err := TagInstance(&Config{Region: "us-east-1"}, "i-123", map[string]string{"env": "prod"}); err != nil {
+		t.Fatalf("TagInstance failed: %v", err)
+	}
+	if m.tagged == nil || m.tagged.Resources[0] != "i-123" || awsv2.ToString(m.tagged.Tags[0].Key) != "env" {
+		t.Errorf("unexpected CreateTags input: %+v", m.tagged)
+	}
+}
@@ -0,0 +1,215 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// minPartSize is the S3 minimum size of every part but the last
+	minPartSize = 5 << 20
+	// maxParts is the S3 limit of parts per multipart upload
+	maxParts = 10000
+	// uploadConcurrency bounds the parts uploaded (and buffered) at once
+	uploadConcurrency = 4
+)
+
+// multipartAPI is the subset of *s3.Client used by UploadLarge
+type multipartAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
+// UploadLarge uploads size bytes from r to the configured bucket with a
+// multipart upload, uploading up to 4 parts of partSize bytes concurrently.
+// partSize is raised to the S3 minimum of 5 MiB, and further if needed to
+// stay within 10000 parts. Objects that fit in one part use PutObject.
+//
+// On any error, including r ending before size bytes, the multipart upload
+// is aborted so no parts are left behind.
+func UploadLarge(ctx context.Context, objKey string, r io.Reader, size int64, partSize int64) error {
+	client, err := getClient()
+	if err != nil {
+		return err
+	}
+
+	configMux.RLock()
+	cfg := globalConfig
+	configMux.RUnlock()
+
+	_, err = uploadStream(ctx, client, cfg.Bucket, strings.TrimLeft(objKey, "/"), r, size, partSize, "")
+	return err
+}
+
+// uploadStream uploads r as described in UploadLarge and returns the bytes
+// uploaded. size is the exact length of r, or -1 to read r until EOF.
+func uploadStream(ctx context.Context, api multipartAPI, bucket, objKey string, r io.Reader, size, partSize int64, contentType string) (int64, error) {
+	partSize = max(partSize, minPartSize)
+	if size >= 0 {
+		partSize = max(partSize, (size+maxParts-1)/maxParts)
+	}
+	var ct *string
+	if contentType != "" {
+		ct = awsv2.String(contentType)
+	}
+
+	// remaining returns the bytes left to read, or partSize if unknown
+	var total int64
+	remaining := func() int64 {
+		if size < 0 {
+			return partSize
+		}
+		return min(partSize, size-total)
+	}
+
+	first, eof, err := readPart(r, remaining())
+	if err != nil {
+		return 0, err
+	}
+	total += int64(len(first))
+	if eof || total == size {
+		if size >= 0 && total != size {
+			return 0, fmt.Errorf("read upload body: %w", io.ErrUnexpectedEOF)
+		}
+		_, err = api.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      awsv2.String(bucket),
+			Key:         awsv2.String(objKey),
+			Body:        bytes.NewReader(first),
+			ContentType: ct,
+		})
+		return total, err
+	}
+
+	created, err := api.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      awsv2.String(bucket),
+		Key:         awsv2.String(objKey),
+		ContentType: ct,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("create multipart upload: %w", err)
+	}
+	uploadID := created.UploadId
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		parts   []types.CompletedPart
+		partsMu sync.Mutex
+		sem     = make(chan struct{}, uploadConcurrency)
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		partErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			partErr = err
+			cancel()
+		})
+	}
+	upload := func(num int32, data []byte) {
+		defer func() { <-sem; wg.Done() }()
+		out, err := api.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     awsv2.String(bucket),
+			Key:        awsv2.String(objKey),
+			UploadId:   uploadID,
+			PartNumber: awsv2.Int32(num),
+			Body:       bytes.NewReader(data),
+		})
+		if err != nil {
+			fail(fmt.Errorf("upload part %d: %w", num, err))
+			return
+		}
+		partsMu.Lock()
+		parts = append(parts, types.CompletedPart{ETag: out.ETag, PartNumber: awsv2.Int32(num)})
+		partsMu.Unlock()
+	}
+
+	sem <- struct{}{}
+	wg.Add(1)
+	go upload(1, first)
+	for num := int32(2); !eof && (size < 0 || total < size); num++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		var data []byte
+		data, eof, err = readPart(r, remaining())
+		if err == nil && num > maxParts && len(data) > 0 {
+			err = fmt.Errorf("upload body exceeds %d parts of %d bytes", maxParts, partSize)
+		}
+		if err != nil || len(data) == 0 {
+			<-sem
+			if err != nil {
+				fail(err)
+			}
+			break
+		}
+		total += int64(len(data))
+		wg.Add(1)
+		go upload(num, data)
+	}
+	wg.Wait()
+	if partErr == nil && ctx.Err() != nil {
+		partErr = ctx.Err() // Canceled by the caller
+	}
+	if partErr == nil && size >= 0 && total != size {
+		partErr = fmt.Errorf("read upload body: %w", io.ErrUnexpectedEOF)
+	}
+
+	if partErr == nil {
+		slices.SortFunc(parts, func(a, b types.CompletedPart) int {
+			return int(*a.PartNumber - *b.PartNumber)
+		})
+		_, partErr = api.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          awsv2.String(bucket),
+			Key:             awsv2.String(objKey),
+			UploadId:        uploadID,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		})
+		if partErr == nil {
+			return total, nil
+		}
+		partErr = fmt.Errorf("complete multipart upload: %w", partErr)
+	}
+
+	// Abort with a fresh context: ctx is canceled by now
+	_, abortErr := api.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+		Bucket:   awsv2.String(bucket),
+		Key:      awsv2.String(objKey),
+		UploadId: uploadID,
+	})
+	if abortErr != nil {
+		abortErr = fmt.Errorf("abort multipart upload: %w", abortErr)
+	}
+	return 0, errors.Join(partErr, abortErr)
+}
+
+// readPart reads up to n bytes of r. eof reports that r ended.
+func readPart(r io.Reader, n int64) (data []byte, eof bool, err error) {
+	data = make([]byte, n)
+	read, err := io.ReadFull(r, data)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return data[:read], true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("read upload body: %w", err)
+	}
+	return data, false, nil
+}
@@ -0,0 +1,163 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/spf13/viper"
+)
+
+// mockMultipart records multipart calls, failing UploadPart for failPart.
+type mockMultipart struct {
+	mu        sync.Mutex
+	parts     map[int32][]byte
+	put       []byte
+	completed []int32
+	aborted   bool
+	failPart  int32
+
+	active, maxActive atomic.Int32
+}
+
+func (m *mockMultipart) PutObject(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	m.put, _ = io.ReadAll(in.Body)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *mockMultipart) CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return &s3.CreateMultipartUploadOutput{UploadId: awsv2.String("upload-1")}, nil
+}
+
+func (m *mockMultipart) UploadPart(ctx context.Context, in *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	n := m.active.Add(1)
+	defer m.active.Add(-1)
+	for {
+		cur := m.maxActive.Load()
+		if n <= cur || m.maxActive.CompareAndSwap(cur, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	num := *in.PartNumber
+	if num == m.failPart {
+		return nil, errors.New("connection reset")
+	}
+	data, _ := io.ReadAll(in.Body)
+	m.mu.Lock()
+	m.parts[num] = data
+	m.mu.Unlock()
+	return &s3.UploadPartOutput{ETag: awsv2.String(fmt.Sprintf("etag-%d", num))}, nil
+}
+
+func (m *mockMultipart) CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	for _, p := range in.MultipartUpload.Parts {
+		if *p.ETag != fmt.Sprintf("etag-%d", *p.PartNumber) {
+			return nil, fmt.Errorf("bad etag for part %d", *p.PartNumber)
+		}
+		m.completed = append(m.completed, *p.PartNumber)
+	}
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (m *mockMultipart) AbortMultipartUpload(ctx context.Context, in *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	m.aborted = true
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func TestUploadLargeMultipart(t *testing.T) {
+	m := &mockMultipart{parts: map[int32][]byte{}}
+	data := bytes.Repeat([]byte("0123456789"), (minPartSize*10+123)/10)
+
+	_, err := uploadStream(context.Background(), m, "bucket", "big.bin", bytes.NewReader(data), int64(len(data)), 1, "")
+	if err != nil {
+		t.Fatalf("uploadStream failed: %v", err)
+	}
+	if fmt.Sprint(m.completed) != "[1 2 3 4 5 6 7 8 9 10 11]" {
+		t.Errorf("unexpected completed parts: %v", m.completed)
+	}
+	var got []byte
+	for i := int32(1); i <= 11; i++ {
+		got = append(got, m.parts[i]...)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("parts do not reassemble the input")
+	}
+	if n := m.maxActive.Load(); n > uploadConcurrency || n < 2 {
+		t.Errorf("expected bounded concurrent uploads, max was %d", n)
+	}
+	if m.aborted {
+		t.Error("successful upload should not abort")
+	}
+}
+
+func TestUploadLargeAborts(t *testing.T) {
+	m := &mockMultipart{parts: map[int32][]byte{}, failPart: 3}
+	size := int64(minPartSize * 8)
+	_, err := uploadStream(context.Background(), m, "bucket", "big.bin", bytes.NewReader(make([]byte, size)), size, minPartSize, "")
+	if err == nil || !strings.Contains(err.Error(), "upload part 3") {
+		t.Errorf("expected part error, got %v", err)
+	}
+	if !m.aborted || len(m.completed) != 0 {
+		t.Errorf("failed upload should abort: aborted=%v completed=%v", m.aborted, m.completed)
+	}
+
+	// Short reader
+	m = &mockMultipart{parts: map[int32][]byte{}}
+	_, err = uploadStream(context.Background(), m, "bucket", "big.bin", bytes.NewReader(make([]byte, minPartSize+1)), minPartSize*3, minPartSize, "")
+	if err == nil || !m.aborted {
+		t.Errorf("short body should abort, got %v", err)
+	}
+}
+
+func TestUploadLargeSinglePart(t *testing.T) {
+	m := &mockMultipart{}
+	if _, err := uploadStream(context.Background(), m, "bucket", "small.txt", strings.NewReader("small"), 5, minPartSize, ""); err != nil {
+		t.Fatalf("uploadStream failed: %v", err)
+	}
+	if string(m.put) != "small" {
+		t.Errorf("small object should use PutObject, got %q", m.put)
+	}
+}
+
+func TestPresignAndPublicURL(t *testing.T) {
+	Reset()
+	viper.Reset()
+	viper.Set("aws.s3.region", "us-east-1")
+	viper.Set("aws.s3.bucket", "my-bucket")
+	viper.Set("aws.s3.access_key", "AKIA_TEST")
+	viper.Set("aws.s3.secret_key", "secret")
+	viper.Set("aws.s3.url_prefix", "https://cdn.example.com/")
+	defer Reset()
+
+	if got := PublicURL("/a/b.png"); got != "https://cdn.example.com/a/b.png" {
+		t.Errorf("unexpected public URL: %s", got)
+	}
+
+	put, err := PresignPut("a/b.png", "image/png", 10*time.Minute)
+	if err != nil {
+		t.Fatalf("PresignPut failed: %v", err)
+	}
+	if !strings.Contains(put, "my-bucket") || !strings.Contains(put, "/a/b.png") ||
+		!strings.Contains(put, "X-Amz-Expires=600") || !strings.Contains(put, "content-type") {
+		t.Errorf("unexpected presigned PUT URL: %s", put)
+	}
+
+	get, err := PresignGet("a/b.png", time.Hour)
+	if err != nil {
+		t.Fatalf("PresignGet failed: %v", err)
+	}
+	if !strings.Contains(get, "X-Amz-Expires=3600") || strings.Contains(get, "content-type") {
+		t.Errorf("unexpected presigned GET URL: %s", get)
+	}
+}
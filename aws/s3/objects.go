@@ -0,0 +1,263 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// maxDeleteKeys is the S3 limit of keys per DeleteObjects request, and of
+// keys per ListObjectsV2 page
+const maxDeleteKeys = 1000
+
+// objectsAPI is the subset of *s3.Client used to list and manage objects
+type objectsAPI interface {
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+}
+
+// ObjectInfo describes an object in the configured bucket
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"` // Without the surrounding quotes
+	LastModified time.Time `json:"last_modified"`
+	URL          string    `json:"url"` // url_prefix joined with the key
+}
+
+// ListOptions configures List
+type ListOptions struct {
+	// MaxKeys is the page size, at most 1000. Default: 1000
+	MaxKeys int32
+	// ContinuationToken is the nextToken of the previous page
+	ContinuationToken string
+}
+
+// KeyError is an object DeletePrefix failed to delete
+type KeyError struct {
+	Key     string `json:"key"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// DeleteError is returned by DeletePrefix when some objects were not
+// deleted. The others were.
+type DeleteError struct {
+	Failed []KeyError
+}
+
+func (e *DeleteError) Error() string {
+	keys := make([]string, len(e.Failed))
+	for i, f := range e.Failed {
+		keys[i] = fmt.Sprintf("%s (%s)", f.Key, f.Code)
+	}
+	return fmt.Sprintf("s3: failed to delete %d objects: %s", len(e.Failed), strings.Join(keys, ", "))
+}
+
+// objectsClient returns the S3 client and configuration
+func objectsClient() (objectsAPI, *Config, error) {
+	client, err := getClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	configMux.RLock()
+	cfg := globalConfig
+	configMux.RUnlock()
+	return client, cfg, nil
+}
+
+// List returns a page of the objects whose keys start with prefix, in key
+// order. nextToken is empty on the last page; pass it as
+// ListOptions.ContinuationToken to get the next one.
+//
+// Example:
+//
+//	var token string
+//	for {
+//	    objects, next, err := s3.List("uploads/2024/", s3.ListOptions{ContinuationToken: token})
+//	    if err != nil {
+//	        return err
+//	    }
+//	    // ...
+//	    if token = next; token == "" {
+//	        break
+//	    }
+//	}
+func List(prefix string, opts ListOptions) ([]ObjectInfo, string, error) {
+	api, cfg, err := objectsClient()
+	if err != nil {
+		return nil, "", err
+	}
+	return listObjects(context.Background(), api, cfg, strings.TrimLeft(prefix, "/"), opts)
+}
+
+func listObjects(ctx context.Context, api objectsAPI, cfg *Config, prefix string, opts ListOptions) ([]ObjectInfo, string, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket:  awsv2.String(cfg.Bucket),
+		Prefix:  awsv2.String(prefix),
+		MaxKeys: awsv2.Int32(maxDeleteKeys),
+	}
+	if opts.MaxKeys > 0 && opts.MaxKeys < maxDeleteKeys {
+		input.MaxKeys = awsv2.Int32(opts.MaxKeys)
+	}
+	if opts.ContinuationToken != "" {
+		input.ContinuationToken = awsv2.String(opts.ContinuationToken)
+	}
+
+	result, err := api.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, "", err
+	}
+	objects := make([]ObjectInfo, len(result.Contents))
+	for i, obj := range result.Contents {
+		key := awsv2.ToString(obj.Key)
+		objects[i] = ObjectInfo{
+			Key:          key,
+			Size:         awsv2.ToInt64(obj.Size),
+			ETag:         strings.Trim(awsv2.ToString(obj.ETag), `"`),
+			LastModified: awsv2.ToTime(obj.LastModified),
+			URL:          publicURL(cfg, key),
+		}
+	}
+	if !awsv2.ToBool(result.IsTruncated) {
+		return objects, "", nil
+	}
+	return objects, awsv2.ToString(result.NextContinuationToken), nil
+}
+
+// Exists reports whether an object exists in the configured bucket
+func Exists(objKey string) (bool, error) {
+	api, cfg, err := objectsClient()
+	if err != nil {
+		return false, err
+	}
+	return objectExists(context.Background(), api, cfg.Bucket, strings.TrimLeft(objKey, "/"))
+}
+
+func objectExists(ctx context.Context, api objectsAPI, bucket, objKey string) (bool, error) {
+	_, err := api.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: awsv2.String(bucket),
+		Key:    awsv2.String(objKey),
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Copy copies an object within the configured bucket, replacing dstKey if
+// it exists. Objects over 5 GB cannot be copied in one request.
+func Copy(srcKey, dstKey string) error {
+	api, cfg, err := objectsClient()
+	if err != nil {
+		return err
+	}
+	return copyObject(context.Background(), api, cfg.Bucket, strings.TrimLeft(srcKey, "/"), strings.TrimLeft(dstKey, "/"))
+}
+
+func copyObject(ctx context.Context, api objectsAPI, bucket, srcKey, dstKey string) error {
+	// CopySource is "bucket/key" with the key URL-encoded
+	segments := strings.Split(srcKey, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	_, err := api.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     awsv2.String(bucket),
+		Key:        awsv2.String(dstKey),
+		CopySource: awsv2.String(bucket + "/" + strings.Join(segments, "/")),
+	})
+	return err
+}
+
+// Move copies an object to dstKey, then deletes srcKey. If the delete
+// fails, both objects exist.
+func Move(srcKey, dstKey string) error {
+	api, cfg, err := objectsClient()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	srcKey, dstKey = strings.TrimLeft(srcKey, "/"), strings.TrimLeft(dstKey, "/")
+	if err := copyObject(ctx, api, cfg.Bucket, srcKey, dstKey); err != nil {
+		return err
+	}
+	_, err = api.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: awsv2.String(cfg.Bucket),
+		Key:    awsv2.String(srcKey),
+	})
+	return err
+}
+
+// DeletePrefix deletes every object whose key starts with prefix, listing
+// and deleting batchSize keys per request (at most 1000, the default).
+// It returns the number of objects deleted. Keys that fail are reported in
+// a *DeleteError after all batches ran; a failed request stops at once.
+// An empty prefix is rejected rather than emptying the bucket.
+func DeletePrefix(ctx context.Context, prefix string, batchSize int) (int, error) {
+	api, cfg, err := objectsClient()
+	if err != nil {
+		return 0, err
+	}
+	return deletePrefix(ctx, api, cfg, strings.TrimLeft(prefix, "/"), batchSize)
+}
+
+func deletePrefix(ctx context.Context, api objectsAPI, cfg *Config, prefix string, batchSize int) (int, error) {
+	if prefix == "" {
+		return 0, fmt.Errorf("s3: DeletePrefix requires a prefix")
+	}
+	if batchSize <= 0 || batchSize > maxDeleteKeys {
+		batchSize = maxDeleteKeys
+	}
+
+	deleted := 0
+	var failed []KeyError
+	opts := ListOptions{MaxKeys: int32(batchSize)}
+	for {
+		objects, next, err := listObjects(ctx, api, cfg, prefix, opts)
+		if err != nil {
+			return deleted, err
+		}
+		if len(objects) > 0 {
+			ids := make([]types.ObjectIdentifier, len(objects))
+			for i, obj := range objects {
+				ids[i] = types.ObjectIdentifier{Key: awsv2.String(obj.Key)}
+			}
+			result, err := api.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+				Bucket: awsv2.String(cfg.Bucket),
+				Delete: &types.Delete{Objects: ids, Quiet: awsv2.Bool(true)},
+			})
+			if err != nil {
+				return deleted, err
+			}
+			// Quiet mode only reports failures
+			deleted += len(objects) - len(result.Errors)
+			for _, e := range result.Errors {
+				failed = append(failed, KeyError{
+					Key:     awsv2.ToString(e.Key),
+					Code:    awsv2.ToString(e.Code),
+					Message: awsv2.ToString(e.Message),
+				})
+			}
+		}
+		if next == "" {
+			break
+		}
+		opts.ContinuationToken = next
+	}
+
+	if len(failed) > 0 {
+		return deleted, &DeleteError{Failed: failed}
+	}
+	return deleted, nil
+}
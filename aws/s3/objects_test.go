@@ -0,0 +1,184 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/spf13/viper"
+)
+
+// mockObjects is a bucket of keys, failing DeleteObjects for failDelete.
+type mockObjects struct {
+	keys       []string // Sorted
+	failDelete map[string]bool
+	copies     []string // "CopySource -> key"
+	pages      int
+	batches    []int
+}
+
+func newMockObjects(keys ...string) *mockObjects {
+	slices.Sort(keys)
+	return &mockObjects{keys: keys}
+}
+
+func (m *mockObjects) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	m.pages++
+	start := 0
+	if in.ContinuationToken != nil {
+		// The token is the last key of the previous page
+		start, _ = slices.BinarySearch(m.keys, *in.ContinuationToken)
+		if start < len(m.keys) && m.keys[start] == *in.ContinuationToken {
+			start++
+		}
+	}
+	out := &s3.ListObjectsV2Output{IsTruncated: awsv2.Bool(false)}
+	for _, key := range m.keys[start:] {
+		if !strings.HasPrefix(key, *in.Prefix) {
+			continue
+		}
+		if len(out.Contents) == int(*in.MaxKeys) {
+			out.IsTruncated = awsv2.Bool(true)
+			out.NextContinuationToken = out.Contents[len(out.Contents)-1].Key
+			break
+		}
+		out.Contents = append(out.Contents, types.Object{
+			Key:          awsv2.String(key),
+			Size:         awsv2.Int64(int64(len(key))),
+			ETag:         awsv2.String(`"` + key + `"`),
+			LastModified: awsv2.Time(time.Unix(1700000000, 0)),
+		})
+	}
+	return out, nil
+}
+
+func (m *mockObjects) HeadObject(ctx context.Context, in *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	if *in.Key == "forbidden" {
+		return nil, errors.New("access denied")
+	}
+	if !slices.Contains(m.keys, *in.Key) {
+		return nil, &types.NotFound{}
+	}
+	return &s3.HeadObjectOutput{}, nil
+}
+
+func (m *mockObjects) CopyObject(ctx context.Context, in *s3.CopyObjectInput, _ ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	m.copies = append(m.copies, *in.CopySource+" -> "+*in.Key)
+	return &s3.CopyObjectOutput{}, nil
+}
+
+func (m *mockObjects) DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	m.keys = slices.DeleteFunc(m.keys, func(k string) bool { return k == *in.Key })
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (m *mockObjects) DeleteObjects(ctx context.Context, in *s3.DeleteObjectsInput, _ ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	m.batches = append(m.batches, len(in.Delete.Objects))
+	out := &s3.DeleteObjectsOutput{}
+	for _, obj := range in.Delete.Objects {
+		if m.failDelete[*obj.Key] {
+			out.Errors = append(out.Errors, types.Error{Key: obj.Key, Code: awsv2.String("AccessDenied"), Message: awsv2.String("Access Denied")})
+			continue
+		}
+		m.keys = slices.DeleteFunc(m.keys, func(k string) bool { return k == *obj.Key })
+	}
+	return out, nil
+}
+
+var testConfig = &Config{Bucket: "my-bucket", URLPrefix: "https://cdn.example.com"}
+
+func TestListPagination(t *testing.T) {
+	m := newMockObjects("a/1", "a/2", "a/3", "a/4", "a/5", "b/1")
+
+	var keys []string
+	var token string
+	for {
+		objects, next, err := listObjects(context.Background(), m, testConfig, "a/", ListOptions{MaxKeys: 2, ContinuationToken: token})
+		if err != nil {
+			t.Fatalf("listObjects failed: %v", err)
+		}
+		for _, obj := range objects {
+			keys = append(keys, obj.Key)
+		}
+		if token = next; token == "" {
+			break
+		}
+	}
+	if strings.Join(keys, ",") != "a/1,a/2,a/3,a/4,a/5" || m.pages != 3 {
+		t.Errorf("expected 5 keys in 3 pages, got %v in %d", keys, m.pages)
+	}
+
+	objects, _, _ := listObjects(context.Background(), m, testConfig, "b/", ListOptions{})
+	if len(objects) != 1 || objects[0].ETag != "b/1" || objects[0].Size != 3 ||
+		objects[0].URL != "https://cdn.example.com/b/1" || objects[0].LastModified.Unix() != 1700000000 {
+		t.Errorf("unexpected object info: %+v", objects)
+	}
+}
+
+func TestExistsAndCopy(t *testing.T) {
+	m := newMockObjects("a/report 1.pdf")
+
+	for key, want := range map[string]bool{"a/report 1.pdf": true, "missing": false} {
+		if ok, err := objectExists(context.Background(), m, "my-bucket", key); err != nil || ok != want {
+			t.Errorf("Exists(%q) = %v, %v", key, ok, err)
+		}
+	}
+	if _, err := objectExists(context.Background(), m, "my-bucket", "forbidden"); err == nil {
+		t.Error("errors other than not found should be returned")
+	}
+
+	copyObject(context.Background(), m, "my-bucket", "a/report 1.pdf", "b/report.pdf")
+	if len(m.copies) != 1 || m.copies[0] != "my-bucket/a/report%201.pdf -> b/report.pdf" {
+		t.Errorf("unexpected copy: %v", m.copies)
+	}
+}
+
+func TestDeletePrefixBatches(t *testing.T) {
+	var keys []string
+	for i := range 2500 {
+		keys = append(keys, fmt.Sprintf("tmp/%04d", i))
+	}
+	m := newMockObjects(append(slices.Clone(keys), "keep/1")...)
+
+	deleted, err := deletePrefix(context.Background(), m, testConfig, "tmp/", 0)
+	if err != nil || deleted != 2500 {
+		t.Fatalf("expected 2500 deleted, got %d, %v", deleted, err)
+	}
+	if fmt.Sprint(m.batches) != "[1000 1000 500]" || len(m.keys) != 1 {
+		t.Errorf("expected 3 batches, got %v with %v left", m.batches, m.keys)
+	}
+
+	m = newMockObjects(slices.Clone(keys[:25])...)
+	m.failDelete = map[string]bool{"tmp/0003": true, "tmp/0021": true}
+	deleted, err = deletePrefix(context.Background(), m, testConfig, "tmp/", 10)
+	var deleteErr *DeleteError
+	if !errors.As(err, &deleteErr) || deleted != 23 || fmt.Sprint(m.batches) != "[10 10 5]" {
+		t.Fatalf("expected 23 deleted in 3 batches, got %d in %v, %v", deleted, m.batches, err)
+	}
+	if len(deleteErr.Failed) != 2 || deleteErr.Failed[0].Key != "tmp/0003" || deleteErr.Failed[1].Code != "AccessDenied" {
+		t.Errorf("unexpected failures: %+v", deleteErr.Failed)
+	}
+
+	if _, err := deletePrefix(context.Background(), m, testConfig, "", 0); err == nil {
+		t.Error("empty prefix should be rejected")
+	}
+}
+
+func TestObjects_NoConfig(t *testing.T) {
+	Reset()
+	viper.Reset()
+
+	if _, _, err := List("a/", ListOptions{}); err == nil {
+		t.Error("Expected error when config is not set")
+	}
+	if _, err := DeletePrefix(context.Background(), "a/", 0); err == nil || !strings.Contains(err.Error(), "s3") {
+		t.Error("Expected error when config is not set")
+	}
+}
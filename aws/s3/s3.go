@@ -14,6 +14,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
 )
@@ -142,7 +143,6 @@ func Upload(objKey string, body io.Reader) (string, error) {
 	configMux.RUnlock()
 
 	bucket := cfg.Bucket
-	urlPrefix := strings.TrimRight(cfg.URLPrefix, "/") + "/"
 	objKey = strings.TrimLeft(objKey, "/")
 
 	ctx := context.Background()
@@ -155,7 +155,69 @@ func Upload(objKey string, body io.Reader) (string, error) {
 		return "", err
 	}
 
-	return urlPrefix + objKey, nil
+	return publicURL(cfg, objKey), nil
+}
+
+// PublicURL returns the public URL of an object in the configured bucket:
+// url_prefix joined with the key. It returns "" if S3 is not configured.
+func PublicURL(objKey string) string {
+	if _, err := getClient(); err != nil {
+		return ""
+	}
+	configMux.RLock()
+	cfg := globalConfig
+	configMux.RUnlock()
+	return publicURL(cfg, objKey)
+}
+
+func publicURL(cfg *Config, objKey string) string {
+	return strings.TrimRight(cfg.URLPrefix, "/") + "/" + strings.TrimLeft(objKey, "/")
+}
+
+// PutObject uploads body to bucket (the configured bucket if empty) with
+// the given content type. Unlike Upload it returns no URL, since url_prefix
+// only applies to the configured bucket.
+func PutObject(ctx context.Context, bucket, objKey string, body io.Reader, contentType string) error {
+	client, err := getClient()
+	if err != nil {
+		return err
+	}
+	if bucket == "" {
+		configMux.RLock()
+		bucket = globalConfig.Bucket
+		configMux.RUnlock()
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket: awsv2.String(bucket),
+		Key:    awsv2.String(strings.TrimLeft(objKey, "/")),
+		Body:   body,
+	}
+	if contentType != "" {
+		input.ContentType = awsv2.String(contentType)
+	}
+	_, err = client.PutObject(ctx, input)
+	return err
+}
+
+// DeleteObject deletes an object from bucket (the configured bucket if empty).
+// Deleting a missing object is not an error.
+func DeleteObject(ctx context.Context, bucket, objKey string) error {
+	client, err := getClient()
+	if err != nil {
+		return err
+	}
+	if bucket == "" {
+		configMux.RLock()
+		bucket = globalConfig.Bucket
+		configMux.RUnlock()
+	}
+
+	_, err = client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: awsv2.String(bucket),
+		Key:    awsv2.String(strings.TrimLeft(objKey, "/")),
+	})
+	return err
 }
 
 // UploadBytes uploads byte data to S3
@@ -194,6 +256,59 @@ func GeneratePresignedURL(objKey string, expiration time.Duration) (string, erro
 	return presignResult.URL, nil
 }
 
+// PresignPut returns a presigned URL a browser can PUT an object to.
+// When contentType is set it is part of the signature, so the upload must
+// send the same Content-Type header.
+func PresignPut(objKey, contentType string, expires time.Duration) (string, error) {
+	client, err := getClient()
+	if err != nil {
+		return "", err
+	}
+
+	configMux.RLock()
+	cfg := globalConfig
+	configMux.RUnlock()
+
+	input := &s3.PutObjectInput{
+		Bucket: awsv2.String(cfg.Bucket),
+		Key:    awsv2.String(strings.TrimLeft(objKey, "/")),
+	}
+	opts := []func(*s3.PresignOptions){s3.WithPresignExpires(expires)}
+	if contentType != "" {
+		// The SDK drops Content-Type from presigned requests without a
+		// body; set it after serialization so it is signed
+		opts = append(opts, s3.WithPresignClientFromClientOptions(func(o *s3.Options) {
+			o.APIOptions = append(o.APIOptions, smithyhttp.SetHeaderValue("Content-Type", contentType))
+		}))
+	}
+	result, err := s3.NewPresignClient(client).PresignPutObject(context.Background(), input, opts...)
+	if err != nil {
+		return "", err
+	}
+	return result.URL, nil
+}
+
+// PresignGet returns a presigned URL to download a private object.
+func PresignGet(objKey string, expires time.Duration) (string, error) {
+	client, err := getClient()
+	if err != nil {
+		return "", err
+	}
+
+	configMux.RLock()
+	cfg := globalConfig
+	configMux.RUnlock()
+
+	result, err := s3.NewPresignClient(client).PresignGetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: awsv2.String(cfg.Bucket),
+		Key:    awsv2.String(strings.TrimLeft(objKey, "/")),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+	return result.URL, nil
+}
+
 // PresignedPostData represents presigned POST form data
 type PresignedPostData struct {
 	URL    string            `json:"url"`
@@ -1,6 +1,7 @@
 package s3
 
 import (
+	"context"
 	"strings"
 	"testing"
 	"time"
@@ -28,6 +29,19 @@ func TestUploadBytes_NoConfig(t *testing.T) {
 	}
 }
 
+func TestPutDeleteObject_NoConfig(t *testing.T) {
+	Reset()
+	viper.Reset()
+
+	err := PutObject(context.Background(), "", "test.txt", strings.NewReader("test content"), "text/plain")
+	if err == nil || !strings.Contains(err.Error(), "s3") {
+		t.Error("Expected error when config is not set")
+	}
+	if err := DeleteObject(context.Background(), "", "test.txt"); err == nil {
+		t.Error("Expected error when config is not set")
+	}
+}
+
 func TestGeneratePresignedURL_NoConfig(t *testing.T) {
 	Reset()
 	viper.Reset()
@@ -0,0 +1,240 @@
+package s3
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadOptions configures UploadHandler
+type UploadOptions struct {
+	// MaxSize is the max file size in bytes. Default: 10 MiB
+	MaxSize int64
+	// AllowedContentTypes lists accepted content types, detected from the
+	// file content; "image/*" matches any image. Empty allows any type.
+	AllowedContentTypes []string
+	// KeyTemplate builds object keys from {date} (2006/01/02), {uuid} and
+	// {ext} (".png", from the file name or content type).
+	// Default: "uploads/{date}/{uuid}{ext}"
+	KeyTemplate string
+	// PostProcess is called after the upload, e.g. to record the file. If
+	// it returns an error the object is deleted and the request fails.
+	PostProcess func(c *gin.Context, result *UploadResult) error
+}
+
+// UploadResult is the JSON response of UploadHandler
+type UploadResult struct {
+	Key         string `json:"key"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+	URL         string `json:"url"`
+}
+
+// errFileTooLarge is returned by the size-limited upload reader
+var errFileTooLarge = errors.New("file too large")
+
+// Object operations, replaced in tests
+var (
+	uploadObject = func(ctx context.Context, objKey string, r io.Reader, contentType string) (int64, error) {
+		client, err := getClient()
+		if err != nil {
+			return 0, err
+		}
+		configMux.RLock()
+		cfg := globalConfig
+		configMux.RUnlock()
+		return uploadStream(ctx, client, cfg.Bucket, objKey, r, -1, 0, contentType)
+	}
+	deleteObject = func(ctx context.Context, objKey string) error {
+		return DeleteObject(ctx, "", objKey)
+	}
+)
+
+// UploadHandler returns a gin handler that uploads the "file" field of a
+// multipart form to S3. The file is streamed in parts rather than buffered,
+// and its content type is detected from the first 512 bytes, ignoring the
+// client's header. Responds with an UploadResult.
+//
+// Example:
+//
+//	r.POST("/upload", s3.UploadHandler(s3.UploadOptions{
+//	    MaxSize:             5 << 20,
+//	    AllowedContentTypes: []string{"image/*"},
+//	    KeyTemplate:         "avatars/{date}/{uuid}{ext}",
+//	}))
+func UploadHandler(opts UploadOptions) gin.HandlerFunc {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = 10 << 20
+	}
+	if opts.KeyTemplate == "" {
+		opts.KeyTemplate = "uploads/{date}/{uuid}{ext}"
+	}
+
+	return func(c *gin.Context) {
+		mr, err := c.Request.MultipartReader()
+		if err != nil {
+			c.JSON(400, gin.H{"error": "multipart form required"})
+			return
+		}
+		var part *multipart.Part
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				c.JSON(400, gin.H{"error": "file required"})
+				return
+			}
+			if p.FormName() == "file" {
+				part = p
+				break
+			}
+		}
+
+		body := bufio.NewReaderSize(&limitedReader{r: part, n: opts.MaxSize}, 512)
+		head, err := body.Peek(512)
+		if err != nil && err != io.EOF {
+			if errors.Is(err, errFileTooLarge) {
+				c.JSON(413, gin.H{"error": "file too large"})
+			} else {
+				c.JSON(400, gin.H{"error": "failed to read file"})
+			}
+			return
+		}
+		if len(head) == 0 {
+			c.JSON(400, gin.H{"error": "file is empty"})
+			return
+		}
+		contentType := http.DetectContentType(head)
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if !contentTypeAllowed(opts.AllowedContentTypes, mediaType) {
+			c.JSON(415, gin.H{"error": "invalid file type"})
+			return
+		}
+
+		objKey, err := uploadKey(opts.KeyTemplate, part.FileName(), mediaType)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "upload failed"})
+			return
+		}
+		size, err := uploadObject(c.Request.Context(), objKey, body, contentType)
+		if errors.Is(err, errFileTooLarge) {
+			c.JSON(413, gin.H{"error": "file too large"})
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"error": "upload failed"})
+			return
+		}
+
+		result := &UploadResult{Key: objKey, Size: size, ContentType: contentType, URL: PublicURL(objKey)}
+		if opts.PostProcess != nil {
+			if err := opts.PostProcess(c, result); err != nil {
+				deleteObject(context.WithoutCancel(c.Request.Context()), objKey)
+				c.JSON(500, gin.H{"error": "post-upload processing failed"})
+				return
+			}
+		}
+		c.JSON(200, result)
+	}
+}
+
+// DeleteHandler returns a gin handler that deletes the object whose key is
+// the "key" route parameter (e.g. "/files/*key") or query parameter.
+// authorize decides whether the request may delete the key; its error
+// responds 403.
+func DeleteHandler(authorize func(c *gin.Context, objKey string) error) gin.HandlerFunc {
+	if authorize == nil {
+		panic("s3: DeleteHandler requires an authorize callback")
+	}
+	return func(c *gin.Context) {
+		objKey := c.Param("key")
+		if objKey == "" {
+			objKey = c.Query("key")
+		}
+		objKey = strings.TrimLeft(objKey, "/")
+		if objKey == "" {
+			c.JSON(400, gin.H{"error": "key required"})
+			return
+		}
+		if err := authorize(c, objKey); err != nil {
+			c.JSON(403, gin.H{"error": err.Error()})
+			return
+		}
+		if err := deleteObject(c.Request.Context(), objKey); err != nil {
+			c.JSON(500, gin.H{"error": "delete failed"})
+			return
+		}
+		c.JSON(200, gin.H{"key": objKey})
+	}
+}
+
+// limitedReader reads up to n bytes, then fails with errFileTooLarge if
+// there is more.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, errFileTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, errFileTooLarge
+	}
+	return n, err
+}
+
+func contentTypeAllowed(allowed []string, mediaType string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == mediaType || (strings.HasSuffix(a, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(a, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+var safeExt = regexp.MustCompile(`^\.[a-z0-9]{1,10}$`)
+
+// uploadKey expands a key template for a file.
+func uploadKey(template, filename, mediaType string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // Version 4
+	b[8] = b[8]&0x3f | 0x80 // Variant 10
+	uuid := fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+
+	ext := strings.ToLower(path.Ext(filename))
+	if !safeExt.MatchString(ext) {
+		ext = ""
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	return strings.NewReplacer(
+		"{date}", time.Now().UTC().Format("2006/01/02"),
+		"{uuid}", uuid,
+		"{ext}", ext,
+	).Replace(template), nil
+}
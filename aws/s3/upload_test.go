@@ -0,0 +1,160 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeObjects replaces uploadObject and deleteObject with an in-memory store.
+func fakeObjects(t *testing.T) map[string][]byte {
+	t.Helper()
+	objects := map[string][]byte{}
+	origUpload, origDelete := uploadObject, deleteObject
+	uploadObject = func(ctx context.Context, objKey string, r io.Reader, contentType string) (int64, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return 0, err
+		}
+		objects[objKey] = data
+		return int64(len(data)), nil
+	}
+	deleteObject = func(ctx context.Context, objKey string) error {
+		delete(objects, objKey)
+		return nil
+	}
+	t.Cleanup(func() { uploadObject, deleteObject = origUpload, origDelete })
+	return objects
+}
+
+func postFile(h gin.HandlerFunc, filename, header string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("note", "first field is skipped")
+	part, _ := w.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {`form-data; name="file"; filename="` + filename + `"`},
+		"Content-Type":        {header},
+	})
+	part.Write(data)
+	w.Close()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/upload", h)
+	req := httptest.NewRequest("POST", "/upload", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+var pngData = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+
+func TestUploadHandler(t *testing.T) {
+	objects := fakeObjects(t)
+	var processed *UploadResult
+	h := UploadHandler(UploadOptions{
+		AllowedContentTypes: []string{"image/*"},
+		KeyTemplate:         "avatars/{date}/{uuid}{ext}",
+		PostProcess: func(c *gin.Context, r *UploadResult) error {
+			processed = r
+			return nil
+		},
+	})
+
+	// The header claims text, the content is a PNG
+	rec := postFile(h, "Me.PNG", "text/plain", pngData)
+	if rec.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var result UploadResult
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if !regexp.MustCompile(`^avatars/\d{4}/\d{2}/\d{2}/[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}\.png$`).MatchString(result.Key) {
+		t.Errorf("unexpected key %q", result.Key)
+	}
+	if result.ContentType != "image/png" || result.Size != int64(len(pngData)) || processed == nil || processed.Key != result.Key {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if !bytes.Equal(objects[result.Key], pngData) {
+		t.Error("uploaded content mismatch")
+	}
+
+	// The header claims an image, the content is a script
+	if rec := postFile(h, "x.png", "image/png", []byte("#!/bin/sh\nrm -rf /\n")); rec.Code != 415 {
+		t.Errorf("expected 415 for disguised file, got %d", rec.Code)
+	}
+}
+
+func TestUploadHandlerLimits(t *testing.T) {
+	objects := fakeObjects(t)
+	h := UploadHandler(UploadOptions{MaxSize: 1000})
+
+	if rec := postFile(h, "big.txt", "text/plain", bytes.Repeat([]byte("a"), 1001)); rec.Code != 413 {
+		t.Errorf("expected 413, got %d", rec.Code)
+	}
+	if rec := postFile(h, "small.txt", "text/plain", bytes.Repeat([]byte("a"), 1000)); rec.Code != 200 {
+		t.Errorf("expected 200 at the limit, got %d", rec.Code)
+	}
+	if rec := postFile(h, "empty.txt", "text/plain", nil); rec.Code != 400 {
+		t.Errorf("expected 400 for empty file, got %d", rec.Code)
+	}
+
+	// A failing hook deletes the object
+	h = UploadHandler(UploadOptions{PostProcess: func(c *gin.Context, r *UploadResult) error {
+		return errors.New("db down")
+	}})
+	n := len(objects)
+	if rec := postFile(h, "a.txt", "text/plain", []byte("hello")); rec.Code != 500 || len(objects) != n {
+		t.Errorf("expected 500 and object deleted, got %d with %d objects", rec.Code, len(objects))
+	}
+}
+
+func TestDeleteHandler(t *testing.T) {
+	objects := fakeObjects(t)
+	objects["users/1/a.png"] = []byte("a")
+	objects["users/2/b.png"] = []byte("b")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.DELETE("/files/*key", DeleteHandler(func(c *gin.Context, key string) error {
+		if !strings.HasPrefix(key, "users/"+c.GetHeader("X-User")+"/") {
+			return errors.New("forbidden")
+		}
+		return nil
+	}))
+	del := func(path, user string) int {
+		req := httptest.NewRequest("DELETE", path, nil)
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := del("/files/users/2/b.png", "1"); code != 403 || objects["users/2/b.png"] == nil {
+		t.Errorf("expected 403, got %d", code)
+	}
+	if code := del("/files/users/1/a.png", "1"); code != 200 || objects["users/1/a.png"] != nil {
+		t.Errorf("expected 200 and deletion, got %d", code)
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	r := &limitedReader{r: strings.NewReader("abcdef"), n: 6}
+	if data, err := io.ReadAll(r); err != nil || string(data) != "abcdef" {
+		t.Errorf("expected full read, got %q, %v", data, err)
+	}
+	r = &limitedReader{r: strings.NewReader("abcdefg"), n: 6}
+	if _, err := io.ReadAll(r); !errors.Is(err, errFileTooLarge) {
+		t.Errorf("expected errFileTooLarge, got %v", err)
+	}
+}
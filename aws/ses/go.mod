@@ -7,6 +7,7 @@ require (
 	github.com/aws/aws-sdk-go-v2/config v1.32.3
 	github.com/aws/aws-sdk-go-v2/credentials v1.19.3
 	github.com/aws/aws-sdk-go-v2/service/sesv2 v1.54.4
+	github.com/gin-gonic/gin v1.11.0
 	github.com/spf13/viper v1.21.0
 )
 
@@ -20,6 +20,8 @@ type Config struct {
 	UseIMDS     bool   `yaml:"use_imds" json:"use_imds"`
 	Region      string `yaml:"region" json:"region"`
 	DefaultFrom string `yaml:"default_from" json:"default_from"`
+	// CheckSuppression enables EmailRequest.CheckSuppression for every SendEmail
+	CheckSuppression bool `yaml:"check_suppression" json:"check_suppression"`
 }
 
 // EmailAttachment represents an email attachment
@@ -39,13 +41,19 @@ type EmailRequest struct {
 	CC          []string          // CC addresses (optional)
 	BCC         []string          // BCC addresses (optional)
 	Attachments []EmailAttachment // Attachments (optional)
+
+	// CheckSuppression skips recipients on the account-level suppression
+	// list, reporting them in EmailResponse.Skipped. The send fails only if
+	// no recipient remains.
+	CheckSuppression bool
 }
 
 // EmailResponse contains the result of sending an email
 type EmailResponse struct {
-	MessageID string // AWS SES message ID
-	Success   bool   // Whether the email was sent successfully
-	Error     error  // Error if sending failed
+	MessageID string   // AWS SES message ID
+	Success   bool     // Whether the email was sent successfully
+	Error     error    // Error if sending failed
+	Skipped   []string // Suppressed recipients not sent to, see EmailRequest.CheckSuppression
 }
 
 var (
@@ -69,6 +77,7 @@ func loadConfigFromViper() (*Config, error) {
 	cfg.SecretKey = viper.GetString("aws.ses.secret_key")
 	cfg.UseIMDS = viper.GetBool("aws.ses.use_imds")
 	cfg.DefaultFrom = viper.GetString("aws.ses.default_from")
+	cfg.CheckSuppression = viper.GetBool("aws.ses.check_suppression")
 
 	// Fall back to global AWS config for missing credentials/region
 	if cfg.Region == "" {
@@ -159,26 +168,50 @@ func SendEmailWith(ctx context.Context, client *sesv2.Client, req *EmailRequest)
 		return &EmailResponse{Success: false, Error: err}, err
 	}
 
+	var skipped []string
+	if req.CheckSuppression {
+		filtered, s, err := filterSuppressed(ctx, client, req)
+		if err != nil {
+			return &EmailResponse{Success: false, Error: err}, err
+		}
+		if len(filtered.To)+len(filtered.CC)+len(filtered.BCC) == 0 {
+			err := fmt.Errorf("all recipients are on the suppression list")
+			return &EmailResponse{Success: false, Error: err, Skipped: s}, err
+		}
+		req, skipped = filtered, s
+	}
+
 	input := buildSESv2Input(req)
 	result, err := client.SendEmail(ctx, input)
 	if err != nil {
-		return &EmailResponse{Success: false, Error: err}, err
+		return &EmailResponse{Success: false, Error: err, Skipped: skipped}, err
 	}
 
 	return &EmailResponse{
 		MessageID: *result.MessageId,
 		Success:   true,
 		Error:     nil,
+		Skipped:   skipped,
 	}, nil
 }
 
 // SendEmail sends an email using the global SES client (lazy-initialized from viper).
 // Existing callers are unaffected by SendEmailWith's introduction.
+// aws.ses.check_suppression enables req.CheckSuppression for every call.
 func SendEmail(req *EmailRequest) (*EmailResponse, error) {
 	client, err := getClient()
 	if err != nil {
 		return &EmailResponse{Success: false, Error: err}, err
 	}
+
+	configMux.RLock()
+	checkSuppression := globalConfig != nil && globalConfig.CheckSuppression
+	configMux.RUnlock()
+	if checkSuppression && !req.CheckSuppression {
+		r := *req
+		r.CheckSuppression = true
+		req = &r
+	}
 	return SendEmailWith(context.Background(), client, req)
 }
 
@@ -131,3 +131,32 @@ func ExampleSendEmail_ec2IAMRole() {
 
 	fmt.Printf("Email sent from EC2! MessageID: %s\n", resp.MessageID)
 }
+
+// ExampleSendBulkTemplatedEmail demonstrates sending a template to many
+// recipients with per-recipient data
+func ExampleSendBulkTemplatedEmail() {
+	viper.Set("aws.ses.region", "us-east-1")
+	viper.Set("aws.ses.use_imds", true)
+
+	// Create the template once, e.g. at deploy time
+	_ = ses.CreateTemplate("weekly-digest",
+		"Your week, {{name}}",
+		"<p>Hi {{name}}, you have {{count}} new messages.</p>",
+		"Hi {{name}}, you have {{count}} new messages.")
+
+	results, err := ses.SendBulkTemplatedEmail(&ses.BulkEmailRequest{
+		From:         "noreply@yourdomain.com",
+		TemplateName: "weekly-digest",
+		TemplateData: map[string]any{"count": 0},
+		Destinations: []ses.BulkDestination{
+			{To: []string{"ann@example.com"}, TemplateData: map[string]any{"name": "Ann", "count": 3}},
+			{To: []string{"bob@example.com"}, TemplateData: map[string]any{"name": "Bob"}},
+		},
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
+	for _, r := range results {
+		fmt.Println(r.To, r.Status, r.MessageID)
+	}
+}
@@ -0,0 +1,361 @@
+package ses
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// SNS message types
+const (
+	SNSSubscriptionConfirmation = "SubscriptionConfirmation"
+	SNSNotification             = "Notification"
+	SNSUnsubscribeConfirmation  = "UnsubscribeConfirmation"
+)
+
+// SES event types
+const (
+	EventBounce    = "Bounce"
+	EventComplaint = "Complaint"
+	EventDelivery  = "Delivery"
+)
+
+// ErrInvalidSignature is returned for SNS messages whose signature or
+// signing certificate cannot be verified.
+var ErrInvalidSignature = errors.New("ses: invalid SNS message signature")
+
+// ErrUnknownTopic is returned for SNS messages from a topic not listed in
+// aws.ses.sns_topic_arns.
+var ErrUnknownTopic = errors.New("ses: SNS topic not allowed")
+
+// SNSMessage is the envelope of a message delivered by SNS over HTTP(S)
+type SNSMessage struct {
+	Type             string `json:"Type"`
+	MessageID        string `json:"MessageId"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+	SubscribeURL     string `json:"SubscribeURL"`
+	UnsubscribeURL   string `json:"UnsubscribeURL"`
+	Token            string `json:"Token"`
+}
+
+// SESEvent is a verified SNS message. For Notification messages EventType
+// and the matching Bounce, Complaint or Delivery are set.
+type SESEvent struct {
+	SNS       *SNSMessage // The SNS envelope
+	EventType string      // EventBounce, EventComplaint, EventDelivery or another SES event type
+	Mail      *MailInfo
+	Bounce    *Bounce
+	Complaint *Complaint
+	Delivery  *Delivery
+}
+
+// MailInfo describes the original email of an SES event
+type MailInfo struct {
+	MessageID   string    `json:"messageId"`
+	Source      string    `json:"source"`
+	Destination []string  `json:"destination"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// Bounce is an SES bounce notification
+type Bounce struct {
+	BounceType        string             `json:"bounceType"` // "Permanent", "Transient" or "Undetermined"
+	BounceSubType     string             `json:"bounceSubType"`
+	BouncedRecipients []BouncedRecipient `json:"bouncedRecipients"`
+	Timestamp         time.Time          `json:"timestamp"`
+	FeedbackID        string             `json:"feedbackId"`
+}
+
+// BouncedRecipient is a recipient of a bounced email
+type BouncedRecipient struct {
+	EmailAddress   string `json:"emailAddress"`
+	Action         string `json:"action"`
+	Status         string `json:"status"`
+	DiagnosticCode string `json:"diagnosticCode"`
+}
+
+// Complaint is an SES complaint (spam report) notification
+type Complaint struct {
+	ComplainedRecipients []struct {
+		EmailAddress string `json:"emailAddress"`
+	} `json:"complainedRecipients"`
+	ComplaintFeedbackType string    `json:"complaintFeedbackType"`
+	Timestamp             time.Time `json:"timestamp"`
+	FeedbackID            string    `json:"feedbackId"`
+}
+
+// Delivery is an SES delivery notification
+type Delivery struct {
+	Recipients           []string  `json:"recipients"`
+	Timestamp            time.Time `json:"timestamp"`
+	ProcessingTimeMillis int64     `json:"processingTimeMillis"`
+	SMTPResponse         string    `json:"smtpResponse"`
+}
+
+// SuppressibleRecipients returns the addresses that should no longer be
+// sent to: recipients of permanent bounces and complaints.
+func (e *SESEvent) SuppressibleRecipients() []string {
+	var emails []string
+	if e.Bounce != nil && e.Bounce.BounceType == "Permanent" {
+		for _, r := range e.Bounce.BouncedRecipients {
+			emails = append(emails, r.EmailAddress)
+		}
+	}
+	if e.Complaint != nil {
+		for _, r := range e.Complaint.ComplainedRecipients {
+			emails = append(emails, r.EmailAddress)
+		}
+	}
+	return emails
+}
+
+var (
+	// snsHTTPClient fetches signing certificates and subscribe URLs
+	snsHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+	// signingCerts caches parsed signing certificates by URL
+	signingCerts sync.Map
+
+	// snsHost matches the SNS endpoints serving signing certificates and
+	// subscription confirmations
+	snsHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+)
+
+// ParseSNSEvent parses and verifies an SNS message carrying SES events.
+// A SubscriptionConfirmation is confirmed by fetching its SubscribeURL; a
+// Notification is decoded from either SES notifications or configuration
+// set event publishing. Messages failing verification return an error
+// wrapping ErrInvalidSignature; messages from a topic not listed in
+// aws.ses.sns_topic_arns return ErrUnknownTopic.
+func ParseSNSEvent(body []byte) (*SESEvent, error) {
+	var msg SNSMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("ses: invalid SNS message: %w", err)
+	}
+	if !slices.Contains(viper.GetStringSlice("aws.ses.sns_topic_arns"), msg.TopicArn) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTopic, msg.TopicArn)
+	}
+	if err := verifySNSMessage(&msg); err != nil {
+		return nil, err
+	}
+
+	event := &SESEvent{SNS: &msg}
+	switch msg.Type {
+	case SNSSubscriptionConfirmation:
+		if err := confirmSubscription(msg.SubscribeURL); err != nil {
+			return nil, err
+		}
+	case SNSNotification:
+		var payload struct {
+			NotificationType string     `json:"notificationType"` // SES notifications
+			EventType        string     `json:"eventType"`        // Configuration set events
+			Mail             *MailInfo  `json:"mail"`
+			Bounce           *Bounce    `json:"bounce"`
+			Complaint        *Complaint `json:"complaint"`
+			Delivery         *Delivery  `json:"delivery"`
+		}
+		if err := json.Unmarshal([]byte(msg.Message), &payload); err != nil {
+			return nil, fmt.Errorf("ses: invalid SES notification: %w", err)
+		}
+		event.EventType = payload.NotificationType
+		if event.EventType == "" {
+			event.EventType = payload.EventType
+		}
+		event.Mail = payload.Mail
+		event.Bounce = payload.Bounce
+		event.Complaint = payload.Complaint
+		event.Delivery = payload.Delivery
+	}
+	return event, nil
+}
+
+// SNSWebhook returns a gin handler for an SNS HTTP(S) subscription. It
+// confirms subscriptions and calls handler with each verified event; a
+// handler error responds 500 so SNS retries the delivery. Messages from
+// topics not listed in aws.ses.sns_topic_arns are rejected with 403.
+//
+// Example:
+//
+//	r.POST("/webhooks/ses", ses.SNSWebhook(func(ctx context.Context, e *ses.SESEvent) error {
+//	    for _, email := range e.SuppressibleRecipients() {
+//	        if err := users.DisableEmail(ctx, email); err != nil {
+//	            return err
+//	        }
+//	    }
+//	    return nil
+//	}))
+func SNSWebhook(handler func(ctx context.Context, event *SESEvent) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, 256<<10))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+			return
+		}
+		event, err := ParseSNSEvent(body)
+		if errors.Is(err, ErrInvalidSignature) || errors.Is(err, ErrUnknownTopic) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid signature"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if event.SNS.Type == SNSNotification {
+			if err := handler(c.Request.Context(), event); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		c.Status(http.StatusOK)
+	}
+}
+
+// verifySNSMessage checks the message signature against its signing
+// certificate, which must be served over HTTPS from an SNS endpoint.
+func verifySNSMessage(msg *SNSMessage) error {
+	var hash crypto.Hash
+	switch msg.SignatureVersion {
+	case "1":
+		hash = crypto.SHA1
+	case "2":
+		hash = crypto.SHA256
+	default:
+		return fmt.Errorf("%w: unsupported signature version %q", ErrInvalidSignature, msg.SignatureVersion)
+	}
+	signature, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	cert, err := signingCert(msg.SigningCertURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: signing certificate is not RSA", ErrInvalidSignature)
+	}
+
+	var digest []byte
+	if hash == crypto.SHA1 {
+		sum := sha1.Sum([]byte(snsStringToSign(msg)))
+		digest = sum[:]
+	} else {
+		sum := sha256.Sum256([]byte(snsStringToSign(msg)))
+		digest = sum[:]
+	}
+	if err := rsa.VerifyPKCS1v15(key, hash, digest, signature); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return nil
+}
+
+// snsStringToSign builds the canonical string SNS signs: selected fields as
+// "Name\nvalue\n" pairs in byte order.
+func snsStringToSign(msg *SNSMessage) string {
+	fields := [][2]string{{"Message", msg.Message}, {"MessageId", msg.MessageID}}
+	if msg.Type == SNSNotification {
+		if msg.Subject != "" {
+			fields = append(fields, [2]string{"Subject", msg.Subject})
+		}
+	} else {
+		fields = append(fields, [2]string{"SubscribeURL", msg.SubscribeURL})
+	}
+	fields = append(fields, [2]string{"Timestamp", msg.Timestamp})
+	if msg.Type != SNSNotification {
+		fields = append(fields, [2]string{"Token", msg.Token})
+	}
+	fields = append(fields, [2]string{"TopicArn", msg.TopicArn}, [2]string{"Type", msg.Type})
+
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteString(f[0] + "\n" + f[1] + "\n")
+	}
+	return b.String()
+}
+
+// signingCert fetches and caches the certificate at certURL.
+func signingCert(certURL string) (*x509.Certificate, error) {
+	if cert, ok := signingCerts.Load(certURL); ok {
+		return cert.(*x509.Certificate), nil
+	}
+	if err := checkSNSURL(certURL); err != nil {
+		return nil, err
+	}
+	resp, err := snsHTTPClient.Get(certURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetch signing certificate: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch signing certificate: status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("fetch signing certificate: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing certificate is not PEM")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse signing certificate: %w", err)
+	}
+	signingCerts.Store(certURL, cert)
+	return cert, nil
+}
+
+// confirmSubscription fetches the SubscribeURL of a subscription confirmation.
+func confirmSubscription(subscribeURL string) error {
+	if err := checkSNSURL(subscribeURL); err != nil {
+		return err
+	}
+	resp, err := snsHTTPClient.Get(subscribeURL)
+	if err != nil {
+		return fmt.Errorf("ses: confirm SNS subscription: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ses: confirm SNS subscription: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// checkSNSURL rejects URLs that are not HTTPS URLs on an SNS endpoint
+// (sns.<region>.amazonaws.com), so a spoofed message cannot make us fetch
+// arbitrary URLs, not even from other AWS services such as S3.
+func checkSNSURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if u.Scheme != "https" || u.Port() != "" || !snsHost.MatchString(u.Hostname()) {
+		return fmt.Errorf("URL %q is not an HTTPS SNS URL", rawURL)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package ses
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	testCertURL  = "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-test.pem"
+	testTopicArn = "arn:aws:sns:us-east-1:123456789012:ses-events"
+)
+
+// snsFixture signs SNS messages and serves AWS URLs from memory.
+type snsFixture struct {
+	key       *rsa.PrivateKey
+	mu        sync.Mutex
+	requested []string
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func setupSNSTest(t *testing.T) *snsFixture {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	f := &snsFixture{key: key}
+	orig := snsHTTPClient
+	snsHTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		f.mu.Lock()
+		f.requested = append(f.requested, r.URL.String())
+		f.mu.Unlock()
+		body := "<ConfirmSubscriptionResponse/>"
+		if r.URL.String() == testCertURL {
+			body = string(certPEM)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	})}
+	signingCerts.Delete(testCertURL)
+	viper.Set("aws.ses.sns_topic_arns", []string{testTopicArn})
+	t.Cleanup(func() {
+		snsHTTPClient = orig
+		signingCerts.Delete(testCertURL)
+		viper.Set("aws.ses.sns_topic_arns", nil)
+	})
+	return f
+}
+
+// sign fills the signature fields of msg and returns it as JSON.
+func (f *snsFixture) sign(t *testing.T, msg SNSMessage, version string) []byte {
+	t.Helper()
+	msg.SignatureVersion = version
+	msg.SigningCertURL = testCertURL
+	var sig []byte
+	var err error
+	if version == "1" {
+		sum := sha1.Sum([]byte(snsStringToSign(&msg)))
+		sig, err = rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA1, sum[:])
+	} else {
+		sum := sha256.Sum256([]byte(snsStringToSign(&msg)))
+		sig, err = rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA256, sum[:])
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Signature = base64.StdEncoding.EncodeToString(sig)
+	data, _ := json.Marshal(msg)
+	return data
+}
+
+func notification(t *testing.T, file string) SNSMessage {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return SNSMessage{
+		Type:      SNSNotification,
+		MessageID: "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+		TopicArn:  testTopicArn,
+		Message:   string(data),
+		Timestamp: "2016-01-27T14:59:38.237Z",
+	}
+}
+
+func TestParseSNSEventNotifications(t *testing.T) {
+	f := setupSNSTest(t)
+
+	event, err := ParseSNSEvent(f.sign(t, notification(t, "bounce.json"), "1"))
+	if err != nil {
+		t.Fatalf("ParseSNSEvent failed: %v", err)
+	}
+	if event.EventType != EventBounce || event.Bounce.BounceType != "Permanent" ||
+		event.Bounce.BouncedRecipients[0].DiagnosticCode != "smtp; 550 5.1.1 user unknown" ||
+		len(event.Mail.Destination) != 3 {
+		t.Errorf("unexpected bounce event: %+v", event)
+	}
+	if got := event.SuppressibleRecipients(); len(got) != 1 || got[0] != "jane@example.com" {
+		t.Errorf("unexpected suppressible recipients: %v", got)
+	}
+
+	msg := notification(t, "complaint.json")
+	msg.Subject = "Amazon SES Email Event Notification"
+	event, err = ParseSNSEvent(f.sign(t, msg, "2"))
+	if err != nil {
+		t.Fatalf("ParseSNSEvent failed: %v", err)
+	}
+	if event.EventType != EventComplaint || event.Complaint.ComplaintFeedbackType != "abuse" {
+		t.Errorf("unexpected complaint event: %+v", event)
+	}
+	if got := event.SuppressibleRecipients(); len(got) != 1 || got[0] != "richard@example.com" {
+		t.Errorf("unexpected suppressible recipients: %v", got)
+	}
+
+	event, err = ParseSNSEvent(f.sign(t, notification(t, "delivery.json"), "2"))
+	if err != nil {
+		t.Fatalf("ParseSNSEvent failed: %v", err)
+	}
+	if event.EventType != EventDelivery || event.Delivery.ProcessingTimeMillis != 546 || len(event.SuppressibleRecipients()) != 0 {
+		t.Errorf("unexpected delivery event: %+v", event)
+	}
+}
+
+func TestParseSNSEventRejectsSpoofed(t *testing.T) {
+	f := setupSNSTest(t)
+
+	// Tampered message
+	var msg SNSMessage
+	json.Unmarshal(f.sign(t, notification(t, "bounce.json"), "2"), &msg)
+	msg.Message = strings.Replace(msg.Message, "jane@", "victim@", 1)
+	data, _ := json.Marshal(msg)
+	if _, err := ParseSNSEvent(data); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for tampered message, got %v", err)
+	}
+
+	// Certificate outside the SNS endpoints
+	for _, certURL := range []string{
+		"https://attacker.example.com/cert.pem",
+		"https://amazonaws.com.attacker.example.com/cert.pem",
+		"http://sns.us-east-1.amazonaws.com/cert.pem",
+		"https://s3.amazonaws.com/attacker-bucket/cert.pem",
+		"https://sns.attacker-bucket.s3.amazonaws.com/cert.pem",
+		"https://sns.us-east-1.amazonaws.com:8443/cert.pem",
+	} {
+		json.Unmarshal(f.sign(t, notification(t, "bounce.json"), "2"), &msg)
+		msg.SigningCertURL = certURL
+		data, _ := json.Marshal(msg)
+		if _, err := ParseSNSEvent(data); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected ErrInvalidSignature, got %v", certURL, err)
+		}
+	}
+	for _, u := range f.requested {
+		if u != testCertURL {
+			t.Errorf("should not fetch %s", u)
+		}
+	}
+}
+
+func TestParseSNSEventRejectsForeignTopic(t *testing.T) {
+	f := setupSNSTest(t)
+
+	msg := notification(t, "bounce.json")
+	msg.TopicArn = "arn:aws:sns:us-east-1:999999999999:attacker"
+	if _, err := ParseSNSEvent(f.sign(t, msg, "2")); !errors.Is(err, ErrUnknownTopic) {
+		t.Errorf("expected ErrUnknownTopic, got %v", err)
+	}
+
+	// A foreign subscription is not confirmed
+	_, err := ParseSNSEvent(f.sign(t, SNSMessage{
+		Type:         SNSSubscriptionConfirmation,
+		MessageID:    "165545c9-2a5c-472c-8df2-7ff2be2b3b1b",
+		Token:        "abc",
+		TopicArn:     msg.TopicArn,
+		SubscribeURL: "https://sns.us-east-1.amazonaws.com/?Action=ConfirmSubscription&Token=abc",
+		Timestamp:    "2016-01-27T14:59:38.237Z",
+	}, "1"))
+	if !errors.Is(err, ErrUnknownTopic) || len(f.requested) != 0 {
+		t.Errorf("expected ErrUnknownTopic without requests, got %v, requested %v", err, f.requested)
+	}
+
+	viper.Set("aws.ses.sns_topic_arns", nil)
+	if _, err := ParseSNSEvent(f.sign(t, notification(t, "bounce.json"), "2")); !errors.Is(err, ErrUnknownTopic) {
+		t.Errorf("expected every topic rejected without an allowlist, got %v", err)
+	}
+}
+
+func TestSNSWebhook(t *testing.T) {
+	f := setupSNSTest(t)
+	gin.SetMode(gin.TestMode)
+
+	var events []*SESEvent
+	fail := false
+	r := gin.New()
+	r.POST("/ses", SNSWebhook(func(ctx context.Context, e *SESEvent) error {
+		if fail {
+			return errors.New("db down")
+		}
+		events = append(events, e)
+		return nil
+	}))
+	post := func(body []byte) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", "/ses", strings.NewReader(string(body))))
+		return w.Code
+	}
+
+	// Subscription confirmation fetches SubscribeURL without calling the handler
+	subscribeURL := "https://sns.us-east-1.amazonaws.com/?Action=ConfirmSubscription&Token=abc"
+	code := post(f.sign(t, SNSMessage{
+		Type:         SNSSubscriptionConfirmation,
+		MessageID:    "165545c9-2a5c-472c-8df2-7ff2be2b3b1b",
+		Token:        "abc",
+		TopicArn:     testTopicArn,
+		Message:      "You have chosen to subscribe to the topic",
+		SubscribeURL: subscribeURL,
+		Timestamp:    "2016-01-27T14:59:38.237Z",
+	}, "1"))
+	if code != http.StatusOK || len(events) != 0 || f.requested[len(f.requested)-1] != subscribeURL {
+		t.Errorf("subscription not confirmed: code %d, requested %v", code, f.requested)
+	}
+
+	if code := post(f.sign(t, notification(t, "bounce.json"), "2")); code != http.StatusOK || len(events) != 1 {
+		t.Errorf("expected event handled, code %d, events %d", code, len(events))
+	}
+	fail = true
+	if code := post(f.sign(t, notification(t, "bounce.json"), "2")); code != http.StatusInternalServerError {
+		t.Errorf("handler error should respond 500, got %d", code)
+	}
+	if code := post([]byte(`{"Type":"Notification","TopicArn":"` + testTopicArn + `","SignatureVersion":"2","Signature":"AAAA","SigningCertURL":"` + testCertURL + `"}`)); code != http.StatusForbidden {
+		t.Errorf("invalid signature should respond 403, got %d", code)
+	}
+	foreign := notification(t, "bounce.json")
+	foreign.TopicArn = "arn:aws:sns:us-east-1:999999999999:attacker"
+	if code := post(f.sign(t, foreign, "2")); code != http.StatusForbidden {
+		t.Errorf("foreign topic should respond 403, got %d", code)
+	}
+}
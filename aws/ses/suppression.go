@@ -0,0 +1,124 @@
+package ses
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+)
+
+// AddToSuppressionList adds an address to the account-level suppression
+// list. reason is "BOUNCE" or "COMPLAINT" (case-insensitive).
+func AddToSuppressionList(email, reason string) error {
+	client, err := getClient()
+	if err != nil {
+		return err
+	}
+	return AddToSuppressionListWith(context.Background(), client, email, reason)
+}
+
+// AddToSuppressionListWith is AddToSuppressionList using the provided client.
+func AddToSuppressionListWith(ctx context.Context, client *sesv2.Client, email, reason string) error {
+	if email == "" {
+		return fmt.Errorf("email address is required")
+	}
+	r := types.SuppressionListReason(strings.ToUpper(reason))
+	if r != types.SuppressionListReasonBounce && r != types.SuppressionListReasonComplaint {
+		return fmt.Errorf("suppression reason must be BOUNCE or COMPLAINT, got %q", reason)
+	}
+	_, err := client.PutSuppressedDestination(ctx, &sesv2.PutSuppressedDestinationInput{
+		EmailAddress: &email,
+		Reason:       r,
+	})
+	return err
+}
+
+// RemoveFromSuppressionList removes an address from the account-level
+// suppression list.
+func RemoveFromSuppressionList(email string) error {
+	client, err := getClient()
+	if err != nil {
+		return err
+	}
+	return RemoveFromSuppressionListWith(context.Background(), client, email)
+}
+
+// RemoveFromSuppressionListWith is RemoveFromSuppressionList using the provided client.
+func RemoveFromSuppressionListWith(ctx context.Context, client *sesv2.Client, email string) error {
+	_, err := client.DeleteSuppressedDestination(ctx, &sesv2.DeleteSuppressedDestinationInput{
+		EmailAddress: &email,
+	})
+	return err
+}
+
+// IsSuppressed reports whether an address is on the account-level
+// suppression list, and the reason ("BOUNCE" or "COMPLAINT") if it is.
+func IsSuppressed(email string) (bool, string, error) {
+	client, err := getClient()
+	if err != nil {
+		return false, "", err
+	}
+	return IsSuppressedWith(context.Background(), client, email)
+}
+
+// IsSuppressedWith is IsSuppressed using the provided client.
+func IsSuppressedWith(ctx context.Context, client *sesv2.Client, email string) (bool, string, error) {
+	out, err := client.GetSuppressedDestination(ctx, &sesv2.GetSuppressedDestinationInput{
+		EmailAddress: &email,
+	})
+	var notFound *types.NotFoundException
+	if errors.As(err, &notFound) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	return true, string(out.SuppressedDestination.Reason), nil
+}
+
+// filterSuppressed returns a copy of req without the suppressed recipients,
+// and the recipients removed. Addresses may include a display name.
+func filterSuppressed(ctx context.Context, client *sesv2.Client, req *EmailRequest) (*EmailRequest, []string, error) {
+	suppressed := make(map[string]bool)
+	var skipped []string
+	keep := func(addrs []string) ([]string, error) {
+		var kept []string
+		for _, addr := range addrs {
+			email := addr
+			if a, err := mail.ParseAddress(addr); err == nil {
+				email = a.Address
+			}
+			s, checked := suppressed[email]
+			if !checked {
+				ok, _, err := IsSuppressedWith(ctx, client, email)
+				if err != nil {
+					return nil, fmt.Errorf("check suppression of %s: %w", email, err)
+				}
+				s, suppressed[email] = ok, ok
+			}
+			if s {
+				skipped = append(skipped, addr)
+				continue
+			}
+			kept = append(kept, addr)
+		}
+		return kept, nil
+	}
+
+	filtered := *req
+	var err error
+	if filtered.To, err = keep(req.To); err != nil {
+		return nil, nil, err
+	}
+	if filtered.CC, err = keep(req.CC); err != nil {
+		return nil, nil, err
+	}
+	if filtered.BCC, err = keep(req.BCC); err != nil {
+		return nil, nil, err
+	}
+	return &filtered, skipped, nil
+}
@@ -0,0 +1,119 @@
+package ses
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+)
+
+// newSuppressionClient returns a client whose suppression list holds
+// suppressed, recording the destinations of sent emails.
+func newSuppressionClient(t *testing.T, suppressed map[string]string, sent *[]map[string]any) *sesv2.Client {
+	t.Helper()
+	return newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		const prefix = "/v2/email/suppression/addresses/"
+		switch {
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, prefix):
+			email, _ := url.PathUnescape(strings.TrimPrefix(r.URL.Path, prefix))
+			reason, ok := suppressed[email]
+			if !ok {
+				w.Header().Set("X-Amzn-ErrorType", "NotFoundException")
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"message":"not found"}`))
+				return
+			}
+			fmt.Fprintf(w, `{"SuppressedDestination":{"EmailAddress":%q,"Reason":%q,"LastUpdateTime":1700000000}}`, email, reason)
+		case r.Method == http.MethodPut:
+			var in struct{ EmailAddress, Reason string }
+			json.NewDecoder(r.Body).Decode(&in)
+			suppressed[in.EmailAddress] = in.Reason
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodDelete:
+			email, _ := url.PathUnescape(strings.TrimPrefix(r.URL.Path, prefix))
+			delete(suppressed, email)
+			w.Write([]byte(`{}`))
+		default:
+			var in struct{ Destination map[string]any }
+			json.NewDecoder(r.Body).Decode(&in)
+			*sent = append(*sent, in.Destination)
+			w.Write([]byte(`{"MessageId":"msg-1"}`))
+		}
+	})
+}
+
+func TestSuppressionList(t *testing.T) {
+	suppressed := map[string]string{}
+	client := newSuppressionClient(t, suppressed, nil)
+	ctx := context.Background()
+
+	if err := AddToSuppressionListWith(ctx, client, "bounce@example.com", "bounce"); err != nil {
+		t.Fatalf("AddToSuppressionListWith failed: %v", err)
+	}
+	if err := AddToSuppressionListWith(ctx, client, "a@example.com", "spam"); err == nil {
+		t.Error("expected error for invalid reason")
+	}
+	ok, reason, err := IsSuppressedWith(ctx, client, "bounce@example.com")
+	if err != nil || !ok || reason != "BOUNCE" {
+		t.Errorf("expected suppressed for BOUNCE, got %v %q %v", ok, reason, err)
+	}
+
+	if err := RemoveFromSuppressionListWith(ctx, client, "bounce@example.com"); err != nil {
+		t.Fatalf("RemoveFromSuppressionListWith failed: %v", err)
+	}
+	if ok, _, err := IsSuppressedWith(ctx, client, "bounce@example.com"); err != nil || ok {
+		t.Errorf("expected not suppressed after removal, got %v %v", ok, err)
+	}
+}
+
+func TestSendEmailWithCheckSuppression(t *testing.T) {
+	var sent []map[string]any
+	client := newSuppressionClient(t, map[string]string{
+		"bounce@example.com":    "BOUNCE",
+		"complaint@example.com": "COMPLAINT",
+	}, &sent)
+	req := &EmailRequest{
+		From:             "sender@example.com",
+		To:               []string{"user@example.com", "Bob <bounce@example.com>"},
+		CC:               []string{"complaint@example.com"},
+		BCC:              []string{"bounce@example.com"},
+		Subject:          "Test",
+		BodyText:         "Hello",
+		CheckSuppression: true,
+	}
+
+	resp, err := SendEmailWith(context.Background(), client, req)
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected result: %+v, %v", resp, err)
+	}
+	if strings.Join(resp.Skipped, ",") != "Bob <bounce@example.com>,complaint@example.com,bounce@example.com" {
+		t.Errorf("unexpected skipped: %v", resp.Skipped)
+	}
+	if len(sent) != 1 || fmt.Sprint(sent[0]) != "map[ToAddresses:[user@example.com]]" {
+		t.Errorf("unexpected destination: %v", sent)
+	}
+	if len(req.To) != 2 {
+		t.Error("request must not be modified")
+	}
+
+	// All suppressed
+	req.To = []string{"bounce@example.com"}
+	req.CC = nil
+	resp, err = SendEmailWith(context.Background(), client, req)
+	if err == nil || resp.Success || len(resp.Skipped) != 2 || len(sent) != 1 {
+		t.Errorf("expected failure without sending, got %+v, %v", resp, err)
+	}
+
+	// Not checked
+	resp, err = SendEmailWith(context.Background(), client, &EmailRequest{
+		From: "sender@example.com", To: req.To, Subject: "Test", BodyText: "Hello",
+	})
+	if err != nil || len(resp.Skipped) != 0 || len(sent) != 2 {
+		t.Errorf("expected send without check, got %+v, %v", resp, err)
+	}
+}
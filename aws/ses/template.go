@@ -0,0 +1,255 @@
+package ses
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+)
+
+const (
+	// maxTemplateDataSize is the SES limit of template data JSON in bytes
+	maxTemplateDataSize = 262144
+	// maxBulkDestinations is the SES limit of destinations per SendBulkEmail call
+	maxBulkDestinations = 50
+)
+
+// TemplatedEmailRequest represents a request to send an email rendered from an SES template
+type TemplatedEmailRequest struct {
+	From         string         // Sender email (must be verified in SES)
+	To           []string       // Recipient email addresses
+	ReplyTo      []string       // Reply-to addresses (optional)
+	CC           []string       // CC addresses (optional)
+	BCC          []string       // BCC addresses (optional)
+	TemplateName string         // Name of a template created with CreateTemplate
+	TemplateData map[string]any // Values for the template's {{placeholders}}
+}
+
+// BulkEmailRequest represents a templated email sent to many destinations,
+// each with its own replacement data
+type BulkEmailRequest struct {
+	From         string            // Sender email (must be verified in SES)
+	ReplyTo      []string          // Reply-to addresses (optional)
+	TemplateName string            // Name of a template created with CreateTemplate
+	TemplateData map[string]any    // Default values, used for keys a destination does not set
+	Destinations []BulkDestination // Recipients, sent in batches of 50
+}
+
+// BulkDestination is one recipient group of a bulk email
+type BulkDestination struct {
+	To           []string       // Recipient email addresses
+	CC           []string       // CC addresses (optional)
+	BCC          []string       // BCC addresses (optional)
+	TemplateData map[string]any // Replacement values for this destination (optional)
+}
+
+// BulkEntryResult is the outcome of one destination of a bulk email, in
+// the order of BulkEmailRequest.Destinations
+type BulkEntryResult struct {
+	To        []string // Recipients of the destination
+	Status    string   // SES status, e.g. "SUCCESS", "MESSAGE_REJECTED", "FAILED"
+	MessageID string   // AWS SES message ID if sent
+	Error     string   // Failure description if not sent
+}
+
+// CreateTemplate creates an SES email template. Subject and parts may use
+// {{placeholders}} filled from the template data; htmlPart or textPart may
+// be empty, but not both.
+func CreateTemplate(name, subject, htmlPart, textPart string) error {
+	client, err := getClient()
+	if err != nil {
+		return err
+	}
+	return CreateTemplateWith(context.Background(), client, name, subject, htmlPart, textPart)
+}
+
+// CreateTemplateWith is CreateTemplate using the provided client.
+func CreateTemplateWith(ctx context.Context, client *sesv2.Client, name, subject, htmlPart, textPart string) error {
+	if name == "" || subject == "" {
+		return fmt.Errorf("template name and subject are required")
+	}
+	if htmlPart == "" && textPart == "" {
+		return fmt.Errorf("template body (htmlPart or textPart) is required")
+	}
+
+	content := &types.EmailTemplateContent{Subject: &subject}
+	if htmlPart != "" {
+		content.Html = &htmlPart
+	}
+	if textPart != "" {
+		content.Text = &textPart
+	}
+	_, err := client.CreateEmailTemplate(ctx, &sesv2.CreateEmailTemplateInput{
+		TemplateName:    &name,
+		TemplateContent: content,
+	})
+	return err
+}
+
+// SendTemplatedEmail sends an email rendered from an SES template using the
+// global SES client (lazy-initialized from viper).
+func SendTemplatedEmail(req *TemplatedEmailRequest) (*EmailResponse, error) {
+	client, err := getClient()
+	if err != nil {
+		return &EmailResponse{Success: false, Error: err}, err
+	}
+	return SendTemplatedEmailWith(context.Background(), client, req)
+}
+
+// SendTemplatedEmailWith is SendTemplatedEmail using the provided client.
+func SendTemplatedEmailWith(ctx context.Context, client *sesv2.Client, req *TemplatedEmailRequest) (*EmailResponse, error) {
+	if req.From == "" {
+		err := fmt.Errorf("sender email (From) is required")
+		return &EmailResponse{Success: false, Error: err}, err
+	}
+	if len(req.To) == 0 {
+		err := fmt.Errorf("at least one recipient (To) is required")
+		return &EmailResponse{Success: false, Error: err}, err
+	}
+	if req.TemplateName == "" {
+		err := fmt.Errorf("template name is required")
+		return &EmailResponse{Success: false, Error: err}, err
+	}
+	data, err := marshalTemplateData(req.TemplateData)
+	if err != nil {
+		return &EmailResponse{Success: false, Error: err}, err
+	}
+
+	input := &sesv2.SendEmailInput{
+		FromEmailAddress: &req.From,
+		Destination: &types.Destination{
+			ToAddresses:  req.To,
+			CcAddresses:  req.CC,
+			BccAddresses: req.BCC,
+		},
+		ReplyToAddresses: req.ReplyTo,
+		Content: &types.EmailContent{
+			Template: &types.Template{
+				TemplateName: &req.TemplateName,
+				TemplateData: &data,
+			},
+		},
+	}
+	result, err := client.SendEmail(ctx, input)
+	if err != nil {
+		return &EmailResponse{Success: false, Error: err}, err
+	}
+
+	return &EmailResponse{
+		MessageID: *result.MessageId,
+		Success:   true,
+		Error:     nil,
+	}, nil
+}
+
+// SendBulkTemplatedEmail sends a templated email to every destination using
+// the global SES client. See SendBulkTemplatedEmailWith.
+func SendBulkTemplatedEmail(req *BulkEmailRequest) ([]BulkEntryResult, error) {
+	client, err := getClient()
+	if err != nil {
+		return nil, err
+	}
+	return SendBulkTemplatedEmailWith(context.Background(), client, req)
+}
+
+// SendBulkTemplatedEmailWith sends a templated email to every destination,
+// 50 per SES call, and returns one result per destination. SES reports
+// per-destination failures in the results; a call that fails as a whole
+// marks its destinations "FAILED", the remaining calls are still made, and
+// the returned error joins such call errors.
+func SendBulkTemplatedEmailWith(ctx context.Context, client *sesv2.Client, req *BulkEmailRequest) ([]BulkEntryResult, error) {
+	if req.From == "" {
+		return nil, fmt.Errorf("sender email (From) is required")
+	}
+	if req.TemplateName == "" {
+		return nil, fmt.Errorf("template name is required")
+	}
+	defaultData, err := marshalTemplateData(req.TemplateData)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]types.BulkEmailEntry, len(req.Destinations))
+	for i, dest := range req.Destinations {
+		if len(dest.To) == 0 {
+			return nil, fmt.Errorf("destination %d: at least one recipient (To) is required", i)
+		}
+		entries[i].Destination = &types.Destination{
+			ToAddresses:  dest.To,
+			CcAddresses:  dest.CC,
+			BccAddresses: dest.BCC,
+		}
+		if dest.TemplateData != nil {
+			data, err := marshalTemplateData(dest.TemplateData)
+			if err != nil {
+				return nil, fmt.Errorf("destination %d: %w", i, err)
+			}
+			entries[i].ReplacementEmailContent = &types.ReplacementEmailContent{
+				ReplacementTemplate: &types.ReplacementTemplate{ReplacementTemplateData: &data},
+			}
+		}
+	}
+
+	results := make([]BulkEntryResult, 0, len(entries))
+	var errs []error
+	for start := 0; start < len(entries); start += maxBulkDestinations {
+		end := min(start+maxBulkDestinations, len(entries))
+
+		out, err := client.SendBulkEmail(ctx, &sesv2.SendBulkEmailInput{
+			FromEmailAddress: &req.From,
+			ReplyToAddresses: req.ReplyTo,
+			DefaultContent: &types.BulkEmailContent{
+				Template: &types.Template{
+					TemplateName: &req.TemplateName,
+					TemplateData: &defaultData,
+				},
+			},
+			BulkEmailEntries: entries[start:end],
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("send bulk email error: %w", err))
+		}
+		for i := start; i < end; i++ {
+			result := BulkEntryResult{To: req.Destinations[i].To, Status: string(types.BulkEmailStatusFailed)}
+			switch {
+			case err != nil:
+				result.Error = err.Error()
+			case i-start < len(out.BulkEmailEntryResults):
+				entry := out.BulkEmailEntryResults[i-start]
+				result.Status = string(entry.Status)
+				result.MessageID = derefString(entry.MessageId)
+				result.Error = derefString(entry.Error)
+			default:
+				result.Error = "no result returned by SES"
+			}
+			results = append(results, result)
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// marshalTemplateData encodes template data as JSON within the SES size limit.
+// Nil data is encoded as an empty object.
+func marshalTemplateData(data map[string]any) (string, error) {
+	if data == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal template data: %w", err)
+	}
+	if len(b) > maxTemplateDataSize {
+		return "", fmt.Errorf("template data is %d bytes, exceeds SES limit of %d", len(b), maxTemplateDataSize)
+	}
+	return string(b), nil
+}
+
+// derefString returns the string s points to, or "" if nil
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,111 @@
+package ses
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+)
+
+// newTestClient returns a client sending requests to handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *sesv2.Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return sesv2.New(sesv2.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: &server.URL,
+		Credentials:  credentials.NewStaticCredentialsProvider("AKIA_TEST", "secret", ""),
+	})
+}
+
+func TestSendTemplatedEmailWith(t *testing.T) {
+	var body map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"MessageId":"msg-1"}`))
+	})
+
+	resp, err := SendTemplatedEmailWith(context.Background(), client, &TemplatedEmailRequest{
+		From:         "sender@example.com",
+		To:           []string{"user@example.com"},
+		TemplateName: "welcome",
+		TemplateData: map[string]any{"name": "Ann"},
+	})
+	if err != nil || !resp.Success || resp.MessageID != "msg-1" {
+		t.Fatalf("unexpected result: %+v, %v", resp, err)
+	}
+	template := body["Content"].(map[string]any)["Template"].(map[string]any)
+	if template["TemplateName"] != "welcome" || template["TemplateData"] != `{"name":"Ann"}` {
+		t.Errorf("unexpected template: %v", template)
+	}
+}
+
+func TestSendBulkTemplatedEmailWith(t *testing.T) {
+	var calls []int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			BulkEmailEntries []struct {
+				Destination struct{ ToAddresses []string }
+			}
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		calls = append(calls, len(in.BulkEmailEntries))
+
+		type result struct{ Status, MessageId, Error string }
+		out := struct{ BulkEmailEntryResults []result }{}
+		for _, e := range in.BulkEmailEntries {
+			if to := e.Destination.ToAddresses[0]; strings.HasPrefix(to, "bad") {
+				out.BulkEmailEntryResults = append(out.BulkEmailEntryResults, result{Status: "MESSAGE_REJECTED", Error: "rejected"})
+			} else {
+				out.BulkEmailEntryResults = append(out.BulkEmailEntryResults, result{Status: "SUCCESS", MessageId: "id-" + to})
+			}
+		}
+		json.NewEncoder(w).Encode(out)
+	})
+
+	req := &BulkEmailRequest{From: "sender@example.com", TemplateName: "digest"}
+	for i := range 120 {
+		to := fmt.Sprintf("user%d@example.com", i)
+		if i == 55 {
+			to = "bad@example.com"
+		}
+		req.Destinations = append(req.Destinations, BulkDestination{To: []string{to}, TemplateData: map[string]any{"n": i}})
+	}
+
+	results, err := SendBulkTemplatedEmailWith(context.Background(), client, req)
+	if err != nil {
+		t.Fatalf("SendBulkTemplatedEmailWith failed: %v", err)
+	}
+	if fmt.Sprint(calls) != "[50 50 20]" {
+		t.Errorf("expected batches of 50, got %v", calls)
+	}
+	if len(results) != 120 {
+		t.Fatalf("expected 120 results, got %d", len(results))
+	}
+	if r := results[119]; r.Status != "SUCCESS" || r.MessageID != "id-user119@example.com" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	if r := results[55]; r.Status != "MESSAGE_REJECTED" || r.Error != "rejected" || r.To[0] != "bad@example.com" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+}
+
+func TestMarshalTemplateDataLimit(t *testing.T) {
+	if data, err := marshalTemplateData(nil); err != nil || data != "{}" {
+		t.Errorf("nil data should encode as {}, got %q, %v", data, err)
+	}
+	_, err := marshalTemplateData(map[string]any{"body": strings.Repeat("x", maxTemplateDataSize)})
+	if err == nil || !strings.Contains(err.Error(), "exceeds SES limit") {
+		t.Errorf("expected size error, got %v", err)
+	}
+	if _, err := marshalTemplateData(map[string]any{"f": func() {}}); err == nil {
+		t.Error("expected marshal error")
+	}
+}
@@ -0,0 +1,192 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+)
+
+// maxBatchSize is the SQS limit of entries per batch request
+const maxBatchSize = 10
+
+// BatchResult reports the outcome of SendBatch
+type BatchResult struct {
+	Sent   int            // Number of messages accepted by SQS
+	Failed []BatchFailure // Entries that were not sent
+}
+
+// BatchFailure is a batch entry that failed
+type BatchFailure struct {
+	ID          string // Entry ID: the index of the message in the input slice
+	Code        string // SQS error code, e.g. "InvalidParameterValue"
+	Message     string
+	SenderFault bool // The entry itself is invalid; retrying as-is will fail again
+}
+
+// ReceivedMessage is a message received from the queue, with the receipt
+// handle needed to delete it
+type ReceivedMessage struct {
+	Message
+	MessageID     string
+	ReceiptHandle string
+}
+
+// SendBatch sends messages with SendMessageBatch, 10 per request. Entry IDs
+// are the indexes of msgs, so callers can re-send only result.Failed.
+// Zero SendAtMS and MaxRetries default as in Send.
+//
+// A request that fails as a whole marks all its entries failed with the
+// error code, and the remaining requests are still sent; the returned error
+// joins such request errors. FIFO queues are not supported.
+func (c *Client) SendBatch(msgs []Message) (*BatchResult, error) {
+	if c.fifo {
+		return nil, fmt.Errorf("sqs: SendBatch is not supported on FIFO queue %s, use SendFIFO", c.queueUrl)
+	}
+	ctx := context.Background()
+	result := &BatchResult{}
+	var errs []error
+
+	for start := 0; start < len(msgs); start += maxBatchSize {
+		end := min(start+maxBatchSize, len(msgs))
+
+		entries := make([]sqstypes.SendMessageBatchRequestEntry, 0, end-start)
+		for i := start; i < end; i++ {
+			msg := msgs[i]
+			if msg.SendAtMS == 0 {
+				msg.SendAtMS = time.Now().UnixMilli()
+			}
+			if msg.MaxRetries == 0 {
+				msg.MaxRetries = 3
+			}
+			body, err := json.Marshal(msg)
+			if err != nil {
+				result.Failed = append(result.Failed, BatchFailure{
+					ID: strconv.Itoa(i), Code: "MarshalError", Message: err.Error(), SenderFault: true,
+				})
+				continue
+			}
+			entries = append(entries, sqstypes.SendMessageBatchRequestEntry{
+				Id:                awsv2.String(strconv.Itoa(i)),
+				MessageBody:       awsv2.String(string(body)),
+				MessageAttributes: withAttributes(messageAttributes(ctx, ""), msg.Attributes, msg.TraceID),
+			})
+		}
+		if len(entries) == 0 {
+			continue
+		}
+
+		out, err := c.sqs.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+			QueueUrl: &c.queueUrl,
+			Entries:  entries,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("send message batch error: %w", err))
+			for _, e := range entries {
+				result.Failed = append(result.Failed, requestFailure(*e.Id, err))
+			}
+			continue
+		}
+		result.Sent += len(out.Successful)
+		for _, e := range out.Failed {
+			result.Failed = append(result.Failed, BatchFailure{
+				ID:          awsv2.ToString(e.Id),
+				Code:        awsv2.ToString(e.Code),
+				Message:     awsv2.ToString(e.Message),
+				SenderFault: e.SenderFault,
+			})
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// requestFailure reports an entry of a batch request that failed as a whole
+func requestFailure(id string, err error) BatchFailure {
+	f := BatchFailure{ID: id, Message: err.Error()}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		f.Code = apiErr.ErrorCode()
+		f.SenderFault = apiErr.ErrorFault() == smithy.FaultClient
+	}
+	return f
+}
+
+// ReceiveBatch receives up to maxMessages (at most 10) messages, long
+// polling up to 20 seconds. Unlike Consume, messages are not deleted: call
+// DeleteBatch with the receipt handles once they are processed. Bodies that
+// are not valid messages are skipped.
+func (c *Client) ReceiveBatch(ctx context.Context, maxMessages int) ([]ReceivedMessage, error) {
+	maxMessages = min(max(maxMessages, 1), maxBatchSize)
+
+	result, err := c.sqs.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:              &c.queueUrl,
+		MaxNumberOfMessages:   int32(maxMessages),
+		WaitTimeSeconds:       20,
+		MessageAttributeNames: []string{string(sqstypes.QueueAttributeNameAll)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("receive message error: %w", err)
+	}
+
+	msgs := make([]ReceivedMessage, 0, len(result.Messages))
+	for _, message := range result.Messages {
+		var msg Message
+		if err := json.Unmarshal([]byte(awsv2.ToString(message.Body)), &msg); err != nil {
+			fmt.Printf("unmarshal message error: %v\n", err)
+			continue
+		}
+		msg.OriginRegion = c.region
+		if attr, ok := message.MessageAttributes[OriginRegionAttribute]; ok && attr.StringValue != nil {
+			msg.OriginRegion = *attr.StringValue
+		}
+		decodeAttributes(&msg, message.MessageAttributes)
+		msg.ctx = traceContext(ctx, message.MessageAttributes)
+		msgs = append(msgs, ReceivedMessage{
+			Message:       msg,
+			MessageID:     awsv2.ToString(message.MessageId),
+			ReceiptHandle: awsv2.ToString(message.ReceiptHandle),
+		})
+	}
+	return msgs, nil
+}
+
+// DeleteBatch deletes messages by receipt handle with DeleteMessageBatch,
+// 10 per request. The error lists the handles that could not be deleted.
+func (c *Client) DeleteBatch(handles []string) error {
+	ctx := context.Background()
+	var errs []error
+
+	for start := 0; start < len(handles); start += maxBatchSize {
+		end := min(start+maxBatchSize, len(handles))
+
+		entries := make([]sqstypes.DeleteMessageBatchRequestEntry, 0, end-start)
+		for i := start; i < end; i++ {
+			entries = append(entries, sqstypes.DeleteMessageBatchRequestEntry{
+				Id:            awsv2.String(strconv.Itoa(i)),
+				ReceiptHandle: awsv2.String(handles[i]),
+			})
+		}
+
+		out, err := c.sqs.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+			QueueUrl: &c.queueUrl,
+			Entries:  entries,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("delete message batch error: %w", err))
+			continue
+		}
+		for _, e := range out.Failed {
+			i, _ := strconv.Atoi(awsv2.ToString(e.Id))
+			errs = append(errs, fmt.Errorf("delete message %s error: %s: %s",
+				handles[i], awsv2.ToString(e.Code), awsv2.ToString(e.Message)))
+		}
+	}
+	return errors.Join(errs...)
+}
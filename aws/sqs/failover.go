@@ -0,0 +1,300 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"github.com/spf13/viper"
+)
+
+// OriginRegionAttribute is the message attribute carrying the region a message
+// was actually sent to. It is set on every send when failover is configured.
+const OriginRegionAttribute = "origin_region"
+
+const (
+	defaultProbeInterval  = 30 * time.Second
+	defaultRecoveryProbes = 3
+
+	// throttleRetries is how often a throttled send is retried in the same
+	// region before the error is returned
+	throttleRetries = 3
+)
+
+// throttleBackoff is the delay before the first retry of a throttled send,
+// doubled on each retry
+var throttleBackoff = 200 * time.Millisecond
+
+// sqsAPI is the subset of the SQS client used by this package.
+type sqsAPI interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+	SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+	DeleteMessageBatch(ctx context.Context, params *sqs.DeleteMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error)
+	CreateQueue(ctx context.Context, params *sqs.CreateQueueInput, optFns ...func(*sqs.Options)) (*sqs.CreateQueueOutput, error)
+	DeleteQueue(ctx context.Context, params *sqs.DeleteQueueInput, optFns ...func(*sqs.Options)) (*sqs.DeleteQueueOutput, error)
+	GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error)
+	SetQueueAttributes(ctx context.Context, params *sqs.SetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.SetQueueAttributesOutput, error)
+	ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
+}
+
+// regionQueue is the same queue in one region. The URL of a failover region
+// is resolved on first use, so an unreachable standby does not block startup.
+type regionQueue struct {
+	region     string
+	name       string
+	attributes map[string]string // Used when creating the queue
+	cfg        *Config           // Visibility and redrive applied after creating, see configureQueue
+	api        sqsAPI
+	redrive    bool // The queue has a redrive policy, see Client.redrive
+
+	mu  sync.Mutex
+	url string
+}
+
+// queueURL returns the queue URL, creating the queue in the region if needed.
+func (q *regionQueue) queueURL(ctx context.Context) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.url != "" {
+		return q.url, nil
+	}
+	result, err := q.api.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName:  awsv2.String(q.name),
+		Attributes: q.attributes,
+	})
+	if err != nil {
+		return "", err
+	}
+	if q.cfg != nil {
+		// Same dead letter queue setup as the primary, in this region
+		if err := configureQueue(ctx, q.api, *result.QueueUrl, q.cfg); err != nil {
+			return "", err
+		}
+	}
+	q.url = *result.QueueUrl
+	return q.url, nil
+}
+
+// failoverConfig holds the failover settings of a queue.
+type failoverConfig struct {
+	regions        []string
+	probeInterval  time.Duration
+	recoveryProbes int
+	consumeAll     bool
+}
+
+// loadFailoverConfig reads the aws.sqs.queues.<name>.failover_* settings.
+func loadFailoverConfig(queueName string) failoverConfig {
+	path := fmt.Sprintf("aws.sqs.queues.%s.", queueName)
+	fc := failoverConfig{
+		regions:        viper.GetStringSlice(path + "failover_regions"),
+		probeInterval:  viper.GetDuration(path + "failover_probe_interval"),
+		recoveryProbes: viper.GetInt(path + "failover_recovery_probes"),
+		consumeAll:     viper.GetBool(path + "consume_all_regions"),
+	}
+	if fc.probeInterval <= 0 {
+		fc.probeInterval = defaultProbeInterval
+	}
+	if fc.recoveryProbes <= 0 {
+		fc.recoveryProbes = defaultRecoveryProbes
+	}
+	return fc
+}
+
+// sendOrder returns the regions to try: primary first while healthy,
+// otherwise the failover regions first and the primary as a last resort.
+func (c *Client) sendOrder() []*regionQueue {
+	primary := &regionQueue{region: c.region, api: c.sqs, url: c.queueUrl}
+
+	c.failMu.Lock()
+	degraded := c.degraded
+	c.failMu.Unlock()
+
+	if degraded {
+		return append(append([]*regionQueue{}, c.failover...), primary)
+	}
+	return append([]*regionQueue{primary}, c.failover...)
+}
+
+// sendParams are the per-message options of a send
+type sendParams struct {
+	delaySeconds int32
+	groupID      string // FIFO message group
+	dedupID      string // FIFO deduplication ID
+	attributes   map[string]string
+	traceID      string
+}
+
+// input builds the SendMessageInput for body
+func (p sendParams) input(ctx context.Context, url, body, region string) *sqs.SendMessageInput {
+	in := &sqs.SendMessageInput{
+		DelaySeconds:      p.delaySeconds,
+		MessageBody:       awsv2.String(body),
+		QueueUrl:          &url,
+		MessageAttributes: withAttributes(messageAttributes(ctx, region), p.attributes, p.traceID),
+	}
+	if p.groupID != "" {
+		in.MessageGroupId = awsv2.String(p.groupID)
+	}
+	if p.dedupID != "" {
+		in.MessageDeduplicationId = awsv2.String(p.dedupID)
+	}
+	return in
+}
+
+// sendBody sends a message body, falling through to the failover regions on
+// connection errors or service unavailability. Throttled sends are retried in
+// the same region, since moving the load elsewhere does not help; client
+// errors (validation, permissions, missing queue) are returned without
+// failover.
+func (c *Client) sendBody(ctx context.Context, body string, params sendParams) error {
+	if len(c.failover) == 0 {
+		_, err := c.sqs.SendMessage(ctx, params.input(ctx, c.queueUrl, body, ""))
+		return err
+	}
+
+	var errs []error
+	for _, q := range c.sendOrder() {
+		err := c.sendTo(ctx, q, body, params)
+		if err == nil {
+			return nil
+		}
+		if !isFailoverError(err) {
+			return err
+		}
+		if q.region == c.region {
+			c.markDegraded()
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", q.region, err))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Client) sendTo(ctx context.Context, q *regionQueue, body string, params sendParams) error {
+	url, err := q.queueURL(ctx)
+	if err != nil {
+		return err
+	}
+	backoff := throttleBackoff
+	for retry := 0; ; retry++ {
+		_, err = q.api.SendMessage(ctx, params.input(ctx, url, body, q.region))
+		if !isThrottleError(err) || retry == throttleRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// isThrottleError reports whether err means the region is rate limiting us.
+func isThrottleError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "RequestThrottled", "ThrottlingException", "Throttling", "KmsThrottled":
+		return true
+	}
+	return false
+}
+
+// isFailoverError reports whether err means the region is unavailable rather
+// than the request being invalid or throttled.
+func isFailoverError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || isThrottleError(err) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) && respErr.HTTPStatusCode() >= 500 {
+		return true
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "ServiceUnavailable", "InternalError", "InternalFailure":
+			return true
+		}
+		return apiErr.ErrorFault() == smithy.FaultServer
+	}
+	return false
+}
+
+// markDegraded switches sending to the failover regions and starts probing
+// the primary for recovery.
+func (c *Client) markDegraded() {
+	c.failMu.Lock()
+	defer c.failMu.Unlock()
+	c.healthyProbes = 0
+	if c.degraded {
+		return
+	}
+	c.degraded = true
+	fmt.Printf("sqs: primary region %s unavailable, sending via failover regions\n", c.region)
+	go c.probeLoop()
+}
+
+// probeLoop probes the primary until it has recovered.
+func (c *Client) probeLoop() {
+	ticker := time.NewTicker(c.probeInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if c.probe(context.Background()) {
+			return
+		}
+	}
+}
+
+// probe checks the primary once. Sending flips back only after
+// recoveryProbes consecutive healthy probes, so a flapping primary does not
+// bounce traffic between regions. Returns true once no longer degraded.
+func (c *Client) probe(ctx context.Context) bool {
+	c.failMu.Lock()
+	degraded := c.degraded
+	c.failMu.Unlock()
+	if !degraded {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err := c.sqs.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       &c.queueUrl,
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameApproximateNumberOfMessages},
+	})
+
+	c.failMu.Lock()
+	defer c.failMu.Unlock()
+	if err != nil {
+		c.healthyProbes = 0
+		return false
+	}
+	c.healthyProbes++
+	if c.healthyProbes < c.recoveryProbes {
+		return false
+	}
+	c.degraded, c.healthyProbes = false, 0
+	fmt.Printf("sqs: primary region %s recovered\n", c.region)
+	return true
+}
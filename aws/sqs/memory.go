@@ -0,0 +1,517 @@
+package sqs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"github.com/rs/xid"
+)
+
+// BackendMemory is the aws.sqs.backend value selecting the in-memory queue
+// backend, for tests and local development without AWS.
+const BackendMemory = "memory"
+
+const (
+	memoryURLPrefix          = "memory://sqs/"
+	memoryARNPrefix          = "arn:aws:sqs:memory:000000000000:"
+	defaultVisibilityTimeout = 30 * time.Second
+	dedupInterval            = 5 * time.Minute
+)
+
+var (
+	memory    = newMemoryBackend()
+	useMemory bool // Set by UseMemoryBackend, guarded by sqsMux
+)
+
+// UseMemoryBackend makes Get create clients on the in-memory backend, as
+// aws.sqs.backend: memory does. Existing clients and queued messages are
+// discarded, so each test starts from empty queues.
+//
+// The backend honors per-message delays, the visibility timeout (unsettled
+// messages are delivered again when it expires), FIFO message groups and
+// deduplication, and max_receive_count: messages received that many times
+// move to the dead letter queue, or are dropped without one.
+func UseMemoryBackend() {
+	sqsMux.Lock()
+	defer sqsMux.Unlock()
+	useMemory = true
+	sqsClients = make(map[string]*Client)
+	memory = newMemoryBackend()
+}
+
+// initMemory creates a client for queueName on the in-memory backend
+func initMemory(cfg *Config) (*Client, error) {
+	ctx := context.Background()
+	name, attributes := queueSpec(cfg)
+	result, err := memory.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName:  awsv2.String(name),
+		Attributes: attributes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create/get queue error: %v", err)
+	}
+	if err := configureQueue(ctx, memory, *result.QueueUrl, cfg); err != nil {
+		return nil, fmt.Errorf("configure queue error: %v", err)
+	}
+	if cfg.DLQName == "" && cfg.MaxReceiveCount > 0 {
+		memory.setMaxReceive(*result.QueueUrl, cfg.MaxReceiveCount)
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = BackendMemory
+	}
+	return &Client{
+		sqs:          memory,
+		queueUrl:     *result.QueueUrl,
+		region:       region,
+		fifo:         cfg.Fifo,
+		contentDedup: cfg.ContentBasedDeduplication,
+		redrive:      cfg.DLQName != "",
+	}, nil
+}
+
+// memoryBackend implements sqsAPI with queues held in process memory
+type memoryBackend struct {
+	mu      sync.Mutex
+	queues  map[string]*memoryQueue // Queue URL -> queue
+	offset  time.Duration           // Added to the wall clock, moved by tests
+	changed chan struct{}           // Closed when messages may have become visible
+}
+
+type memoryQueue struct {
+	name       string
+	attributes map[string]string
+	messages   []*memoryMessage // In send order
+	dedup      map[string]time.Time
+	maxReceive int    // 0 = unlimited
+	dlqARN     string // Dead letter queue; empty drops messages over maxReceive
+}
+
+type memoryMessage struct {
+	id           string
+	body         string
+	attributes   map[string]sqstypes.MessageAttributeValue
+	groupID      string
+	dedupID      string
+	sentAt       time.Time
+	visibleAt    time.Time
+	receiveCount int
+	receipt      string // Handle of the latest receive
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{
+		queues:  make(map[string]*memoryQueue),
+		changed: make(chan struct{}),
+	}
+}
+
+func (b *memoryBackend) now() time.Time {
+	return time.Now().Add(b.offset)
+}
+
+// notify wakes long-polling receivers. Requires b.mu.
+func (b *memoryBackend) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// queue returns the queue at url. Requires b.mu.
+func (b *memoryBackend) queue(url *string) (*memoryQueue, error) {
+	q, ok := b.queues[awsv2.ToString(url)]
+	if !ok {
+		return nil, memoryError("AWS.SimpleQueueService.NonExistentQueue", "queue %s does not exist", awsv2.ToString(url))
+	}
+	return q, nil
+}
+
+func (b *memoryBackend) setMaxReceive(url string, n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if q, ok := b.queues[url]; ok {
+		q.maxReceive = n
+	}
+}
+
+func (q *memoryQueue) fifo() bool {
+	return q.attributes[string(sqstypes.QueueAttributeNameFifoQueue)] == "true"
+}
+
+func (q *memoryQueue) visibilityTimeout() time.Duration {
+	if s, err := strconv.Atoi(q.attributes[string(sqstypes.QueueAttributeNameVisibilityTimeout)]); err == nil {
+		return time.Duration(s) * time.Second
+	}
+	return defaultVisibilityTimeout
+}
+
+func memoryError(code, format string, args ...any) error {
+	return &smithy.GenericAPIError{Code: code, Message: fmt.Sprintf(format, args...), Fault: smithy.FaultClient}
+}
+
+func (b *memoryBackend) CreateQueue(ctx context.Context, in *sqs.CreateQueueInput, _ ...func(*sqs.Options)) (*sqs.CreateQueueOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	url := memoryURLPrefix + awsv2.ToString(in.QueueName)
+	if _, ok := b.queues[url]; !ok {
+		attributes := make(map[string]string, len(in.Attributes))
+		for k, v := range in.Attributes {
+			attributes[k] = v
+		}
+		b.queues[url] = &memoryQueue{name: awsv2.ToString(in.QueueName), attributes: attributes, dedup: make(map[string]time.Time)}
+	}
+	return &sqs.CreateQueueOutput{QueueUrl: awsv2.String(url)}, nil
+}
+
+func (b *memoryBackend) DeleteQueue(ctx context.Context, in *sqs.DeleteQueueInput, _ ...func(*sqs.Options)) (*sqs.DeleteQueueOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, err := b.queue(in.QueueUrl); err != nil {
+		return nil, err
+	}
+	delete(b.queues, awsv2.ToString(in.QueueUrl))
+	return &sqs.DeleteQueueOutput{}, nil
+}
+
+func (b *memoryBackend) GetQueueAttributes(ctx context.Context, in *sqs.GetQueueAttributesInput, _ ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, err := b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	now := b.now()
+	visible, inFlight := 0, 0
+	for _, m := range q.messages {
+		switch {
+		case m.receipt != "" && now.Before(m.visibleAt):
+			inFlight++
+		case !now.Before(m.visibleAt):
+			visible++
+		}
+	}
+	attributes := map[string]string{
+		string(sqstypes.QueueAttributeNameQueueArn):                              memoryARNPrefix + q.name,
+		string(sqstypes.QueueAttributeNameApproximateNumberOfMessages):           strconv.Itoa(visible),
+		string(sqstypes.QueueAttributeNameApproximateNumberOfMessagesNotVisible): strconv.Itoa(inFlight),
+	}
+	for k, v := range q.attributes {
+		attributes[k] = v
+	}
+	return &sqs.GetQueueAttributesOutput{Attributes: attributes}, nil
+}
+
+// SetQueueAttributes stores the attributes; a RedrivePolicy sets the dead
+// letter queue and the max receive count.
+func (b *memoryBackend) SetQueueAttributes(ctx context.Context, in *sqs.SetQueueAttributesInput, _ ...func(*sqs.Options)) (*sqs.SetQueueAttributesOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, err := b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range in.Attributes {
+		q.attributes[k] = v
+	}
+	if policy, ok := in.Attributes[string(sqstypes.QueueAttributeNameRedrivePolicy)]; ok {
+		var redrive struct {
+			DeadLetterTargetArn string      `json:"deadLetterTargetArn"`
+			MaxReceiveCount     json.Number `json:"maxReceiveCount"`
+		}
+		if err := json.Unmarshal([]byte(policy), &redrive); err != nil {
+			return nil, memoryError("InvalidAttributeValue", "invalid RedrivePolicy: %v", err)
+		}
+		n, _ := redrive.MaxReceiveCount.Int64()
+		q.dlqARN, q.maxReceive = redrive.DeadLetterTargetArn, int(n)
+	}
+	return &sqs.SetQueueAttributesOutput{}, nil
+}
+
+func (b *memoryBackend) SendMessage(ctx context.Context, in *sqs.SendMessageInput, _ ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, err := b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	id, err := b.send(q, awsv2.ToString(in.MessageBody), in.MessageAttributes, in.DelaySeconds,
+		awsv2.ToString(in.MessageGroupId), awsv2.ToString(in.MessageDeduplicationId))
+	if err != nil {
+		return nil, err
+	}
+	return &sqs.SendMessageOutput{MessageId: awsv2.String(id)}, nil
+}
+
+func (b *memoryBackend) SendMessageBatch(ctx context.Context, in *sqs.SendMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, err := b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	out := &sqs.SendMessageBatchOutput{}
+	for _, e := range in.Entries {
+		id, err := b.send(q, awsv2.ToString(e.MessageBody), e.MessageAttributes, e.DelaySeconds,
+			awsv2.ToString(e.MessageGroupId), awsv2.ToString(e.MessageDeduplicationId))
+		if err != nil {
+			apiErr := err.(*smithy.GenericAPIError)
+			out.Failed = append(out.Failed, sqstypes.BatchResultErrorEntry{
+				Id: e.Id, Code: awsv2.String(apiErr.Code), Message: awsv2.String(apiErr.Message), SenderFault: true,
+			})
+			continue
+		}
+		out.Successful = append(out.Successful, sqstypes.SendMessageBatchResultEntry{Id: e.Id, MessageId: awsv2.String(id)})
+	}
+	return out, nil
+}
+
+// send validates and enqueues a message like SQS. Requires b.mu.
+func (b *memoryBackend) send(q *memoryQueue, body string, attributes map[string]sqstypes.MessageAttributeValue, delaySeconds int32, groupID, dedupID string) (string, error) {
+	if delaySeconds < 0 || delaySeconds > maxDelaySeconds {
+		return "", memoryError("InvalidParameterValue", "DelaySeconds must be between 0 and %d", maxDelaySeconds)
+	}
+	now := b.now()
+	id := xid.New().String()
+
+	if q.fifo() {
+		if groupID == "" {
+			return "", memoryError("MissingParameter", "MessageGroupId is required for FIFO queues")
+		}
+		if delaySeconds != 0 {
+			return "", memoryError("InvalidParameterValue", "per-message DelaySeconds is not supported on FIFO queues")
+		}
+		if dedupID == "" {
+			if q.attributes[string(sqstypes.QueueAttributeNameContentBasedDeduplication)] != "true" {
+				return "", memoryError("InvalidParameterValue", "MessageDeduplicationId is required without content-based deduplication")
+			}
+			sum := sha256.Sum256([]byte(body))
+			dedupID = hex.EncodeToString(sum[:])
+		}
+		for k, at := range q.dedup {
+			if now.Sub(at) >= dedupInterval {
+				delete(q.dedup, k)
+			}
+		}
+		if _, dup := q.dedup[dedupID]; dup {
+			return id, nil // Accepted but not delivered again
+		}
+		q.dedup[dedupID] = now
+	} else if groupID != "" {
+		return "", memoryError("InvalidParameterValue", "MessageGroupId is only supported on FIFO queues")
+	}
+
+	if delaySeconds == 0 {
+		if s, err := strconv.Atoi(q.attributes[string(sqstypes.QueueAttributeNameDelaySeconds)]); err == nil {
+			delaySeconds = int32(s)
+		}
+	}
+	q.messages = append(q.messages, &memoryMessage{
+		id:         id,
+		body:       body,
+		attributes: attributes,
+		groupID:    groupID,
+		dedupID:    dedupID,
+		sentAt:     now,
+		visibleAt:  now.Add(time.Duration(delaySeconds) * time.Second),
+	})
+	b.notify()
+	return id, nil
+}
+
+// ReceiveMessage returns visible messages, long polling up to
+// WaitTimeSeconds for one to arrive or become visible.
+func (b *memoryBackend) ReceiveMessage(ctx context.Context, in *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	deadline := time.Now().Add(time.Duration(in.WaitTimeSeconds) * time.Second)
+	for {
+		msgs, changed, wait, err := b.receive(in)
+		if err != nil || len(msgs) > 0 || !time.Now().Before(deadline) {
+			return &sqs.ReceiveMessageOutput{Messages: msgs}, err
+		}
+
+		timer := time.NewTimer(min(time.Until(deadline), wait))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-changed:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// receive takes up to MaxNumberOfMessages visible messages, hiding them for
+// the visibility timeout. It also returns the channel closed on the next
+// change and how long until the next hidden message becomes visible.
+func (b *memoryBackend) receive(in *sqs.ReceiveMessageInput) ([]sqstypes.Message, chan struct{}, time.Duration, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, err := b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	limit := min(max(int(in.MaxNumberOfMessages), 1), maxBatchSize)
+	visibility := q.visibilityTimeout()
+	if in.VisibilityTimeout > 0 {
+		visibility = time.Duration(in.VisibilityTimeout) * time.Second
+	}
+
+	now := b.now()
+	wait := time.Duration(1<<63 - 1)
+	blocked := map[string]bool{} // FIFO groups with an earlier message hidden
+	var msgs []sqstypes.Message
+	kept := q.messages[:0]
+	for _, m := range q.messages {
+		if len(msgs) == limit || blocked[m.groupID] {
+			kept = append(kept, m)
+			continue
+		}
+		if now.Before(m.visibleAt) {
+			wait = min(wait, m.visibleAt.Sub(now))
+			if m.groupID != "" {
+				blocked[m.groupID] = true
+			}
+			kept = append(kept, m)
+			continue
+		}
+		if q.maxReceive > 0 && m.receiveCount >= q.maxReceive {
+			b.deadLetter(q, m)
+			continue
+		}
+
+		m.receiveCount++
+		m.receipt = xid.New().String()
+		m.visibleAt = now.Add(visibility)
+		kept = append(kept, m)
+		msgs = append(msgs, m.output())
+	}
+	clear(q.messages[len(kept):])
+	q.messages = kept
+	return msgs, b.changed, wait, nil
+}
+
+// deadLetter moves m to the dead letter queue of q, or drops it when there
+// is none. Requires b.mu.
+func (b *memoryBackend) deadLetter(q *memoryQueue, m *memoryMessage) {
+	if !strings.HasPrefix(q.dlqARN, memoryARNPrefix) {
+		return
+	}
+	dlq, ok := b.queues[memoryURLPrefix+strings.TrimPrefix(q.dlqARN, memoryARNPrefix)]
+	if !ok {
+		return
+	}
+	m.receiveCount, m.receipt, m.visibleAt = 0, "", b.now()
+	dlq.messages = append(dlq.messages, m)
+}
+
+// output converts m to a received message with its system attributes
+func (m *memoryMessage) output() sqstypes.Message {
+	attributes := map[string]string{
+		string(sqstypes.MessageSystemAttributeNameSentTimestamp):           strconv.FormatInt(m.sentAt.UnixMilli(), 10),
+		string(sqstypes.MessageSystemAttributeNameApproximateReceiveCount): strconv.Itoa(m.receiveCount),
+	}
+	if m.groupID != "" {
+		attributes[string(sqstypes.MessageSystemAttributeNameMessageGroupId)] = m.groupID
+		attributes[string(sqstypes.MessageSystemAttributeNameMessageDeduplicationId)] = m.dedupID
+	}
+	return sqstypes.Message{
+		MessageId:         awsv2.String(m.id),
+		ReceiptHandle:     awsv2.String(m.receipt),
+		Body:              awsv2.String(m.body),
+		MessageAttributes: m.attributes,
+		Attributes:        attributes,
+	}
+}
+
+// inFlight returns the index of the message last received with receipt.
+// Requires b.mu.
+func (q *memoryQueue) inFlight(receipt string) int {
+	for i, m := range q.messages {
+		if receipt != "" && m.receipt == receipt {
+			return i
+		}
+	}
+	return -1
+}
+
+func (b *memoryBackend) DeleteMessage(ctx context.Context, in *sqs.DeleteMessageInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, err := b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.delete(awsv2.ToString(in.ReceiptHandle)); err != nil {
+		return nil, err
+	}
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (b *memoryBackend) DeleteMessageBatch(ctx context.Context, in *sqs.DeleteMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, err := b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	out := &sqs.DeleteMessageBatchOutput{}
+	for _, e := range in.Entries {
+		if err := q.delete(awsv2.ToString(e.ReceiptHandle)); err != nil {
+			apiErr := err.(*smithy.GenericAPIError)
+			out.Failed = append(out.Failed, sqstypes.BatchResultErrorEntry{
+				Id: e.Id, Code: awsv2.String(apiErr.Code), Message: awsv2.String(apiErr.Message), SenderFault: true,
+			})
+			continue
+		}
+		out.Successful = append(out.Successful, sqstypes.DeleteMessageBatchResultEntry{Id: e.Id})
+	}
+	return out, nil
+}
+
+// delete removes the message last received with receipt. Requires b.mu.
+func (q *memoryQueue) delete(receipt string) error {
+	i := q.inFlight(receipt)
+	if i < 0 {
+		return memoryError("ReceiptHandleIsInvalid", "receipt handle %s is invalid", receipt)
+	}
+	q.messages = append(q.messages[:i], q.messages[i+1:]...)
+	return nil
+}
+
+func (b *memoryBackend) ChangeMessageVisibility(ctx context.Context, in *sqs.ChangeMessageVisibilityInput, _ ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, err := b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	i := q.inFlight(awsv2.ToString(in.ReceiptHandle))
+	if i < 0 {
+		return nil, memoryError("ReceiptHandleIsInvalid", "receipt handle %s is invalid", awsv2.ToString(in.ReceiptHandle))
+	}
+	now := b.now()
+	if !now.Before(q.messages[i].visibleAt) {
+		return nil, memoryError("MessageNotInflight", "message is not in flight")
+	}
+	q.messages[i].visibleAt = now.Add(time.Duration(in.VisibilityTimeout) * time.Second)
+	b.notify()
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
@@ -0,0 +1,243 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/spf13/viper"
+)
+
+// newMemoryClient returns the client of queue name on a fresh in-memory
+// backend, with the aws.sqs.queues.<name> settings in queueConfig.
+func newMemoryClient(t *testing.T, name string, queueConfig map[string]any) *Client {
+	t.Helper()
+	UseMemoryBackend()
+	if queueConfig != nil {
+		viper.Set("aws.sqs.queues."+name, queueConfig)
+		t.Cleanup(func() { viper.Set("aws.sqs.queues."+name, nil) })
+	}
+	c, err := Get(name)
+	if err != nil {
+		t.Fatalf("Get(%s) failed: %v", name, err)
+	}
+	return c
+}
+
+// queued returns the messages of c's queue, visible or not.
+func queued(c *Client) []*memoryMessage {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	return append([]*memoryMessage(nil), memory.queues[c.queueUrl].messages...)
+}
+
+// advance moves the backend clock forward, waking long-polling receivers.
+func advance(d time.Duration) {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	memory.offset += d
+	memory.notify()
+}
+
+// receiveNow receives without long polling.
+func receiveNow(t *testing.T, c *Client) []sqstypes.Message {
+	t.Helper()
+	out, err := c.sqs.ReceiveMessage(context.Background(), &sqs.ReceiveMessageInput{QueueUrl: &c.queueUrl, MaxNumberOfMessages: 10})
+	if err != nil {
+		t.Fatalf("ReceiveMessage failed: %v", err)
+	}
+	return out.Messages
+}
+
+func TestMemoryBackendConfig(t *testing.T) {
+	sqsMux.Lock()
+	useMemory, sqsClients = false, make(map[string]*Client)
+	sqsMux.Unlock()
+	viper.Set("aws.sqs.backend", BackendMemory)
+	defer viper.Set("aws.sqs.backend", nil)
+
+	// No region or credentials needed
+	c, err := Get("jobs")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if err := c.Send("job.run", map[string]int{"id": 1}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	msgs, err := c.ReceiveBatch(context.Background(), 10)
+	if err != nil || len(msgs) != 1 || msgs[0].Action != "job.run" || msgs[0].OriginRegion != BackendMemory {
+		t.Fatalf("unexpected messages: %+v, %v", msgs, err)
+	}
+
+	viper.Set("aws.sqs.backend", "kafka")
+	if _, err := Get("other"); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
+
+func TestMemoryDelayAndVisibility(t *testing.T) {
+	c := newMemoryClient(t, "events", nil)
+	if err := c.SendWithOptions("report.build", nil, SendOptions{DelaySeconds: 60}); err != nil {
+		t.Fatalf("SendWithOptions failed: %v", err)
+	}
+
+	if msgs := receiveNow(t, c); len(msgs) != 0 {
+		t.Fatalf("delayed message delivered early: %v", msgs)
+	}
+	advance(60 * time.Second)
+	msgs := receiveNow(t, c)
+	if len(msgs) != 1 {
+		t.Fatalf("expected the message after its delay, got %d", len(msgs))
+	}
+
+	// Unsettled messages reappear after the visibility timeout (30s)
+	if msgs := receiveNow(t, c); len(msgs) != 0 {
+		t.Fatal("in-flight message delivered twice")
+	}
+	advance(30 * time.Second)
+	again := receiveNow(t, c)
+	if len(again) != 1 || again[0].Attributes["ApproximateReceiveCount"] != "2" {
+		t.Fatalf("expected redelivery, got %v", again)
+	}
+	if err := c.DeleteBatch([]string{*msgs[0].ReceiptHandle}); err == nil {
+		t.Error("stale receipt handle should be rejected")
+	}
+	if err := c.DeleteBatch([]string{*again[0].ReceiptHandle}); err != nil || len(queued(c)) != 0 {
+		t.Errorf("delete failed: %v", err)
+	}
+}
+
+func TestMemoryMaxReceive(t *testing.T) {
+	c := newMemoryClient(t, "exports", map[string]any{"max_receive_count": 2})
+	c.Send("export", nil)
+	for range 2 {
+		if msgs := receiveNow(t, c); len(msgs) != 1 {
+			t.Fatalf("expected a delivery, got %d", len(msgs))
+		}
+		advance(30 * time.Second)
+	}
+	if msgs := receiveNow(t, c); len(msgs) != 0 || len(queued(c)) != 0 {
+		t.Fatalf("message should be dropped after 2 receives, got %d", len(msgs))
+	}
+
+	// With a dead letter queue it is moved there instead
+	c = newMemoryClient(t, "imports", map[string]any{"dlq_name": "imports-dlq", "max_receive_count": 1})
+	c.Send("import", nil)
+	receiveNow(t, c)
+	advance(30 * time.Second)
+	receiveNow(t, c)
+	dlq, _ := Get("imports-dlq")
+	if msgs := receiveNow(t, dlq); len(msgs) != 1 || len(queued(c)) != 0 {
+		t.Errorf("message should be in the dead letter queue, got %d", len(msgs))
+	}
+}
+
+func TestMemoryConsumeRetry(t *testing.T) {
+	c := newMemoryClient(t, "events", nil)
+	c.Send("user.registered", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	var retries []int
+	done := make(chan struct{})
+	go func() {
+		c.Consume(ctx, func(msg Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			retries = append(retries, msg.RetryCount)
+			if msg.RetryCount == 0 {
+				return errors.New("temporary failure")
+			}
+			cancel()
+			return nil
+		})
+		close(done)
+	}()
+
+	// The retry copy is delayed by a minute
+	waitFor(t, func() bool {
+		msgs := queued(c)
+		return len(msgs) == 1 && strings.Contains(msgs[0].body, `"retryCount":1`)
+	})
+	advance(time.Minute)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		cancel()
+		t.Fatal("retry copy not delivered")
+	}
+	if len(retries) != 2 || retries[1] != 1 || len(queued(c)) != 0 {
+		t.Errorf("expected one retry then success, got %v with %d queued", retries, len(queued(c)))
+	}
+}
+
+func TestMemoryConsumeDeadLetter(t *testing.T) {
+	c := newMemoryClient(t, "reports", map[string]any{"dlq_name": "reports-dlq", "max_receive_count": 2})
+	dlq, err := Get("reports-dlq")
+	if err != nil {
+		t.Fatalf("Get(reports-dlq) failed: %v", err)
+	}
+	c.Send("report.build", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	var retries []int
+	done := make(chan struct{})
+	go func() {
+		c.Consume(ctx, func(msg Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			retries = append(retries, msg.RetryCount)
+			return errors.New("template missing")
+		})
+		close(done)
+	}()
+
+	// Released at once, so no clock advance is needed
+	waitFor(t, func() bool { return len(queued(dlq)) == 1 })
+	cancel()
+	<-done
+	mu.Lock()
+	defer mu.Unlock()
+	if len(retries) != 2 || retries[0] != 0 || retries[1] != 0 || len(queued(c)) != 0 {
+		t.Errorf("expected two receives of the original then the DLQ, got %v with %d queued", retries, len(queued(c)))
+	}
+}
+
+func TestMemoryFifoGroups(t *testing.T) {
+	c := newMemoryClient(t, "orders", map[string]any{"fifo": true})
+	c.SendFIFO("a1", nil, "a", "1")
+	c.SendFIFO("a2", nil, "a", "2")
+	c.SendFIFO("b1", nil, "b", "3")
+	c.SendFIFO("a1", nil, "a", "1") // Duplicate
+
+	first, err := c.sqs.ReceiveMessage(context.Background(), &sqs.ReceiveMessageInput{QueueUrl: &c.queueUrl})
+	if err != nil || len(first.Messages) != 1 || first.Messages[0].Attributes["MessageGroupId"] != "a" {
+		t.Fatalf("expected the first message of group a, got %v, %v", first, err)
+	}
+	// Group a is blocked while a1 is in flight
+	msgs := receiveNow(t, c)
+	if len(msgs) != 1 || msgs[0].Attributes["MessageGroupId"] != "b" {
+		t.Fatalf("expected only group b, got %v", msgs)
+	}
+	c.DeleteBatch([]string{*first.Messages[0].ReceiptHandle})
+	if msgs := receiveNow(t, c); len(msgs) != 1 || msgs[0].Attributes["MessageDeduplicationId"] != "2" {
+		t.Errorf("expected a2 once a1 is deleted, got %v", msgs)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,75 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// defaultMaxReceiveCount is the redrive threshold when max_receive_count is unset
+const defaultMaxReceiveCount = 5
+
+// configureQueue applies the visibility timeout and the redrive policy of
+// cfg to the queue at url, creating the dead letter queue if needed. The
+// attributes are set after creation so they also apply to existing queues.
+func configureQueue(ctx context.Context, api sqsAPI, url string, cfg *Config) error {
+	attrs := map[string]string{}
+	if cfg.VisibilityTimeoutSeconds > 0 {
+		attrs[string(sqstypes.QueueAttributeNameVisibilityTimeout)] = strconv.Itoa(cfg.VisibilityTimeoutSeconds)
+	}
+
+	if cfg.DLQName != "" {
+		// The dead letter queue of a FIFO queue must be FIFO too
+		name, dlqAttrs := queueSpec(&Config{QueueName: cfg.DLQName, Fifo: cfg.Fifo})
+		dlq, err := api.CreateQueue(ctx, &sqs.CreateQueueInput{
+			QueueName:  awsv2.String(name),
+			Attributes: dlqAttrs,
+		})
+		if err != nil {
+			return err
+		}
+		out, err := api.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+			QueueUrl:       dlq.QueueUrl,
+			AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameQueueArn},
+		})
+		if err != nil {
+			return err
+		}
+
+		maxReceive := cfg.MaxReceiveCount
+		if maxReceive <= 0 {
+			maxReceive = defaultMaxReceiveCount
+		}
+		policy, _ := json.Marshal(map[string]string{
+			"deadLetterTargetArn": out.Attributes[string(sqstypes.QueueAttributeNameQueueArn)],
+			"maxReceiveCount":     strconv.Itoa(maxReceive),
+		})
+		attrs[string(sqstypes.QueueAttributeNameRedrivePolicy)] = string(policy)
+	}
+
+	if len(attrs) == 0 {
+		return nil
+	}
+	_, err := api.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+		QueueUrl:   &url,
+		Attributes: attrs,
+	})
+	return err
+}
+
+// ChangeVisibility sets the visibility timeout of a received message to
+// seconds from now (0 to 43200), so a long-running handler keeps the message
+// hidden from other consumers, or 0 to release it immediately. The receipt
+// handle must come from the primary region's queue.
+func (c *Client) ChangeVisibility(receiptHandle string, seconds int32) error {
+	_, err := c.sqs.ChangeMessageVisibility(context.Background(), &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          &c.queueUrl,
+		ReceiptHandle:     &receiptHandle,
+		VisibilityTimeout: seconds,
+	})
+	return err
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,19 +15,54 @@ import (
 	awscredentials "github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/rs/xid"
 	"github.com/spf13/viper"
 )
 
+const (
+	fifoSuffix    = ".fifo"
+	maxDedupIDLen = 128
+)
+
 var sqsClients map[string]*Client = make(map[string]*Client)
 var sqsMux sync.RWMutex
 
 // Message represents a message in SQS queue
 type Message struct {
-	Action     string      `json:"action"`
-	Params     interface{} `json:"params"`
-	SendAtMS   int64       `json:"sendAtMS"`
-	RetryCount int         `json:"retryCount"`
-	MaxRetries int         `json:"maxRetries"`
+	Action      string      `json:"action"`
+	Params      interface{} `json:"params"`
+	SendAtMS    int64       `json:"sendAtMS"`              // Unix milliseconds
+	DeliverAtMS int64       `json:"deliverAtMS,omitempty"` // Unix milliseconds a delayed message is due, 0 without delay
+	RetryCount  int         `json:"retryCount"`
+	MaxRetries  int         `json:"maxRetries"`
+
+	// OriginRegion is the region the message was sent to, set on receive.
+	OriginRegion string `json:"-"`
+
+	// GroupID is the message group of a FIFO queue message, set on receive.
+	GroupID string `json:"-"`
+
+	// Attributes are the String message attributes, excluding the ones this
+	// package sets itself. Sent by SendWithOptions and SendBatch, set on receive.
+	Attributes map[string]string `json:"-"`
+
+	// TraceID identifies the message across producer and consumers. Sent as
+	// the trace_id attribute, set on receive (see TraceID).
+	TraceID string `json:"-"`
+
+	dedupID string
+
+	ctx context.Context
+}
+
+// Context returns the context of a received message. When the message was
+// sent with SendContext it carries the producer's OpenTelemetry span as a
+// remote parent, so log entries and spans in the handler join that trace.
+func (msg *Message) Context() context.Context {
+	if msg.ctx == nil {
+		return context.Background()
+	}
+	return msg.ctx
 }
 
 // ParseParams parses message parameters to specified struct
@@ -48,9 +85,23 @@ func (msg *Message) ParseParams(target interface{}) error {
 
 // Client represents an SQS client instance
 type Client struct {
-	sqs      *sqs.Client
+	sqs      sqsAPI
 	queueUrl string
 	region   string
+
+	fifo         bool
+	contentDedup bool
+	redrive      bool // dlq_name is set: failed messages are left to the redrive policy
+
+	// Failover regions in order (aws.sqs.queues.<name>.failover_regions)
+	failover       []*regionQueue
+	consumeAll     bool
+	probeInterval  time.Duration
+	recoveryProbes int
+
+	failMu        sync.Mutex
+	degraded      bool // Sending via failover regions until the primary recovers
+	healthyProbes int
 }
 
 // Config represents SQS configuration for a specific queue
@@ -60,6 +111,18 @@ type Config struct {
 	UseIMDS   bool   `yaml:"use_imds" json:"use_imds"`
 	Region    string `yaml:"region" json:"region"`
 	QueueName string `yaml:"queue_name" json:"queue_name"`
+
+	// FIFO queue; the ".fifo" suffix is appended to the queue name
+	Fifo                      bool `yaml:"fifo" json:"fifo"`
+	ContentBasedDeduplication bool `yaml:"content_based_deduplication" json:"content_based_deduplication"`
+
+	// Queue visibility timeout; 0 keeps the queue's setting (default 30s)
+	VisibilityTimeoutSeconds int `yaml:"visibility_timeout_seconds" json:"visibility_timeout_seconds"`
+
+	// Dead letter queue receiving messages received more than MaxReceiveCount
+	// times (default 5); empty disables the redrive policy
+	DLQName         string `yaml:"dlq_name" json:"dlq_name"`
+	MaxReceiveCount int    `yaml:"max_receive_count" json:"max_receive_count"`
 }
 
 // loadConfig loads AWS configuration for SQS
@@ -89,7 +152,7 @@ func loadConfig(region string, cfg *Config) (awsv2.Config, error) {
 // 1. aws.sqs.queues.<queueName> - Queue-specific config
 // 2. aws.sqs - SQS service config
 // 3. aws - Global AWS config
-func loadConfigFromViper(queueName string) (*Config, error) {
+func loadConfigFromViper(queueName string) *Config {
 	cfg := &Config{
 		QueueName: queueName,
 	}
@@ -102,6 +165,11 @@ func loadConfigFromViper(queueName string) (*Config, error) {
 		cfg.AccessKey = viper.GetString(queueConfigPath + ".access_key")
 		cfg.SecretKey = viper.GetString(queueConfigPath + ".secret_key")
 		cfg.UseIMDS = viper.GetBool(queueConfigPath + ".use_imds")
+		cfg.Fifo = viper.GetBool(queueConfigPath + ".fifo")
+		cfg.ContentBasedDeduplication = viper.GetBool(queueConfigPath + ".content_based_deduplication")
+		cfg.VisibilityTimeoutSeconds = viper.GetInt(queueConfigPath + ".visibility_timeout_seconds")
+		cfg.DLQName = viper.GetString(queueConfigPath + ".dlq_name")
+		cfg.MaxReceiveCount = viper.GetInt(queueConfigPath + ".max_receive_count")
 	}
 
 	// Fall back to SQS service config for missing values
@@ -132,21 +200,25 @@ func loadConfigFromViper(queueName string) (*Config, error) {
 		cfg.UseIMDS = viper.GetBool("aws.use_imds")
 	}
 
-	// Validate required fields
-	if cfg.Region == "" {
-		return nil, fmt.Errorf("sqs region not configured for queue: %s (check aws.region, aws.sqs.region, or aws.sqs.queues.%s.region)", queueName, queueName)
-	}
-
-	return cfg, nil
+	return cfg
 }
 
 // initSqs initializes SQS client for a specific queue
 // The queueName parameter is used as the queue name and config lookup key
 func initSqs(queueName string) (*Client, error) {
 	// Load config from viper
-	cfg, err := loadConfigFromViper(queueName)
-	if err != nil {
-		return nil, err
+	cfg := loadConfigFromViper(queueName)
+
+	switch backend := viper.GetString("aws.sqs.backend"); {
+	case useMemory || backend == BackendMemory:
+		return initMemory(cfg)
+	case backend != "" && backend != "aws":
+		return nil, fmt.Errorf("unknown sqs backend %q (aws.sqs.backend: aws or memory)", backend)
+	}
+
+	// Validate required fields
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("sqs region not configured for queue: %s (check aws.region, aws.sqs.region, or aws.sqs.queues.%s.region)", queueName, queueName)
 	}
 
 	awsCfg, err := loadConfig(cfg.Region, cfg)
@@ -158,27 +230,71 @@ func initSqs(queueName string) (*Client, error) {
 	ctx := context.Background()
 
 	// Create or get queue
+	name, attributes := queueSpec(cfg)
 	result, err := sqsClient.CreateQueue(ctx, &sqs.CreateQueueInput{
-		QueueName: awsv2.String(queueName),
-		Attributes: map[string]string{
-			string(sqstypes.QueueAttributeNameDelaySeconds):           "0",
-			string(sqstypes.QueueAttributeNameMessageRetentionPeriod): "345600", // 4 days
-		},
+		QueueName:  awsv2.String(name),
+		Attributes: attributes,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("create/get queue error: %v", err)
 	}
+	if err := configureQueue(ctx, sqsClient, *result.QueueUrl, cfg); err != nil {
+		return nil, fmt.Errorf("configure queue error: %v", err)
+	}
+
+	client := &Client{
+		sqs:          sqsClient,
+		queueUrl:     *result.QueueUrl,
+		region:       cfg.Region,
+		fifo:         cfg.Fifo,
+		contentDedup: cfg.ContentBasedDeduplication,
+		redrive:      cfg.DLQName != "",
+	}
+
+	fc := loadFailoverConfig(queueName)
+	client.consumeAll = fc.consumeAll
+	client.probeInterval = fc.probeInterval
+	client.recoveryProbes = fc.recoveryProbes
+	for _, region := range fc.regions {
+		regionCfg, err := loadConfig(region, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("create aws session for failover region %s error: %v", region, err)
+		}
+		client.failover = append(client.failover, &regionQueue{
+			region:     region,
+			name:       name,
+			attributes: attributes,
+			cfg:        cfg,
+			api:        sqs.NewFromConfig(regionCfg),
+			redrive:    cfg.DLQName != "",
+		})
+	}
+
+	return client, nil
+}
 
-	return &Client{
-		sqs:      sqsClient,
-		queueUrl: *result.QueueUrl,
-		region:   cfg.Region,
-	}, nil
+// queueSpec returns the SQS queue name and the attributes to create it with
+func queueSpec(cfg *Config) (string, map[string]string) {
+	name := cfg.QueueName
+	attributes := map[string]string{
+		string(sqstypes.QueueAttributeNameDelaySeconds):           "0",
+		string(sqstypes.QueueAttributeNameMessageRetentionPeriod): "345600", // 4 days
+	}
+	if cfg.Fifo {
+		if !strings.HasSuffix(name, fifoSuffix) {
+			name += fifoSuffix
+		}
+		attributes[string(sqstypes.QueueAttributeNameFifoQueue)] = "true"
+		attributes[string(sqstypes.QueueAttributeNameContentBasedDeduplication)] = strconv.FormatBool(cfg.ContentBasedDeduplication)
+	}
+	return name, attributes
 }
 
 // Get returns SQS client for specified queue
 // Config is automatically loaded from viper configuration file
 // Configuration should be under aws.sqs.queues.<queueName> or aws.sqs (global)
+// With aws.sqs.backend: memory (or after UseMemoryBackend) the queue lives
+// in process memory and no AWS configuration is needed
 func Get(queueName string) (*Client, error) {
 	sqsMux.RLock()
 	client, ok := sqsClients[queueName]
@@ -200,17 +316,21 @@ func Get(queueName string) (*Client, error) {
 }
 
 // sendMessage sends a message to the queue (internal method)
-func (c *Client) sendMessage(msg Message) error {
+func (c *Client) sendMessage(ctx context.Context, msg Message, delaySeconds int32) error {
+	if c.fifo && msg.GroupID == "" {
+		return fmt.Errorf("sqs: queue %s is a FIFO queue, use SendFIFO", c.queueUrl)
+	}
 	msgBt, _ := json.Marshal(msg)
-	ctx := context.Background()
 
-	_, err := c.sqs.SendMessage(ctx, &sqs.SendMessageInput{
-		DelaySeconds: 0,
-		MessageBody:  awsv2.String(string(msgBt)),
-		QueueUrl:     &c.queueUrl,
+	err := c.sendBody(ctx, string(msgBt), sendParams{
+		delaySeconds: delaySeconds,
+		groupID:      msg.GroupID,
+		dedupID:      msg.dedupID,
+		attributes:   msg.Attributes,
+		traceID:      msg.TraceID,
 	})
 	if err != nil {
-		return fmt.Errorf("send message error: %v", err)
+		return fmt.Errorf("send message error: %w", err)
 	}
 	return nil
 }
@@ -220,11 +340,160 @@ func (c *Client) Send(action string, params interface{}) error {
 	msg := Message{
 		Action:     action,
 		Params:     params,
-		SendAtMS:   time.Now().UnixMicro(),
+		SendAtMS:   time.Now().UnixMilli(),
 		RetryCount: 0,
 		MaxRetries: 3,
 	}
-	return c.sendMessage(msg)
+	return c.sendMessage(context.Background(), msg, 0)
+}
+
+// SendContext sends a message like Send, propagating the OpenTelemetry span
+// and the trace ID of ctx to the consumer (see Message.Context).
+func (c *Client) SendContext(ctx context.Context, action string, params interface{}) error {
+	msg := Message{
+		Action:     action,
+		Params:     params,
+		SendAtMS:   time.Now().UnixMilli(),
+		RetryCount: 0,
+		MaxRetries: 3,
+		TraceID:    TraceID(ctx),
+	}
+	return c.sendMessage(ctx, msg, 0)
+}
+
+// SendDelayed sends a message like Send that consumers receive after delay,
+// at most 15 minutes (the SQS limit). The delay is rounded up to whole
+// seconds. Not supported on FIFO queues.
+func (c *Client) SendDelayed(action string, params interface{}, delay time.Duration) error {
+	if delay < 0 {
+		return fmt.Errorf("sqs: negative delay %v", delay)
+	}
+	return c.SendAt(action, params, time.Now().Add(delay))
+}
+
+// SendAt sends a message like Send that consumers receive at the given time,
+// at most 15 minutes from now (the SQS limit); a time in the past sends the
+// message for immediate delivery. Not supported on FIFO queues.
+//
+// Example:
+//
+//	// Remind the user when the trial ends, if that is within 15 minutes
+//	err := client.SendAt("trial.ending", user, trialEnd)
+func (c *Client) SendAt(action string, params interface{}, at time.Time) error {
+	now := time.Now()
+	delaySeconds, err := delayUntil(now, at)
+	if err != nil {
+		return err
+	}
+	msg := Message{
+		Action:      action,
+		Params:      params,
+		SendAtMS:    now.UnixMilli(),
+		DeliverAtMS: at.UnixMilli(),
+		RetryCount:  0,
+		MaxRetries:  3,
+	}
+	return c.sendMessage(context.Background(), msg, delaySeconds)
+}
+
+// delayUntil returns the DelaySeconds delivering a message sent at now no
+// earlier than at, rounding up to whole seconds
+func delayUntil(now, at time.Time) (int32, error) {
+	delay := at.Sub(now)
+	if delay <= 0 {
+		return 0, nil
+	}
+	seconds := (delay + time.Second - 1) / time.Second
+	if seconds > maxDelaySeconds {
+		return 0, fmt.Errorf("sqs: delivery in %v exceeds the maximum delay of %d seconds", delay.Round(time.Second), maxDelaySeconds)
+	}
+	return int32(seconds), nil
+}
+
+// SendOptions are the per-message options of SendWithOptions
+type SendOptions struct {
+	// Attributes are sent as String message attributes, so consumers can
+	// filter without decoding the body. At most 7; the names used by this
+	// package (trace_id, traceparent, origin_region) are reserved.
+	Attributes map[string]string
+
+	// DelaySeconds hides the message from consumers for up to 900 seconds.
+	// Not supported on FIFO queues.
+	DelaySeconds int32
+
+	// DeliverAt delivers the message at the given time instead of after
+	// DelaySeconds, at most 900 seconds from now (see SendAt)
+	DeliverAt time.Time
+
+	// TraceID identifies the message in logs across services; a new xid
+	// when empty
+	TraceID string
+}
+
+// SendWithOptions sends a message like Send with message attributes, a
+// delivery delay and a trace ID.
+//
+// Example:
+//
+//	client.SendWithOptions("order.paid", order, sqs.SendOptions{
+//	    Attributes: map[string]string{"tenant": "acme"},
+//	    TraceID:    requestID,
+//	})
+func (c *Client) SendWithOptions(action string, params interface{}, opts SendOptions) error {
+	if c.fifo {
+		return fmt.Errorf("sqs: queue %s is a FIFO queue, use SendFIFO", c.queueUrl)
+	}
+	if len(opts.Attributes) > maxUserAttributes {
+		return fmt.Errorf("sqs: %d message attributes, at most %d are allowed", len(opts.Attributes), maxUserAttributes)
+	}
+	for name := range opts.Attributes {
+		if isReservedAttribute(name) {
+			return fmt.Errorf("sqs: message attribute %q is reserved", name)
+		}
+	}
+	if opts.DelaySeconds < 0 || opts.DelaySeconds > maxDelaySeconds {
+		return fmt.Errorf("sqs: delay of %d seconds is out of range 0-%d", opts.DelaySeconds, maxDelaySeconds)
+	}
+	now := time.Now()
+	var deliverAtMS int64
+	switch {
+	case !opts.DeliverAt.IsZero():
+		if opts.DelaySeconds != 0 {
+			return fmt.Errorf("sqs: set either DelaySeconds or DeliverAt")
+		}
+		delaySeconds, err := delayUntil(now, opts.DeliverAt)
+		if err != nil {
+			return err
+		}
+		opts.DelaySeconds, deliverAtMS = delaySeconds, opts.DeliverAt.UnixMilli()
+	case opts.DelaySeconds > 0:
+		deliverAtMS = now.Add(time.Duration(opts.DelaySeconds) * time.Second).UnixMilli()
+	}
+	if opts.TraceID == "" {
+		opts.TraceID = xid.New().String()
+	}
+
+	msg := Message{
+		Action:      action,
+		Params:      params,
+		SendAtMS:    now.UnixMilli(),
+		DeliverAtMS: deliverAtMS,
+		RetryCount:  0,
+		MaxRetries:  3,
+		Attributes:  opts.Attributes,
+		TraceID:     opts.TraceID,
+	}
+	msgBt, _ := json.Marshal(msg)
+
+	err := c.sendBody(context.Background(), string(msgBt), sendParams{
+		delaySeconds: opts.DelaySeconds,
+		attributes:   msg.Attributes,
+		traceID:      msg.TraceID,
+	})
+	if err != nil {
+		return fmt.Errorf("send message error: %w", err)
+	}
+	return nil
 }
 
 // SendWithRetry sends a message with custom max retry count
@@ -232,56 +501,197 @@ func (c *Client) SendWithRetry(action string, params interface{}, maxRetries int
 	msg := Message{
 		Action:     action,
 		Params:     params,
-		SendAtMS:   time.Now().UnixMicro(),
+		SendAtMS:   time.Now().UnixMilli(),
 		RetryCount: 0,
 		MaxRetries: maxRetries,
 	}
-	return c.sendMessage(msg)
+	return c.sendMessage(context.Background(), msg, 0)
 }
 
-// retry retries a failed message (internal method)
-func (c *Client) retry(msg Message) error {
-	if msg.RetryCount >= msg.MaxRetries {
-		return fmt.Errorf("message has reached max retries: %d", msg.MaxRetries)
+// SendFIFO sends a message to a FIFO queue. Messages with the same groupID
+// are delivered in order. dedupID deduplicates sends within 5 minutes; it
+// may be empty when content_based_deduplication is enabled.
+func (c *Client) SendFIFO(action string, params interface{}, groupID string, dedupID string) error {
+	if !c.fifo {
+		return fmt.Errorf("sqs: SendFIFO on standard queue %s (set fifo: true in the queue config)", c.queueUrl)
+	}
+	if groupID == "" {
+		return fmt.Errorf("sqs: SendFIFO requires a message group ID")
+	}
+	if dedupID == "" && !c.contentDedup {
+		return fmt.Errorf("sqs: SendFIFO requires a deduplication ID unless content_based_deduplication is enabled")
+	}
+	msg := Message{
+		Action:     action,
+		Params:     params,
+		SendAtMS:   time.Now().UnixMilli(),
+		RetryCount: 0,
+		MaxRetries: 3,
+		GroupID:    groupID,
+		dedupID:    dedupID,
 	}
+	return c.sendMessage(context.Background(), msg, 0)
+}
 
+// retry re-sends a failed message with exponential backoff (internal method).
+// FIFO queues do not support per-message delays: the retry copy is sent to
+// the same message group immediately, so the group stays ordered.
+func (c *Client) retry(msg Message) error {
 	msg.RetryCount++
-	delaySeconds := int32(math.Pow(2, float64(msg.RetryCount-1))) * 60
+	params := sendParams{delaySeconds: retryDelaySeconds(msg.RetryCount)}
+	msg.DeliverAtMS = time.Now().Add(time.Duration(params.delaySeconds) * time.Second).UnixMilli()
+	if c.fifo {
+		params = sendParams{groupID: msg.GroupID, dedupID: retryDedupID(msg.dedupID, msg.RetryCount)}
+		msg.DeliverAtMS = 0
+	}
+	params.attributes, params.traceID = msg.Attributes, msg.TraceID
 
 	msgBt, _ := json.Marshal(msg)
-	ctx := context.Background()
 
-	_, err := c.sqs.SendMessage(ctx, &sqs.SendMessageInput{
-		DelaySeconds: delaySeconds,
-		MessageBody:  awsv2.String(string(msgBt)),
-		QueueUrl:     &c.queueUrl,
-	})
+	// The retry copy keeps the trace of the original message
+	err := c.sendBody(msg.Context(), string(msgBt), params)
 	if err != nil {
-		return fmt.Errorf("retry message error: %v", err)
+		return fmt.Errorf("retry message error: %w", err)
 	}
 	return nil
 }
 
+// retryDedupID derives the deduplication ID of a retry copy, which must
+// differ from the original's to not be dropped as a duplicate. An empty id
+// (content-based deduplication) stays empty: the retry count changes the body.
+func retryDedupID(id string, retry int) string {
+	if id == "" {
+		return ""
+	}
+	suffix := fmt.Sprintf("-retry%d", retry)
+	// Deduplication IDs are limited to 128 characters
+	return id[:min(len(id), maxDedupIDLen-len(suffix))] + suffix
+}
+
+// retryDelaySeconds is the backoff before retry n: 1, 2, 4, 8 minutes, then
+// the SQS maximum of 15 minutes
+func retryDelaySeconds(n int) int32 {
+	return int32(min(math.Pow(2, float64(n-1))*60, maxDelaySeconds))
+}
+
 // MessageHandler is the function type for processing messages
 type MessageHandler func(msg Message) error
 
-// Consume consumes messages from the queue
-func (c *Client) Consume(handler MessageHandler) {
-	ctx := context.Background()
+// ReceivedMessageHandler processes messages with their receipt handle, e.g.
+// to extend the visibility timeout of long-running work (see ChangeVisibility)
+type ReceivedMessageHandler func(msg ReceivedMessage) error
+
+// ContextHandler processes messages like ReceivedMessageHandler, with ctx
+// carrying the message's trace ID (see TraceID) and OpenTelemetry span.
+type ContextHandler func(ctx context.Context, msg ReceivedMessage) error
+
+// Consume consumes messages from the queue until ctx is done.
+// With consume_all_regions enabled, the failover regions are polled
+// concurrently as well, and handler may be called from several goroutines.
+//
+// Cancelling ctx stops polling; messages already received are still handled
+// and settled (deleted or re-sent for retry) before Consume returns nil.
+// Message.Context is derived from ctx, so handlers can observe the shutdown.
+//
+// A failed message is deleted only after its retry copy was sent. If the
+// re-send fails too, the message is left in the queue and is delivered
+// again once its visibility timeout expires. With dlq_name set, a failed
+// message in the primary region is not re-sent but released right away, so
+// it moves to the dead letter queue after max_receive_count receives.
+func (c *Client) Consume(ctx context.Context, handler MessageHandler) error {
+	if handler == nil {
+		return fmt.Errorf("sqs: nil message handler")
+	}
+	return c.ConsumeReceived(ctx, func(msg ReceivedMessage) error {
+		return handler(msg.Message)
+	})
+}
 
-	for {
-		result, err := c.sqs.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
-			QueueUrl:            &c.queueUrl,
+// ConsumeReceived consumes messages like Consume, passing handlers the
+// receipt handle along with the message.
+//
+// Example:
+//
+//	client.ConsumeReceived(ctx, func(msg sqs.ReceivedMessage) error {
+//	    // Keep the message hidden while the export runs
+//	    client.ChangeVisibility(msg.ReceiptHandle, 600)
+//	    return runExport(msg.Context(), msg.Message)
+//	})
+func (c *Client) ConsumeReceived(ctx context.Context, handler ReceivedMessageHandler) error {
+	if handler == nil {
+		return fmt.Errorf("sqs: nil message handler")
+	}
+
+	queues := []*regionQueue{{region: c.region, api: c.sqs, url: c.queueUrl, redrive: c.redrive}}
+	if c.consumeAll {
+		queues = append(queues, c.failover...)
+	}
+
+	var wg sync.WaitGroup
+	for _, q := range queues {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.consumeRegion(ctx, q, handler)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// ConsumeContext consumes messages like ConsumeReceived, passing handlers the
+// message context, so the trace ID flows into logs and into messages sent
+// on with SendContext.
+//
+// Example:
+//
+//	client.ConsumeContext(ctx, func(ctx context.Context, msg sqs.ReceivedMessage) error {
+//	    if msg.Attributes["tenant"] != tenant {
+//	        return nil
+//	    }
+//	    return handleOrder(ctx, msg.Message) // sqs.TraceID(ctx) == msg.TraceID
+//	})
+func (c *Client) ConsumeContext(ctx context.Context, handler ContextHandler) error {
+	if handler == nil {
+		return fmt.Errorf("sqs: nil message handler")
+	}
+	return c.ConsumeReceived(ctx, func(msg ReceivedMessage) error {
+		return handler(msg.Context(), msg)
+	})
+}
+
+// consumeRegion polls one region until ctx is done.
+func (c *Client) consumeRegion(ctx context.Context, q *regionQueue, handler ReceivedMessageHandler) {
+	// Received messages are settled even after ctx is cancelled
+	settleCtx := context.WithoutCancel(ctx)
+
+	for ctx.Err() == nil {
+		queueUrl, err := q.queueURL(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Printf("resolve queue in %s error: %v\n", q.region, err)
+			sleepCtx(ctx, time.Second)
+			continue
+		}
+
+		result, err := q.api.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            &queueUrl,
 			MaxNumberOfMessages: 1,
 			WaitTimeSeconds:     20,
 			AttributeNames: []sqstypes.QueueAttributeName{
 				sqstypes.QueueAttributeNameAll,
 			},
+			MessageAttributeNames: []string{string(sqstypes.QueueAttributeNameAll)},
 		})
 
 		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
 			fmt.Printf("receive message error: %v\n", err)
-			time.Sleep(time.Second)
+			sleepCtx(ctx, time.Second)
 			continue
 		}
 
@@ -291,19 +701,49 @@ func (c *Client) Consume(handler MessageHandler) {
 				fmt.Printf("unmarshal message error: %v\n", err)
 				continue
 			}
+			msg.OriginRegion = q.region
+			if attr, ok := message.MessageAttributes[OriginRegionAttribute]; ok && attr.StringValue != nil {
+				msg.OriginRegion = *attr.StringValue
+			}
+			msg.GroupID = message.Attributes[string(sqstypes.MessageSystemAttributeNameMessageGroupId)]
+			msg.dedupID = message.Attributes[string(sqstypes.MessageSystemAttributeNameMessageDeduplicationId)]
+			decodeAttributes(&msg, message.MessageAttributes)
+			msg.ctx = traceContext(ctx, message.MessageAttributes)
 
 			// Process message
-			if err := handler(msg); err != nil {
-				// If processing failed, retry
-				retryErr := c.retry(msg)
-				if retryErr != nil {
-					fmt.Printf("retry message failed: %v\n", retryErr)
+			err := handler(ReceivedMessage{
+				Message:       msg,
+				MessageID:     awsv2.ToString(message.MessageId),
+				ReceiptHandle: awsv2.ToString(message.ReceiptHandle),
+			})
+			if err != nil && q.redrive {
+				// Make it visible again; the redrive policy counts the receives
+				if _, err := q.api.ChangeMessageVisibility(settleCtx, &sqs.ChangeMessageVisibilityInput{
+					QueueUrl:          &queueUrl,
+					ReceiptHandle:     message.ReceiptHandle,
+					VisibilityTimeout: 0,
+				}); err != nil {
+					fmt.Printf("release message error: %v\n", err)
+				}
+				continue
+			}
+			if err != nil {
+				if msg.RetryCount >= msg.MaxRetries {
+					fmt.Printf("message %s has reached max retries: %d\n", msg.Action, msg.MaxRetries)
+				} else {
+					// Re-send outside ctx so a shutdown does not drop the retry copy
+					msg.ctx = traceContext(settleCtx, message.MessageAttributes)
+					if retryErr := c.retry(msg); retryErr != nil {
+						// Keep the message: it is redelivered after the visibility timeout
+						fmt.Printf("retry message failed: %v\n", retryErr)
+						continue
+					}
 				}
 			}
 
 			// Delete processed message
-			_, err := c.sqs.DeleteMessage(ctx, &sqs.DeleteMessageInput{
-				QueueUrl:      &c.queueUrl,
+			_, err = q.api.DeleteMessage(settleCtx, &sqs.DeleteMessageInput{
+				QueueUrl:      &queueUrl,
 				ReceiptHandle: message.ReceiptHandle,
 			})
 			if err != nil {
@@ -313,6 +753,14 @@ func (c *Client) Consume(handler MessageHandler) {
 	}
 }
 
+// sleepCtx sleeps for d or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+}
+
 // CreateQueue creates a new SQS queue and returns its URL
 func (c *Client) CreateQueue(queueName string) (string, error) {
 	ctx := context.Background()
@@ -376,6 +824,8 @@ func (c *Client) ConsumeQueue(queueUrl string, msgCh chan *Message) {
 			fmt.Printf("error queue message: %v", err)
 			continue
 		}
+		decodeAttributes(msg, msgResult.Messages[0].MessageAttributes)
+		msg.ctx = traceContext(ctx, msgResult.Messages[0].MessageAttributes)
 		msgCh <- msg
 	}
 }
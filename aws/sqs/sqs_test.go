@@ -0,0 +1,853 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeSQS is an in-memory queue for one region.
+type fakeSQS struct {
+	region string
+
+	mu       sync.Mutex
+	sendErr  error
+	probeErr error
+	throttle int // Sends rejected with RequestThrottled before sendErr applies
+	sent     []*sqs.SendMessageInput
+	inbox    []sqstypes.Message
+	deleted  []string
+
+	batchFail map[string]string // Message body -> error code for batch sends
+	batches   int
+
+	created    map[string]map[string]string // Queue name -> creation attributes
+	attributes map[string]map[string]string // Queue URL -> attributes set later
+	visibility map[string]int32             // Receipt handle -> visibility timeout
+}
+
+func (f *fakeSQS) SendMessage(ctx context.Context, in *sqs.SendMessageInput, _ ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.throttle > 0 {
+		f.throttle--
+		return nil, errThrottled
+	}
+	if f.sendErr != nil {
+		return nil, f.sendErr
+	}
+	f.sent = append(f.sent, in)
+	return &sqs.SendMessageOutput{}, nil
+}
+
+func (f *fakeSQS) ReceiveMessage(ctx context.Context, in *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	f.mu.Lock()
+	if len(f.inbox) > 0 {
+		msgs := f.inbox
+		f.inbox = nil
+		f.mu.Unlock()
+		return &sqs.ReceiveMessageOutput{Messages: msgs}, nil
+	}
+	f.mu.Unlock()
+	// Long polling: wait briefly for more messages or cancellation
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(5 * time.Millisecond):
+		return &sqs.ReceiveMessageOutput{}, nil
+	}
+}
+
+func (f *fakeSQS) DeleteMessage(ctx context.Context, in *sqs.DeleteMessageInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, *in.ReceiptHandle)
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+// SendMessageBatch accepts every entry except those listed in batchFail.
+func (f *fakeSQS) SendMessageBatch(ctx context.Context, in *sqs.SendMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.sendErr != nil {
+		return nil, f.sendErr
+	}
+	f.batches++
+	out := &sqs.SendMessageBatchOutput{}
+	for _, e := range in.Entries {
+		if code, ok := f.batchFail[*e.MessageBody]; ok {
+			out.Failed = append(out.Failed, sqstypes.BatchResultErrorEntry{Id: e.Id, Code: awsv2.String(code), SenderFault: true})
+			continue
+		}
+		f.sent = append(f.sent, &sqs.SendMessageInput{MessageBody: e.MessageBody, MessageAttributes: e.MessageAttributes})
+		out.Successful = append(out.Successful, sqstypes.SendMessageBatchResultEntry{Id: e.Id})
+	}
+	return out, nil
+}
+
+func (f *fakeSQS) DeleteMessageBatch(ctx context.Context, in *sqs.DeleteMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches++
+	out := &sqs.DeleteMessageBatchOutput{}
+	for _, e := range in.Entries {
+		if *e.ReceiptHandle == "expired" {
+			out.Failed = append(out.Failed, sqstypes.BatchResultErrorEntry{Id: e.Id, Code: awsv2.String("ReceiptHandleIsInvalid")})
+			continue
+		}
+		f.deleted = append(f.deleted, *e.ReceiptHandle)
+	}
+	return out, nil
+}
+
+func (f *fakeSQS) CreateQueue(ctx context.Context, in *sqs.CreateQueueInput, _ ...func(*sqs.Options)) (*sqs.CreateQueueOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.created == nil {
+		f.created = make(map[string]map[string]string)
+	}
+	f.created[*in.QueueName] = in.Attributes
+	return &sqs.CreateQueueOutput{QueueUrl: awsv2.String("https://sqs." + f.region + ".amazonaws.com/1/" + *in.QueueName)}, nil
+}
+
+func (f *fakeSQS) DeleteQueue(ctx context.Context, in *sqs.DeleteQueueInput, _ ...func(*sqs.Options)) (*sqs.DeleteQueueOutput, error) {
+	return &sqs.DeleteQueueOutput{}, nil
+}
+
+func (f *fakeSQS) GetQueueAttributes(ctx context.Context, in *sqs.GetQueueAttributesInput, _ ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name := (*in.QueueUrl)[strings.LastIndex(*in.QueueUrl, "/")+1:]
+	return &sqs.GetQueueAttributesOutput{
+		Attributes: map[string]string{"QueueArn": "arn:aws:sqs:" + f.region + ":1:" + name},
+	}, f.probeErr
+}
+
+func (f *fakeSQS) SetQueueAttributes(ctx context.Context, in *sqs.SetQueueAttributesInput, _ ...func(*sqs.Options)) (*sqs.SetQueueAttributesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.attributes == nil {
+		f.attributes = make(map[string]map[string]string)
+	}
+	f.attributes[*in.QueueUrl] = in.Attributes
+	return &sqs.SetQueueAttributesOutput{}, nil
+}
+
+func (f *fakeSQS) ChangeMessageVisibility(ctx context.Context, in *sqs.ChangeMessageVisibilityInput, _ ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.visibility == nil {
+		f.visibility = make(map[string]int32)
+	}
+	f.visibility[*in.ReceiptHandle] = in.VisibilityTimeout
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+func (f *fakeSQS) set(sendErr, probeErr error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sendErr, f.probeErr = sendErr, probeErr
+}
+
+func (f *fakeSQS) sentCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sent)
+}
+
+// newFailoverClient builds a client for queue "events" with a us-east-1
+// primary and the given failover regions.
+func newFailoverClient(regions ...string) (*Client, map[string]*fakeSQS) {
+	fakes := map[string]*fakeSQS{"us-east-1": {region: "us-east-1"}}
+	c := &Client{
+		sqs:            fakes["us-east-1"],
+		queueUrl:       "https://sqs.us-east-1.amazonaws.com/1/events",
+		region:         "us-east-1",
+		probeInterval:  time.Hour, // Probes are driven by the tests
+		recoveryProbes: 3,
+	}
+	for _, r := range regions {
+		fakes[r] = &fakeSQS{region: r}
+		c.failover = append(c.failover, &regionQueue{region: r, name: "events", api: fakes[r]})
+	}
+	return c, fakes
+}
+
+var (
+	errUnavailable = &smithy.GenericAPIError{Code: "ServiceUnavailable", Message: "down", Fault: smithy.FaultServer}
+	errConnection  = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	errValidation  = &smithy.GenericAPIError{Code: "InvalidParameterValue", Message: "bad", Fault: smithy.FaultClient}
+	errThrottled   = &smithy.GenericAPIError{Code: "RequestThrottled", Message: "slow down", Fault: smithy.FaultServer}
+)
+
+func originRegion(in *sqs.SendMessageInput) string {
+	return *in.MessageAttributes[OriginRegionAttribute].StringValue
+}
+
+func TestFailoverOnErrorClasses(t *testing.T) {
+	for name, err := range map[string]error{"unavailable": errUnavailable, "connection": errConnection} {
+		t.Run(name, func(t *testing.T) {
+			c, fakes := newFailoverClient("us-west-2", "eu-west-1")
+			fakes["us-east-1"].set(err, err)
+			fakes["us-west-2"].set(errUnavailable, nil)
+
+			if sendErr := c.Send("user.registered", map[string]int{"id": 1}); sendErr != nil {
+				t.Fatalf("Send should fail over, got %v", sendErr)
+			}
+			eu := fakes["eu-west-1"]
+			if eu.sentCount() != 1 || originRegion(eu.sent[0]) != "eu-west-1" {
+				t.Fatalf("expected message in eu-west-1 stamped with its region, got %d", eu.sentCount())
+			}
+			if *eu.sent[0].QueueUrl != "https://sqs.eu-west-1.amazonaws.com/1/events" {
+				t.Errorf("failover queue URL not resolved: %s", *eu.sent[0].QueueUrl)
+			}
+			if !c.degraded {
+				t.Error("primary failure should switch sending to failover regions")
+			}
+		})
+	}
+}
+
+func TestNoFailoverOnValidationError(t *testing.T) {
+	c, fakes := newFailoverClient("us-west-2")
+	fakes["us-east-1"].set(errValidation, nil)
+
+	err := c.Send("user.registered", nil)
+	if err == nil || !errors.Is(err, errValidation) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	if fakes["us-west-2"].sentCount() != 0 || c.degraded {
+		t.Error("validation errors must not fail over")
+	}
+}
+
+func TestThrottlingRetriesInRegion(t *testing.T) {
+	defer func(d time.Duration) { throttleBackoff = d }(throttleBackoff)
+	throttleBackoff = time.Millisecond
+
+	c, fakes := newFailoverClient("us-west-2")
+	fakes["us-east-1"].throttle = 2
+	if err := c.Send("user.registered", nil); err != nil {
+		t.Fatalf("throttled send should be retried, got %v", err)
+	}
+	if fakes["us-east-1"].sentCount() != 1 || fakes["us-west-2"].sentCount() != 0 || c.degraded {
+		t.Error("throttling must not fail over")
+	}
+
+	fakes["us-east-1"].throttle = throttleRetries + 1
+	if err := c.Send("user.registered", nil); !errors.Is(err, errThrottled) {
+		t.Fatalf("expected the throttling error once retries are used up, got %v", err)
+	}
+	if fakes["us-west-2"].sentCount() != 0 || c.degraded {
+		t.Error("throttling must not fail over")
+	}
+}
+
+func TestFailoverQueueRedrive(t *testing.T) {
+	c, fakes := newFailoverClient("us-west-2")
+	c.failover[0].cfg = &Config{QueueName: "events", DLQName: "events-dlq", MaxReceiveCount: 3}
+	fakes["us-east-1"].set(errUnavailable, nil)
+
+	if err := c.Send("user.registered", nil); err != nil {
+		t.Fatalf("Send should fail over, got %v", err)
+	}
+	west := fakes["us-west-2"]
+	if _, ok := west.created["events-dlq"]; !ok {
+		t.Fatal("dead letter queue not created in the failover region")
+	}
+	policy := west.attributes["https://sqs.us-west-2.amazonaws.com/1/events"][string(sqstypes.QueueAttributeNameRedrivePolicy)]
+	if !strings.Contains(policy, "arn:aws:sqs:us-west-2:1:events-dlq") || !strings.Contains(policy, `"maxReceiveCount":"3"`) {
+		t.Errorf("unexpected redrive policy in the failover region: %q", policy)
+	}
+}
+
+func TestFailoverAllRegionsDown(t *testing.T) {
+	c, fakes := newFailoverClient("us-west-2")
+	fakes["us-east-1"].set(errConnection, errConnection)
+	fakes["us-west-2"].set(errUnavailable, nil)
+
+	err := c.Send("user.registered", nil)
+	if !errors.Is(err, errConnection) || !errors.Is(err, errUnavailable) {
+		t.Fatalf("expected errors from every region, got %v", err)
+	}
+}
+
+func TestRecoveryWithHysteresis(t *testing.T) {
+	c, fakes := newFailoverClient("us-west-2")
+	primary := fakes["us-east-1"]
+	primary.set(errUnavailable, errUnavailable)
+
+	c.Send("a", nil)
+	if !c.degraded {
+		t.Fatal("expected degraded after primary failure")
+	}
+
+	// While degraded the primary is skipped entirely
+	primary.set(nil, errUnavailable)
+	c.Send("b", nil)
+	if primary.sentCount() != 0 || fakes["us-west-2"].sentCount() != 2 {
+		t.Fatalf("degraded sends should go to failover: primary=%d failover=%d",
+			primary.sentCount(), fakes["us-west-2"].sentCount())
+	}
+
+	// Two healthy probes, then a failure, resets the count
+	primary.set(nil, nil)
+	c.probe(context.Background())
+	c.probe(context.Background())
+	primary.set(nil, errUnavailable)
+	if c.probe(context.Background()) {
+		t.Fatal("failed probe must not recover")
+	}
+	primary.set(nil, nil)
+	c.probe(context.Background())
+	c.probe(context.Background())
+	if !c.degraded {
+		t.Fatal("should still be degraded before 3 consecutive healthy probes")
+	}
+	if !c.probe(context.Background()) || c.degraded {
+		t.Fatal("expected recovery after 3 consecutive healthy probes")
+	}
+
+	c.Send("c", nil)
+	if primary.sentCount() != 1 || originRegion(primary.sent[0]) != "us-east-1" {
+		t.Errorf("sending should flip back to the primary, got %d", primary.sentCount())
+	}
+}
+
+func TestConsumeAllRegions(t *testing.T) {
+	c, fakes := newFailoverClient("us-west-2")
+	c.consumeAll = true
+
+	region := func(r string) map[string]sqstypes.MessageAttributeValue {
+		return map[string]sqstypes.MessageAttributeValue{
+			OriginRegionAttribute: {DataType: awsv2.String("String"), StringValue: awsv2.String(r)},
+		}
+	}
+	fakes["us-east-1"].inbox = []sqstypes.Message{
+		{Body: awsv2.String(`{"action":"a"}`), ReceiptHandle: awsv2.String("r1"), MessageAttributes: region("us-east-1")},
+	}
+	fakes["us-west-2"].inbox = []sqstypes.Message{
+		{Body: awsv2.String(`{"action":"b"}`), ReceiptHandle: awsv2.String("r2"), MessageAttributes: region("us-west-2")},
+		{Body: awsv2.String(`{"action":"c"}`), ReceiptHandle: awsv2.String("r3")},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	var got []string
+	done := make(chan struct{})
+	go func() {
+		c.Consume(ctx, func(msg Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, msg.Action+"@"+msg.OriginRegion)
+			if len(got) == 3 {
+				cancel()
+			}
+			return nil
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		cancel()
+		t.Fatal("consume did not receive messages from all regions")
+	}
+
+	sort.Strings(got)
+	want := []string{"a@us-east-1", "b@us-west-2", "c@us-west-2"}
+	for i := range want {
+		if i >= len(got) || got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	if len(fakes["us-west-2"].deleted) != 2 || fakes["us-east-1"].deleted[0] != "r1" {
+		t.Errorf("messages should be deleted from their own region: %v %v",
+			fakes["us-east-1"].deleted, fakes["us-west-2"].deleted)
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	c := newMemoryClient(t, "events", nil)
+
+	traceID := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	spanID := trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+	spanCtx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled,
+	}))
+
+	if err := c.SendContext(spanCtx, "user.registered", map[string]int{"id": 1}); err != nil {
+		t.Fatalf("SendContext failed: %v", err)
+	}
+	if err := c.Send("user.deleted", nil); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	sent := queued(c)
+	if tp := *sent[0].attributes[TraceParentAttribute].StringValue; tp != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("unexpected traceparent %q", tp)
+	}
+	if sent[1].attributes != nil {
+		t.Errorf("untraced send should carry no attributes, got %v", sent[1].attributes)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	got := map[string]trace.SpanContext{}
+	c.Consume(ctx, func(msg Message) error {
+		got[msg.Action] = trace.SpanContextFromContext(msg.Context())
+		if len(got) == 2 {
+			cancel()
+		}
+		return errors.New("fail once") // Re-sent as a retry copy
+	})
+
+	if sc := got["user.registered"]; sc.TraceID() != traceID || sc.SpanID() != spanID || !sc.IsRemote() {
+		t.Errorf("span not restored as remote parent: %+v", sc)
+	}
+	if got["user.deleted"].IsValid() {
+		t.Errorf("untraced message should have no span, got %+v", got["user.deleted"])
+	}
+	if retry := queued(c)[0]; retry.attributes[TraceParentAttribute].StringValue == nil {
+		t.Error("retry copy should keep the trace context")
+	}
+}
+
+func TestSendWithOptions(t *testing.T) {
+	c := newMemoryClient(t, "events", nil)
+
+	before := time.Now().UnixMilli()
+	err := c.SendWithOptions("order.paid", map[string]int{"id": 1}, SendOptions{
+		Attributes:   map[string]string{"tenant": "acme"},
+		DelaySeconds: 30,
+	})
+	if err != nil {
+		t.Fatalf("SendWithOptions failed: %v", err)
+	}
+	in := queued(c)[0]
+	traceID := awsv2.ToString(in.attributes[TraceIDAttribute].StringValue)
+	if awsv2.ToString(in.attributes["tenant"].StringValue) != "acme" || len(traceID) != 20 || in.visibleAt.Sub(in.sentAt) != 30*time.Second {
+		t.Fatalf("unexpected message: visible at %v, attributes %v", in.visibleAt, in.attributes)
+	}
+
+	for name, opts := range map[string]SendOptions{
+		"reserved": {Attributes: map[string]string{TraceIDAttribute: "x"}},
+		"too many": {Attributes: map[string]string{"a": "", "b": "", "c": "", "d": "", "e": "", "f": "", "g": "", "h": ""}},
+		"too late": {DelaySeconds: 901},
+	} {
+		if err := c.SendWithOptions("order.paid", nil, opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	advance(30 * time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	var got ReceivedMessage
+	var ctxTraceID string
+	c.ConsumeContext(ctx, func(ctx context.Context, msg ReceivedMessage) error {
+		got, ctxTraceID = msg, TraceID(ctx)
+		cancel()
+		return errors.New("fail once") // Re-sent as a retry copy
+	})
+
+	if got.TraceID != traceID || ctxTraceID != traceID || len(got.Attributes) != 1 || got.Attributes["tenant"] != "acme" {
+		t.Errorf("attributes not received: trace %q/%q, %v", got.TraceID, ctxTraceID, got.Attributes)
+	}
+	if got.SendAtMS < before || got.SendAtMS > time.Now().UnixMilli() {
+		t.Errorf("SendAtMS should be in milliseconds, got %d", got.SendAtMS)
+	}
+	retry := queued(c)[0]
+	if awsv2.ToString(retry.attributes[TraceIDAttribute].StringValue) != traceID ||
+		awsv2.ToString(retry.attributes["tenant"].StringValue) != "acme" {
+		t.Errorf("retry copy should keep the attributes, got %v", retry.attributes)
+	}
+
+	// SendContext continues the trace of a handler's ctx
+	c.SendContext(WithTraceID(context.Background(), traceID), "order.shipped", nil)
+	if next := queued(c)[1]; awsv2.ToString(next.attributes[TraceIDAttribute].StringValue) != traceID {
+		t.Errorf("SendContext should send the ctx trace ID, got %v", next.attributes)
+	}
+}
+
+func TestSendDelayed(t *testing.T) {
+	primary := &fakeSQS{region: "us-east-1"}
+	c := &Client{sqs: primary, queueUrl: "https://sqs.us-east-1.amazonaws.com/1/events", region: "us-east-1"}
+
+	now := time.Now()
+	sends := []struct {
+		name  string
+		send  func() error
+		delay int32
+	}{
+		{"delayed", func() error { return c.SendDelayed("a", nil, 90*time.Second) }, 90},
+		{"rounded up", func() error { return c.SendDelayed("a", nil, 1500*time.Millisecond) }, 2},
+		{"at the limit", func() error { return c.SendDelayed("a", nil, 15*time.Minute) }, 900},
+		{"at", func() error { return c.SendAt("a", nil, now.Add(5*time.Minute)) }, 300},
+		{"at in the past", func() error { return c.SendAt("a", nil, now.Add(-time.Minute)) }, 0},
+		{"options", func() error { return c.SendWithOptions("a", nil, SendOptions{DeliverAt: now.Add(time.Minute)}) }, 60},
+	}
+	for _, tt := range sends {
+		primary.sent = nil
+		if err := tt.send(); err != nil {
+			t.Fatalf("%s: send failed: %v", tt.name, err)
+		}
+		in := primary.sent[0]
+		if in.DelaySeconds != tt.delay {
+			t.Errorf("%s: expected DelaySeconds %d, got %d", tt.name, tt.delay, in.DelaySeconds)
+		}
+		var msg Message
+		json.Unmarshal([]byte(*in.MessageBody), &msg)
+		due := msg.SendAtMS + int64(tt.delay)*1000
+		if tt.delay > 0 && (msg.DeliverAtMS > due || msg.DeliverAtMS < due-1000) {
+			t.Errorf("%s: DeliverAtMS %d should be due within the delay, sent at %d", tt.name, msg.DeliverAtMS, msg.SendAtMS)
+		}
+	}
+
+	primary.sent = nil
+	for name, send := range map[string]func() error{
+		"negative":       func() error { return c.SendDelayed("a", nil, -time.Second) },
+		"beyond limit":   func() error { return c.SendDelayed("a", nil, 15*time.Minute+time.Second) },
+		"at beyond":      func() error { return c.SendAt("a", nil, time.Now().Add(time.Hour)) },
+		"options beyond": func() error { return c.SendWithOptions("a", nil, SendOptions{DeliverAt: time.Now().Add(time.Hour)}) },
+		"both options": func() error {
+			return c.SendWithOptions("a", nil, SendOptions{DelaySeconds: 5, DeliverAt: time.Now().Add(time.Minute)})
+		},
+	} {
+		if err := send(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if len(primary.sent) != 0 {
+		t.Errorf("invalid delays should not be sent, sent %d", len(primary.sent))
+	}
+}
+
+func TestRetryDelayCapped(t *testing.T) {
+	primary := &fakeSQS{region: "us-east-1"}
+	c := &Client{sqs: primary, queueUrl: "https://sqs.us-east-1.amazonaws.com/1/events", region: "us-east-1"}
+
+	for retryCount, want := range []int32{60, 120, 240, 480, 900, 900, 900} {
+		primary.sent = nil
+		if err := c.retry(Message{Action: "a", RetryCount: retryCount, MaxRetries: 10}); err != nil {
+			t.Fatalf("retry failed: %v", err)
+		}
+		if got := primary.sent[0].DelaySeconds; got != want {
+			t.Errorf("retry %d: expected DelaySeconds %d, got %d", retryCount+1, want, got)
+		}
+	}
+	if got := retryDelaySeconds(100); got != maxDelaySeconds {
+		t.Errorf("expected large retry counts capped at %d, got %d", maxDelaySeconds, got)
+	}
+}
+
+func TestConsumeStopsOnCancel(t *testing.T) {
+	primary := &fakeSQS{region: "us-east-1"}
+	c := &Client{sqs: primary, queueUrl: "https://sqs.us-east-1.amazonaws.com/1/events", region: "us-east-1"}
+	primary.inbox = []sqstypes.Message{
+		{Body: awsv2.String(`{"action":"a","maxRetries":3}`), ReceiptHandle: awsv2.String("r1")},
+		{Body: awsv2.String(`{"action":"b","maxRetries":3}`), ReceiptHandle: awsv2.String("r2")},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var handled []string
+	done := make(chan error)
+	go func() {
+		done <- c.Consume(ctx, func(msg Message) error {
+			handled = append(handled, msg.Action)
+			cancel() // Shutdown while the batch is in flight
+			if msg.Action == "b" {
+				return msg.Context().Err()
+			}
+			return nil
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Consume returned %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Consume did not stop after cancel")
+	}
+
+	if len(handled) != 2 {
+		t.Fatalf("the current batch should be finished, handled %v", handled)
+	}
+	if len(primary.deleted) != 2 {
+		t.Errorf("in-flight messages should be settled after cancel, deleted %v", primary.deleted)
+	}
+	if len(primary.sent) != 1 || !strings.Contains(*primary.sent[0].MessageBody, `"retryCount":1`) {
+		t.Errorf("failed message should be re-sent for retry, sent %d", len(primary.sent))
+	}
+}
+
+func TestConsumeRetryBeforeDelete(t *testing.T) {
+	primary := &fakeSQS{region: "us-east-1"}
+	c := &Client{sqs: primary, queueUrl: "https://sqs.us-east-1.amazonaws.com/1/events", region: "us-east-1"}
+	primary.set(errValidation, nil)
+	primary.inbox = []sqstypes.Message{
+		{Body: awsv2.String(`{"action":"retry","maxRetries":3}`), ReceiptHandle: awsv2.String("r1")},
+		{Body: awsv2.String(`{"action":"exhausted","retryCount":3,"maxRetries":3}`), ReceiptHandle: awsv2.String("r2")},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := 0
+	c.Consume(ctx, func(msg Message) error {
+		if n++; n == 2 {
+			cancel()
+		}
+		return errors.New("handler failed")
+	})
+
+	// r1 stays in the queue since its retry copy could not be sent;
+	// r2 has no retries left and is dropped without a re-send
+	if len(primary.deleted) != 1 || primary.deleted[0] != "r2" {
+		t.Errorf("only the exhausted message should be deleted, got %v", primary.deleted)
+	}
+}
+
+func TestSendBatch(t *testing.T) {
+	primary := &fakeSQS{region: "us-east-1"}
+	c := &Client{sqs: primary, queueUrl: "https://sqs.us-east-1.amazonaws.com/1/events", region: "us-east-1"}
+
+	msgs := make([]Message, 23)
+	for i := range msgs {
+		msgs[i] = Message{Action: "event", Params: i, SendAtMS: 1}
+	}
+	msgs[4].Params = func() {} // Cannot be marshaled
+	primary.batchFail = map[string]string{`{"action":"event","params":12,"sendAtMS":1,"retryCount":0,"maxRetries":3}`: "InvalidParameterValue"}
+
+	result, err := c.SendBatch(msgs)
+	if err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+	if primary.batches != 3 || result.Sent != 21 {
+		t.Errorf("expected 21 messages sent in 3 requests, got %d in %d", result.Sent, primary.batches)
+	}
+	if len(result.Failed) != 2 || result.Failed[0].ID != "4" || result.Failed[0].Code != "MarshalError" ||
+		result.Failed[1].ID != "12" || result.Failed[1].Code != "InvalidParameterValue" {
+		t.Errorf("unexpected failures: %+v", result.Failed)
+	}
+
+	// A request failing as a whole fails all its entries
+	primary.set(errUnavailable, nil)
+	result, err = c.SendBatch(msgs[:3])
+	if !errors.Is(err, errUnavailable) || len(result.Failed) != 3 || result.Failed[0].Code != "ServiceUnavailable" || result.Failed[0].SenderFault {
+		t.Errorf("unexpected result for failed request: %+v, %v", result, err)
+	}
+}
+
+func TestReceiveAndDeleteBatch(t *testing.T) {
+	c := newMemoryClient(t, "events", nil)
+	for _, body := range []string{`{"action":"a","params":{"id":1}}`, `not json`, `{"action":"b"}`} {
+		c.sqs.SendMessage(context.Background(), &sqs.SendMessageInput{QueueUrl: &c.queueUrl, MessageBody: awsv2.String(body)})
+	}
+
+	msgs, err := c.ReceiveBatch(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("ReceiveBatch failed: %v", err)
+	}
+	sent := queued(c)
+	if len(msgs) != 2 || msgs[0].Action != "a" || msgs[0].MessageID != sent[0].id || msgs[0].ReceiptHandle != sent[0].receipt || msgs[1].ReceiptHandle != sent[2].receipt {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+	var params struct{ ID int }
+	if err := msgs[0].ParseParams(&params); err != nil || params.ID != 1 {
+		t.Errorf("ParseParams: %v %+v", err, params)
+	}
+
+	// More handles than fit in one request
+	for i := range 10 {
+		c.Send(fmt.Sprint("event", i), nil)
+	}
+	more, _ := c.ReceiveBatch(context.Background(), 10)
+	handles := []string{msgs[0].ReceiptHandle, msgs[1].ReceiptHandle}
+	for _, msg := range more {
+		handles = append(handles, msg.ReceiptHandle)
+	}
+	handles = append(handles, "expired")
+	err = c.DeleteBatch(handles)
+	if err == nil || !strings.Contains(err.Error(), "expired") || !strings.Contains(err.Error(), "ReceiptHandleIsInvalid") {
+		t.Errorf("expected failure for the expired handle, got %v", err)
+	}
+	if left := queued(c); len(handles) != 13 || len(left) != 1 || left[0].body != "not json" {
+		t.Errorf("expected 12 deletes, %d messages left", len(left))
+	}
+}
+
+func TestFifoQueueSpec(t *testing.T) {
+	name, attrs := queueSpec(&Config{QueueName: "orders", Fifo: true, ContentBasedDeduplication: true})
+	if name != "orders.fifo" || attrs["FifoQueue"] != "true" || attrs["ContentBasedDeduplication"] != "true" {
+		t.Errorf("unexpected FIFO queue spec: %s %v", name, attrs)
+	}
+	if name, _ := queueSpec(&Config{QueueName: "orders.fifo", Fifo: true}); name != "orders.fifo" {
+		t.Errorf("suffix should not be appended twice: %s", name)
+	}
+	if name, attrs := queueSpec(&Config{QueueName: "events"}); name != "events" || attrs["FifoQueue"] != "" {
+		t.Errorf("standard queue should have no FIFO attributes: %s %v", name, attrs)
+	}
+}
+
+func TestSendFIFO(t *testing.T) {
+	standard := newMemoryClient(t, "events", nil)
+	if err := standard.SendFIFO("order.paid", nil, "order-1", "d1"); err == nil || !strings.Contains(err.Error(), "standard queue") {
+		t.Errorf("SendFIFO on a standard queue should fail, got %v", err)
+	}
+
+	viper.Set("aws.sqs.queues.orders", map[string]any{"fifo": true})
+	viper.Set("aws.sqs.queues.receipts", map[string]any{"fifo": true, "content_based_deduplication": true})
+	defer viper.Set("aws.sqs.queues", nil)
+	fifo, err := Get("orders")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if err := fifo.Send("order.paid", nil); err == nil {
+		t.Error("Send without a group ID should fail on a FIFO queue")
+	}
+	if err := fifo.SendFIFO("order.paid", nil, "", "d1"); err == nil {
+		t.Error("SendFIFO without a group ID should fail")
+	}
+	if err := fifo.SendFIFO("order.paid", nil, "order-1", ""); err == nil {
+		t.Error("SendFIFO without a deduplication ID should fail without content-based deduplication")
+	}
+	if _, err := fifo.SendBatch([]Message{{Action: "order.paid"}}); err == nil {
+		t.Error("SendBatch should fail on a FIFO queue")
+	}
+	if len(queued(fifo)) != 0 {
+		t.Fatalf("invalid sends reached SQS: %d", len(queued(fifo)))
+	}
+
+	if err := fifo.SendFIFO("order.paid", map[string]int{"id": 1}, "order-1", "d1"); err != nil {
+		t.Fatalf("SendFIFO failed: %v", err)
+	}
+	fifo.SendFIFO("order.paid", map[string]int{"id": 1}, "order-1", "d1")
+	if sent := queued(fifo); len(sent) != 1 || sent[0].groupID != "order-1" || sent[0].dedupID != "d1" || sent[0].visibleAt != sent[0].sentAt {
+		t.Errorf("unexpected FIFO sends: %d", len(sent))
+	}
+
+	receipts, err := Get("receipts")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if err := receipts.SendFIFO("order.paid", nil, "order-1", ""); err != nil || len(queued(receipts)) != 1 {
+		t.Errorf("content-based deduplication should allow an empty ID: %v", err)
+	}
+}
+
+func TestFifoRetryKeepsGroup(t *testing.T) {
+	c := newMemoryClient(t, "orders", map[string]any{"fifo": true})
+	c.SendFIFO("order.paid", nil, "order-1", "d1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Consume(ctx, func(msg Message) error {
+		if msg.GroupID != "order-1" {
+			t.Errorf("group ID not set on receive: %q", msg.GroupID)
+		}
+		cancel()
+		return errors.New("handler failed")
+	})
+
+	sent := queued(c)
+	if len(sent) != 1 || sent[0].receiveCount != 0 {
+		t.Fatalf("expected a retry copy, got %d messages", len(sent))
+	}
+	if retry := sent[0]; retry.groupID != "order-1" || retry.dedupID != "d1-retry1" || retry.visibleAt != retry.sentAt {
+		t.Errorf("retry copy should stay in the group without a delay: %+v", retry)
+	}
+	if id := retryDedupID(strings.Repeat("x", 128), 2); len(id) != 128 || !strings.HasSuffix(id, "-retry2") {
+		t.Errorf("retry deduplication ID should fit 128 characters: %s", id)
+	}
+}
+
+func TestConfigureQueue(t *testing.T) {
+	f := &fakeSQS{region: "us-east-1"}
+	url := "https://sqs.us-east-1.amazonaws.com/1/orders.fifo"
+	err := configureQueue(context.Background(), f, url, &Config{
+		Fifo:                     true,
+		VisibilityTimeoutSeconds: 120,
+		DLQName:                  "orders-dlq",
+		MaxReceiveCount:          3,
+	})
+	if err != nil {
+		t.Fatalf("configureQueue failed: %v", err)
+	}
+	if dlq, ok := f.created["orders-dlq.fifo"]; !ok || dlq["FifoQueue"] != "true" {
+		t.Errorf("FIFO dead letter queue not created: %v", f.created)
+	}
+	attrs := f.attributes[url]
+	if attrs["VisibilityTimeout"] != "120" ||
+		attrs["RedrivePolicy"] != `{"deadLetterTargetArn":"arn:aws:sqs:us-east-1:1:orders-dlq.fifo","maxReceiveCount":"3"}` {
+		t.Errorf("unexpected queue attributes: %v", attrs)
+	}
+
+	// Defaults: no attributes without config, maxReceiveCount 5 with a DLQ
+	f = &fakeSQS{region: "us-east-1"}
+	if err := configureQueue(context.Background(), f, url, &Config{}); err != nil || f.attributes != nil {
+		t.Errorf("nothing should be set without config: %v %v", err, f.attributes)
+	}
+	configureQueue(context.Background(), f, url, &Config{DLQName: "dlq"})
+	if p := f.attributes[url]["RedrivePolicy"]; !strings.Contains(p, `"maxReceiveCount":"5"`) || !strings.Contains(p, ":dlq\"") {
+		t.Errorf("unexpected default redrive policy: %s", p)
+	}
+}
+
+func TestConsumeReceivedVisibility(t *testing.T) {
+	c := newMemoryClient(t, "events", nil)
+	c.Send("export", nil)
+	id := queued(c)[0].id
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.ConsumeReceived(ctx, func(msg ReceivedMessage) error {
+		defer cancel()
+		if msg.Action != "export" || msg.MessageID != id || msg.ReceiptHandle == "" {
+			t.Errorf("unexpected message: %+v", msg)
+		}
+		if err := c.ChangeVisibility(msg.ReceiptHandle, 600); err != nil {
+			return err
+		}
+		if m := queued(c)[0]; m.visibleAt.Sub(memory.now()) < 599*time.Second {
+			t.Errorf("visibility not extended: %v", m.visibleAt)
+		}
+		return nil
+	})
+
+	if left := queued(c); len(left) != 0 {
+		t.Errorf("message should be deleted after handling: %d left", len(left))
+	}
+}
+
+func TestMessageAttributesNoAllocs(t *testing.T) {
+	ctx := context.Background()
+	if allocs := testing.AllocsPerRun(100, func() { messageAttributes(ctx, "") }); allocs != 0 {
+		t.Errorf("expected no allocations without a span, got %v", allocs)
+	}
+}
+
+func BenchmarkMessageAttributesNoop(b *testing.B) {
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		messageAttributes(ctx, "")
+	}
+}
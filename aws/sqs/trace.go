@@ -0,0 +1,116 @@
+package sqs
+
+import (
+	"context"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceParentAttribute is the message attribute carrying the W3C traceparent
+// of the span active when the message was sent (see SendContext).
+const TraceParentAttribute = "traceparent"
+
+// TraceIDAttribute is the message attribute carrying Message.TraceID.
+const TraceIDAttribute = "trace_id"
+
+const (
+	// maxUserAttributes leaves room for the attributes this package sets
+	// within the SQS limit of 10 per message
+	maxUserAttributes = 7
+	maxDelaySeconds   = 900
+)
+
+type traceIDKey struct{}
+
+// WithTraceID returns ctx carrying the trace ID id, which SendContext sends
+// with the message.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceID returns the trace ID carried by ctx: the received message's in a
+// ConsumeContext handler or Message.Context, or one set by WithTraceID.
+func TraceID(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+// isReservedAttribute reports whether name is set by this package
+func isReservedAttribute(name string) bool {
+	return name == TraceIDAttribute || name == TraceParentAttribute || name == OriginRegionAttribute
+}
+
+// withAttributes adds the caller's attributes and the trace ID to attrs
+func withAttributes(attrs map[string]sqstypes.MessageAttributeValue, user map[string]string, traceID string) map[string]sqstypes.MessageAttributeValue {
+	if len(user) == 0 && traceID == "" {
+		return attrs
+	}
+	if attrs == nil {
+		attrs = make(map[string]sqstypes.MessageAttributeValue, len(user)+1)
+	}
+	for name, value := range user {
+		attrs[name] = sqstypes.MessageAttributeValue{DataType: awsv2.String("String"), StringValue: awsv2.String(value)}
+	}
+	if traceID != "" {
+		attrs[TraceIDAttribute] = sqstypes.MessageAttributeValue{DataType: awsv2.String("String"), StringValue: awsv2.String(traceID)}
+	}
+	return attrs
+}
+
+// decodeAttributes sets the trace ID and the caller's attributes of a
+// received message
+func decodeAttributes(msg *Message, attrs map[string]sqstypes.MessageAttributeValue) {
+	for name, attr := range attrs {
+		if attr.StringValue == nil {
+			continue // Binary attributes
+		}
+		switch {
+		case name == TraceIDAttribute:
+			msg.TraceID = *attr.StringValue
+		case !isReservedAttribute(name):
+			if msg.Attributes == nil {
+				msg.Attributes = make(map[string]string, len(attrs))
+			}
+			msg.Attributes[name] = *attr.StringValue
+		}
+	}
+}
+
+// messageAttributes returns the attributes to send with a message: the
+// traceparent of ctx when a span is active, and the origin region when
+// region is set. Returns nil when there is neither.
+func messageAttributes(ctx context.Context, region string) map[string]sqstypes.MessageAttributeValue {
+	var attrs map[string]sqstypes.MessageAttributeValue
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		carrier := propagation.MapCarrier{}
+		propagation.TraceContext{}.Inject(ctx, carrier)
+		attrs = map[string]sqstypes.MessageAttributeValue{
+			TraceParentAttribute: {DataType: awsv2.String("String"), StringValue: awsv2.String(carrier.Get("traceparent"))},
+		}
+	}
+	if region != "" {
+		if attrs == nil {
+			attrs = make(map[string]sqstypes.MessageAttributeValue, 1)
+		}
+		attrs[OriginRegionAttribute] = sqstypes.MessageAttributeValue{DataType: awsv2.String("String"), StringValue: awsv2.String(region)}
+	}
+	return attrs
+}
+
+// traceContext restores the trace ID and the span propagated in attrs into
+// ctx, the span as a remote parent. ctx is returned unchanged when the
+// message carries no trace.
+func traceContext(ctx context.Context, attrs map[string]sqstypes.MessageAttributeValue) context.Context {
+	if attr, ok := attrs[TraceIDAttribute]; ok && attr.StringValue != nil {
+		ctx = WithTraceID(ctx, *attr.StringValue)
+	}
+	attr, ok := attrs[TraceParentAttribute]
+	if !ok || attr.StringValue == nil {
+		return ctx
+	}
+	carrier := propagation.MapCarrier{"traceparent": *attr.StringValue}
+	return propagation.TraceContext{}.Extract(ctx, carrier)
+}
@@ -0,0 +1,170 @@
+package ssm
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"golang.org/x/sync/singleflight"
+)
+
+// cachedParameter is a parameter value cached in process. Values are kept
+// as bytes so SecureString values can be zeroed once dropped.
+type cachedParameter struct {
+	value   []byte
+	secure  bool
+	ttl     time.Duration
+	expires time.Time
+}
+
+// wipe zeroes the value of a SecureString parameter
+func (p *cachedParameter) wipe() {
+	if p.secure {
+		clear(p.value)
+	}
+}
+
+// ParameterCacheStats counts GetParameterCached lookups since start or Reset
+type ParameterCacheStats struct {
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"` // Lookups that called SSM, including shared ones
+	Entries int    `json:"entries"`
+}
+
+var (
+	paramCache   = make(map[string]*cachedParameter)
+	paramCacheMu sync.RWMutex
+	paramGroup   singleflight.Group // One SSM call per name at a time
+	cacheHits    atomic.Uint64
+	cacheMisses  atomic.Uint64
+)
+
+// GetParameterCached is GetParameter cached in process for ttl. Concurrent
+// misses of the same name share one SSM call. Errors are not cached.
+//
+// Example:
+//
+//	key, err := ssm.GetParameterCached("/myapp/prod/stripe/key", 5*time.Minute)
+func GetParameterCached(name string, ttl time.Duration) (string, error) {
+	paramCacheMu.RLock()
+	p, hit := paramCache[name], false
+	var value string
+	if p != nil && time.Now().Before(p.expires) {
+		value, hit = string(p.value), true
+	}
+	paramCacheMu.RUnlock()
+	if hit {
+		cacheHits.Add(1)
+		return value, nil
+	}
+
+	cacheMisses.Add(1)
+	v, err, _ := paramGroup.Do(name, func() (any, error) {
+		return loadParameter(name, ttl, nil)
+	})
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// InvalidateParameter drops name from the cache, zeroing a SecureString
+// value, so the next GetParameterCached reads it from SSM.
+func InvalidateParameter(name string) {
+	paramCacheMu.Lock()
+	defer paramCacheMu.Unlock()
+	if p, ok := paramCache[name]; ok {
+		delete(paramCache, name)
+		p.wipe()
+	}
+}
+
+// RefreshAll re-fetches every interval the cached parameters that would
+// expire before the next run, so hot parameters never miss. Refresh errors
+// are skipped; the entry then expires as usual. It stops when ctx is done.
+//
+// Example:
+//
+//	go ssm.RefreshAll(ctx, time.Minute)
+func RefreshAll(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		refreshExpiring(time.Now().Add(interval))
+	}
+}
+
+// CacheStats returns the GetParameterCached counters, for monitoring
+func CacheStats() ParameterCacheStats {
+	paramCacheMu.RLock()
+	entries := len(paramCache)
+	paramCacheMu.RUnlock()
+	return ParameterCacheStats{Hits: cacheHits.Load(), Misses: cacheMisses.Load(), Entries: entries}
+}
+
+// refreshExpiring re-fetches the entries expiring before deadline
+func refreshExpiring(deadline time.Time) {
+	expiring := make(map[string]*cachedParameter)
+	paramCacheMu.RLock()
+	for name, p := range paramCache {
+		if p.expires.Before(deadline) {
+			expiring[name] = p
+		}
+	}
+	paramCacheMu.RUnlock()
+
+	for name, p := range expiring {
+		paramGroup.Do(name, func() (any, error) {
+			return loadParameter(name, p.ttl, p)
+		})
+	}
+}
+
+// loadParameter reads name from SSM and caches it for ttl. A refresh of
+// prev is dropped if prev was invalidated or replaced meanwhile.
+func loadParameter(name string, ttl time.Duration, prev *cachedParameter) (string, error) {
+	api, err := getParameterClient()
+	if err != nil {
+		return "", err
+	}
+	param, err := fetchParameter(context.Background(), api, name)
+	if err != nil {
+		return "", err
+	}
+
+	paramCacheMu.Lock()
+	defer paramCacheMu.Unlock()
+	old := paramCache[name]
+	if prev != nil && old != prev {
+		return *param.Value, nil
+	}
+	paramCache[name] = &cachedParameter{
+		value:   []byte(*param.Value),
+		secure:  param.Type == types.ParameterTypeSecureString,
+		ttl:     ttl,
+		expires: time.Now().Add(ttl),
+	}
+	if old != nil {
+		old.wipe()
+	}
+	return *param.Value, nil
+}
+
+// clearParameterCache empties the cache and resets its counters
+func clearParameterCache() {
+	paramCacheMu.Lock()
+	defer paramCacheMu.Unlock()
+	for _, p := range paramCache {
+		p.wipe()
+	}
+	paramCache = make(map[string]*cachedParameter)
+	cacheHits.Store(0)
+	cacheMisses.Store(0)
+}
@@ -0,0 +1,170 @@
+package ssm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// mockParameterAPI serves "<name>-v<call>" values, SecureString for names
+// starting with /secret, after delay.
+type mockParameterAPI struct {
+	calls atomic.Int64
+	delay time.Duration
+	fail  atomic.Bool
+}
+
+func (m *mockParameterAPI) GetParameter(ctx context.Context, in *ssm.GetParameterInput, _ ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	n := m.calls.Add(1)
+	time.Sleep(m.delay)
+	if m.fail.Load() {
+		return nil, errors.New("throttled")
+	}
+	name := awsv2.ToString(in.Name)
+	typ := types.ParameterTypeString
+	if len(name) > 7 && name[:7] == "/secret" {
+		typ = types.ParameterTypeSecureString
+	}
+	return &ssm.GetParameterOutput{Parameter: &types.Parameter{
+		Name:  in.Name,
+		Value: awsv2.String(fmt.Sprintf("%s-v%d", name, n)),
+		Type:  typ,
+	}}, nil
+}
+
+func setupCacheTest(t *testing.T, delay time.Duration) *mockParameterAPI {
+	t.Helper()
+	m := &mockParameterAPI{delay: delay}
+	orig := getParameterClient
+	getParameterClient = func() (parameterAPI, error) { return m, nil }
+	clearParameterCache()
+	t.Cleanup(func() {
+		getParameterClient = orig
+		clearParameterCache()
+	})
+	return m
+}
+
+func TestGetParameterCachedConcurrent(t *testing.T) {
+	m := setupCacheTest(t, 20*time.Millisecond)
+
+	var wg sync.WaitGroup
+	values := make([]string, 50)
+	for i := range values {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := GetParameterCached("/app/db", time.Minute)
+			if err != nil {
+				t.Errorf("GetParameterCached failed: %v", err)
+			}
+			values[i] = v
+		}()
+	}
+	wg.Wait()
+
+	if m.calls.Load() != 1 {
+		t.Errorf("expected one SSM call for concurrent misses, got %d", m.calls.Load())
+	}
+	for _, v := range values {
+		if v != "/app/db-v1" {
+			t.Fatalf("unexpected value %q", v)
+		}
+	}
+	if v, _ := GetParameterCached("/app/db", time.Minute); v != "/app/db-v1" {
+		t.Errorf("expected cached value, got %q", v)
+	}
+	stats := CacheStats()
+	if stats.Hits+stats.Misses != 51 || stats.Hits == 0 || stats.Entries != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestGetParameterCachedExpiry(t *testing.T) {
+	m := setupCacheTest(t, 0)
+
+	GetParameterCached("/app/db", 20*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	if v, _ := GetParameterCached("/app/db", 20*time.Millisecond); v != "/app/db-v2" {
+		t.Errorf("expected refetch after expiry, got %q", v)
+	}
+
+	// Errors are returned and not cached
+	m.fail.Store(true)
+	InvalidateParameter("/app/db")
+	if _, err := GetParameterCached("/app/db", time.Minute); err == nil {
+		t.Error("expected error")
+	}
+	m.fail.Store(false)
+	if v, err := GetParameterCached("/app/db", time.Minute); err != nil || v != "/app/db-v4" {
+		t.Errorf("expected fresh value after error, got %q, %v", v, err)
+	}
+}
+
+func TestInvalidateParameter(t *testing.T) {
+	setupCacheTest(t, 0)
+
+	GetParameterCached("/secret/key", time.Minute)
+	GetParameterCached("/app/db", time.Minute)
+	paramCacheMu.RLock()
+	secret, plain := paramCache["/secret/key"], paramCache["/app/db"]
+	paramCacheMu.RUnlock()
+
+	InvalidateParameter("/secret/key")
+	InvalidateParameter("/app/db")
+	InvalidateParameter("/unknown")
+	for _, b := range secret.value {
+		if b != 0 {
+			t.Fatalf("SecureString value not zeroed: %q", secret.value)
+		}
+	}
+	if string(plain.value) != "/app/db-v2" {
+		t.Errorf("String values are left as is, got %q", plain.value)
+	}
+	if v, _ := GetParameterCached("/secret/key", time.Minute); v != "/secret/key-v3" {
+		t.Errorf("expected refetch after invalidation, got %q", v)
+	}
+}
+
+func TestRefreshAll(t *testing.T) {
+	m := setupCacheTest(t, 0)
+	GetParameterCached("/secret/key", 30*time.Millisecond)
+	GetParameterCached("/app/db", time.Hour)
+	paramCacheMu.RLock()
+	old := paramCache["/secret/key"]
+	paramCacheMu.RUnlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go RefreshAll(ctx, 20*time.Millisecond)
+
+	// Keeps the short-lived entry fresh; the long-lived one is untouched
+	time.Sleep(100 * time.Millisecond)
+	hits := CacheStats().Hits
+	v, _ := GetParameterCached("/secret/key", 30*time.Millisecond)
+	if v == "/secret/key-v1" || CacheStats().Hits != hits+1 {
+		t.Errorf("expected a refreshed hit, got %q", v)
+	}
+	if v, _ := GetParameterCached("/app/db", time.Hour); v != "/app/db-v2" {
+		t.Errorf("entry far from expiry should not be refreshed, got %q", v)
+	}
+	if old.value[0] != 0 {
+		t.Error("replaced SecureString value not zeroed")
+	}
+
+	cancel()
+	time.Sleep(30 * time.Millisecond)
+	calls := m.calls.Load()
+	time.Sleep(50 * time.Millisecond)
+	if m.calls.Load() != calls {
+		t.Error("refresh continued after cancel")
+	}
+}
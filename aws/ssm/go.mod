@@ -8,6 +8,7 @@ require (
 	github.com/aws/aws-sdk-go-v2/credentials v1.19.3
 	github.com/aws/aws-sdk-go-v2/service/ssm v1.67.5
 	github.com/spf13/viper v1.21.0
+	golang.org/x/sync v0.20.0
 )
 
 require (
@@ -0,0 +1,162 @@
+package ssm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/spf13/viper"
+)
+
+// pathAPI is the subset of *ssm.Client used to read parameter trees
+type pathAPI interface {
+	GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error)
+}
+
+// getPathClient returns the client for path reads, replaced in tests
+var getPathClient = func() (pathAPI, error) {
+	return getClient()
+}
+
+// versionedValue is a parameter value with its version
+type versionedValue struct {
+	value   string
+	version int64
+}
+
+// GetParametersByPath gets all parameters under path (automatically
+// decrypts), following pagination. With recursive it includes the whole
+// hierarchy, otherwise only the parameters directly under path.
+// Returns a map of parameter names to values.
+func GetParametersByPath(path string, recursive bool) (map[string]string, error) {
+	api, err := getPathClient()
+	if err != nil {
+		return nil, err
+	}
+	params, err := fetchPath(context.Background(), api, path, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(params))
+	for name, p := range params {
+		values[name] = p.value
+	}
+	return values, nil
+}
+
+// LoadIntoViper loads the parameter tree under path into viper, so
+// viper-driven packages pick the values up. Names relative to path map to
+// keys with slashes as dots under keyPrefix:
+//
+//	// /myapp/prod/redis/addr -> redis.addr
+//	ssm.LoadIntoViper("/myapp/prod/", "")
+//	// /myapp/prod/redis/addr -> app.redis.addr
+//	ssm.LoadIntoViper("/myapp/prod/", "app")
+func LoadIntoViper(path string, keyPrefix string) error {
+	values, err := GetParametersByPath(path, true)
+	if err != nil {
+		return err
+	}
+	for name, value := range values {
+		viper.Set(viperKey(path, keyPrefix, name), value)
+	}
+	return nil
+}
+
+// WatchPath polls the parameter tree under path every interval and calls
+// onChange with the parameters whose version changed since the previous
+// poll; deleted parameters are included with an empty value. The initial
+// tree is read before WatchPath returns, and its error is returned; later
+// poll errors are skipped. Polling stops when ctx is done.
+//
+// Example:
+//
+//	ssm.WatchPath(ctx, "/myapp/prod/", time.Minute, func(changed map[string]string) {
+//	    for name, value := range changed {
+//	        viper.Set(strings.ReplaceAll(strings.TrimPrefix(name, "/myapp/prod/"), "/", "."), value)
+//	    }
+//	})
+func WatchPath(ctx context.Context, path string, interval time.Duration, onChange func(changed map[string]string)) error {
+	api, err := getPathClient()
+	if err != nil {
+		return err
+	}
+	current, err := fetchPath(ctx, api, path, true)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			next, err := fetchPath(ctx, api, path, true)
+			if err != nil {
+				continue
+			}
+			if changed := diffParams(current, next); len(changed) > 0 {
+				onChange(changed)
+			}
+			current = next
+		}
+	}()
+	return nil
+}
+
+// fetchPath reads all parameters under path with their versions.
+func fetchPath(ctx context.Context, api pathAPI, path string, recursive bool) (map[string]versionedValue, error) {
+	params := make(map[string]versionedValue)
+	paginator := ssm.NewGetParametersByPathPaginator(api, &ssm.GetParametersByPathInput{
+		Path:           awsv2.String(path),
+		Recursive:      awsv2.Bool(recursive),
+		WithDecryption: awsv2.Bool(true), // Automatically decrypt SecureString type
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get SSM parameters by path %s: %w", path, err)
+		}
+		for _, param := range page.Parameters {
+			if param.Name != nil && param.Value != nil {
+				params[*param.Name] = versionedValue{value: *param.Value, version: param.Version}
+			}
+		}
+	}
+	return params, nil
+}
+
+// diffParams returns the parameters added, updated (by version) or deleted
+// (as "") between two reads.
+func diffParams(prev, next map[string]versionedValue) map[string]string {
+	changed := make(map[string]string)
+	for name, p := range next {
+		if old, ok := prev[name]; !ok || old.version != p.version {
+			changed[name] = p.value
+		}
+	}
+	for name := range prev {
+		if _, ok := next[name]; !ok {
+			changed[name] = ""
+		}
+	}
+	return changed
+}
+
+// viperKey maps a parameter name under path to a dotted viper key.
+func viperKey(path, keyPrefix, name string) string {
+	key := strings.Trim(strings.TrimPrefix(name, path), "/")
+	key = strings.ReplaceAll(key, "/", ".")
+	if keyPrefix != "" {
+		key = strings.TrimSuffix(keyPrefix, ".") + "." + key
+	}
+	return key
+}
@@ -0,0 +1,172 @@
+package ssm
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/spf13/viper"
+)
+
+// mockPathAPI serves a parameter tree two parameters per page.
+type mockPathAPI struct {
+	mu     sync.Mutex
+	params []types.Parameter
+	calls  int
+}
+
+func (m *mockPathAPI) GetParametersByPath(ctx context.Context, in *ssm.GetParametersByPathInput, _ ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	if !awsv2.ToBool(in.WithDecryption) {
+		panic("decryption must be requested")
+	}
+
+	var matched []types.Parameter
+	for _, p := range m.params {
+		rest, ok := strings.CutPrefix(*p.Name, *in.Path)
+		if ok && (awsv2.ToBool(in.Recursive) || !strings.Contains(strings.TrimPrefix(rest, "/"), "/")) {
+			matched = append(matched, p)
+		}
+	}
+	start := 0
+	if in.NextToken != nil {
+		start = int((*in.NextToken)[0] - '0')
+	}
+	end := min(start+2, len(matched))
+	out := &ssm.GetParametersByPathOutput{Parameters: matched[start:end]}
+	if end < len(matched) {
+		out.NextToken = awsv2.String(string(rune('0' + end)))
+	}
+	return out, nil
+}
+
+func (m *mockPathAPI) set(name, value string, version int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, p := range m.params {
+		if *p.Name == name {
+			m.params[i].Value, m.params[i].Version = &value, version
+			return
+		}
+	}
+	m.params = append(m.params, types.Parameter{Name: &name, Value: &value, Version: version})
+}
+
+func (m *mockPathAPI) remove(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, p := range m.params {
+		if *p.Name == name {
+			m.params = append(m.params[:i], m.params[i+1:]...)
+			return
+		}
+	}
+}
+
+func setupPathTest(t *testing.T) *mockPathAPI {
+	t.Helper()
+	m := &mockPathAPI{}
+	m.set("/myapp/prod/redis/addr", "redis:6379", 1)
+	m.set("/myapp/prod/redis/password", "secret", 1)
+	m.set("/myapp/prod/github/owner", "wordgate", 1)
+	m.set("/myapp/prod/debug", "false", 1)
+	m.set("/myapp/prod/aws/ses/region", "us-east-1", 1)
+
+	orig := getPathClient
+	getPathClient = func() (pathAPI, error) { return m, nil }
+	t.Cleanup(func() { getPathClient = orig })
+	return m
+}
+
+func TestGetParametersByPath(t *testing.T) {
+	m := setupPathTest(t)
+
+	values, err := GetParametersByPath("/myapp/prod/", true)
+	if err != nil {
+		t.Fatalf("GetParametersByPath failed: %v", err)
+	}
+	if len(values) != 5 || values["/myapp/prod/redis/password"] != "secret" {
+		t.Errorf("unexpected values: %v", values)
+	}
+	if m.calls != 3 {
+		t.Errorf("expected 3 pages, got %d", m.calls)
+	}
+
+	values, err = GetParametersByPath("/myapp/prod/", false)
+	if err != nil || len(values) != 1 || values["/myapp/prod/debug"] != "false" {
+		t.Errorf("non-recursive read should only return direct children: %v, %v", values, err)
+	}
+}
+
+func TestLoadIntoViper(t *testing.T) {
+	setupPathTest(t)
+	viper.Reset()
+	defer viper.Reset()
+
+	if err := LoadIntoViper("/myapp/prod", ""); err != nil {
+		t.Fatalf("LoadIntoViper failed: %v", err)
+	}
+	if viper.GetString("redis.addr") != "redis:6379" || viper.GetString("aws.ses.region") != "us-east-1" || viper.GetBool("debug") {
+		t.Errorf("unexpected viper values: %v", viper.AllSettings())
+	}
+
+	if err := LoadIntoViper("/myapp/prod/", "app."); err != nil {
+		t.Fatalf("LoadIntoViper failed: %v", err)
+	}
+	if viper.GetString("app.github.owner") != "wordgate" {
+		t.Errorf("expected prefixed key, got %v", viper.AllSettings())
+	}
+}
+
+func TestWatchPath(t *testing.T) {
+	m := setupPathTest(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan map[string]string, 10)
+	if err := WatchPath(ctx, "/myapp/prod/", 10*time.Millisecond, func(changed map[string]string) {
+		changes <- changed
+	}); err != nil {
+		t.Fatalf("WatchPath failed: %v", err)
+	}
+
+	// Unchanged polls do not call back
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case c := <-changes:
+		t.Fatalf("unexpected change: %v", c)
+	default:
+	}
+
+	m.set("/myapp/prod/redis/password", "rotated", 2)
+	m.set("/myapp/prod/new", "x", 1)
+	m.remove("/myapp/prod/debug")
+	select {
+	case c := <-changes:
+		if len(c) != 3 || c["/myapp/prod/redis/password"] != "rotated" || c["/myapp/prod/new"] != "x" {
+			t.Errorf("unexpected change: %v", c)
+		}
+		if v, ok := c["/myapp/prod/debug"]; !ok || v != "" {
+			t.Errorf("deleted parameter should be reported empty: %v", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no change reported")
+	}
+
+	cancel()
+	time.Sleep(30 * time.Millisecond)
+	m.set("/myapp/prod/redis/addr", "other:6379", 2)
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case c := <-changes:
+		t.Errorf("no polls after cancel, got %v", c)
+	default:
+	}
+}
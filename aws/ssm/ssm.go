@@ -128,27 +128,40 @@ func getClient() (*ssm.Client, error) {
 
 // GetParameter gets a single SSM parameter value (automatically decrypts SecureString)
 func GetParameter(name string) (string, error) {
-	client, err := getClient()
+	api, err := getParameterClient()
+	if err != nil {
+		return "", err
+	}
+	param, err := fetchParameter(context.Background(), api, name)
 	if err != nil {
 		return "", err
 	}
+	return *param.Value, nil
+}
 
-	ctx := context.Background()
-	input := &ssm.GetParameterInput{
+// parameterAPI is the subset of *ssm.Client used to read single parameters
+type parameterAPI interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// getParameterClient returns the client for single reads, replaced in tests
+var getParameterClient = func() (parameterAPI, error) {
+	return getClient()
+}
+
+// fetchParameter reads a parameter, decrypted, failing if it has no value
+func fetchParameter(ctx context.Context, api parameterAPI, name string) (*types.Parameter, error) {
+	result, err := api.GetParameter(ctx, &ssm.GetParameterInput{
 		Name:           awsv2.String(name),
 		WithDecryption: awsv2.Bool(true), // Automatically decrypt SecureString type
-	}
-
-	result, err := client.GetParameter(ctx, input)
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to get SSM parameter %s: %w", name, err)
+		return nil, fmt.Errorf("failed to get SSM parameter %s: %w", name, err)
 	}
-
 	if result.Parameter == nil || result.Parameter.Value == nil {
-		return "", fmt.Errorf("SSM parameter %s is empty", name)
+		return nil, fmt.Errorf("SSM parameter %s is empty", name)
 	}
-
-	return *result.Parameter.Value, nil
+	return result.Parameter, nil
 }
 
 // Parameter represents an SSM parameter with metadata
@@ -341,8 +354,8 @@ func DeleteParameters(names []string) error {
 	return nil
 }
 
-// Reset resets the SSM client and configuration
-// This is mainly useful for testing
+// Reset resets the SSM client and configuration and empties the parameter
+// cache. This is mainly useful for testing
 func Reset() {
 	configMux.Lock()
 	defer configMux.Unlock()
@@ -351,4 +364,5 @@ func Reset() {
 	globalClient = nil
 	initErr = nil
 	clientOnce = sync.Once{}
+	clearParameterCache()
 }
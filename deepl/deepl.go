@@ -71,6 +71,11 @@ func getClient() (*deepl.Translator, error) {
 
 // TranslateTpl 翻译单个文本，保护模板标签
 func TranslateTpl(ctx context.Context, text, fromLang, targetLang string) (string, error) {
+	return translateTpl(ctx, text, targetLang)
+}
+
+// translateTpl 翻译单个文本，保护模板标签，extra 为附加的翻译选项
+func translateTpl(ctx context.Context, text, targetLang string, extra ...deepl.TranslateOption) (string, error) {
 	if text == "" {
 		return "", nil
 	}
@@ -88,6 +93,7 @@ func TranslateTpl(ctx context.Context, text, fromLang, targetLang string) (strin
 	// if fromLang != "" && fromLang != "auto" {
 	// 	opts = append(opts, deepl.WithSourceLang(normalizeLanguageCode(fromLang)))
 	// }
+	opts = append(opts, extra...)
 
 	// 如果包含模板标签，使用 XML 标签处理
 	if hasTemplate {
@@ -106,7 +112,7 @@ func TranslateTpl(ctx context.Context, text, fromLang, targetLang string) (strin
 		// 执行翻译
 		results, err := client.TranslateText([]string{protected}, normalizeLanguageCode(targetLang), opts...)
 		if err != nil {
-			return "", fmt.Errorf("translation failed: %w", err)
+			return "", fmt.Errorf("translation failed: %w", wrapError(err))
 		}
 
 		if len(results) == 0 {
@@ -123,7 +129,7 @@ func TranslateTpl(ctx context.Context, text, fromLang, targetLang string) (strin
 	// 没有模板标签，直接翻译
 	results, err := client.TranslateText([]string{text}, normalizeLanguageCode(targetLang), opts...)
 	if err != nil {
-		return "", fmt.Errorf("translation failed: %w", err)
+		return "", fmt.Errorf("translation failed: %w", wrapError(err))
 	}
 
 	if len(results) == 0 {
@@ -177,7 +183,7 @@ func TranslateTpls(ctx context.Context, texts []string, fromLang, targetLang str
 	// 执行翻译
 	results, err := client.TranslateText(protectedTexts, normalizeLanguageCode(targetLang), opts...)
 	if err != nil {
-		return nil, fmt.Errorf("translation failed: %w", err)
+		return nil, fmt.Errorf("translation failed: %w", wrapError(err))
 	}
 
 	// 提取结果并清理保护标签
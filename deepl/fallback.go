@@ -0,0 +1,159 @@
+package deepl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/cluttrdev/deepl-go/deepl"
+	"github.com/spf13/viper"
+	"github.com/wordgate/qtoolkit/ai"
+)
+
+// 翻译引擎，用于 TranslateResult.Engine
+const (
+	EngineDeepL = "deepl"
+	EngineAI    = "ai"
+)
+
+// ErrUnsupportedLanguage DeepL 不支持目标语言
+var ErrUnsupportedLanguage = errors.New("deepl: unsupported language")
+
+// supportedLanguages normalizeLanguageCode 能识别的 DeepL 目标语言
+var supportedLanguages = map[string]bool{
+	"ZH": true, "EN-US": true, "EN-GB": true, "JA": true, "KO": true, "ES": true,
+	"FR": true, "DE": true, "IT": true, "RU": true, "PT-PT": true, "PT-BR": true,
+	"NL": true, "PL": true, "SV": true, "DA": true, "NB": true, "FI": true,
+	"CS": true, "HU": true, "EL": true, "BG": true, "RO": true, "SK": true,
+	"SL": true, "ET": true, "LV": true, "LT": true, "TR": true, "UK": true,
+	"AR": true, "ID": true,
+}
+
+// TranslateResult 翻译结果及产生它的引擎，便于缓存区分
+type TranslateResult struct {
+	Text     string `json:"text"`
+	Engine   string `json:"engine"`   // EngineDeepL 或 EngineAI
+	Provider string `json:"provider"` // EngineAI 时为实际使用的 AI provider
+}
+
+// FallbackOption 配置 TranslateWithFallback
+type FallbackOption func(*fallbackOptions)
+
+type fallbackOptions struct {
+	glossaryID string
+	glossary   map[string]string
+	formality  string
+	provider   string
+}
+
+// WithGlossary 设置术语表：glossaryID 用于 DeepL，entries 用于 AI 翻译
+// 两者内容应一致，通常 entries 即创建术语表时传入的 CreateGlossary entries
+func WithGlossary(glossaryID string, entries map[string]string) FallbackOption {
+	return func(o *fallbackOptions) {
+		o.glossaryID = glossaryID
+		o.glossary = entries
+	}
+}
+
+// WithFormality 设置 DeepL 正式程度（more、less、prefer_more、prefer_less）
+// AI 翻译时对应 formal / casual 风格
+func WithFormality(formality string) FallbackOption {
+	return func(o *fallbackOptions) { o.formality = formality }
+}
+
+// WithFallbackProvider 指定 AI provider，覆盖 deepl.fallback.provider
+func WithFallbackProvider(provider string) FallbackOption {
+	return func(o *fallbackOptions) { o.provider = provider }
+}
+
+// IsLanguageSupported 判断 DeepL 是否支持目标语言
+func IsLanguageSupported(code string) bool {
+	return supportedLanguages[normalizeLanguageCode(code)]
+}
+
+// TranslateWithFallback 优先使用 DeepL 翻译，保护模板标签
+// 以下情况在 deepl.fallback.enabled 开启时改用 ai 包翻译：
+//   - DeepL 不支持目标语言（不请求 DeepL）
+//   - 额度用完（ErrQuotaExceeded）
+//   - 临时故障：429、5xx、网络错误
+//
+// 术语表和正式程度会同时应用于两种引擎
+func TranslateWithFallback(ctx context.Context, text, fromLang, targetLang string, opts ...FallbackOption) (*TranslateResult, error) {
+	o := &fallbackOptions{provider: viper.GetString("deepl.fallback.provider")}
+	for _, opt := range opts {
+		opt(o)
+	}
+	enabled := viper.GetBool("deepl.fallback.enabled")
+
+	if text == "" {
+		return &TranslateResult{Engine: EngineDeepL}, nil
+	}
+
+	if !IsLanguageSupported(targetLang) {
+		if !enabled {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedLanguage, targetLang)
+		}
+		return translateWithAI(ctx, text, targetLang, o)
+	}
+
+	var extra []deepl.TranslateOption
+	if o.glossaryID != "" && fromLang != "" && fromLang != "auto" {
+		extra = append(extra,
+			deepl.WithSourceLang(baseLanguageCode(fromLang)),
+			deepl.WithGlossaryID(o.glossaryID),
+		)
+	}
+	if o.formality != "" {
+		extra = append(extra, deepl.WithFormality(o.formality))
+	}
+
+	translated, err := translateTpl(ctx, text, targetLang, extra...)
+	if err == nil {
+		return &TranslateResult{Text: translated, Engine: EngineDeepL}, nil
+	}
+	if !enabled || !shouldFallback(err) {
+		return nil, err
+	}
+
+	result, aiErr := translateWithAI(ctx, text, targetLang, o)
+	if aiErr != nil {
+		return nil, fmt.Errorf("%w; fallback: %w", err, aiErr)
+	}
+	return result, nil
+}
+
+// translateWithAI 使用 ai 包翻译，参数与 ai.Translate / ai.TranslateTemplate 一致
+func translateWithAI(ctx context.Context, text, targetLang string, o *fallbackOptions) (*TranslateResult, error) {
+	r := ai.NewRequest(text).
+		Translate(targetLang).
+		WithTemperature(0.3).
+		UseProvider(o.provider)
+	if templateTagRegex.MatchString(text) {
+		r.AsTemplate().WithTemperature(0.2)
+	}
+	if len(o.glossary) > 0 {
+		r.WithGlossary(o.glossary)
+	}
+	switch o.formality {
+	case "more", "prefer_more":
+		r.WithStyle(ai.StyleFormal)
+	case "less", "prefer_less":
+		r.WithStyle(ai.StyleCasual)
+	}
+
+	result, err := r.ExecuteWithUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ai translation failed: %w", err)
+	}
+	return &TranslateResult{Text: result.Content, Engine: EngineAI, Provider: result.Provider}, nil
+}
+
+// shouldFallback 判断 DeepL 错误是否应改用 AI 翻译
+func shouldFallback(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, ErrQuotaExceeded) ||
+		errors.Is(err, deepl.ErrorStatusTooManyRequests) ||
+		errors.Is(err, deepl.ErrorStatusInternalServerError) ||
+		errors.As(err, &netErr)
+}
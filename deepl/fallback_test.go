@@ -0,0 +1,122 @@
+package deepl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/wordgate/qtoolkit/ai"
+)
+
+// setupFallback 启用回退并使用 ai 的 mock provider，返回 AI 收到的提示词
+func setupFallback(t *testing.T) *string {
+	t.Helper()
+	viper.Set("deepl.fallback.enabled", true)
+	viper.Set("deepl.fallback.provider", "mock")
+
+	var prompt string
+	ai.MockRespond(func(msgs []ai.Message) bool {
+		prompt = msgs[0].Content + "\n" + msgs[len(msgs)-1].Content
+		return true
+	}, "שלום {{.Name}}")
+
+	t.Cleanup(func() {
+		viper.Set("deepl.fallback.enabled", false)
+		viper.Set("deepl.fallback.provider", "")
+		ai.ResetMockResponses()
+	})
+	return &prompt
+}
+
+func TestTranslateWithFallback(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("deepl", func(t *testing.T) {
+		setupFallback(t)
+		req := translateServer(t, func(s string) string { return strings.ReplaceAll(s, "Hello", "Hallo") })
+
+		result, err := TranslateWithFallback(ctx, "Hello {{.Name}}", "en", "de",
+			WithGlossary("g-1", map[string]string{"Wordgate": "Wordgate"}), WithFormality("more"))
+		if err != nil {
+			t.Fatalf("TranslateWithFallback failed: %v", err)
+		}
+		if result.Text != "Hallo {{.Name}}" || result.Engine != EngineDeepL {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		if (*req)["glossary_id"] != "g-1" || (*req)["formality"] != "more" || (*req)["source_lang"] != "EN" {
+			t.Errorf("unexpected request: %v", *req)
+		}
+	})
+
+	t.Run("unsupported language", func(t *testing.T) {
+		prompt := setupFallback(t)
+		called := false
+		setupServer(t, func(w http.ResponseWriter, r *http.Request) { called = true })
+
+		result, err := TranslateWithFallback(ctx, "Hello {{.Name}}", "en", "he",
+			WithGlossary("g-1", map[string]string{"Wordgate": "וורדגייט"}), WithFormality("more"))
+		if err != nil {
+			t.Fatalf("TranslateWithFallback failed: %v", err)
+		}
+		if called {
+			t.Error("DeepL should not be called for an unsupported language")
+		}
+		if result.Text != "שלום {{.Name}}" || result.Engine != EngineAI || result.Provider != "mock" {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		if !strings.Contains(*prompt, "וורדגייט") || !strings.Contains(strings.ToLower(*prompt), "formal") {
+			t.Errorf("glossary and style should be passed to AI, prompt: %s", *prompt)
+		}
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		setupFallback(t)
+		setupServer(t, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(456) })
+
+		result, err := TranslateWithFallback(ctx, "Hello {{.Name}}", "en", "de")
+		if err != nil {
+			t.Fatalf("TranslateWithFallback failed: %v", err)
+		}
+		if result.Engine != EngineAI {
+			t.Errorf("expected AI fallback, got %+v", result)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		setupFallback(t)
+		viper.Set("deepl.fallback.enabled", false)
+		setupServer(t, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(456) })
+
+		if _, err := TranslateWithFallback(ctx, "Hello", "en", "de"); !errors.Is(err, ErrQuotaExceeded) {
+			t.Errorf("expected ErrQuotaExceeded, got %v", err)
+		}
+		if _, err := TranslateWithFallback(ctx, "Hello", "en", "hi"); !errors.Is(err, ErrUnsupportedLanguage) {
+			t.Errorf("expected ErrUnsupportedLanguage, got %v", err)
+		}
+	})
+
+	t.Run("client error", func(t *testing.T) {
+		setupFallback(t)
+		setupServer(t, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusForbidden) })
+
+		if _, err := TranslateWithFallback(ctx, "Hello", "en", "de"); err == nil || !strings.Contains(err.Error(), "403") {
+			t.Errorf("expected 403 without fallback, got %v", err)
+		}
+	})
+}
+
+func TestIsLanguageSupported(t *testing.T) {
+	for _, code := range []string{"en", "zh-TW", "pt-br", "JA", "de"} {
+		if !IsLanguageSupported(code) {
+			t.Errorf("IsLanguageSupported(%q) = false", code)
+		}
+	}
+	for _, code := range []string{"he", "hi", "th", "", "xyz"} {
+		if IsLanguageSupported(code) {
+			t.Errorf("IsLanguageSupported(%q) = true", code)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package deepl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cluttrdev/deepl-go/deepl"
+)
+
+// ErrQuotaExceeded 字符额度已用完（DeepL 返回 456）
+var ErrQuotaExceeded = errors.New("deepl: quota exceeded")
+
+// GlossaryInfo 术语表信息
+type GlossaryInfo struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	SourceLang string `json:"source_lang"`
+	TargetLang string `json:"target_lang"`
+	EntryCount int    `json:"entry_count"`
+	Ready      bool   `json:"ready"` // 新建的术语表可能需要片刻才能使用
+	CreatedAt  string `json:"created_at"`
+}
+
+// UsageInfo 当前计费周期的字符用量
+type UsageInfo struct {
+	CharacterCount int `json:"character_count"`
+	CharacterLimit int `json:"character_limit"`
+}
+
+// Remaining 返回剩余可翻译字符数
+func (u *UsageInfo) Remaining() int {
+	return max(u.CharacterLimit-u.CharacterCount, 0)
+}
+
+// CreateGlossary 创建术语表，entries 为 原文 -> 译文
+// 语言代码与 TranslateTpl 相同，如 "en"、"zh"
+func CreateGlossary(ctx context.Context, name, sourceLang, targetLang string, entries map[string]string) (string, error) {
+	if len(entries) == 0 {
+		return "", fmt.Errorf("glossary entries are empty")
+	}
+
+	client, err := getClient()
+	if err != nil {
+		return "", err
+	}
+
+	// 按原文排序，保证请求内容稳定
+	list := make([]deepl.GlossaryEntry, 0, len(entries))
+	for source, target := range entries {
+		list = append(list, deepl.GlossaryEntry{Source: source, Target: target})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Source < list[j].Source })
+
+	info, err := client.CreateGlossary(name, baseLanguageCode(sourceLang), baseLanguageCode(targetLang), list)
+	if err != nil {
+		return "", fmt.Errorf("create glossary failed: %w", wrapError(err))
+	}
+	return info.GlossaryId, nil
+}
+
+// ListGlossaries 列出账号下的所有术语表
+func ListGlossaries(ctx context.Context) ([]GlossaryInfo, error) {
+	client, err := getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	glossaries, err := client.ListGlossaries()
+	if err != nil {
+		return nil, fmt.Errorf("list glossaries failed: %w", wrapError(err))
+	}
+
+	result := make([]GlossaryInfo, len(glossaries))
+	for i, g := range glossaries {
+		result[i] = GlossaryInfo{
+			ID:         g.GlossaryId,
+			Name:       g.Name,
+			SourceLang: g.SourceLang,
+			TargetLang: g.TargetLang,
+			EntryCount: g.EntryCount,
+			Ready:      g.Ready,
+			CreatedAt:  g.CreationTime,
+		}
+	}
+	return result, nil
+}
+
+// DeleteGlossary 删除术语表
+func DeleteGlossary(ctx context.Context, glossaryID string) error {
+	client, err := getClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.DeleteGlossary(glossaryID); err != nil {
+		return fmt.Errorf("delete glossary failed: %w", wrapError(err))
+	}
+	return nil
+}
+
+// TranslateTplWithGlossary 使用术语表翻译单个文本，保护模板标签
+// DeepL 要求使用术语表时指定源语言，fromLang 必须与术语表一致
+func TranslateTplWithGlossary(ctx context.Context, text, fromLang, targetLang, glossaryID string) (string, error) {
+	if glossaryID == "" {
+		return TranslateTpl(ctx, text, fromLang, targetLang)
+	}
+	if fromLang == "" || fromLang == "auto" {
+		return "", fmt.Errorf("source language is required when using a glossary")
+	}
+	return translateTpl(ctx, text, targetLang,
+		deepl.WithSourceLang(baseLanguageCode(fromLang)),
+		deepl.WithGlossaryID(glossaryID),
+	)
+}
+
+// Usage 查询字符用量，批量任务可据此在额度耗尽前停止
+func Usage(ctx context.Context) (*UsageInfo, error) {
+	client, err := getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := client.GetUsage()
+	if err != nil {
+		return nil, fmt.Errorf("get usage failed: %w", wrapError(err))
+	}
+	return &UsageInfo{
+		CharacterCount: usage.CharacterCount,
+		CharacterLimit: usage.CharacterLimit,
+	}, nil
+}
+
+// baseLanguageCode 返回不带地区的语言代码，源语言和术语表只接受这种格式
+// 如 en -> EN、pt-BR -> PT
+func baseLanguageCode(code string) string {
+	base, _, _ := strings.Cut(normalizeLanguageCode(code), "-")
+	return base
+}
+
+// wrapError 将 456 错误转换为 ErrQuotaExceeded
+// 底层库只返回 "456 - ..." 格式的错误文本
+func wrapError(err error) error {
+	if err != nil && strings.HasPrefix(err.Error(), "456 ") {
+		return fmt.Errorf("%w: %v", ErrQuotaExceeded, err)
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+package deepl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// setupServer 使用 httptest 服务器代替 DeepL API
+func setupServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+
+	resetClient := func() {
+		clientOnce = sync.Once{}
+		defaultClient = nil
+		clientErr = nil
+	}
+	resetClient()
+	t.Setenv("DEEPL_API_KEY", "")
+	viper.Set("deepl.api_key", "test-key:fx")
+	viper.Set("deepl.server_url", server.URL)
+
+	t.Cleanup(func() {
+		server.Close()
+		viper.Set("deepl.api_key", "")
+		viper.Set("deepl.server_url", "")
+		resetClient()
+	})
+}
+
+func TestGlossary(t *testing.T) {
+	var created map[string]string
+	var translated map[string]any
+	setupServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "DeepL-Auth-Key test-key:fx" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v2/glossaries":
+			json.Unmarshal(body, &created)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"glossary_id":"g-1","name":"brand","ready":true,"source_lang":"en","target_lang":"zh","entry_count":2}`))
+		case "GET /v2/glossaries":
+			w.Write([]byte(`{"glossaries":[{"glossary_id":"g-1","name":"brand","ready":true,"source_lang":"en","target_lang":"zh","creation_time":"2024-05-01T00:00:00Z","entry_count":2}]}`))
+		case "DELETE /v2/glossaries/g-1":
+			w.WriteHeader(http.StatusNoContent)
+		case "POST /v2/translate":
+			json.Unmarshal(body, &translated)
+			w.Write([]byte(`{"translations":[{"detected_source_language":"EN","text":"<x>{{.Name}}</x>，欢迎使用 Wordgate"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	ctx := context.Background()
+
+	id, err := CreateGlossary(ctx, "brand", "en", "zh-CN", map[string]string{"Wordgate": "Wordgate", "VPN": "VPN"})
+	if err != nil {
+		t.Fatalf("CreateGlossary failed: %v", err)
+	}
+	if id != "g-1" {
+		t.Errorf("glossary ID = %q, want g-1", id)
+	}
+	if created["source_lang"] != "EN" || created["target_lang"] != "ZH" || created["entries"] != "VPN\tVPN\nWordgate\tWordgate" {
+		t.Errorf("unexpected create request: %v", created)
+	}
+
+	glossaries, err := ListGlossaries(ctx)
+	if err != nil {
+		t.Fatalf("ListGlossaries failed: %v", err)
+	}
+	if len(glossaries) != 1 || glossaries[0].ID != "g-1" || glossaries[0].EntryCount != 2 || !glossaries[0].Ready {
+		t.Errorf("unexpected glossaries: %+v", glossaries)
+	}
+
+	result, err := TranslateTplWithGlossary(ctx, "{{.Name}}, welcome to Wordgate", "en", "zh", "g-1")
+	if err != nil {
+		t.Fatalf("TranslateTplWithGlossary failed: %v", err)
+	}
+	if result != "{{.Name}}，欢迎使用 Wordgate" {
+		t.Errorf("unexpected translation: %q", result)
+	}
+	if translated["glossary_id"] != "g-1" || translated["source_lang"] != "EN" || translated["tag_handling"] != "xml" {
+		t.Errorf("unexpected translate request: %v", translated)
+	}
+
+	if _, err := TranslateTplWithGlossary(ctx, "Hello", "auto", "zh", "g-1"); err == nil {
+		t.Error("expected error without source language")
+	}
+
+	if err := DeleteGlossary(ctx, "g-1"); err != nil {
+		t.Errorf("DeleteGlossary failed: %v", err)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	setupServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"character_count":499000,"character_limit":500000}`))
+	})
+
+	usage, err := Usage(context.Background())
+	if err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	if usage.CharacterCount != 499000 || usage.CharacterLimit != 500000 || usage.Remaining() != 1000 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestQuotaExceeded(t *testing.T) {
+	setupServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(456)
+	})
+
+	_, err := TranslateTpl(context.Background(), "Hello", "en", "zh")
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded, got %v", err)
+	}
+	_, err = TranslateTpls(context.Background(), []string{"Hello"}, "en", "zh")
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded, got %v", err)
+	}
+}
+
+func TestBaseLanguageCode(t *testing.T) {
+	tests := map[string]string{"en": "EN", "en-GB": "EN", "pt-br": "PT", "zh-TW": "ZH", "ja": "JA", "": ""}
+	for input, want := range tests {
+		if got := baseLanguageCode(input); got != want {
+			t.Errorf("baseLanguageCode(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package deepl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cluttrdev/deepl-go/deepl"
+)
+
+// ErrPlaceholderMismatch 译文中的模板标签与原文不一致
+var ErrPlaceholderMismatch = errors.New("deepl: template placeholders changed in translation")
+
+var (
+	// htmlIgnoreTags 不翻译的 HTML 区域，x 为模板标签保护标签
+	htmlIgnoreTags = []string{"x", "script", "style", "code", "pre"}
+
+	// protectedTagRegex 匹配保护后的模板标签 <x>{{...}}</x>
+	protectedTagRegex = regexp.MustCompile(`<x>(\{\{[^}]*\}\})</x>`)
+)
+
+// TranslateHTML 翻译 HTML 文档，保留标签结构
+// 始终启用 XML 标签处理，script/style/code/pre 中的内容不翻译，
+// 文本中的模板标签与 TranslateTpl 一样受保护，属性值（如 href="{{.URL}}"）DeepL 本身不会翻译
+// 译文中的模板标签与原文不一致时返回 ErrPlaceholderMismatch
+func TranslateHTML(ctx context.Context, html, fromLang, targetLang string) (string, error) {
+	if html == "" {
+		return "", nil
+	}
+	results, err := TranslateHTMLs(ctx, []string{html}, fromLang, targetLang)
+	if err != nil {
+		return "", err
+	}
+	return results[0], nil
+}
+
+// TranslateHTMLs 批量翻译 HTML 文档，规则同 TranslateHTML
+func TranslateHTMLs(ctx context.Context, htmls []string, fromLang, targetLang string) ([]string, error) {
+	if len(htmls) == 0 {
+		return []string{}, nil
+	}
+
+	client, err := getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	protected := make([]string, len(htmls))
+	for i, html := range htmls {
+		protected[i] = protectHTMLTemplates(html)
+	}
+
+	results, err := client.TranslateText(protected, normalizeLanguageCode(targetLang),
+		deepl.WithTagHandling("xml"),
+		deepl.WithIgnoreTags(htmlIgnoreTags),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("translation failed: %w", wrapError(err))
+	}
+	if len(results) != len(htmls) {
+		return nil, fmt.Errorf("got %d translation results, want %d", len(results), len(htmls))
+	}
+
+	translations := make([]string, len(results))
+	for i, result := range results {
+		translations[i] = protectedTagRegex.ReplaceAllString(result.Text, "$1")
+		if err := checkPlaceholders(htmls[i], translations[i]); err != nil {
+			return nil, err
+		}
+	}
+	return translations, nil
+}
+
+// protectHTMLTemplates 将文本中的模板标签包裹为 <x>{{...}}</x>
+// 标签内部（含引号中的属性值）的模板标签保持不变，否则会破坏标签结构
+func protectHTMLTemplates(html string) string {
+	var b strings.Builder
+	inTag := false
+	var quote byte
+
+	for i := 0; i < len(html); {
+		c := html[i]
+		switch {
+		case inTag:
+			if quote != 0 {
+				if c == quote {
+					quote = 0
+				}
+			} else if c == '"' || c == '\'' {
+				quote = c
+			} else if c == '>' {
+				inTag = false
+			}
+		case c == '<':
+			inTag = true
+		case strings.HasPrefix(html[i:], "{{"):
+			if loc := templateTagRegex.FindStringIndex(html[i:]); loc != nil && loc[0] == 0 {
+				b.WriteString("<x>")
+				b.WriteString(html[i : i+loc[1]])
+				b.WriteString("</x>")
+				i += loc[1]
+				continue
+			}
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return b.String()
+}
+
+// checkPlaceholders 检查译文中的模板标签与原文一致（包括出现次数）
+func checkPlaceholders(source, translated string) error {
+	counts := make(map[string]int)
+	for _, tag := range templateTagRegex.FindAllString(source, -1) {
+		counts[tag]++
+	}
+	var unexpected []string
+	for _, tag := range templateTagRegex.FindAllString(translated, -1) {
+		if counts[tag] == 0 {
+			unexpected = append(unexpected, tag)
+			continue
+		}
+		counts[tag]--
+	}
+
+	var missing []string
+	for _, tag := range templateTagRegex.FindAllString(source, -1) {
+		if counts[tag] > 0 {
+			missing = append(missing, tag)
+			counts[tag]--
+		}
+	}
+
+	switch {
+	case len(missing) > 0:
+		return fmt.Errorf("%w: missing %s", ErrPlaceholderMismatch, strings.Join(missing, ", "))
+	case len(unexpected) > 0:
+		return fmt.Errorf("%w: unexpected %s", ErrPlaceholderMismatch, strings.Join(unexpected, ", "))
+	}
+	return nil
+}
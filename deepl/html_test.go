@@ -0,0 +1,115 @@
+package deepl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// translateServer 模拟翻译接口，用 translate 处理每段文本并记录请求
+func translateServer(t *testing.T, translate func(string) string) *map[string]any {
+	t.Helper()
+	var req map[string]any
+	setupServer(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &req)
+		var body struct {
+			Text []string `json:"text"`
+		}
+		json.Unmarshal(data, &body)
+
+		type translation struct {
+			Text string `json:"text"`
+		}
+		resp := struct {
+			Translations []translation `json:"translations"`
+		}{}
+		for _, text := range body.Text {
+			resp.Translations = append(resp.Translations, translation{Text: translate(text)})
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+	return &req
+}
+
+func TestTranslateHTML(t *testing.T) {
+	hello := func(s string) string { return strings.ReplaceAll(s, "Hello", "你好") }
+
+	tests := []struct {
+		name      string
+		html      string
+		translate func(string) string
+		protected string // 发送给 DeepL 的文本
+		want      string
+		wantErr   error
+	}{
+		{
+			name:      "nested tags",
+			html:      `<div><p>Hello <b><i>{{.Name}}</i></b></p></div>`,
+			translate: hello,
+			protected: `<div><p>Hello <b><i><x>{{.Name}}</x></i></b></p></div>`,
+			want:      `<div><p>你好 <b><i>{{.Name}}</i></b></p></div>`,
+		},
+		{
+			name:      "attributes with entities and quoted >",
+			html:      `<a href="{{.URL}}?a=1&amp;b=2" title="a &gt; b > {{.C}}">Hello</a>`,
+			translate: hello,
+			protected: `<a href="{{.URL}}?a=1&amp;b=2" title="a &gt; b > {{.C}}">Hello</a>`,
+			want:      `<a href="{{.URL}}?a=1&amp;b=2" title="a &gt; b > {{.C}}">你好</a>`,
+		},
+		{
+			name:      "mixed template and html",
+			html:      `{{if .VIP}}<p>Hello {{ .Name }}</p>{{end}}<pre>Hello {{.Code}}</pre>`,
+			translate: func(s string) string { return s },
+			protected: `<x>{{if .VIP}}</x><p>Hello <x>{{ .Name }}</x></p><x>{{end}}</x><pre>Hello <x>{{.Code}}</x></pre>`,
+			want:      `{{if .VIP}}<p>Hello {{ .Name }}</p>{{end}}<pre>Hello {{.Code}}</pre>`,
+		},
+		{
+			name:      "lost placeholder",
+			html:      `<p>Hello {{.Name}}, {{.Name}}</p>`,
+			translate: func(s string) string { return strings.Replace(s, ", <x>{{.Name}}</x>", "", 1) },
+			protected: `<p>Hello <x>{{.Name}}</x>, <x>{{.Name}}</x></p>`,
+			wantErr:   ErrPlaceholderMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := translateServer(t, tt.translate)
+
+			got, err := TranslateHTML(context.Background(), tt.html, "en", "zh")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !strings.Contains(err.Error(), "{{.Name}}") {
+					t.Errorf("expected %v naming the placeholder, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("TranslateHTML failed: %v", err)
+			} else if got != tt.want {
+				t.Errorf("TranslateHTML() = %q, want %q", got, tt.want)
+			}
+
+			if text := (*req)["text"].([]any)[0]; text != tt.protected {
+				t.Errorf("sent %q, want %q", text, tt.protected)
+			}
+			if (*req)["tag_handling"] != "xml" || len((*req)["ignore_tags"].([]any)) != len(htmlIgnoreTags) {
+				t.Errorf("unexpected options: %v", *req)
+			}
+		})
+	}
+}
+
+func TestTranslateHTMLs(t *testing.T) {
+	translateServer(t, func(s string) string { return strings.ReplaceAll(s, "Hello", "Hallo") })
+
+	got, err := TranslateHTMLs(context.Background(), []string{"<p>Hello</p>", "<p>{{.A}} Hello</p>"}, "en", "de")
+	if err != nil {
+		t.Fatalf("TranslateHTMLs failed: %v", err)
+	}
+	if len(got) != 2 || got[0] != "<p>Hallo</p>" || got[1] != "<p>{{.A}} Hallo</p>" {
+		t.Errorf("unexpected translations: %q", got)
+	}
+}
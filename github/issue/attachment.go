@@ -0,0 +1,152 @@
+package issue
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/wordgate/qtoolkit/aws/s3"
+)
+
+// Attachment is a file attached to an issue or comment.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// ErrInvalidAttachment is wrapped by errors for attachments that are
+// empty, too large or of a type not in github.attachments.allowed_types.
+var ErrInvalidAttachment = errors.New("invalid attachment")
+
+// S3 operations, replaced in tests.
+var (
+	putObject    = s3.PutObject
+	deleteObject = s3.DeleteObject
+)
+
+// CreateIssueWithAttachments uploads attachments to S3 and creates an issue
+// whose body links them: images inline, other files as links. If an upload
+// or the issue creation fails, the uploaded objects are deleted.
+func CreateIssueWithAttachments(ctx context.Context, req *CreateIssueRequest, attachments []Attachment, appUserID string) (*Issue, error) {
+	links, keys, err := uploadAttachments(ctx, attachments)
+	if err != nil {
+		return nil, err
+	}
+	withLinks := *req
+	withLinks.Body = appendLinks(req.Body, links)
+	issue, err := CreateIssue(ctx, &withLinks, appUserID)
+	if err != nil {
+		return nil, errors.Join(err, deleteAttachments(keys))
+	}
+	return issue, nil
+}
+
+// CreateCommentWithAttachments is CreateComment with attachments, uploaded
+// and linked like CreateIssueWithAttachments.
+func CreateCommentWithAttachments(ctx context.Context, number int, req *CreateCommentRequest, attachments []Attachment, appUserID string) (*Comment, error) {
+	links, keys, err := uploadAttachments(ctx, attachments)
+	if err != nil {
+		return nil, err
+	}
+	withLinks := *req
+	withLinks.Body = appendLinks(req.Body, links)
+	comment, err := CreateComment(ctx, number, &withLinks, appUserID)
+	if err != nil {
+		return nil, errors.Join(err, deleteAttachments(keys))
+	}
+	return comment, nil
+}
+
+// uploadAttachments validates all attachments, then uploads them and
+// returns their markdown links and object keys. On failure the objects
+// already uploaded are deleted.
+func uploadAttachments(ctx context.Context, attachments []Attachment) (links, keys []string, err error) {
+	cfg := getConfig().Attachments
+	if len(attachments) > 0 && cfg.URLPrefix == "" {
+		return nil, nil, fmt.Errorf("github.attachments.url_prefix not configured")
+	}
+	for _, a := range attachments {
+		if err := validateAttachment(&cfg, a); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, a := range attachments {
+		key, err := attachmentKey(cfg.Prefix, a.Filename)
+		if err == nil {
+			err = putObject(ctx, cfg.Bucket, key, bytes.NewReader(a.Data), a.ContentType)
+		}
+		if err != nil {
+			err = fmt.Errorf("upload attachment %q: %w", a.Filename, err)
+			return nil, nil, errors.Join(err, deleteAttachments(keys))
+		}
+		keys = append(keys, key)
+		links = append(links, attachmentLink(a, strings.TrimRight(cfg.URLPrefix, "/")+"/"+key))
+	}
+	return links, keys, nil
+}
+
+func validateAttachment(cfg *AttachmentConfig, a Attachment) error {
+	if len(a.Data) == 0 {
+		return fmt.Errorf("%w: %q is empty", ErrInvalidAttachment, a.Filename)
+	}
+	if int64(len(a.Data)) > cfg.MaxBytes {
+		return fmt.Errorf("%w: %q is %d bytes, max %d", ErrInvalidAttachment, a.Filename, len(a.Data), cfg.MaxBytes)
+	}
+	mediaType, _, err := mime.ParseMediaType(a.ContentType)
+	if err != nil || !slices.Contains(cfg.AllowedTypes, mediaType) {
+		return fmt.Errorf("%w: %q has content type %q", ErrInvalidAttachment, a.Filename, a.ContentType)
+	}
+	return nil
+}
+
+// attachmentKey returns a unique object key keeping the file extension:
+// <prefix><yyyy/mm/dd>/<random><ext>.
+func attachmentKey(prefix, filename string) (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	ext := strings.ToLower(path.Ext(filename))
+	if len(ext) > 10 || strings.ContainsAny(ext, "/\\?#%") {
+		ext = ""
+	}
+	return prefix + time.Now().UTC().Format("2006/01/02/") + hex.EncodeToString(b) + ext, nil
+}
+
+// attachmentLink renders a markdown reference: inline for images.
+func attachmentLink(a Attachment, url string) string {
+	name := strings.NewReplacer("[", "(", "]", ")", "\n", " ").Replace(path.Base(a.Filename))
+	if strings.HasPrefix(a.ContentType, "image/") {
+		return fmt.Sprintf("![%s](%s)", name, url)
+	}
+	return fmt.Sprintf("[%s](%s)", name, url)
+}
+
+func appendLinks(body string, links []string) string {
+	if len(links) == 0 {
+		return body
+	}
+	return strings.TrimRight(body, "\n") + "\n\n" + strings.Join(links, "\n")
+}
+
+// deleteAttachments removes uploaded objects, returning the keys it could not delete.
+func deleteAttachments(keys []string) error {
+	bucket := getConfig().Attachments.Bucket
+	var errs []error
+	for _, key := range keys {
+		if err := deleteObject(context.Background(), bucket, key); err != nil {
+			errs = append(errs, fmt.Errorf("delete attachment %s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
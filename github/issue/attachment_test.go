@@ -0,0 +1,146 @@
+package issue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// fakeStore replaces the S3 operations, failing the upload of failOn.
+type fakeStore struct {
+	objects map[string]string // key -> content type
+	failOn  string
+}
+
+func setupAttachmentTest(t *testing.T, store *fakeStore, status int) *string {
+	t.Helper()
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		body = req["body"]
+		w.WriteHeader(status)
+		if strings.HasSuffix(r.URL.Path, "/comments") {
+			json.NewEncoder(w).Encode(ghComment{ID: 1, Body: body})
+			return
+		}
+		json.NewEncoder(w).Encode(ghIssue{Number: 7, Body: body})
+	}))
+	t.Cleanup(server.Close)
+
+	viper.Reset()
+	viper.Set("github.owner", "o")
+	viper.Set("github.repo", "r")
+	viper.Set("github.attachments.url_prefix", "https://cdn.example.com/")
+	viper.Set("github.attachments.max_bytes", 16)
+	SetAPIBaseURL(server.URL)
+	resetClient()
+	DisableCache()
+
+	store.objects = map[string]string{}
+	origPut, origDelete := putObject, deleteObject
+	putObject = func(ctx context.Context, bucket, key string, r io.Reader, contentType string) error {
+		if strings.Contains(key, store.failOn) && store.failOn != "" {
+			return errors.New("access denied")
+		}
+		store.objects[key] = contentType
+		return nil
+	}
+	deleteObject = func(ctx context.Context, bucket, key string) error {
+		delete(store.objects, key)
+		return nil
+	}
+	t.Cleanup(func() {
+		EnableCache()
+		putObject, deleteObject = origPut, origDelete
+	})
+	return &body
+}
+
+func TestCreateIssueWithAttachments(t *testing.T) {
+	store := &fakeStore{}
+	body := setupAttachmentTest(t, store, http.StatusCreated)
+
+	_, err := CreateIssueWithAttachments(context.Background(), &CreateIssueRequest{Title: "Crash", Body: "It crashed\n"}, []Attachment{
+		{Filename: "screen [1].PNG", ContentType: "image/png", Data: []byte("png")},
+		{Filename: "log.txt", ContentType: "text/plain; charset=utf-8", Data: []byte("trace")},
+	}, "user1")
+	if err != nil {
+		t.Fatalf("CreateIssueWithAttachments failed: %v", err)
+	}
+	if len(store.objects) != 2 {
+		t.Fatalf("expected 2 uploads, got %v", store.objects)
+	}
+	for key, ct := range store.objects {
+		if !strings.HasPrefix(key, "github/attachments/") {
+			t.Errorf("unexpected key %s", key)
+		}
+		if strings.HasSuffix(key, ".png") && ct != "image/png" {
+			t.Errorf("unexpected content type %s for %s", ct, key)
+		}
+	}
+	lines := strings.Split(*body, "\n")
+	if lines[0] != "It crashed" || lines[1] != "" ||
+		!strings.HasPrefix(lines[2], "![screen (1).PNG](https://cdn.example.com/github/attachments/") ||
+		!strings.HasPrefix(lines[3], "[log.txt](https://cdn.example.com/") {
+		t.Errorf("unexpected body:\n%s", *body)
+	}
+	if !strings.Contains(*body, "<!-- app_user_id: user1 -->") {
+		t.Errorf("metadata should follow the links:\n%s", *body)
+	}
+}
+
+func TestCreateCommentWithAttachmentsValidation(t *testing.T) {
+	store := &fakeStore{}
+	setupAttachmentTest(t, store, http.StatusCreated)
+	ctx := context.Background()
+	req := &CreateCommentRequest{Body: "see attached"}
+
+	for _, a := range []Attachment{
+		{Filename: "big.png", ContentType: "image/png", Data: make([]byte, 17)},
+		{Filename: "run.sh", ContentType: "application/x-sh", Data: []byte("rm")},
+		{Filename: "empty.png", ContentType: "image/png"},
+	} {
+		_, err := CreateCommentWithAttachments(ctx, 1, req, []Attachment{{Filename: "ok.png", ContentType: "image/png", Data: []byte("ok")}, a}, "u")
+		if !errors.Is(err, ErrInvalidAttachment) {
+			t.Errorf("%s: expected ErrInvalidAttachment, got %v", a.Filename, err)
+		}
+	}
+	if len(store.objects) != 0 {
+		t.Errorf("nothing should be uploaded before validation passes: %v", store.objects)
+	}
+}
+
+func TestAttachmentsCleanedUpOnFailure(t *testing.T) {
+	store := &fakeStore{failOn: ".gif"}
+	setupAttachmentTest(t, store, http.StatusCreated)
+	ctx := context.Background()
+	files := []Attachment{
+		{Filename: "a.png", ContentType: "image/png", Data: []byte("a")},
+		{Filename: "b.gif", ContentType: "image/gif", Data: []byte("b")},
+	}
+
+	if _, err := CreateIssueWithAttachments(ctx, &CreateIssueRequest{Title: "t"}, files, "u"); err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("expected upload error, got %v", err)
+	}
+	if len(store.objects) != 0 {
+		t.Errorf("uploaded objects should be deleted: %v", store.objects)
+	}
+
+	// A GitHub failure also removes the uploads
+	store.failOn = ""
+	setupAttachmentTest(t, store, http.StatusUnprocessableEntity)
+	if _, err := CreateIssueWithAttachments(ctx, &CreateIssueRequest{Title: "t"}, files, "u"); err == nil {
+		t.Error("expected GitHub error")
+	}
+	if len(store.objects) != 0 {
+		t.Errorf("uploaded objects should be deleted: %v", store.objects)
+	}
+}
@@ -0,0 +1,303 @@
+package issue
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ========== Bootstrap Spec ==========
+
+// RepoSpec describes the resources a support repository should have.
+type RepoSpec struct {
+	Labels    []LabelSpec    `yaml:"labels"`
+	Webhook   *WebhookSpec   `yaml:"webhook"`
+	Templates []TemplateSpec `yaml:"templates"`
+	Branch    string         `yaml:"branch"` // Branch for template commits (default branch if empty)
+}
+
+// LabelSpec describes a repository label.
+type LabelSpec struct {
+	Name        string `yaml:"name"`
+	Color       string `yaml:"color"` // Hex color, with or without leading '#'
+	Description string `yaml:"description"`
+}
+
+// WebhookSpec describes the repository webhook pointing at the app.
+type WebhookSpec struct {
+	URL          string   `yaml:"url"`
+	Secret       string   `yaml:"secret"`
+	Events       []string `yaml:"events"`
+	RotateSecret bool     `yaml:"rotate_secret"` // Push Secret to an existing hook
+}
+
+// TemplateSpec describes a file under .github/ISSUE_TEMPLATE.
+type TemplateSpec struct {
+	Name    string `yaml:"name"` // File name, e.g. "bug_report.md"
+	Content string `yaml:"content"`
+}
+
+// BootstrapResult lists resources by outcome.
+type BootstrapResult struct {
+	Created   []string `json:"created"`
+	Updated   []string `json:"updated"`
+	Unchanged []string `json:"unchanged"`
+}
+
+// BootstrapReport is the outcome of BootstrapRepo per resource category.
+type BootstrapReport struct {
+	Labels    BootstrapResult `json:"labels"`
+	Webhook   BootstrapResult `json:"webhook"`
+	Templates BootstrapResult `json:"templates"`
+}
+
+const issueTemplateDir = ".github/ISSUE_TEMPLATE"
+
+// defaultOfficialLabelColor is used when the official label is created implicitly.
+const defaultOfficialLabelColor = "0e8a16"
+
+// ========== GitHub API Types (bootstrap) ==========
+
+type ghLabelFull struct {
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+}
+
+type ghHook struct {
+	ID     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Active bool     `json:"active"`
+	Events []string `json:"events"`
+	Config struct {
+		URL         string `json:"url"`
+		ContentType string `json:"content_type"`
+	} `json:"config"`
+}
+
+type ghContent struct {
+	SHA string `json:"sha"`
+}
+
+// ========== Bootstrap ==========
+
+// BootstrapRepo idempotently ensures the configured repository has the labels,
+// official label, webhook and issue templates described by spec.
+// Resources not in spec are never deleted.
+func BootstrapRepo(ctx context.Context, spec *RepoSpec) (*BootstrapReport, error) {
+	report := &BootstrapReport{}
+
+	if err := ensureLabels(ctx, spec.Labels, &report.Labels); err != nil {
+		return report, fmt.Errorf("labels: %w", err)
+	}
+	if spec.Webhook != nil {
+		if err := ensureWebhook(ctx, spec.Webhook, &report.Webhook); err != nil {
+			return report, fmt.Errorf("webhook: %w", err)
+		}
+	}
+	for _, tpl := range spec.Templates {
+		if err := ensureTemplate(ctx, tpl, spec.Branch, &report.Templates); err != nil {
+			return report, fmt.Errorf("template %s: %w", tpl.Name, err)
+		}
+	}
+
+	return report, nil
+}
+
+func ensureLabels(ctx context.Context, specs []LabelSpec, result *BootstrapResult) error {
+	cfg := getConfig()
+
+	existing, err := listLabels(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The official label must exist; its appearance is only managed when listed in spec
+	if !slices.ContainsFunc(specs, func(l LabelSpec) bool { return l.Name == cfg.OfficialLabel }) {
+		if _, ok := existing[cfg.OfficialLabel]; ok {
+			result.Unchanged = append(result.Unchanged, cfg.OfficialLabel)
+		} else {
+			specs = append(specs, LabelSpec{
+				Name:        cfg.OfficialLabel,
+				Color:       defaultOfficialLabelColor,
+				Description: "Official reply",
+			})
+		}
+	}
+
+	base := fmt.Sprintf("/repos/%s/%s/labels", cfg.Owner, cfg.Repo)
+	for _, spec := range specs {
+		want := ghLabelFull{
+			Name:        spec.Name,
+			Color:       normalizeColor(spec.Color),
+			Description: spec.Description,
+		}
+
+		cur, ok := existing[spec.Name]
+		switch {
+		case !ok:
+			if err := doJSON(ctx, "POST", base, want, nil, http.StatusCreated); err != nil {
+				return fmt.Errorf("create %s: %w", spec.Name, err)
+			}
+			result.Created = append(result.Created, spec.Name)
+		case normalizeColor(cur.Color) != want.Color || cur.Description != want.Description:
+			path := base + "/" + url.PathEscape(spec.Name)
+			if err := doJSON(ctx, "PATCH", path, want, nil, http.StatusOK); err != nil {
+				return fmt.Errorf("update %s: %w", spec.Name, err)
+			}
+			result.Updated = append(result.Updated, spec.Name)
+		default:
+			result.Unchanged = append(result.Unchanged, spec.Name)
+		}
+	}
+	return nil
+}
+
+func listLabels(ctx context.Context) (map[string]ghLabelFull, error) {
+	cfg := getConfig()
+	labels := make(map[string]ghLabelFull)
+
+	for page := 1; ; page++ {
+		var batch []ghLabelFull
+		path := fmt.Sprintf("/repos/%s/%s/labels?per_page=100&page=%d", cfg.Owner, cfg.Repo, page)
+		if err := doJSON(ctx, "GET", path, nil, &batch, http.StatusOK); err != nil {
+			return nil, err
+		}
+		for _, l := range batch {
+			labels[l.Name] = l
+		}
+		if len(batch) < 100 {
+			return labels, nil
+		}
+	}
+}
+
+func ensureWebhook(ctx context.Context, spec *WebhookSpec, result *BootstrapResult) error {
+	cfg := getConfig()
+	base := fmt.Sprintf("/repos/%s/%s/hooks", cfg.Owner, cfg.Repo)
+
+	var hooks []ghHook
+	if err := doJSON(ctx, "GET", base+"?per_page=100", nil, &hooks, http.StatusOK); err != nil {
+		return err
+	}
+
+	events := slices.Clone(spec.Events)
+	slices.Sort(events)
+
+	config := map[string]string{
+		"url":          spec.URL,
+		"content_type": "json",
+		"secret":       spec.Secret,
+	}
+
+	idx := slices.IndexFunc(hooks, func(h ghHook) bool { return h.Config.URL == spec.URL })
+	if idx < 0 {
+		payload := map[string]any{
+			"name":   "web",
+			"active": true,
+			"events": events,
+			"config": config,
+		}
+		if err := doJSON(ctx, "POST", base, payload, nil, http.StatusCreated); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+		result.Created = append(result.Created, spec.URL)
+		return nil
+	}
+
+	hook := hooks[idx]
+	current := slices.Clone(hook.Events)
+	slices.Sort(current)
+
+	// GitHub never returns the secret, so the config (which carries it) is
+	// only sent when rotating or when it has to be rewritten anyway.
+	payload := map[string]any{}
+	if !slices.Equal(current, events) {
+		payload["events"] = events
+	}
+	if !hook.Active {
+		payload["active"] = true
+	}
+	if spec.RotateSecret || hook.Config.ContentType != "json" {
+		payload["config"] = config
+	}
+
+	if len(payload) == 0 {
+		result.Unchanged = append(result.Unchanged, spec.URL)
+		return nil
+	}
+
+	path := fmt.Sprintf("%s/%d", base, hook.ID)
+	if err := doJSON(ctx, "PATCH", path, payload, nil, http.StatusOK); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	result.Updated = append(result.Updated, spec.URL)
+	return nil
+}
+
+func ensureTemplate(ctx context.Context, spec TemplateSpec, branch string, result *BootstrapResult) error {
+	cfg := getConfig()
+	filePath := issueTemplateDir + "/" + spec.Name
+	path := fmt.Sprintf("/repos/%s/%s/contents/%s", cfg.Owner, cfg.Repo, filePath)
+
+	getPath := path
+	if branch != "" {
+		getPath += "?ref=" + url.QueryEscape(branch)
+	}
+
+	var cur ghContent
+	err := doJSON(ctx, "GET", getPath, nil, &cur, http.StatusOK)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	// The contents API reports the git blob SHA, so compare against the blob hash of the desired content
+	if exists && cur.SHA == gitBlobSHA(spec.Content) {
+		result.Unchanged = append(result.Unchanged, filePath)
+		return nil
+	}
+
+	payload := map[string]string{
+		"content": base64.StdEncoding.EncodeToString([]byte(spec.Content)),
+	}
+	if branch != "" {
+		payload["branch"] = branch
+	}
+	if exists {
+		payload["message"] = "Update issue template " + spec.Name
+		payload["sha"] = cur.SHA
+	} else {
+		payload["message"] = "Add issue template " + spec.Name
+	}
+
+	if err := doJSON(ctx, "PUT", path, payload, nil, http.StatusOK, http.StatusCreated); err != nil {
+		return err
+	}
+
+	if exists {
+		result.Updated = append(result.Updated, filePath)
+	} else {
+		result.Created = append(result.Created, filePath)
+	}
+	return nil
+}
+
+// normalizeColor converts "#FFAA00" style colors to GitHub's "ffaa00".
+func normalizeColor(color string) string {
+	return strings.ToLower(strings.TrimPrefix(color, "#"))
+}
+
+// gitBlobSHA returns the git object hash of content.
+func gitBlobSHA(content string) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write([]byte(content))
+	return hex.EncodeToString(h.Sum(nil))
+}
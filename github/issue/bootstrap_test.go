@@ -0,0 +1,343 @@
+package issue
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// fakeRepo is an in-memory GitHub repository serving the bootstrap endpoints.
+type fakeRepo struct {
+	mu       sync.Mutex
+	labels   map[string]ghLabelFull
+	hooks    []map[string]any
+	files    map[string]string // path -> content
+	requests []string          // "METHOD path"
+	payloads map[string]map[string]any
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{
+		labels:   make(map[string]ghLabelFull),
+		files:    make(map[string]string),
+		payloads: make(map[string]map[string]any),
+	}
+}
+
+func (f *fakeRepo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	const prefix = "/repos/test-owner/test-repo"
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	key := r.Method + " " + path
+	f.requests = append(f.requests, key)
+
+	var body map[string]any
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+		f.payloads[key] = body
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == "GET" && path == "/labels":
+		labels := []ghLabelFull{}
+		for _, l := range f.labels {
+			labels = append(labels, l)
+		}
+		json.NewEncoder(w).Encode(labels)
+
+	case r.Method == "POST" && path == "/labels":
+		name := body["name"].(string)
+		f.labels[name] = ghLabelFull{Name: name, Color: body["color"].(string), Description: body["description"].(string)}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(f.labels[name])
+
+	case r.Method == "PATCH" && strings.HasPrefix(path, "/labels/"):
+		name := strings.TrimPrefix(path, "/labels/")
+		f.labels[name] = ghLabelFull{Name: name, Color: body["color"].(string), Description: body["description"].(string)}
+		json.NewEncoder(w).Encode(f.labels[name])
+
+	case r.Method == "GET" && path == "/hooks":
+		hooks := []map[string]any{}
+		for _, h := range f.hooks {
+			// Secrets are never returned by GitHub
+			cfg := map[string]any{"url": h["config"].(map[string]any)["url"], "content_type": h["config"].(map[string]any)["content_type"]}
+			hooks = append(hooks, map[string]any{"id": h["id"], "active": h["active"], "events": h["events"], "config": cfg})
+		}
+		json.NewEncoder(w).Encode(hooks)
+
+	case r.Method == "POST" && path == "/hooks":
+		body["id"] = len(f.hooks) + 1
+		f.hooks = append(f.hooks, body)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(body)
+
+	case r.Method == "PATCH" && strings.HasPrefix(path, "/hooks/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(path, "/hooks/"))
+		hook := f.hooks[id-1]
+		for k, v := range body {
+			hook[k] = v
+		}
+		json.NewEncoder(w).Encode(hook)
+
+	case r.Method == "GET" && strings.HasPrefix(path, "/contents/"):
+		content, ok := f.files[strings.TrimPrefix(path, "/contents/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Not Found"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(ghContent{SHA: gitBlobSHA(content)})
+
+	case r.Method == "PUT" && strings.HasPrefix(path, "/contents/"):
+		file := strings.TrimPrefix(path, "/contents/")
+		old, exists := f.files[file]
+		if exists && body["sha"] != gitBlobSHA(old) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		data, _ := base64.StdEncoding.DecodeString(body["content"].(string))
+		f.files[file] = string(data)
+		if exists {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{}`))
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeRepo) called(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Contains(f.requests, key)
+}
+
+func (f *fakeRepo) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = nil
+	f.payloads = make(map[string]map[string]any)
+}
+
+func setupBootstrapTest(t *testing.T) *fakeRepo {
+	t.Helper()
+	repo := newFakeRepo()
+	server := httptest.NewServer(repo)
+	t.Cleanup(server.Close)
+
+	viper.Reset()
+	viper.Set("github.owner", "test-owner")
+	viper.Set("github.repo", "test-repo")
+	viper.Set("github.token", "ghp_test123")
+	viper.Set("github.official_label", "official-reply")
+
+	SetAPIBaseURL(server.URL)
+	resetClient()
+	return repo
+}
+
+func testSpec() *RepoSpec {
+	return &RepoSpec{
+		Labels: []LabelSpec{
+			{Name: "bug", Color: "#D73A4A", Description: "Something isn't working"},
+			{Name: "feature", Color: "a2eeef", Description: "New feature"},
+		},
+		Webhook: &WebhookSpec{
+			URL:    "https://app.example.com/github/webhook",
+			Secret: "s3cret",
+			Events: []string{"issues", "issue_comment"},
+		},
+		Templates: []TemplateSpec{
+			{Name: "bug_report.md", Content: "---\nname: Bug report\n---\n"},
+		},
+	}
+}
+
+func TestBootstrapRepoCreatesEverything(t *testing.T) {
+	repo := setupBootstrapTest(t)
+
+	report, err := BootstrapRepo(context.Background(), testSpec())
+	if err != nil {
+		t.Fatalf("BootstrapRepo failed: %v", err)
+	}
+
+	if len(report.Labels.Created) != 3 {
+		t.Errorf("expected 3 labels created (incl. official), got %v", report.Labels.Created)
+	}
+	if repo.labels["bug"].Color != "d73a4a" {
+		t.Errorf("expected normalized color, got %s", repo.labels["bug"].Color)
+	}
+	if _, ok := repo.labels["official-reply"]; !ok {
+		t.Error("official label should be created")
+	}
+
+	if len(report.Webhook.Created) != 1 || len(repo.hooks) != 1 {
+		t.Fatalf("expected webhook created, got %+v", report.Webhook)
+	}
+	cfg := repo.hooks[0]["config"].(map[string]any)
+	if cfg["secret"] != "s3cret" || cfg["content_type"] != "json" {
+		t.Errorf("unexpected webhook config: %v", cfg)
+	}
+
+	if len(report.Templates.Created) != 1 {
+		t.Errorf("expected template created, got %+v", report.Templates)
+	}
+	if repo.files[".github/ISSUE_TEMPLATE/bug_report.md"] != "---\nname: Bug report\n---\n" {
+		t.Error("template content not committed")
+	}
+}
+
+func TestBootstrapRepoIdempotent(t *testing.T) {
+	repo := setupBootstrapTest(t)
+	spec := testSpec()
+
+	if _, err := BootstrapRepo(context.Background(), spec); err != nil {
+		t.Fatalf("first run failed: %v", err)
+	}
+	repo.reset()
+
+	report, err := BootstrapRepo(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("second run failed: %v", err)
+	}
+
+	if len(report.Labels.Created)+len(report.Labels.Updated) != 0 || len(report.Labels.Unchanged) != 3 {
+		t.Errorf("labels should be unchanged: %+v", report.Labels)
+	}
+	if len(report.Webhook.Unchanged) != 1 {
+		t.Errorf("webhook should be unchanged: %+v", report.Webhook)
+	}
+	if len(report.Templates.Unchanged) != 1 {
+		t.Errorf("template should be unchanged: %+v", report.Templates)
+	}
+	for _, req := range repo.requests {
+		if !strings.HasPrefix(req, "GET ") {
+			t.Errorf("unexpected write on idempotent run: %s", req)
+		}
+	}
+}
+
+func TestBootstrapRepoUpdates(t *testing.T) {
+	repo := setupBootstrapTest(t)
+	repo.labels["bug"] = ghLabelFull{Name: "bug", Color: "ffffff", Description: "old"}
+	repo.labels["official-reply"] = ghLabelFull{Name: "official-reply", Color: "123456"}
+	repo.labels["wontfix"] = ghLabelFull{Name: "wontfix", Color: "000000"}
+	repo.hooks = []map[string]any{{
+		"id":     1,
+		"active": true,
+		"events": []any{"issues"},
+		"config": map[string]any{"url": "https://app.example.com/github/webhook", "content_type": "json", "secret": "old"},
+	}}
+	repo.files[".github/ISSUE_TEMPLATE/bug_report.md"] = "outdated"
+
+	report, err := BootstrapRepo(context.Background(), testSpec())
+	if err != nil {
+		t.Fatalf("BootstrapRepo failed: %v", err)
+	}
+
+	if !slices.Equal(report.Labels.Updated, []string{"bug"}) {
+		t.Errorf("expected bug updated, got %v", report.Labels.Updated)
+	}
+	if !slices.Contains(report.Labels.Unchanged, "official-reply") {
+		t.Error("existing official label should be left as is")
+	}
+	if repo.labels["official-reply"].Color != "123456" {
+		t.Error("official label not in spec must not be restyled")
+	}
+	if _, ok := repo.labels["wontfix"]; !ok {
+		t.Error("labels not in spec must never be deleted")
+	}
+
+	if len(report.Webhook.Updated) != 1 {
+		t.Fatalf("expected webhook updated, got %+v", report.Webhook)
+	}
+	patch := repo.payloads["PATCH /hooks/1"]
+	if _, ok := patch["config"]; ok {
+		t.Error("config (and secret) should not be sent without rotation")
+	}
+	if len(patch["events"].([]any)) != 2 {
+		t.Errorf("expected events update, got %v", patch["events"])
+	}
+	if repo.hooks[0]["config"].(map[string]any)["secret"] != "old" {
+		t.Error("secret should be kept without rotation")
+	}
+
+	if len(report.Templates.Updated) != 1 {
+		t.Errorf("expected template updated, got %+v", report.Templates)
+	}
+	put := repo.payloads["PUT /contents/.github/ISSUE_TEMPLATE/bug_report.md"]
+	if put["sha"] != gitBlobSHA("outdated") {
+		t.Errorf("update should reference current sha, got %v", put["sha"])
+	}
+}
+
+func TestBootstrapRepoRotatesSecret(t *testing.T) {
+	repo := setupBootstrapTest(t)
+	spec := &RepoSpec{Webhook: testSpec().Webhook}
+
+	if _, err := BootstrapRepo(context.Background(), spec); err != nil {
+		t.Fatalf("first run failed: %v", err)
+	}
+
+	spec.Webhook.Secret = "rotated"
+	spec.Webhook.RotateSecret = true
+	report, err := BootstrapRepo(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("rotation failed: %v", err)
+	}
+
+	if len(report.Webhook.Updated) != 1 {
+		t.Errorf("expected webhook updated, got %+v", report.Webhook)
+	}
+	if got := repo.hooks[0]["config"].(map[string]any)["secret"]; got != "rotated" {
+		t.Errorf("expected rotated secret, got %v", got)
+	}
+	if len(repo.hooks) != 1 {
+		t.Errorf("rotation must not create a second hook, got %d", len(repo.hooks))
+	}
+}
+
+func TestBootstrapRepoTemplateBranch(t *testing.T) {
+	repo := setupBootstrapTest(t)
+	spec := &RepoSpec{
+		Templates: []TemplateSpec{{Name: "feature.md", Content: "feature"}},
+		Branch:    "main",
+	}
+
+	if _, err := BootstrapRepo(context.Background(), spec); err != nil {
+		t.Fatalf("BootstrapRepo failed: %v", err)
+	}
+
+	put := repo.payloads["PUT /contents/.github/ISSUE_TEMPLATE/feature.md"]
+	if put["branch"] != "main" {
+		t.Errorf("expected branch main, got %v", put["branch"])
+	}
+	if _, ok := put["sha"]; ok {
+		t.Error("create should not send sha")
+	}
+	if !repo.called("GET /contents/.github/ISSUE_TEMPLATE/feature.md") {
+		t.Error("expected existence check before commit")
+	}
+}
+
+func TestGitBlobSHA(t *testing.T) {
+	// git hash-object of "hello\n"
+	if got := gitBlobSHA("hello\n"); got != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("unexpected blob sha: %s", got)
+	}
+}
@@ -51,6 +51,9 @@ func TestLoadConfigFromViperDefaults(t *testing.T) {
 	if cfg.CacheTTL != 300 {
 		t.Errorf("expected default cache_ttl 300, got %d", cfg.CacheTTL)
 	}
+	if cfg.ReactionsCacheTTL != 60 {
+		t.Errorf("expected default reactions_cache_ttl 60, got %d", cfg.ReactionsCacheTTL)
+	}
 }
 
 // ========== DTO Tests ==========
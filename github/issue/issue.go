@@ -26,6 +26,20 @@ type Config struct {
 	Token         string `yaml:"token"`          // GitHub PAT
 	OfficialLabel string `yaml:"official_label"` // Label for official replies
 	CacheTTL      int    `yaml:"cache_ttl"`      // Cache TTL in seconds
+
+	ReactionsCacheTTL int  `yaml:"reactions_cache_ttl"` // Reaction counts cache TTL in seconds
+	AutoCreateLabels  bool `yaml:"auto_create_labels"`  // Create missing labels in AddLabels/SetLabels
+
+	Attachments AttachmentConfig `yaml:"attachments"`
+}
+
+// AttachmentConfig configures where attachments are uploaded and what is accepted.
+type AttachmentConfig struct {
+	Bucket       string   `yaml:"bucket"`        // S3 bucket; aws.s3.bucket if empty
+	Prefix       string   `yaml:"prefix"`        // Object key prefix
+	URLPrefix    string   `yaml:"url_prefix"`    // Public URL of the bucket, required
+	MaxBytes     int64    `yaml:"max_bytes"`     // Max size of one attachment
+	AllowedTypes []string `yaml:"allowed_types"` // Allowed content types
 }
 
 var (
@@ -42,6 +56,15 @@ func loadConfigFromViper() *Config {
 	cfg.Token = viper.GetString("github.token")
 	cfg.OfficialLabel = viper.GetString("github.official_label")
 	cfg.CacheTTL = viper.GetInt("github.cache_ttl")
+	cfg.ReactionsCacheTTL = viper.GetInt("github.reactions_cache_ttl")
+	cfg.AutoCreateLabels = viper.GetBool("github.auto_create_labels")
+	cfg.Attachments = AttachmentConfig{
+		Bucket:       viper.GetString("github.attachments.bucket"),
+		Prefix:       viper.GetString("github.attachments.prefix"),
+		URLPrefix:    viper.GetString("github.attachments.url_prefix"),
+		MaxBytes:     viper.GetInt64("github.attachments.max_bytes"),
+		AllowedTypes: viper.GetStringSlice("github.attachments.allowed_types"),
+	}
 
 	// Defaults
 	if cfg.OfficialLabel == "" {
@@ -50,6 +73,18 @@ func loadConfigFromViper() *Config {
 	if cfg.CacheTTL == 0 {
 		cfg.CacheTTL = 300
 	}
+	if cfg.ReactionsCacheTTL == 0 {
+		cfg.ReactionsCacheTTL = 60
+	}
+	if cfg.Attachments.Prefix == "" {
+		cfg.Attachments.Prefix = "github/attachments/"
+	}
+	if cfg.Attachments.MaxBytes == 0 {
+		cfg.Attachments.MaxBytes = 10 << 20
+	}
+	if len(cfg.Attachments.AllowedTypes) == 0 {
+		cfg.Attachments.AllowedTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "text/plain", "application/pdf"}
+	}
 
 	return cfg
 }
@@ -116,20 +151,41 @@ type IssueDetail struct {
 
 // ListIssuesResponse is the paginated list response.
 type ListIssuesResponse struct {
-	Issues  []Issue `json:"issues"`
-	Page    int     `json:"page"`
-	PerPage int     `json:"per_page"`
-	HasMore bool    `json:"has_more"`
+	Issues     []Issue `json:"issues"`
+	Page       int     `json:"page"`
+	PerPage    int     `json:"per_page"`
+	HasMore    bool    `json:"has_more"`
+	TotalCount int     `json:"total_count,omitempty"` // Only set for search results
 }
 
 // ========== Request DTOs ==========
 
+// ListOptions filters ListIssuesFiltered. Zero values mean state "all",
+// page 1 and 20 per page.
+type ListOptions struct {
+	State     string   // "open", "closed" or "all"
+	Labels    []string // Issues must have all of these labels
+	Query     string   // Free-text search; switches to the search API
+	Sort      string   // "created", "updated" or "comments"
+	Direction string   // "asc" or "desc"
+	Page      int
+	PerPage   int
+}
+
 // CreateIssueRequest is the request to create a new issue.
 type CreateIssueRequest struct {
 	Title string `json:"title" binding:"required,min=5,max=200"`
 	Body  string `json:"body" binding:"required,min=10,max=10000"`
 }
 
+// UpdateIssueRequest is the request to edit an issue. Empty fields are
+// left unchanged.
+type UpdateIssueRequest struct {
+	Title string `json:"title" binding:"omitempty,min=5,max=200"`
+	Body  string `json:"body" binding:"omitempty,min=10,max=10000"`
+	State string `json:"state" binding:"omitempty,oneof=open closed"`
+}
+
 // CreateCommentRequest is the request to create a new comment.
 type CreateCommentRequest struct {
 	Body string `json:"body" binding:"required,min=1,max=5000"`
@@ -137,13 +193,21 @@ type CreateCommentRequest struct {
 
 // ========== Utility Functions ==========
 
-var metadataRegex = regexp.MustCompile(`\n\n<!-- app_user_id: [^>]+ -->$`)
+var metadataRegex = regexp.MustCompile(`\n\n<!-- app_user_id: ([^>]+) -->$`)
 
 // stripMetadata removes the embedded user metadata from body.
 func stripMetadata(body string) string {
 	return strings.TrimSpace(metadataRegex.ReplaceAllString(body, ""))
 }
 
+// extractMetadata returns the user ID embedded in body, or "" if none.
+func extractMetadata(body string) string {
+	if m := metadataRegex.FindStringSubmatch(body); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
 // injectMetadata adds user metadata to body as invisible HTML comment.
 func injectMetadata(body, userID string) string {
 	return fmt.Sprintf("%s\n\n<!-- app_user_id: %s -->", body, userID)
@@ -0,0 +1,206 @@
+package issue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wordgate/qtoolkit/redis"
+)
+
+// ========== Notification Tracking ==========
+
+// notificationsEnabled toggles participation tracking and read markers.
+var notificationsEnabled = false
+
+// ErrNotificationsDisabled is returned when notification tracking is off.
+var ErrNotificationsDisabled = errors.New("issue notifications are disabled")
+
+// TrackUserNotifications enables or disables user notification tracking.
+// When enabled, CreateIssue and CreateComment record the user's participation
+// so official replies can be reported by GetUnreadReplies. Only issues created
+// or commented on while tracking is enabled are known.
+func TrackUserNotifications(enabled bool) {
+	notificationsEnabled = enabled
+}
+
+// ReplyNotification is an official reply the user has not read yet.
+type ReplyNotification struct {
+	IssueNumber int       `json:"issue_number"`
+	IssueTitle  string    `json:"issue_title"`
+	CommentID   int64     `json:"comment_id"`
+	Excerpt     string    `json:"excerpt"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DigestIssue groups unread replies of one issue.
+type DigestIssue struct {
+	Number   int                 `json:"number"`
+	Title    string              `json:"title"`
+	Replies  []ReplyNotification `json:"replies"`
+	LatestAt time.Time           `json:"latest_at"`
+}
+
+// Digest is a per-user summary of unread official replies, e.g. for a daily email.
+type Digest struct {
+	AppUserID    string        `json:"app_user_id"`
+	Since        time.Time     `json:"since"`
+	Issues       []DigestIssue `json:"issues"` // Most recently replied first
+	TotalReplies int           `json:"total_replies"`
+}
+
+// issueMarker is stored per user+issue; its presence means the user participates.
+type issueMarker struct {
+	ReadAt time.Time `json:"read_at"`
+}
+
+const excerptMaxRunes = 200
+
+func notifyKey(appUserID string) string {
+	return "github:notify:" + appUserID
+}
+
+// MarkIssueRead records that the user has read the issue up to at.
+// Markers only move forward; an older at is ignored.
+func MarkIssueRead(ctx context.Context, appUserID string, number int, at time.Time) (err error) {
+	if !notificationsEnabled {
+		return ErrNotificationsDisabled
+	}
+	defer recoverRedis(&err)
+
+	key := notifyKey(appUserID)
+	field := strconv.Itoa(number)
+
+	var cur issueMarker
+	if _, err := redis.CacheHGet(key, field, &cur); err != nil {
+		return fmt.Errorf("read marker: %w", err)
+	}
+	if !at.After(cur.ReadAt) {
+		return nil
+	}
+	if err := redis.CacheHSet(key, field, issueMarker{ReadAt: at}); err != nil {
+		return fmt.Errorf("save marker: %w", err)
+	}
+	return nil
+}
+
+// trackParticipation records that the user created or commented on an issue.
+// Their own action implies they have read it up to now. Fail-safe.
+func trackParticipation(ctx context.Context, appUserID string, number int) {
+	if !notificationsEnabled || appUserID == "" || appUserID == "anonymous" {
+		return
+	}
+	MarkIssueRead(ctx, appUserID, number, time.Now())
+}
+
+// GetUnreadReplies returns official comments newer than both since and the
+// user's read marker, across all issues the user created or commented on,
+// oldest first.
+func GetUnreadReplies(ctx context.Context, appUserID string, since time.Time) (replies []ReplyNotification, err error) {
+	if !notificationsEnabled {
+		return nil, ErrNotificationsDisabled
+	}
+
+	markers, err := loadMarkers(appUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	numbers := make([]int, 0, len(markers))
+	for field := range markers {
+		if n, err := strconv.Atoi(field); err == nil {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+
+	for _, number := range numbers {
+		after := markers[strconv.Itoa(number)].ReadAt
+		if since.After(after) {
+			after = since
+		}
+
+		detail, err := GetIssue(ctx, number)
+		if err != nil {
+			return nil, fmt.Errorf("issue %d: %w", number, err)
+		}
+		for _, c := range detail.Comments {
+			if !c.IsOfficial || !c.CreatedAt.After(after) {
+				continue
+			}
+			replies = append(replies, ReplyNotification{
+				IssueNumber: number,
+				IssueTitle:  detail.Title,
+				CommentID:   c.ID,
+				Excerpt:     excerpt(c.Body),
+				CreatedAt:   c.CreatedAt,
+			})
+		}
+	}
+
+	sort.SliceStable(replies, func(i, j int) bool {
+		return replies[i].CreatedAt.Before(replies[j].CreatedAt)
+	})
+	return replies, nil
+}
+
+// BuildDigest groups the user's unread replies by issue. Rendering and
+// sending (e.g. via the mail or ses template features) is left to the caller.
+// Returns a digest with no issues when there is nothing unread.
+func BuildDigest(ctx context.Context, appUserID string, since time.Time) (*Digest, error) {
+	replies, err := GetUnreadReplies(ctx, appUserID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := &Digest{AppUserID: appUserID, Since: since, TotalReplies: len(replies)}
+	index := make(map[int]int)
+	for _, r := range replies {
+		i, ok := index[r.IssueNumber]
+		if !ok {
+			i = len(digest.Issues)
+			index[r.IssueNumber] = i
+			digest.Issues = append(digest.Issues, DigestIssue{Number: r.IssueNumber, Title: r.IssueTitle})
+		}
+		group := &digest.Issues[i]
+		group.Replies = append(group.Replies, r)
+		if r.CreatedAt.After(group.LatestAt) {
+			group.LatestAt = r.CreatedAt
+		}
+	}
+
+	sort.SliceStable(digest.Issues, func(i, j int) bool {
+		return digest.Issues[i].LatestAt.After(digest.Issues[j].LatestAt)
+	})
+	return digest, nil
+}
+
+func loadMarkers(appUserID string) (markers map[string]issueMarker, err error) {
+	defer recoverRedis(&err)
+	markers, err = redis.CacheHGetAll[issueMarker](notifyKey(appUserID))
+	if err != nil {
+		return nil, fmt.Errorf("load markers: %w", err)
+	}
+	return markers, nil
+}
+
+// recoverRedis turns a Redis panic (not configured) into an error.
+func recoverRedis(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("redis: %v", r)
+	}
+}
+
+// excerpt shortens a comment body to a single-line preview.
+func excerpt(body string) string {
+	s := strings.Join(strings.Fields(body), " ")
+	if utf8.RuneCountInString(s) <= excerptMaxRunes {
+		return s
+	}
+	return string([]rune(s)[:excerptMaxRunes]) + "…"
+}
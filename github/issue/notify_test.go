@@ -0,0 +1,220 @@
+package issue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/wordgate/qtoolkit/redis"
+)
+
+var notifyBase = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// setupNotifyTest serves issues 1-3 from a mock GitHub API, enables tracking
+// and returns a unique app user ID whose markers are removed on cleanup.
+func setupNotifyTest(t *testing.T) string {
+	t.Helper()
+	if os.Getenv("REDIS_TEST_SKIP") != "" {
+		t.Skip("Skipping Redis tests (REDIS_TEST_SKIP is set)")
+	}
+
+	comments := map[int][]ghComment{
+		1: {
+			{ID: 11, Body: "Thanks, looking into it", User: ghUser{Login: "support-bot"}, CreatedAt: notifyBase.Add(1 * time.Hour)},
+			{ID: 12, Body: "Still broken\n\n<!-- app_user_id: u -->", User: ghUser{Login: "app-bot"}, CreatedAt: notifyBase.Add(2 * time.Hour)},
+			{ID: 13, Body: "Fixed in   v2.1,\nplease update", User: ghUser{Login: "support-bot"}, CreatedAt: notifyBase.Add(3 * time.Hour)},
+		},
+		2: {
+			{ID: 21, Body: "Duplicate of #1", User: ghUser{Login: "support-bot"}, CreatedAt: notifyBase.Add(4 * time.Hour)},
+		},
+		3: {
+			{ID: 31, Body: "Unrelated reply", User: ghUser{Login: "support-bot"}, CreatedAt: notifyBase.Add(5 * time.Hour)},
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var number int
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/repos/o/r/issues/2/comments":
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(ghComment{ID: 22, Body: "me too", CreatedAt: time.Now()})
+		case r.Method == "POST" && r.URL.Path == "/repos/o/r/issues":
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(ghIssue{Number: 1, Title: "Crash on start"})
+		case strings.HasSuffix(r.URL.Path, "/comments"):
+			fmt.Sscanf(r.URL.Path, "/repos/o/r/issues/%d/comments", &number)
+			json.NewEncoder(w).Encode(comments[number])
+		default:
+			fmt.Sscanf(r.URL.Path, "/repos/o/r/issues/%d", &number)
+			json.NewEncoder(w).Encode(ghIssue{Number: number, Title: fmt.Sprintf("Issue %d", number)})
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	viper.Reset()
+	viper.Set("github.owner", "o")
+	viper.Set("github.repo", "r")
+	viper.Set("github.official_users", []string{"support-bot"})
+	viper.Set("redis.addr", "localhost:6379")
+	SetAPIBaseURL(server.URL)
+	resetClient()
+	DisableCache()
+	TrackUserNotifications(true)
+
+	if err := func() (err error) {
+		defer recoverRedis(&err)
+		return redis.Client().Ping(context.Background()).Err()
+	}(); err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+
+	user := fmt.Sprintf("user_%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		redis.CacheDel(notifyKey(user))
+		TrackUserNotifications(false)
+		EnableCache()
+	})
+	return user
+}
+
+func TestMarkIssueRead(t *testing.T) {
+	user := setupNotifyTest(t)
+	ctx := context.Background()
+
+	if err := MarkIssueRead(ctx, user, 1, notifyBase.Add(2*time.Hour)); err != nil {
+		t.Fatalf("MarkIssueRead failed: %v", err)
+	}
+	// Older markers do not move the marker back
+	if err := MarkIssueRead(ctx, user, 1, notifyBase); err != nil {
+		t.Fatalf("MarkIssueRead failed: %v", err)
+	}
+
+	markers, err := loadMarkers(user)
+	if err != nil {
+		t.Fatalf("loadMarkers failed: %v", err)
+	}
+	if len(markers) != 1 || !markers["1"].ReadAt.Equal(notifyBase.Add(2*time.Hour)) {
+		t.Errorf("unexpected markers: %+v", markers)
+	}
+
+	TrackUserNotifications(false)
+	if err := MarkIssueRead(ctx, user, 1, time.Now()); !errors.Is(err, ErrNotificationsDisabled) {
+		t.Errorf("expected ErrNotificationsDisabled, got %v", err)
+	}
+}
+
+func TestGetUnreadReplies(t *testing.T) {
+	user := setupNotifyTest(t)
+	ctx := context.Background()
+
+	// Read issue 1 up to the first official reply; issue 2 never read
+	MarkIssueRead(ctx, user, 1, notifyBase.Add(time.Hour))
+	MarkIssueRead(ctx, user, 2, notifyBase)
+
+	replies, err := GetUnreadReplies(ctx, user, time.Time{})
+	if err != nil {
+		t.Fatalf("GetUnreadReplies failed: %v", err)
+	}
+
+	var ids []int64
+	for _, r := range replies {
+		ids = append(ids, r.CommentID)
+	}
+	// 11 is read, 12 is not official, issue 3 is not the user's
+	if fmt.Sprint(ids) != "[13 21]" {
+		t.Fatalf("expected replies [13 21], got %v", ids)
+	}
+	if replies[0].IssueTitle != "Issue 1" || replies[0].Excerpt != "Fixed in v2.1, please update" {
+		t.Errorf("unexpected notification: %+v", replies[0])
+	}
+
+	// since further limits results
+	replies, _ = GetUnreadReplies(ctx, user, notifyBase.Add(3*time.Hour+time.Minute))
+	if len(replies) != 1 || replies[0].CommentID != 21 {
+		t.Errorf("expected only reply 21 after since, got %+v", replies)
+	}
+}
+
+func TestUnreadRepliesParticipatedIssue(t *testing.T) {
+	user := setupNotifyTest(t)
+	ctx := context.Background()
+
+	// The user comments on issue 2, which someone else created
+	if _, err := CreateComment(ctx, 2, &CreateCommentRequest{Body: "me too"}, user); err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+	if _, err := CreateIssue(ctx, &CreateIssueRequest{Title: "Crash on start", Body: "It crashes"}, user); err != nil {
+		t.Fatalf("CreateIssue failed: %v", err)
+	}
+
+	markers, _ := loadMarkers(user)
+	if _, ok := markers["2"]; !ok {
+		t.Fatalf("commented issue should be tracked, got %+v", markers)
+	}
+	if _, ok := markers["1"]; !ok {
+		t.Fatalf("created issue should be tracked, got %+v", markers)
+	}
+
+	// Participation marks the issue read up to now, so older replies are not unread
+	replies, _ := GetUnreadReplies(ctx, user, time.Time{})
+	if len(replies) != 0 {
+		t.Errorf("expected no unread replies right after participating, got %+v", replies)
+	}
+
+	// Rewind the marker to simulate a reply arriving after the comment
+	redis.CacheHSet(notifyKey(user), "2", issueMarker{ReadAt: notifyBase})
+	replies, _ = GetUnreadReplies(ctx, user, time.Time{})
+	if len(replies) != 1 || replies[0].IssueNumber != 2 {
+		t.Errorf("expected reply on participated issue 2, got %+v", replies)
+	}
+}
+
+func TestBuildDigest(t *testing.T) {
+	user := setupNotifyTest(t)
+	ctx := context.Background()
+
+	MarkIssueRead(ctx, user, 1, notifyBase)
+	MarkIssueRead(ctx, user, 2, notifyBase)
+
+	digest, err := BuildDigest(ctx, user, time.Time{})
+	if err != nil {
+		t.Fatalf("BuildDigest failed: %v", err)
+	}
+
+	if digest.AppUserID != user || digest.TotalReplies != 3 || len(digest.Issues) != 2 {
+		t.Fatalf("unexpected digest: %+v", digest)
+	}
+	// Issue 2 has the most recent reply and comes first
+	if digest.Issues[0].Number != 2 || digest.Issues[1].Number != 1 {
+		t.Errorf("expected issues ordered [2 1], got [%d %d]", digest.Issues[0].Number, digest.Issues[1].Number)
+	}
+	if len(digest.Issues[1].Replies) != 2 || !digest.Issues[1].LatestAt.Equal(notifyBase.Add(3*time.Hour)) {
+		t.Errorf("unexpected issue 1 group: %+v", digest.Issues[1])
+	}
+
+	MarkIssueRead(ctx, user, 1, time.Now())
+	MarkIssueRead(ctx, user, 2, time.Now())
+	digest, _ = BuildDigest(ctx, user, time.Time{})
+	if digest.TotalReplies != 0 || len(digest.Issues) != 0 {
+		t.Errorf("expected empty digest after reading, got %+v", digest)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	long := strings.Repeat("字", excerptMaxRunes+10)
+	if got := excerpt(long); got != strings.Repeat("字", excerptMaxRunes)+"…" {
+		t.Errorf("long excerpt not truncated on rune boundary: %q", got)
+	}
+	if got := excerpt("  a\n\nb  "); got != "a b" {
+		t.Errorf("expected collapsed whitespace, got %q", got)
+	}
+}
@@ -0,0 +1,83 @@
+package issue
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitInfo is the GitHub API rate limit reported by the latest response.
+type RateLimitInfo struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"` // When Remaining is restored to Limit
+}
+
+var (
+	rateLimit    RateLimitInfo
+	rateLimitMux sync.RWMutex
+)
+
+// RateLimit returns the rate limit from the X-RateLimit-* headers of the
+// latest GitHub response; the zero value before any request.
+func RateLimit() RateLimitInfo {
+	rateLimitMux.RLock()
+	defer rateLimitMux.RUnlock()
+	return rateLimit
+}
+
+// ErrRateLimited matches errors returned when GitHub rejects a request for
+// exceeding its rate limit. Use errors.As with *RateLimitError for the reset time.
+var ErrRateLimited = errors.New("github api: rate limited")
+
+// RateLimitError is returned when GitHub rejects a request with a rate
+// limit 403 or 429.
+type RateLimitError struct {
+	Reset time.Time // When requests may be retried
+	Body  string
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v until %s: %s", ErrRateLimited, e.Reset.Format(time.RFC3339), e.Body)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// checkRateLimit records the rate limit headers of resp and returns a
+// *RateLimitError, consuming the body, if resp is a rate limit rejection:
+// a primary limit (remaining 0) or a secondary limit (Retry-After).
+func checkRateLimit(resp *http.Response) error {
+	h := resp.Header
+	remaining := h.Get("X-RateLimit-Remaining")
+	var reset time.Time
+	if sec, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(sec, 0)
+	}
+	if remaining != "" {
+		info := RateLimitInfo{Reset: reset}
+		info.Remaining, _ = strconv.Atoi(remaining)
+		info.Limit, _ = strconv.Atoi(h.Get("X-RateLimit-Limit"))
+		rateLimitMux.Lock()
+		rateLimit = info
+		rateLimitMux.Unlock()
+	}
+
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+	retryAfter := h.Get("Retry-After")
+	if remaining != "0" && retryAfter == "" {
+		return nil // An ordinary permission error
+	}
+	if sec, err := strconv.Atoi(retryAfter); err == nil {
+		reset = time.Now().Add(time.Duration(sec) * time.Second)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return &RateLimitError{Reset: reset, Body: string(body)}
+}
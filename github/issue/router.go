@@ -3,6 +3,7 @@ package issue
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,7 +28,19 @@ func handleListIssues(c *gin.Context) {
 		perPage = 20
 	}
 
-	resp, err := ListIssues(c.Request.Context(), page, perPage)
+	opts := ListOptions{
+		State:     c.Query("state"),
+		Query:     c.Query("q"),
+		Sort:      c.Query("sort"),
+		Direction: c.Query("direction"),
+		Page:      page,
+		PerPage:   perPage,
+	}
+	if labels := c.Query("labels"); labels != "" {
+		opts.Labels = strings.Split(labels, ",")
+	}
+
+	resp, err := ListIssuesFiltered(c.Request.Context(), opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
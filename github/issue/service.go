@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -47,6 +51,23 @@ func cacheSet(key string, val any, ttl int) {
 	redis.CacheSet(key, val, ttl)
 }
 
+// cacheGetOrSet is redis.CacheGetOrSet, calling load directly when caching
+// is disabled or Redis is not configured.
+func cacheGetOrSet[T any](ctx context.Context, key string, ttl int, load func(context.Context) (T, error), opts ...redis.CacheOption) (v T, err error) {
+	if !cacheEnabled {
+		return load(ctx)
+	}
+	defer func() {
+		if recover() != nil { // Redis not configured
+			v, err = load(ctx)
+		}
+	}()
+	err = redis.CacheGetOrSet(ctx, key, ttl, &v, func(ctx context.Context) (any, error) {
+		return load(ctx)
+	}, opts...)
+	return v, err
+}
+
 func cacheDel(key string) {
 	if !cacheEnabled {
 		return
@@ -55,19 +76,60 @@ func cacheDel(key string) {
 	redis.CacheDel(key)
 }
 
-func cacheDelPattern(pattern string) {
+// cacheVersion prefixes cached issue keys; bump it when the cached value
+// format changes so stale entries are never decoded.
+const cacheVersion = "v3"
+
+// staleRetention is how long an expired entry is kept for its ETags to
+// revalidate it with a conditional request.
+const staleRetention = 24 * 60 * 60
+
+// cacheEntry is a cached response with the ETags of the GitHub requests it
+// was built from. Past FreshUntil it is revalidated with If-None-Match; a
+// 304 extends it without re-downloading.
+type cacheEntry[T any] struct {
+	Value      T                 `json:"value"`
+	ETags      map[string]string `json:"etags"`       // Request path -> ETag
+	FreshUntil int64             `json:"fresh_until"` // Unix seconds
+}
+
+func (e *cacheEntry[T]) fresh() bool {
+	return time.Now().Unix() < e.FreshUntil
+}
+
+// save stores the entry, fresh for ttl seconds.
+func (e *cacheEntry[T]) save(key string, ttl int) {
+	e.FreshUntil = time.Now().Unix() + int64(ttl)
+	cacheSet(key, e, ttl+staleRetention)
+}
+
+// Cache key namespaces. Issues and lists are versioned with cacheVersion;
+// reactions hold plain counts and are not.
+var (
+	issuesNS    = redis.NS("github:" + cacheVersion + ":issues")
+	listsNS     = issuesNS.NS("list")
+	reactionsNS = redis.NS("github:issues")
+)
+
+func listCacheKey(opts ListOptions) string {
+	return listsNS.Key(opts.cacheKey())
+}
+
+func issueCacheKey(number int) string {
+	return issuesNS.Key(strconv.Itoa(number))
+}
+
+func reactionsCacheKey(number int) string {
+	return reactionsNS.Key(strconv.Itoa(number) + ":reactions")
+}
+
+// cacheInvalidate drops every key of ns
+func cacheInvalidate(ns *redis.Namespace) {
 	if !cacheEnabled {
 		return
 	}
 	defer func() { recover() }()
-	ctx := context.Background()
-	keys, err := redis.Client().Keys(ctx, pattern).Result()
-	if err != nil {
-		return
-	}
-	for _, k := range keys {
-		redis.CacheDel(k)
-	}
+	ns.InvalidateAll(context.Background())
 }
 
 // ========== GitHub API Types (internal) ==========
@@ -91,6 +153,12 @@ type ghIssue struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// ghSearchResult is the /search/issues response; items share the issue shape.
+type ghSearchResult struct {
+	TotalCount int       `json:"total_count"`
+	Items      []ghIssue `json:"items"`
+}
+
 type ghComment struct {
 	ID        int64     `json:"id"`
 	Body      string    `json:"body"`
@@ -136,6 +204,13 @@ func getHTTPClient() *http.Client {
 }
 
 func doRequest(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	return doRequestWith(ctx, method, path, body, nil)
+}
+
+// doRequestWith is doRequest with extra headers, such as a preview Accept
+// header or If-None-Match. Rate limit rejections are returned as
+// *RateLimitError.
+func doRequestWith(ctx context.Context, method, path string, body interface{}, header map[string]string) (*http.Response, error) {
 	cfg := getConfig()
 
 	url := fmt.Sprintf("%s%s", getAPIBaseURL(), path)
@@ -160,41 +235,140 @@ func doRequest(ctx context.Context, method, path string, body interface{}) (*htt
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
 
-	return getHTTPClient().Do(req)
+	resp, err := getHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRateLimit(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
 }
 
-// ========== Service Functions ==========
+// APIError is returned when GitHub responds with an unexpected status.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
 
-// ListIssues returns paginated issues list (cache-first).
-func ListIssues(ctx context.Context, page, perPage int) (*ListIssuesResponse, error) {
-	cfg := getConfig()
+func (e *APIError) Error() string {
+	return fmt.Sprintf("github api: status %d, body: %s", e.StatusCode, e.Body)
+}
 
-	// Try cache first
-	cacheKey := fmt.Sprintf("github:issues:list:p%d:n%d", page, perPage)
-	var cached ListIssuesResponse
-	if cacheGet(cacheKey, &cached) {
-		return &cached, nil
+// isNotFound reports whether err is a GitHub 404 response.
+func isNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// doJSON performs a request, checks the status against want and decodes
+// the JSON response into out (skipped when out is nil).
+func doJSON(ctx context.Context, method, path string, body, out any, want ...int) error {
+	resp, err := doRequest(ctx, method, path, body)
+	if err != nil {
+		return fmt.Errorf("github api: %w", err)
 	}
+	defer resp.Body.Close()
 
-	// Fetch from GitHub
-	path := fmt.Sprintf("/repos/%s/%s/issues?page=%d&per_page=%d&state=all",
-		cfg.Owner, cfg.Repo, page, perPage)
+	if !slices.Contains(want, resp.StatusCode) {
+		data, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(data)}
+	}
 
-	resp, err := doRequest(ctx, "GET", path, nil)
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// getConditional GETs path, sending If-None-Match when etag is set. On 304
+// it reports notModified and returns etag unchanged; otherwise it decodes
+// the response into out and returns the new ETag.
+func getConditional(ctx context.Context, path, etag string, out any) (newETag string, notModified bool, err error) {
+	var header map[string]string
+	if etag != "" {
+		header = map[string]string{"If-None-Match": etag}
+	}
+	resp, err := doRequestWith(ctx, "GET", path, nil, header)
 	if err != nil {
-		return nil, fmt.Errorf("github api: %w", err)
+		return "", false, fmt.Errorf("github api: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("github api: status %d, body: %s", resp.StatusCode, body)
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return etag, true, nil
+	case http.StatusOK:
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return "", false, fmt.Errorf("decode response: %w", err)
+		}
+		return resp.Header.Get("ETag"), false, nil
+	default:
+		data, _ := io.ReadAll(resp.Body)
+		return "", false, &APIError{StatusCode: resp.StatusCode, Body: string(data)}
 	}
+}
 
-	var ghIssues []ghIssue
-	if err := json.NewDecoder(resp.Body).Decode(&ghIssues); err != nil {
-		return nil, fmt.Errorf("decode response: %w", err)
+// ========== Service Functions ==========
+
+// ListIssues returns paginated issues list (cache-first).
+func ListIssues(ctx context.Context, page, perPage int) (*ListIssuesResponse, error) {
+	return ListIssuesFiltered(ctx, ListOptions{Page: page, PerPage: perPage})
+}
+
+// ListIssuesFiltered returns issues matching opts (cache-first). A non-empty
+// Query uses the GitHub search API, which also fills TotalCount; otherwise
+// the issues endpoint is filtered by state and labels.
+//
+// Concurrent misses for the same list share one GitHub request. Expired
+// lists are served stale while they are revalidated in the background with
+// their ETag, so a rate limited GitHub keeps serving the stale list.
+func ListIssuesFiltered(ctx context.Context, opts ListOptions) (*ListIssuesResponse, error) {
+	cfg := getConfig()
+	opts = opts.withDefaults()
+
+	cacheKey := listCacheKey(opts)
+	entry, err := cacheGetOrSet(ctx, cacheKey, cfg.CacheTTL, func(ctx context.Context) (*cacheEntry[ListIssuesResponse], error) {
+		var stale cacheEntry[ListIssuesResponse]
+		cacheGet(redis.StaleKey(cacheKey), &stale)
+		return fetchIssueList(ctx, cfg, opts, &stale)
+	}, redis.StaleWhileRevalidate(staleRetention))
+	if err != nil {
+		return nil, err
+	}
+	return &entry.Value, nil
+}
+
+// fetchIssueList fetches the list from GitHub, revalidating stale with its
+// ETag. A 304 returns stale as is.
+func fetchIssueList(ctx context.Context, cfg *Config, opts ListOptions, stale *cacheEntry[ListIssuesResponse]) (*cacheEntry[ListIssuesResponse], error) {
+	var (
+		ghIssues []ghIssue
+		search   ghSearchResult
+		out      any = &ghIssues
+		path         = opts.issuesPath(cfg)
+	)
+	if opts.Query != "" {
+		out, path = &search, opts.searchPath(cfg)
+	}
+	etag, notModified, err := getConditional(ctx, path, stale.ETags[path], out)
+	if err != nil {
+		return nil, err
+	}
+	if notModified {
+		return stale, nil
+	}
+	total := 0
+	if opts.Query != "" {
+		ghIssues, total = search.Items, search.TotalCount
 	}
 
 	// Transform to DTOs
@@ -203,81 +377,140 @@ func ListIssues(ctx context.Context, page, perPage int) (*ListIssuesResponse, er
 		issues[i] = *transformToIssue(&gh)
 	}
 
-	result := &ListIssuesResponse{
-		Issues:  issues,
-		Page:    page,
-		PerPage: perPage,
-		HasMore: len(ghIssues) == perPage,
+	result := ListIssuesResponse{
+		Issues:     issues,
+		Page:       opts.Page,
+		PerPage:    opts.PerPage,
+		HasMore:    len(ghIssues) == opts.PerPage,
+		TotalCount: total,
+	}
+	if opts.Query != "" {
+		result.HasMore = opts.Page*opts.PerPage < total
 	}
+	return &cacheEntry[ListIssuesResponse]{Value: result, ETags: map[string]string{path: etag}}, nil
+}
 
-	// Cache result
-	cacheSet(cacheKey, result, cfg.CacheTTL)
+func (o ListOptions) withDefaults() ListOptions {
+	if o.State == "" {
+		o.State = "all"
+	}
+	if o.Page < 1 {
+		o.Page = 1
+	}
+	if o.PerPage < 1 {
+		o.PerPage = 20
+	}
+	o.Labels = slices.Sorted(slices.Values(o.Labels))
+	return o
+}
 
-	return result, nil
+// cacheKey encodes every filter field, so different filters never share an entry.
+func (o ListOptions) cacheKey() string {
+	q := url.Values{}
+	q.Set("state", o.State)
+	q.Set("labels", strings.Join(o.Labels, ","))
+	q.Set("q", o.Query)
+	q.Set("sort", o.Sort)
+	q.Set("direction", o.Direction)
+	q.Set("page", strconv.Itoa(o.Page))
+	q.Set("per_page", strconv.Itoa(o.PerPage))
+	return q.Encode()
 }
 
-// GetIssue returns issue detail with comments (cache-first).
+func (o ListOptions) issuesPath(cfg *Config) string {
+	q := url.Values{}
+	q.Set("state", o.State)
+	if len(o.Labels) > 0 {
+		q.Set("labels", strings.Join(o.Labels, ","))
+	}
+	if o.Sort != "" {
+		q.Set("sort", o.Sort)
+	}
+	if o.Direction != "" {
+		q.Set("direction", o.Direction)
+	}
+	q.Set("page", strconv.Itoa(o.Page))
+	q.Set("per_page", strconv.Itoa(o.PerPage))
+	return fmt.Sprintf("/repos/%s/%s/issues?%s", cfg.Owner, cfg.Repo, q.Encode())
+}
+
+func (o ListOptions) searchPath(cfg *Config) string {
+	terms := []string{o.Query, fmt.Sprintf("repo:%s/%s", cfg.Owner, cfg.Repo), "is:issue"}
+	if o.State != "all" {
+		terms = append(terms, "state:"+o.State)
+	}
+	for _, l := range o.Labels {
+		terms = append(terms, fmt.Sprintf("label:%q", l))
+	}
+
+	q := url.Values{}
+	q.Set("q", strings.Join(terms, " "))
+	if o.Sort != "" {
+		q.Set("sort", o.Sort)
+	}
+	if o.Direction != "" {
+		q.Set("order", o.Direction)
+	}
+	q.Set("page", strconv.Itoa(o.Page))
+	q.Set("per_page", strconv.Itoa(o.PerPage))
+	return "/search/issues?" + q.Encode()
+}
+
+// GetIssue returns issue detail with comments (cache-first). Expired
+// entries are revalidated like ListIssuesFiltered.
 func GetIssue(ctx context.Context, number int) (*IssueDetail, error) {
 	cfg := getConfig()
 
 	// Try cache first
-	cacheKey := fmt.Sprintf("github:issues:%d", number)
-	var cached IssueDetail
-	if cacheGet(cacheKey, &cached) {
-		return &cached, nil
+	cacheKey := issueCacheKey(number)
+	var entry cacheEntry[IssueDetail]
+	cached := cacheGet(cacheKey, &entry)
+	if cached && entry.fresh() {
+		return &entry.Value, nil
 	}
 
 	// Fetch issue
 	path := fmt.Sprintf("/repos/%s/%s/issues/%d", cfg.Owner, cfg.Repo, number)
-	resp, err := doRequest(ctx, "GET", path, nil)
-	if err != nil {
-		return nil, fmt.Errorf("github api: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("github api: status %d, body: %s", resp.StatusCode, body)
-	}
-
 	var ghIssue ghIssue
-	if err := json.NewDecoder(resp.Body).Decode(&ghIssue); err != nil {
-		return nil, fmt.Errorf("decode issue: %w", err)
-	}
-
-	// Fetch comments
-	commentsPath := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", cfg.Owner, cfg.Repo, number)
-	commentsResp, err := doRequest(ctx, "GET", commentsPath, nil)
+	issueETag, issueNotModified, err := getConditional(ctx, path, entry.ETags[path], &ghIssue)
 	if err != nil {
-		return nil, fmt.Errorf("github api comments: %w", err)
-	}
-	defer commentsResp.Body.Close()
-
-	if commentsResp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(commentsResp.Body)
-		return nil, fmt.Errorf("github api comments: status %d, body: %s", commentsResp.StatusCode, body)
+		if cached && errors.Is(err, ErrRateLimited) {
+			return &entry.Value, nil
+		}
+		return nil, err
 	}
 
+	// Fetch comments
+	commentsPath := path + "/comments"
 	var ghComments []ghComment
-	if err := json.NewDecoder(commentsResp.Body).Decode(&ghComments); err != nil {
-		return nil, fmt.Errorf("decode comments: %w", err)
+	commentsETag, commentsNotModified, err := getConditional(ctx, commentsPath, entry.ETags[commentsPath], &ghComments)
+	if err != nil {
+		if cached && errors.Is(err, ErrRateLimited) {
+			return &entry.Value, nil
+		}
+		return nil, fmt.Errorf("comments: %w", err)
 	}
 
-	// Transform to DTOs
-	comments := make([]Comment, len(ghComments))
-	for i, gh := range ghComments {
-		comments[i] = *transformToComment(&gh)
+	// Transform to DTOs, keeping the cached part that was not modified
+	result := entry.Value
+	if !issueNotModified {
+		result.Issue = *transformToIssue(&ghIssue)
 	}
-
-	result := &IssueDetail{
-		Issue:    *transformToIssue(&ghIssue),
-		Comments: comments,
+	if !commentsNotModified {
+		result.Comments = make([]Comment, len(ghComments))
+		for i, gh := range ghComments {
+			result.Comments[i] = *transformToComment(&gh)
+		}
 	}
 
 	// Cache result
-	cacheSet(cacheKey, result, cfg.CacheTTL)
+	entry = cacheEntry[IssueDetail]{
+		Value: result,
+		ETags: map[string]string{path: issueETag, commentsPath: commentsETag},
+	}
+	entry.save(cacheKey, cfg.CacheTTL)
 
-	return result, nil
+	return &result, nil
 }
 
 // CreateIssue creates a new issue on GitHub (invalidates cache).
@@ -312,6 +545,8 @@ func CreateIssue(ctx context.Context, req *CreateIssueRequest, appUserID string)
 	// Invalidate list cache
 	invalidateListCache()
 
+	trackParticipation(ctx, appUserID, ghIssue.Number)
+
 	return transformToIssue(&ghIssue), nil
 }
 
@@ -344,11 +579,230 @@ func CreateComment(ctx context.Context, number int, req *CreateCommentRequest, a
 	}
 
 	// Invalidate issue cache
-	cacheDel(fmt.Sprintf("github:issues:%d", number))
+	cacheDel(issueCacheKey(number))
+
+	trackParticipation(ctx, appUserID, number)
 
 	return transformToComment(&ghComment), nil
 }
 
+// reactionsAccept is the Accept header of the reactions preview API.
+const reactionsAccept = "application/vnd.github.squirrel-girl-preview+json"
+
+// validReactions are the reaction contents GitHub accepts.
+var validReactions = []string{"+1", "-1", "laugh", "confused", "heart", "hooray", "rocket", "eyes"}
+
+// AddReaction adds a reaction ("+1", "heart", ...) to an issue. Reactions
+// are made as the token's user, so adding the same one twice is a no-op.
+func AddReaction(ctx context.Context, number int, reaction string) error {
+	if !slices.Contains(validReactions, reaction) {
+		return fmt.Errorf("invalid reaction %q", reaction)
+	}
+	cfg := getConfig()
+
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/reactions", cfg.Owner, cfg.Repo, number)
+	resp, err := doRequestWith(ctx, "POST", path, map[string]string{"content": reaction}, map[string]string{"Accept": reactionsAccept})
+	if err != nil {
+		return fmt.Errorf("github api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// 200 means the reaction already existed
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	cacheDel(reactionsCacheKey(number))
+	return nil
+}
+
+// GetReactions returns reaction counts of an issue by content (cache-first,
+// cached for github.reactions_cache_ttl seconds).
+func GetReactions(ctx context.Context, number int) (map[string]int, error) {
+	cfg := getConfig()
+
+	cacheKey := reactionsCacheKey(number)
+	var cached map[string]int
+	if cacheGet(cacheKey, &cached) {
+		return cached, nil
+	}
+
+	counts := make(map[string]int)
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("/repos/%s/%s/issues/%d/reactions?per_page=100&page=%d", cfg.Owner, cfg.Repo, number, page)
+		resp, err := doRequestWith(ctx, "GET", path, nil, map[string]string{"Accept": reactionsAccept})
+		if err != nil {
+			return nil, fmt.Errorf("github api: %w", err)
+		}
+
+		var batch []struct {
+			Content string `json:"content"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+		}
+		err = json.NewDecoder(resp.Body).Decode(&batch)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode reactions: %w", err)
+		}
+
+		for _, r := range batch {
+			counts[r.Content]++
+		}
+		if len(batch) < 100 {
+			break
+		}
+	}
+
+	cacheSet(cacheKey, counts, cfg.ReactionsCacheTTL)
+	return counts, nil
+}
+
+// CloseIssue closes an issue as completed, first posting comment when it
+// is not empty (invalidates cache).
+func CloseIssue(ctx context.Context, number int, comment string) error {
+	cfg := getConfig()
+
+	if comment != "" {
+		path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", cfg.Owner, cfg.Repo, number)
+		if err := doJSON(ctx, "POST", path, map[string]string{"body": comment}, nil, http.StatusCreated); err != nil {
+			return err
+		}
+	}
+
+	return setIssueState(ctx, number, map[string]string{"state": "closed", "state_reason": "completed"})
+}
+
+// ReopenIssue reopens a closed issue (invalidates cache).
+func ReopenIssue(ctx context.Context, number int) error {
+	return setIssueState(ctx, number, map[string]string{"state": "open"})
+}
+
+func setIssueState(ctx context.Context, number int, payload map[string]string) error {
+	cfg := getConfig()
+
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d", cfg.Owner, cfg.Repo, number)
+	if err := doJSON(ctx, "PATCH", path, payload, nil, http.StatusOK); err != nil {
+		return err
+	}
+
+	cacheDel(issueCacheKey(number))
+	invalidateListCache()
+	return nil
+}
+
+// UpdateIssue edits the title, body and/or state of an issue (invalidates
+// cache). A new body keeps the app_user_id metadata of the current one, so
+// the issue stays attributed to the user who submitted it.
+func UpdateIssue(ctx context.Context, number int, req *UpdateIssueRequest) (*Issue, error) {
+	if req.Title == "" && req.Body == "" && req.State == "" {
+		return nil, errors.New("nothing to update")
+	}
+	if req.State != "" && req.State != "open" && req.State != "closed" {
+		return nil, fmt.Errorf("invalid state %q", req.State)
+	}
+	cfg := getConfig()
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d", cfg.Owner, cfg.Repo, number)
+
+	payload := map[string]string{}
+	if req.Title != "" {
+		payload["title"] = req.Title
+	}
+	if req.State != "" {
+		payload["state"] = req.State
+	}
+	if req.Body != "" {
+		var current ghIssue
+		if err := doJSON(ctx, "GET", path, nil, &current, http.StatusOK); err != nil {
+			return nil, err
+		}
+		payload["body"] = req.Body
+		if userID := extractMetadata(current.Body); userID != "" {
+			payload["body"] = injectMetadata(req.Body, userID)
+		}
+	}
+
+	var ghIssue ghIssue
+	if err := doJSON(ctx, "PATCH", path, payload, &ghIssue, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	cacheDel(issueCacheKey(number))
+	invalidateListCache()
+	return transformToIssue(&ghIssue), nil
+}
+
+// AddLabels adds labels to an issue, keeping its existing ones (invalidates
+// cache). With github.auto_create_labels, labels missing from the repo are
+// created first.
+func AddLabels(ctx context.Context, number int, labels []string) error {
+	return putLabels(ctx, "POST", number, labels)
+}
+
+// SetLabels replaces all labels of an issue; an empty list clears them
+// (invalidates cache). Missing labels are handled as in AddLabels.
+func SetLabels(ctx context.Context, number int, labels []string) error {
+	return putLabels(ctx, "PUT", number, labels)
+}
+
+func putLabels(ctx context.Context, method string, number int, labels []string) error {
+	cfg := getConfig()
+
+	if cfg.AutoCreateLabels {
+		if err := createMissingLabels(ctx, cfg, labels); err != nil {
+			return err
+		}
+	}
+
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/labels", cfg.Owner, cfg.Repo, number)
+	if err := doJSON(ctx, method, path, map[string][]string{"labels": labels}, nil, http.StatusOK); err != nil {
+		return err
+	}
+
+	cacheDel(issueCacheKey(number))
+	invalidateListCache()
+	return nil
+}
+
+// RemoveLabel removes a label from an issue (invalidates cache). Removing a
+// label the issue does not have is a no-op.
+func RemoveLabel(ctx context.Context, number int, label string) error {
+	cfg := getConfig()
+
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/labels/%s", cfg.Owner, cfg.Repo, number, url.PathEscape(label))
+	if err := doJSON(ctx, "DELETE", path, nil, nil, http.StatusOK, http.StatusNoContent); err != nil && !isNotFound(err) {
+		return err
+	}
+
+	cacheDel(issueCacheKey(number))
+	invalidateListCache()
+	return nil
+}
+
+// createMissingLabels creates the labels that do not exist in the repo yet.
+func createMissingLabels(ctx context.Context, cfg *Config, labels []string) error {
+	existing, err := listLabels(ctx)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/repos/%s/%s/labels", cfg.Owner, cfg.Repo)
+	for _, label := range labels {
+		if _, ok := existing[label]; ok {
+			continue
+		}
+		// 422 means it was created concurrently
+		if err := doJSON(ctx, "POST", path, map[string]string{"name": label}, nil, http.StatusCreated, http.StatusUnprocessableEntity); err != nil {
+			return fmt.Errorf("create label %s: %w", label, err)
+		}
+	}
+	return nil
+}
+
 // ========== Transform Functions ==========
 
 func transformToIssue(gh *ghIssue) *Issue {
@@ -392,5 +846,5 @@ func transformToComment(gh *ghComment) *Comment {
 // ========== Cache Invalidation ==========
 
 func invalidateListCache() {
-	cacheDelPattern("github:issues:list:*")
+	cacheInvalidate(listsNS)
 }
@@ -3,12 +3,20 @@ package issue
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/spf13/viper"
+	"github.com/wordgate/qtoolkit/redis"
 )
 
 // ========== Service Function Tests ==========
@@ -88,6 +96,89 @@ func TestListIssues(t *testing.T) {
 	}
 }
 
+func TestListIssuesFiltered(t *testing.T) {
+	DisableCache()
+	defer EnableCache()
+
+	var searched, listed url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		issue := ghIssue{Number: 7, Title: "Crash on login", State: "open", Labels: []ghLabel{{Name: "bug"}}}
+
+		switch r.URL.Path {
+		case "/search/issues":
+			searched = r.URL.Query()
+			json.NewEncoder(w).Encode(ghSearchResult{TotalCount: 41, Items: []ghIssue{issue}})
+		case "/repos/test-owner/test-repo/issues":
+			listed = r.URL.Query()
+			json.NewEncoder(w).Encode([]ghIssue{issue})
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	viper.Reset()
+	viper.Set("github.owner", "test-owner")
+	viper.Set("github.repo", "test-repo")
+	viper.Set("github.token", "ghp_test123")
+
+	SetAPIBaseURL(server.URL)
+	resetClient()
+
+	resp, err := ListIssuesFiltered(context.Background(), ListOptions{
+		Query:     "login crash",
+		State:     "open",
+		Labels:    []string{"bug", "ios app"},
+		Sort:      "updated",
+		Direction: "desc",
+		Page:      2,
+		PerPage:   20,
+	})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	wantQ := `login crash repo:test-owner/test-repo is:issue state:open label:"bug" label:"ios app"`
+	if searched.Get("q") != wantQ || searched.Get("sort") != "updated" || searched.Get("order") != "desc" || searched.Get("page") != "2" {
+		t.Errorf("unexpected search query: %v", searched)
+	}
+	if resp.TotalCount != 41 || !resp.HasMore || len(resp.Issues) != 1 || resp.Issues[0].Labels[0] != "bug" {
+		t.Errorf("unexpected search response: %+v", resp)
+	}
+
+	resp, err = ListIssuesFiltered(context.Background(), ListOptions{State: "closed", Labels: []string{"bug", "api"}})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if listed.Get("state") != "closed" || listed.Get("labels") != "api,bug" || listed.Get("per_page") != "20" {
+		t.Errorf("unexpected issues query: %v", listed)
+	}
+	if resp.TotalCount != 0 || resp.HasMore {
+		t.Errorf("unexpected list response: %+v", resp)
+	}
+}
+
+func TestListOptionsCacheKey(t *testing.T) {
+	base := ListOptions{State: "open", Labels: []string{"b", "a"}}.withDefaults()
+	same := ListOptions{State: "open", Labels: []string{"a", "b"}}.withDefaults()
+	if base.cacheKey() != same.cacheKey() {
+		t.Error("label order should not change the cache key")
+	}
+	for _, o := range []ListOptions{
+		{State: "closed", Labels: []string{"a", "b"}},
+		{State: "open", Labels: []string{"a"}},
+		{State: "open", Labels: []string{"a", "b"}, Query: "x"},
+		{State: "open", Labels: []string{"a", "b"}, Sort: "created"},
+		{State: "open", Labels: []string{"a", "b"}, Direction: "asc"},
+		{State: "open", Labels: []string{"a", "b"}, Page: 2},
+		{State: "open", Labels: []string{"a", "b"}, PerPage: 50},
+	} {
+		if o.withDefaults().cacheKey() == base.cacheKey() {
+			t.Errorf("options %+v share the cache key of %+v", o, base)
+		}
+	}
+}
+
 func TestGetIssue(t *testing.T) {
 	DisableCache()
 	defer EnableCache()
@@ -293,6 +384,272 @@ func TestCreateComment(t *testing.T) {
 	}
 }
 
+func TestReactions(t *testing.T) {
+	DisableCache()
+	defer EnableCache()
+
+	var added string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != reactionsAccept {
+			t.Errorf("missing preview Accept header: %s", r.Header.Get("Accept"))
+		}
+		if r.URL.Path != "/repos/test-owner/test-repo/issues/42/reactions" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case "POST":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			added = body["content"]
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":1}`))
+		case "GET":
+			w.Write([]byte(`[{"content":"+1"},{"content":"+1"},{"content":"heart"}]`))
+		}
+	}))
+	defer server.Close()
+
+	viper.Reset()
+	viper.Set("github.owner", "test-owner")
+	viper.Set("github.repo", "test-repo")
+	viper.Set("github.token", "ghp_test123")
+
+	SetAPIBaseURL(server.URL)
+	resetClient()
+
+	ctx := context.Background()
+	if err := AddReaction(ctx, 42, "+1"); err != nil || added != "+1" {
+		t.Fatalf("AddReaction failed: %v (sent %q)", err, added)
+	}
+	if err := AddReaction(ctx, 42, "thumbsup"); err == nil {
+		t.Error("expected error for invalid reaction")
+	}
+
+	counts, err := GetReactions(ctx, 42)
+	if err != nil {
+		t.Fatalf("GetReactions failed: %v", err)
+	}
+	if counts["+1"] != 2 || counts["heart"] != 1 || len(counts) != 2 {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+}
+
+func TestCloseAndReopenIssue(t *testing.T) {
+	DisableCache()
+	defer EnableCache()
+
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/repos/test-owner/test-repo/issues/42/comments":
+			calls = append(calls, "comment:"+body["body"])
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == "PATCH" && r.URL.Path == "/repos/test-owner/test-repo/issues/42":
+			calls = append(calls, "state:"+body["state"])
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	viper.Reset()
+	viper.Set("github.owner", "test-owner")
+	viper.Set("github.repo", "test-repo")
+	viper.Set("github.token", "ghp_test123")
+
+	SetAPIBaseURL(server.URL)
+	resetClient()
+
+	ctx := context.Background()
+	if err := CloseIssue(ctx, 42, "Fixed in 2.3"); err != nil {
+		t.Fatalf("CloseIssue failed: %v", err)
+	}
+	if err := CloseIssue(ctx, 42, ""); err != nil {
+		t.Fatalf("CloseIssue without comment failed: %v", err)
+	}
+	if err := ReopenIssue(ctx, 42); err != nil {
+		t.Fatalf("ReopenIssue failed: %v", err)
+	}
+
+	want := []string{"comment:Fixed in 2.3", "state:closed", "state:closed", "state:open"}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("expected calls %v, got %v", want, calls)
+	}
+}
+
+func TestUpdateIssue(t *testing.T) {
+	DisableCache()
+	defer EnableCache()
+
+	var patched map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/test-owner/test-repo/issues/42" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		current := ghIssue{Number: 42, Title: "crash", Body: "app crash\n\n<!-- app_user_id: user-7 -->", State: "open"}
+		if r.Method == "PATCH" {
+			patched = nil
+			json.NewDecoder(r.Body).Decode(&patched)
+			for k, v := range patched {
+				switch k {
+				case "title":
+					current.Title = v
+				case "body":
+					current.Body = v
+				case "state":
+					current.State = v
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(current)
+	}))
+	defer server.Close()
+
+	viper.Reset()
+	viper.Set("github.owner", "test-owner")
+	viper.Set("github.repo", "test-repo")
+	viper.Set("github.token", "ghp_test123")
+	SetAPIBaseURL(server.URL)
+	resetClient()
+
+	ctx := context.Background()
+	updated, err := UpdateIssue(ctx, 42, &UpdateIssueRequest{Title: "App crashes on launch", Body: "The app crashes on launch"})
+	if err != nil {
+		t.Fatalf("UpdateIssue failed: %v", err)
+	}
+	if patched["body"] != "The app crashes on launch\n\n<!-- app_user_id: user-7 -->" {
+		t.Errorf("metadata should be preserved, got body %q", patched["body"])
+	}
+	if _, ok := patched["state"]; ok {
+		t.Errorf("unset state should not be sent: %v", patched)
+	}
+	if updated.Title != "App crashes on launch" || updated.Body != "The app crashes on launch" {
+		t.Errorf("unexpected issue: %+v", updated)
+	}
+
+	if _, err := UpdateIssue(ctx, 42, &UpdateIssueRequest{State: "closed"}); err != nil || fmt.Sprint(patched) != "map[state:closed]" {
+		t.Errorf("expected state-only update, got %v, %v", patched, err)
+	}
+	if _, err := UpdateIssue(ctx, 42, &UpdateIssueRequest{}); err == nil {
+		t.Error("expected error for empty update")
+	}
+	if _, err := UpdateIssue(ctx, 42, &UpdateIssueRequest{State: "merged"}); err == nil {
+		t.Error("expected error for invalid state")
+	}
+}
+
+func TestLabels(t *testing.T) {
+	DisableCache()
+	defer EnableCache()
+
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/repos/test-owner/test-repo/labels":
+			json.NewEncoder(w).Encode([]ghLabelFull{{Name: "bug"}})
+			return
+		case r.Method == "POST" && r.URL.Path == "/repos/test-owner/test-repo/labels":
+			calls = append(calls, fmt.Sprint("create:", body["name"]))
+			w.WriteHeader(http.StatusCreated)
+		case r.URL.Path == "/repos/test-owner/test-repo/issues/42/labels":
+			calls = append(calls, fmt.Sprint(r.Method, ":", body["labels"]))
+		case r.Method == "DELETE" && r.URL.Path == "/repos/test-owner/test-repo/issues/42/labels/needs triage":
+			calls = append(calls, "remove:needs triage")
+		case r.Method == "DELETE" && r.URL.Path == "/repos/test-owner/test-repo/issues/42/labels/absent":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Label does not exist"}`))
+			return
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	viper.Reset()
+	viper.Set("github.owner", "test-owner")
+	viper.Set("github.repo", "test-repo")
+	viper.Set("github.token", "ghp_test123")
+	SetAPIBaseURL(server.URL)
+	resetClient()
+
+	ctx := context.Background()
+	if err := AddLabels(ctx, 42, []string{"bug", "ios"}); err != nil {
+		t.Fatalf("AddLabels failed: %v", err)
+	}
+	if err := RemoveLabel(ctx, 42, "needs triage"); err != nil {
+		t.Fatalf("RemoveLabel failed: %v", err)
+	}
+	if err := RemoveLabel(ctx, 42, "absent"); err != nil {
+		t.Errorf("removing a missing label should be a no-op: %v", err)
+	}
+
+	viper.Set("github.auto_create_labels", true)
+	resetClient()
+	if err := SetLabels(ctx, 42, []string{"bug", "android"}); err != nil {
+		t.Fatalf("SetLabels failed: %v", err)
+	}
+
+	want := []string{"POST:[bug ios]", "remove:needs triage", "create:android", "PUT:[bug android]"}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("expected calls %v, got %v", want, calls)
+	}
+}
+
+func TestLabelsInvalidateCache(t *testing.T) {
+	if os.Getenv("REDIS_TEST_SKIP") != "" {
+		t.Skip("Skipping Redis tests (REDIS_TEST_SKIP is set)")
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	viper.Reset()
+	viper.Set("github.owner", "o")
+	viper.Set("github.repo", "r")
+	viper.Set("redis.addr", "localhost:6379")
+	SetAPIBaseURL(server.URL)
+	resetClient()
+	if err := func() (err error) {
+		defer recoverRedis(&err)
+		return redis.Client().Ping(context.Background()).Err()
+	}(); err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+
+	number := int(time.Now().UnixNano() % 1_000_000_000)
+	listKey := listCacheKey(ListOptions{Labels: []string{"ios"}}.withDefaults())
+	cacheSet(issueCacheKey(number), cacheEntry[IssueDetail]{}, 60)
+	cacheSet(listKey, cacheEntry[ListIssuesResponse]{}, 60)
+	defer cacheDel(issueCacheKey(number))
+	defer cacheDel(listKey)
+
+	if err := AddLabels(context.Background(), number, []string{"ios"}); err != nil {
+		t.Fatalf("AddLabels failed: %v", err)
+	}
+	var entry cacheEntry[IssueDetail]
+	if cacheGet(issueCacheKey(number), &entry) {
+		t.Error("issue cache should be invalidated")
+	}
+	var list cacheEntry[ListIssuesResponse]
+	if cacheGet(listKey, &list) {
+		t.Error("list cache should be invalidated")
+	}
+}
+
 // ========== Transform Function Tests ==========
 
 func TestTransformToIssue(t *testing.T) {
@@ -351,3 +708,182 @@ func TestTransformToComment(t *testing.T) {
 		t.Error("expected IsOfficial to be true")
 	}
 }
+
+func TestRateLimited(t *testing.T) {
+	DisableCache()
+	defer EnableCache()
+	reset := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Reset", fmt.Sprint(reset.Unix()))
+		if r.URL.Path == "/repos/o/r/issues/1" {
+			w.Header().Set("X-RateLimit-Remaining", "42")
+			json.NewEncoder(w).Encode(ghIssue{Number: 1})
+			return
+		}
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"API rate limit exceeded"}`))
+	}))
+	defer server.Close()
+
+	viper.Reset()
+	viper.Set("github.owner", "o")
+	viper.Set("github.repo", "r")
+	SetAPIBaseURL(server.URL)
+	resetClient()
+
+	_, err := ListIssues(context.Background(), 1, 20)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+	var rlErr *RateLimitError
+	if !errors.As(err, &rlErr) || !rlErr.Reset.Equal(reset) {
+		t.Errorf("expected reset %v, got %v", reset, err)
+	}
+	if rl := RateLimit(); rl.Remaining != 0 || rl.Limit != 5000 || !rl.Reset.Equal(reset) {
+		t.Errorf("unexpected rate limit: %+v", rl)
+	}
+
+	// Comments are rate limited too
+	if _, err := GetIssue(context.Background(), 1); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got %v", err)
+	}
+	if rl := RateLimit(); rl.Remaining != 0 {
+		t.Errorf("expected latest remaining 0, got %+v", rl)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	if os.Getenv("REDIS_TEST_SKIP") != "" {
+		t.Skip("Skipping Redis tests (REDIS_TEST_SKIP is set)")
+	}
+	number := int(time.Now().UnixNano() % 1_000_000_000)
+	var fetched, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"v1"`
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fetched++
+		w.Header().Set("ETag", etag)
+		if strings.HasSuffix(r.URL.Path, "/comments") {
+			json.NewEncoder(w).Encode([]ghComment{{ID: 1, Body: "reply"}})
+			return
+		}
+		json.NewEncoder(w).Encode(ghIssue{Number: number, Title: "Cached"})
+	}))
+	defer server.Close()
+
+	viper.Reset()
+	viper.Set("github.owner", "o")
+	viper.Set("github.repo", "r")
+	viper.Set("redis.addr", "localhost:6379")
+	SetAPIBaseURL(server.URL)
+	resetClient()
+	if err := func() (err error) {
+		defer recoverRedis(&err)
+		return redis.Client().Ping(context.Background()).Err()
+	}(); err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+	defer cacheDel(issueCacheKey(number))
+
+	ctx := context.Background()
+	if _, err := GetIssue(ctx, number); err != nil {
+		t.Fatalf("GetIssue failed: %v", err)
+	}
+	if _, err := GetIssue(ctx, number); err != nil || fetched != 2 || notModified != 0 {
+		t.Fatalf("fresh entry should be served from cache: fetched=%d notModified=%d err=%v", fetched, notModified, err)
+	}
+
+	// Expire the entry: it is revalidated, not re-downloaded
+	var entry cacheEntry[IssueDetail]
+	if !cacheGet(issueCacheKey(number), &entry) || len(entry.ETags) != 2 {
+		t.Fatalf("expected cached entry with ETags, got %+v", entry)
+	}
+	entry.FreshUntil = time.Now().Unix() - 1
+	cacheSet(issueCacheKey(number), entry, 60)
+
+	detail, err := GetIssue(ctx, number)
+	if err != nil {
+		t.Fatalf("GetIssue failed: %v", err)
+	}
+	if fetched != 2 || notModified != 2 {
+		t.Errorf("expected 2 conditional requests, got fetched=%d notModified=%d", fetched, notModified)
+	}
+	if detail.Title != "Cached" || len(detail.Comments) != 1 || detail.Comments[0].Body != "reply" {
+		t.Errorf("unexpected detail: %+v", detail)
+	}
+	if !cacheGet(issueCacheKey(number), &entry) || !entry.fresh() {
+		t.Errorf("304 should refresh the entry: %+v", entry)
+	}
+}
+
+func TestListIssuesSharedLoad(t *testing.T) {
+	if os.Getenv("REDIS_TEST_SKIP") != "" {
+		t.Skip("Skipping Redis tests (REDIS_TEST_SKIP is set)")
+	}
+	var fetched, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"list-v1"`
+		if r.Header.Get("If-None-Match") == etag {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fetched.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("ETag", etag)
+		json.NewEncoder(w).Encode([]ghIssue{{Number: 1, Title: "Listed"}})
+	}))
+	defer server.Close()
+
+	viper.Reset()
+	viper.Set("github.owner", "o")
+	viper.Set("github.repo", "r")
+	viper.Set("redis.addr", "localhost:6379")
+	SetAPIBaseURL(server.URL)
+	resetClient()
+	if err := func() (err error) {
+		defer recoverRedis(&err)
+		return redis.Client().Ping(context.Background()).Err()
+	}(); err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+	key := listCacheKey(ListOptions{Page: 1, PerPage: 20}.withDefaults())
+	defer cacheDel(key)
+	defer cacheDel(redis.StaleKey(key))
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp, err := ListIssues(ctx, 1, 20); err != nil || len(resp.Issues) != 1 {
+				t.Errorf("ListIssues: %+v, %v", resp, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := fetched.Load(); n != 1 {
+		t.Errorf("concurrent misses should share one request, got %d", n)
+	}
+
+	// Expire the list: the stale copy is served and revalidated in the background
+	cacheDel(key)
+	resp, err := ListIssues(ctx, 1, 20)
+	if err != nil || resp.Issues[0].Title != "Listed" {
+		t.Fatalf("expected stale list, got %+v, %v", resp, err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for notModified.Load() != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if fetched.Load() != 1 || notModified.Load() != 1 {
+		t.Errorf("expected one conditional revalidation, got fetched=%d notModified=%d", fetched.Load(), notModified.Load())
+	}
+}
@@ -0,0 +1,118 @@
+package issue
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBytes is the largest payload GitHub delivers.
+const maxWebhookBytes = 25 << 20
+
+// Event is an issues or issue_comment webhook event.
+type Event struct {
+	Type       string   `json:"type"`   // "issues" or "issue_comment"
+	Action     string   `json:"action"` // e.g. opened, edited, closed, labeled; created, deleted for comments
+	DeliveryID string   `json:"delivery_id"`
+	Sender     string   `json:"sender"`      // GitHub login of the user who triggered the event
+	AppUserID  string   `json:"app_user_id"` // App user who created the issue, "" if not created by the app
+	Issue      Issue    `json:"issue"`
+	Comment    *Comment `json:"comment,omitempty"` // issue_comment events only
+}
+
+// ghWebhookPayload is the part of issues and issue_comment payloads used.
+type ghWebhookPayload struct {
+	Action  string     `json:"action"`
+	Issue   *ghIssue   `json:"issue"`
+	Comment *ghComment `json:"comment"`
+	Sender  ghUser     `json:"sender"`
+}
+
+// WebhookHandler receives GitHub webhooks for the repository. It verifies
+// the X-Hub-Signature-256 signature with secret, invalidates the cached
+// issue and lists on issues and issue_comment events, and, if events is not
+// nil, forwards them as Event. Other events are acknowledged and skipped.
+//
+// The send to events blocks until it is received or the request ends, so
+// use a buffered channel and keep up with it; GitHub waits 10 seconds.
+//
+// Usage:
+//
+//	events := make(chan issue.Event, 100)
+//	r.POST("/webhooks/github", issue.WebhookHandler(os.Getenv("GITHUB_WEBHOOK_SECRET"), events))
+//	go func() {
+//	    for e := range events {
+//	        if e.Type == "issue_comment" && e.Comment.IsOfficial {
+//	            notifyUser(e.AppUserID, e.Issue.Number)
+//	        }
+//	    }
+//	}()
+func WebhookHandler(secret string, events chan<- Event) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBytes))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+			return
+		}
+		if !validSignature(secret, body, c.GetHeader("X-Hub-Signature-256")) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+			return
+		}
+
+		typ := c.GetHeader("X-GitHub-Event")
+		if typ != "issues" && typ != "issue_comment" {
+			c.Status(http.StatusOK)
+			return
+		}
+		var payload ghWebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil || payload.Issue == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		// Comments change the issue's comment count in lists too
+		cacheDel(issueCacheKey(payload.Issue.Number))
+		invalidateListCache()
+
+		if events != nil {
+			event := Event{
+				Type:       typ,
+				Action:     payload.Action,
+				DeliveryID: c.GetHeader("X-GitHub-Delivery"),
+				Sender:     payload.Sender.Login,
+				AppUserID:  extractMetadata(payload.Issue.Body),
+				Issue:      *transformToIssue(payload.Issue),
+			}
+			if typ == "issue_comment" && payload.Comment != nil {
+				event.Comment = transformToComment(payload.Comment)
+			}
+			select {
+			case events <- event:
+			case <-c.Request.Context().Done():
+			}
+		}
+		c.Status(http.StatusOK)
+	}
+}
+
+// validSignature reports whether signature is "sha256=" followed by the
+// HMAC-SHA256 of body with secret. An empty secret accepts nothing.
+func validSignature(secret string, body []byte, signature string) bool {
+	sig, ok := strings.CutPrefix(signature, "sha256=")
+	if secret == "" || !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,147 @@
+package issue
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/wordgate/qtoolkit/redis"
+)
+
+const webhookSecret = "s3cret"
+
+func sign(body string) string {
+	mac := hmac.New(sha256.New, []byte(webhookSecret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func deliver(events chan<- Event, typ, body, signature string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/webhook", WebhookHandler(webhookSecret, events))
+	req := httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", typ)
+	req.Header.Set("X-GitHub-Delivery", "delivery-1")
+	req.Header.Set("X-Hub-Signature-256", signature)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+const commentPayload = `{
+	"action": "created",
+	"issue": {"number": 7, "title": "Crash on start", "state": "open", "comments": 2,
+		"body": "It crashes\n\n<!-- app_user_id: user-42 -->", "labels": [{"name": "official-reply"}]},
+	"comment": {"id": 99, "body": "Fixed in 1.2", "user": {"login": "support-bot"}},
+	"sender": {"login": "support-bot"}
+}`
+
+func TestWebhookSignature(t *testing.T) {
+	DisableCache()
+	defer EnableCache()
+
+	for name, signature := range map[string]string{
+		"missing":    "",
+		"wrong":      sign(commentPayload + " "),
+		"not hex":    "sha256=zz",
+		"no prefix":  strings.TrimPrefix(sign(commentPayload), "sha256="),
+		"sha1 style": "sha1=" + strings.TrimPrefix(sign(commentPayload), "sha256="),
+	} {
+		events := make(chan Event, 1)
+		if w := deliver(events, "issue_comment", commentPayload, signature); w.Code != http.StatusUnauthorized || len(events) != 0 {
+			t.Errorf("%s signature: expected 401 without event, got %d", name, w.Code)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/webhook", WebhookHandler("", nil))
+	req := httptest.NewRequest("POST", "/webhook", strings.NewReader("{}"))
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(hmac.New(sha256.New, nil).Sum(nil)))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("an empty secret must reject every delivery, got %d", w.Code)
+	}
+}
+
+func TestWebhookEvents(t *testing.T) {
+	DisableCache()
+	defer EnableCache()
+	viper.Reset()
+	viper.Set("github.official_users", []string{"support-bot"})
+	resetClient()
+
+	events := make(chan Event, 1)
+	if w := deliver(events, "issue_comment", commentPayload, sign(commentPayload)); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	e := <-events
+	if e.Type != "issue_comment" || e.Action != "created" || e.DeliveryID != "delivery-1" || e.Sender != "support-bot" ||
+		e.AppUserID != "user-42" || e.Issue.Number != 7 || e.Issue.Body != "It crashes" || !e.Issue.HasOfficial {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if e.Comment == nil || e.Comment.ID != 99 || !e.Comment.IsOfficial {
+		t.Errorf("unexpected comment: %+v", e.Comment)
+	}
+
+	// Unsupported events are acknowledged and skipped
+	ping := `{"zen": "Keep it logically awesome."}`
+	if w := deliver(events, "ping", ping, sign(ping)); w.Code != http.StatusOK || len(events) != 0 {
+		t.Errorf("expected 200 without event for ping, got %d", w.Code)
+	}
+
+	// Without a channel events are only used for invalidation
+	if w := deliver(nil, "issues", commentPayload, sign(commentPayload)); w.Code != http.StatusOK {
+		t.Errorf("expected 200 without channel, got %d", w.Code)
+	}
+
+	if w := deliver(events, "issues", `{"action": "opened"}`, sign(`{"action": "opened"}`)); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for payload without issue, got %d", w.Code)
+	}
+}
+
+func TestWebhookInvalidatesCache(t *testing.T) {
+	viper.Reset()
+	viper.Set("redis.addr", "localhost:6379")
+	resetClient()
+	if err := func() (err error) {
+		defer recoverRedis(&err)
+		return redis.Client().Ping(context.Background()).Err()
+	}(); err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+
+	listKey := listCacheKey(ListOptions{State: "open", Page: 1, PerPage: 20})
+	keys := []string{issueCacheKey(7), issueCacheKey(8), listKey}
+	for _, key := range keys {
+		cacheSet(key, cacheEntry[string]{Value: "cached"}, 60)
+	}
+	t.Cleanup(func() {
+		for _, key := range keys {
+			cacheDel(key)
+		}
+	})
+
+	if w := deliver(nil, "issue_comment", commentPayload, sign(commentPayload)); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var entry cacheEntry[string]
+	if cacheGet(issueCacheKey(7), &entry) {
+		t.Error("issue 7 still cached")
+	}
+	if cacheGet(listKey, &entry) {
+		t.Error("issue list still cached")
+	}
+	if !cacheGet(issueCacheKey(8), &entry) {
+		t.Error("issue 8 should stay cached")
+	}
+}
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/xid"
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	fieldsKey  struct{}
+	traceIDKey struct{}
+)
+
+// httpModule is the module of request logs, for log.levels.http.
+const httpModule = "http"
+
+// WithFields returns ctx carrying fields, merged over fields already in ctx.
+// Every leveled function logging with the returned ctx includes them.
+//
+// For gin contexts the fields are attached to the request context, so they
+// also reach code that receives c.Request.Context(), and c is returned.
+func WithFields(ctx context.Context, fields map[string]any) context.Context {
+	merged := make(logrus.Fields, len(fields))
+	maps.Copy(merged, contextFields(ctx))
+	maps.Copy(merged, fields)
+	return withValue(ctx, fieldsKey{}, merged)
+}
+
+// WithTraceID returns ctx carrying id, logged as reqId by every leveled
+// function instead of "background".
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return withValue(ctx, traceIDKey{}, id)
+}
+
+// GinMiddleware assigns each request a trace ID, taken from the X-Request-ID
+// header or generated, and echoes it in the response header. The ID is
+// stored in the request context, so handlers and the packages they pass
+// c or c.Request.Context() to log it. On completion it logs method, path,
+// status and duration as module "http".
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(CTX_REQUEST_ID)
+		if id == "" {
+			id = xid.New().String()
+		}
+		c.Set(CTX_REQUEST_ID, id)
+		c.Request = c.Request.WithContext(WithTraceID(c.Request.Context(), id))
+		c.Header(CTX_REQUEST_ID, id)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := logrus.InfoLevel
+		if status >= 500 {
+			level = logrus.ErrorLevel
+		} else if status >= 400 {
+			level = logrus.WarnLevel
+		}
+		if e := processLog(c, level, httpModule); e != nil {
+			e.WithFields(logrus.Fields{
+				"method":   c.Request.Method,
+				"path":     c.Request.URL.Path,
+				"status":   status,
+				"duration": time.Since(start).Milliseconds(),
+			}).Log(level, "request completed")
+		}
+	}
+}
+
+// withValue stores key in ctx, or in the request context of a gin context.
+func withValue(ctx context.Context, key, val any) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ginCtx := ginContext(ctx); ginCtx != nil && ginCtx.Request != nil {
+		ginCtx.Request = ginCtx.Request.WithContext(context.WithValue(ginCtx.Request.Context(), key, val))
+		return ctx
+	}
+	return context.WithValue(ctx, key, val)
+}
+
+// value looks key up in ctx, or in the request context of a gin context.
+func value(ctx context.Context, key any) any {
+	if ctx == nil {
+		return nil
+	}
+	if ginCtx := ginContext(ctx); ginCtx != nil {
+		if ginCtx.Request == nil {
+			return nil
+		}
+		ctx = ginCtx.Request.Context()
+	}
+	return ctx.Value(key)
+}
+
+// ginContext returns the gin context ctx is or wraps, if any.
+func ginContext(ctx context.Context) *gin.Context {
+	switch v := ctx.(type) {
+	case *gin.Context:
+		return v
+	case interface{ GetContext() *gin.Context }:
+		return v.GetContext()
+	}
+	return nil
+}
+
+// contextFields returns the fields stored by WithFields.
+func contextFields(ctx context.Context) logrus.Fields {
+	fields, _ := value(ctx, fieldsKey{}).(logrus.Fields)
+	return fields
+}
+
+// contextTraceID returns the ID stored by WithTraceID.
+func contextTraceID(ctx context.Context) string {
+	id, _ := value(ctx, traceIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWithFields(t *testing.T) {
+	buf := captureLog(t)
+
+	ctx := WithFields(context.Background(), map[string]any{"user": 1, "plan": "free"})
+	ctx = WithFields(ctx, map[string]any{"plan": "pro"})
+	ctx = WithTraceID(ctx, "trace-1")
+
+	Infof(ctx, "upgraded")
+	entry := decodeEntry(t, buf)
+	if entry["user"] != float64(1) || entry["plan"] != "pro" || entry["reqId"] != "trace-1" {
+		t.Errorf("expected context fields and trace ID, got %v", entry)
+	}
+
+	Info(context.Background(), "plain")
+	if entry := decodeEntry(t, buf); entry["user"] != nil || entry["reqId"] != "background" {
+		t.Errorf("fields leaked into an unrelated context: %v", entry)
+	}
+}
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	buf := captureLog(t)
+
+	var handlerCtx context.Context
+	r := gin.New()
+	r.Use(GinMiddleware())
+	r.GET("/items/:id", func(c *gin.Context) {
+		WithFields(c, map[string]any{"item": c.Param("id")})
+		handlerCtx = c.Request.Context()
+		c.Status(404)
+	})
+
+	req := httptest.NewRequest("GET", "/items/7", nil)
+	req.Header.Set(CTX_REQUEST_ID, "from-client")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get(CTX_REQUEST_ID); got != "from-client" {
+		t.Errorf("expected X-Request-ID echoed, got %q", got)
+	}
+	entry := decodeEntry(t, buf)
+	if entry["reqId"] != "from-client" || entry["item"] != "7" || entry["method"] != "GET" ||
+		entry["path"] != "/items/7" || entry["status"] != float64(404) || entry["level"] != "warning" {
+		t.Errorf("unexpected completion log: %v", entry)
+	}
+	if _, ok := entry["duration"]; !ok {
+		t.Errorf("completion log lacks duration: %v", entry)
+	}
+
+	// Code handed the request context logs the same trace ID
+	Warn(handlerCtx, "downstream")
+	if entry := decodeEntry(t, buf); entry["reqId"] != "from-client" || entry["item"] != "7" {
+		t.Errorf("request context lost fields: %v", entry)
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/items/8", nil))
+	if entry := decodeEntry(t, buf); entry["reqId"] == "" || entry["reqId"] == "background" {
+		t.Errorf("expected a generated trace ID, got %v", entry)
+	}
+}
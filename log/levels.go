@@ -0,0 +1,176 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+type moduleKey struct{}
+
+// levelConfig is the default level plus per-module overrides. It is replaced
+// as a whole on change, so loggers read it without locking.
+type levelConfig struct {
+	base    logrus.Level
+	modules map[string]logrus.Level
+}
+
+var (
+	levels   atomic.Pointer[levelConfig]
+	levelsMu sync.Mutex // Serializes level changes
+)
+
+// packageDir is the directory of this package, whose frames are skipped when
+// looking for the caller.
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// Module returns ctx naming the module its log lines belong to, for
+// log.levels.<module> overrides. Without it the module is the last element
+// of the caller's package path, e.g. "sqs" for qtoolkit/aws/sqs.
+func Module(ctx context.Context, name string) context.Context {
+	return withValue(ctx, moduleKey{}, name)
+}
+
+// SetLevel sets the default level. Safe to call while logging.
+func SetLevel(level string) error {
+	l, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	getLogger()
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	cfg := *levels.Load()
+	cfg.base = l
+	storeLevels(&cfg)
+	return nil
+}
+
+// SetModuleLevel overrides the level of module. An empty level removes the
+// override. Safe to call while logging.
+func SetModuleLevel(module, level string) error {
+	var l logrus.Level
+	if level != "" {
+		var err error
+		if l, err = logrus.ParseLevel(level); err != nil {
+			return err
+		}
+	}
+	getLogger()
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	cfg := *levels.Load()
+	cfg.modules = maps.Clone(cfg.modules)
+	if level == "" {
+		delete(cfg.modules, module)
+	} else {
+		cfg.modules[module] = l
+	}
+	storeLevels(&cfg)
+	return nil
+}
+
+// SetFormat switches the output format: "json" or "text". Safe to call while
+// logging.
+func SetFormat(format string) error {
+	f, err := newFormatter(format)
+	if err != nil {
+		return err
+	}
+	getLogger().SetFormatter(f)
+	return nil
+}
+
+// loadLevels reads log.level and the log.levels.<module> overrides.
+func loadLevels() {
+	cfg := &levelConfig{
+		base:    parseLogLevel(viper.GetString("log.level")),
+		modules: map[string]logrus.Level{},
+	}
+	for module, level := range viper.GetStringMapString("log.levels") {
+		cfg.modules[module] = parseLogLevel(level)
+	}
+	storeLevels(cfg)
+}
+
+// storeLevels publishes cfg and lets the logger pass the most verbose level
+// in use; entries are filtered per module in processLog.
+func storeLevels(cfg *levelConfig) {
+	levels.Store(cfg)
+	most := cfg.base
+	for _, l := range cfg.modules {
+		most = max(most, l)
+	}
+	logger.SetLevel(most)
+}
+
+// enabled reports whether module logs at level. Fatal and panic entries are
+// never filtered, so Fatal always exits and Panic always panics.
+func (c *levelConfig) enabled(module string, level logrus.Level) bool {
+	if level <= logrus.FatalLevel {
+		return true
+	}
+	if l, ok := c.modules[module]; ok {
+		return level <= l
+	}
+	return level <= c.base
+}
+
+// newFormatter returns the formatter for log.format. Both formats use the
+// same timestamp layout; context fields are top-level keys in JSON.
+func newFormatter(format string) (logrus.Formatter, error) {
+	switch format {
+	case "", "json":
+		return &logrus.JSONFormatter{TimestampFormat: "2006-01-02 15:04:05.000"}, nil
+	case "text":
+		return &logrus.TextFormatter{TimestampFormat: "2006-01-02 15:04:05.000", FullTimestamp: true}, nil
+	}
+	return nil, fmt.Errorf("log: unknown format %q, expected json or text", format)
+}
+
+// callerFrame returns the first frame outside this package's non-test files.
+func callerFrame() runtime.Frame {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != packageDir || strings.HasSuffix(frame.File, "_test.go") {
+			return frame
+		}
+		if !more {
+			return frame
+		}
+	}
+}
+
+// packageName returns the last element of the package path of function,
+// e.g. "redis" for "github.com/wordgate/qtoolkit/redis.(*Broadcast).Pub".
+func packageName(function string) string {
+	name := function[strings.LastIndex(function, "/")+1:]
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// callerString formats frame as dir/file.go:line.
+func callerString(frame runtime.Frame) string {
+	if frame.File == "" {
+		return ""
+	}
+	dir, file := filepath.Split(frame.File)
+	return filepath.Base(dir) + "/" + file + ":" + strconv.Itoa(frame.Line)
+}
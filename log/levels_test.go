@@ -0,0 +1,97 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestModuleLevels(t *testing.T) {
+	buf := captureLog(t)
+	if err := SetLevel("info"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetModuleLevel("broadcast", "warn"); err != nil {
+		t.Fatal(err)
+	}
+	defer SetModuleLevel("broadcast", "")
+
+	ctx := Module(context.Background(), "broadcast")
+	Info(ctx, "filtered")
+	if buf.Len() != 0 {
+		t.Fatalf("info should be filtered for broadcast: %s", buf)
+	}
+	// A named module is filtered without walking the stack
+	if allocs := testing.AllocsPerRun(100, func() { Info(ctx, "filtered") }); allocs != 0 {
+		t.Errorf("filtered entry allocates %v times per call", allocs)
+	}
+	Warn(ctx, "kept")
+	if entry := decodeEntry(t, buf); entry["module"] != "broadcast" || entry["msg"] != "kept" {
+		t.Errorf("unexpected entry: %v", entry)
+	}
+
+	// Without Module the caller's package names the module
+	Info(context.Background(), "inferred")
+	entry := decodeEntry(t, buf)
+	if entry["module"] != "log" || !strings.HasPrefix(entry["caller"].(string), "log/levels_test.go:") {
+		t.Errorf("expected inferred module and caller, got %v", entry)
+	}
+
+	if err := SetModuleLevel("log", "error"); err != nil {
+		t.Fatal(err)
+	}
+	defer SetModuleLevel("log", "")
+	Warn(context.Background(), "filtered")
+	if buf.Len() != 0 {
+		t.Errorf("warn should be filtered for log: %s", buf)
+	}
+
+	if err := SetLevel("loud"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
+
+func TestSetFormat(t *testing.T) {
+	buf := captureLog(t)
+	defer SetFormat("json")
+
+	if err := SetFormat("xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	if err := SetFormat("text"); err != nil {
+		t.Fatal(err)
+	}
+	Info(WithFields(context.Background(), map[string]any{"user": 1}), "signed up")
+	line := buf.String()
+	buf.Reset()
+	if !strings.Contains(line, `msg="signed up"`) || !strings.Contains(line, "user=1") || !strings.Contains(line, "caller=") {
+		t.Errorf("unexpected text line: %q", line)
+	}
+
+	SetFormat("json")
+	Info(context.Background(), "json again")
+	decodeEntry(t, buf)
+}
+
+func TestSetLevelConcurrent(t *testing.T) {
+	captureLog(t)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 50 {
+				if i%2 == 0 {
+					SetModuleLevel("log", []string{"debug", "warn"}[j%2])
+					SetFormat([]string{"json", "text"}[j%2])
+				} else {
+					Infof(context.Background(), "line %d", j)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	SetModuleLevel("log", "")
+	SetFormat("json")
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path"
+	"runtime"
 	"sync"
 
 	"github.com/gin-gonic/gin"
@@ -29,10 +31,14 @@ func initialize() {
 	isDev := viper.GetBool("is_dev")
 
 	logger = logrus.New()
-	logger.SetFormatter(&logrus.JSONFormatter{
-		TimestampFormat: "2006-01-02 15:04:05.000",
-	})
-	logger.SetLevel(parseLogLevel(level))
+	formatter, err := newFormatter(viper.GetString("log.format"))
+	if err != nil {
+		fmt.Printf("%v, falling back to json\n", err)
+		formatter, _ = newFormatter("json")
+	}
+	logger.SetFormatter(formatter)
+	loadLevels()
+	loadSampling()
 	logger.SetOutput(createLogWriter(logPath, level))
 
 	// Set gin default writers
@@ -85,95 +91,157 @@ func parseLogLevel(levelS string) logrus.Level {
 	return level
 }
 
-// processLog creates log entry with request ID
-func processLog(ctx context.Context) *logrus.Entry {
-	return getLogger().WithField("reqId", RequestId(ctx))
+// processLog creates the entry to log at level, or returns nil when level is
+// disabled for the module or the entry is dropped by log.sampling. The entry has the fields of ctx, the request ID,
+// the caller, the module and, when a span is active, its trace and span IDs.
+// An empty module is taken from ctx or the caller's package.
+func processLog(ctx context.Context, level logrus.Level, module string) *logrus.Entry {
+	l := getLogger()
+	// storeLevels lets the logger pass the most verbose level in use, so a
+	// level it filters is off for every module: skip the stack walk.
+	if level > logrus.FatalLevel && !l.IsLevelEnabled(level) {
+		return nil
+	}
+	cfg := levels.Load()
+	if module == "" {
+		module, _ = value(ctx, moduleKey{}).(string)
+	}
+	// The caller's package only matters for the level when modules override it
+	var frame runtime.Frame
+	walked := false
+	if module == "" && len(cfg.modules) > 0 {
+		frame, walked = callerFrame(), true
+		module = packageName(frame.Function)
+	}
+	if !cfg.enabled(module, level) {
+		return nil
+	}
+	if !walked {
+		frame = callerFrame()
+	}
+	if module == "" {
+		module = packageName(frame.Function)
+	}
+	caller := callerString(frame)
+	if !sampled(ctx, level, caller) {
+		return nil
+	}
+
+	fields := logrus.Fields{}
+	maps.Copy(fields, contextFields(ctx))
+	fields["reqId"] = RequestId(ctx)
+	fields["caller"] = caller
+	fields["module"] = module
+	return l.WithFields(withTrace(ctx, fields))
 }
 
-// RequestId returns the request identifier from context
+// RequestId returns the request identifier from context: the trace ID set
+// by WithTraceID or GinMiddleware, an ID generated for gin contexts, or
+// "background"
 func RequestId(ctx context.Context) string {
 	if ctx == nil {
 		return "background"
 	}
 
-	var ginCtx *gin.Context
-	switch v := ctx.(type) {
-	case *gin.Context:
-		ginCtx = v
-	case interface{ GetContext() *gin.Context }:
-		ginCtx = v.GetContext()
-	}
-
-	if ginCtx != nil {
+	if ginCtx := ginContext(ctx); ginCtx != nil {
 		if id := ginCtx.GetString(CTX_REQUEST_ID); id != "" {
 			return id
 		}
-		id := xid.New().String()
+		id := contextTraceID(ctx)
+		if id == "" {
+			id = xid.New().String()
+		}
 		ginCtx.Set(CTX_REQUEST_ID, id)
 		return id
 	}
+	if id := contextTraceID(ctx); id != "" {
+		return id
+	}
 	return "background"
 }
 
-// WithFields creates a log entry with custom fields
-func WithFields(ctx context.Context, fields logrus.Fields) *logrus.Entry {
-	return getLogger().WithFields(fields).WithField("reqId", RequestId(ctx))
-}
-
 // --- Log methods ---
 
 func Tracef(ctx context.Context, format string, args ...any) {
-	processLog(ctx).Tracef(format, args...)
+	if e := processLog(ctx, logrus.TraceLevel, ""); e != nil {
+		e.Tracef(format, args...)
+	}
 }
 
 func Trace(ctx context.Context, args ...any) {
-	processLog(ctx).Trace(args...)
+	if e := processLog(ctx, logrus.TraceLevel, ""); e != nil {
+		e.Trace(args...)
+	}
 }
 
 func Debugf(ctx context.Context, format string, args ...any) {
-	processLog(ctx).Debugf(format, args...)
+	if e := processLog(ctx, logrus.DebugLevel, ""); e != nil {
+		e.Debugf(format, args...)
+	}
 }
 
 func Debug(ctx context.Context, args ...any) {
-	processLog(ctx).Debug(args...)
+	if e := processLog(ctx, logrus.DebugLevel, ""); e != nil {
+		e.Debug(args...)
+	}
 }
 
 func Infof(ctx context.Context, format string, args ...any) {
-	processLog(ctx).Infof(format, args...)
+	if e := processLog(ctx, logrus.InfoLevel, ""); e != nil {
+		e.Infof(format, args...)
+	}
 }
 
 func Info(ctx context.Context, args ...any) {
-	processLog(ctx).Info(args...)
+	if e := processLog(ctx, logrus.InfoLevel, ""); e != nil {
+		e.Info(args...)
+	}
 }
 
 func Warnf(ctx context.Context, format string, args ...any) {
-	processLog(ctx).Warnf(format, args...)
+	if e := processLog(ctx, logrus.WarnLevel, ""); e != nil {
+		e.Warnf(format, args...)
+	}
 }
 
 func Warn(ctx context.Context, args ...any) {
-	processLog(ctx).Warn(args...)
+	if e := processLog(ctx, logrus.WarnLevel, ""); e != nil {
+		e.Warn(args...)
+	}
 }
 
 func Errorf(ctx context.Context, format string, args ...any) {
-	processLog(ctx).Errorf(format, args...)
+	if e := processLog(ctx, logrus.ErrorLevel, ""); e != nil {
+		e.Errorf(format, args...)
+	}
 }
 
 func Error(ctx context.Context, args ...any) {
-	processLog(ctx).Error(args...)
+	if e := processLog(ctx, logrus.ErrorLevel, ""); e != nil {
+		e.Error(args...)
+	}
 }
 
 func Fatalf(ctx context.Context, format string, args ...any) {
-	processLog(ctx).Fatalf(format, args...)
+	if e := processLog(ctx, logrus.FatalLevel, ""); e != nil {
+		e.Fatalf(format, args...)
+	}
 }
 
 func Fatal(ctx context.Context, args ...any) {
-	processLog(ctx).Fatal(args...)
+	if e := processLog(ctx, logrus.FatalLevel, ""); e != nil {
+		e.Fatal(args...)
+	}
 }
 
 func Panicf(ctx context.Context, format string, args ...any) {
-	processLog(ctx).Panicf(format, args...)
+	if e := processLog(ctx, logrus.PanicLevel, ""); e != nil {
+		e.Panicf(format, args...)
+	}
 }
 
 func Panic(ctx context.Context, args ...any) {
-	processLog(ctx).Panic(args...)
+	if e := processLog(ctx, logrus.PanicLevel, ""); e != nil {
+		e.Panic(args...)
+	}
 }
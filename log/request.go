@@ -67,9 +67,6 @@ func MiddlewareRequestLog(logResponseContent bool) gin.HandlerFunc {
 			req["body"] = reqBody
 		}
 
-		// Create log entry
-		entry := WithFields(c, logrus.Fields{"request": req})
-
 		// Process request
 		c.Next()
 
@@ -93,12 +90,14 @@ func MiddlewareRequestLog(logResponseContent bool) gin.HandlerFunc {
 		}
 
 		// Log with appropriate level
+		level := logrus.InfoLevel
 		if status >= 500 {
-			entry.WithField("response", res).Error()
+			level = logrus.ErrorLevel
 		} else if status >= 400 {
-			entry.WithField("response", res).Warn()
-		} else {
-			entry.WithField("response", res).Info()
+			level = logrus.WarnLevel
+		}
+		if e := processLog(c, level, httpModule); e != nil {
+			e.WithFields(logrus.Fields{"request": req, "response": res}).Log(level)
 		}
 	}
 }
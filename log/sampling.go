@@ -0,0 +1,161 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// samplingReportInterval is how often the suppressed counts are logged.
+const samplingReportInterval = time.Minute
+
+// samplingRule limits the entries of one level logged per call site.
+type samplingRule struct {
+	everyN    uint64 // Log 1 in everyN occurrences; 0 or 1 logs all
+	perSecond int64  // Log at most perSecond occurrences a second; 0 is unlimited
+}
+
+// samplingConfig holds the rules indexed by level. Like levelConfig it is
+// replaced as a whole; a nil config means sampling is off.
+type samplingConfig struct {
+	rules [logrus.TraceLevel + 1]*samplingRule
+}
+
+// siteCounter counts the occurrences of one call site.
+type siteCounter struct {
+	count      atomic.Uint64
+	second     atomic.Int64 // Unix second of inSecond
+	inSecond   atomic.Int64
+	suppressed atomic.Int64
+}
+
+// forcedContext marks the ctx of a single log call as exempt from sampling.
+// It is not inherited by contexts derived from it.
+type forcedContext struct{ context.Context }
+
+// GetContext keeps a wrapped gin context visible to ginContext.
+func (c forcedContext) GetContext() *gin.Context { return ginContext(c.Context) }
+
+var (
+	sampling     atomic.Pointer[samplingConfig]
+	sites        sync.Map // caller -> *siteCounter
+	reporterOnce sync.Once
+	now          = time.Now
+)
+
+// ForceNext returns ctx for one log call that bypasses log.sampling; the
+// level filter still applies.
+//
+//	log.Tracef(log.ForceNext(ctx), "cache miss for %s", key)
+func ForceNext(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return forcedContext{ctx}
+}
+
+// loadSampling reads the log.sampling.<level>.every_n and .per_second rules.
+func loadSampling() {
+	cfg := &samplingConfig{}
+	enabled := false
+	for name := range viper.GetStringMap("log.sampling") {
+		level, err := logrus.ParseLevel(name)
+		if err != nil {
+			fmt.Printf("log: ignoring sampling for unknown level %q\n", name)
+			continue
+		}
+		rule := &samplingRule{
+			everyN:    uint64(max(viper.GetInt("log.sampling."+name+".every_n"), 0)),
+			perSecond: int64(max(viper.GetInt("log.sampling."+name+".per_second"), 0)),
+		}
+		if rule.everyN > 1 || rule.perSecond > 0 {
+			cfg.rules[level] = rule
+			enabled = true
+		}
+	}
+	if !enabled {
+		cfg = nil
+	}
+	storeSampling(cfg)
+}
+
+// storeSampling publishes cfg and starts the reporter on first use.
+func storeSampling(cfg *samplingConfig) {
+	sampling.Store(cfg)
+	if cfg != nil {
+		reporterOnce.Do(func() {
+			go func() {
+				for range time.Tick(samplingReportInterval) {
+					reportSuppressed()
+				}
+			}()
+		})
+	}
+}
+
+// sampled reports whether the entry logged at level from caller passes the
+// sampling rule of level. every_n is applied first, per_second to the rest.
+// Fatal and panic entries are never sampled.
+func sampled(ctx context.Context, level logrus.Level, caller string) bool {
+	cfg := sampling.Load()
+	if cfg == nil || level <= logrus.FatalLevel {
+		return true
+	}
+	rule := cfg.rules[level]
+	if rule == nil {
+		return true
+	}
+	if _, forced := ctx.(forcedContext); forced {
+		return true
+	}
+	v, ok := sites.Load(caller)
+	if !ok {
+		v, _ = sites.LoadOrStore(caller, &siteCounter{})
+	}
+	site := v.(*siteCounter)
+
+	if rule.everyN > 1 && (site.count.Add(1)-1)%rule.everyN != 0 {
+		site.suppressed.Add(1)
+		return false
+	}
+	if rule.perSecond > 0 {
+		sec := now().Unix()
+		if old := site.second.Load(); old != sec && site.second.CompareAndSwap(old, sec) {
+			site.inSecond.Store(0)
+		}
+		if site.inSecond.Add(1) > rule.perSecond {
+			site.suppressed.Add(1)
+			return false
+		}
+	}
+	return true
+}
+
+// reportSuppressed logs how many entries each call site had suppressed since
+// the last report, if any.
+func reportSuppressed() {
+	counts := logrus.Fields{}
+	var total int64
+	sites.Range(func(caller, v any) bool {
+		if n := v.(*siteCounter).suppressed.Swap(0); n > 0 {
+			counts[caller.(string)] = n
+			total += n
+		}
+		return true
+	})
+	if total == 0 {
+		return
+	}
+	getLogger().WithFields(logrus.Fields{
+		"reqId":      "background",
+		"module":     "log",
+		"suppressed": total,
+		"sites":      counts,
+	}).Infof("log sampling suppressed %d entries", total)
+}
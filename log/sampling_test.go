@@ -0,0 +1,177 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// withSampling applies the log.sampling config for the test.
+func withSampling(t *testing.T, cfg map[string]any) {
+	t.Helper()
+	viper.Set("log.sampling", cfg)
+	loadSampling()
+	t.Cleanup(func() {
+		viper.Set("log.sampling", nil)
+		loadSampling()
+		sites.Clear()
+	})
+}
+
+func countLines(buf *bytes.Buffer) int {
+	n := strings.Count(buf.String(), "\n")
+	buf.Reset()
+	return n
+}
+
+func TestSamplingDisabledByDefault(t *testing.T) {
+	buf := captureLog(t)
+	if sampling.Load() != nil {
+		t.Fatal("sampling should be off without config")
+	}
+	for range 10 {
+		Debug(context.Background(), "unsampled")
+	}
+	if n := countLines(buf); n != 10 {
+		t.Errorf("expected 10 lines, got %d", n)
+	}
+}
+
+func TestSamplingEveryN(t *testing.T) {
+	buf := captureLog(t)
+	withSampling(t, map[string]any{"debug": map[string]any{"every_n": 4}})
+	ctx := context.Background()
+
+	for range 10 {
+		Debug(ctx, "sampled")
+	}
+	if n := countLines(buf); n != 3 {
+		t.Errorf("expected occurrences 1, 5 and 9, got %d lines", n)
+	}
+
+	// Call sites are counted separately
+	for range 2 {
+		Debug(ctx, "first")
+		Debug(ctx, "second")
+	}
+	if n := countLines(buf); n != 2 {
+		t.Errorf("expected one line per call site, got %d", n)
+	}
+
+	// Other levels are not sampled
+	for range 3 {
+		Info(ctx, "info")
+	}
+	if n := countLines(buf); n != 3 {
+		t.Errorf("expected info unsampled, got %d lines", n)
+	}
+
+	Debug(ForceNext(WithTraceID(ctx, "trace-1")), "forced")
+	entry := decodeEntry(t, buf)
+	if entry["msg"] != "forced" || entry["reqId"] != "trace-1" {
+		t.Errorf("unexpected forced entry: %v", entry)
+	}
+
+	reportSuppressed()
+	entry = decodeEntry(t, buf)
+	if entry["suppressed"] != float64(9) || entry["module"] != "log" || len(entry["sites"].(map[string]any)) != 3 {
+		t.Errorf("unexpected summary: %v", entry)
+	}
+	reportSuppressed()
+	if buf.Len() != 0 {
+		t.Errorf("summary repeated without new suppressions: %s", buf)
+	}
+}
+
+func TestSamplingPerSecond(t *testing.T) {
+	buf := captureLog(t)
+	withSampling(t, map[string]any{"warn": map[string]any{"per_second": 2}})
+	clock := time.Unix(1700000000, 0)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	burst := func() {
+		for range 5 {
+			Warn(context.Background(), "burst")
+		}
+	}
+	burst()
+	if n := countLines(buf); n != 2 {
+		t.Errorf("expected 2 lines in the first second, got %d", n)
+	}
+	clock = clock.Add(time.Second)
+	burst()
+	if n := countLines(buf); n != 2 {
+		t.Errorf("expected 2 lines in the next second, got %d", n)
+	}
+}
+
+func TestSamplingUnknownLevel(t *testing.T) {
+	withSampling(t, map[string]any{"loud": map[string]any{"every_n": 10}})
+	if sampling.Load() != nil {
+		t.Error("unknown level should leave sampling off")
+	}
+}
+
+func benchmarkTracef(b *testing.B, cfg map[string]any) {
+	l := getLogger()
+	out, lv := l.Out, levels.Load()
+	l.SetOutput(io.Discard)
+	SetLevel("trace")
+	viper.Set("log.sampling", cfg)
+	loadSampling()
+	b.Cleanup(func() {
+		l.SetOutput(out)
+		storeLevels(lv)
+		viper.Set("log.sampling", nil)
+		loadSampling()
+		sites.Clear()
+	})
+
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		Tracef(ctx, "item %d", i)
+	}
+}
+
+func BenchmarkTracefSamplingOff(b *testing.B) {
+	benchmarkTracef(b, nil)
+}
+
+func BenchmarkTracefSamplingOn(b *testing.B) {
+	benchmarkTracef(b, map[string]any{"trace": map[string]any{"every_n": 100}})
+}
+
+// BenchmarkTracefDisabled measures a Tracef whose level is off, which must not
+// walk the stack or allocate.
+func BenchmarkTracefDisabled(b *testing.B) {
+	lv := levels.Load()
+	SetLevel("info")
+	b.Cleanup(func() { storeLevels(lv) })
+
+	ctx := context.Background()
+	if allocs := testing.AllocsPerRun(100, func() { Tracef(ctx, "cache miss") }); allocs != 0 {
+		b.Fatalf("disabled Tracef allocates %v times per call", allocs)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		Tracef(ctx, "cache miss")
+	}
+}
+
+// BenchmarkSampledOff measures the check processLog adds when sampling is off.
+func BenchmarkSampledOff(b *testing.B) {
+	sampling.Store(nil)
+	ctx := context.Background()
+	for range b.N {
+		sampled(ctx, logrus.TraceLevel, "log/sampling_test.go:1")
+	}
+}
@@ -0,0 +1,34 @@
+package log
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanContext returns the OpenTelemetry span context carried by ctx.
+// For gin contexts the span lives on the request context, where HTTP
+// tracing middleware (e.g. otelgin) puts it.
+//
+// Without an otel SDK no span is ever started, the returned span context is
+// invalid and log entries are left untouched.
+func spanContext(ctx context.Context) trace.SpanContext {
+	if ctx == nil {
+		return trace.SpanContext{}
+	}
+
+	if ginCtx := ginContext(ctx); ginCtx != nil && ginCtx.Request != nil {
+		ctx = ginCtx.Request.Context()
+	}
+	return trace.SpanContextFromContext(ctx)
+}
+
+// withTrace adds trace_id and span_id to fields when ctx carries a span.
+func withTrace(ctx context.Context, fields logrus.Fields) logrus.Fields {
+	if sc := spanContext(ctx); sc.IsValid() {
+		fields["trace_id"] = sc.TraceID().String()
+		fields["span_id"] = sc.SpanID().String()
+	}
+	return fields
+}
@@ -0,0 +1,109 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	testTraceID = trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	testSpanID  = trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+)
+
+// spanCtx returns ctx carrying the span context a tracer would have started.
+func spanCtx(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    testTraceID,
+		SpanID:     testSpanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+}
+
+// captureLog redirects the logger to a buffer for the duration of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	l := getLogger()
+	out, cfg := l.Out, levels.Load()
+	l.SetOutput(&buf)
+	SetLevel("debug")
+	t.Cleanup(func() {
+		l.SetOutput(out)
+		storeLevels(cfg)
+	})
+	return &buf
+}
+
+func decodeEntry(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid log line %q: %v", buf.String(), err)
+	}
+	buf.Reset()
+	return entry
+}
+
+func TestTraceFields(t *testing.T) {
+	buf := captureLog(t)
+	ctx := spanCtx(context.Background())
+
+	logs := map[string]func(){
+		"printf":     func() { Infof(ctx, "user %d signed up", 1) },
+		"plain":      func() { Warn(ctx, "slow query") },
+		"WithFields": func() { Info(WithFields(ctx, map[string]any{"user": 1}), "signed up") },
+	}
+	for name, log := range logs {
+		log()
+		entry := decodeEntry(t, buf)
+		if entry["trace_id"] != testTraceID.String() || entry["span_id"] != testSpanID.String() {
+			t.Errorf("%s: expected trace fields, got %v", name, entry)
+		}
+		if entry["reqId"] != "background" {
+			t.Errorf("%s: reqId lost: %v", name, entry)
+		}
+	}
+
+	Info(context.Background(), "no span")
+	if entry := decodeEntry(t, buf); entry["trace_id"] != nil || entry["span_id"] != nil {
+		t.Errorf("expected no trace fields without a span, got %v", entry)
+	}
+}
+
+func TestTraceFieldsGinContext(t *testing.T) {
+	buf := captureLog(t)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	c.Request = c.Request.WithContext(spanCtx(c.Request.Context()))
+
+	Debug(c, "handled")
+	entry := decodeEntry(t, buf)
+	if entry["trace_id"] != testTraceID.String() || entry["reqId"] == "background" {
+		t.Errorf("expected request trace and request ID, got %v", entry)
+	}
+}
+
+func TestSpanContextNoAllocs(t *testing.T) {
+	ctx := context.Background()
+	fields := logrus.Fields{"reqId": "background"}
+	if allocs := testing.AllocsPerRun(100, func() { withTrace(ctx, fields) }); allocs != 0 {
+		t.Errorf("expected no allocations without a span, got %v", allocs)
+	}
+}
+
+func BenchmarkWithTraceNoop(b *testing.B) {
+	ctx := context.Background()
+	fields := logrus.Fields{"reqId": "background"}
+	b.ReportAllocs()
+	for b.Loop() {
+		withTrace(ctx, fields)
+	}
+}
@@ -0,0 +1,239 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CalendarMethod is the iTIP method of a calendar invitation (RFC 5546).
+type CalendarMethod string
+
+const (
+	MethodRequest CalendarMethod = "REQUEST" // New invitation or update
+	MethodCancel  CalendarMethod = "CANCEL"  // Cancellation
+)
+
+// CalendarEvent is a single-event invitation rendered as an RFC 5545 ICS body.
+//
+// To update an event, resend it with the same UID and a higher Sequence.
+// To cancel, resend with Method: MethodCancel and a higher Sequence.
+type CalendarEvent struct {
+	UID         string         // Stable identifier across updates
+	Sequence    int            // Revision number, increment on every change
+	Method      CalendarMethod // Defaults to MethodRequest
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	TimeZone    string // IANA zone (e.g. "Asia/Shanghai"); empty renders UTC
+	Organizer   Attendee
+	Attendees   []Attendee
+	Stamp       time.Time // DTSTAMP; defaults to now
+}
+
+// Attendee is a calendar participant.
+type Attendee struct {
+	Name  string
+	Email string
+}
+
+const icsTimeFormat = "20060102T150405"
+
+func (e *CalendarEvent) method() CalendarMethod {
+	if e.Method == "" {
+		return MethodRequest
+	}
+	return e.Method
+}
+
+func (e *CalendarEvent) validate() error {
+	switch {
+	case e.UID == "":
+		return errors.New("calendar event UID is required")
+	case e.Start.IsZero() || e.End.IsZero():
+		return errors.New("calendar event start and end are required")
+	case !e.End.After(e.Start):
+		return errors.New("calendar event end must be after start")
+	case e.Organizer.Email == "":
+		return errors.New("calendar event organizer email is required")
+	}
+	if m := e.method(); m != MethodRequest && m != MethodCancel {
+		return fmt.Errorf("unsupported calendar method %q", m)
+	}
+	if e.TimeZone != "" {
+		if _, err := time.LoadLocation(e.TimeZone); err != nil {
+			return fmt.Errorf("calendar event time zone: %w", err)
+		}
+	}
+	return nil
+}
+
+// ICS renders the event as an iCalendar object with CRLF line endings,
+// 75-octet line folding and TEXT escaping per RFC 5545.
+func (e *CalendarEvent) ICS() (string, error) {
+	if err := e.validate(); err != nil {
+		return "", err
+	}
+
+	method := e.method()
+	stamp := e.Stamp
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+
+	var loc *time.Location
+	if e.TimeZone != "" {
+		loc, _ = time.LoadLocation(e.TimeZone)
+	}
+
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(foldLine(s))
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("PRODID:-//wordgate//qtoolkit mail//EN")
+	line("VERSION:2.0")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:" + string(method))
+	if loc != nil {
+		writeTimezone(line, e.TimeZone, e.Start.In(loc), e.End.In(loc))
+	}
+	line("BEGIN:VEVENT")
+	line("UID:" + escapeText(e.UID))
+	line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+	line("DTSTAMP:" + stamp.UTC().Format(icsTimeFormat) + "Z")
+	line(formatDateTime("DTSTART", e.Start, loc, e.TimeZone))
+	line(formatDateTime("DTEND", e.End, loc, e.TimeZone))
+	if e.Summary != "" {
+		line("SUMMARY:" + escapeText(e.Summary))
+	}
+	if e.Description != "" {
+		line("DESCRIPTION:" + escapeText(e.Description))
+	}
+	if e.Location != "" {
+		line("LOCATION:" + escapeText(e.Location))
+	}
+	line("ORGANIZER" + cnParam(e.Organizer.Name) + ":mailto:" + e.Organizer.Email)
+	for _, a := range e.Attendees {
+		params := cnParam(a.Name) + ";ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION"
+		if method == MethodRequest {
+			params += ";RSVP=TRUE"
+		}
+		line("ATTENDEE" + params + ":mailto:" + a.Email)
+	}
+	if method == MethodCancel {
+		line("STATUS:CANCELLED")
+	} else {
+		line("STATUS:CONFIRMED")
+	}
+	line("END:VEVENT")
+	line("END:VCALENDAR")
+
+	return b.String(), nil
+}
+
+// formatDateTime renders a DATE-TIME property in UTC ("Z") or with a TZID parameter.
+func formatDateTime(name string, t time.Time, loc *time.Location, tzid string) string {
+	if loc == nil {
+		return name + ":" + t.UTC().Format(icsTimeFormat) + "Z"
+	}
+	return name + ";TZID=" + tzid + ":" + t.In(loc).Format(icsTimeFormat)
+}
+
+// writeTimezone emits a VTIMEZONE with one observance per UTC offset period
+// overlapping [start, end], as required for every TZID referenced (RFC 5545 3.6.5).
+func writeTimezone(line func(string), tzid string, start, end time.Time) {
+	line("BEGIN:VTIMEZONE")
+	line("TZID:" + tzid)
+
+	for t := start; ; {
+		name, offset := t.Zone()
+		zoneStart, zoneEnd := t.ZoneBounds()
+
+		kind := "STANDARD"
+		if t.IsDST() {
+			kind = "DAYLIGHT"
+		}
+
+		// DTSTART of an observance is local time in the offset before the transition
+		onset := "19700101T000000"
+		offsetFrom := offset
+		if !zoneStart.IsZero() {
+			_, offsetFrom = zoneStart.Add(-time.Second).Zone()
+			onset = zoneStart.UTC().Add(time.Duration(offsetFrom) * time.Second).Format(icsTimeFormat)
+		}
+
+		line("BEGIN:" + kind)
+		line("DTSTART:" + onset)
+		line("TZOFFSETFROM:" + formatOffset(offsetFrom))
+		line("TZOFFSETTO:" + formatOffset(offset))
+		line("TZNAME:" + escapeText(name))
+		line("END:" + kind)
+
+		if zoneEnd.IsZero() || !zoneEnd.Before(end) {
+			break
+		}
+		t = zoneEnd
+	}
+
+	line("END:VTIMEZONE")
+}
+
+// formatOffset renders seconds east of UTC as "+HHMM" / "-HHMM".
+func formatOffset(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign = '-'
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// cnParam renders a ";CN=" parameter, quoting values that contain separators.
+func cnParam(name string) string {
+	if name == "" {
+		return ""
+	}
+	name = strings.ReplaceAll(name, `"`, "")
+	if strings.ContainsAny(name, ":;,") {
+		name = `"` + name + `"`
+	}
+	return ";CN=" + name
+}
+
+// escapeText escapes a TEXT value (RFC 5545 3.3.11).
+func escapeText(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return r.Replace(s)
+}
+
+// foldLine splits a content line into 75-octet chunks without breaking
+// UTF-8 sequences; continuation lines start with a single space.
+func foldLine(s string) string {
+	var b strings.Builder
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // leading space counts towards the 75 octets
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+	return b.String()
+}
@@ -0,0 +1,243 @@
+package mail
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// icsFixture is a known-good invitation: TZID with matching VTIMEZONE,
+// escaped TEXT values, quoted CN parameter and a folded ATTENDEE line.
+const icsFixture = "BEGIN:VCALENDAR\r\n" +
+	"PRODID:-//wordgate//qtoolkit mail//EN\r\n" +
+	"VERSION:2.0\r\n" +
+	"CALSCALE:GREGORIAN\r\n" +
+	"METHOD:REQUEST\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Europe/Berlin\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:20241027T030000\r\n" +
+	"TZOFFSETFROM:+0200\r\n" +
+	"TZOFFSETTO:+0100\r\n" +
+	"TZNAME:CET\r\n" +
+	"END:STANDARD\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:evt-123@example.com\r\n" +
+	"SEQUENCE:0\r\n" +
+	"DTSTAMP:20250101T000000Z\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20250115T100000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20250115T110000\r\n" +
+	"SUMMARY:Design review\\; Q1\\, planning\r\n" +
+	"DESCRIPTION:Agenda:\\n1. Roadmap\\n2. Budget\r\n" +
+	"LOCATION:Room 4\\\\B\r\n" +
+	"ORGANIZER;CN=Alice:mailto:alice@example.com\r\n" +
+	"ATTENDEE;CN=\"Bob, Jr.\";ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE\r\n" +
+	" :mailto:bob@example.com\r\n" +
+	"STATUS:CONFIRMED\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func testEvent() *CalendarEvent {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	return &CalendarEvent{
+		UID:         "evt-123@example.com",
+		Summary:     "Design review; Q1, planning",
+		Description: "Agenda:\n1. Roadmap\n2. Budget",
+		Location:    `Room 4\B`,
+		Start:       time.Date(2025, 1, 15, 10, 0, 0, 0, berlin),
+		End:         time.Date(2025, 1, 15, 11, 0, 0, 0, berlin),
+		TimeZone:    "Europe/Berlin",
+		Organizer:   Attendee{Name: "Alice", Email: "alice@example.com"},
+		Attendees:   []Attendee{{Name: "Bob, Jr.", Email: "bob@example.com"}},
+		Stamp:       time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestCalendarICSFixture(t *testing.T) {
+	ics, err := testEvent().ICS()
+	if err != nil {
+		t.Fatalf("ICS: %v", err)
+	}
+	if ics != icsFixture {
+		t.Errorf("ICS mismatch\ngot:\n%s\nwant:\n%s", ics, icsFixture)
+	}
+}
+
+func TestCalendarICSUTC(t *testing.T) {
+	e := testEvent()
+	e.TimeZone = ""
+
+	ics, err := e.ICS()
+	if err != nil {
+		t.Fatalf("ICS: %v", err)
+	}
+	if strings.Contains(ics, "VTIMEZONE") || strings.Contains(ics, "TZID") {
+		t.Error("UTC event must not reference a time zone")
+	}
+	if !strings.Contains(ics, "DTSTART:20250115T090000Z\r\n") {
+		t.Errorf("expected UTC DTSTART, got:\n%s", ics)
+	}
+}
+
+func TestCalendarICSDaylightTransition(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	e := testEvent()
+	e.Start = time.Date(2025, 3, 29, 10, 0, 0, 0, berlin)
+	e.End = time.Date(2025, 3, 31, 10, 0, 0, 0, berlin)
+
+	ics, _ := e.ICS()
+	for _, want := range []string{
+		"BEGIN:DAYLIGHT\r\nDTSTART:20250330T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\n",
+		"DTEND;TZID=Europe/Berlin:20250331T100000\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("missing %q in:\n%s", want, ics)
+		}
+	}
+}
+
+func TestCalendarUpdateAndCancel(t *testing.T) {
+	update := testEvent()
+	update.Sequence = 1
+	update.Location = "Room 5"
+
+	ics, _ := update.ICS()
+	for _, want := range []string{"METHOD:REQUEST\r\n", "UID:evt-123@example.com\r\n", "SEQUENCE:1\r\n", "LOCATION:Room 5\r\n"} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("update missing %q", want)
+		}
+	}
+
+	cancel := testEvent()
+	cancel.Sequence = 2
+	cancel.Method = MethodCancel
+
+	ics, _ = cancel.ICS()
+	for _, want := range []string{"METHOD:CANCEL\r\n", "UID:evt-123@example.com\r\n", "SEQUENCE:2\r\n", "STATUS:CANCELLED\r\n"} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("cancel missing %q", want)
+		}
+	}
+	if strings.Contains(ics, "RSVP=TRUE") {
+		t.Error("cancellation must not request RSVP")
+	}
+}
+
+func TestCalendarValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(e *CalendarEvent)
+	}{
+		{"missing uid", func(e *CalendarEvent) { e.UID = "" }},
+		{"end before start", func(e *CalendarEvent) { e.End = e.Start.Add(-time.Hour) }},
+		{"missing organizer", func(e *CalendarEvent) { e.Organizer.Email = "" }},
+		{"unknown zone", func(e *CalendarEvent) { e.TimeZone = "Mars/Olympus" }},
+		{"unknown method", func(e *CalendarEvent) { e.Method = "PUBLISH" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := testEvent()
+			tt.mutate(e)
+			if _, err := e.ICS(); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+}
+
+func TestFoldLine(t *testing.T) {
+	long := strings.Repeat("中", 40) // 120 octets
+	folded := foldLine("SUMMARY:" + long)
+	for _, l := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+		if len(l) > 75 {
+			t.Errorf("line exceeds 75 octets: %d", len(l))
+		}
+	}
+	unfolded := strings.ReplaceAll(folded, "\r\n ", "")
+	if unfolded != "SUMMARY:"+long+"\r\n" {
+		t.Error("folding must not split UTF-8 sequences")
+	}
+}
+
+func TestBuildMessageMIMENesting(t *testing.T) {
+	msg := &Message{
+		To:          "bob@example.com",
+		Subject:     "Invitation: Design review",
+		Body:        `<p>See you there</p><img src="cid:logo">`,
+		IsHTML:      true,
+		Calendar:    testEvent(),
+		Attachments: []Attachment{{Filename: "agenda.txt", Data: []byte("agenda")}},
+		InlineImages: []InlineImage{
+			{CID: "logo", Data: []byte("\x89PNG"), ContentType: "image/png"},
+		},
+	}
+	if err := validateMessage(msg); err != nil {
+		t.Fatalf("validateMessage: %v", err)
+	}
+
+	m, err := buildMessage("alice@example.com", msg)
+	if err != nil {
+		t.Fatalf("buildMessage: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	raw := buf.String()
+
+	// Each marker must appear in this order for the nesting to be correct
+	order := []string{
+		"multipart/mixed",
+		"multipart/related",
+		"multipart/alternative",
+		"Content-Type: text/html",
+		"Content-Type: text/calendar; method=REQUEST",
+		"Content-ID: <logo>",
+		`Content-Disposition: attachment; filename="agenda.txt"`,
+	}
+	pos := 0
+	for _, marker := range order {
+		i := strings.Index(raw[pos:], marker)
+		if i < 0 {
+			t.Fatalf("marker %q missing or out of order in:\n%s", marker, raw)
+		}
+		pos += i + len(marker)
+	}
+	if !strings.Contains(raw, "Content-Type: image/png") {
+		t.Error("inline image should keep its content type")
+	}
+}
+
+func TestBuildMessageCancelMethod(t *testing.T) {
+	e := testEvent()
+	e.Method = MethodCancel
+	e.Sequence = 1
+
+	m, err := buildMessage("alice@example.com", &Message{To: "bob@example.com", Subject: "Cancelled", Body: "cancelled", Calendar: e})
+	if err != nil {
+		t.Fatalf("buildMessage: %v", err)
+	}
+	var buf bytes.Buffer
+	m.WriteTo(&buf)
+	raw := buf.String()
+
+	if !strings.Contains(raw, "Content-Type: text/calendar; method=CANCEL") {
+		t.Errorf("expected CANCEL calendar part in:\n%s", raw)
+	}
+	if strings.Contains(raw, "multipart/mixed") || strings.Contains(raw, "multipart/related") {
+		t.Error("no mixed/related parts expected without attachments or images")
+	}
+}
+
+func TestValidateInlineImages(t *testing.T) {
+	msg := &Message{To: "a@example.com", Subject: "s", InlineImages: []InlineImage{{CID: "", Data: []byte("x")}}}
+	if err := validateMessage(msg); err == nil {
+		t.Error("expected error for empty CID")
+	}
+	msg.InlineImages = []InlineImage{{CID: "logo"}}
+	if err := validateMessage(msg); err == nil {
+		t.Error("expected error for empty data")
+	}
+}
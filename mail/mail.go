@@ -1,6 +1,7 @@
 package mail
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
 	"github.com/spf13/viper"
 	"github.com/wordgate/qtoolkit/aws/ses"
 	"gopkg.in/gomail.v2"
@@ -25,9 +27,17 @@ type Message struct {
 	Subject     string       // Subject line
 	Body        string       // Plain text or HTML body
 	IsHTML      bool         // Body is HTML when true
+	AltText     string       // Optional plain-text alternative of an HTML body
 	ReplyTo     string       // Optional Reply-To header
 	Cc          []string     // Optional CC recipients
 	Attachments []Attachment // Optional attachments
+
+	// Calendar adds a text/calendar alternative part so mail clients show
+	// the message as a meeting invitation.
+	Calendar *CalendarEvent
+	// InlineImages are embedded in a multipart/related part and referenced
+	// from the HTML body as <img src="cid:...">.
+	InlineImages []InlineImage
 }
 
 // Attachment is an in-memory file attached to a Message.
@@ -36,6 +46,13 @@ type Attachment struct {
 	Data     []byte
 }
 
+// InlineImage is an image embedded in the message body by Content-ID.
+type InlineImage struct {
+	CID         string // Referenced as "cid:<CID>" in the HTML body
+	Data        []byte
+	ContentType string // e.g. "image/png"
+}
+
 // Sender is a handle bound to a viper config prefix.
 // Returned by Config(prefix). Safe for concurrent use.
 type Sender struct {
@@ -59,9 +76,14 @@ type sender struct {
 	prefix   string
 	cfg      *config
 	smtp     *gomail.Dialer
+	smtpPool *smtpPool
 	ses      *sesv2.Client
+	pool     poolConfig
 	initOnce sync.Once
 	initErr  error
+
+	queue       *asyncQueue
+	workersOnce sync.Once
 }
 
 var registry sync.Map // string -> *sender
@@ -79,6 +101,7 @@ func Config(prefix string) *Sender {
 }
 
 // Send dispatches msg using the sender identity bound to s.prefix.
+// SMTP connections are reused when <prefix>.pool.idle_seconds is set.
 func (s *Sender) Send(msg *Message) error {
 	if s.prefix == "" {
 		return ErrEmptyPrefix
@@ -90,10 +113,7 @@ func (s *Sender) Send(msg *Message) error {
 	if err != nil {
 		return err
 	}
-	if snd.cfg.Provider == "ses" {
-		return sendViaSES(snd, msg)
-	}
-	return sendViaSMTP(snd, msg)
+	return snd.send(msg)
 }
 
 // Send is the package-level shortcut for Config("mail").Send(msg).
@@ -134,10 +154,15 @@ func ResetForTest() {
 	registry = sync.Map{}
 }
 
+// registeredSender returns the *sender for prefix without initializing it.
+func registeredSender(prefix string) *sender {
+	v, _ := registry.LoadOrStore(prefix, &sender{prefix: prefix, queue: &asyncQueue{}})
+	return v.(*sender)
+}
+
 // resolveSender returns (and lazy-initializes) the *sender for prefix.
 func resolveSender(prefix string) (*sender, error) {
-	v, _ := registry.LoadOrStore(prefix, &sender{prefix: prefix})
-	snd := v.(*sender)
+	snd := registeredSender(prefix)
 	snd.initOnce.Do(func() {
 		cfg, err := loadConfig(prefix)
 		if err != nil {
@@ -145,9 +170,11 @@ func resolveSender(prefix string) (*sender, error) {
 			return
 		}
 		snd.cfg = cfg
+		snd.pool = loadPoolConfig(prefix)
 		switch cfg.Provider {
 		case "smtp":
 			snd.smtp = gomail.NewDialer(cfg.SMTPHost, cfg.SMTPPort, cfg.Username, cfg.Password)
+			snd.smtpPool = newSMTPPool(snd.smtp, snd.pool)
 		case "ses":
 			client, err := ses.NewClient(&ses.Config{
 				AccessKey:   cfg.AccessKey,
@@ -248,20 +275,68 @@ func validateMessage(msg *Message) error {
 			return fmt.Errorf("attachment data cannot be empty")
 		}
 	}
+	for _, img := range msg.InlineImages {
+		if img.CID == "" {
+			return fmt.Errorf("inline image CID cannot be empty")
+		}
+		if len(img.Data) == 0 {
+			return fmt.Errorf("inline image data cannot be empty")
+		}
+	}
+	if msg.Calendar != nil {
+		if err := msg.Calendar.validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// needsRawMIME reports whether msg needs a MIME structure SES simple content cannot express.
+func needsRawMIME(msg *Message) bool {
+	return msg.Calendar != nil || len(msg.InlineImages) > 0
+}
+
+// send dispatches msg through the configured provider.
+func (snd *sender) send(msg *Message) error {
+	if snd.cfg.Provider == "ses" {
+		return sendViaSES(snd, msg)
+	}
+	return sendViaSMTP(snd, msg)
+}
+
 func sendViaSMTP(snd *sender, msg *Message) error {
+	m, err := buildMessage(snd.cfg.SendFrom, msg)
+	if err != nil {
+		return err
+	}
+	return snd.smtpPool.send(m)
+}
+
+// buildMessage assembles the MIME message:
+//
+//	multipart/mixed                 (when attachments)
+//	├─ multipart/related            (when inline images)
+//	│  ├─ multipart/alternative     (when calendar or AltText)
+//	│  │  ├─ text/plain             (AltText of an HTML body)
+//	│  │  ├─ text/html | text/plain
+//	│  │  └─ text/calendar; method=...
+//	│  └─ inline images
+//	└─ attachments
+func buildMessage(from string, msg *Message) (*gomail.Message, error) {
 	m := gomail.NewMessage()
-	m.SetHeader("From", snd.cfg.SendFrom)
+	m.SetHeader("From", from)
 	m.SetHeader("To", msg.To)
 	m.SetHeader("Subject", msg.Subject)
 
-	contentType := "text/plain"
-	if msg.IsHTML {
-		contentType = "text/html"
+	switch {
+	case msg.IsHTML && msg.AltText != "":
+		m.SetBody("text/plain", msg.AltText)
+		m.AddAlternative("text/html", msg.Body)
+	case msg.IsHTML:
+		m.SetBody("text/html", msg.Body)
+	default:
+		m.SetBody("text/plain", msg.Body)
 	}
-	m.SetBody(contentType, msg.Body)
 
 	if msg.ReplyTo != "" {
 		m.SetHeader("Reply-To", msg.ReplyTo)
@@ -270,15 +345,38 @@ func sendViaSMTP(snd *sender, msg *Message) error {
 		m.SetHeader("Cc", msg.Cc...)
 	}
 
+	if msg.Calendar != nil {
+		ics, err := msg.Calendar.ICS()
+		if err != nil {
+			return nil, err
+		}
+		m.AddAlternative("text/calendar; method="+string(msg.Calendar.method()), ics)
+	}
+
+	for _, img := range msg.InlineImages {
+		data := img.Data
+		header := map[string][]string{"Content-ID": {"<" + img.CID + ">"}}
+		if img.ContentType != "" {
+			header["Content-Type"] = []string{img.ContentType}
+		}
+		m.Embed(img.CID, gomail.SetHeader(header), gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		}))
+	}
+
 	for _, att := range msg.Attachments {
 		if err := attachBytes(m, att.Filename, att.Data); err != nil {
-			return err
+			return nil, err
 		}
 	}
-	return snd.smtp.DialAndSend(m)
+	return m, nil
 }
 
 func sendViaSES(snd *sender, msg *Message) error {
+	if needsRawMIME(msg) {
+		return sendRawViaSES(snd, msg)
+	}
 	req := &ses.EmailRequest{
 		From:    snd.cfg.SendFrom,
 		To:      []string{msg.To},
@@ -286,6 +384,7 @@ func sendViaSES(snd *sender, msg *Message) error {
 	}
 	if msg.IsHTML {
 		req.BodyHTML = msg.Body
+		req.BodyText = msg.AltText
 	} else {
 		req.BodyText = msg.Body
 	}
@@ -305,6 +404,30 @@ func sendViaSES(snd *sender, msg *Message) error {
 	return err
 }
 
+// sendRawViaSES sends the fully built MIME message as SES raw content.
+func sendRawViaSES(snd *sender, msg *Message) error {
+	m, err := buildMessage(snd.cfg.SendFrom, msg)
+	if err != nil {
+		return err
+	}
+	var raw bytes.Buffer
+	if _, err := m.WriteTo(&raw); err != nil {
+		return err
+	}
+
+	_, err = snd.ses.SendEmail(context.Background(), &sesv2.SendEmailInput{
+		FromEmailAddress: &snd.cfg.SendFrom,
+		Destination: &types.Destination{
+			ToAddresses: []string{msg.To},
+			CcAddresses: msg.Cc,
+		},
+		Content: &types.EmailContent{
+			Raw: &types.RawMessage{Data: raw.Bytes()},
+		},
+	})
+	return err
+}
+
 func attachBytes(m *gomail.Message, filename string, data []byte) error {
 	if filename == "" {
 		return fmt.Errorf("attachment filename cannot be empty")
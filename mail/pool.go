@@ -0,0 +1,266 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"gopkg.in/gomail.v2"
+)
+
+// ErrQueueFull is returned by SendAsync when the send queue is full.
+var ErrQueueFull = errors.New("mail: send queue full")
+
+// Pool defaults, overridden by <prefix>.pool.*.
+const (
+	defaultPoolWorkers   = 4
+	defaultPoolQueueSize = 1000
+)
+
+// ErrorFunc is called when a message queued with SendAsync fails.
+type ErrorFunc func(msg *Message, err error)
+
+// poolConfig holds the <prefix>.pool.* settings.
+type poolConfig struct {
+	Workers   int           // SMTP connections and async workers
+	Idle      time.Duration // How long an idle SMTP connection is kept; 0 closes it after each send
+	QueueSize int
+}
+
+func loadPoolConfig(prefix string) poolConfig {
+	cfg := poolConfig{
+		Workers:   viper.GetInt(prefix + ".pool.workers"),
+		Idle:      time.Duration(viper.GetInt(prefix+".pool.idle_seconds")) * time.Second,
+		QueueSize: viper.GetInt(prefix + ".pool.queue_size"),
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultPoolWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultPoolQueueSize
+	}
+	return cfg
+}
+
+// smtpPool reuses SMTP connections. At most cap(slots) connections are open
+// at once; idle ones are closed after idle.
+type smtpPool struct {
+	dialer *gomail.Dialer
+	idle   time.Duration
+	slots  chan struct{}
+
+	dialMu sync.Mutex // gomail.Dialer.Dial sets Dialer.Auth on first use
+
+	mu    sync.Mutex
+	conns []*pooledConn // Idle connections, most recently used last
+}
+
+type pooledConn struct {
+	gomail.SendCloser
+	timer *time.Timer
+}
+
+func newSMTPPool(dialer *gomail.Dialer, cfg poolConfig) *smtpPool {
+	return &smtpPool{
+		dialer: dialer,
+		idle:   cfg.Idle,
+		slots:  make(chan struct{}, cfg.Workers),
+	}
+}
+
+// send delivers m over an idle connection, or a new one when none is idle.
+// gomail redials by itself when the server closed the connection.
+func (p *smtpPool) send(m *gomail.Message) error {
+	p.slots <- struct{}{}
+	defer func() { <-p.slots }()
+
+	conn, err := p.get()
+	if err != nil {
+		return err
+	}
+	if err := gomail.Send(conn, m); err != nil {
+		conn.Close()
+		return err
+	}
+	p.put(conn)
+	return nil
+}
+
+func (p *smtpPool) get() (*pooledConn, error) {
+	p.mu.Lock()
+	if n := len(p.conns); n > 0 {
+		conn := p.conns[n-1]
+		p.conns = p.conns[:n-1]
+		p.mu.Unlock()
+		conn.timer.Stop()
+		return conn, nil
+	}
+	p.mu.Unlock()
+
+	p.dialMu.Lock()
+	sc, err := p.dialer.Dial()
+	p.dialMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return &pooledConn{SendCloser: sc}, nil
+}
+
+func (p *smtpPool) put(conn *pooledConn) {
+	if p.idle <= 0 {
+		conn.Close()
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conn.timer = time.AfterFunc(p.idle, func() { p.expire(conn) })
+	p.conns = append(p.conns, conn)
+}
+
+// expire closes conn if it is still idle.
+func (p *smtpPool) expire(conn *pooledConn) {
+	p.mu.Lock()
+	for i, c := range p.conns {
+		if c == conn {
+			p.conns = append(p.conns[:i], p.conns[i+1:]...)
+			p.mu.Unlock()
+			conn.Close()
+			return
+		}
+	}
+	p.mu.Unlock()
+}
+
+// asyncQueue is the in-process queue drained by the SendAsync workers.
+type asyncQueue struct {
+	ch chan *Message
+
+	mu      sync.Mutex
+	pending int           // Queued plus in-flight messages
+	drained chan struct{} // Closed when pending drops to zero
+	onError ErrorFunc
+}
+
+// SendAsync validates msg and queues it for delivery by the sender's
+// workers (<prefix>.pool.workers, default 4). It returns ErrQueueFull when
+// <prefix>.pool.queue_size (default 1000) messages are already waiting.
+// Delivery errors are reported to the OnError callback; use Flush to wait
+// for the queue to drain.
+//
+// Example:
+//
+//	mail.OnError(func(msg *mail.Message, err error) {
+//	    log.Printf("mail to %s failed: %v", msg.To, err)
+//	})
+//	for _, user := range users {
+//	    mail.SendAsync(&mail.Message{To: user.Email, Subject: "Notice", Body: body})
+//	}
+//	mail.Flush(ctx)
+func (s *Sender) SendAsync(msg *Message) error {
+	if s.prefix == "" {
+		return ErrEmptyPrefix
+	}
+	if err := validateMessage(msg); err != nil {
+		return err
+	}
+	snd, err := resolveSender(s.prefix)
+	if err != nil {
+		return err
+	}
+	q := snd.startWorkers()
+
+	q.mu.Lock()
+	if q.pending == 0 {
+		q.drained = make(chan struct{})
+	}
+	q.pending++
+	q.mu.Unlock()
+
+	select {
+	case q.ch <- msg:
+		return nil
+	default:
+		q.done()
+		return ErrQueueFull
+	}
+}
+
+// OnError sets the callback for failed SendAsync deliveries. Pass nil to
+// remove it; failures are then dropped.
+func (s *Sender) OnError(fn ErrorFunc) {
+	q := registeredSender(s.prefix).queue
+	q.mu.Lock()
+	q.onError = fn
+	q.mu.Unlock()
+}
+
+// Flush blocks until every message queued with SendAsync has been delivered
+// or reported to OnError, or ctx is done.
+func (s *Sender) Flush(ctx context.Context) error {
+	q := registeredSender(s.prefix).queue
+	q.mu.Lock()
+	if q.pending == 0 {
+		q.mu.Unlock()
+		return nil
+	}
+	drained := q.drained
+	q.mu.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("mail: flush: %w", ctx.Err())
+	}
+}
+
+// SendAsync is the package-level shortcut for Config("mail").SendAsync(msg).
+func SendAsync(msg *Message) error {
+	return Config("mail").SendAsync(msg)
+}
+
+// OnError is the package-level shortcut for Config("mail").OnError(fn).
+func OnError(fn ErrorFunc) {
+	Config("mail").OnError(fn)
+}
+
+// Flush is the package-level shortcut for Config("mail").Flush(ctx).
+func Flush(ctx context.Context) error {
+	return Config("mail").Flush(ctx)
+}
+
+// startWorkers starts the sender's async workers once and returns its queue.
+func (snd *sender) startWorkers() *asyncQueue {
+	snd.workersOnce.Do(func() {
+		snd.queue.ch = make(chan *Message, snd.pool.QueueSize)
+		for range snd.pool.Workers {
+			go func() {
+				for msg := range snd.queue.ch {
+					if err := snd.send(msg); err != nil {
+						snd.queue.mu.Lock()
+						fn := snd.queue.onError
+						snd.queue.mu.Unlock()
+						if fn != nil {
+							fn(msg, err)
+						}
+					}
+					snd.queue.done()
+				}
+			}()
+		}
+	})
+	return snd.queue
+}
+
+// done marks one queued message as finished.
+func (q *asyncQueue) done() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending--
+	if q.pending == 0 {
+		close(q.drained)
+	}
+}
@@ -0,0 +1,235 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// fakeSMTP is an SMTP server accepting any number of connections. It counts
+// connections and delivered messages, and tracks how many sessions were in
+// a transaction at once. Recipients containing "reject" are refused.
+type fakeSMTP struct {
+	conns     atomic.Int32
+	delivered atomic.Int32
+	active    atomic.Int32
+	maxActive atomic.Int32
+	delay     time.Duration // Applied before accepting DATA
+}
+
+func startFakeSMTP(t *testing.T, delay time.Duration) (*fakeSMTP, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	f := &fakeSMTP{delay: delay}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			f.conns.Add(1)
+			go f.serve(conn)
+		}
+	}()
+	return f, ln.Addr().String()
+}
+
+func (f *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	write := func(s string) { _, _ = fmt.Fprint(conn, s) }
+	write("220 localhost test ready\r\n")
+
+	inData := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if inData {
+			if strings.TrimRight(line, "\r\n") == "." {
+				inData = false
+				f.delivered.Add(1)
+				f.active.Add(-1)
+				write("250 2.0.0 OK\r\n")
+			}
+			continue
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			write("250 localhost\r\n")
+		case strings.HasPrefix(cmd, "MAIL FROM"):
+			n := f.active.Add(1)
+			for {
+				max := f.maxActive.Load()
+				if n <= max || f.maxActive.CompareAndSwap(max, n) {
+					break
+				}
+			}
+			write("250 2.1.0 OK\r\n")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			if strings.Contains(cmd, "REJECT") {
+				f.active.Add(-1)
+				write("550 5.1.1 no such user\r\n")
+				continue
+			}
+			write("250 2.1.0 OK\r\n")
+		case cmd == "DATA":
+			time.Sleep(f.delay)
+			write("354 End data with <CRLF>.<CRLF>\r\n")
+			inData = true
+		case cmd == "QUIT":
+			write("221 2.0.0 bye\r\n")
+			return
+		case cmd == "RSET", cmd == "NOOP":
+			write("250 2.0.0 OK\r\n")
+		default:
+			write("502 5.5.1 not implemented\r\n")
+		}
+	}
+}
+
+// setupPool points the "pool" prefix at addr with the given pool settings.
+func setupPool(t *testing.T, addr string, workers, idleSeconds int) *Sender {
+	t.Helper()
+	host, port, _ := net.SplitHostPort(addr)
+	viper.Set("pool.send_from", "from@example.com")
+	viper.Set("pool.username", "u")
+	viper.Set("pool.password", "p")
+	viper.Set("pool.smtp_host", host)
+	viper.Set("pool.smtp_port", port)
+	viper.Set("pool.pool.workers", workers)
+	viper.Set("pool.pool.idle_seconds", idleSeconds)
+	resetMailer()
+	t.Cleanup(resetMailer)
+	return Config("pool")
+}
+
+func TestSendReusesConnection(t *testing.T) {
+	f, addr := startFakeSMTP(t, 0)
+	s := setupPool(t, addr, 2, 30)
+
+	for i := range 5 {
+		if err := s.Send(&Message{To: "rcpt@example.com", Subject: fmt.Sprint("n", i), Body: "hi"}); err != nil {
+			t.Fatalf("Send %d: %v", i, err)
+		}
+	}
+	if got := f.conns.Load(); got != 1 {
+		t.Errorf("expected 1 connection for sequential sends, got %d", got)
+	}
+	if got := f.delivered.Load(); got != 5 {
+		t.Errorf("expected 5 messages delivered, got %d", got)
+	}
+}
+
+func TestSendWithoutIdleClosesConnection(t *testing.T) {
+	f, addr := startFakeSMTP(t, 0)
+	s := setupPool(t, addr, 2, 0)
+
+	for range 3 {
+		if err := s.Send(&Message{To: "rcpt@example.com", Subject: "s", Body: "hi"}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if got := f.conns.Load(); got != 3 {
+		t.Errorf("expected a connection per send without idle_seconds, got %d", got)
+	}
+}
+
+func TestSendAsync(t *testing.T) {
+	f, addr := startFakeSMTP(t, 10*time.Millisecond)
+	s := setupPool(t, addr, 3, 30)
+
+	var mu sync.Mutex
+	var failed []string
+	s.OnError(func(msg *Message, err error) {
+		mu.Lock()
+		failed = append(failed, msg.To)
+		mu.Unlock()
+	})
+
+	for i := range 20 {
+		to := fmt.Sprintf("user%d@example.com", i)
+		if i == 7 {
+			to = "reject@example.com"
+		}
+		if err := s.SendAsync(&Message{To: to, Subject: "Notice", Body: "hi"}); err != nil {
+			t.Fatalf("SendAsync %d: %v", i, err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	if got := f.delivered.Load(); got != 19 {
+		t.Errorf("expected 19 messages delivered, got %d", got)
+	}
+	if len(failed) != 1 || failed[0] != "reject@example.com" {
+		t.Errorf("expected the rejected message reported to OnError, got %v", failed)
+	}
+	if got := f.maxActive.Load(); got < 2 || got > 3 {
+		t.Errorf("expected 2-3 concurrent sessions with 3 workers, got %d", got)
+	}
+	if got := f.conns.Load(); got > 4 {
+		t.Errorf("expected at most 3 connections (plus 1 redial after the rejection), got %d", got)
+	}
+}
+
+func TestSendAsyncQueueFull(t *testing.T) {
+	_, addr := startFakeSMTP(t, 200*time.Millisecond)
+	s := setupPool(t, addr, 1, 30)
+	viper.Set("pool.pool.queue_size", 1)
+	t.Cleanup(func() { viper.Set("pool.pool.queue_size", 0) })
+
+	msg := &Message{To: "rcpt@example.com", Subject: "s", Body: "hi"}
+	var err error
+	for range 5 {
+		if err = s.SendAsync(msg); err != nil {
+			break
+		}
+	}
+	if !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := s.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected Flush to time out, got %v", err)
+	}
+	if err := s.Flush(context.Background()); err != nil {
+		t.Errorf("Flush: %v", err)
+	}
+}
+
+func TestSendAsyncValidation(t *testing.T) {
+	_, addr := startFakeSMTP(t, 0)
+	s := setupPool(t, addr, 1, 0)
+
+	if err := s.SendAsync(&Message{Subject: "s"}); err == nil {
+		t.Error("SendAsync should validate the message")
+	}
+	if err := Config("").SendAsync(&Message{To: "a@b.c", Subject: "s"}); !errors.Is(err, ErrEmptyPrefix) {
+		t.Errorf("expected ErrEmptyPrefix, got %v", err)
+	}
+	if err := s.Flush(context.Background()); err != nil {
+		t.Errorf("Flush with nothing queued: %v", err)
+	}
+}
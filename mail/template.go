@@ -0,0 +1,191 @@
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// ErrTemplateNotFound is returned when a template file does not exist.
+var ErrTemplateNotFound = errors.New("mail: template not found")
+
+// layoutFile is the optional shared layout in the templates directory. It
+// renders the page with {{template "content" .}}.
+const layoutFile = "layout.html"
+
+// TemplateMessage is an email whose HTML body is rendered from a template
+// in <prefix>.templates_dir.
+type TemplateMessage struct {
+	To          string
+	Subject     string
+	Template    string // File name without ".html", e.g. "welcome" for welcome.html
+	Data        any    // Template data
+	ReplyTo     string
+	Cc          []string
+	Attachments []Attachment
+}
+
+var (
+	templateCache   = map[string]*template.Template{} // "<dir>/<name>" -> parsed template
+	templateCacheMu sync.RWMutex
+)
+
+// SendTemplate renders <prefix>.templates_dir/<templateName>.html with data
+// and sends it as HTML with a plain-text alternative.
+//
+// When the directory contains layout.html and the template defines
+// "content", the layout is rendered around it:
+//
+//	<!-- layout.html -->
+//	<html><body>{{template "content" .}}<p>Footer</p></body></html>
+//
+//	<!-- welcome.html -->
+//	{{define "content"}}<h1>Hi {{.Name}}</h1>{{end}}
+//
+// Parsed templates are cached; call Reload after editing them.
+func (s *Sender) SendTemplate(to, subject, templateName string, data any) error {
+	return s.SendTemplateMessage(&TemplateMessage{
+		To:       to,
+		Subject:  subject,
+		Template: templateName,
+		Data:     data,
+	})
+}
+
+// SendTemplateMessage is SendTemplate with Cc, Reply-To and attachments.
+func (s *Sender) SendTemplateMessage(msg *TemplateMessage) error {
+	if s.prefix == "" {
+		return ErrEmptyPrefix
+	}
+	body, err := renderTemplate(viper.GetString(s.prefix+".templates_dir"), msg.Template, msg.Data)
+	if err != nil {
+		return err
+	}
+	return s.Send(&Message{
+		To:          msg.To,
+		Subject:     msg.Subject,
+		Body:        body,
+		IsHTML:      true,
+		AltText:     htmlToText(body),
+		ReplyTo:     msg.ReplyTo,
+		Cc:          msg.Cc,
+		Attachments: msg.Attachments,
+	})
+}
+
+// SendTemplate is the package-level shortcut for
+// Config("mail").SendTemplate(to, subject, templateName, data).
+//
+// Example:
+//
+//	mail.SendTemplate("user@example.com", "Welcome", "welcome", map[string]any{
+//	    "Name": "Alice",
+//	})
+func SendTemplate(to, subject, templateName string, data any) error {
+	return Config("mail").SendTemplate(to, subject, templateName, data)
+}
+
+// SendTemplateMessage is the package-level shortcut for
+// Config("mail").SendTemplateMessage(msg).
+func SendTemplateMessage(msg *TemplateMessage) error {
+	return Config("mail").SendTemplateMessage(msg)
+}
+
+// Reload drops all cached templates so they are parsed again on next use.
+func Reload() {
+	templateCacheMu.Lock()
+	templateCache = map[string]*template.Template{}
+	templateCacheMu.Unlock()
+}
+
+// renderTemplate executes dir/name.html, inside dir/layout.html when both
+// exist and the template defines "content".
+func renderTemplate(dir, name string, data any) (string, error) {
+	t, err := loadTemplate(dir, name)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("mail: render template %q: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+func loadTemplate(dir, name string) (*template.Template, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("%w: templates_dir not configured for template %q", ErrMissingConfig, name)
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("mail: invalid template name %q", name)
+	}
+	key := filepath.Join(dir, name)
+
+	templateCacheMu.RLock()
+	t := templateCache[key]
+	templateCacheMu.RUnlock()
+	if t != nil {
+		return t, nil
+	}
+
+	path := filepath.Join(dir, name+".html")
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("%w: %q (%s)", ErrTemplateNotFound, name, path)
+	}
+	t, err := template.ParseFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("mail: parse template %q: %w", name, err)
+	}
+
+	layout := filepath.Join(dir, layoutFile)
+	if _, err := os.Stat(layout); err == nil && t.Lookup("content") != nil {
+		if t, err = template.ParseFiles(layout, path); err != nil {
+			return nil, fmt.Errorf("mail: parse template %q with layout: %w", name, err)
+		}
+	}
+
+	templateCacheMu.Lock()
+	templateCache[key] = t
+	templateCacheMu.Unlock()
+	return t, nil
+}
+
+var (
+	invisibleRegex = regexp.MustCompile(`(?is)<(head|style|script)\b.*?</(head|style|script)>`)
+	linkRegex      = regexp.MustCompile(`(?is)<a\s[^>]*href\s*=\s*["']([^"']+)["'][^>]*>(.*?)</a>`)
+	breakRegex     = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|h[1-6]|li|tr|table|blockquote)>`)
+	tagRegex       = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankRegex     = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText derives the plain-text alternative of an HTML body: block
+// ends become line breaks, links keep their URL, other tags are dropped.
+func htmlToText(body string) string {
+	text := invisibleRegex.ReplaceAllString(body, "")
+	text = linkRegex.ReplaceAllStringFunc(text, func(a string) string {
+		m := linkRegex.FindStringSubmatch(a)
+		label := strings.TrimSpace(tagRegex.ReplaceAllString(m[2], ""))
+		if label == "" || label == m[1] {
+			return m[1]
+		}
+		return label + " (" + m[1] + ")"
+	})
+	text = breakRegex.ReplaceAllString(text, "\n")
+	text = html.UnescapeString(tagRegex.ReplaceAllString(text, ""))
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	text = blankRegex.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,141 @@
+package mail
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// setupTemplates writes files into a temp templates_dir for the "mail" prefix.
+func setupTemplates(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	viper.Set("mail.templates_dir", dir)
+	Reload()
+	t.Cleanup(func() {
+		viper.Set("mail.templates_dir", "")
+		Reload()
+	})
+	return dir
+}
+
+func TestRenderTemplate(t *testing.T) {
+	dir := setupTemplates(t, map[string]string{
+		"layout.html":  `<html><body>{{template "content" .}}<p>Footer</p></body></html>`,
+		"welcome.html": `{{define "content"}}<h1>Hi {{.Name}}</h1>{{end}}`,
+		"plain.html":   `<p>No layout for {{.Name}}</p>`,
+		"broken.html":  `{{define "content"}}{{.Missing.Field}}{{end}}`,
+	})
+	type user struct{ Name string }
+
+	got, err := renderTemplate(dir, "welcome", user{Name: "<Alice>"})
+	if err != nil {
+		t.Fatalf("renderTemplate: %v", err)
+	}
+	if got != `<html><body><h1>Hi &lt;Alice&gt;</h1><p>Footer</p></body></html>` {
+		t.Errorf("unexpected layout rendering: %s", got)
+	}
+
+	got, err = renderTemplate(dir, "plain", user{Name: "Bob"})
+	if err != nil || got != `<p>No layout for Bob</p>` {
+		t.Errorf("template without content should render alone, got %q, %v", got, err)
+	}
+
+	_, err = renderTemplate(dir, "missing", nil)
+	if !errors.Is(err, ErrTemplateNotFound) || !strings.Contains(err.Error(), `"missing"`) {
+		t.Errorf("expected ErrTemplateNotFound naming the template, got %v", err)
+	}
+	_, err = renderTemplate(dir, "broken", user{Name: "x"})
+	if err == nil || !strings.Contains(err.Error(), `"broken"`) {
+		t.Errorf("expected execution error naming the template, got %v", err)
+	}
+	if _, err := renderTemplate(dir, "../secret", nil); err == nil {
+		t.Error("expected error for a path in the template name")
+	}
+	if _, err := renderTemplate("", "welcome", nil); !errors.Is(err, ErrMissingConfig) {
+		t.Errorf("expected ErrMissingConfig without templates_dir, got %v", err)
+	}
+}
+
+func TestTemplateReload(t *testing.T) {
+	dir := setupTemplates(t, map[string]string{"note.html": `v1`})
+
+	if got, _ := renderTemplate(dir, "note", nil); got != "v1" {
+		t.Fatalf("got %q", got)
+	}
+	os.WriteFile(filepath.Join(dir, "note.html"), []byte(`v2`), 0o644)
+	if got, _ := renderTemplate(dir, "note", nil); got != "v1" {
+		t.Errorf("template should be cached, got %q", got)
+	}
+	Reload()
+	if got, _ := renderTemplate(dir, "note", nil); got != "v2" {
+		t.Errorf("Reload should re-parse templates, got %q", got)
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	body := `<html><head><title>T</title><style>p{color:red}</style></head><body>
+<h1>Hello &amp; welcome</h1><p>Line one<br>Line   two</p>
+<p>Visit <a href="https://example.com/x">our <b>site</b></a> or https://example.com</p>
+<ul><li>One</li><li>Two</li></ul></body></html>`
+	want := "Hello & welcome\nLine one\nLine two\n\nVisit our site (https://example.com/x) or https://example.com\n\nOne\nTwo"
+	if got := htmlToText(body); got != want {
+		t.Errorf("htmlToText() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSendTemplateMessage(t *testing.T) {
+	host, port, bodyCh := captureSMTP(t)
+	setupTemplates(t, map[string]string{
+		"layout.html":  `<html><body>{{template "content" .}}</body></html>`,
+		"receipt.html": `{{define "content"}}<p>Order {{.OrderID}} paid</p>{{end}}`,
+	})
+
+	resetMailer()
+	viper.Set("mail.provider", "")
+	viper.Set("mail.send_from", "from@example.com")
+	viper.Set("mail.username", "u")
+	viper.Set("mail.password", "p")
+	viper.Set("mail.smtp_host", host)
+	viper.Set("mail.smtp_port", port)
+
+	err := SendTemplateMessage(&TemplateMessage{
+		To:          "rcpt@example.com",
+		Subject:     "Receipt",
+		Template:    "receipt",
+		Data:        map[string]string{"OrderID": "A-42"},
+		Cc:          []string{"cc@example.com"},
+		Attachments: []Attachment{{Filename: "receipt.txt", Data: []byte("paid")}},
+	})
+	if err != nil {
+		t.Fatalf("SendTemplateMessage: %v", err)
+	}
+
+	body := <-bodyCh
+	for _, want := range []string{
+		"Cc: cc@example.com",
+		"multipart/alternative",
+		"Content-Type: text/plain",
+		"Order A-42 paid\r\n",
+		"Content-Type: text/html",
+		"<p>Order A-42 paid</p>",
+		`filename="receipt.txt"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("message should contain %q; raw DATA was:\n%s", want, body)
+		}
+	}
+
+	if err := SendTemplate("rcpt@example.com", "Missing", "nope", nil); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("expected ErrTemplateNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package nextpay
+
+// Disputes (chargebacks): a customer's bank reverses a payment and NextPay opens
+// a dispute against the order. The App either contests it by submitting
+// evidence before EvidenceDueBy, or accepts it (the funds stay reversed).
+//
+// Evidence files never pass through the JSON API: RequestEvidenceUpload returns
+// a presigned target, the file is PUT there directly (UploadEvidence), and the
+// returned FileID is referenced from DisputeEvidence.FileIDs.
+//
+// dispute.created / dispute.closed webhooks carry WebhookDisputeData, so the
+// App learns about a dispute as it is opened instead of from a statement.
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Dispute is a chargeback opened against an order.
+type Dispute struct {
+	UUID          string `json:"uuid"`
+	OrderUUID     string `json:"orderUuid"` // the disputed order
+	Amount        uint64 `json:"amount"`    // disputed amount, cents
+	Currency      string `json:"currency"`
+	ReasonCode    string `json:"reasonCode"`         // card network reason, e.g. "fraudulent", "product_not_received"
+	Reason        string `json:"reason,omitempty"`   // human-readable reason
+	Status        string `json:"status"`             // needs_response, under_review, won, lost, accepted
+	EvidenceDueBy int64  `json:"evidenceDueBy"`      // unix seconds; 0 once evidence can no longer be submitted
+	ClosedAt      int64  `json:"closedAt,omitempty"` // unix seconds
+	CreatedAt     int64  `json:"createdAt"`
+	Order         *Order `json:"order,omitempty"`
+	User          *User  `json:"user,omitempty"`
+}
+
+// EvidenceDeadline returns EvidenceDueBy as a time.Time, or the zero time when
+// no deadline is set.
+func (d *Dispute) EvidenceDeadline() time.Time {
+	return unixTime(d.EvidenceDueBy)
+}
+
+// DisputeEvidence is the evidence submitted to contest a dispute. Text fields
+// are free-form; files are uploaded first via RequestEvidenceUpload and
+// referenced here by FileID.
+type DisputeEvidence struct {
+	CustomerCommunication string   `json:"customerCommunication,omitempty"` // emails/chats with the customer
+	ServiceDescription    string   `json:"serviceDescription,omitempty"`    // what was sold and how it was delivered
+	RefundPolicy          string   `json:"refundPolicy,omitempty"`          // policy text shown to the customer
+	FileIDs               []string `json:"fileIds,omitempty"`               // UploadTarget.FileID values
+}
+
+// UploadTarget is a presigned location for one evidence file. PUT the file to
+// URL with Headers set (see UploadEvidence) before ExpiresAt.
+type UploadTarget struct {
+	FileID    string            `json:"fileId"`
+	URL       string            `json:"url"`
+	Method    string            `json:"method"` // "PUT"
+	Headers   map[string]string `json:"headers,omitempty"`
+	ExpiresAt int64             `json:"expiresAt"` // unix seconds
+}
+
+// ListDisputes returns one page of the app's disputes. An empty status returns
+// every status; page is 1-based.
+func ListDisputes(ctx context.Context, status string, page, perPage int) ([]Dispute, error) {
+	return do(ctx, func(ctx context.Context, c *Client) ([]Dispute, error) {
+		return c.listDisputes(ctx, status, page, perPage)
+	})
+}
+
+// GetDispute returns a single dispute by its uuid, including the linked order.
+func GetDispute(ctx context.Context, disputeUUID string) (*Dispute, error) {
+	return do(ctx, func(ctx context.Context, c *Client) (*Dispute, error) { return c.getDispute(ctx, disputeUUID) })
+}
+
+// SubmitDisputeEvidence submits evidence to contest a dispute. It must be
+// called before the dispute's EvidenceDueBy; evidence is final once submitted.
+func SubmitDisputeEvidence(ctx context.Context, disputeUUID string, evidence *DisputeEvidence) error {
+	if evidence == nil {
+		return fmt.Errorf("%w: evidence is required", ErrInvalidInput)
+	}
+	_, err := do(ctx, func(ctx context.Context, c *Client) (struct{}, error) {
+		return struct{}{}, c.submitDisputeEvidence(ctx, disputeUUID, evidence)
+	})
+	return err
+}
+
+// RequestEvidenceUpload reserves an evidence file for a dispute and returns
+// where to upload it.
+func RequestEvidenceUpload(ctx context.Context, disputeUUID, filename, contentType string) (*UploadTarget, error) {
+	return do(ctx, func(ctx context.Context, c *Client) (*UploadTarget, error) {
+		return c.requestEvidenceUpload(ctx, disputeUUID, filename, contentType)
+	})
+}
+
+// UploadEvidence sends a file to a target returned by RequestEvidenceUpload.
+// The URL is presigned, so the request carries no access key.
+func UploadEvidence(ctx context.Context, target *UploadTarget, content io.Reader) error {
+	_, err := do(ctx, func(ctx context.Context, c *Client) (struct{}, error) {
+		return struct{}{}, c.uploadEvidence(ctx, target, content)
+	})
+	return err
+}
+
+// AcceptDispute concedes a dispute that will not be contested. The disputed
+// amount stays with the customer and the dispute closes as "accepted".
+func AcceptDispute(ctx context.Context, disputeUUID string) error {
+	_, err := do(ctx, func(ctx context.Context, c *Client) (struct{}, error) {
+		return struct{}{}, c.acceptDispute(ctx, disputeUUID)
+	})
+	return err
+}
+
+// --- Client methods: disputes ---
+
+func disputePath(disputeUUID, action string) string {
+	path := "/api/disputes/" + url.PathEscape(disputeUUID)
+	if action != "" {
+		path += "/" + action
+	}
+	return path
+}
+
+func (c *Client) listDisputes(ctx context.Context, status string, page, perPage int) ([]Dispute, error) {
+	q := url.Values{}
+	if status != "" {
+		q.Set("status", status)
+	}
+	if page > 0 {
+		q.Set("page", strconv.Itoa(page))
+	}
+	if perPage > 0 {
+		q.Set("pageSize", strconv.Itoa(perPage))
+	}
+	path := "/api/disputes"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	resp, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return decodeItems[Dispute](resp.Data)
+}
+
+func (c *Client) getDispute(ctx context.Context, disputeUUID string) (*Dispute, error) {
+	resp, err := c.doRequest(ctx, "GET", disputePath(disputeUUID, ""), nil)
+	if err != nil {
+		return nil, err
+	}
+	return decodeData[Dispute](resp.Data)
+}
+
+func (c *Client) submitDisputeEvidence(ctx context.Context, disputeUUID string, evidence *DisputeEvidence) error {
+	_, err := c.doRequest(ctx, "POST", disputePath(disputeUUID, "evidence"), evidence)
+	return err
+}
+
+func (c *Client) requestEvidenceUpload(ctx context.Context, disputeUUID, filename, contentType string) (*UploadTarget, error) {
+	resp, err := c.doRequest(ctx, "POST", disputePath(disputeUUID, "evidence/uploads"),
+		map[string]any{"filename": filename, "contentType": contentType})
+	if err != nil {
+		return nil, err
+	}
+	return decodeData[UploadTarget](resp.Data)
+}
+
+func (c *Client) acceptDispute(ctx context.Context, disputeUUID string) error {
+	_, err := c.doRequest(ctx, "POST", disputePath(disputeUUID, "accept"), nil)
+	return err
+}
+
+func (c *Client) uploadEvidence(ctx context.Context, target *UploadTarget, content io.Reader) error {
+	if target == nil || target.URL == "" {
+		return fmt.Errorf("%w: upload target is required", ErrInvalidInput)
+	}
+	method := target.Method
+	if method == "" {
+		method = "PUT"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.URL, content)
+	if err != nil {
+		return fmt.Errorf("failed to create upload request: %w", err)
+	}
+	for k, v := range target.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("upload failed: HTTP %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// unixTime converts unix seconds to time.Time, mapping 0 to the zero time.
+func unixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
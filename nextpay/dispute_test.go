@@ -0,0 +1,185 @@
+package nextpay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListDisputes_Success(t *testing.T) {
+	resetState()
+	defer mock(t, func(t *testing.T, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/api/disputes" {
+			t.Errorf("got %s %s", r.Method, r.URL.Path)
+		}
+		q := r.URL.Query()
+		if q.Get("status") != "needs_response" || q.Get("page") != "2" || q.Get("pageSize") != "20" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+	}, testResponse{Data: items(
+		map[string]any{"uuid": "dp_1", "orderUuid": "ord_1", "amount": 999, "currency": "usd",
+			"reasonCode": "fraudulent", "status": "needs_response", "evidenceDueBy": 1700086400},
+	)})()
+
+	disputes, err := ListDisputes(context.Background(), "needs_response", 2, 20)
+	if err != nil {
+		t.Fatalf("ListDisputes: %v", err)
+	}
+	if len(disputes) != 1 || disputes[0].UUID != "dp_1" || disputes[0].ReasonCode != "fraudulent" {
+		t.Errorf("unexpected disputes: %+v", disputes)
+	}
+}
+
+func TestListDisputes_NoFilter(t *testing.T) {
+	resetState()
+	defer mock(t, func(t *testing.T, r *http.Request) {
+		if r.URL.RawQuery != "" {
+			t.Errorf("expected no query, got %q", r.URL.RawQuery)
+		}
+	}, testResponse{Data: items()})()
+
+	if _, err := ListDisputes(context.Background(), "", 0, 0); err != nil {
+		t.Fatalf("ListDisputes: %v", err)
+	}
+}
+
+func TestGetDispute_DueDate(t *testing.T) {
+	resetState()
+	defer mock(t, func(t *testing.T, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/api/disputes/dp_1" {
+			t.Errorf("got %s %s", r.Method, r.URL.Path)
+		}
+	}, testResponse{Data: map[string]any{
+		"uuid": "dp_1", "orderUuid": "ord_1", "amount": 1999, "currency": "usd",
+		"reasonCode": "product_not_received", "status": "needs_response", "evidenceDueBy": 1700086400,
+		"order": map[string]any{"uuid": "ord_1", "amount": 1999, "status": "paid"},
+	}})()
+
+	d, err := GetDispute(context.Background(), "dp_1")
+	if err != nil {
+		t.Fatalf("GetDispute: %v", err)
+	}
+	if d.Order == nil || d.Order.UUID != "ord_1" || d.Amount != 1999 {
+		t.Errorf("unexpected dispute: %+v", d)
+	}
+	want := time.Date(2023, 11, 15, 22, 13, 20, 0, time.UTC)
+	if !d.EvidenceDeadline().Equal(want) {
+		t.Errorf("EvidenceDeadline = %v, want %v", d.EvidenceDeadline(), want)
+	}
+	if !(&Dispute{}).EvidenceDeadline().IsZero() {
+		t.Error("missing due date should map to the zero time")
+	}
+}
+
+func TestSubmitDisputeEvidence_Body(t *testing.T) {
+	resetState()
+	defer mock(t, func(t *testing.T, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/disputes/dp_1/evidence" {
+			t.Errorf("got %s %s", r.Method, r.URL.Path)
+		}
+		body := decodeBody(t, r)
+		if body["customerCommunication"] != "chat log" || body["serviceDescription"] != "Pro plan" ||
+			body["refundPolicy"] != "30 days" {
+			t.Errorf("unexpected text fields: %v", body)
+		}
+		files, _ := body["fileIds"].([]any)
+		if len(files) != 2 || files[0] != "file_1" || files[1] != "file_2" {
+			t.Errorf("unexpected fileIds: %v", body["fileIds"])
+		}
+	}, testResponse{})()
+
+	err := SubmitDisputeEvidence(context.Background(), "dp_1", &DisputeEvidence{
+		CustomerCommunication: "chat log",
+		ServiceDescription:    "Pro plan",
+		RefundPolicy:          "30 days",
+		FileIDs:               []string{"file_1", "file_2"},
+	})
+	if err != nil {
+		t.Fatalf("SubmitDisputeEvidence: %v", err)
+	}
+
+	if err := SubmitDisputeEvidence(context.Background(), "dp_1", nil); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput for nil evidence, got %v", err)
+	}
+}
+
+func TestEvidenceUploadFlow(t *testing.T) {
+	resetState()
+
+	var uploaded string
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Query().Get("sig") != "abc" {
+			t.Errorf("got %s %s", r.Method, r.URL)
+		}
+		if r.Header.Get("X-Access-Key") != "" {
+			t.Error("presigned upload must not carry the access key")
+		}
+		if r.Header.Get("Content-Type") != "application/pdf" {
+			t.Errorf("Content-Type = %q, want application/pdf", r.Header.Get("Content-Type"))
+		}
+		data, _ := io.ReadAll(r.Body)
+		uploaded = string(data)
+	}))
+	defer storage.Close()
+
+	defer mock(t, func(t *testing.T, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/disputes/dp_1/evidence/uploads" {
+			t.Errorf("got %s %s", r.Method, r.URL.Path)
+		}
+		body := decodeBody(t, r)
+		if body["filename"] != "receipt.pdf" || body["contentType"] != "application/pdf" {
+			t.Errorf("unexpected body: %v", body)
+		}
+	}, testResponse{Data: map[string]any{
+		"fileId": "file_1", "url": storage.URL + "/upload?sig=abc", "method": "PUT",
+		"headers": map[string]string{"Content-Type": "application/pdf"}, "expiresAt": 1700000900,
+	}})()
+
+	ctx := context.Background()
+	target, err := RequestEvidenceUpload(ctx, "dp_1", "receipt.pdf", "application/pdf")
+	if err != nil {
+		t.Fatalf("RequestEvidenceUpload: %v", err)
+	}
+	if target.FileID != "file_1" || target.ExpiresAt != 1700000900 {
+		t.Errorf("unexpected target: %+v", target)
+	}
+
+	if err := UploadEvidence(ctx, target, strings.NewReader("%PDF-1.4")); err != nil {
+		t.Fatalf("UploadEvidence: %v", err)
+	}
+	if uploaded != "%PDF-1.4" {
+		t.Errorf("uploaded %q", uploaded)
+	}
+}
+
+func TestUploadEvidence_StorageError(t *testing.T) {
+	resetState()
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "signature expired", http.StatusForbidden)
+	}))
+	defer storage.Close()
+	SetConfig(&Config{AccessKey: "test-key", Endpoint: storage.URL})
+
+	err := UploadEvidence(context.Background(), &UploadTarget{URL: storage.URL}, strings.NewReader("x"))
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("expected HTTP 403 error, got %v", err)
+	}
+}
+
+func TestAcceptDispute_Success(t *testing.T) {
+	resetState()
+	defer mock(t, func(t *testing.T, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/disputes/dp_1/accept" {
+			t.Errorf("got %s %s", r.Method, r.URL.Path)
+		}
+	}, testResponse{})()
+
+	if err := AcceptDispute(context.Background(), "dp_1"); err != nil {
+		t.Fatalf("AcceptDispute: %v", err)
+	}
+}
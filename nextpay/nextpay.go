@@ -89,6 +89,10 @@ type Config struct {
 	AccessKey string `yaml:"access_key"`
 	Endpoint  string `yaml:"endpoint"`
 	Timeout   int    `yaml:"timeout"` // seconds
+
+	// Retry is the retry policy for transient failures. The zero value
+	// disables retries; loaded configs default to 3 attempts.
+	Retry RetryPolicy `yaml:"retry"`
 }
 
 var (
@@ -105,6 +109,12 @@ func loadConfigFromViper() (*Config, error) {
 		AccessKey: viper.GetString("nextpay.access_key"),
 		Endpoint:  viper.GetString("nextpay.endpoint"),
 		Timeout:   viper.GetInt("nextpay.timeout"),
+		Retry: RetryPolicy{
+			MaxAttempts:    viper.GetInt("nextpay.retry.max_attempts"),
+			Backoff:        viper.GetDuration("nextpay.retry.backoff"),
+			StatusCodes:    viper.GetIntSlice("nextpay.retry.status_codes"),
+			AttemptTimeout: viper.GetDuration("nextpay.retry.attempt_timeout"),
+		},
 	}
 
 	if cfg.Endpoint == "" {
@@ -113,6 +123,9 @@ func loadConfigFromViper() (*Config, error) {
 	if cfg.Timeout == 0 {
 		cfg.Timeout = 30
 	}
+	if !viper.IsSet("nextpay.retry.max_attempts") {
+		cfg.Retry.MaxAttempts = defaultMaxAttempts
+	}
 
 	if cfg.AccessKey == "" {
 		return nil, fmt.Errorf("nextpay.access_key is required")
@@ -280,6 +293,17 @@ type RechargeContract struct {
 	User                  *User  `json:"user,omitempty"`
 }
 
+// Refund is a full or partial refund of an order.
+type Refund struct {
+	UUID      string `json:"uuid"`
+	OrderUUID string `json:"orderUuid"`
+	Amount    uint64 `json:"amount"` // refunded amount, cents
+	Currency  string `json:"currency"`
+	Status    string `json:"status"` // pending, succeeded, failed
+	Reason    string `json:"reason,omitempty"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
 // --- Request/result types ---
 
 // OrderRequest creates a one-time payment order.
@@ -390,6 +414,7 @@ type PendingChargeRequest struct {
 	Amount         uint64 `json:"amount"` // in cents, > 0
 	Description    string `json:"description"`
 	Metadata       string `json:"metadata,omitempty"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"` // optional; dedupes retries
 }
 
 // PendingChargeResult is the result of CreatePendingCharge (distinct from the
@@ -408,6 +433,13 @@ type PendingChargeResult struct {
 	CreatedAt           int64  `json:"createdAt"`
 }
 
+// RefundRequest refunds a paid order.
+type RefundRequest struct {
+	Amount         uint64 `json:"amount,omitempty"` // in cents; 0 refunds the remaining amount
+	Reason         string `json:"reason,omitempty"` // audit note
+	IdempotencyKey string `json:"idempotencyKey"`   // required; unique per app, dedupes retries
+}
+
 // RechargeContractRequest creates an auto-recharge contract.
 type RechargeContractRequest struct {
 	UserID        string `json:"userId"`
@@ -551,6 +583,16 @@ func GetOrder(ctx context.Context, orderUUID string) (*Order, error) {
 	return do(ctx, func(ctx context.Context, c *Client) (*Order, error) { return c.getOrder(ctx, orderUUID) })
 }
 
+// RefundOrder refunds a paid order, fully or partially. Partial refunds can be
+// repeated until the order amount is used up; the order moves to "refunded"
+// once fully refunded. Idempotent on req.IdempotencyKey.
+func RefundOrder(ctx context.Context, orderUUID string, req *RefundRequest) (*Refund, error) {
+	if req == nil {
+		return nil, fmt.Errorf("%w: refund request is required", ErrInvalidInput)
+	}
+	return do(ctx, func(ctx context.Context, c *Client) (*Refund, error) { return c.refundOrder(ctx, orderUUID, req) })
+}
+
 // ListPlans returns the app's plans. When includeInactive is false only active
 // plans are returned.
 func ListPlans(ctx context.Context, includeInactive bool) ([]Plan, error) {
@@ -727,20 +769,57 @@ func do[T any](ctx context.Context, fn func(context.Context, *Client) (T, error)
 }
 
 // doRequest performs an authenticated request and returns the decoded envelope.
-// A non-zero response code is mapped to *APIError.
+// A non-zero response code is mapped to *APIError. Transient failures are
+// retried under the config's RetryPolicy; when more than one attempt was made
+// the final error is wrapped in *RetryError.
 func (c *Client) doRequest(ctx context.Context, method, path string, body any) (*Response, error) {
-	var reqBody io.Reader
+	var data []byte
 	if body != nil {
-		data, err := json.Marshal(body)
-		if err != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
 			return nil, fmt.Errorf("failed to marshal request: %w", err)
 		}
-		reqBody = bytes.NewReader(data)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, c.config.Endpoint+path, reqBody)
+	policy := &c.config.Retry
+	attempts := policy.attempts(method, body)
+	for n := 1; ; n++ {
+		resp, retry, retryAfter, err := c.send(ctx, method, path, data)
+		if err == nil {
+			return resp, nil
+		}
+		if !retry || n == attempts {
+			return nil, attemptsError(n, err)
+		}
+
+		timer := time.NewTimer(policy.delay(n, retryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, attemptsError(n, err)
+		case <-timer.C:
+		}
+	}
+}
+
+// send performs one attempt. retry reports whether the failure is transient
+// (a network error or a retryable HTTP status); retryAfter is the server's
+// Retry-After hint.
+func (c *Client) send(ctx context.Context, method, path string, data []byte) (resp *Response, retry bool, retryAfter time.Duration, err error) {
+	reqCtx := ctx
+	if timeout := c.config.Retry.AttemptTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var reqBody io.Reader
+	if data != nil {
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(reqCtx, method, c.config.Endpoint+path, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, false, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// AccessKey auth: the server reads X-Access-Key. (Authorization: Bearer is
@@ -748,30 +827,47 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body any) (
 	req.Header.Set("X-Access-Key", c.config.AccessKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.http.Do(req)
+	// Network errors and attempt timeouts are transient; the caller's ctx
+	// ending is not.
+	httpResp, err := c.http.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, ctx.Err() == nil, 0, fmt.Errorf("request failed: %w", err)
 	}
-	defer resp.Body.Close()
+	defer httpResp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(httpResp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, ctx.Err() == nil, 0, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if c.config.Retry.retryStatus(httpResp.StatusCode) {
+		retry, retryAfter = true, parseRetryAfter(httpResp.Header)
 	}
 
 	var apiResp Response
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		if resp.StatusCode >= 400 {
-			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
+		if httpResp.StatusCode >= 400 {
+			return nil, retry, retryAfter, fmt.Errorf("HTTP %d: %s", httpResp.StatusCode, string(respBody))
 		}
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, false, 0, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	if apiResp.Code != 0 {
-		return nil, &APIError{Code: apiResp.Code, Message: apiResp.Message}
+		return nil, retry, retryAfter, &APIError{Code: apiResp.Code, Message: apiResp.Message}
+	}
+	if retry {
+		return nil, true, retryAfter, fmt.Errorf("HTTP %d", httpResp.StatusCode)
 	}
 
-	return &apiResp, nil
+	return &apiResp, false, 0, nil
+}
+
+// attemptsError wraps err in *RetryError when the request was sent more than once.
+func attemptsError(n int, err error) error {
+	if n == 1 {
+		return err
+	}
+	return &RetryError{Attempts: n, Err: err}
 }
 
 // decodeData unmarshals a single-object data payload into T.
@@ -838,6 +934,14 @@ func (c *Client) getOrder(ctx context.Context, orderUUID string) (*Order, error)
 	return decodeData[Order](resp.Data)
 }
 
+func (c *Client) refundOrder(ctx context.Context, orderUUID string, req *RefundRequest) (*Refund, error) {
+	resp, err := c.doRequest(ctx, "POST", "/api/orders/"+url.PathEscape(orderUUID)+"/refund", req)
+	if err != nil {
+		return nil, err
+	}
+	return decodeData[Refund](resp.Data)
+}
+
 // --- Client methods: plans ---
 
 func (c *Client) listPlans(ctx context.Context, includeInactive bool) ([]Plan, error) {
@@ -229,6 +229,59 @@ func TestGetOrder_Success(t *testing.T) {
 	}
 }
 
+func TestRefundOrder_Partial(t *testing.T) {
+	resetState()
+	defer mock(t, func(t *testing.T, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/orders/ord_1/refund" {
+			t.Errorf("got %s %s", r.Method, r.URL.Path)
+		}
+		body := decodeBody(t, r)
+		if body["amount"].(float64) != 300 || body["reason"] != "duplicate" || body["idempotencyKey"] != "refund-1" {
+			t.Errorf("unexpected body: %v", body)
+		}
+	}, testResponse{Data: map[string]any{
+		"uuid": "rf_1", "orderUuid": "ord_1", "amount": 300, "currency": "usd", "status": "succeeded", "reason": "duplicate",
+	}})()
+
+	refund, err := RefundOrder(t.Context(), "ord_1", &RefundRequest{Amount: 300, Reason: "duplicate", IdempotencyKey: "refund-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if refund.UUID != "rf_1" || refund.Amount != 300 || refund.Status != "succeeded" {
+		t.Errorf("unexpected refund: %+v", refund)
+	}
+}
+
+func TestRefundOrder_Full(t *testing.T) {
+	resetState()
+	defer mock(t, func(t *testing.T, r *http.Request) {
+		// A zero amount is omitted so the server refunds the remainder.
+		if _, ok := decodeBody(t, r)["amount"]; ok {
+			t.Error("full refund must not send an amount")
+		}
+	}, testResponse{Data: map[string]any{"uuid": "rf_2", "orderUuid": "ord_1", "amount": 999, "status": "succeeded"}})()
+
+	refund, err := RefundOrder(t.Context(), "ord_1", &RefundRequest{IdempotencyKey: "refund-2"})
+	if err != nil || refund.Amount != 999 {
+		t.Fatalf("unexpected result: %+v, %v", refund, err)
+	}
+}
+
+func TestRefundOrder_Errors(t *testing.T) {
+	if _, err := RefundOrder(t.Context(), "ord_1", nil); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("nil request: expected ErrInvalidInput, got %v", err)
+	}
+
+	resetState()
+	defer mock(t, nil, testResponse{Code: 400502, Message: "refund exceeds order amount"})()
+
+	_, err := RefundOrder(t.Context(), "ord_1", &RefundRequest{Amount: 5000, IdempotencyKey: "k"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 400502 {
+		t.Fatalf("expected *APIError 400502, got %v", err)
+	}
+}
+
 // --- Plans ---
 
 func TestListPlans_ActiveOnly(t *testing.T) {
@@ -0,0 +1,91 @@
+package nextpay
+
+// Plan sync keeps the dashboard's plans in step with plan definitions kept in
+// code: each definition is looked up by its Code, created when the server
+// answers 404 and upserted when any field differs. Plans missing from the
+// list are left alone; retire them with DeletePlan.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SyncResult reports what SyncPlans did, by plan code.
+type SyncResult struct {
+	Created   []string
+	Updated   []string
+	Unchanged []string
+}
+
+// SyncPlans upserts plans by Code and reports the codes created, updated and
+// unchanged. Every plan must have a Code. A failing plan does not stop the
+// others; the returned error joins the failures, and the result covers the
+// plans that succeeded.
+//
+// Example:
+//
+//	res, err := nextpay.SyncPlans(ctx, []nextpay.CreatePlanRequest{
+//	    {Code: "pro-monthly", Name: "Pro", Amount: 999, IntervalType: "month"},
+//	    {Code: "pro-yearly", Name: "Pro", Amount: 9900, IntervalType: "year", TrialDays: 7},
+//	})
+//	log.Printf("plans: %d created, %d updated", len(res.Created), len(res.Updated))
+func SyncPlans(ctx context.Context, plans []CreatePlanRequest) (*SyncResult, error) {
+	seen := make(map[string]bool, len(plans))
+	for _, p := range plans {
+		if p.Code == "" {
+			return nil, fmt.Errorf("%w: plan %q has no code", ErrInvalidInput, p.Name)
+		}
+		if seen[p.Code] {
+			return nil, fmt.Errorf("%w: duplicate plan code %q", ErrInvalidInput, p.Code)
+		}
+		seen[p.Code] = true
+	}
+	return do(ctx, func(ctx context.Context, c *Client) (*SyncResult, error) { return c.syncPlans(ctx, plans) })
+}
+
+// --- Client methods: plan sync ---
+
+func (c *Client) syncPlans(ctx context.Context, plans []CreatePlanRequest) (*SyncResult, error) {
+	result := &SyncResult{}
+	var errs []error
+	for i := range plans {
+		want := &plans[i]
+		current, err := c.getPlan(ctx, want.Code)
+		var apiErr *APIError
+		switch {
+		case errors.As(err, &apiErr) && apiErr.Code == 404:
+			if _, err := c.createPlan(ctx, want); err != nil {
+				errs = append(errs, fmt.Errorf("create plan %s: %w", want.Code, err))
+				continue
+			}
+			result.Created = append(result.Created, want.Code)
+		case err != nil:
+			errs = append(errs, fmt.Errorf("get plan %s: %w", want.Code, err))
+		case planMatches(current, want):
+			result.Unchanged = append(result.Unchanged, want.Code)
+		default:
+			// POST with a Code is an upsert, and unlike PUT it also sets TrialDays.
+			if _, err := c.createPlan(ctx, want); err != nil {
+				errs = append(errs, fmt.Errorf("update plan %s: %w", want.Code, err))
+				continue
+			}
+			result.Updated = append(result.Updated, want.Code)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// planMatches reports whether p already has the fields of want.
+func planMatches(p *Plan, want *CreatePlanRequest) bool {
+	currency := want.Currency
+	if currency == "" {
+		currency = "usd"
+	}
+	return p.Name == want.Name &&
+		p.Description == want.Description &&
+		p.Amount == want.Amount &&
+		p.Currency == currency &&
+		p.IntervalType == want.IntervalType &&
+		p.TrialDays == want.TrialDays
+}
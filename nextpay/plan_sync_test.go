@@ -0,0 +1,122 @@
+package nextpay
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// planServer serves GET/POST /api/plans from an in-memory store keyed by
+// code, answering unknown codes with 404. Codes in failing answer 500.
+func planServer(t *testing.T, plans map[string]Plan, failing ...string) *[]string {
+	t.Helper()
+	var mu sync.Mutex
+	var posted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		code := strings.TrimPrefix(r.URL.Path, "/api/plans/")
+		for _, f := range failing {
+			if code == f {
+				_ = json.NewEncoder(w).Encode(testResponse{Code: 500, Message: "boom"})
+				return
+			}
+		}
+
+		switch {
+		case r.Method == "GET" && code != r.URL.Path:
+			p, ok := plans[code]
+			if !ok {
+				_ = json.NewEncoder(w).Encode(testResponse{Code: 404, Message: "plan not found"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(testResponse{Data: p})
+		case r.Method == "POST" && r.URL.Path == "/api/plans":
+			var req CreatePlanRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("decode: %v", err)
+			}
+			posted = append(posted, req.Code)
+			p := Plan{Code: req.Code, Name: req.Name, Description: req.Description, Amount: req.Amount,
+				Currency: "usd", IntervalType: req.IntervalType, TrialDays: req.TrialDays, IsActive: true}
+			plans[req.Code] = p
+			_ = json.NewEncoder(w).Encode(testResponse{Data: p})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	SetConfig(&Config{AccessKey: "test-key", Endpoint: server.URL})
+	return &posted
+}
+
+func TestSyncPlans(t *testing.T) {
+	resetState()
+	plans := map[string]Plan{
+		"pro-monthly": {Code: "pro-monthly", Name: "Pro", Amount: 999, Currency: "usd", IntervalType: "month", IsActive: true},
+		"pro-yearly":  {Code: "pro-yearly", Name: "Pro", Amount: 9900, Currency: "usd", IntervalType: "year", IsActive: true},
+	}
+	posted := planServer(t, plans)
+
+	res, err := SyncPlans(t.Context(), []CreatePlanRequest{
+		{Code: "pro-monthly", Name: "Pro", Amount: 999, IntervalType: "month"},              // unchanged
+		{Code: "pro-yearly", Name: "Pro", Amount: 9900, IntervalType: "year", TrialDays: 7}, // trial added
+		{Code: "team-monthly", Name: "Team", Amount: 2999, IntervalType: "month"},           // 404 -> create
+	})
+	if err != nil {
+		t.Fatalf("SyncPlans: %v", err)
+	}
+	if strings.Join(res.Unchanged, ",") != "pro-monthly" || strings.Join(res.Updated, ",") != "pro-yearly" ||
+		strings.Join(res.Created, ",") != "team-monthly" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if strings.Join(*posted, ",") != "pro-yearly,team-monthly" {
+		t.Errorf("unchanged plans must not be written, posted %v", *posted)
+	}
+	if plans["pro-yearly"].TrialDays != 7 || plans["team-monthly"].Amount != 2999 {
+		t.Errorf("store not updated: %+v", plans)
+	}
+
+	// A second run finds everything in place
+	res, err = SyncPlans(t.Context(), []CreatePlanRequest{
+		{Code: "pro-yearly", Name: "Pro", Amount: 9900, Currency: "usd", IntervalType: "year", TrialDays: 7},
+		{Code: "team-monthly", Name: "Team", Amount: 2999, IntervalType: "month"},
+	})
+	if err != nil || len(res.Unchanged) != 2 || len(res.Created)+len(res.Updated) != 0 {
+		t.Errorf("expected no changes, got %+v, %v", res, err)
+	}
+}
+
+func TestSyncPlans_PartialFailure(t *testing.T) {
+	resetState()
+	planServer(t, map[string]Plan{}, "broken")
+
+	res, err := SyncPlans(t.Context(), []CreatePlanRequest{
+		{Code: "broken", Name: "Broken", Amount: 100, IntervalType: "month"},
+		{Code: "basic", Name: "Basic", Amount: 499, IntervalType: "month"},
+	})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 500 || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("expected error for the broken plan, got %v", err)
+	}
+	if strings.Join(res.Created, ",") != "basic" {
+		t.Errorf("other plans should still sync: %+v", res)
+	}
+}
+
+func TestSyncPlans_InvalidInput(t *testing.T) {
+	resetState()
+	for name, plans := range map[string][]CreatePlanRequest{
+		"no code":   {{Name: "Pro", Amount: 999, IntervalType: "month"}},
+		"duplicate": {{Code: "pro", Name: "Pro"}, {Code: "pro", Name: "Pro 2"}},
+	} {
+		if _, err := SyncPlans(t.Context(), plans); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: expected ErrInvalidInput, got %v", name, err)
+		}
+	}
+}
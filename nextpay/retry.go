@@ -0,0 +1,103 @@
+package nextpay
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Retry defaults applied by loadConfigFromViper when nextpay.retry is unset.
+const (
+	defaultMaxAttempts = 3
+	defaultBackoff     = 200 * time.Millisecond
+)
+
+// defaultRetryStatusCodes are the HTTP statuses retried when
+// RetryPolicy.StatusCodes is empty.
+var defaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy controls automatic retries of transient failures: network errors
+// and the retryable HTTP statuses. GETs are always retryable; POST/PUT/DELETE
+// are retried only when the request carries an IdempotencyKey, so a retry can
+// never charge or grant twice.
+type RetryPolicy struct {
+	MaxAttempts    int           `yaml:"max_attempts"`    // total attempts including the first; <= 1 disables retries
+	Backoff        time.Duration `yaml:"backoff"`         // delay before the 2nd attempt, doubled for each further one
+	StatusCodes    []int         `yaml:"status_codes"`    // retryable HTTP statuses; default 429, 502, 503, 504
+	AttemptTimeout time.Duration `yaml:"attempt_timeout"` // per-attempt limit within the ctx deadline; 0 = none
+}
+
+// RetryError wraps the final error of a request that was attempted more than
+// once. errors.As still finds the underlying *APIError.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%v (after %d attempts)", e.Err, e.Attempts)
+}
+
+func (e *RetryError) Unwrap() error { return e.Err }
+
+// idempotent is implemented by requests carrying an IdempotencyKey.
+type idempotent interface {
+	idempotencyKey() string
+}
+
+func (r *GrantSubscriptionRequest) idempotencyKey() string { return r.IdempotencyKey }
+func (r *ChargeRequest) idempotencyKey() string            { return r.IdempotencyKey }
+func (r *WalletDepositRequest) idempotencyKey() string     { return r.IdempotencyKey }
+func (r *WalletDeductRequest) idempotencyKey() string      { return r.IdempotencyKey }
+func (r *RefundRequest) idempotencyKey() string            { return r.IdempotencyKey }
+func (r *PendingChargeRequest) idempotencyKey() string     { return r.IdempotencyKey }
+
+// attempts returns how many times a request may be sent under p.
+func (p *RetryPolicy) attempts(method string, body any) int {
+	if p.MaxAttempts <= 1 {
+		return 1
+	}
+	if method == http.MethodGet {
+		return p.MaxAttempts
+	}
+	if r, ok := body.(idempotent); ok && r.idempotencyKey() != "" {
+		return p.MaxAttempts
+	}
+	return 1
+}
+
+// retryStatus reports whether an HTTP status is retryable under p.
+func (p *RetryPolicy) retryStatus(status int) bool {
+	codes := p.StatusCodes
+	if len(codes) == 0 {
+		codes = defaultRetryStatusCodes
+	}
+	return slices.Contains(codes, status)
+}
+
+// delay returns the wait before attempt n+1. A Retry-After hint from the
+// server takes precedence when it is longer.
+func (p *RetryPolicy) delay(n int, retryAfter time.Duration) time.Duration {
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+	d := backoff << (n - 1)
+	return max(d, retryAfter)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds.
+func parseRetryAfter(h http.Header) time.Duration {
+	secs, err := strconv.Atoi(h.Get("Retry-After"))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
@@ -0,0 +1,151 @@
+package nextpay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// flaky serves status for the first failures requests, then a success
+// envelope. It returns the request counter.
+func flaky(t *testing.T, failures int32, status int, policy RetryPolicy) *atomic.Int32 {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(testResponse{Code: status, Message: http.StatusText(status)})
+			return
+		}
+		json.NewEncoder(w).Encode(testResponse{Data: map[string]any{"uuid": "x", "chargeId": "ch_1"}})
+	}))
+	t.Cleanup(server.Close)
+	resetState()
+	SetConfig(&Config{AccessKey: "test-key", Endpoint: server.URL, Retry: policy})
+	return &calls
+}
+
+var fastRetry = RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+
+func TestRetry_GetRecovers(t *testing.T) {
+	calls := flaky(t, 2, http.StatusServiceUnavailable, fastRetry)
+
+	if _, err := GetOrder(context.Background(), "x"); err != nil {
+		t.Fatalf("GetOrder should succeed after retries: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d, want 3", calls.Load())
+	}
+}
+
+func TestRetry_Exhausted(t *testing.T) {
+	calls := flaky(t, 10, http.StatusBadGateway, fastRetry)
+
+	_, err := GetOrder(context.Background(), "x")
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 3 {
+		t.Fatalf("expected RetryError after 3 attempts, got %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadGateway {
+		t.Errorf("APIError should still be reachable, got %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d, want 3", calls.Load())
+	}
+}
+
+func TestRetry_PostNeedsIdempotencyKey(t *testing.T) {
+	calls := flaky(t, 1, http.StatusTooManyRequests, fastRetry)
+	if _, err := CreateOrder(context.Background(), &OrderRequest{UserID: "u", Amount: 1}); err == nil {
+		t.Fatal("POST without an idempotency key must not be retried")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+
+	calls = flaky(t, 1, http.StatusTooManyRequests, fastRetry)
+	if _, err := ChargeContract(context.Background(), "rc_1", &ChargeRequest{Amount: 1, IdempotencyKey: "k"}); err != nil {
+		t.Fatalf("POST with an idempotency key should be retried: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestRetry_NonRetryableStatus(t *testing.T) {
+	calls := flaky(t, 1, http.StatusBadRequest, fastRetry)
+	_, err := GetOrder(context.Background(), "x")
+	var retryErr *RetryError
+	if err == nil || errors.As(err, &retryErr) || calls.Load() != 1 {
+		t.Errorf("400 must fail on the first attempt, got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestRetry_ContextDeadline(t *testing.T) {
+	calls := flaky(t, 10, http.StatusServiceUnavailable, RetryPolicy{MaxAttempts: 5, Backoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := GetOrder(ctx, "x"); err == nil {
+		t.Fatal("expected error")
+	}
+	if time.Since(start) > time.Second || calls.Load() != 1 {
+		t.Errorf("ctx deadline should cut the backoff short, took %v with %d calls", time.Since(start), calls.Load())
+	}
+}
+
+func TestRetry_AttemptTimeout(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		json.NewEncoder(w).Encode(testResponse{Data: map[string]any{"uuid": "x"}})
+	}))
+	defer server.Close()
+	resetState()
+	SetConfig(&Config{AccessKey: "test-key", Endpoint: server.URL,
+		Retry: RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond, AttemptTimeout: 50 * time.Millisecond}})
+
+	if _, err := GetOrder(context.Background(), "x"); err != nil {
+		t.Fatalf("a slow attempt should be retried: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestRetry_ViperDefaults(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("nextpay.access_key", "k")
+
+	cfg, err := loadConfigFromViper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Retry.MaxAttempts != defaultMaxAttempts {
+		t.Errorf("MaxAttempts = %d, want %d", cfg.Retry.MaxAttempts, defaultMaxAttempts)
+	}
+
+	viper.Set("nextpay.retry.max_attempts", 0)
+	viper.Set("nextpay.retry.status_codes", []int{500})
+	cfg, _ = loadConfigFromViper()
+	if cfg.Retry.MaxAttempts != 0 || !cfg.Retry.retryStatus(500) || cfg.Retry.retryStatus(503) {
+		t.Errorf("explicit retry config not honored: %+v", cfg.Retry)
+	}
+}
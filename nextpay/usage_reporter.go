@@ -0,0 +1,242 @@
+package nextpay
+
+// Usage reporting batches post-paid usage locally instead of creating a
+// pending charge per API request. Records accumulate in the open window, summed
+// per description; a flush seals the window and sends one CreatePendingCharge
+// per description.
+//
+// Each charge carries an IdempotencyKey derived from the subscription, the
+// window start and the description, so retrying a sealed window (after a
+// timeout, or on the next tick after a failure) can never bill twice. Windows
+// that keep failing stay buffered until they are older than the max retention,
+// and are then dropped and reported to the failure hook.
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// defaultUsageRetention is how long a window that fails to flush is retried
+// before it is dropped.
+const defaultUsageRetention = 24 * time.Hour
+
+// defaultUsageFlushInterval replaces a non-positive flush interval.
+const defaultUsageFlushInterval = time.Minute
+
+// UsageWindow is one sealed window of aggregated usage.
+type UsageWindow struct {
+	Start   time.Time
+	Amounts map[string]uint64 // cents, by description
+}
+
+// UsageReporter buffers usage for one subscription and flushes it as pending
+// charges on an interval, when maxBuffer records are buffered, and on Close.
+type UsageReporter struct {
+	subscriptionID string
+	maxBuffer      int
+	now            func() time.Time
+
+	mu        sync.Mutex
+	open      *usageWindow
+	records   int // Record calls in the open window
+	sealed    []*usageWindow
+	retention time.Duration
+	onSuccess func(UsageWindow)
+	onFailure func(w UsageWindow, err error, dropped bool)
+	closed    bool
+
+	flushMu sync.Mutex // serializes flushes
+	kick    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+type usageWindow struct {
+	start   time.Time
+	amounts map[string]uint64
+	sent    map[string]bool // descriptions already charged
+}
+
+// NewUsageReporter starts a reporter for subscriptionID that flushes every
+// flushInterval (1m when <= 0), or as soon as maxBuffer records are buffered
+// (0 = no limit). Call Close to flush the remainder and stop it.
+//
+// Example:
+//
+//	usage := nextpay.NewUsageReporter("sub_uuid", time.Minute, 1000)
+//	defer usage.Close(ctx)
+//	usage.Record(2, "API calls")
+func NewUsageReporter(subscriptionID string, flushInterval time.Duration, maxBuffer int) *UsageReporter {
+	r := &UsageReporter{
+		subscriptionID: subscriptionID,
+		maxBuffer:      maxBuffer,
+		now:            time.Now,
+		retention:      defaultUsageRetention,
+		kick:           make(chan struct{}, 1),
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	if flushInterval <= 0 {
+		flushInterval = defaultUsageFlushInterval
+	}
+	go r.run(flushInterval)
+	return r
+}
+
+// SetMaxRetention sets how long a window that fails to flush is kept for
+// retry before it is dropped (default 24h).
+func (r *UsageReporter) SetMaxRetention(d time.Duration) {
+	r.mu.Lock()
+	r.retention = d
+	r.mu.Unlock()
+}
+
+// OnFlushSuccess sets a hook called after every charge of a window is created.
+func (r *UsageReporter) OnFlushSuccess(f func(UsageWindow)) {
+	r.mu.Lock()
+	r.onSuccess = f
+	r.mu.Unlock()
+}
+
+// OnFlushFailure sets a hook called when a window fails to flush. dropped is
+// true when the window outlived the max retention and will not be retried.
+func (r *UsageReporter) OnFlushFailure(f func(w UsageWindow, err error, dropped bool)) {
+	r.mu.Lock()
+	r.onFailure = f
+	r.mu.Unlock()
+}
+
+// Record adds amount cents of usage under description. Non-positive amounts
+// and records after Close are ignored.
+func (r *UsageReporter) Record(amount int, description string) {
+	if amount <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	if r.open == nil {
+		r.open = &usageWindow{start: r.now(), amounts: make(map[string]uint64)}
+	}
+	r.open.amounts[description] += uint64(amount)
+	r.records++
+	if r.maxBuffer > 0 && r.records >= r.maxBuffer {
+		select {
+		case r.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Close stops the reporter and flushes everything buffered within ctx. Usage
+// that still fails to flush is lost; the returned error reports it.
+func (r *UsageReporter) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.mu.Unlock()
+
+	close(r.stop)
+	<-r.done
+	return r.flush(ctx)
+}
+
+func (r *UsageReporter) run(interval time.Duration) {
+	defer close(r.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		case <-r.kick:
+		}
+		_ = r.flush(context.Background())
+	}
+}
+
+// flush seals the open window and sends every sealed window, oldest first.
+func (r *UsageReporter) flush(ctx context.Context) error {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	r.mu.Lock()
+	if r.open != nil {
+		r.open.sent = make(map[string]bool)
+		r.sealed = append(r.sealed, r.open)
+		r.open, r.records = nil, 0
+	}
+	windows := slices.Clone(r.sealed)
+	r.mu.Unlock()
+
+	var errs []error
+	for _, w := range windows {
+		err := r.send(ctx, w)
+
+		r.mu.Lock()
+		dropped := err != nil && r.now().Sub(w.start) >= r.retention
+		if err == nil || dropped {
+			r.sealed = slices.DeleteFunc(r.sealed, func(o *usageWindow) bool { return o == w })
+		}
+		onSuccess, onFailure := r.onSuccess, r.onFailure
+		r.mu.Unlock()
+
+		if err == nil {
+			if onSuccess != nil {
+				onSuccess(w.public())
+			}
+			continue
+		}
+		errs = append(errs, err)
+		if onFailure != nil {
+			onFailure(w.public(), err, dropped)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// send creates the charges of w not sent yet. Only flush touches w.sent, under
+// flushMu.
+func (r *UsageReporter) send(ctx context.Context, w *usageWindow) error {
+	var errs []error
+	for _, desc := range slices.Sorted(maps.Keys(w.amounts)) {
+		if w.sent[desc] {
+			continue
+		}
+		_, err := CreatePendingCharge(ctx, &PendingChargeRequest{
+			SubscriptionID: r.subscriptionID,
+			Amount:         w.amounts[desc],
+			Description:    desc,
+			IdempotencyKey: r.idempotencyKey(w, desc),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("usage %q: %w", desc, err))
+			continue
+		}
+		w.sent[desc] = true
+	}
+	return errors.Join(errs...)
+}
+
+// idempotencyKey is stable for a window and description across retries.
+func (r *UsageReporter) idempotencyKey(w *usageWindow, desc string) string {
+	sum := sha256.Sum256([]byte(desc))
+	return fmt.Sprintf("usage-%s-%d-%s", r.subscriptionID, w.start.UnixNano(), hex.EncodeToString(sum[:8]))
+}
+
+func (w *usageWindow) public() UsageWindow {
+	return UsageWindow{Start: w.start, Amounts: maps.Clone(w.amounts)}
+}
@@ -0,0 +1,174 @@
+package nextpay
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chargeServer records POST /api/billing/pending-charges bodies and answers
+// 500 while *failing is true.
+func chargeServer(t *testing.T, failing *bool) (*[]PendingChargeRequest, *sync.Mutex) {
+	t.Helper()
+	var mu sync.Mutex
+	var got []PendingChargeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != "POST" || r.URL.Path != "/api/billing/pending-charges" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var req PendingChargeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		got = append(got, req)
+		if failing != nil && *failing {
+			_ = json.NewEncoder(w).Encode(testResponse{Code: 400, Message: "gateway down"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(testResponse{Data: map[string]any{"chargeId": "chg_1", "amount": req.Amount}})
+	}))
+	t.Cleanup(server.Close)
+	SetConfig(&Config{AccessKey: "test-key", Endpoint: server.URL})
+	return &got, &mu
+}
+
+func TestUsageReporter_AggregatesOnClose(t *testing.T) {
+	resetState()
+	got, _ := chargeServer(t, nil)
+
+	u := NewUsageReporter("sub_1", time.Hour, 0)
+	var flushed []UsageWindow
+	u.OnFlushSuccess(func(w UsageWindow) { flushed = append(flushed, w) })
+	u.Record(2, "API calls")
+	u.Record(3, "API calls")
+	u.Record(10, "Storage")
+	u.Record(0, "ignored")
+
+	if err := u.Close(t.Context()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if len(*got) != 2 {
+		t.Fatalf("expected one charge per description, got %+v", *got)
+	}
+	calls, storage := (*got)[0], (*got)[1]
+	if calls.Description != "API calls" || calls.Amount != 5 || storage.Amount != 10 || calls.SubscriptionID != "sub_1" {
+		t.Errorf("unexpected charges: %+v", *got)
+	}
+	if calls.IdempotencyKey == "" || calls.IdempotencyKey == storage.IdempotencyKey {
+		t.Errorf("each charge needs its own idempotency key: %+v", *got)
+	}
+	if len(flushed) != 1 || flushed[0].Amounts["API calls"] != 5 {
+		t.Errorf("unexpected success hook calls: %+v", flushed)
+	}
+
+	u.Record(1, "API calls") // after Close
+	if err := u.Close(t.Context()); err != nil || len(*got) != 2 {
+		t.Errorf("closed reporter should not send: %v, %+v", err, *got)
+	}
+}
+
+func TestUsageReporter_NonPositiveInterval(t *testing.T) {
+	resetState()
+	got, _ := chargeServer(t, nil)
+
+	u := NewUsageReporter("sub_1", 0, 0)
+	u.Record(1, "API calls")
+	if err := u.Close(t.Context()); err != nil || len(*got) != 1 {
+		t.Errorf("reporter with zero interval: %v, %+v", err, *got)
+	}
+}
+
+func TestUsageReporter_RetryKeepsKey(t *testing.T) {
+	resetState()
+	failing := true
+	got, mu := chargeServer(t, &failing)
+
+	u := NewUsageReporter("sub_1", time.Hour, 0)
+	var failures int
+	u.OnFlushFailure(func(w UsageWindow, err error, dropped bool) {
+		failures++
+		if dropped {
+			t.Error("window should be kept within the retention")
+		}
+	})
+	u.Record(7, "API calls")
+	var apiErr *APIError
+	if err := u.flush(t.Context()); !errors.As(err, &apiErr) {
+		t.Fatalf("expected the API error, got %v", err)
+	}
+
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+	u.Record(1, "API calls") // lands in a new window
+	if err := u.Close(t.Context()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if failures != 1 || len(*got) != 3 {
+		t.Fatalf("expected a failed attempt and two charges, got %d failures, %+v", failures, *got)
+	}
+	if (*got)[0].IdempotencyKey != (*got)[1].IdempotencyKey || (*got)[1].Amount != 7 {
+		t.Errorf("retried window must reuse its key: %+v", *got)
+	}
+	if (*got)[2].IdempotencyKey == (*got)[1].IdempotencyKey || (*got)[2].Amount != 1 {
+		t.Errorf("new window needs a new key: %+v", *got)
+	}
+}
+
+func TestUsageReporter_DropsAfterRetention(t *testing.T) {
+	resetState()
+	failing := true
+	chargeServer(t, &failing)
+
+	now := time.Unix(1000, 0)
+	u := NewUsageReporter("sub_1", time.Hour, 0)
+	u.now = func() time.Time { return now }
+	u.SetMaxRetention(time.Minute)
+	var dropped []bool
+	u.OnFlushFailure(func(w UsageWindow, err error, d bool) { dropped = append(dropped, d) })
+
+	u.Record(5, "API calls")
+	_ = u.flush(t.Context())
+	now = now.Add(time.Minute)
+	_ = u.flush(t.Context())
+	if len(dropped) != 2 || dropped[0] || !dropped[1] {
+		t.Fatalf("expected kept then dropped, got %v", dropped)
+	}
+	if err := u.Close(t.Context()); err != nil {
+		t.Errorf("dropped window should not be retried on Close: %v", err)
+	}
+}
+
+func TestUsageReporter_FlushOnOverflow(t *testing.T) {
+	resetState()
+	got, mu := chargeServer(t, nil)
+
+	u := NewUsageReporter("sub_1", time.Hour, 3)
+	defer u.Close(t.Context())
+	for range 3 {
+		u.Record(1, "API calls")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(*got)
+		mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("a full buffer should flush before the interval")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if (*got)[0].Amount != 3 {
+		t.Errorf("unexpected charge: %+v", (*got)[0])
+	}
+}
@@ -4,24 +4,30 @@ package nextpay
 // WebhookURL. This file is the App-side receiving contract — signature
 // verification, the event envelope, and typed accessors for each event's data.
 //
-// It is framework-agnostic on purpose: the App reads the raw request body and
-// the X-NextPay-Signature header from whatever HTTP stack it uses (gin, echo,
-// net/http, ...) and hands both to ParseWebhook. See the README for the ~8-line
-// gin binding.
+// It is framework-agnostic on purpose: WebhookHandler is a plain net/http
+// handler (mount it in gin with gin.WrapH), and ParseWebhook takes the raw
+// request body and the X-NextPay-Signature header from any HTTP stack.
 //
 // Delivery is at-least-once: NextPay retries up to 15 times plus a sweeper
 // cron, so the SAME event may arrive more than once. Handlers MUST be
 // idempotent — dedupe on WebhookEvent.ID (an "evt_<uuid>" string).
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/spf13/viper"
 )
 
 // WebhookEventType identifies an inbound webhook event.
@@ -53,6 +59,10 @@ const (
 	// Wallet events carry WebhookWalletData.
 	WebhookWalletDeposited WebhookEventType = "wallet.deposited"
 	WebhookWalletDeducted  WebhookEventType = "wallet.deducted"
+
+	// Dispute events carry WebhookDisputeData.
+	WebhookDisputeCreated WebhookEventType = "dispute.created"
+	WebhookDisputeClosed  WebhookEventType = "dispute.closed"
 )
 
 // WebhookEvent is the envelope of every inbound webhook. Data is left as raw
@@ -125,17 +135,49 @@ type WebhookWalletData struct {
 	RelatedOrderID string `json:"relatedOrderId,omitempty"`
 }
 
-// ErrInvalidWebhookSignature is returned when the X-NextPay-Signature header is
-// missing, malformed, or does not match the payload under the app secret.
-var ErrInvalidWebhookSignature = errors.New("nextpay: invalid webhook signature")
+// WebhookDisputeData is the data for dispute.* events. On dispute.closed,
+// Status is the outcome: won, lost or accepted.
+type WebhookDisputeData struct {
+	DisputeID     string `json:"disputeId"`
+	OrderID       string `json:"orderId"`
+	UserID        string `json:"userId"`
+	Amount        uint64 `json:"amount"` // disputed amount, cents
+	Currency      string `json:"currency"`
+	ReasonCode    string `json:"reasonCode"`
+	Status        string `json:"status"`
+	EvidenceDueBy int64  `json:"evidenceDueBy,omitempty"` // unix seconds
+	ClosedAt      int64  `json:"closedAt,omitempty"`      // unix seconds, dispute.closed only
+}
+
+// EvidenceDeadline returns EvidenceDueBy as a time.Time, or the zero time when
+// no deadline is set.
+func (d *WebhookDisputeData) EvidenceDeadline() time.Time {
+	return unixTime(d.EvidenceDueBy)
+}
+
+// WebhookSignatureHeader is the request header carrying the webhook signature.
+const WebhookSignatureHeader = "X-NextPay-Signature"
+
+var (
+	// ErrInvalidWebhookSignature is returned when the X-NextPay-Signature header is
+	// missing, malformed, or does not match the payload under the app secret.
+	ErrInvalidWebhookSignature = errors.New("nextpay: invalid webhook signature")
+
+	// ErrStaleWebhook is returned when a correctly signed webhook was signed
+	// outside the webhook tolerance of now.
+	ErrStaleWebhook = errors.New("nextpay: webhook timestamp outside tolerance")
+)
 
 // VerifyWebhookSignature checks the X-NextPay-Signature header against rawBody
 // using the app's webhook secret. The header format is "t=<unix>,v1=<hex>" and
-// the signature is HMAC-SHA256(secret, "<t>.<rawBody>").
+// the signature is HMAC-SHA256(secret, "<t>.<rawBody>"); the comparison is
+// constant-time. A valid signature whose t is further than
+// nextpay.webhook_tolerance (default 5m) from now returns ErrStaleWebhook, so a
+// captured request cannot be replayed later; a tolerance <= 0 disables the
+// check.
 //
 // rawBody must be the exact bytes received — verify BEFORE any JSON decode or
-// re-encode, or the HMAC will not match. Replay protection is the caller's job
-// via dedupe on WebhookEvent.ID; there is deliberately no timestamp tolerance.
+// re-encode, or the HMAC will not match.
 func VerifyWebhookSignature(rawBody []byte, signatureHeader, secret string) error {
 	t, v1, ok := parseSignatureHeader(signatureHeader)
 	if !ok {
@@ -149,9 +191,30 @@ func VerifyWebhookSignature(rawBody []byte, signatureHeader, secret string) erro
 	if !hmac.Equal([]byte(expected), []byte(v1)) {
 		return ErrInvalidWebhookSignature
 	}
+
+	if tolerance := webhookTolerance(); tolerance > 0 {
+		signedAt, err := strconv.ParseInt(t, 10, 64)
+		if err != nil {
+			return ErrInvalidWebhookSignature
+		}
+		if age := time.Since(time.Unix(signedAt, 0)); age > tolerance || age < -tolerance {
+			return ErrStaleWebhook
+		}
+	}
 	return nil
 }
 
+// DefaultWebhookTolerance is how far the signature timestamp may be from now
+// when nextpay.webhook_tolerance is not set.
+const DefaultWebhookTolerance = 5 * time.Minute
+
+func webhookTolerance() time.Duration {
+	if !viper.IsSet("nextpay.webhook_tolerance") {
+		return DefaultWebhookTolerance
+	}
+	return viper.GetDuration("nextpay.webhook_tolerance")
+}
+
 // ParseWebhook verifies the signature and decodes the envelope in one step. It
 // is the single entry point for an inbound webhook handler: on success the
 // returned event is authentic and ready to dispatch on Type.
@@ -166,6 +229,45 @@ func ParseWebhook(rawBody []byte, signatureHeader, secret string) (*WebhookEvent
 	return &evt, nil
 }
 
+// maxWebhookBody caps the body WebhookHandler reads; events are a few KB.
+const maxWebhookBody = 1 << 20
+
+// WebhookHandler returns an http.Handler that verifies and decodes each
+// inbound webhook, then calls fn with the request context. Mount it with
+// gin.WrapH under gin.
+//
+// Responses follow NextPay's retry semantics: a bad signature, stale
+// timestamp or malformed body gets 400 (not retried), an error from fn gets
+// 500 (retried later), and success gets 200. fn must be idempotent — dedupe on
+// WebhookEvent.ID.
+func WebhookHandler(secret string, fn func(context.Context, *WebhookEvent) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+		if err != nil {
+			writeWebhookResponse(w, http.StatusBadRequest, map[string]any{"error": "read body"})
+			return
+		}
+
+		evt, err := ParseWebhook(body, r.Header.Get(WebhookSignatureHeader), secret)
+		if err != nil {
+			writeWebhookResponse(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+
+		if err := fn(r.Context(), evt); err != nil {
+			writeWebhookResponse(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeWebhookResponse(w, http.StatusOK, map[string]any{"received": true})
+	})
+}
+
+func writeWebhookResponse(w http.ResponseWriter, status int, body map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
 // OrderData decodes the payload of an order.* event.
 func (e *WebhookEvent) OrderData() (*WebhookOrderData, error) {
 	return decodeWebhookData[WebhookOrderData](e, WebhookOrderPaid, WebhookOrderExpired, WebhookOrderFailed)
@@ -194,6 +296,11 @@ func (e *WebhookEvent) WalletData() (*WebhookWalletData, error) {
 	return decodeWebhookData[WebhookWalletData](e, WebhookWalletDeposited, WebhookWalletDeducted)
 }
 
+// DisputeData decodes the payload of a dispute.* event.
+func (e *WebhookEvent) DisputeData() (*WebhookDisputeData, error) {
+	return decodeWebhookData[WebhookDisputeData](e, WebhookDisputeCreated, WebhookDisputeClosed)
+}
+
 // decodeWebhookData decodes e.Data into T after confirming e.Type is one of the
 // event types that carry T, so a caller that reaches for the wrong accessor
 // gets a clear error instead of a silently zero-valued struct.
@@ -1,12 +1,19 @@
 package nextpay
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/spf13/viper"
 )
 
 // signBody reproduces the server's signing scheme so the tests exercise the
@@ -22,7 +29,7 @@ func signBody(t int64, body []byte, secret string) string {
 func TestVerifyWebhookSignature_Valid(t *testing.T) {
 	body := []byte(`{"id":"evt_1","type":"order.paid","timestamp":100,"data":{}}`)
 	secret := "whsec_test"
-	header := signBody(1700000000, body, secret)
+	header := signBody(time.Now().Unix(), body, secret)
 
 	if err := VerifyWebhookSignature(body, header, secret); err != nil {
 		t.Fatalf("expected valid signature, got %v", err)
@@ -32,7 +39,7 @@ func TestVerifyWebhookSignature_Valid(t *testing.T) {
 func TestVerifyWebhookSignature_TamperedBody(t *testing.T) {
 	body := []byte(`{"id":"evt_1","type":"order.paid","timestamp":100,"data":{}}`)
 	secret := "whsec_test"
-	header := signBody(1700000000, body, secret)
+	header := signBody(time.Now().Unix(), body, secret)
 
 	tampered := []byte(`{"id":"evt_1","type":"order.paid","timestamp":100,"data":{"x":1}}`)
 	if err := VerifyWebhookSignature(tampered, header, secret); !errors.Is(err, ErrInvalidWebhookSignature) {
@@ -42,7 +49,7 @@ func TestVerifyWebhookSignature_TamperedBody(t *testing.T) {
 
 func TestVerifyWebhookSignature_WrongSecret(t *testing.T) {
 	body := []byte(`{"id":"evt_1"}`)
-	header := signBody(1700000000, body, "whsec_real")
+	header := signBody(time.Now().Unix(), body, "whsec_real")
 
 	if err := VerifyWebhookSignature(body, header, "whsec_wrong"); !errors.Is(err, ErrInvalidWebhookSignature) {
 		t.Fatalf("expected ErrInvalidWebhookSignature, got %v", err)
@@ -62,10 +69,11 @@ func TestVerifyWebhookSignature_HeaderOrderIndependent(t *testing.T) {
 	body := []byte(`{"id":"evt_1"}`)
 	secret := "whsec_test"
 	// server emits t first; prove verification does not depend on ordering.
+	now := time.Now().Unix()
 	mac := hmac.New(sha256.New, []byte(secret))
-	fmt.Fprintf(mac, "%d.%s", int64(42), string(body))
+	fmt.Fprintf(mac, "%d.%s", now, string(body))
 	sig := hex.EncodeToString(mac.Sum(nil))
-	header := fmt.Sprintf("v1=%s,t=%d", sig, 42)
+	header := fmt.Sprintf("v1=%s,t=%d", sig, now)
 
 	if err := VerifyWebhookSignature(body, header, secret); err != nil {
 		t.Fatalf("expected valid signature regardless of field order, got %v", err)
@@ -91,7 +99,7 @@ func TestParseWebhook_OrderPaid(t *testing.T) {
 			"paidAt": 1700000001
 		}
 	}`)
-	header := signBody(1700000000, body, secret)
+	header := signBody(time.Now().Unix(), body, secret)
 
 	evt, err := ParseWebhook(body, header, secret)
 	if err != nil {
@@ -185,3 +193,102 @@ func TestWebhookEvent_WrongAccessorRejected(t *testing.T) {
 		t.Fatal("expected error when decoding order event as subscription data")
 	}
 }
+
+func TestWebhookEvent_DisputeData(t *testing.T) {
+	evt := &WebhookEvent{
+		Type: WebhookDisputeCreated,
+		Data: []byte(`{"disputeId":"dp_1","orderId":"ord_1","userId":"user123","amount":1999,"currency":"usd","reasonCode":"fraudulent","status":"needs_response","evidenceDueBy":1700086400}`),
+	}
+	d, err := evt.DisputeData()
+	if err != nil {
+		t.Fatalf("DisputeData: %v", err)
+	}
+	if d.DisputeID != "dp_1" || d.OrderID != "ord_1" || d.Amount != 1999 || d.ReasonCode != "fraudulent" {
+		t.Errorf("unexpected dispute data: %+v", d)
+	}
+	if got := d.EvidenceDeadline(); got.Unix() != 1700086400 {
+		t.Errorf("EvidenceDeadline = %v", got)
+	}
+
+	closed := &WebhookEvent{Type: WebhookDisputeClosed, Data: []byte(`{"disputeId":"dp_1","status":"won","closedAt":1700100000}`)}
+	d, err = closed.DisputeData()
+	if err != nil || d.Status != "won" || d.ClosedAt != 1700100000 || !d.EvidenceDeadline().IsZero() {
+		t.Errorf("unexpected closed dispute data: %+v, %v", d, err)
+	}
+}
+
+func TestVerifyWebhookSignature_Tolerance(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	secret := "whsec_test"
+	now := time.Now()
+
+	// Default config: a 5 minute window in both directions.
+	for name, signedAt := range map[string]time.Time{
+		"stale":  now.Add(-6 * time.Minute),
+		"future": now.Add(6 * time.Minute),
+	} {
+		if err := VerifyWebhookSignature(body, signBody(signedAt.Unix(), body, secret), secret); !errors.Is(err, ErrStaleWebhook) {
+			t.Errorf("%s: expected ErrStaleWebhook, got %v", name, err)
+		}
+	}
+	if err := VerifyWebhookSignature(body, signBody(now.Add(-time.Minute).Unix(), body, secret), secret); err != nil {
+		t.Errorf("signature within tolerance rejected: %v", err)
+	}
+
+	// A forged stale request fails the signature check first.
+	if err := VerifyWebhookSignature(body, signBody(1, body, "wrong"), secret); !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Errorf("expected ErrInvalidWebhookSignature, got %v", err)
+	}
+
+	viper.Set("nextpay.webhook_tolerance", "1h")
+	if err := VerifyWebhookSignature(body, signBody(now.Add(-30*time.Minute).Unix(), body, secret), secret); err != nil {
+		t.Errorf("signature within configured tolerance rejected: %v", err)
+	}
+	viper.Set("nextpay.webhook_tolerance", "0")
+	if err := VerifyWebhookSignature(body, signBody(1700000000, body, secret), secret); err != nil {
+		t.Errorf("tolerance 0 should disable the check, got %v", err)
+	}
+	viper.Set("nextpay.webhook_tolerance", nil)
+}
+
+func TestWebhookHandler(t *testing.T) {
+	secret := "whsec_test"
+	body := `{"id":"evt_1","type":"order.paid","timestamp":1700000000,"data":{"orderId":"ord_1"}}`
+
+	var handled []string
+	var fail error
+	handler := WebhookHandler(secret, func(ctx context.Context, evt *WebhookEvent) error {
+		d, err := evt.OrderData()
+		if err != nil {
+			return err
+		}
+		handled = append(handled, d.OrderID)
+		return fail
+	})
+
+	post := func(signature string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/webhooks/nextpay", strings.NewReader(body))
+		req.Header.Set(WebhookSignatureHeader, signature)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post(signBody(time.Now().Unix(), []byte(body), secret)); rec.Code != http.StatusOK {
+		t.Errorf("valid webhook: status %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := post(signBody(time.Now().Unix(), []byte(body), "wrong")); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad signature: status %d, want 400", rec.Code)
+	}
+	if rec := post(signBody(time.Now().Add(-time.Hour).Unix(), []byte(body), secret)); rec.Code != http.StatusBadRequest {
+		t.Errorf("stale webhook: status %d, want 400", rec.Code)
+	}
+
+	fail = errors.New("db down")
+	if rec := post(signBody(time.Now().Unix(), []byte(body), secret)); rec.Code != http.StatusInternalServerError {
+		t.Errorf("handler error: status %d, want 500 so NextPay retries", rec.Code)
+	}
+	if len(handled) != 2 || handled[0] != "ord_1" {
+		t.Errorf("unexpected handled events: %v", handled)
+	}
+}
@@ -2,11 +2,16 @@ package redis
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,18 +19,132 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/redis/go-redis/v9"
+	"github.com/rs/xid"
 )
 
 // BroadcastMessage 广播消息结构
+// Seq 为频道内单调递增的序号，客户端可据此检测丢失的消息（收到 7 后收到 9）
+// Redis 不可用时本地投递的消息 Seq 为 0；恢复后补发的消息 Delayed 为 true
+// ID 为全局唯一的消息 ID (xid)，补发时不变，客户端可据此去重
 type BroadcastMessage struct {
+	ID        string      `json:"id"`
 	Channel   string      `json:"channel"`
+	Seq       int64       `json:"seq"`
 	Timestamp int64       `json:"timestamp"`
 	Payload   interface{} `json:"payload"`
+	Delayed   bool        `json:"delayed,omitempty"`
+	Origin    string      `json:"origin,omitempty"` // 补发消息的来源实例，来源实例不再重复投递
+	Ack       bool        `json:"ack,omitempty"`    // 需要送达回执，见 PubWithAck
+
+	noCache bool // 不写入历史，见 NoCache
+	stored  bool // 已分配序号并写入历史，补发时只重新发布
+}
+
+// PubOption Pub 的可选参数
+type PubOption func(*BroadcastMessage)
+
+// NoCache 消息只实时投递，不写入历史：迟到的订阅者、按序号续传和 MissedHandler
+// 都拿不到该消息，序号照常递增。适用于即发即弃的频道（如输入中提示）
+func NoCache() PubOption {
+	return func(m *BroadcastMessage) {
+		m.noCache = true
+	}
+}
+
+// AckResult PubWithAck 的送达回执
+type AckResult struct {
+	ID          string `json:"id"`
+	Seq         int64  `json:"seq"`
+	Subscribers int    `json:"subscribers"` // 各实例收到消息的订阅者数之和
+	Instances   int    `json:"instances"`   // 超时前回执的实例数
+	Expected    int    `json:"expected"`    // 发布时运行 Run 的实例数，全部回执后提前返回
+}
+
+// broadcastAck Run 投递 Ack 消息后发布到回执频道的内容
+type broadcastAck struct {
+	Instance    string `json:"instance"`
+	Subscribers int    `json:"subscribers"`
+}
+
+// ErrPubQueued Redis 不可用时 Pub 返回的错误：消息已投递给本实例的订阅者，
+// 并进入待补发队列，Redis 恢复后按顺序补发给其他实例
+var ErrPubQueued = errors.New("broadcast: redis unavailable, delivered locally and queued for republish")
+
+const (
+	// broadcastSeqTTL 频道空闲超过该时间后，序号计数器和历史消息自动过期
+	broadcastSeqTTL = 24 * time.Hour
+	// broadcastHistoryMaxLen 每个频道保留的历史消息数（近似值）
+	broadcastHistoryMaxLen = 1000
+	// broadcastOutboxSize 降级模式下待补发队列的默认容量，满时丢弃最旧的消息
+	broadcastOutboxSize = 1000
+	// broadcastOutboxMaxAge 默认超过该时长的待补发消息不再补发
+	broadcastOutboxMaxAge = 5 * time.Minute
+	// broadcastRecoveryInterval 降级期间检测 Redis 恢复的间隔
+	broadcastRecoveryInterval = time.Second
+	// broadcastSubscriberBuffer 每个订阅者默认的消息缓冲区大小
+	broadcastSubscriberBuffer = 16
+	// broadcastReplayMax 长轮询迟到订阅者一次最多补发的缓存消息数
+	broadcastReplayMax = 100
+)
+
+// pubScript 原子地分配序号并写入历史，保证历史顺序与序号一致
+// KEYS 为同一频道的序号和历史 key，带相同的 hash tag，集群模式下位于同一槽
+// ARGV[1]/ARGV[2] 为消息 JSON 中序号前后的部分，ARGV[5] 为 "0" 时不写入历史
+// 返回序号
+var pubScript = redis.NewScript(`
+local seq = redis.call('INCR', KEYS[1])
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+if ARGV[5] == '1' then
+	redis.call('XADD', KEYS[2], 'MAXLEN', '~', ARGV[4], seq .. '-0', 'data', ARGV[1] .. seq .. ARGV[2])
+	redis.call('EXPIRE', KEYS[2], ARGV[3])
+end
+return seq
+`)
+
+// subscriber 单个订阅者，消息通道带缓冲
+// 投递不阻塞：缓冲区满时丢弃消息，慢订阅者不影响同频道的其他订阅者
+type subscriber struct {
+	ch chan *BroadcastMessage
+
+	mu     sync.Mutex // 保护 closed 和 drops，避免向已关闭的通道发送
+	closed bool
+	drops  int // 连续丢弃的消息数
+}
+
+func newSubscriber(buffer int) *subscriber {
+	return &subscriber{ch: make(chan *BroadcastMessage, buffer)}
+}
+
+// send 非阻塞投递，返回是否投递成功及当前连续丢弃数，已关闭的订阅者直接忽略
+func (s *subscriber) send(message *BroadcastMessage) (bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return true, 0
+	}
+	select {
+	case s.ch <- message:
+		s.drops = 0
+		return true, 0
+	default:
+		s.drops++
+		return false, s.drops
+	}
+}
+
+// close 关闭消息通道，订阅方读到关闭后退出
+func (s *subscriber) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
 }
 
 // ChannelSubscribers 频道订阅者管理
 type ChannelSubscribers struct {
-	subscribers sync.Map // chan *BroadcastMessage -> bool
+	subscribers sync.Map // *subscriber -> bool
 }
 
 func (c *ChannelSubscribers) count() int64 {
@@ -44,38 +163,198 @@ func (c *ChannelSubscribers) isEmpty() bool {
 // Broadcast 广播服务
 type Broadcast struct {
 	channels             sync.Map // string -> *ChannelSubscribers
-	rds                  *redis.Client
+	rds                  redis.UniversalClient
 	cacheSecondsForLated int64
-	metrics              struct {
-		activeChannels   atomic.Int64 // 活跃channel数
-		messagesSent     atomic.Int64 // 发送消息数
-		messagesDropped  atomic.Int64 // 丢弃消息数
-		subscribeLatency atomic.Int64 // 订阅延迟(毫秒)
+	seqTTL               time.Duration
+	instanceID           string
+
+	// 降级模式：Redis 不可用时的待补发队列
+	outboxMu         sync.Mutex
+	outbox           []*BroadcastMessage
+	outboxSize       int
+	outboxMaxAge     time.Duration
+	recoveryInterval time.Duration
+	recovering       bool
+
+	// 订阅者缓冲区大小，以及连续丢弃多少条消息后断开订阅者（0 不断开）
+	subscriberBuffer    int
+	maxConsecutiveDrops int
+
+	authorizer Authorizer // 订阅鉴权，nil 表示不鉴权
+
+	local *localStore // 本地模式的序号和历史，nil 表示 Redis 模式
+
+	metrics struct {
+		activeChannels      atomic.Int64 // 活跃channel数
+		messagesSent        atomic.Int64 // 发送消息数
+		messagesDropped     atomic.Int64 // 丢弃消息数（以下各原因之和）
+		droppedBufferFull   atomic.Int64 // 订阅者缓冲区满丢弃的消息数
+		droppedOutboxFull   atomic.Int64 // 待补发队列满丢弃的消息数
+		droppedReceiveError atomic.Int64 // 从 Redis 接收失败的次数
+		slowDisconnects     atomic.Int64 // 因连续丢弃被断开的订阅者数
+		degradedDeliveries  atomic.Int64 // 降级模式下本地投递的消息数
+		messagesRepublished atomic.Int64 // Redis 恢复后补发的消息数
+		messagesExpired     atomic.Int64 // 超过最大时长未补发的消息数
+
+		fanoutLatency atomic.Pointer[fanoutHistogram] // 投递延迟直方图，见 RegisterMetrics
 	}
 }
 
 // NewBroadcast 创建新的广播服务实例
+// 配置 redis.broadcast.mode 为 local 时返回本地模式的实例，见 NewLocalBroadcast
 func NewBroadcast(cacheSecondsForLated int64) *Broadcast {
+	if broadcastModeFromConfig("") == BroadcastModeLocal {
+		return NewLocalBroadcast(cacheSecondsForLated)
+	}
+	return newBroadcast(cacheSecondsForLated, Client())
+}
+
+// NewBroadcastFor 创建使用 app 的 Redis 的广播服务，连接配置见 LoadConfig
+// 配置 <app>.redis.broadcast.mode（回退到 redis.broadcast.mode）为 local 时返回本地模式的实例
+func NewBroadcastFor(app string, cacheSecondsForLated int64) *Broadcast {
+	if broadcastModeFromConfig(app) == BroadcastModeLocal {
+		return NewLocalBroadcast(cacheSecondsForLated)
+	}
+	return newBroadcast(cacheSecondsForLated, ClientFor(app))
+}
+
+func newBroadcast(cacheSecondsForLated int64, rds redis.UniversalClient) *Broadcast {
 	if cacheSecondsForLated <= 0 {
 		cacheSecondsForLated = 10
 	}
-	return &Broadcast{
-		rds:                  Client(),
+	id := make([]byte, 8)
+	rand.Read(id)
+	b := &Broadcast{
+		rds:                  rds,
 		cacheSecondsForLated: cacheSecondsForLated,
+		seqTTL:               broadcastSeqTTL,
+		instanceID:           hex.EncodeToString(id),
+		outboxSize:           broadcastOutboxSize,
+		outboxMaxAge:         broadcastOutboxMaxAge,
+		recoveryInterval:     broadcastRecoveryInterval,
+		subscriberBuffer:     broadcastSubscriberBuffer,
 	}
+	b.metrics.fanoutLatency.Store(newFanoutHistogram())
+	return b
+}
+
+// SetOutbox 设置降级模式的待补发队列容量和消息最大补发时长
+// size <= 0 关闭降级模式，Redis 不可用时 Pub 直接返回错误
+func (b *Broadcast) SetOutbox(size int, maxAge time.Duration) {
+	b.outboxMu.Lock()
+	defer b.outboxMu.Unlock()
+	b.outboxSize = size
+	b.outboxMaxAge = maxAge
+}
+
+// SetSubscriberBuffer 设置每个订阅者的消息缓冲区大小（<= 0 使用默认值 16）
+// 缓冲区满时新消息被丢弃；maxConsecutiveDrops > 0 时，连续丢弃该数量消息的
+// 订阅者被断开（WebSocket 关闭连接），客户端可重连并用 Last-Event-ID 续传
+// 只影响之后建立的订阅
+func (b *Broadcast) SetSubscriberBuffer(size int, maxConsecutiveDrops int) {
+	if size <= 0 {
+		size = broadcastSubscriberBuffer
+	}
+	b.subscriberBuffer = size
+	b.maxConsecutiveDrops = maxConsecutiveDrops
 }
 
 func (b *Broadcast) broadcastKey() string {
 	return "broadcast"
 }
 
-func (b *Broadcast) messageCacheKey(channel string) string {
-	return fmt.Sprintf("broadcast/%s", channel)
+// seqKey 和 historyKey 以频道名为 hash tag，集群模式下位于同一槽
+func (b *Broadcast) seqKey(channel string) string {
+	return fmt.Sprintf("broadcast:{%s}:seq", channel)
+}
+
+func (b *Broadcast) historyKey(channel string) string {
+	return fmt.Sprintf("broadcast:{%s}:history", channel)
+}
+
+func (b *Broadcast) ackKey(id string) string {
+	return fmt.Sprintf("broadcast/ack/%s", id)
+}
+
+// History 按序号返回频道历史消息，范围为 [fromSeq, toSeq]，toSeq <= 0 表示不限
+// 历史保存在 Redis Stream 中，消息 ID 即序号，因此结果严格按序号排列
+func (b *Broadcast) History(ctx context.Context, channel string, fromSeq, toSeq int64) ([]*BroadcastMessage, error) {
+	if fromSeq < 1 {
+		fromSeq = 1
+	}
+	end := "+"
+	if toSeq > 0 {
+		if toSeq < fromSeq {
+			return nil, nil
+		}
+		end = fmt.Sprintf("%d-0", toSeq)
+	}
+	if b.local != nil {
+		return b.historyLocal(channel, fromSeq, toSeq), nil
+	}
+	entries, err := b.rds.XRange(ctx, b.historyKey(channel), fmt.Sprintf("%d-0", fromSeq), end).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeHistory(entries), nil
+}
+
+// recent 返回 cacheSecondsForLated 内、时间戳晚于 since 的历史消息，按序号升序
+// 最多返回最近的 broadcastReplayMax 条
+func (b *Broadcast) recent(ctx context.Context, channel string, since int64) ([]*BroadcastMessage, error) {
+	if b.local != nil {
+		return b.recentLocal(channel, since), nil
+	}
+	entries, err := b.rds.XRevRangeN(ctx, b.historyKey(channel), "+", "-", broadcastReplayMax).Result()
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-time.Duration(b.cacheSecondsForLated) * time.Second).UnixMilli()
+	var messages []*BroadcastMessage
+	for _, message := range decodeHistory(entries) {
+		if message.Timestamp > since && message.Timestamp >= cutoff {
+			messages = append(messages, message)
+		}
+	}
+	slices.Reverse(messages)
+	return messages, nil
+}
+
+func decodeHistory(entries []redis.XMessage) []*BroadcastMessage {
+	messages := make([]*BroadcastMessage, 0, len(entries))
+	for _, entry := range entries {
+		data, _ := entry.Values["data"].(string)
+		message := &BroadcastMessage{}
+		if err := json.Unmarshal([]byte(data), message); err != nil {
+			log.Printf("decode history message failed: id:%s err:%v", entry.ID, err)
+			continue
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// lastSeq 读取客户端最后收到的序号，支持 Last-Event-ID 头和 last_seq 参数
+func lastSeq(c *gin.Context) int64 {
+	v := c.GetHeader("Last-Event-ID")
+	if v == "" {
+		v = c.Query("last_seq")
+	}
+	seq, _ := strconv.ParseInt(v, 10, 64)
+	return seq
+}
+
+// subscribe 为频道注册一个订阅者
+func (b *Broadcast) subscribe(channel string) (*subscriber, *ChannelSubscribers) {
+	sub := newSubscriber(b.subscriberBuffer)
+	subscribers := b.getOrCreateChannelSubscribers(channel)
+	subscribers.subscribers.Store(sub, true)
+	return sub, subscribers
 }
 
-func (b *Broadcast) unsubscribe(channel string, ch chan *BroadcastMessage, subscribers *ChannelSubscribers) {
-	if _, exists := subscribers.subscribers.LoadAndDelete(ch); exists {
-		close(ch)
+func (b *Broadcast) unsubscribe(channel string, sub *subscriber, subscribers *ChannelSubscribers) {
+	if _, exists := subscribers.subscribers.LoadAndDelete(sub); exists {
+		sub.close()
 	}
 	b.cleanEmptyChannel(channel, subscribers)
 }
@@ -83,6 +362,10 @@ func (b *Broadcast) unsubscribe(channel string, ch chan *BroadcastMessage, subsc
 // WsSubChannel WebSocket订阅频道
 func (b *Broadcast) WsSubChannel(c *gin.Context, channel string) error {
 	log.Printf("new websocket connection for channel: %s", channel)
+	if err := b.authorize(c, channel); err != nil {
+		log.Printf("websocket subscription denied: channel:%s err:%v", channel, err)
+		return err
+	}
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool {
 			return true
@@ -97,15 +380,30 @@ func (b *Broadcast) WsSubChannel(c *gin.Context, channel string) error {
 	defer ws.Close()
 
 	// 创建消息通道
-	ch := make(chan *BroadcastMessage)
-	subscribers := b.getOrCreateChannelSubscribers(channel)
-	subscribers.subscribers.Store(ch, true)
+	sub, subscribers := b.subscribe(channel)
 
 	// 清理工作
 	defer func() {
-		b.unsubscribe(channel, ch, subscribers)
+		b.unsubscribe(channel, sub, subscribers)
 	}()
 
+	// 断线重连：先订阅再补发 Last-Event-ID 之后的历史消息，避免两者之间的空档
+	var sent int64
+	if last := lastSeq(c); last > 0 {
+		missed, err := b.History(c, channel, last+1, 0)
+		if err != nil {
+			log.Printf("websocket resume failed: channel:%s err:%v", channel, err)
+		}
+		for _, msg := range missed {
+			data, _ := json.Marshal(msg)
+			if err := ws.WriteMessage(websocket.TextMessage, data); err != nil {
+				log.Printf("write message failed: %v", err)
+				return err
+			}
+			sent = msg.Seq
+		}
+	}
+
 	// 用于协调goroutine退出
 	done := make(chan struct{})
 	defer close(done)
@@ -135,7 +433,16 @@ func (b *Broadcast) WsSubChannel(c *gin.Context, channel string) error {
 	// 处理接收到的消息
 	for {
 		select {
-		case msg := <-ch:
+		case msg, ok := <-sub.ch:
+			if !ok {
+				// 频道被删除，或订阅者因连续丢弃消息被断开
+				log.Printf("websocket subscriber disconnected: channel:%s", channel)
+				return nil
+			}
+			if msg.Seq > 0 && msg.Seq <= sent {
+				// 已在补发的历史消息中发送过
+				continue
+			}
 			data, err := json.Marshal(msg)
 			if err != nil {
 				log.Printf("marshal message failed: %v", err)
@@ -163,8 +470,9 @@ func (b *Broadcast) WsSub(paramName string) gin.HandlerFunc {
 }
 
 // HttpSub HTTP长轮询订阅处理器
-// since 毫秒时间戳
+// since 毫秒时间戳，返回 cacheSecondsForLated 内时间戳晚于 since 的全部缓存消息
 // timeout 客户端请求时设置的超时时间，单位为毫秒
+// 有消息时 data 为按序号升序的消息数组，客户端以最后一条的 timestamp 或 seq 继续轮询
 func (b *Broadcast) HttpSub(paramName string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		channel := c.Param(paramName)
@@ -178,6 +486,11 @@ func (b *Broadcast) HttpSub(paramName string) gin.HandlerFunc {
 			return
 		}
 
+		if err := b.authorize(c, channel); err != nil {
+			log.Printf("http subscription denied: channel:%s err:%v", channel, err)
+			return
+		}
+
 		since, _ := strconv.ParseInt(c.Query("since"), 10, 64)
 		timeout, _ := strconv.ParseInt(c.Query("timeout"), 10, 64)
 		log.Printf("new http subscription: channel:%s since:%d timeout:%d",
@@ -190,46 +503,72 @@ func (b *Broadcast) HttpSub(paramName string) gin.HandlerFunc {
 
 		ctx, cancel := context.WithTimeout(c, time.Duration(timeout)*time.Millisecond)
 		defer cancel()
-		message := &BroadcastMessage{}
-		key := b.messageCacheKey(channel)
-		val, err := b.rds.Get(ctx, key).Result()
-		if err == redis.Nil {
+
+		// 按序号续传：返回 last_seq 之后的消息，客户端带上新的序号继续轮询
+		if last := lastSeq(c); last > 0 {
+			missed, err := b.History(ctx, channel, last+1, 0)
+			if err != nil {
+				c.JSON(200, map[string]interface{}{
+					"code": 500,
+					"msg":  "cache error",
+					"data": nil,
+				})
+				return
+			}
+			if len(missed) > 0 {
+				c.JSON(200, map[string]interface{}{
+					"code": 0,
+					"msg":  "",
+					"data": missed,
+				})
+				return
+			}
 			goto listen
 		}
-		if err != nil {
-			c.JSON(200, map[string]interface{}{
-				"code": 500,
-				"msg":  "cache error",
-				"data": nil,
-			})
-			return
-		}
-		json.Unmarshal([]byte(val), message)
-		if message.Timestamp >= since {
-			c.JSON(200, map[string]interface{}{
-				"code": 0,
-				"msg":  "",
-				"data": message,
-			})
-			return
+
+		{
+			// 迟到的订阅者可以拿到 cacheSecondsForLated 内 since 之后的缓存消息
+			messages, err := b.recent(ctx, channel, since)
+			if err != nil {
+				c.JSON(200, map[string]interface{}{
+					"code": 500,
+					"msg":  "cache error",
+					"data": nil,
+				})
+				return
+			}
+			if len(messages) > 0 {
+				c.JSON(200, map[string]interface{}{
+					"code": 0,
+					"msg":  "",
+					"data": messages,
+				})
+				return
+			}
 		}
 	listen:
 		log.Printf("start listen channel:%s", channel)
-		ch := make(chan *BroadcastMessage)
-		subscribers := b.getOrCreateChannelSubscribers(channel)
-		subscribers.subscribers.Store(ch, true)
+		sub, subscribers := b.subscribe(channel)
 
 		defer func() {
-			b.unsubscribe(channel, ch, subscribers)
+			b.unsubscribe(channel, sub, subscribers)
 		}()
 
 		select {
-		case msg := <-ch:
+		case msg, ok := <-sub.ch:
+			if !ok {
+				c.JSON(200, map[string]interface{}{
+					"code": 410,
+					"msg":  "channel closed",
+					"data": nil,
+				})
+				return
+			}
 			log.Printf("http sub message delivered: channel:%s message:%+v", channel, msg)
 			c.JSON(200, map[string]interface{}{
 				"code": 0,
 				"msg":  "",
-				"data": msg,
+				"data": []*BroadcastMessage{msg},
 			})
 		case <-ctx.Done():
 			log.Printf("http sub timeout: channel:%s duration:%dms",
@@ -246,27 +585,353 @@ func (b *Broadcast) HttpSub(paramName string) gin.HandlerFunc {
 }
 
 // Pub 发布消息到频道
-func (b *Broadcast) Pub(ctx context.Context, channel string, payload interface{}) error {
+// 序号分配和历史写入在同一个 Lua 脚本中完成，并发发布时序号严格递增；
+// 发布在脚本之后，并发发布的实时消息可能不按序号到达，历史仍严格按序号排列
+// 本地模式下直接投递给本进程的订阅者，总是返回 nil
+//
+// Redis 不可用时进入降级模式：消息直接投递给本实例的订阅者并进入待补发队列，
+// 返回 ErrPubQueued（可用 errors.Is 判断）；其他错误表示消息未送达
+func (b *Broadcast) Pub(ctx context.Context, channel string, payload interface{}, opts ...PubOption) error {
+	message := newBroadcastMessage(channel, payload, opts)
+	if b.local != nil {
+		b.pubLocal(message)
+		return nil
+	}
+	_, err := b.publish(ctx, message)
+	return err
+}
+
+// PubWithAck 发布消息并等待送达回执：各实例的 Run 投递给本实例订阅者后，
+// 在由消息 ID 派生的回执频道上报告送达的订阅者数
+// 在 timeout 内汇总回执，所有实例都回执后提前返回；超时不算错误，
+// 未回执的实例不计入 Subscribers。本地模式下直接返回本进程的投递结果。
+// Redis 不可用时与 Pub 相同，返回 ErrPubQueued，此时没有回执。
+//
+// Example:
+//
+//	result, err := b.PubWithAck(ctx, "orders/123", payload, time.Second)
+//	if err == nil && result.Subscribers == 0 {
+//	    // 没有在线的订阅者，改用其他方式通知
+//	}
+func (b *Broadcast) PubWithAck(ctx context.Context, channel string, payload interface{}, timeout time.Duration, opts ...PubOption) (*AckResult, error) {
+	message := newBroadcastMessage(channel, payload, opts)
+	message.Ack = true
+	result := &AckResult{ID: message.ID}
+	if b.local != nil {
+		result.Subscribers = b.pubLocal(message)
+		result.Seq, result.Instances, result.Expected = message.Seq, 1, 1
+		return result, nil
+	}
+
+	// 先订阅回执频道再发布，避免漏掉回执
+	pubsub := b.rds.Subscribe(ctx, b.ackKey(message.ID))
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return nil, err
+	}
+	acks := pubsub.Channel()
+
+	receivers, err := b.publish(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	result.Seq, result.Expected = message.Seq, int(receivers)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	acked := make(map[string]bool)
+	for len(acked) < result.Expected {
+		select {
+		case msg := <-acks:
+			var ack broadcastAck
+			if err := json.Unmarshal([]byte(msg.Payload), &ack); err != nil || acked[ack.Instance] {
+				continue
+			}
+			acked[ack.Instance] = true
+			result.Instances++
+			result.Subscribers += ack.Subscribers
+		case <-timer.C:
+			return result, nil
+		case <-ctx.Done():
+			return result, ctx.Err()
+		}
+	}
+	return result, nil
+}
+
+func newBroadcastMessage(channel string, payload interface{}, opts []PubOption) *BroadcastMessage {
 	message := &BroadcastMessage{
+		ID:        xid.New().String(),
 		Channel:   channel,
 		Timestamp: time.Now().UnixMilli(),
 		Payload:   payload,
 	}
-	data, _ := json.Marshal(message)
-	err := b.rds.Publish(ctx, b.broadcastKey(), data).Err()
+	for _, opt := range opts {
+		opt(message)
+	}
+	return message
+}
+
+// publish 发布到 Redis，成功时设置 message.Seq 并返回运行 Run 的实例数
+// Redis 不可用时进入降级模式，见 Pub
+func (b *Broadcast) publish(ctx context.Context, message *BroadcastMessage) (int64, error) {
+	// 待补发队列未清空时新消息也排队，保证补发顺序与发布顺序一致
+	if b.enqueueIfPending(message) {
+		b.deliverLocal(message)
+		return 0, fmt.Errorf("%w: outbox not yet drained", ErrPubQueued)
+	}
+
+	head, tail, err := encodeMessage(message)
 	if err != nil {
-		log.Printf("pub to channel:%s with err:%v", channel, err)
+		return 0, err
+	}
+	seq, receivers, err := b.publishEncoded(ctx, message.Channel, head, tail, !message.noCache)
+	if seq > 0 {
+		message.Seq, message.stored = seq, err != nil
+	}
+	if err == nil {
+		return receivers, nil
+	}
+	log.Printf("pub to channel:%s with err:%v", message.Channel, err)
+
+	// 调用方取消不属于 Redis 故障
+	if ctx.Err() != nil || !b.enqueue(message) {
+		return 0, err
+	}
+	b.deliverLocal(message)
+	return 0, fmt.Errorf("%w: %v", ErrPubQueued, err)
+}
+
+// encodeMessage 序列化消息并在序号处切开
+// Seq 为 0 时序列化结果中必然包含 "seq":0，在此处切开由脚本填入实际序号
+func encodeMessage(message *BroadcastMessage) (head, tail string, err error) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return "", "", err
+	}
+	head, tail, _ = strings.Cut(string(data), `"seq":0`)
+	return head + `"seq":`, tail, nil
+}
+
+// publishEncoded 通过 Lua 脚本分配序号、写入历史（cache 为 true 时），再发布到 Redis
+// 发布频道不属于脚本的 key，集群模式下不会跨槽
+// 返回序号和运行 Run 的实例数；脚本成功而发布失败时仍返回序号
+func (b *Broadcast) publishEncoded(ctx context.Context, channel, head, tail string, cache bool) (int64, int64, error) {
+	keys := []string{b.seqKey(channel), b.historyKey(channel)}
+	cacheArg := "0"
+	if cache {
+		cacheArg = "1"
+	}
+	seq, err := pubScript.Run(ctx, b.rds, keys, head, tail, int64(b.seqTTL.Seconds()), broadcastHistoryMaxLen, cacheArg).Int64()
+	if err != nil {
+		return 0, 0, err
+	}
+	receivers, err := b.rds.Publish(ctx, b.broadcastKey(), head+strconv.FormatInt(seq, 10)+tail).Result()
+	if err != nil {
+		return seq, 0, err
+	}
+	return seq, receivers, nil
+}
+
+// enqueueIfPending 待补发队列非空时将消息加入队列
+func (b *Broadcast) enqueueIfPending(message *BroadcastMessage) bool {
+	b.outboxMu.Lock()
+	defer b.outboxMu.Unlock()
+	if len(b.outbox) == 0 {
+		return false
+	}
+	b.pushOutbox(message)
+	return true
+}
+
+// enqueue 将消息加入待补发队列并启动恢复检测，降级模式关闭时返回 false
+func (b *Broadcast) enqueue(message *BroadcastMessage) bool {
+	b.outboxMu.Lock()
+	defer b.outboxMu.Unlock()
+	if b.outboxSize <= 0 {
+		return false
+	}
+	b.pushOutbox(message)
+	return true
+}
+
+// pushOutbox 需持有 outboxMu，队列满时丢弃最旧的消息
+func (b *Broadcast) pushOutbox(message *BroadcastMessage) {
+	if len(b.outbox) >= b.outboxSize {
+		b.outbox = b.outbox[1:]
+		b.metrics.messagesDropped.Add(1)
+		b.metrics.droppedOutboxFull.Add(1)
+	}
+	b.outbox = append(b.outbox, message)
+	b.metrics.degradedDeliveries.Add(1)
+
+	if !b.recovering {
+		b.recovering = true
+		go b.recoveryLoop()
+	}
+}
+
+// recoveryLoop 定期检测 Redis，恢复后按顺序补发待补发队列，清空后退出
+func (b *Broadcast) recoveryLoop() {
+	ticker := time.NewTicker(b.recoveryInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), b.recoveryInterval)
+		err := b.rds.Ping(ctx).Err()
+		cancel()
+		if err != nil {
+			continue
+		}
+		if b.flushOutbox() {
+			log.Printf("broadcast: redis recovered, outbox drained")
+			return
+		}
+	}
+}
+
+// flushOutbox 补发队列中的消息，超过最大时长的消息丢弃
+// 全部补发完成返回 true，补发失败返回 false 等待下次检测
+func (b *Broadcast) flushOutbox() bool {
+	for {
+		b.outboxMu.Lock()
+		if len(b.outbox) == 0 {
+			b.recovering = false
+			b.outboxMu.Unlock()
+			return true
+		}
+		message := b.outbox[0]
+		maxAge := b.outboxMaxAge
+		b.outboxMu.Unlock()
+
+		if maxAge > 0 && time.Since(time.UnixMilli(message.Timestamp)) > maxAge {
+			b.metrics.messagesExpired.Add(1)
+			log.Printf("broadcast: outbox message expired, channel:%s", message.Channel)
+		} else {
+			if err := b.republish(message); err != nil {
+				log.Printf("broadcast: republish failed, channel:%s err:%v", message.Channel, err)
+				return false
+			}
+			b.metrics.messagesRepublished.Add(1)
+		}
+
+		// 补发期间队列满时队首可能已被丢弃，只移除刚补发的消息
+		b.outboxMu.Lock()
+		if len(b.outbox) > 0 && b.outbox[0] == message {
+			b.outbox = b.outbox[1:]
+		}
+		b.outboxMu.Unlock()
+	}
+}
+
+// republish 补发一条待补发消息
+// 已写入历史的消息沿用原序号只重新发布，避免历史中出现重复；
+// 补发时脚本成功而发布失败的消息同样标记为已写入
+func (b *Broadcast) republish(message *BroadcastMessage) error {
+	ctx := context.Background()
+	republished := *message
+	republished.Delayed = true
+	republished.Origin = b.instanceID
+	if message.stored {
+		data, _ := json.Marshal(&republished) // 入队前已成功序列化过
+		return b.rds.Publish(ctx, b.broadcastKey(), data).Err()
+	}
+	head, tail, _ := encodeMessage(&republished)
+	seq, _, err := b.publishEncoded(ctx, republished.Channel, head, tail, !republished.noCache)
+	if err != nil && seq > 0 {
+		message.Seq, message.stored = seq, true
 	}
 	return err
 }
 
+// outboxDepth 返回待补发队列长度
+func (b *Broadcast) outboxDepth() int64 {
+	b.outboxMu.Lock()
+	defer b.outboxMu.Unlock()
+	return int64(len(b.outbox))
+}
+
+// deliverLocal 将消息投递给本实例该频道的订阅者，返回投递成功的订阅者数
+func (b *Broadcast) deliverLocal(message *BroadcastMessage) int {
+	chs, ok := b.Load(message.Channel)
+	if !ok {
+		log.Printf("broadcast:no subscribers for channel:%s", message.Channel)
+		return 0
+	}
+	log.Printf("broadcast:find subscribers, channel:%s subscribers count:%d",
+		message.Channel, chs.count())
+	delivered := 0
+	chs.subscribers.Range(func(key, _ interface{}) bool {
+		sub := key.(*subscriber)
+		ok, drops := sub.send(message)
+		if ok {
+			delivered++
+			return true
+		}
+
+		b.metrics.messagesDropped.Add(1)
+		b.metrics.droppedBufferFull.Add(1)
+		log.Printf("broadcast:subscriber buffer full, message dropped, channel:%s consecutive drops:%d",
+			message.Channel, drops)
+		if b.maxConsecutiveDrops > 0 && drops >= b.maxConsecutiveDrops {
+			b.metrics.slowDisconnects.Add(1)
+			log.Printf("broadcast:disconnect slow subscriber, channel:%s", message.Channel)
+			b.unsubscribe(message.Channel, sub, chs)
+		}
+		return true
+	})
+	return delivered
+}
+
+// MissedHandler 返回频道中丢失的消息，供客户端检测到序号缺口后补齐
+// 查询参数 from、to 为序号闭区间，to 省略表示到最新
+func (b *Broadcast) MissedHandler(paramName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		channel := c.Param(paramName)
+		from, _ := strconv.ParseInt(c.Query("from"), 10, 64)
+		to, _ := strconv.ParseInt(c.Query("to"), 10, 64)
+		if channel == "" || from <= 0 {
+			c.JSON(200, map[string]interface{}{
+				"code": 400,
+				"msg":  "channel and from are required",
+				"data": nil,
+			})
+			return
+		}
+		if err := b.authorize(c, channel); err != nil {
+			return
+		}
+
+		messages, err := b.History(c, channel, from, to)
+		if err != nil {
+			c.JSON(200, map[string]interface{}{
+				"code": 500,
+				"msg":  "cache error",
+				"data": nil,
+			})
+			return
+		}
+		c.JSON(200, map[string]interface{}{
+			"code": 0,
+			"msg":  "",
+			"data": messages,
+		})
+	}
+}
+
 // Del 删除频道（别名）
 func (b *Broadcast) Del(channel string) {
 	b.Delete(channel)
 }
 
 // Run 运行广播服务
+// 本地模式下消息在 Pub 中直接投递，Run 只阻塞，启动代码无需区分模式
 func (b *Broadcast) Run() {
+	if b.local != nil {
+		log.Printf("broadcast service started in local mode")
+		select {}
+	}
+
 	ctx := context.Background()
 	pubsub := b.rds.Subscribe(ctx, b.broadcastKey())
 	defer pubsub.Close()
@@ -277,6 +942,7 @@ func (b *Broadcast) Run() {
 		msg, err := pubsub.ReceiveMessage(ctx)
 		if err != nil {
 			b.metrics.messagesDropped.Add(1)
+			b.metrics.droppedReceiveError.Add(1)
 			log.Printf("receive message error: %v, total dropped: %d",
 				err, b.metrics.messagesDropped.Load())
 			time.Sleep(time.Second)
@@ -288,30 +954,30 @@ func (b *Broadcast) Run() {
 		json.Unmarshal([]byte(msg.Payload), message)
 		log.Printf("broadcast:get message from redis, message:%s", msg)
 
-		chs, ok := b.Load(message.Channel)
-		if ok {
-			log.Printf("broadcast:find subscribers, channel:%s subscribers count:%d",
-				message.Channel, chs.count())
-			chs.subscribers.Range(func(key, _ interface{}) bool {
-				ch := key.(chan *BroadcastMessage)
-				ch <- message
-				log.Printf("broadcast:send to one subscriber done, channel:%s",
-					message.Channel)
-				return true
-			})
-		} else {
-			log.Printf("broadcast:no subscribers for channel:%s", message.Channel)
+		// 本实例降级期间已投递过的补发消息不再重复投递
+		if !(message.Delayed && message.Origin == b.instanceID) {
+			delivered := b.deliverLocal(message)
+			if message.Ack {
+				b.sendAck(message.ID, delivered)
+			}
 		}
-		log.Printf("broadcast:cache a backup to redis, message:%s", msg)
-		key := b.messageCacheKey(message.Channel)
-		b.rds.SetNX(ctx, key, message, time.Duration(b.cacheSecondsForLated)*time.Second)
 
-		latency := time.Since(startTime).Milliseconds()
-		b.metrics.subscribeLatency.Store(latency)
+		latency := time.Since(startTime)
+		b.observeFanout(latency)
 		b.metrics.messagesSent.Add(1)
 
 		log.Printf("broadcast:message processed, channel:%s latency:%dms sent:%d",
-			message.Channel, latency, b.metrics.messagesSent.Load())
+			message.Channel, latency.Milliseconds(), b.metrics.messagesSent.Load())
+	}
+}
+
+// sendAck 在回执频道上报告本实例送达的订阅者数
+func (b *Broadcast) sendAck(id string, delivered int) {
+	data, _ := json.Marshal(broadcastAck{Instance: b.instanceID, Subscribers: delivered})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := b.rds.Publish(ctx, b.ackKey(id), data).Err(); err != nil {
+		log.Printf("broadcast: ack failed, id:%s err:%v", id, err)
 	}
 }
 
@@ -326,8 +992,7 @@ func (b *Broadcast) getOrCreateChannelSubscribers(channel string) *ChannelSubscr
 }
 
 func (b *Broadcast) cleanEmptyChannel(channel string, subscribers *ChannelSubscribers) {
-	if subscribers.isEmpty() {
-		b.channels.Delete(channel)
+	if subscribers.isEmpty() && b.channels.CompareAndDelete(channel, subscribers) {
 		b.metrics.activeChannels.Add(-1)
 		log.Printf("channel cleaned: %s, remaining active channels: %d",
 			channel, b.metrics.activeChannels.Load())
@@ -338,12 +1003,14 @@ func (b *Broadcast) cleanEmptyChannel(channel string, subscribers *ChannelSubscr
 func (b *Broadcast) Delete(channel string) {
 	if value, ok := b.channels.Load(channel); ok {
 		subscribers := value.(*ChannelSubscribers)
-		subscribers.subscribers.Range(func(ch, _ interface{}) bool {
-			subscribers.subscribers.Delete(ch)
-			close(ch.(chan *BroadcastMessage))
+		subscribers.subscribers.Range(func(sub, _ interface{}) bool {
+			subscribers.subscribers.Delete(sub)
+			sub.(*subscriber).close()
 			return true
 		})
-		b.channels.Delete(channel)
+		if b.channels.CompareAndDelete(channel, subscribers) {
+			b.metrics.activeChannels.Add(-1)
+		}
 	}
 }
 
@@ -356,14 +1023,25 @@ func (b *Broadcast) Load(channel string) (*ChannelSubscribers, bool) {
 	return value.(*ChannelSubscribers), ok
 }
 
-// GetMetrics 获取广播服务指标
+// GetMetrics 获取广播服务指标，subscribe_latency 为平均投递延迟（毫秒）
+// Prometheus 见 RegisterMetrics
 func (b *Broadcast) GetMetrics(c *gin.Context) {
 	c.JSON(200,
 		map[string]int64{
 			"active_channels":   b.metrics.activeChannels.Load(),
 			"messages_sent":     b.metrics.messagesSent.Load(),
 			"messages_dropped":  b.metrics.messagesDropped.Load(),
-			"subscribe_latency": b.metrics.subscribeLatency.Load(),
+			"subscribe_latency": b.fanoutAvgMillis(),
+
+			"dropped_buffer_full":   b.metrics.droppedBufferFull.Load(),
+			"dropped_outbox_full":   b.metrics.droppedOutboxFull.Load(),
+			"dropped_receive_error": b.metrics.droppedReceiveError.Load(),
+			"slow_disconnects":      b.metrics.slowDisconnects.Load(),
+
+			"degraded_deliveries":  b.metrics.degradedDeliveries.Load(),
+			"outbox_depth":         b.outboxDepth(),
+			"messages_republished": b.metrics.messagesRepublished.Load(),
+			"messages_expired":     b.metrics.messagesExpired.Load(),
 		})
 }
 
@@ -371,6 +1049,13 @@ func (b *Broadcast) GetMetrics(c *gin.Context) {
 func (b *Broadcast) ResetMetrics() {
 	b.metrics.messagesSent.Store(0)
 	b.metrics.messagesDropped.Store(0)
-	b.metrics.subscribeLatency.Store(0)
-	// 注意：不重置 activeChannels，因为这是实时状态
+	b.metrics.droppedBufferFull.Store(0)
+	b.metrics.droppedOutboxFull.Store(0)
+	b.metrics.droppedReceiveError.Store(0)
+	b.metrics.slowDisconnects.Store(0)
+	b.metrics.fanoutLatency.Store(newFanoutHistogram())
+	b.metrics.degradedDeliveries.Store(0)
+	b.metrics.messagesRepublished.Store(0)
+	b.metrics.messagesExpired.Store(0)
+	// 注意：不重置 activeChannels 和 outbox_depth，因为这是实时状态
 }
\ No newline at end of file
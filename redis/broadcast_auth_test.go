@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTokenAuthorizer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := TokenAuthorizer("secret")
+	channel := "user/123/notifications"
+
+	check := func(token string) error {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/sub?token="+url.QueryEscape(token), nil)
+		return auth(c, channel)
+	}
+
+	if err := check(SignChannelToken("secret", channel, time.Minute)); err != nil {
+		t.Errorf("valid token rejected: %v", err)
+	}
+	for name, token := range map[string]string{
+		"missing":       "",
+		"malformed":     "abc",
+		"other channel": SignChannelToken("secret", "user/456/notifications", time.Minute),
+		"wrong secret":  SignChannelToken("other", channel, time.Minute),
+		"expired":       SignChannelToken("secret", channel, -time.Minute),
+	} {
+		if err := check(token); err == nil {
+			t.Errorf("%s token accepted", name)
+		}
+	}
+}
+
+func TestBroadcastAuthorizerDenies(t *testing.T) {
+	b, channel := setupTestBroadcast(t)
+	b.SetAuthorizer(func(c *gin.Context, channel string) error {
+		return errors.New("forbidden channel")
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws/:channel", b.WsSub("channel"))
+	r.GET("/sub/:channel", b.HttpSub("channel"))
+	r.GET("/missed/:channel", b.MissedHandler("channel"))
+
+	for _, path := range []string{"/ws/" + channel, "/sub/" + channel, "/missed/" + channel + "?from=1"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		var resp struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != 403 || resp.Code != 403 || resp.Msg != "forbidden channel" {
+			t.Errorf("%s: expected 403 forbidden channel, got %d %s", path, w.Code, w.Body.String())
+		}
+	}
+	if _, ok := b.Load(channel); ok {
+		t.Error("denied subscription should not register a subscriber")
+	}
+}
@@ -0,0 +1,160 @@
+package redis
+
+import (
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// BroadcastModeLocal 单实例部署的本地模式，配置 redis.broadcast.mode: local
+// 时 NewBroadcast 返回本地模式的实例，<app>.redis.broadcast.mode 对 NewBroadcastFor 生效
+const BroadcastModeLocal = "local"
+
+// broadcastLocalSweepInterval 本地模式清理空闲频道的最小间隔
+const broadcastLocalSweepInterval = time.Minute
+
+// NewLocalBroadcast 创建本地模式的广播服务，不依赖 Redis
+// Pub 直接投递给本进程的订阅者，序号和历史保存在内存中（每个频道最近 1000 条），
+// WsSub/HttpSub/MissedHandler/GetMetrics 的行为与 Redis 模式一致，Run 只阻塞
+// 仅适用于单实例部署：其他实例的订阅者收不到消息
+func NewLocalBroadcast(cacheSecondsForLated int64) *Broadcast {
+	b := newBroadcast(cacheSecondsForLated, nil)
+	b.local = &localStore{
+		channels: make(map[string]*localChannel),
+		size:     broadcastHistoryMaxLen,
+	}
+	return b
+}
+
+// broadcastModeFromConfig 读取 app 的 broadcast.mode，与 LoadConfig 一样回退到
+// redis.broadcast.mode，默认 redis
+func broadcastModeFromConfig(app string) string {
+	return viper.GetString(configKey(app, "broadcast.mode"))
+}
+
+// localStore 本地模式下各频道的序号和历史
+type localStore struct {
+	mu       sync.Mutex
+	channels map[string]*localChannel
+	size     int       // 每个频道保留的历史消息数
+	swept    time.Time // 上次清理空闲频道的时间
+}
+
+// localChannel 单个频道的序号计数器和历史环形缓冲
+// mu 同时保证投递顺序与序号一致
+type localChannel struct {
+	mu      sync.Mutex
+	seq     int64
+	last    time.Time // 最后一次发布的时间
+	history []*BroadcastMessage
+	start   int // 最旧一条在 history 中的位置
+}
+
+// channel 返回频道状态，不存在时创建；空闲超过 ttl 的频道与 Redis 模式一样
+// 重新从序号 1 开始
+func (s *localStore) channel(name string, ttl time.Duration) *localChannel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.swept) >= broadcastLocalSweepInterval {
+		s.swept = now
+		for k, ch := range s.channels {
+			ch.mu.Lock()
+			idle := now.Sub(ch.last) > ttl
+			ch.mu.Unlock()
+			if idle {
+				delete(s.channels, k)
+			}
+		}
+	}
+	ch, ok := s.channels[name]
+	if !ok {
+		ch = &localChannel{}
+		s.channels[name] = ch
+	}
+	return ch
+}
+
+// lookup 返回已存在的频道状态
+func (s *localStore) lookup(name string) (*localChannel, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch, ok := s.channels[name]
+	return ch, ok
+}
+
+// push 写入历史，满时覆盖最旧的消息。需持有 mu
+func (c *localChannel) push(message *BroadcastMessage, size int) {
+	if len(c.history) < size {
+		c.history = append(c.history, message)
+		return
+	}
+	c.history[c.start] = message
+	c.start = (c.start + 1) % len(c.history)
+}
+
+// messages 按序号升序返回满足 keep 的历史消息
+func (c *localChannel) messages(keep func(*BroadcastMessage) bool) []*BroadcastMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var messages []*BroadcastMessage
+	for i := range c.history {
+		message := c.history[(c.start+i)%len(c.history)]
+		if keep(message) {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// pubLocal 本地模式的 Pub：分配序号、写入历史并投递给本进程的订阅者
+// 返回投递成功的订阅者数
+func (b *Broadcast) pubLocal(message *BroadcastMessage) int {
+	startTime := time.Now()
+	ch := b.local.channel(message.Channel, b.seqTTL)
+
+	ch.mu.Lock()
+	if !ch.last.IsZero() && startTime.Sub(ch.last) > b.seqTTL {
+		ch.seq, ch.history, ch.start = 0, nil, 0
+	}
+	ch.seq++
+	ch.last = startTime
+	message.Seq = ch.seq
+	if !message.noCache {
+		ch.push(message, b.local.size)
+	}
+	delivered := b.deliverLocal(message)
+	ch.mu.Unlock()
+
+	b.observeFanout(time.Since(startTime))
+	b.metrics.messagesSent.Add(1)
+	return delivered
+}
+
+// historyLocal 本地模式的 History
+func (b *Broadcast) historyLocal(channel string, fromSeq, toSeq int64) []*BroadcastMessage {
+	ch, ok := b.local.lookup(channel)
+	if !ok {
+		return nil
+	}
+	return ch.messages(func(m *BroadcastMessage) bool {
+		return m.Seq >= fromSeq && (toSeq <= 0 || m.Seq <= toSeq)
+	})
+}
+
+// recentLocal 本地模式的 recent
+func (b *Broadcast) recentLocal(channel string, since int64) []*BroadcastMessage {
+	ch, ok := b.local.lookup(channel)
+	if !ok {
+		return nil
+	}
+	cutoff := time.Now().Add(-time.Duration(b.cacheSecondsForLated) * time.Second).UnixMilli()
+	messages := ch.messages(func(m *BroadcastMessage) bool {
+		return m.Timestamp > since && m.Timestamp >= cutoff
+	})
+	if len(messages) > broadcastReplayMax {
+		messages = messages[len(messages)-broadcastReplayMax:]
+	}
+	return messages
+}
@@ -0,0 +1,130 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+func TestLocalBroadcastFromConfig(t *testing.T) {
+	viper.Set("redis.broadcast.mode", BroadcastModeLocal)
+	defer viper.Set("redis.broadcast.mode", nil)
+
+	// 不访问 Redis：未配置 redis.addr 时 Client() 会 panic
+	clientOnce = sync.Once{}
+	defaultClient = nil
+	clientOnce.Do(func() {})
+	t.Cleanup(setupTestRedis)
+
+	b := NewBroadcast(10)
+	if b.local == nil {
+		t.Fatal("expected local mode from redis.broadcast.mode")
+	}
+	go b.Run() // 本地模式下只阻塞
+
+	sub, subscribers := b.subscribe("room")
+	defer b.unsubscribe("room", sub, subscribers)
+	if err := b.Pub(context.Background(), "room", "hello"); err != nil {
+		t.Fatalf("Pub failed: %v", err)
+	}
+	select {
+	case msg := <-sub.ch:
+		if msg.Seq != 1 || msg.Payload != "hello" {
+			t.Errorf("unexpected message: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not delivered")
+	}
+}
+
+func TestBroadcastModePerApp(t *testing.T) {
+	viper.Set("chat.redis.broadcast.mode", BroadcastModeLocal)
+	defer viper.Set("chat.redis.broadcast.mode", nil)
+
+	if b := NewBroadcastFor("chat", 10); b.local == nil {
+		t.Error("expected local mode from chat.redis.broadcast.mode")
+	}
+	if mode := broadcastModeFromConfig(""); mode == BroadcastModeLocal {
+		t.Error("an app's mode must not apply to NewBroadcast")
+	}
+
+	viper.Set("redis.broadcast.mode", BroadcastModeLocal)
+	defer viper.Set("redis.broadcast.mode", nil)
+	if mode := broadcastModeFromConfig("orders"); mode != BroadcastModeLocal {
+		t.Errorf("expected fallback to redis.broadcast.mode, got %q", mode)
+	}
+}
+
+func TestLocalBroadcastReplay(t *testing.T) {
+	b := NewLocalBroadcast(10)
+	ctx := context.Background()
+	for i := 1; i <= 3; i++ {
+		b.Pub(ctx, "room", i)
+	}
+	b.Pub(ctx, "room", "typing", NoCache())
+	b.Pub(ctx, "room", 5)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/sub/:channel", b.HttpSub("channel"))
+	r.GET("/missed/:channel", b.MissedHandler("channel"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/sub/room", nil))
+	var resp broadcastResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Code != 0 || len(resp.Data) != 4 || resp.Data[3].Seq != 5 {
+		t.Fatalf("expected the 4 cached messages, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/sub/room?last_seq=3", nil))
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Data) != 1 || resp.Data[0].Seq != 5 {
+		t.Fatalf("expected seq 5 after last_seq 3, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/missed/room?from=2&to=3", nil))
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Data) != 2 || resp.Data[0].Seq != 2 || resp.Data[1].Seq != 3 {
+		t.Fatalf("expected seqs 2,3, got %s", w.Body.String())
+	}
+}
+
+func TestLocalBroadcastRingBuffer(t *testing.T) {
+	b := NewLocalBroadcast(10)
+	b.local.size = 3
+	ctx := context.Background()
+	for i := 1; i <= 5; i++ {
+		b.Pub(ctx, "room", i)
+	}
+
+	messages, _ := b.History(ctx, "room", 1, 0)
+	if len(messages) != 3 || messages[0].Seq != 3 || messages[2].Seq != 5 {
+		t.Fatalf("expected the last 3 messages in order, got %+v", messages)
+	}
+	if recent, _ := b.recent(ctx, "room", messages[0].Timestamp-1); len(recent) != 3 {
+		t.Errorf("expected 3 recent messages, got %d", len(recent))
+	}
+	if messages, _ := b.History(ctx, "other", 1, 0); len(messages) != 0 {
+		t.Errorf("unknown channel should have no history, got %+v", messages)
+	}
+}
+
+func TestLocalBroadcastPubWithAck(t *testing.T) {
+	b := NewLocalBroadcast(10)
+	subscribeLocal(b, "room")
+	subscribeLocal(b, "room")
+
+	result, err := b.PubWithAck(context.Background(), "room", "hi", time.Second)
+	if err != nil || result.Subscribers != 2 || result.Instances != 1 || result.Seq != 1 || result.ID == "" {
+		t.Errorf("unexpected ack result: %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,152 @@
+package redis
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// broadcastOtherChannels 不在白名单中的频道汇总到该 channel 标签值下
+const broadcastOtherChannels = "_other"
+
+// broadcastLatencyBuckets 投递延迟直方图的分桶（秒）
+// 投递只是向本实例订阅者的缓冲区写入，通常在毫秒以内
+var broadcastLatencyBuckets = []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1}
+
+// fanoutHistogram 包装投递延迟直方图，ResetMetrics 时整体替换
+type fanoutHistogram struct {
+	prometheus.Histogram
+}
+
+func newFanoutHistogram() *fanoutHistogram {
+	return &fanoutHistogram{prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "broadcast_fanout_latency_seconds",
+		Help:    "Time to deliver a message to the subscribers of this instance.",
+		Buckets: broadcastLatencyBuckets,
+	})}
+}
+
+// observeFanout 记录一条消息的投递耗时
+func (b *Broadcast) observeFanout(d time.Duration) {
+	b.metrics.fanoutLatency.Load().Observe(d.Seconds())
+}
+
+// fanoutAvgMillis 返回投递延迟的平均值（毫秒），供 GetMetrics 的 subscribe_latency
+func (b *Broadcast) fanoutAvgMillis() int64 {
+	var m dto.Metric
+	if err := b.metrics.fanoutLatency.Load().Write(&m); err != nil || m.Histogram.GetSampleCount() == 0 {
+		return 0
+	}
+	return int64(m.Histogram.GetSampleSum() / float64(m.Histogram.GetSampleCount()) * 1000)
+}
+
+// RegisterMetrics 将广播服务指标注册到 Prometheus，Redis 模式和本地模式均可用
+// 指标与 GetMetrics 读取同一组计数器，数值一致：
+//
+//	broadcast_active_channels                   活跃频道数
+//	broadcast_messages_sent_total               投递的消息数
+//	broadcast_messages_dropped_total{reason}    丢弃的消息数，reason 为 buffer_full、outbox_full、receive_error
+//	broadcast_slow_disconnects_total            因连续丢弃被断开的订阅者数
+//	broadcast_degraded_deliveries_total         降级模式下本地投递的消息数
+//	broadcast_messages_republished_total        Redis 恢复后补发的消息数
+//	broadcast_messages_expired_total            超过最大时长未补发的消息数
+//	broadcast_outbox_depth                      待补发队列长度
+//	broadcast_subscriber_count{channel}         频道订阅者数
+//	broadcast_fanout_latency_seconds            投递延迟直方图
+//
+// channels 为单独上报订阅者数的频道白名单，其余频道汇总为 channel="_other"，
+// 避免频道过多时标签基数失控
+// 同一进程有多个实例时，用 prometheus.WrapRegistererWith 加上区分的标签，否则重复注册报错
+//
+// 示例：
+//
+//	broadcast.RegisterMetrics(prometheus.DefaultRegisterer, "announcements", "lobby")
+//	router.GET("/prometheus", gin.WrapH(promhttp.Handler()))
+func (b *Broadcast) RegisterMetrics(reg prometheus.Registerer, channels ...string) error {
+	allow := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		allow[channel] = true
+	}
+	return reg.Register(&broadcastCollector{b: b, channels: allow})
+}
+
+var (
+	broadcastActiveChannelsDesc = prometheus.NewDesc("broadcast_active_channels",
+		"Channels with subscribers on this instance.", nil, nil)
+	broadcastMessagesSentDesc = prometheus.NewDesc("broadcast_messages_sent_total",
+		"Messages delivered to the subscribers of this instance.", nil, nil)
+	broadcastMessagesDroppedDesc = prometheus.NewDesc("broadcast_messages_dropped_total",
+		"Messages dropped, by reason.", []string{"reason"}, nil)
+	broadcastSlowDisconnectsDesc = prometheus.NewDesc("broadcast_slow_disconnects_total",
+		"Subscribers disconnected after too many consecutive drops.", nil, nil)
+	broadcastDegradedDeliveriesDesc = prometheus.NewDesc("broadcast_degraded_deliveries_total",
+		"Messages delivered locally while Redis was unavailable.", nil, nil)
+	broadcastMessagesRepublishedDesc = prometheus.NewDesc("broadcast_messages_republished_total",
+		"Outbox messages republished after Redis recovered.", nil, nil)
+	broadcastMessagesExpiredDesc = prometheus.NewDesc("broadcast_messages_expired_total",
+		"Outbox messages dropped for exceeding the max age.", nil, nil)
+	broadcastOutboxDepthDesc = prometheus.NewDesc("broadcast_outbox_depth",
+		"Messages waiting to be republished.", nil, nil)
+	broadcastSubscriberCountDesc = prometheus.NewDesc("broadcast_subscriber_count",
+		"Subscribers on this instance, by channel.", []string{"channel"}, nil)
+)
+
+// broadcastCollector 在抓取时读取 Broadcast 的计数器
+type broadcastCollector struct {
+	b        *Broadcast
+	channels map[string]bool // subscriber_count 单独上报的频道
+}
+
+func (c *broadcastCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- broadcastActiveChannelsDesc
+	ch <- broadcastMessagesSentDesc
+	ch <- broadcastMessagesDroppedDesc
+	ch <- broadcastSlowDisconnectsDesc
+	ch <- broadcastDegradedDeliveriesDesc
+	ch <- broadcastMessagesRepublishedDesc
+	ch <- broadcastMessagesExpiredDesc
+	ch <- broadcastOutboxDepthDesc
+	ch <- broadcastSubscriberCountDesc
+	c.b.metrics.fanoutLatency.Load().Describe(ch)
+}
+
+func (c *broadcastCollector) Collect(ch chan<- prometheus.Metric) {
+	m := &c.b.metrics
+	gauge := func(desc *prometheus.Desc, v int64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(v), labels...)
+	}
+	counter := func(desc *prometheus.Desc, v int64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(v), labels...)
+	}
+
+	gauge(broadcastActiveChannelsDesc, m.activeChannels.Load())
+	counter(broadcastMessagesSentDesc, m.messagesSent.Load())
+	counter(broadcastMessagesDroppedDesc, m.droppedBufferFull.Load(), "buffer_full")
+	counter(broadcastMessagesDroppedDesc, m.droppedOutboxFull.Load(), "outbox_full")
+	counter(broadcastMessagesDroppedDesc, m.droppedReceiveError.Load(), "receive_error")
+	counter(broadcastSlowDisconnectsDesc, m.slowDisconnects.Load())
+	counter(broadcastDegradedDeliveriesDesc, m.degradedDeliveries.Load())
+	counter(broadcastMessagesRepublishedDesc, m.messagesRepublished.Load())
+	counter(broadcastMessagesExpiredDesc, m.messagesExpired.Load())
+	gauge(broadcastOutboxDepthDesc, c.b.outboxDepth())
+
+	counts := make(map[string]int64, len(c.channels)+1)
+	for channel := range c.channels {
+		counts[channel] = 0
+	}
+	counts[broadcastOtherChannels] = 0
+	c.b.channels.Range(func(key, value interface{}) bool {
+		channel := key.(string)
+		if !c.channels[channel] {
+			channel = broadcastOtherChannels
+		}
+		counts[channel] += value.(*ChannelSubscribers).count()
+		return true
+	})
+	for channel, n := range counts {
+		gauge(broadcastSubscriberCountDesc, n, channel)
+	}
+
+	m.fanoutLatency.Load().Collect(ch)
+}
@@ -0,0 +1,157 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// scrape 抓取 registry，返回 name 中标签匹配 labels 的指标
+func scrape(t *testing.T, reg *prometheus.Registry, name string, labels ...string) *dto.Metric {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather failed: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	next:
+		for _, m := range family.GetMetric() {
+			for i := 0; i+1 < len(labels); i += 2 {
+				found := false
+				for _, l := range m.GetLabel() {
+					if l.GetName() == labels[i] && l.GetValue() == labels[i+1] {
+						found = true
+					}
+				}
+				if !found {
+					continue next
+				}
+			}
+			return m
+		}
+	}
+	t.Fatalf("metric %s%v not found", name, labels)
+	return nil
+}
+
+func counterValue(t *testing.T, reg *prometheus.Registry, name string, labels ...string) float64 {
+	t.Helper()
+	return scrape(t, reg, name, labels...).GetCounter().GetValue()
+}
+
+func gaugeValue(t *testing.T, reg *prometheus.Registry, name string, labels ...string) float64 {
+	t.Helper()
+	return scrape(t, reg, name, labels...).GetGauge().GetValue()
+}
+
+// jsonMetrics 调用 GetMetrics 返回的 JSON
+func jsonMetrics(t *testing.T, b *Broadcast) map[string]int64 {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	b.GetMetrics(c)
+	var metrics map[string]int64
+	if err := json.Unmarshal(w.Body.Bytes(), &metrics); err != nil {
+		t.Fatalf("invalid metrics JSON: %v", err)
+	}
+	return metrics
+}
+
+func TestBroadcastPrometheusMetricsLocal(t *testing.T) {
+	b := NewLocalBroadcast(10)
+	b.SetSubscriberBuffer(1, 0)
+	reg := prometheus.NewRegistry()
+	if err := b.RegisterMetrics(reg, "lobby"); err != nil {
+		t.Fatalf("RegisterMetrics failed: %v", err)
+	}
+	if err := b.RegisterMetrics(reg); err == nil {
+		t.Error("expected error registering twice")
+	}
+
+	subscribeLocal(b, "lobby")
+	subscribeLocal(b, "lobby")
+	subscribeLocal(b, "room-1")
+	subscribeLocal(b, "room-2")
+	if gaugeValue(t, reg, "broadcast_subscriber_count", "channel", "lobby") != 2 ||
+		gaugeValue(t, reg, "broadcast_subscriber_count", "channel", "_other") != 2 {
+		t.Error("expected allowlisted channel reported alone, the rest as _other")
+	}
+
+	ctx := context.Background()
+	b.Pub(ctx, "lobby", "a")
+	b.Pub(ctx, "lobby", "b") // 缓冲区为 1，每个订阅者丢弃一条
+	b.Pub(ctx, "room-1", "c")
+
+	if v := counterValue(t, reg, "broadcast_messages_sent_total"); v != 3 {
+		t.Errorf("expected 3 sent, got %v", v)
+	}
+	if v := counterValue(t, reg, "broadcast_messages_dropped_total", "reason", "buffer_full"); v != 2 {
+		t.Errorf("expected 2 dropped for buffer_full, got %v", v)
+	}
+	if v := gaugeValue(t, reg, "broadcast_active_channels"); v != 3 {
+		t.Errorf("expected 3 active channels, got %v", v)
+	}
+	if n := scrape(t, reg, "broadcast_fanout_latency_seconds").GetHistogram().GetSampleCount(); n != 3 {
+		t.Errorf("expected 3 latency samples, got %d", n)
+	}
+
+	metrics := jsonMetrics(t, b)
+	if metrics["messages_sent"] != 3 || metrics["messages_dropped"] != 2 || metrics["dropped_buffer_full"] != 2 {
+		t.Errorf("GetMetrics disagrees with Prometheus: %v", metrics)
+	}
+
+	b.ResetMetrics()
+	if counterValue(t, reg, "broadcast_messages_sent_total") != 0 ||
+		scrape(t, reg, "broadcast_fanout_latency_seconds").GetHistogram().GetSampleCount() != 0 {
+		t.Error("expected ResetMetrics to reset the exported counters")
+	}
+}
+
+func TestBroadcastPrometheusMetricsRun(t *testing.T) {
+	b, channel := setupTestBroadcast(t)
+	reg := prometheus.NewRegistry()
+	if err := b.RegisterMetrics(reg, channel); err != nil {
+		t.Fatalf("RegisterMetrics failed: %v", err)
+	}
+	go b.Run()
+	ch := subscribeLocal(b, channel)
+	time.Sleep(100 * time.Millisecond) // 等待 Run 完成订阅
+
+	before := counterValue(t, reg, "broadcast_messages_sent_total")
+	ctx := context.Background()
+	for range 3 {
+		if err := b.Pub(ctx, channel, "hello"); err != nil {
+			t.Fatalf("Pub failed: %v", err)
+		}
+		if msg := receive(t, ch, time.Second); msg == nil {
+			t.Fatal("message not delivered")
+		}
+	}
+
+	// Run 投递后才计数，消息到达订阅者时可能还未更新
+	deadline := time.Now().Add(time.Second)
+	for counterValue(t, reg, "broadcast_messages_sent_total") < before+3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if v := counterValue(t, reg, "broadcast_messages_sent_total"); v < before+3 {
+		t.Errorf("expected at least %v sent, got %v", before+3, v)
+	}
+	if v := gaugeValue(t, reg, "broadcast_subscriber_count", "channel", channel); v != 1 {
+		t.Errorf("expected 1 subscriber, got %v", v)
+	}
+	if n := scrape(t, reg, "broadcast_fanout_latency_seconds").GetHistogram().GetSampleCount(); n < 3 {
+		t.Errorf("expected latency samples for each delivery, got %d", n)
+	}
+	if metrics := jsonMetrics(t, b); float64(metrics["messages_sent"]) != counterValue(t, reg, "broadcast_messages_sent_total") {
+		t.Errorf("GetMetrics disagrees with Prometheus: %v", metrics)
+	}
+}
@@ -0,0 +1,656 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupTestBroadcast(t *testing.T) (*Broadcast, string) {
+	t.Helper()
+	if os.Getenv("REDIS_TEST_SKIP") != "" {
+		t.Skip("Skipping Redis tests (REDIS_TEST_SKIP is set)")
+	}
+
+	setupTestRedis()
+	if err := Client().Ping(context.Background()).Err(); err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+
+	b := NewBroadcast(10)
+	channel := fmt.Sprintf("test_%s_%d", t.Name(), time.Now().UnixNano())
+	t.Cleanup(func() {
+		Client().Del(context.Background(), b.seqKey(channel), b.historyKey(channel))
+	})
+	return b, channel
+}
+
+type broadcastResponse struct {
+	Code int                 `json:"code"`
+	Data []*BroadcastMessage `json:"data"`
+}
+
+func TestBroadcastSeqMonotonic(t *testing.T) {
+	b, channel := setupTestBroadcast(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := b.Pub(ctx, channel, map[string]int{"i": i}); err != nil {
+				t.Errorf("Pub failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	messages, err := b.History(ctx, channel, 1, 0)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(messages) != 50 {
+		t.Fatalf("expected 50 messages, got %d", len(messages))
+	}
+	for i, msg := range messages {
+		if msg.Seq != int64(i+1) {
+			t.Fatalf("expected seq %d at position %d, got %d", i+1, i, msg.Seq)
+		}
+		if msg.Channel != channel {
+			t.Errorf("unexpected channel: %s", msg.Channel)
+		}
+	}
+}
+
+func TestBroadcastCluster(t *testing.T) {
+	cluster := setupTestCluster(t)
+	ctx := context.Background()
+	b := newBroadcast(10, cluster)
+	channel := fmt.Sprintf("test_%s_%d", t.Name(), time.Now().UnixNano())
+	defer cluster.Del(ctx, b.seqKey(channel), b.historyKey(channel))
+
+	// The script only touches keys of one slot
+	if keySlot(b.seqKey(channel)) != keySlot(b.historyKey(channel)) {
+		t.Fatal("seq and history keys must share a hash slot")
+	}
+	for i := range 2 {
+		if err := b.Pub(ctx, channel, i); err != nil {
+			t.Fatalf("Pub failed: %v", err)
+		}
+	}
+	messages, err := b.History(ctx, channel, 1, 0)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Seq != 1 || messages[1].Seq != 2 {
+		t.Errorf("unexpected history %+v", messages)
+	}
+}
+
+func TestBroadcastMissedHandler(t *testing.T) {
+	b, channel := setupTestBroadcast(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 10; i++ {
+		if err := b.Pub(ctx, channel, i); err != nil {
+			t.Fatalf("Pub failed: %v", err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/missed/:channel", b.MissedHandler("channel"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/missed/"+channel+"?from=8&to=9", nil))
+
+	var resp struct {
+		Code int                 `json:"code"`
+		Data []*BroadcastMessage `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Code != 0 || len(resp.Data) != 2 {
+		t.Fatalf("expected 2 messages, got code=%d body=%s", resp.Code, w.Body.String())
+	}
+	if resp.Data[0].Seq != 8 || resp.Data[1].Seq != 9 {
+		t.Errorf("expected seqs 8,9, got %d,%d", resp.Data[0].Seq, resp.Data[1].Seq)
+	}
+	if resp.Data[0].Payload.(float64) != 8 {
+		t.Errorf("expected payload 8, got %v", resp.Data[0].Payload)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/missed/"+channel, nil))
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Code != 400 {
+		t.Errorf("expected 400 without from, got %d", resp.Code)
+	}
+}
+
+func TestBroadcastResumeBySeq(t *testing.T) {
+	b, channel := setupTestBroadcast(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		b.Pub(ctx, channel, i)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/sub/:channel", b.HttpSub("channel"))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/sub/"+channel, nil)
+	req.Header.Set("Last-Event-ID", "1")
+	r.ServeHTTP(w, req)
+
+	var resp broadcastResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Code != 0 || len(resp.Data) != 2 || resp.Data[0].Seq != 2 || resp.Data[1].Seq != 3 {
+		t.Fatalf("expected seqs 2,3 after Last-Event-ID 1, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/sub/"+channel+"?last_seq=2", nil))
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Data) != 1 || resp.Data[0].Seq != 3 {
+		t.Fatalf("expected seq 3 after last_seq 2, got %s", w.Body.String())
+	}
+}
+
+func TestBroadcastReplayCached(t *testing.T) {
+	b, channel := setupTestBroadcast(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		b.Pub(ctx, channel, i)
+	}
+	b.Pub(ctx, channel, "typing", NoCache())
+	messages, _ := b.History(ctx, channel, 1, 0)
+	if len(messages) != 3 {
+		t.Fatalf("NoCache message should not be stored, got %d messages", len(messages))
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/sub/:channel", b.HttpSub("channel"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/sub/"+channel, nil))
+	var resp broadcastResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Code != 0 || len(resp.Data) != 3 {
+		t.Fatalf("expected all 3 cached messages, got %s", w.Body.String())
+	}
+	for i, msg := range resp.Data {
+		if msg.Seq != int64(i+1) {
+			t.Errorf("expected seq %d at position %d, got %d", i+1, i, msg.Seq)
+		}
+	}
+
+	// 只返回 since 之后的消息
+	Client().XAdd(ctx, &redis.XAddArgs{
+		Stream: b.historyKey(channel),
+		ID:     "10-0",
+		Values: map[string]interface{}{
+			"data": fmt.Sprintf(`{"channel":%q,"seq":10,"timestamp":%d,"payload":"late"}`,
+				channel, resp.Data[2].Timestamp+1),
+		},
+	})
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET",
+		fmt.Sprintf("/sub/%s?since=%d", channel, resp.Data[2].Timestamp), nil))
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Data) != 1 || resp.Data[0].Seq != 10 {
+		t.Fatalf("expected only seq 10 after since, got %s", w.Body.String())
+	}
+}
+
+func TestBroadcastSeqTTL(t *testing.T) {
+	b, channel := setupTestBroadcast(t)
+	ctx := context.Background()
+
+	if err := b.Pub(ctx, channel, "x"); err != nil {
+		t.Fatalf("Pub failed: %v", err)
+	}
+
+	for _, key := range []string{b.seqKey(channel), b.historyKey(channel)} {
+		ttl := Client().TTL(ctx, key).Val()
+		if ttl <= 0 || ttl > broadcastSeqTTL {
+			t.Errorf("expected ttl within (0, %v] for %s, got %v", broadcastSeqTTL, key, ttl)
+		}
+	}
+
+	// An expired counter restarts the channel from 1
+	Client().Del(ctx, b.seqKey(channel), b.historyKey(channel))
+	b.Pub(ctx, channel, "y")
+	messages, _ := b.History(ctx, channel, 1, 0)
+	if len(messages) != 1 || messages[0].Seq != 1 {
+		t.Errorf("expected restart from seq 1, got %+v", messages)
+	}
+}
+
+// flakyProxy 转发到真实 Redis 的 TCP 代理，用于模拟 Redis 宕机和恢复
+type flakyProxy struct {
+	t      *testing.T
+	target string
+	addr   string
+	mu     sync.Mutex
+	ln     net.Listener
+	conns  []net.Conn
+}
+
+func newFlakyProxy(t *testing.T, target string) *flakyProxy {
+	p := &flakyProxy{t: t, target: target, addr: "127.0.0.1:0"}
+	p.restore()
+	p.addr = p.ln.Addr().String()
+	t.Cleanup(p.kill)
+	return p
+}
+
+func (p *flakyProxy) restore() {
+	ln, err := net.Listen("tcp", p.addr)
+	if err != nil {
+		p.t.Fatalf("proxy listen failed: %v", err)
+	}
+	p.mu.Lock()
+	p.ln = ln
+	p.mu.Unlock()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			upstream, err := net.Dial("tcp", p.target)
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			p.mu.Lock()
+			p.conns = append(p.conns, conn, upstream)
+			p.mu.Unlock()
+			go io.Copy(upstream, conn)
+			go io.Copy(conn, upstream)
+		}
+	}()
+}
+
+func (p *flakyProxy) kill() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ln.Close()
+	for _, c := range p.conns {
+		c.Close()
+	}
+	p.conns = nil
+}
+
+// newDegradableBroadcast 返回经由代理连接 Redis 的广播实例
+func newDegradableBroadcast(t *testing.T) (*Broadcast, *flakyProxy) {
+	proxy := newFlakyProxy(t, "localhost:6379")
+	b := NewBroadcast(10)
+	b.rds = redis.NewClient(&redis.Options{
+		Addr:         proxy.addr,
+		MaxRetries:   -1,
+		DialTimeout:  200 * time.Millisecond,
+		ReadTimeout:  200 * time.Millisecond,
+		WriteTimeout: 200 * time.Millisecond,
+	})
+	b.recoveryInterval = 20 * time.Millisecond
+	t.Cleanup(func() { b.rds.Close() })
+	return b, proxy
+}
+
+func subscribeLocal(b *Broadcast, channel string) chan *BroadcastMessage {
+	sub, _ := b.subscribe(channel)
+	return sub.ch
+}
+
+func receive(t *testing.T, ch chan *BroadcastMessage, timeout time.Duration) *BroadcastMessage {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(timeout):
+		return nil
+	}
+}
+
+func TestBroadcastDegradedMode(t *testing.T) {
+	remote, channel := setupTestBroadcast(t)
+	go remote.Run()
+	remoteCh := subscribeLocal(remote, channel)
+	time.Sleep(100 * time.Millisecond) // 等待 Run 完成订阅
+
+	local, proxy := newDegradableBroadcast(t)
+	localCh := subscribeLocal(local, channel)
+	ctx := context.Background()
+
+	proxy.kill()
+	for i := 1; i <= 3; i++ {
+		if err := local.Pub(ctx, channel, i); !errors.Is(err, ErrPubQueued) {
+			t.Fatalf("expected ErrPubQueued during outage, got %v", err)
+		}
+		msg := receive(t, localCh, time.Second)
+		if msg == nil || msg.Payload != i || msg.Seq != 0 || msg.Delayed {
+			t.Fatalf("expected local delivery of %d, got %+v", i, msg)
+		}
+	}
+	if msg := receive(t, remoteCh, 100*time.Millisecond); msg != nil {
+		t.Fatalf("remote instance should not receive during outage, got %+v", msg)
+	}
+	if local.metrics.degradedDeliveries.Load() != 3 || local.outboxDepth() != 3 {
+		t.Errorf("unexpected metrics: degraded=%d depth=%d",
+			local.metrics.degradedDeliveries.Load(), local.outboxDepth())
+	}
+
+	proxy.restore()
+
+	var lastSeq int64
+	for i := 1; i <= 3; i++ {
+		msg := receive(t, remoteCh, 2*time.Second)
+		if msg == nil {
+			t.Fatalf("expected republished message %d", i)
+		}
+		if msg.Payload.(float64) != float64(i) || !msg.Delayed || msg.Seq <= lastSeq {
+			t.Fatalf("expected ordered delayed message %d, got %+v", i, msg)
+		}
+		lastSeq = msg.Seq
+	}
+	// 消息补发成功后才出队，等待队列清空
+	for deadline := time.Now().Add(time.Second); local.outboxDepth() > 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if local.metrics.messagesRepublished.Load() != 3 || local.outboxDepth() != 0 {
+		t.Errorf("unexpected metrics: republished=%d depth=%d",
+			local.metrics.messagesRepublished.Load(), local.outboxDepth())
+	}
+
+	// 恢复后直接发布
+	if err := local.Pub(ctx, channel, 4); err != nil {
+		t.Fatalf("Pub after recovery failed: %v", err)
+	}
+	if msg := receive(t, remoteCh, 2*time.Second); msg == nil || msg.Delayed || msg.Payload.(float64) != 4 {
+		t.Errorf("expected live message 4, got %+v", msg)
+	}
+}
+
+func TestBroadcastOutboxExpiry(t *testing.T) {
+	remote, channel := setupTestBroadcast(t)
+	go remote.Run()
+	remoteCh := subscribeLocal(remote, channel)
+	time.Sleep(100 * time.Millisecond)
+
+	local, proxy := newDegradableBroadcast(t)
+	local.SetOutbox(10, 100*time.Millisecond)
+	ctx := context.Background()
+
+	proxy.kill()
+	local.Pub(ctx, channel, "old")
+	time.Sleep(150 * time.Millisecond)
+	local.Pub(ctx, channel, "new")
+	proxy.restore()
+
+	msg := receive(t, remoteCh, 2*time.Second)
+	if msg == nil || msg.Payload != "new" {
+		t.Fatalf("expected only the fresh message, got %+v", msg)
+	}
+	if extra := receive(t, remoteCh, 100*time.Millisecond); extra != nil {
+		t.Errorf("expired message should not be republished, got %+v", extra)
+	}
+	if local.metrics.messagesExpired.Load() != 1 {
+		t.Errorf("expected 1 expired message, got %d", local.metrics.messagesExpired.Load())
+	}
+}
+
+// pushDuringEval 在第一次执行脚本时调用 push，模拟补发期间的并发发布
+type pushDuringEval struct {
+	once sync.Once
+	push func()
+}
+
+func (h *pushDuringEval) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *pushDuringEval) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if name := cmd.Name(); name == "evalsha" || name == "eval" {
+			h.once.Do(h.push)
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *pushDuringEval) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestBroadcastFlushOutboxFull(t *testing.T) {
+	_, channel := setupTestBroadcast(t)
+	rds := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer rds.Close()
+	b := newBroadcast(10, rds)
+	b.SetOutbox(1, 0)
+
+	first := newBroadcastMessage(channel, "first", nil)
+	second := newBroadcastMessage(channel, "second", nil)
+	b.outbox, b.recovering = []*BroadcastMessage{first}, true
+	// 补发 first 期间队列已满，second 挤掉 first
+	rds.AddHook(&pushDuringEval{push: func() {
+		b.outboxMu.Lock()
+		b.pushOutbox(second)
+		b.outboxMu.Unlock()
+	}})
+
+	if !b.flushOutbox() {
+		t.Fatal("flushOutbox failed")
+	}
+	messages, err := b.History(context.Background(), channel, 1, 0)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Payload != "first" || messages[1].Payload != "second" {
+		t.Errorf("the message pushed during republish must not be lost, got %+v", messages)
+	}
+}
+
+// failPublish 让第一次 PUBLISH 失败，模拟脚本成功后发布失败
+type failPublish struct{ failed atomic.Bool }
+
+func (h *failPublish) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *failPublish) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "publish" && h.failed.CompareAndSwap(false, true) {
+			err := errors.New("connection reset")
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *failPublish) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestBroadcastRepublishKeepsSeq(t *testing.T) {
+	remote, channel := setupTestBroadcast(t)
+	go remote.Run()
+	remoteCh := subscribeLocal(remote, channel)
+	time.Sleep(100 * time.Millisecond)
+
+	local, _ := newDegradableBroadcast(t)
+	local.rds.AddHook(&failPublish{})
+	if err := local.Pub(context.Background(), channel, "once"); !errors.Is(err, ErrPubQueued) {
+		t.Fatalf("expected ErrPubQueued, got %v", err)
+	}
+
+	msg := receive(t, remoteCh, 2*time.Second)
+	if msg == nil || msg.Payload != "once" || !msg.Delayed {
+		t.Fatalf("expected the republished message, got %+v", msg)
+	}
+	messages, err := local.History(context.Background(), channel, 1, 0)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	// 历史已在第一次发布时写入，补发沿用原序号
+	if len(messages) != 1 || messages[0].Seq != msg.Seq {
+		t.Errorf("history should hold the message once with seq %d, got %+v", msg.Seq, messages)
+	}
+}
+
+func TestBroadcastPubFailureWithoutOutbox(t *testing.T) {
+	_, channel := setupTestBroadcast(t)
+	local, proxy := newDegradableBroadcast(t)
+	local.SetOutbox(0, 0)
+	localCh := subscribeLocal(local, channel)
+
+	proxy.kill()
+	err := local.Pub(context.Background(), channel, "x")
+	if err == nil || errors.Is(err, ErrPubQueued) {
+		t.Fatalf("expected total failure with degraded mode off, got %v", err)
+	}
+	if msg := receive(t, localCh, 50*time.Millisecond); msg != nil {
+		t.Errorf("message should not be delivered, got %+v", msg)
+	}
+}
+
+func TestBroadcastSkipsOwnRepublished(t *testing.T) {
+	b, channel := setupTestBroadcast(t)
+	go b.Run()
+	ch := subscribeLocal(b, channel)
+	time.Sleep(100 * time.Millisecond)
+
+	ctx := context.Background()
+	own := &BroadcastMessage{Channel: channel, Payload: "own", Delayed: true, Origin: b.instanceID}
+	head, tail, _ := encodeMessage(own)
+	b.publishEncoded(ctx, channel, head, tail, true)
+	b.Pub(ctx, channel, "other")
+
+	msg := receive(t, ch, 2*time.Second)
+	if msg == nil || msg.Payload != "other" {
+		t.Errorf("own republished message should be skipped, got %+v", msg)
+	}
+}
+
+func TestBroadcastSlowSubscriberDoesNotBlock(t *testing.T) {
+	b, channel := setupTestBroadcast(t)
+	b.SetSubscriberBuffer(2, 0)
+
+	slow := subscribeLocal(b, channel)
+	fast := subscribeLocal(b, channel)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 5 {
+			b.deliverLocal(&BroadcastMessage{Channel: channel, Seq: int64(i + 1)})
+			if msg := receive(t, fast, time.Second); msg == nil || msg.Seq != int64(i+1) {
+				t.Errorf("fast subscriber missed message %d: %+v", i+1, msg)
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("delivery blocked on slow subscriber")
+	}
+
+	if len(slow) != 2 {
+		t.Errorf("expected slow subscriber buffer to hold 2 messages, got %d", len(slow))
+	}
+	if b.metrics.messagesDropped.Load() != 3 || b.metrics.droppedBufferFull.Load() != 3 {
+		t.Errorf("expected 3 dropped messages, got %d (buffer full %d)",
+			b.metrics.messagesDropped.Load(), b.metrics.droppedBufferFull.Load())
+	}
+}
+
+func TestBroadcastDisconnectSlowSubscriber(t *testing.T) {
+	b, channel := setupTestBroadcast(t)
+	b.SetSubscriberBuffer(1, 2)
+
+	slow := subscribeLocal(b, channel)
+	for i := range 3 {
+		b.deliverLocal(&BroadcastMessage{Channel: channel, Seq: int64(i + 1)})
+	}
+
+	if msg, ok := <-slow; !ok || msg.Seq != 1 {
+		t.Fatalf("expected buffered message 1, got %+v", msg)
+	}
+	if _, ok := <-slow; ok {
+		t.Fatal("slow subscriber should be disconnected")
+	}
+	if b.metrics.slowDisconnects.Load() != 1 || b.metrics.activeChannels.Load() != 0 {
+		t.Errorf("expected 1 disconnect and no active channels, got %d, %d",
+			b.metrics.slowDisconnects.Load(), b.metrics.activeChannels.Load())
+	}
+
+	// 断开后的投递不再发往该订阅者
+	b.deliverLocal(&BroadcastMessage{Channel: channel, Seq: 4})
+}
+
+func TestBroadcastPubWithAck(t *testing.T) {
+	b1, channel := setupTestBroadcast(t)
+	b2 := NewBroadcast(10)
+	go b1.Run()
+	go b2.Run()
+	ch1a := subscribeLocal(b1, channel)
+	ch1b := subscribeLocal(b1, channel)
+	ch2 := subscribeLocal(b2, channel)
+	time.Sleep(100 * time.Millisecond)
+
+	ctx := context.Background()
+	start := time.Now()
+	result, err := b1.PubWithAck(ctx, channel, "critical", 5*time.Second)
+	if err != nil {
+		t.Fatalf("PubWithAck failed: %v", err)
+	}
+	// 所有实例回执后提前返回，其他测试的实例回执 0 个订阅者
+	if result.Subscribers != 3 || result.Instances < 2 || result.Instances != result.Expected {
+		t.Errorf("expected 3 subscribers from all instances, got %+v", result)
+	}
+	if time.Since(start) > 4*time.Second || result.Seq != 1 {
+		t.Errorf("expected early return with seq 1, got %+v after %v", result, time.Since(start))
+	}
+	for _, ch := range []chan *BroadcastMessage{ch1a, ch1b, ch2} {
+		if msg := receive(t, ch, time.Second); msg == nil || msg.ID != result.ID || !msg.Ack {
+			t.Errorf("expected message %s, got %+v", result.ID, msg)
+		}
+	}
+
+	// 订阅了广播但不回执的实例：等到超时，只汇总已回执的实例
+	silent := Client().Subscribe(ctx, b1.broadcastKey())
+	defer silent.Close()
+	silent.Receive(ctx)
+	result, err = b2.PubWithAck(ctx, channel, "critical", 300*time.Millisecond)
+	if err != nil || result.Subscribers != 3 || result.Instances != result.Expected-1 {
+		t.Errorf("expected a missing ack after timeout, got %+v, %v", result, err)
+	}
+}
+
+func TestBroadcastMessageID(t *testing.T) {
+	b, channel := setupTestBroadcast(t)
+	ctx := context.Background()
+	b.Pub(ctx, channel, "a")
+	b.Pub(ctx, channel, "b")
+
+	messages, _ := b.History(ctx, channel, 1, 0)
+	if len(messages) != 2 || messages[0].ID == "" || messages[0].ID == messages[1].ID || messages[0].Ack {
+		t.Errorf("expected unique message IDs, got %+v", messages)
+	}
+}
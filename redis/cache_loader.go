@@ -0,0 +1,137 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// staleKeySuffix names the shadow key holding the last loaded value when
+// stale-while-revalidate is enabled.
+const staleKeySuffix = ":stale"
+
+// cacheLoadTimeout bounds a load, which does not use the caller's deadline.
+const cacheLoadTimeout = time.Minute
+
+// loadGroup deduplicates concurrent loads of the same key in this process.
+var loadGroup singleflight.Group
+
+// CacheLoader loads the value to cache on a miss. The value is stored as JSON.
+type CacheLoader func(ctx context.Context) (any, error)
+
+// CacheOption configures CacheGetOrSet
+type CacheOption func(*cacheOptions)
+
+type cacheOptions struct {
+	staleSeconds int
+}
+
+// StaleWhileRevalidate keeps the last loaded value under a shadow key
+// (StaleKey) for ttl+seconds. After the cached value expires, the shadow
+// value is served while a single goroutine of this process reloads it in the
+// background. Load errors in the background are logged and the shadow value
+// keeps being served until it expires too.
+func StaleWhileRevalidate(seconds int) CacheOption {
+	return func(o *cacheOptions) {
+		o.staleSeconds = seconds
+	}
+}
+
+// StaleKey returns the shadow key used by StaleWhileRevalidate for key.
+// Deleting key alone does not drop the stale value; delete both to force a
+// foreground reload.
+func StaleKey(key string) string {
+	return key + staleKeySuffix
+}
+
+// CacheGetOrSet reads key into dest. On a miss, loader runs once per process
+// for concurrent callers of the same key; its result is cached for ttl
+// seconds and decoded into dest for every caller. The loader gets a context
+// without the callers' cancellation, bounded by a one minute timeout, so a
+// caller that gives up returns ctx.Err() without failing the others.
+//
+// The cache is best effort: Redis read errors are treated as a miss and write
+// errors are ignored, so only loader and decoding errors are returned.
+//
+// Example:
+//
+//	var user User
+//	err := redis.CacheGetOrSet(ctx, "user:1", 300, &user, func(ctx context.Context) (any, error) {
+//	    return db.FindUser(ctx, 1)
+//	}, redis.StaleWhileRevalidate(3600))
+func CacheGetOrSet(ctx context.Context, key string, ttl int, dest any, loader CacheLoader, opts ...CacheOption) error {
+	var o cacheOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	client := Client()
+	if data, err := client.Get(ctx, key).Bytes(); err == nil {
+		return json.Unmarshal(data, dest)
+	}
+
+	if o.staleSeconds > 0 {
+		if data, err := client.Get(ctx, StaleKey(key)).Bytes(); err == nil {
+			// Refresh without the caller's deadline; DoChan joins a load
+			// already in flight instead of starting another one.
+			loadGroup.DoChan(key, func() (any, error) {
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheLoadTimeout)
+				defer cancel()
+				data, err := loadAndStore(ctx, key, ttl, o, loader)
+				if err != nil {
+					log.Printf("redis: background refresh of %s failed: %v", key, err)
+				}
+				return data, err
+			})
+			return json.Unmarshal(data, dest)
+		}
+	}
+
+	ch := loadGroup.DoChan(key, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheLoadTimeout)
+		defer cancel()
+		// Another process may have filled the key while we waited.
+		if data, err := client.Get(ctx, key).Bytes(); err == nil {
+			return data, nil
+		}
+		return loadAndStore(ctx, key, ttl, o, loader)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return res.Err
+		}
+		return json.Unmarshal(res.Val.([]byte), dest)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// loadAndStore runs loader and caches its JSON result under key, and under
+// the shadow key when stale-while-revalidate is enabled.
+func loadAndStore(ctx context.Context, key string, ttl int, o cacheOptions, loader CacheLoader) ([]byte, error) {
+	value, err := loader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = Client().Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, data, time.Duration(ttl)*time.Second)
+		if o.staleSeconds > 0 {
+			pipe.Set(ctx, StaleKey(key), data, time.Duration(ttl+o.staleSeconds)*time.Second)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("redis: failed to cache %s: %v", key, err)
+	}
+	return data, nil
+}
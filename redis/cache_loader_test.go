@@ -0,0 +1,169 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func setupLoaderTest(t *testing.T, keys ...string) {
+	t.Helper()
+	if os.Getenv("REDIS_TEST_SKIP") != "" {
+		t.Skip("Skipping Redis tests (REDIS_TEST_SKIP is set)")
+	}
+	setupTestRedis()
+	if err := Client().Ping(context.Background()).Err(); err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+	clean := func() {
+		for _, k := range keys {
+			CacheDel(k)
+			CacheDel(StaleKey(k))
+		}
+	}
+	clean()
+	t.Cleanup(clean)
+}
+
+func TestCacheGetOrSet(t *testing.T) {
+	key := "test_getorset"
+	setupLoaderTest(t, key)
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (any, error) {
+		calls.Add(1)
+		<-release
+		return map[string]int{"n": 42}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]map[string]int, 10)
+	errs := make([]error, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = CacheGetOrSet(ctx, key, 60, &results[i], loader)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader should run once for concurrent callers, ran %d times", n)
+	}
+	for i := range results {
+		if errs[i] != nil || results[i]["n"] != 42 {
+			t.Errorf("caller %d: got %v, %v", i, results[i], errs[i])
+		}
+	}
+
+	// Served from the cache afterwards
+	var cached map[string]int
+	if err := CacheGetOrSet(ctx, key, 60, &cached, loader); err != nil || cached["n"] != 42 {
+		t.Fatalf("cached read: got %v, %v", cached, err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("cached value should not reload, loader ran %d times", n)
+	}
+}
+
+func TestCacheGetOrSetCallerCancel(t *testing.T) {
+	key := "test_getorset_cancel"
+	setupLoaderTest(t, key)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	loader := func(ctx context.Context) (any, error) {
+		close(started)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return 7, nil
+	}
+
+	// The first caller starts the load, then gives up
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		var n int
+		first <- CacheGetOrSet(ctx, key, 60, &n, loader)
+	}()
+	<-started
+	second := make(chan error, 1)
+	var n int
+	go func() { second <- CacheGetOrSet(context.Background(), key, 60, &n, loader) }()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller: expected context.Canceled, got %v", err)
+	}
+	close(release)
+	if err := <-second; err != nil || n != 7 {
+		t.Errorf("waiting caller should get the loaded value, got %d, %v", n, err)
+	}
+}
+
+func TestCacheGetOrSetError(t *testing.T) {
+	key := "test_getorset_error"
+	setupLoaderTest(t, key)
+
+	errLoad := errors.New("load failed")
+	var v string
+	err := CacheGetOrSet(context.Background(), key, 60, &v, func(ctx context.Context) (any, error) {
+		return nil, errLoad
+	})
+	if !errors.Is(err, errLoad) {
+		t.Fatalf("expected loader error, got %v", err)
+	}
+	if exist, _ := CacheGet(key, &v); exist {
+		t.Error("failed load should not be cached")
+	}
+}
+
+func TestCacheGetOrSetStaleWhileRevalidate(t *testing.T) {
+	key := "test_getorset_stale"
+	setupLoaderTest(t, key)
+	ctx := context.Background()
+
+	if err := CacheSet(StaleKey(key), "old", 60); err != nil {
+		t.Fatalf("CacheSet failed: %v", err)
+	}
+
+	refreshed := make(chan struct{})
+	var v string
+	err := CacheGetOrSet(ctx, key, 60, &v, func(ctx context.Context) (any, error) {
+		defer close(refreshed)
+		return "new", nil
+	}, StaleWhileRevalidate(60))
+	if err != nil || v != "old" {
+		t.Fatalf("expired key should serve the stale value, got %q, %v", v, err)
+	}
+
+	select {
+	case <-refreshed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("stale value was not refreshed in the background")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if exist, _ := CacheGet(key, &v); exist && v == "new" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("refreshed value not cached, got %q", v)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if exist, _ := CacheGet(StaleKey(key), &v); !exist || v != "new" {
+		t.Errorf("shadow key should hold the refreshed value, got %q", v)
+	}
+}
@@ -0,0 +1,347 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Counter 计数器写入接口
+// Coalescer 和 DirectCounter 都实现该接口，业务代码只依赖 Counter 即可在两者之间切换
+type Counter interface {
+	IncrBy(key string, delta int64) error
+	HIncrBy(key, field string, delta int64) error
+	Expire(key string, ttl time.Duration) error
+}
+
+// ErrCoalescerClosed Close 之后的写入返回该错误，写入不会生效
+var ErrCoalescerClosed = errors.New("redis: coalescer closed")
+
+// coalescedOps 一批待写入的合并操作
+type coalescedOps struct {
+	incr   map[string]int64
+	hincr  map[string]map[string]int64
+	expire map[string]time.Duration
+}
+
+func newCoalescedOps() *coalescedOps {
+	return &coalescedOps{
+		incr:   make(map[string]int64),
+		hincr:  make(map[string]map[string]int64),
+		expire: make(map[string]time.Duration),
+	}
+}
+
+// size returns the number of distinct commands the batch will produce
+func (o *coalescedOps) size() int {
+	n := len(o.incr) + len(o.expire)
+	for _, fields := range o.hincr {
+		n += len(fields)
+	}
+	return n
+}
+
+// merge folds an older batch back into o. Deltas are summed; for expire the
+// value already in o is newer and wins.
+func (o *coalescedOps) merge(old *coalescedOps) {
+	for key, delta := range old.incr {
+		o.incr[key] += delta
+	}
+	for key, fields := range old.hincr {
+		if o.hincr[key] == nil {
+			o.hincr[key] = make(map[string]int64, len(fields))
+		}
+		for field, delta := range fields {
+			o.hincr[key][field] += delta
+		}
+	}
+	for key, ttl := range old.expire {
+		if _, ok := o.expire[key]; !ok {
+			o.expire[key] = ttl
+		}
+	}
+}
+
+// Coalescer 合并高频计数器写入，按周期、批量阈值或 Close 时以单个 pipeline 写入 Redis
+//
+// 同一 key（或 hash field）的多次 IncrBy 在内存中先求和，Expire 只保留最后一次。
+// 写入失败会重试一次，仍失败则将本批数据合并回待写队列并通过 OnError 回调通知，不丢失计数。
+type Coalescer struct {
+	mu       sync.Mutex
+	closed   bool
+	pending  *coalescedOps
+	maxBatch int
+	onError  func(error)
+	flushFn  func(ctx context.Context, ops *coalescedOps) error
+
+	tick      <-chan time.Time
+	trigger   chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewCoalescer 创建写入合并器，使用默认 Redis 客户端
+// flushInterval 为定时写入周期，maxBatch 为触发立即写入的待写命令数（<=0 表示不按数量触发）
+func NewCoalescer(flushInterval time.Duration, maxBatch int) *Coalescer {
+	ticker := time.NewTicker(flushInterval)
+	c := newCoalescer(ticker.C, maxBatch, pipelineFlush(Client()))
+	go func() {
+		<-c.done
+		ticker.Stop()
+	}()
+	return c
+}
+
+func newCoalescer(tick <-chan time.Time, maxBatch int, flushFn func(context.Context, *coalescedOps) error) *Coalescer {
+	c := &Coalescer{
+		pending:  newCoalescedOps(),
+		maxBatch: maxBatch,
+		onError: func(err error) {
+			log.Printf("redis coalescer flush failed: %v", err)
+		},
+		flushFn: flushFn,
+		tick:    tick,
+		trigger: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go c.loop()
+	return c
+}
+
+// bySlot splits the batch by cluster hash slot. All commands for a key land
+// in the same part.
+func (o *coalescedOps) bySlot() []*coalescedOps {
+	slots := make(map[int]*coalescedOps)
+	part := func(key string) *coalescedOps {
+		slot := keySlot(key)
+		if slots[slot] == nil {
+			slots[slot] = newCoalescedOps()
+		}
+		return slots[slot]
+	}
+	for key, delta := range o.incr {
+		part(key).incr[key] = delta
+	}
+	for key, fields := range o.hincr {
+		part(key).hincr[key] = fields
+	}
+	for key, ttl := range o.expire {
+		part(key).expire[key] = ttl
+	}
+	parts := make([]*coalescedOps, 0, len(slots))
+	for _, p := range slots {
+		parts = append(parts, p)
+	}
+	return parts
+}
+
+// remove drops the keys of part from o
+func (o *coalescedOps) remove(part *coalescedOps) {
+	for key := range part.incr {
+		delete(o.incr, key)
+	}
+	for key := range part.hincr {
+		delete(o.hincr, key)
+	}
+	for key := range part.expire {
+		delete(o.expire, key)
+	}
+}
+
+// pipelineFlush writes a batch in a single MULTI/EXEC pipeline so a failed
+// flush never half-applies and can be safely retried.
+// A cluster transaction cannot span hash slots, so on a cluster each slot is
+// written in its own transaction and ops keeps only the slots that failed.
+func pipelineFlush(rds redis.UniversalClient) func(context.Context, *coalescedOps) error {
+	return func(ctx context.Context, ops *coalescedOps) error {
+		if _, ok := rds.(*redis.ClusterClient); !ok {
+			return txFlush(ctx, rds, ops)
+		}
+		parts := ops.bySlot()
+		errs := make([]error, len(parts))
+		var wg sync.WaitGroup
+		for i, part := range parts {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = txFlush(ctx, rds, part)
+			}()
+		}
+		wg.Wait()
+		for i, part := range parts {
+			if errs[i] == nil {
+				ops.remove(part)
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// txFlush writes ops in one MULTI/EXEC transaction
+func txFlush(ctx context.Context, rds redis.UniversalClient, ops *coalescedOps) error {
+	_, err := rds.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, delta := range ops.incr {
+			pipe.IncrBy(ctx, key, delta)
+		}
+		for key, fields := range ops.hincr {
+			for field, delta := range fields {
+				pipe.HIncrBy(ctx, key, field, delta)
+			}
+		}
+		for key, ttl := range ops.expire {
+			pipe.Expire(ctx, key, ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// OnError 设置写入失败回调，默认输出日志
+func (c *Coalescer) OnError(fn func(error)) {
+	c.mu.Lock()
+	c.onError = fn
+	c.mu.Unlock()
+}
+
+// IncrBy 累加 key 的计数，Close 之后返回 ErrCoalescerClosed
+func (c *Coalescer) IncrBy(key string, delta int64) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrCoalescerClosed
+	}
+	c.pending.incr[key] += delta
+	c.added()
+	return nil
+}
+
+// HIncrBy 累加 hash field 的计数，Close 之后返回 ErrCoalescerClosed
+func (c *Coalescer) HIncrBy(key, field string, delta int64) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrCoalescerClosed
+	}
+	fields := c.pending.hincr[key]
+	if fields == nil {
+		fields = make(map[string]int64)
+		c.pending.hincr[key] = fields
+	}
+	fields[field] += delta
+	c.added()
+	return nil
+}
+
+// Expire 设置 key 的过期时间，在同批计数写入之后执行，Close 之后返回 ErrCoalescerClosed
+func (c *Coalescer) Expire(key string, ttl time.Duration) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrCoalescerClosed
+	}
+	c.pending.expire[key] = ttl
+	c.added()
+	return nil
+}
+
+// added releases the lock taken by the caller and signals the loop when the
+// batch threshold is reached.
+func (c *Coalescer) added() {
+	full := c.maxBatch > 0 && c.pending.size() >= c.maxBatch
+	c.mu.Unlock()
+	if full {
+		select {
+		case c.trigger <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Flush 立即写入当前累积的数据
+func (c *Coalescer) Flush() error {
+	return c.flush()
+}
+
+// Close 停止定时写入并同步写入剩余数据，可重复调用
+// 之后的写入返回 ErrCoalescerClosed
+func (c *Coalescer) Close() error {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.closed = true
+		c.mu.Unlock()
+		close(c.stop)
+		<-c.done
+	})
+	return c.closeErr
+}
+
+func (c *Coalescer) loop() {
+	defer close(c.done)
+	for {
+		select {
+		case <-c.tick:
+			c.flush()
+		case <-c.trigger:
+			c.flush()
+		case <-c.stop:
+			c.closeErr = c.flush()
+			return
+		}
+	}
+}
+
+func (c *Coalescer) flush() error {
+	c.mu.Lock()
+	ops := c.pending
+	if ops.size() == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	c.pending = newCoalescedOps()
+	c.mu.Unlock()
+
+	ctx := context.Background()
+	err := c.flushFn(ctx, ops)
+	if err != nil {
+		err = c.flushFn(ctx, ops)
+	}
+	if err == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.pending.merge(ops)
+	onError := c.onError
+	c.mu.Unlock()
+	if onError != nil {
+		onError(err)
+	}
+	return err
+}
+
+// DirectCounter 返回直接写入 Redis 的 Counter，每次调用一次往返
+// 用于不需要合并的场景，或在测试中替代 Coalescer
+func DirectCounter() Counter {
+	return directCounter{}
+}
+
+type directCounter struct{}
+
+func (directCounter) IncrBy(key string, delta int64) error {
+	return Client().IncrBy(context.Background(), key, delta).Err()
+}
+
+func (directCounter) HIncrBy(key, field string, delta int64) error {
+	return Client().HIncrBy(context.Background(), key, field, delta).Err()
+}
+
+func (directCounter) Expire(key string, ttl time.Duration) error {
+	return Client().Expire(context.Background(), key, ttl).Err()
+}
@@ -0,0 +1,255 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingFlush collects flushed batches and fails the first `failures` calls
+type recordingFlush struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	batches  []*coalescedOps
+	flushed  chan struct{}
+}
+
+func newRecordingFlush(failures int) *recordingFlush {
+	return &recordingFlush{failures: failures, flushed: make(chan struct{}, 16)}
+}
+
+func (r *recordingFlush) fn(ctx context.Context, ops *coalescedOps) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("transient failure")
+	}
+	r.batches = append(r.batches, ops)
+	r.flushed <- struct{}{}
+	return nil
+}
+
+func (r *recordingFlush) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-r.flushed:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for flush")
+	}
+}
+
+func TestCoalescerMerging(t *testing.T) {
+	rec := newRecordingFlush(0)
+	c := newCoalescer(nil, 0, rec.fn)
+
+	c.IncrBy("hits", 1)
+	c.IncrBy("hits", 2)
+	c.IncrBy("hits", -1)
+	c.HIncrBy("stats", "sent", 5)
+	c.HIncrBy("stats", "sent", 5)
+	c.HIncrBy("stats", "dropped", 1)
+	c.Expire("hits", time.Minute)
+	c.Expire("hits", time.Hour)
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if len(rec.batches) != 1 {
+		t.Fatalf("expected 1 batch, got %d", len(rec.batches))
+	}
+	ops := rec.batches[0]
+	if ops.incr["hits"] != 2 {
+		t.Errorf("expected hits=2, got %d", ops.incr["hits"])
+	}
+	if ops.hincr["stats"]["sent"] != 10 || ops.hincr["stats"]["dropped"] != 1 {
+		t.Errorf("unexpected hash deltas: %v", ops.hincr["stats"])
+	}
+	if ops.expire["hits"] != time.Hour {
+		t.Errorf("expected last expire to win, got %v", ops.expire["hits"])
+	}
+}
+
+func TestCoalescerIntervalTrigger(t *testing.T) {
+	tick := make(chan time.Time)
+	rec := newRecordingFlush(0)
+	c := newCoalescer(tick, 0, rec.fn)
+	defer c.Close()
+
+	c.IncrBy("a", 1)
+	tick <- time.Now()
+	rec.wait(t)
+
+	c.IncrBy("a", 3)
+	tick <- time.Now()
+	rec.wait(t)
+
+	if len(rec.batches) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(rec.batches))
+	}
+	if rec.batches[1].incr["a"] != 3 {
+		t.Errorf("expected second batch a=3, got %d", rec.batches[1].incr["a"])
+	}
+}
+
+func TestCoalescerSizeTrigger(t *testing.T) {
+	rec := newRecordingFlush(0)
+	c := newCoalescer(nil, 3, rec.fn)
+	defer c.Close()
+
+	c.IncrBy("a", 1)
+	c.IncrBy("a", 1) // merged, still one pending command
+	c.IncrBy("b", 1)
+	c.HIncrBy("h", "f", 1)
+	rec.wait(t)
+
+	ops := rec.batches[0]
+	if ops.size() != 3 || ops.incr["a"] != 2 {
+		t.Errorf("unexpected batch: size=%d a=%d", ops.size(), ops.incr["a"])
+	}
+}
+
+func TestCoalescerRetryOnce(t *testing.T) {
+	rec := newRecordingFlush(1)
+	c := newCoalescer(nil, 0, rec.fn)
+
+	c.IncrBy("a", 7)
+	if err := c.Close(); err != nil {
+		t.Fatalf("expected retry to succeed, got %v", err)
+	}
+	if rec.calls != 2 || rec.batches[0].incr["a"] != 7 {
+		t.Errorf("expected one retry with a=7, calls=%d", rec.calls)
+	}
+}
+
+func TestCoalescerNoLossOnFailure(t *testing.T) {
+	rec := newRecordingFlush(2)
+	c := newCoalescer(nil, 0, rec.fn)
+
+	var reported error
+	c.OnError(func(err error) { reported = err })
+
+	c.IncrBy("a", 4)
+	c.HIncrBy("h", "f", 1)
+	if err := c.Flush(); err == nil {
+		t.Fatal("expected flush error after retry")
+	}
+	if reported == nil {
+		t.Error("expected error callback to be called")
+	}
+
+	// Deltas accumulated after the failure merge with the re-queued batch
+	c.IncrBy("a", 1)
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	ops := rec.batches[0]
+	if ops.incr["a"] != 5 || ops.hincr["h"]["f"] != 1 {
+		t.Errorf("expected a=5 h.f=1, got a=%d h.f=%d", ops.incr["a"], ops.hincr["h"]["f"])
+	}
+}
+
+func TestCoalescerCloseIdempotent(t *testing.T) {
+	rec := newRecordingFlush(0)
+	c := newCoalescer(nil, 0, rec.fn)
+	c.IncrBy("a", 1)
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("second Close failed: %v", err)
+	}
+	if len(rec.batches) != 1 {
+		t.Errorf("expected 1 batch, got %d", len(rec.batches))
+	}
+}
+
+func TestCoalescerWriteAfterClose(t *testing.T) {
+	rec := newRecordingFlush(0)
+	c := newCoalescer(nil, 0, rec.fn)
+	c.Close()
+
+	for name, err := range map[string]error{
+		"IncrBy":  c.IncrBy("a", 1),
+		"HIncrBy": c.HIncrBy("h", "f", 1),
+		"Expire":  c.Expire("a", time.Minute),
+	} {
+		if !errors.Is(err, ErrCoalescerClosed) {
+			t.Errorf("%s after Close: expected ErrCoalescerClosed, got %v", name, err)
+		}
+	}
+	if c.Flush(); len(rec.batches) != 0 {
+		t.Errorf("writes after Close must not be flushed, got %d batches", len(rec.batches))
+	}
+}
+
+func TestCoalescerRedis(t *testing.T) {
+	if os.Getenv("REDIS_TEST_SKIP") != "" {
+		t.Skip("Skipping Redis tests (REDIS_TEST_SKIP is set)")
+	}
+
+	setupTestRedis()
+
+	client := Client()
+	ctx := context.Background()
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+
+	defer client.Del(ctx, "test_coalesce", "test_coalesce_hash")
+
+	c := NewCoalescer(time.Hour, 0)
+	c.IncrBy("test_coalesce", 2)
+	c.IncrBy("test_coalesce", 3)
+	c.HIncrBy("test_coalesce_hash", "f", 4)
+	c.Expire("test_coalesce", time.Minute)
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if v, _ := client.Get(ctx, "test_coalesce").Int64(); v != 5 {
+		t.Errorf("expected 5, got %d", v)
+	}
+	if v, _ := client.HGet(ctx, "test_coalesce_hash", "f").Int64(); v != 4 {
+		t.Errorf("expected 4, got %d", v)
+	}
+	if ttl := client.TTL(ctx, "test_coalesce").Val(); ttl <= 0 {
+		t.Errorf("expected ttl to be set, got %v", ttl)
+	}
+}
+
+func TestCoalescerCluster(t *testing.T) {
+	cluster := setupTestCluster(t)
+	ctx := context.Background()
+	keys := []string{"test_coalesce_a", "test_coalesce_b", "test_coalesce_hash"}
+	if keySlot(keys[0]) == keySlot(keys[1]) {
+		t.Fatal("test keys must hash to different slots")
+	}
+	defer cluster.Del(ctx, keys...)
+
+	c := newCoalescer(nil, 0, pipelineFlush(cluster))
+	c.IncrBy(keys[0], 2)
+	c.IncrBy(keys[1], 3)
+	c.HIncrBy(keys[2], "f", 4)
+	c.Expire(keys[0], time.Minute)
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if v, _ := cluster.Get(ctx, keys[0]).Int64(); v != 2 {
+		t.Errorf("expected 2, got %d", v)
+	}
+	if v, _ := cluster.Get(ctx, keys[1]).Int64(); v != 3 {
+		t.Errorf("expected 3, got %d", v)
+	}
+	if v, _ := cluster.HGet(ctx, keys[2], "f").Int64(); v != 4 {
+		t.Errorf("expected 4, got %d", v)
+	}
+}
@@ -0,0 +1,171 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// invalidateBatch InvalidateAll 每次 SCAN 的 COUNT 和每批 UNLINK 的键数
+const invalidateBatch = 1000
+
+// Get 读取 key 的 JSON 值并解码为 T，键不存在时返回零值和 false
+//
+// 示例：
+//
+//	user, ok, err := redis.Get[User](ctx, "user:1")
+func Get[T any](ctx context.Context, key string) (T, bool, error) {
+	var val T
+	data, err := Client().Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return val, false, nil
+	}
+	if err != nil {
+		return val, false, err
+	}
+	if err := json.Unmarshal(data, &val); err != nil {
+		return val, false, err
+	}
+	return val, true, nil
+}
+
+// Set 将 val 编码为 JSON 写入 key，ttl 为 0 表示不过期
+func Set[T any](ctx context.Context, key string, val T, ttl time.Duration) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	return Client().Set(ctx, key, data, ttl).Err()
+}
+
+// Namespace 键前缀，方法中的 key 自动加上 "<prefix>:"
+// 泛型读写用 Get/Set 配合 Key：redis.Get[User](ctx, users.Key("1"))
+type Namespace struct {
+	prefix string
+}
+
+// NS 创建前缀为 prefix 的命名空间，使用默认客户端
+//
+// 示例：
+//
+//	var issues = redis.NS("github:issues")
+//	issues.Set(ctx, "7", issue, time.Hour)   // 键为 github:issues:7
+//	issues.InvalidateAll(ctx)                // 删除 github:issues:*
+func NS(prefix string) *Namespace {
+	return &Namespace{prefix: prefix}
+}
+
+// NS 返回子命名空间，前缀为 "<prefix>:<sub>"
+func (n *Namespace) NS(sub string) *Namespace {
+	return &Namespace{prefix: n.Key(sub)}
+}
+
+// Prefix 返回命名空间前缀
+func (n *Namespace) Prefix() string {
+	return n.prefix
+}
+
+// Key 返回 key 加上前缀后的完整键
+func (n *Namespace) Key(key string) string {
+	return n.prefix + ":" + key
+}
+
+// Get 读取 key 的 JSON 值到 val，返回是否存在
+func (n *Namespace) Get(ctx context.Context, key string, val any) (bool, error) {
+	data, err := Client().Get(ctx, n.Key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, val)
+}
+
+// Set 将 val 编码为 JSON 写入 key，ttl 为 0 表示不过期
+func (n *Namespace) Set(ctx context.Context, key string, val any, ttl time.Duration) error {
+	return Set(ctx, n.Key(key), val, ttl)
+}
+
+// Del 删除（UNLINK）命名空间中的 keys
+func (n *Namespace) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	full := make([]string, len(keys))
+	for i, key := range keys {
+		full[i] = n.Key(key)
+	}
+	return unlinkEach(ctx, Client(), full)
+}
+
+// InvalidateAll 删除命名空间中的所有键，返回删除的键数
+// 用 SCAN 分批遍历、UNLINK 异步释放，不像 KEYS 那样阻塞 Redis；
+// 集群模式下遍历每个主节点。遍历期间新写入的键可能不被删除
+func (n *Namespace) InvalidateAll(ctx context.Context) (int64, error) {
+	pattern := escapeGlob(n.prefix) + ":*"
+	client := Client()
+	if cluster, ok := client.(*redis.ClusterClient); ok {
+		var total atomic.Int64
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			deleted, err := unlinkMatching(ctx, node, pattern)
+			total.Add(deleted)
+			return err
+		})
+		return total.Load(), err
+	}
+	return unlinkMatching(ctx, client, pattern)
+}
+
+// unlinkMatching 用 SCAN 遍历 client 上匹配 pattern 的键并 UNLINK
+func unlinkMatching(ctx context.Context, client redis.Cmdable, pattern string) (int64, error) {
+	var (
+		cursor  uint64
+		deleted int64
+	)
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, invalidateBatch).Result()
+		if err != nil {
+			return deleted, err
+		}
+		if len(keys) > 0 {
+			if err := unlinkEach(ctx, client, keys); err != nil {
+				return deleted, err
+			}
+			deleted += int64(len(keys))
+		}
+		if next == 0 {
+			return deleted, nil
+		}
+		cursor = next
+	}
+}
+
+// unlinkEach 逐个 UNLINK keys（管道批量发送），集群模式下不同槽位的键不能合并到一条命令
+func unlinkEach(ctx context.Context, client redis.Cmdable, keys []string) error {
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Unlink(ctx, key)
+		}
+		return nil
+	})
+	return err
+}
+
+// escapeGlob 转义 SCAN MATCH 的通配符，前缀中的 * ? [ ] \ 按字面匹配
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
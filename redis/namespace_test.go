@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func setupNamespaceTest(tb testing.TB) context.Context {
+	tb.Helper()
+	if os.Getenv("REDIS_TEST_SKIP") != "" {
+		tb.Skip("Skipping Redis tests (REDIS_TEST_SKIP is set)")
+	}
+	setupTestRedis()
+	ctx := context.Background()
+	if err := Client().Ping(ctx).Err(); err != nil {
+		tb.Skipf("Redis not available: %v", err)
+	}
+	return ctx
+}
+
+type nsUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestGetSetGeneric(t *testing.T) {
+	ctx := setupNamespaceTest(t)
+	defer CacheDel("test_generic_user")
+
+	if _, ok, err := Get[nsUser](ctx, "test_generic_user"); ok || err != nil {
+		t.Fatalf("expected miss, got %v, %v", ok, err)
+	}
+	if err := Set(ctx, "test_generic_user", nsUser{Name: "Ann", Age: 30}, time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	user, ok, err := Get[nsUser](ctx, "test_generic_user")
+	if !ok || err != nil || user.Name != "Ann" || user.Age != 30 {
+		t.Errorf("unexpected value: %+v, %v, %v", user, ok, err)
+	}
+	if _, _, err := Get[int](ctx, "test_generic_user"); err == nil {
+		t.Error("expected decode error for mismatched type")
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	ctx := setupNamespaceTest(t)
+	ns := NS("test:ns*[1]")
+	other := NS("test:ns")
+	t.Cleanup(func() {
+		ns.InvalidateAll(ctx)
+		other.InvalidateAll(ctx)
+	})
+
+	if ns.Key("7") != "test:ns*[1]:7" || ns.NS("list").Key("a") != "test:ns*[1]:list:a" {
+		t.Errorf("unexpected keys: %s, %s", ns.Key("7"), ns.NS("list").Key("a"))
+	}
+
+	for i := range 25 {
+		if err := ns.Set(ctx, fmt.Sprint(i), nsUser{Age: i}, time.Minute); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	other.Set(ctx, "keep", 1, time.Minute)
+
+	var user nsUser
+	if ok, err := ns.Get(ctx, "3", &user); !ok || err != nil || user.Age != 3 {
+		t.Errorf("unexpected value: %+v, %v, %v", user, ok, err)
+	}
+	if typed, ok, _ := Get[nsUser](ctx, ns.Key("4")); !ok || typed.Age != 4 {
+		t.Errorf("expected generic Get on a namespaced key, got %+v", typed)
+	}
+	if err := ns.Del(ctx, "3", "missing"); err != nil {
+		t.Fatalf("Del failed: %v", err)
+	}
+	if ok, _ := ns.Get(ctx, "3", &user); ok {
+		t.Error("expected key 3 deleted")
+	}
+
+	// The glob characters in the prefix match literally, so test:ns:* is kept
+	deleted, err := ns.InvalidateAll(ctx)
+	if err != nil || deleted != 24 {
+		t.Errorf("expected 24 keys invalidated, got %d, %v", deleted, err)
+	}
+	if ok, _ := ns.Get(ctx, "4", &user); ok {
+		t.Error("expected namespace emptied")
+	}
+	var v int
+	if ok, _ := other.Get(ctx, "keep", &v); !ok {
+		t.Error("keys outside the namespace must be kept")
+	}
+}
+
+const benchmarkKeys = 5000
+
+// fillNamespace writes benchmarkKeys keys under ns plus as many unrelated ones
+func fillNamespace(b *testing.B, ctx context.Context, ns *Namespace) {
+	b.Helper()
+	pipe := Client().Pipeline()
+	for i := range benchmarkKeys {
+		pipe.Set(ctx, ns.Key(fmt.Sprint(i)), "x", time.Minute)
+		pipe.Set(ctx, fmt.Sprintf("bench:unrelated:%d", i), "x", time.Minute)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		b.Fatal(err)
+	}
+}
+
+// BenchmarkInvalidateAllScan measures SCAN+UNLINK invalidation
+func BenchmarkInvalidateAllScan(b *testing.B) {
+	ctx := setupNamespaceTest(b)
+	ns := NS("bench:ns")
+	defer NS("bench:unrelated").InvalidateAll(ctx)
+	for b.Loop() {
+		b.StopTimer()
+		fillNamespace(b, ctx, ns)
+		b.StartTimer()
+		if _, err := ns.InvalidateAll(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkInvalidateKeys measures the KEYS+DEL approach InvalidateAll replaces
+func BenchmarkInvalidateKeys(b *testing.B) {
+	ctx := setupNamespaceTest(b)
+	ns := NS("bench:ns")
+	defer NS("bench:unrelated").InvalidateAll(ctx)
+	for b.Loop() {
+		b.StopTimer()
+		fillNamespace(b, ctx, ns)
+		b.StartTimer()
+		keys, err := Client().Keys(ctx, "bench:ns:*").Result()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, k := range keys {
+			CacheDel(k)
+		}
+	}
+}
@@ -2,41 +2,210 @@ package redis
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 )
 
+// redis.mode 的取值
+const (
+	ModeSingle   = "single"   // 单实例（默认）
+	ModeSentinel = "sentinel" // 哨兵，addrs 为哨兵地址
+	ModeCluster  = "cluster"  // 集群，addrs 为集群节点
+)
+
+// ConnConfig Redis 连接配置
+// 按 key 从 <app>.redis.* 读取，未设置的 key 回退到 redis.*
+type ConnConfig struct {
+	Mode             string
+	Addrs            []string // addrs，未设置时为 [addr]
+	MasterName       string   // 哨兵模式的主节点名称
+	Password         string
+	SentinelPassword string
+	DB               int // 集群模式不支持
+	PoolSize         int // 0 使用 go-redis 默认值（每个 CPU 10 个连接）
+	DialTimeout      time.Duration
+	ReadTimeout      time.Duration
+	WriteTimeout     time.Duration
+}
+
 // 全局单例客户端
 var (
-	defaultClient *redis.Client
+	defaultClient redis.UniversalClient
 	clientOnce    sync.Once
+
+	appClients    = make(map[string]redis.UniversalClient)
+	appClientsMux sync.Mutex
 )
 
-// initClient initializes the singleton client from viper configuration (lazy load)
-func initClient() *redis.Client {
-	clientOnce.Do(func() {
-		addr := viper.GetString("redis.addr")
-		password := viper.GetString("redis.password")
-		db := viper.GetInt("redis.db")
+// LoadConfig 读取 app 的连接配置，app 为空时只读取 redis.*
+//
+// addr、addrs、mode、master_name 作为一组读取：<app>.redis 设置了其中任一项时
+// 只使用 <app>.redis 的这组配置，否则只使用 redis.* 的，避免混出 app 地址
+// 加全局哨兵模式这样的连接；其余配置逐项回退到 redis.*
+//
+// Example:
+//
+//	# password、pool_size 等回退到 redis.*
+//	chat:
+//	  redis:
+//	    mode: "sentinel"
+//	    addrs: ["10.0.0.1:26379", "10.0.0.2:26379"]
+//	    master_name: "mymaster"
+func LoadConfig(app string) (*ConnConfig, error) {
+	key := func(name string) string { return configKey(app, name) }
 
-		if addr == "" {
-			return
+	// 连接拓扑整组取自同一处
+	name := "redis"
+	for _, k := range []string{"addr", "addrs", "mode", "master_name"} {
+		if app != "" && viper.IsSet(app+".redis."+k) {
+			name = app + ".redis"
+			break
 		}
+	}
+	topo := func(k string) string { return name + "." + k }
+
+	cfg := &ConnConfig{
+		Mode:             viper.GetString(topo("mode")),
+		Addrs:            viper.GetStringSlice(topo("addrs")),
+		MasterName:       viper.GetString(topo("master_name")),
+		Password:         viper.GetString(key("password")),
+		SentinelPassword: viper.GetString(key("sentinel_password")),
+		DB:               viper.GetInt(key("db")),
+		PoolSize:         viper.GetInt(key("pool_size")),
+		DialTimeout:      viper.GetDuration(key("dial_timeout")),
+		ReadTimeout:      viper.GetDuration(key("read_timeout")),
+		WriteTimeout:     viper.GetDuration(key("write_timeout")),
+	}
+	if addr := viper.GetString(topo("addr")); len(cfg.Addrs) == 0 && addr != "" {
+		cfg.Addrs = []string{addr}
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = ModeSingle
+	}
+
+	switch {
+	case cfg.Mode != ModeSingle && cfg.Mode != ModeSentinel && cfg.Mode != ModeCluster:
+		return nil, fmt.Errorf("%s.mode %q is invalid (single, sentinel or cluster)", name, cfg.Mode)
+	case len(cfg.Addrs) == 0:
+		return nil, fmt.Errorf("%s.addr is not configured", name)
+	case cfg.Mode == ModeSentinel && cfg.MasterName == "":
+		return nil, fmt.Errorf("%s.master_name is required in sentinel mode", name)
+	}
+	return cfg, nil
+}
 
-		defaultClient = redis.NewClient(&redis.Options{
-			Addr:     addr,
-			Password: password,
-			DB:       db,
+// configKey 返回 app 的配置项：<app>.redis.<name> 已设置时使用，否则回退到 redis.<name>
+func configKey(app, name string) string {
+	if app != "" && viper.IsSet(app+".redis."+name) {
+		return app + ".redis." + name
+	}
+	return "redis." + name
+}
+
+// NewClient 按 cfg.Mode 创建客户端：单实例和哨兵模式为 *redis.Client，
+// 集群模式为 *redis.ClusterClient
+func NewClient(cfg *ConnConfig) redis.UniversalClient {
+	switch cfg.Mode {
+	case ModeSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.MasterName,
+			SentinelAddrs:    cfg.Addrs,
+			SentinelPassword: cfg.SentinelPassword,
+			Password:         cfg.Password,
+			DB:               cfg.DB,
+			PoolSize:         cfg.PoolSize,
+			DialTimeout:      cfg.DialTimeout,
+			ReadTimeout:      cfg.ReadTimeout,
+			WriteTimeout:     cfg.WriteTimeout,
+		})
+	case ModeCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        cfg.Addrs,
+			Password:     cfg.Password,
+			PoolSize:     cfg.PoolSize,
+			DialTimeout:  cfg.DialTimeout,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
 		})
+	}
+	return redis.NewClient(&redis.Options{
+		Addr:         cfg.Addrs[0],
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		PoolSize:     cfg.PoolSize,
+		DialTimeout:  cfg.DialTimeout,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+	})
+}
+
+// keySlot 返回 key 的集群哈希槽（CRC16 mod 16384），key 含 {hash tag} 时只计算 tag
+// 集群模式下事务和 Lua 脚本的所有 key 必须位于同一槽
+func keySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int(crc) % 16384
+}
+
+// initClient initializes the singleton client from viper configuration (lazy load)
+func initClient() redis.UniversalClient {
+	clientOnce.Do(func() {
+		if cfg, err := LoadConfig(""); err == nil {
+			defaultClient = NewClient(cfg)
+		}
 	})
 	return defaultClient
 }
 
+// appClient returns the client of app, creating it on first use
+func appClient(app string) (redis.UniversalClient, error) {
+	if app == "" {
+		if client := initClient(); client != nil {
+			return client, nil
+		}
+		_, err := LoadConfig("")
+		if err == nil {
+			err = fmt.Errorf("redis client not configured")
+		}
+		return nil, err
+	}
+
+	appClientsMux.Lock()
+	defer appClientsMux.Unlock()
+	if client, ok := appClients[app]; ok {
+		return client, nil
+	}
+	cfg, err := LoadConfig(app)
+	if err != nil {
+		return nil, err
+	}
+	client := NewClient(cfg)
+	appClients[app] = client
+	return client, nil
+}
+
 // Client 获取Redis客户端
 // Configuration is automatically loaded from viper on first use
-func Client() *redis.Client {
+func Client() redis.UniversalClient {
 	client := initClient()
 	if client == nil {
 		panic("redis client not configured")
@@ -44,6 +213,25 @@ func Client() *redis.Client {
 	return client
 }
 
+// ClientFor 获取 app 的Redis客户端，配置见 LoadConfig，app 为空时等同 Client
+// 每个 app 只创建一个客户端；配置无效时 panic
+func ClientFor(app string) redis.UniversalClient {
+	client, err := appClient(app)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// HealthCheck ping app 的Redis，app 为空时检查默认客户端
+func HealthCheck(ctx context.Context, app string) error {
+	client, err := appClient(app)
+	if err != nil {
+		return err
+	}
+	return client.Ping(ctx).Err()
+}
+
 // Subscribe 订阅Redis频道
 func Subscribe(channel string) chan string {
 	rds := Client()
@@ -70,10 +258,19 @@ func Publish(channel string, payload string) error {
 	return rds.Publish(ctx, channel, payload).Err()
 }
 
-// Close 关闭Redis连接
+// Close 关闭Redis连接，包括 ClientFor 创建的客户端
 func Close() error {
+	var firstErr error
 	if defaultClient != nil {
-		return defaultClient.Close()
+		firstErr = defaultClient.Close()
 	}
-	return nil
-}
\ No newline at end of file
+	appClientsMux.Lock()
+	defer appClientsMux.Unlock()
+	for app, client := range appClients {
+		if err := client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(appClients, app)
+	}
+	return firstErr
+}
@@ -3,10 +3,12 @@ package redis
 import (
 	"context"
 	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 )
 
@@ -456,4 +458,151 @@ func TestPubSub(t *testing.T) {
 	case <-time.After(time.Second):
 		t.Fatal("Timeout waiting for message")
 	}
-}
\ No newline at end of file
+}
+func TestLoadConfigFallback(t *testing.T) {
+	viper.Reset()
+	defer setupTestRedis()
+	viper.Set("redis.addr", "global:6379")
+	viper.Set("redis.password", "global-secret")
+	viper.Set("redis.db", 2)
+	viper.Set("redis.pool_size", 20)
+	viper.Set("chat.redis.addr", "chat:6379")
+	viper.Set("chat.redis.db", 0)
+	viper.Set("chat.redis.read_timeout", "2s")
+
+	cfg, err := LoadConfig("chat")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	// chat.redis.* 优先，其余回退到 redis.*
+	if cfg.Mode != ModeSingle || cfg.Addrs[0] != "chat:6379" || cfg.DB != 0 || cfg.Password != "global-secret" ||
+		cfg.PoolSize != 20 || cfg.ReadTimeout != 2*time.Second {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	if cfg, _ := LoadConfig("other"); cfg.Addrs[0] != "global:6379" || cfg.DB != 2 {
+		t.Errorf("unconfigured app should use redis.*: %+v", cfg)
+	}
+
+	// 连接拓扑整组取自 chat.redis，不与全局的 addrs、mode 混用
+	viper.Set("redis.addrs", []string{"g1:6379", "g2:6379"})
+	if cfg, _ := LoadConfig("chat"); len(cfg.Addrs) != 1 || cfg.Addrs[0] != "chat:6379" {
+		t.Errorf("app addr should win over global addrs: %+v", cfg)
+	}
+	viper.Set("redis.addrs", nil)
+	viper.Set("redis.mode", ModeSentinel)
+	viper.Set("redis.master_name", "global-master")
+	if cfg, err := LoadConfig("chat"); err != nil || cfg.Mode != ModeSingle || cfg.MasterName != "" {
+		t.Errorf("app addr should not inherit global sentinel mode: %+v, %v", cfg, err)
+	}
+	if cfg, _ := LoadConfig("other"); cfg.Mode != ModeSentinel || cfg.MasterName != "global-master" {
+		t.Errorf("unconfigured app should use the global topology: %+v", cfg)
+	}
+	viper.Set("redis.mode", nil)
+	viper.Set("redis.master_name", nil)
+
+	viper.Set("chat.redis.mode", ModeSentinel)
+	viper.Set("chat.redis.addrs", []string{"s1:26379", "s2:26379"})
+	if _, err := LoadConfig("chat"); err == nil {
+		t.Error("sentinel mode without master_name should fail")
+	}
+	viper.Set("chat.redis.master_name", "mymaster")
+	if cfg, err := LoadConfig("chat"); err != nil || len(cfg.Addrs) != 2 || cfg.MasterName != "mymaster" {
+		t.Errorf("unexpected sentinel config: %+v, %v", cfg, err)
+	}
+	viper.Set("chat.redis.mode", "proxy")
+	if _, err := LoadConfig("chat"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+	viper.Set("redis.addr", nil)
+	if _, err := LoadConfig(""); err == nil {
+		t.Error("expected error without addr")
+	}
+}
+
+func TestNewClientModes(t *testing.T) {
+	single := NewClient(&ConnConfig{Mode: ModeSingle, Addrs: []string{"localhost:6379"}, DB: 3, PoolSize: 5})
+	defer single.Close()
+	if c, ok := single.(*redis.Client); !ok || c.Options().DB != 3 || c.Options().PoolSize != 5 {
+		t.Errorf("single mode should create a *redis.Client with the options: %T", single)
+	}
+
+	sentinel := NewClient(&ConnConfig{Mode: ModeSentinel, Addrs: []string{"localhost:26379"}, MasterName: "mymaster"})
+	defer sentinel.Close()
+	if _, ok := sentinel.(*redis.Client); !ok {
+		t.Errorf("sentinel mode should create a failover *redis.Client, got %T", sentinel)
+	}
+
+	cluster := NewClient(&ConnConfig{Mode: ModeCluster, Addrs: []string{"n1:7000", "n2:7000"}})
+	defer cluster.Close()
+	if c, ok := cluster.(*redis.ClusterClient); !ok || len(c.Options().Addrs) != 2 {
+		t.Errorf("cluster mode should create a *redis.ClusterClient, got %T", cluster)
+	}
+}
+
+// setupTestCluster returns a cluster client for the test Redis, skipping the
+// test unless the server answers CLUSTER SLOTS
+func setupTestCluster(t *testing.T) redis.UniversalClient {
+	t.Helper()
+	if os.Getenv("REDIS_TEST_SKIP") != "" {
+		t.Skip("Skipping Redis tests (REDIS_TEST_SKIP is set)")
+	}
+	cluster := NewClient(&ConnConfig{Mode: ModeCluster, Addrs: []string{"localhost:6379"}})
+	t.Cleanup(func() { cluster.Close() })
+	if err := cluster.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("Redis cluster not available: %v", err)
+	}
+	return cluster
+}
+
+func TestKeySlot(t *testing.T) {
+	tests := map[string]int{
+		"123456789":     12739,
+		"foo":           12182,
+		"{foo}.bar":     12182,
+		"foo{}{bar}":    keySlot("foo{}{bar}"),
+		"foo{{bar}}zap": keySlot("{bar"),
+	}
+	for key, want := range tests {
+		if got := keySlot(key); got != want {
+			t.Errorf("keySlot(%q) = %d, want %d", key, got, want)
+		}
+	}
+	if keySlot("{user1000}.following") != keySlot("{user1000}.followers") {
+		t.Error("keys with the same hash tag must share a slot")
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	if os.Getenv("REDIS_TEST_SKIP") != "" {
+		t.Skip("Skipping Redis tests (REDIS_TEST_SKIP is set)")
+	}
+	setupTestRedis()
+	ctx := context.Background()
+	if err := HealthCheck(ctx, ""); err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+	defer func() {
+		viper.Set("healthy", nil)
+		viper.Set("down", nil)
+		Close()
+	}()
+
+	viper.Set("healthy.redis.db", 1)
+	viper.Set("down.redis.addr", "127.0.0.1:1")
+	viper.Set("down.redis.dial_timeout", "100ms")
+	if err := HealthCheck(ctx, "healthy"); err != nil {
+		t.Errorf("healthy app: %v", err)
+	}
+	if ClientFor("healthy") != ClientFor("healthy") || ClientFor("healthy") == Client() {
+		t.Error("each app should have its own cached client")
+	}
+	if err := HealthCheck(ctx, "down"); err == nil {
+		t.Error("expected error for unreachable Redis")
+	}
+
+	viper.Set("broken.redis.mode", "proxy")
+	defer viper.Set("broken", nil)
+	if err := HealthCheck(ctx, "broken"); err == nil || !strings.Contains(err.Error(), "broken.redis.mode") {
+		t.Errorf("expected config error, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package unred
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// batchConcurrency CreateLinks 的并发请求数
+const batchConcurrency = 5
+
+// LinkInfo 短链接信息
+type LinkInfo struct {
+	Subdomain string `json:"subdomain,omitempty"`
+	Path      string `json:"path"`
+	URL       string `json:"url,omitempty"`
+	TargetURL string `json:"target_url"`
+	ExpireAt  int64  `json:"expire_at"`  // 过期时间戳（0 = 不过期）
+	CreatedAt int64  `json:"created_at"` // 创建时间戳
+	Clicks    int64  `json:"clicks"`     // 累计点击数
+}
+
+// LinkList 短链接分页列表
+type LinkList struct {
+	Links []LinkInfo `json:"links"`
+	Total int        `json:"total"` // 符合条件的总数
+	Page  int        `json:"page"`
+	Limit int        `json:"limit"`
+}
+
+// LinkStats 短链接点击统计
+type LinkStats struct {
+	Path        string           `json:"path"`
+	Clicks      int64            `json:"clicks"`
+	LastClickAt int64            `json:"last_click_at"`   // 最后点击时间戳（0 = 未被点击）
+	Daily       map[string]int64 `json:"daily,omitempty"` // 按日期（2006-01-02）统计的点击数
+}
+
+// CreateLinkSpec 批量创建中的一个短链接
+type CreateLinkSpec struct {
+	Path      string `json:"path"`
+	TargetURL string `json:"target_url"`
+	ExpireAt  int64  `json:"expire_at"`
+}
+
+// GetLink 查询短链接信息（GET path）
+// Configuration is automatically loaded from viper on first use
+func GetLink(path string) (*LinkInfo, error) {
+	client := initClient()
+	if client == nil {
+		return nil, fmt.Errorf("unred client not configured")
+	}
+	return client.GetLink(path)
+}
+
+// ListLinks 分页列出短链接（GET /?prefix=&page=&limit=）
+// prefix 为空时列出全部，page 从 1 开始
+// Configuration is automatically loaded from viper on first use
+func ListLinks(prefix string, page, limit int) (*LinkList, error) {
+	client := initClient()
+	if client == nil {
+		return nil, fmt.Errorf("unred client not configured")
+	}
+	return client.ListLinks(prefix, page, limit)
+}
+
+// GetLinkStats 查询短链接点击统计（GET path?stats=1）
+// Configuration is automatically loaded from viper on first use
+func GetLinkStats(path string) (*LinkStats, error) {
+	client := initClient()
+	if client == nil {
+		return nil, fmt.Errorf("unred client not configured")
+	}
+	return client.GetLinkStats(path)
+}
+
+// CreateLinks 并发批量创建短链接
+// Configuration is automatically loaded from viper on first use
+func CreateLinks(specs []CreateLinkSpec) ([]CreateLinkResponse, error) {
+	client := initClient()
+	if client == nil {
+		return nil, fmt.Errorf("unred client not configured")
+	}
+	return client.CreateLinks(specs)
+}
+
+// GetLink 使用自定义客户端查询短链接信息
+func (c *Client) GetLink(path string) (*LinkInfo, error) {
+	var info LinkInfo
+	if err := c.doRequest("GET", path, nil, nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// ListLinks 使用自定义客户端分页列出短链接
+func (c *Client) ListLinks(prefix string, page, limit int) (*LinkList, error) {
+	query := url.Values{}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var list LinkList
+	if err := c.doRequest("GET", "/", query, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetLinkStats 使用自定义客户端查询短链接点击统计
+func (c *Client) GetLinkStats(path string) (*LinkStats, error) {
+	var stats LinkStats
+	if err := c.doRequest("GET", path, url.Values{"stats": {"1"}}, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// CreateLinks 使用自定义客户端并发批量创建短链接
+// 结果与 specs 顺序一致；失败的项 Success 为 false、Message 为错误信息，
+// 所有失败项的错误合并后返回
+func (c *Client) CreateLinks(specs []CreateLinkSpec) ([]CreateLinkResponse, error) {
+	results := make([]CreateLinkResponse, len(specs))
+	errs := make([]error, len(specs))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, batchConcurrency)
+	for i, spec := range specs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			resp, err := c.CreateLink(spec.Path, spec.TargetURL, spec.ExpireAt)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", spec.Path, err)
+				results[i] = CreateLinkResponse{Path: spec.Path, Message: err.Error()}
+				return
+			}
+			results[i] = *resp
+		}()
+	}
+	wg.Wait()
+
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,168 @@
+package unred
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServer 模拟 Unred 管理接口，链接保存在内存中
+// conflicts 为接下来 PUT 请求中强制返回冲突的次数
+type fakeServer struct {
+	mu        sync.Mutex
+	links     map[string]LinkInfo
+	conflicts int
+	puts      []string
+}
+
+func newFakeServer(t *testing.T) (*fakeServer, *Client) {
+	t.Helper()
+	f := &fakeServer{links: map[string]LinkInfo{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, NewClient(server.URL, "secret")
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reply := func(status int, v any) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+	if r.Header.Get("X-Secret-Key") != "secret" {
+		reply(http.StatusUnauthorized, map[string]any{"success": false, "message": "unauthorized"})
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case r.Method == "PUT":
+		var req CreateLinkRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.puts = append(f.puts, path)
+		if _, ok := f.links[path]; ok || f.conflicts > 0 {
+			f.conflicts--
+			reply(http.StatusConflict, map[string]any{"success": false, "message": "path already exists"})
+			return
+		}
+		if strings.Contains(req.TargetURL, "invalid") {
+			reply(http.StatusBadRequest, map[string]any{"success": false, "message": "invalid target_url"})
+			return
+		}
+		f.links[path] = LinkInfo{Path: path, TargetURL: req.TargetURL, ExpireAt: req.ExpireAt, URL: "https://x.test" + path}
+		reply(http.StatusCreated, CreateLinkResponse{Success: true, Path: path, URL: "https://x.test" + path})
+	case r.Method == "DELETE":
+		delete(f.links, path)
+		reply(http.StatusOK, DeleteLinkResponse{Success: true})
+	case r.Method == "GET" && path == "/":
+		list := LinkList{Page: 1, Limit: 20}
+		for p, link := range f.links {
+			if strings.HasPrefix(p, r.URL.Query().Get("prefix")) {
+				list.Links = append(list.Links, link)
+			}
+		}
+		list.Total = len(list.Links)
+		reply(http.StatusOK, list)
+	case r.Method == "GET":
+		link, ok := f.links[path]
+		if !ok {
+			reply(http.StatusNotFound, map[string]any{"success": false, "message": "not found"})
+			return
+		}
+		if r.URL.Query().Get("stats") == "1" {
+			reply(http.StatusOK, LinkStats{Path: path, Clicks: 3, Daily: map[string]int64{"2024-05-01": 3}})
+			return
+		}
+		link.Clicks = 3
+		reply(http.StatusOK, link)
+	}
+}
+
+func TestLinkLookup(t *testing.T) {
+	_, client := newFakeServer(t)
+
+	if _, err := client.CreateLink("s/a", "https://example.com/a", 0); err != nil {
+		t.Fatalf("CreateLink failed: %v", err)
+	}
+	client.CreateLink("/p/b", "https://example.com/b", 0)
+
+	info, err := client.GetLink("/s/a")
+	if err != nil {
+		t.Fatalf("GetLink failed: %v", err)
+	}
+	if info.TargetURL != "https://example.com/a" || info.Clicks != 3 {
+		t.Errorf("unexpected link: %+v", info)
+	}
+
+	list, err := client.ListLinks("/s/", 1, 20)
+	if err != nil {
+		t.Fatalf("ListLinks failed: %v", err)
+	}
+	if list.Total != 1 || list.Links[0].Path != "/s/a" {
+		t.Errorf("unexpected list: %+v", list)
+	}
+
+	stats, err := client.GetLinkStats("/s/a")
+	if err != nil {
+		t.Fatalf("GetLinkStats failed: %v", err)
+	}
+	if stats.Clicks != 3 || stats.Daily["2024-05-01"] != 3 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	var apiErr *APIError
+	if _, err := client.GetLink("/missing"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 APIError, got %v", err)
+	}
+}
+
+func TestCreateLinkAPIError(t *testing.T) {
+	_, client := newFakeServer(t)
+
+	resp, err := client.CreateLink("/s/bad", "invalid", 0)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 APIError, got %v", err)
+	}
+	if resp == nil || resp.Message != "invalid target_url" {
+		t.Errorf("response should be returned with the API error: %+v", resp)
+	}
+
+	resp, err = NewClient(client.apiEndpoint, "wrong").CreateLink("/s/a", "https://example.com", 0)
+	if err == nil || !strings.Contains(err.Error(), "status=401, message=unauthorized") {
+		t.Errorf("expected 401 error, got %v (%+v)", err, resp)
+	}
+}
+
+func TestCreateLinks(t *testing.T) {
+	f, client := newFakeServer(t)
+
+	specs := []CreateLinkSpec{
+		{Path: "/b/1", TargetURL: "https://example.com/1"},
+		{Path: "/b/2", TargetURL: "invalid"},
+		{Path: "/b/3", TargetURL: "https://example.com/3"},
+	}
+	for i := 4; i <= 12; i++ {
+		specs = append(specs, CreateLinkSpec{Path: "/b/n" + string(rune('a'+i)), TargetURL: "https://example.com/n"})
+	}
+
+	results, err := client.CreateLinks(specs)
+	if err == nil || !strings.Contains(err.Error(), "/b/2") || strings.Contains(err.Error(), "/b/1") {
+		t.Errorf("error should name only the failed path, got %v", err)
+	}
+	if len(results) != len(specs) {
+		t.Fatalf("got %d results, want %d", len(results), len(specs))
+	}
+	if !results[0].Success || results[1].Success || results[1].Path != "/b/2" || !results[2].Success {
+		t.Errorf("unexpected results: %+v", results[:3])
+	}
+	if len(f.links) != len(specs)-1 {
+		t.Errorf("created %d links, want %d", len(f.links), len(specs)-1)
+	}
+}
@@ -0,0 +1,108 @@
+package unred
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// defaultRandomPathLength 随机路径默认长度
+	defaultRandomPathLength = 8
+	// randomPathAttempts 路径冲突时最多尝试次数（含首次）
+	randomPathAttempts = 4
+
+	base62Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// ErrPathTaken 路径已存在
+var ErrPathTaken = errors.New("unred: path already exists")
+
+// Is 使 errors.Is(err, ErrPathTaken) 能识别路径冲突：409 或提示已存在
+func (e *APIError) Is(target error) bool {
+	if target != ErrPathTaken {
+		return false
+	}
+	return e.StatusCode == http.StatusConflict || strings.Contains(strings.ToLower(e.Message), "exist")
+}
+
+// LinkOption 配置 CreateRandomLink
+type LinkOption func(*linkOptions)
+
+type linkOptions struct {
+	prefix string
+	length int
+}
+
+// WithPrefix 设置随机路径前缀，如 "/s/"
+func WithPrefix(prefix string) LinkOption {
+	return func(o *linkOptions) { o.prefix = prefix }
+}
+
+// WithPathLength 设置随机部分的长度，覆盖 unred.random_path_length
+func WithPathLength(length int) LinkOption {
+	return func(o *linkOptions) { o.length = length }
+}
+
+// CreateRandomLink 使用随机路径创建短链接
+// 路径为前缀加 unred.random_path_length（默认 8）位 base62 字符，
+// 路径已存在时换一个路径重试，最多重试 3 次
+// Configuration is automatically loaded from viper on first use
+func CreateRandomLink(targetURL string, expireAt int64, opts ...LinkOption) (*CreateLinkResponse, error) {
+	client := initClient()
+	if client == nil {
+		return nil, fmt.Errorf("unred client not configured")
+	}
+	if length := viper.GetInt("unred.random_path_length"); length > 0 {
+		opts = append([]LinkOption{WithPathLength(length)}, opts...)
+	}
+	return client.CreateRandomLink(targetURL, expireAt, opts...)
+}
+
+// CreateRandomLink 使用自定义客户端以随机路径创建短链接
+// 随机部分默认 8 位，可用 WithPathLength 修改
+func (c *Client) CreateRandomLink(targetURL string, expireAt int64, opts ...LinkOption) (*CreateLinkResponse, error) {
+	o := &linkOptions{length: defaultRandomPathLength}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.length <= 0 {
+		return nil, fmt.Errorf("invalid random path length: %d", o.length)
+	}
+
+	var err error
+	for range randomPathAttempts {
+		path := o.prefix + randomPath(o.length)
+		var resp *CreateLinkResponse
+		resp, err = c.CreateLink(path, targetURL, expireAt)
+		if !errors.Is(err, ErrPathTaken) {
+			return resp, err
+		}
+	}
+	return nil, fmt.Errorf("no free path after %d attempts: %w", randomPathAttempts, err)
+}
+
+// randomPath 生成 n 位 base62 随机字符串
+func randomPath(n int) string {
+	b := make([]byte, n)
+	buf := make([]byte, n)
+	for i := 0; i < n; {
+		rand.Read(buf)
+		for _, v := range buf {
+			if i == n {
+				break
+			}
+			// 丢弃 248 及以上的值，避免取模偏差（248 = 62 * 4）
+			if v >= 248 {
+				continue
+			}
+			b[i] = base62Chars[v%62]
+			i++
+		}
+	}
+	return string(b)
+}
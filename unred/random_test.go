@@ -0,0 +1,72 @@
+package unred
+
+import (
+	"errors"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestCreateLinkPathTaken(t *testing.T) {
+	_, client := newFakeServer(t)
+
+	client.CreateLink("/s/a", "https://example.com/a", 0)
+	resp, err := client.CreateLink("/s/a", "https://example.com/b", 0)
+	if !errors.Is(err, ErrPathTaken) {
+		t.Fatalf("expected ErrPathTaken, got %v", err)
+	}
+	if resp == nil || resp.Success {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestCreateRandomLink(t *testing.T) {
+	f, client := newFakeServer(t)
+	f.conflicts = 2
+
+	resp, err := client.CreateRandomLink("https://example.com", 0, WithPrefix("/s/"))
+	if err != nil {
+		t.Fatalf("CreateRandomLink failed: %v", err)
+	}
+	if !regexp.MustCompile(`^/s/[0-9A-Za-z]{8}$`).MatchString(resp.Path) || resp.URL != "https://x.test"+resp.Path {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if len(f.puts) != 3 || f.puts[0] == f.puts[1] || f.puts[1] == f.puts[2] {
+		t.Errorf("expected 3 attempts with fresh paths, got %v", f.puts)
+	}
+
+	f.conflicts = 10
+	if _, err := client.CreateRandomLink("https://example.com", 0); !errors.Is(err, ErrPathTaken) {
+		t.Errorf("expected ErrPathTaken after retries, got %v", err)
+	}
+	if len(f.puts) != 3+randomPathAttempts {
+		t.Errorf("expected %d attempts, got %d", randomPathAttempts, len(f.puts)-3)
+	}
+}
+
+func TestCreateRandomLinkPathLength(t *testing.T) {
+	_, client := newFakeServer(t)
+
+	clientOnce = sync.Once{}
+	defaultClient = nil
+	viper.Set("unred.api_endpoint", client.apiEndpoint)
+	viper.Set("unred.secret_key", "secret")
+	viper.Set("unred.random_path_length", 12)
+	t.Cleanup(func() {
+		viper.Set("unred.api_endpoint", "")
+		viper.Set("unred.secret_key", "")
+		viper.Set("unred.random_path_length", 0)
+		clientOnce = sync.Once{}
+		defaultClient = nil
+	})
+
+	resp, err := CreateRandomLink("https://example.com", 0)
+	if err != nil {
+		t.Fatalf("CreateRandomLink failed: %v", err)
+	}
+	if !regexp.MustCompile(`^/[0-9A-Za-z]{12}$`).MatchString(resp.Path) {
+		t.Errorf("unexpected path: %q", resp.Path)
+	}
+}
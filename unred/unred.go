@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -47,6 +48,16 @@ type DeleteLinkResponse struct {
 	Message string `json:"message,omitempty"`
 }
 
+// APIError 接口返回非 2xx 状态码
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api error: status=%d, message=%s", e.StatusCode, e.Message)
+}
+
 // initClient initializes the singleton client from viper configuration (lazy load)
 func initClient() *Client {
 	clientOnce.Do(func() {
@@ -78,62 +89,7 @@ func CreateLink(path string, targetURL string, expireAt int64) (*CreateLinkRespo
 	if client == nil {
 		return nil, fmt.Errorf("unred client not configured")
 	}
-
-	// 确保 path 以 / 开头
-	if !strings.HasPrefix(path, "/") {
-		path = "/" + path
-	}
-
-	// 构建请求体
-	reqBody := CreateLinkRequest{
-		TargetURL: targetURL,
-	}
-	if expireAt > 0 {
-		reqBody.ExpireAt = expireAt
-	}
-
-	bodyBytes, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// 构建请求 URL
-	url := fmt.Sprintf("https://%s%s", client.apiEndpoint, path)
-
-	// 创建 HTTP 请求
-	req, err := http.NewRequest("PUT", url, bytes.NewBuffer(bodyBytes))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Secret-Key", client.secretKey)
-
-	// 发送请求
-	resp, err := client.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// 读取响应
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// 解析响应
-	var result CreateLinkResponse
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w, body: %s", err, string(respBody))
-	}
-
-	// 检查 HTTP 状态码
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return &result, fmt.Errorf("api error: status=%d, message=%s", resp.StatusCode, result.Message)
-	}
-
-	return &result, nil
+	return client.CreateLink(path, targetURL, expireAt)
 }
 
 // DeleteLink 删除短链接
@@ -144,51 +100,11 @@ func DeleteLink(path string) (*DeleteLinkResponse, error) {
 	if client == nil {
 		return nil, fmt.Errorf("unred client not configured")
 	}
-
-	// 确保 path 以 / 开头
-	if !strings.HasPrefix(path, "/") {
-		path = "/" + path
-	}
-
-	// 构建请求 URL
-	url := fmt.Sprintf("https://%s%s", client.apiEndpoint, path)
-
-	// 创建 HTTP 请求
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("X-Secret-Key", client.secretKey)
-
-	// 发送请求
-	resp, err := client.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// 读取响应
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// 解析响应
-	var result DeleteLinkResponse
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w, body: %s", err, string(respBody))
-	}
-
-	// 检查 HTTP 状态码
-	if resp.StatusCode != http.StatusOK {
-		return &result, fmt.Errorf("api error: status=%d, message=%s", resp.StatusCode, result.Message)
-	}
-
-	return &result, nil
+	return client.DeleteLink(path)
 }
 
 // NewClient 创建自定义客户端（不使用全局单例）
+// apiEndpoint 默认使用 https，也可以带上协议，如 "http://localhost:8080"
 func NewClient(apiEndpoint, secretKey string) *Client {
 	return &Client{
 		apiEndpoint: strings.TrimSuffix(apiEndpoint, "/"),
@@ -201,11 +117,6 @@ func NewClient(apiEndpoint, secretKey string) *Client {
 
 // CreateLink 使用自定义客户端创建短链接
 func (c *Client) CreateLink(path string, targetURL string, expireAt int64) (*CreateLinkResponse, error) {
-	// 确保 path 以 / 开头
-	if !strings.HasPrefix(path, "/") {
-		path = "/" + path
-	}
-
 	// 构建请求体
 	reqBody := CreateLinkRequest{
 		TargetURL: targetURL,
@@ -214,91 +125,101 @@ func (c *Client) CreateLink(path string, targetURL string, expireAt int64) (*Cre
 		reqBody.ExpireAt = expireAt
 	}
 
-	bodyBytes, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// 构建请求 URL
-	url := fmt.Sprintf("https://%s%s", c.apiEndpoint, path)
-
-	// 创建 HTTP 请求
-	req, err := http.NewRequest("PUT", url, bytes.NewBuffer(bodyBytes))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Secret-Key", c.secretKey)
-
-	// 发送请求
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// 读取响应
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// 解析响应
 	var result CreateLinkResponse
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w, body: %s", err, string(respBody))
-	}
-
-	// 检查 HTTP 状态码
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return &result, fmt.Errorf("api error: status=%d, message=%s", resp.StatusCode, result.Message)
+	if err := c.doRequest("PUT", path, nil, reqBody, &result); err != nil {
+		return resultOnAPIError(&result, err)
 	}
-
 	return &result, nil
 }
 
 // DeleteLink 使用自定义客户端删除短链接
 func (c *Client) DeleteLink(path string) (*DeleteLinkResponse, error) {
+	var result DeleteLinkResponse
+	if err := c.doRequest("DELETE", path, nil, nil, &result); err != nil {
+		return resultOnAPIError(&result, err)
+	}
+	return &result, nil
+}
+
+// doRequest 发送管理接口请求，body 不为 nil 时以 JSON 发送，响应解析到 out
+// 状态码不是 2xx 时返回 *APIError，若响应是 JSON 也会解析到 out
+func (c *Client) doRequest(method, path string, query url.Values, body, out any) error {
 	// 确保 path 以 / 开头
 	if !strings.HasPrefix(path, "/") {
 		path = "/" + path
 	}
 
 	// 构建请求 URL
-	url := fmt.Sprintf("https://%s%s", c.apiEndpoint, path)
+	reqURL := c.baseURL() + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(bodyBytes)
+	}
 
 	// 创建 HTTP 请求
-	req, err := http.NewRequest("DELETE", url, nil)
+	req, err := http.NewRequest(method, reqURL, reader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
-
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
 	req.Header.Set("X-Secret-Key", c.secretKey)
 
 	// 发送请求
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// 检查 HTTP 状态码
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var msg struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(respBody, &msg) == nil {
+			json.Unmarshal(respBody, out)
+		} else {
+			msg.Message = strings.TrimSpace(string(respBody))
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: msg.Message}
 	}
 
 	// 解析响应
-	var result DeleteLinkResponse
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w, body: %s", err, string(respBody))
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w, body: %s", err, string(respBody))
 	}
+	return nil
+}
 
-	// 检查 HTTP 状态码
-	if resp.StatusCode != http.StatusOK {
-		return &result, fmt.Errorf("api error: status=%d, message=%s", resp.StatusCode, result.Message)
+// baseURL 返回接口地址，未指定协议时使用 https
+func (c *Client) baseURL() string {
+	if strings.Contains(c.apiEndpoint, "://") {
+		return c.apiEndpoint
 	}
+	return "https://" + c.apiEndpoint
+}
 
-	return &result, nil
+// resultOnAPIError 接口返回错误状态时同时返回解析到的响应，其他错误只返回 error
+func resultOnAPIError[T any](result *T, err error) (*T, error) {
+	if _, ok := err.(*APIError); ok {
+		return result, err
+	}
+	return nil, err
 }